@@ -10,9 +10,18 @@ import (
 	"github.com/a4abhishek/fileops/internal/cli"
 	"github.com/a4abhishek/fileops/internal/config"
 	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+)
+
+// Build information, set with -ldflags "-X main.Version=... -X main.GitCommit=..."
+var (
+	Version   = "dev"
+	GitCommit = "unknown"
 )
 
 func main() {
+	cli.Version, cli.GitCommit = Version, GitCommit
+
 	// Setup graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -23,7 +32,7 @@ func main() {
 
 	go func() {
 		<-sigChan
-		fmt.Println("\n🛑 Gracefully shutting down...")
+		ui.Printf("\n🛑 Gracefully shutting down...\n")
 		cancel()
 	}()
 
@@ -34,6 +43,12 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Messages outside commands follow the configured theme and locale too
+	if err := ui.Configure(cfg.Output.Theme, cfg.Output.Locale); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+
 	// Initialize logger
 	logConfig := logger.LoggingConfig{
 		Level:  cfg.Logging.Level,
@@ -50,6 +65,6 @@ func main() {
 	rootCmd := cli.NewRootCommand(ctx, cfg, log)
 	if err := rootCmd.Execute(); err != nil {
 		log.Error("Command execution failed", "error", err)
-		os.Exit(1)
+		os.Exit(cli.ExitCode(err))
 	}
 }
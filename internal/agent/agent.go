@@ -0,0 +1,557 @@
+// Package agent lets fileops on one machine list, stat and hash files on
+// other machines. An agent serves the roots it exports over HTTP; the
+// coordinator compares the inventories of several agents to find duplicates
+// across hosts, and syncs trees between agents. Comparing never moves file
+// contents; syncing transfers only content the destination does not hold.
+package agent
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+	"github.com/a4abhishek/fileops/pkg/schema"
+)
+
+// DefaultAddress is where an agent listens unless told otherwise
+const DefaultAddress = ":7447"
+
+// SchemaHeader carries the schema version of the agent API on every JSON
+// response, also on the JSON lines of a listing
+const SchemaHeader = "Fileops-Schema-Version"
+
+// maxHashBatch bounds the number of paths hashed by one request
+const maxHashBatch = 1024
+
+// Entry is a regular file below an exported root
+type Entry struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Device  uint64    `json:"device,omitempty"`
+	Inode   uint64    `json:"inode,omitempty"`
+}
+
+// Info describes an agent
+type Info struct {
+	SchemaVersion int      `json:"schema_version"` // See package schema
+	Host          string   `json:"host"`
+	Roots         []string `json:"roots"`
+	Version       string   `json:"version"`
+	Writable      bool     `json:"writable"`
+	Scope         string   `json:"scope"` // What the credentials of the caller allow
+}
+
+// HashRequest asks for the content hashes of files
+type HashRequest struct {
+	Algorithm string   `json:"algorithm"`
+	Paths     []string `json:"paths"`
+}
+
+// HashResponse holds the hashes, and the error for every file that could
+// not be hashed
+type HashResponse struct {
+	SchemaVersion int               `json:"schema_version"`
+	Hashes        map[string]string `json:"hashes"`
+	Errors        map[string]string `json:"errors,omitempty"`
+}
+
+// PlaceRequest asks an agent to put a copy of one of its files at another
+// path, by hardlink where possible, or to move it there
+type PlaceRequest struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Move   bool   `json:"move"`
+}
+
+// PlaceResponse tells how a file was placed: linked, copied or moved
+type PlaceResponse struct {
+	SchemaVersion int    `json:"schema_version"`
+	Method        string `json:"method"`
+}
+
+// Server serves the files below a set of roots. Every request must carry the
+// token, when one is set, as a bearer token, and with mutual TLS a client
+// certificate. A server without credentials only serves the local machine
+// unless it is marked insecure. Only writable servers accept files and place
+// them, and only from clients with the operate scope, which requests without
+// credentials never have.
+type Server struct {
+	roots     []string
+	resolved  []string // Roots with their symbolic links resolved
+	token     string
+	readToken string
+	fs        *filesystem.OSFileSystem
+	writable  bool
+	insecure  bool
+	tls       *tls.Config
+}
+
+// scopeKey holds the scope of a request in its context
+type scopeKey struct{}
+
+// NewServer creates an agent exporting roots
+func NewServer(roots []string, token string, chunkSize int64) (*Server, error) {
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("at least one root is required")
+	}
+	cleaned := make([]string, 0, len(roots))
+	for _, root := range roots {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return nil, fmt.Errorf("invalid root %s: %w", root, err)
+		}
+		if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("root is not a directory: %s", abs)
+		}
+		cleaned = append(cleaned, abs)
+	}
+	resolved := make([]string, 0, len(cleaned))
+	for _, root := range cleaned {
+		real, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			return nil, fmt.Errorf("invalid root %s: %w", root, err)
+		}
+		resolved = append(resolved, real)
+	}
+	return &Server{roots: cleaned, resolved: resolved, token: token, fs: filesystem.NewOSFileSystem(chunkSize)}, nil
+}
+
+// SetWritable lets other machines write files below the roots and link,
+// copy or move files within them, as the destination of a sync
+func (s *Server) SetWritable(writable bool) {
+	s.writable = writable
+}
+
+// SetReadToken sets a second token that grants the read scope only
+func (s *Server) SetReadToken(token string) {
+	s.readToken = token
+}
+
+// SetInsecure lets a server without credentials serve other machines, so
+// anyone who can reach it can list, hash and read the files of the roots
+func (s *Server) SetInsecure(insecure bool) {
+	s.insecure = insecure
+}
+
+// SetTLS serves the API over TLS. A config with client CAs requires client
+// certificates, whose scope then applies to every request.
+func (s *Server) SetTLS(config *tls.Config) {
+	s.tls = config
+}
+
+// Handler returns the HTTP API of the agent
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/info", s.handleInfo)
+	mux.HandleFunc("GET /v1/list", s.handleList)
+	mux.HandleFunc("GET /v1/stat", s.handleStat)
+	mux.HandleFunc("POST /v1/hash", s.handleHash)
+	mux.HandleFunc("GET /v1/read", s.handleRead)
+	mux.HandleFunc("PUT /v1/write", operate(s.handleWrite))
+	mux.HandleFunc("POST /v1/place", operate(s.handlePlace))
+	return s.authorize(mux)
+}
+
+// ListenAndServe serves the API on address until ctx is cancelled. A
+// writable server needs a token or client certificates, as anyone who can
+// reach it could otherwise write into the roots; a server without any
+// credentials listens on a loopback address only, unless it is insecure.
+func (s *Server) ListenAndServe(ctx context.Context, address string) error {
+	if s.writable && s.token == "" && !s.clientCertificates() {
+		return fmt.Errorf("a writable agent requires a token or client certificates")
+	}
+	if !s.credentialed() && !s.insecure && !IsLoopback(address) {
+		return fmt.Errorf("an agent without a token or client certificates only listens on a loopback address")
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	if s.tls != nil {
+		listener = tls.NewListener(listener, s.tls)
+	}
+	server := &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// authorize rejects requests without valid credentials and records the
+// scope they grant. A request holding both a client certificate and a token
+// gets the narrower of their scopes; one without credentials, to an agent
+// that requires none, the read scope.
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.credentialed() && !s.insecure && !IsLoopback(r.RemoteAddr) {
+			http.Error(w, "the agent requires no credentials and serves the local machine only", http.StatusForbidden)
+			return
+		}
+		scope := ScopeRead
+		certified := r.TLS != nil && len(r.TLS.VerifiedChains) > 0
+		if certified {
+			scope = certificateScope(r.TLS.VerifiedChains[0][0])
+		}
+		if s.token != "" || s.readToken != "" {
+			given := []byte(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+			switch {
+			case s.token != "" && subtle.ConstantTimeCompare(given, []byte(s.token)) == 1:
+				if !certified {
+					scope = ScopeOperate
+				}
+			case s.readToken != "" && subtle.ConstantTimeCompare(given, []byte(s.readToken)) == 1:
+				scope = ScopeRead
+			default:
+				http.Error(w, "invalid or missing token", http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), scopeKey{}, scope)))
+	})
+}
+
+// clientCertificates reports whether the server requires client certificates
+func (s *Server) clientCertificates() bool {
+	return s.tls != nil && s.tls.ClientCAs != nil
+}
+
+// credentialed reports whether requests must carry a token or a certificate
+func (s *Server) credentialed() bool {
+	return s.token != "" || s.readToken != "" || s.clientCertificates()
+}
+
+// IsLoopback reports whether a host:port address is on the loopback
+// interface; an empty host listens on every interface
+func IsLoopback(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// operate restricts a handler to requests with the operate scope
+func operate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if requestScope(r) != ScopeOperate {
+			http.Error(w, "the credentials grant read access only", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// requestScope returns the scope authorize granted to r
+func requestScope(r *http.Request) string {
+	scope, _ := r.Context().Value(scopeKey{}).(string)
+	return scope
+}
+
+func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
+	host, _ := os.Hostname()
+	writeJSON(w, Info{SchemaVersion: schema.AgentVersion, Host: host, Roots: s.roots, Version: "1", Writable: s.writable, Scope: requestScope(r)})
+}
+
+// handleList streams the regular files below root as JSON lines
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	root, ok := s.exported(r.URL.Query().Get("root"))
+	if !ok {
+		http.Error(w, "root is not exported by this agent", http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set(SchemaHeader, strconv.Itoa(schema.AgentVersion))
+	writer := bufio.NewWriter(w)
+	encoder := json.NewEncoder(writer)
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err := r.Context().Err(); err != nil {
+			return err
+		}
+		if err != nil || !d.Type().IsRegular() {
+			return nil // Unreadable directories and special files are left out
+		}
+		info, err := s.fs.Stat(path)
+		if err != nil {
+			return nil
+		}
+		return encoder.Encode(Entry{Path: path, Size: info.Size, ModTime: info.ModTime, Device: info.Device, Inode: info.Inode})
+	})
+	writer.Flush()
+}
+
+func (s *Server) handleStat(w http.ResponseWriter, r *http.Request) {
+	path, ok := s.exported(r.URL.Query().Get("path"))
+	if !ok {
+		http.Error(w, "path is not below an exported root", http.StatusForbidden)
+		return
+	}
+	info, err := s.fs.Stat(path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, Entry{Path: path, Size: info.Size, ModTime: info.ModTime, Device: info.Device, Inode: info.Inode})
+}
+
+func (s *Server) handleHash(w http.ResponseWriter, r *http.Request) {
+	var request HashRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(request.Paths) > maxHashBatch {
+		http.Error(w, fmt.Sprintf("at most %d paths per request", maxHashBatch), http.StatusBadRequest)
+		return
+	}
+	if _, err := filesystem.NewHasher(request.Algorithm); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := HashResponse{SchemaVersion: schema.AgentVersion, Hashes: make(map[string]string), Errors: make(map[string]string)}
+	for _, requested := range request.Paths {
+		if r.Context().Err() != nil {
+			return
+		}
+		path, ok := s.exported(requested)
+		if !ok {
+			response.Errors[requested] = "not below an exported root"
+			continue
+		}
+		hash, err := s.fs.ComputeHash(path, request.Algorithm)
+		if err != nil {
+			response.Errors[requested] = err.Error()
+			continue
+		}
+		response.Hashes[requested] = hash
+	}
+	writeJSON(w, response)
+}
+
+// handleRead streams the content of a file
+func (s *Server) handleRead(w http.ResponseWriter, r *http.Request) {
+	path, ok := s.exported(r.URL.Query().Get("path"))
+	if !ok {
+		http.Error(w, "path is not below an exported root", http.StatusForbidden)
+		return
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+	w.Header().Set("Content-Type", "application/octet-stream")
+	io.Copy(w, file)
+}
+
+// handleWrite stores the request body at path. The file appears only once it
+// is complete and, when a hash is given, verified.
+func (s *Server) handleWrite(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	path, ok := s.writablePath(w, query.Get("path"))
+	if !ok {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".fileops-sync-*")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	var hasher hash.Hash
+	var writer io.Writer = tmp
+	if algorithm := query.Get("algorithm"); algorithm != "" {
+		if hasher, err = filesystem.NewHasher(algorithm); err != nil {
+			tmp.Close()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writer = io.MultiWriter(tmp, hasher)
+	}
+	_, err = io.Copy(writer, r.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if hasher != nil && hex.EncodeToString(hasher.Sum(nil)) != query.Get("hash") {
+		http.Error(w, "content does not match its hash", http.StatusUnprocessableEntity)
+		return
+	}
+
+	if modTime, err := time.Parse(time.RFC3339Nano, query.Get("mtime")); err == nil {
+		os.Chtimes(tmp.Name(), modTime, modTime)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, PlaceResponse{SchemaVersion: schema.AgentVersion, Method: "written"})
+}
+
+// handlePlace puts a file that is already on this machine at another path:
+// moved, hardlinked or, across filesystems, copied
+func (s *Server) handlePlace(w http.ResponseWriter, r *http.Request) {
+	var request PlaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	source, ok := s.writablePath(w, request.Source)
+	if !ok {
+		return
+	}
+	target, ok := s.writablePath(w, request.Target)
+	if !ok {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if request.Move {
+		if err := os.Rename(source, target); err == nil {
+			writeJSON(w, PlaceResponse{SchemaVersion: schema.AgentVersion, Method: "moved"})
+			return
+		}
+	}
+
+	// Link or copy under a temporary name, then replace the target at once
+	tmp := filepath.Join(filepath.Dir(target), fmt.Sprintf(".fileops-sync-%d", time.Now().UnixNano()))
+	method := "linked"
+	if err := os.Link(source, tmp); err != nil {
+		method = "copied"
+		if err := s.fs.Copy(source, tmp); err != nil && !errors.Is(err, filesystem.ErrMetadataNotPreserved) {
+			os.Remove(tmp)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if info, err := os.Stat(source); err == nil {
+			os.Chtimes(tmp, info.ModTime(), info.ModTime())
+		}
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// A source that cannot be removed stays, and the file counts as copied
+	if request.Move && method == "copied" && os.Remove(source) == nil {
+		method = "moved"
+	}
+	writeJSON(w, PlaceResponse{SchemaVersion: schema.AgentVersion, Method: method})
+}
+
+// writablePath checks that the agent accepts changes to path, answering the
+// request with an error when it does not
+func (s *Server) writablePath(w http.ResponseWriter, path string) (string, bool) {
+	if !s.writable {
+		http.Error(w, "agent is read-only (start it with --writable)", http.StatusForbidden)
+		return "", false
+	}
+	path, ok := s.exported(path)
+	if !ok {
+		http.Error(w, "path is not below an exported root", http.StatusForbidden)
+		return "", false
+	}
+	for _, root := range s.roots {
+		if path == root {
+			http.Error(w, "a root cannot be replaced", http.StatusForbidden)
+			return "", false
+		}
+	}
+	return path, true
+}
+
+// exported cleans path and reports whether it is at or below an exported
+// root, both as given and with its symbolic links resolved, so that a link
+// below a root cannot lead out of it
+func (s *Server) exported(path string) (string, bool) {
+	if path == "" || !filepath.IsAbs(path) {
+		return "", false
+	}
+	path = filepath.Clean(path)
+	real, err := resolve(path)
+	if err != nil {
+		return "", false
+	}
+	if !below(path, s.roots) || !below(real, s.resolved) {
+		return "", false
+	}
+	return path, true
+}
+
+// below reports whether path is one of roots or below one
+func below(path string, roots []string) bool {
+	for _, root := range roots {
+		if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve resolves the symbolic links of path. Of a path that does not exist
+// yet, those of its nearest existing parent are resolved.
+func resolve(path string) (string, error) {
+	var rest []string
+	for {
+		real, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(append([]string{real}, rest...)...), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", err
+		}
+		rest = append([]string{filepath.Base(path)}, rest...)
+		path = parent
+	}
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(SchemaHeader, strconv.Itoa(schema.AgentVersion))
+	json.NewEncoder(w).Encode(value)
+}
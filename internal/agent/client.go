@@ -0,0 +1,211 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/schema"
+)
+
+// Client talks to an agent
+type Client struct {
+	baseURL  string
+	token    string
+	http     *http.Client
+	implicit bool // No scheme was given
+}
+
+// NewClient creates a client for the agent at address ("host:port" or a URL)
+func NewClient(address, token string) *Client {
+	implicit := !strings.Contains(address, "://")
+	if implicit {
+		address = "http://" + address
+	}
+	return &Client{
+		baseURL:  strings.TrimSuffix(address, "/"),
+		token:    token,
+		http:     &http.Client{}, // Listing and hashing large trees takes long; contexts bound requests
+		implicit: implicit,
+	}
+}
+
+// SetTLS connects over TLS with config; addresses given without a scheme use
+// https from then on
+func (c *Client) SetTLS(config *tls.Config) {
+	c.http = &http.Client{Transport: &http.Transport{TLSClientConfig: config, Proxy: http.ProxyFromEnvironment}}
+	if c.implicit {
+		c.baseURL = "https://" + strings.TrimPrefix(c.baseURL, "http://")
+	}
+}
+
+// Address returns the URL of the agent
+func (c *Client) Address() string {
+	return c.baseURL
+}
+
+// Info returns the host name and exported roots of the agent
+func (c *Client) Info(ctx context.Context) (*Info, error) {
+	var info Info
+	if err := c.getJSON(ctx, "/v1/info", nil, &info); err != nil {
+		return nil, err
+	}
+	// Agents of older versions are understood; those of newer ones may
+	// answer in ways this coordinator misreads
+	if err := schema.Check(schema.Agent, info.SchemaVersion); err != nil {
+		return nil, fmt.Errorf("agent %s: %w, upgrade fileops here", c.baseURL, err)
+	}
+	return &info, nil
+}
+
+// List calls fn for every regular file below root
+func (c *Client) List(ctx context.Context, root string, fn func(Entry) error) error {
+	response, err := c.do(ctx, http.MethodGet, "/v1/list", url.Values{"root": {root}}, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	scanner := bufio.NewScanner(response.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("invalid listing from %s: %w", c.baseURL, err)
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Stat returns the size and modification time of a file
+func (c *Client) Stat(ctx context.Context, path string) (*Entry, error) {
+	var entry Entry
+	if err := c.getJSON(ctx, "/v1/stat", url.Values{"path": {path}}, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Hash returns the content hashes of paths, computed by the agent. Paths are
+// sent in batches; files that could not be hashed are returned in the errors.
+func (c *Client) Hash(ctx context.Context, algorithm string, paths []string) (*HashResponse, error) {
+	result := &HashResponse{Hashes: make(map[string]string), Errors: make(map[string]string)}
+	for start := 0; start < len(paths); start += maxHashBatch {
+		end := min(start+maxHashBatch, len(paths))
+		body, err := json.Marshal(HashRequest{Algorithm: algorithm, Paths: paths[start:end]})
+		if err != nil {
+			return nil, err
+		}
+		response, err := c.do(ctx, http.MethodPost, "/v1/hash", nil, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		var batch HashResponse
+		err = json.NewDecoder(response.Body).Decode(&batch)
+		response.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid hash response from %s: %w", c.baseURL, err)
+		}
+		for path, hash := range batch.Hashes {
+			result.Hashes[path] = hash
+		}
+		for path, message := range batch.Errors {
+			result.Errors[path] = message
+		}
+	}
+	return result, nil
+}
+
+// Read streams the content of a file; the caller closes the reader
+func (c *Client) Read(ctx context.Context, path string) (io.ReadCloser, error) {
+	response, err := c.do(ctx, http.MethodGet, "/v1/read", url.Values{"path": {path}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return response.Body, nil
+}
+
+// Write stores content at path on the agent. The agent verifies the content
+// against hash and keeps modTime.
+func (c *Client) Write(ctx context.Context, path string, content io.Reader, modTime time.Time, algorithm, hash string) error {
+	query := url.Values{
+		"path":      {path},
+		"mtime":     {modTime.Format(time.RFC3339Nano)},
+		"algorithm": {algorithm},
+		"hash":      {hash},
+	}
+	response, err := c.do(ctx, http.MethodPut, "/v1/write", query, content)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	return nil
+}
+
+// Place has the agent link, copy or move one of its files to target and
+// returns how it was placed
+func (c *Client) Place(ctx context.Context, source, target string, move bool) (string, error) {
+	body, err := json.Marshal(PlaceRequest{Source: source, Target: target, Move: move})
+	if err != nil {
+		return "", err
+	}
+	response, err := c.do(ctx, http.MethodPost, "/v1/place", nil, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	var placed PlaceResponse
+	if err := json.NewDecoder(response.Body).Decode(&placed); err != nil {
+		return "", fmt.Errorf("invalid place response from %s: %w", c.baseURL, err)
+	}
+	return placed.Method, nil
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, value interface{}) error {
+	response, err := c.do(ctx, http.MethodGet, path, query, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	return json.NewDecoder(response.Body).Decode(value)
+}
+
+// do sends a request and turns error statuses into errors
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body io.Reader) (*http.Response, error) {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	request, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if body != nil && method == http.MethodPost {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := c.http.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("agent %s unreachable: %w", c.baseURL, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		response.Body.Close()
+		return nil, fmt.Errorf("agent %s: %s: %s", c.baseURL, response.Status, strings.TrimSpace(string(message)))
+	}
+	return response, nil
+}
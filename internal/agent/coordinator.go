@@ -0,0 +1,247 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// Host is an agent taking part in a comparison
+type Host struct {
+	Label  string // Name used in reports, e.g. laptop or nas
+	Client *Client
+	Roots  []string // Roots to compare; all exported roots when empty
+}
+
+// Comparison is the outcome of comparing the inventories of several hosts
+type Comparison struct {
+	Hosts     []string                `json:"hosts"`
+	Files     map[string]int          `json:"files"` // Files listed per host
+	Bytes     map[string]int64        `json:"bytes"`
+	Groups    []domain.DuplicateGroup `json:"groups"`
+	Saveable  int64                   `json:"saveable"` // Bytes held more than once across hosts
+	Hashed    int                     `json:"hashed"`
+	HashFails map[string]string       `json:"hash_errors,omitempty"` // "label:path" -> error
+
+	mu sync.Mutex
+}
+
+// hostFile is one file of a host
+type hostFile struct {
+	host  int
+	entry Entry
+	hash  string
+}
+
+// Compare lists the files of every host and reports content that exists on
+// more than one of them. Only files whose size occurs on several hosts are
+// hashed, each by its own agent, so no content crosses the network.
+func Compare(ctx context.Context, hosts []Host, algorithm string) (*Comparison, error) {
+	if len(hosts) < 2 {
+		return nil, fmt.Errorf("at least two agents are required")
+	}
+	comparison := &Comparison{
+		Files:     make(map[string]int),
+		Bytes:     make(map[string]int64),
+		Groups:    make([]domain.DuplicateGroup, 0),
+		HashFails: make(map[string]string),
+	}
+
+	// List all hosts at once
+	listings := make([][]*hostFile, len(hosts))
+	errs := make([]error, len(hosts))
+	var wg sync.WaitGroup
+	for i := range hosts {
+		comparison.Hosts = append(comparison.Hosts, hosts[i].Label)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			listings[i], errs[i] = listHost(ctx, i, hosts[i])
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", hosts[i].Label, err)
+		}
+	}
+
+	// Only sizes found on two hosts or more can be cross-host duplicates
+	bySize := make(map[int64][]*hostFile)
+	for i, files := range listings {
+		for _, file := range files {
+			comparison.Files[hosts[i].Label]++
+			comparison.Bytes[hosts[i].Label] += file.entry.Size
+			if file.entry.Size > 0 {
+				bySize[file.entry.Size] = append(bySize[file.entry.Size], file)
+			}
+		}
+	}
+	toHash := make([][]*hostFile, len(hosts))
+	for _, files := range bySize {
+		if spansHosts(files) {
+			for _, file := range files {
+				toHash[file.host] = append(toHash[file.host], file)
+			}
+		}
+	}
+
+	// Every agent hashes its own candidates
+	for i := range hosts {
+		if len(toHash[i]) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = hashHost(ctx, hosts[i], toHash[i], algorithm, comparison)
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash files on %s: %w", hosts[i].Label, err)
+		}
+	}
+
+	byHash := make(map[string][]*hostFile)
+	for _, files := range toHash {
+		for _, file := range files {
+			if file.hash == "" {
+				continue
+			}
+			comparison.Hashed++
+			key := fmt.Sprintf("%d:%s", file.entry.Size, file.hash)
+			byHash[key] = append(byHash[key], file)
+		}
+	}
+
+	keys := make([]string, 0, len(byHash))
+	for key, files := range byHash {
+		if spansHosts(files) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		group := newGroup(len(comparison.Groups)+1, hosts, byHash[key], algorithm)
+		comparison.Saveable += group.SaveablSize
+		comparison.Groups = append(comparison.Groups, group)
+	}
+	sort.SliceStable(comparison.Groups, func(i, j int) bool {
+		return comparison.Groups[i].SaveablSize > comparison.Groups[j].SaveablSize
+	})
+	return comparison, nil
+}
+
+// listHost lists the regular files below the roots of host, counting
+// hardlinked files once
+func listHost(ctx context.Context, index int, host Host) ([]*hostFile, error) {
+	roots := host.Roots
+	if len(roots) == 0 {
+		info, err := host.Client.Info(ctx)
+		if err != nil {
+			return nil, err
+		}
+		roots = info.Roots
+	}
+
+	files := make([]*hostFile, 0)
+	seen := make(map[[2]uint64]bool)
+	visited := make(map[string]bool) // Overlapping roots list paths twice
+	for _, root := range roots {
+		err := host.Client.List(ctx, root, func(entry Entry) error {
+			if visited[entry.Path] {
+				return nil
+			}
+			visited[entry.Path] = true
+			if entry.Inode != 0 {
+				key := [2]uint64{entry.Device, entry.Inode}
+				if seen[key] {
+					return nil
+				}
+				seen[key] = true
+			}
+			files = append(files, &hostFile{host: index, entry: entry})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// hashHost has the agent of host hash files and records the files it could
+// not hash in comparison
+func hashHost(ctx context.Context, host Host, files []*hostFile, algorithm string, comparison *Comparison) error {
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file.entry.Path
+	}
+	response, err := host.Client.Hash(ctx, algorithm, paths)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		file.hash = response.Hashes[file.entry.Path]
+	}
+
+	comparison.mu.Lock()
+	defer comparison.mu.Unlock()
+	for path, message := range response.Errors {
+		comparison.HashFails[host.Label+":"+path] = message
+	}
+	return nil
+}
+
+// spansHosts reports whether files are on more than one host
+func spansHosts(files []*hostFile) bool {
+	for _, file := range files[1:] {
+		if file.host != files[0].host {
+			return true
+		}
+	}
+	return false
+}
+
+// newGroup describes identical files as a duplicate group labeled with the
+// hosts holding them. All copies but one could be saved.
+func newGroup(number int, hosts []Host, files []*hostFile, algorithm string) domain.DuplicateGroup {
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].host != files[j].host {
+			return files[i].host < files[j].host
+		}
+		return files[i].entry.Path < files[j].entry.Path
+	})
+	size := files[0].entry.Size
+	group := domain.DuplicateGroup{
+		ID:          fmt.Sprintf("group-%d", number),
+		Files:       make([]domain.FileInfo, 0, len(files)),
+		TotalSize:   size * int64(len(files)),
+		SaveablSize: size * int64(len(files)-1),
+		HashType:    algorithm,
+		Confidence:  filesystem.HashConfidence(algorithm),
+	}
+	for _, file := range files {
+		label := hosts[file.host].Label
+		group.Files = append(group.Files, domain.FileInfo{
+			Path:     file.entry.Path,
+			Name:     filepath.Base(file.entry.Path),
+			Size:     file.entry.Size,
+			ModTime:  file.entry.ModTime,
+			Hash:     file.hash,
+			HashType: algorithm,
+			Label:    label,
+		})
+		if len(group.Labels) == 0 || group.Labels[len(group.Labels)-1] != label {
+			group.Labels = append(group.Labels, label)
+		}
+	}
+	return group
+}
@@ -0,0 +1,394 @@
+package agent
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Agents announce themselves on the LAN with multicast DNS service discovery
+// (RFC 6762, 6763) as instances of this service
+const (
+	mdnsService = "_fileops._tcp.local."
+	mdnsGroup   = "224.0.0.251:5353"
+	mdnsTTL     = 120
+)
+
+// DNS record types and classes used by service discovery
+const (
+	dnsTypeA    = 1
+	dnsTypePTR  = 12
+	dnsTypeTXT  = 16
+	dnsTypeSRV  = 33
+	dnsTypeANY  = 255
+	dnsClassIN  = 1
+	dnsCacheBit = 0x8000 // Cache flush in answers, unicast response in questions
+)
+
+// Discovered is an agent found on the LAN
+type Discovered struct {
+	Name    string `json:"name"` // Instance name, the host name of the agent by default
+	Host    string `json:"host"`
+	Address string `json:"address"` // host:port to connect to
+	Version string `json:"version,omitempty"`
+}
+
+// Advertise answers service discovery queries for the agent listening on port
+// until ctx is cancelled. The instance is named after the host.
+func Advertise(ctx context.Context, port int) error {
+	group, err := net.ResolveUDPAddr("udp4", mdnsGroup)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return fmt.Errorf("failed to join the mDNS group: %w", err)
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	host, _ := os.Hostname()
+	host = strings.Split(host, ".")[0]
+	instance := host + "." + mdnsService
+
+	buffer := make([]byte, 9000)
+	for {
+		n, source, err := conn.ReadFromUDP(buffer)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		query, err := parseDNS(buffer[:n])
+		if err != nil || query.response || !query.asks(mdnsService) {
+			continue
+		}
+
+		var answer []byte
+		answer = appendRecord(answer, mdnsService, dnsTypePTR, dnsClassIN, encodeName(instance))
+		additional := appendRecord(nil, instance, dnsTypeSRV, dnsClassIN|dnsCacheBit, srvData(port, host+".local."))
+		additional = appendRecord(additional, instance, dnsTypeTXT, dnsClassIN|dnsCacheBit, txtData("version=1", "host="+host))
+		extra := 2
+		for _, ip := range localIPv4() {
+			additional = appendRecord(additional, host+".local.", dnsTypeA, dnsClassIN|dnsCacheBit, ip)
+			extra++
+		}
+
+		// Legacy queriers (any port but 5353) and those asking for it get a
+		// unicast reply that echoes the question (RFC 6762, 6.7)
+		var message []byte
+		target := group
+		if source.Port != 5353 || query.unicast {
+			target = source
+			message = dnsHeader(query.id, 1, 1, extra)
+			message = append(message, encodeName(mdnsService)...)
+			message = binary.BigEndian.AppendUint16(message, dnsTypePTR)
+			message = binary.BigEndian.AppendUint16(message, dnsClassIN)
+		} else {
+			message = dnsHeader(0, 0, 1, extra)
+		}
+		message = append(append(message, answer...), additional...)
+		conn.WriteToUDP(message, target)
+	}
+}
+
+// Discover asks the LAN for agents and collects the answers that arrive
+// within timeout
+func Discover(ctx context.Context, timeout time.Duration) ([]Discovered, error) {
+	group, err := net.ResolveUDPAddr("udp4", mdnsGroup)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	query := dnsHeader(0, 1, 0, 0)
+	query = append(query, encodeName(mdnsService)...)
+	query = binary.BigEndian.AppendUint16(query, dnsTypePTR)
+	query = binary.BigEndian.AppendUint16(query, dnsClassIN|dnsCacheBit)
+
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetReadDeadline(deadline)
+
+	// Ask twice: the first packet is easily lost while switches learn the group
+	if _, err := conn.WriteToUDP(query, group); err != nil {
+		return nil, fmt.Errorf("failed to send the mDNS query: %w", err)
+	}
+	resend := time.AfterFunc(timeout/3, func() { conn.WriteToUDP(query, group) })
+	defer resend.Stop()
+
+	found := make(map[string]*Discovered)
+	buffer := make([]byte, 9000)
+	for {
+		n, source, err := conn.ReadFromUDP(buffer)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				break
+			}
+			return nil, err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		message, err := parseDNS(buffer[:n])
+		if err != nil || !message.response {
+			continue
+		}
+		for _, record := range message.records {
+			instance, ok := strings.CutSuffix(strings.ToLower(record.name), "."+mdnsService)
+			if !ok {
+				continue
+			}
+			agent := found[instance]
+			if agent == nil {
+				agent = &Discovered{Name: instance}
+				found[instance] = agent
+			}
+			switch record.kind {
+			case dnsTypeSRV:
+				// The sender is reachable from here; the host names in the
+				// record need not resolve
+				agent.Address = net.JoinHostPort(source.IP.String(), fmt.Sprint(record.port))
+				if agent.Host == "" {
+					agent.Host = strings.TrimSuffix(record.target, ".local.")
+				}
+			case dnsTypeTXT:
+				for _, entry := range record.text {
+					key, value, _ := strings.Cut(entry, "=")
+					switch key {
+					case "version":
+						agent.Version = value
+					case "host":
+						agent.Host = value
+					}
+				}
+			}
+		}
+	}
+
+	agents := make([]Discovered, 0, len(found))
+	for _, agent := range found {
+		if agent.Address != "" {
+			agents = append(agents, *agent)
+		}
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].Name < agents[j].Name })
+	return agents, nil
+}
+
+// dnsMessage holds the parts of a DNS message service discovery needs
+type dnsMessage struct {
+	id        uint16
+	response  bool
+	unicast   bool // A question asked for a unicast response
+	questions []dnsQuestion
+	records   []dnsRecord
+}
+
+type dnsQuestion struct {
+	name string
+	kind uint16
+}
+
+type dnsRecord struct {
+	name   string
+	kind   uint16
+	port   uint16   // SRV
+	target string   // SRV, PTR
+	text   []string // TXT
+}
+
+// asks reports whether the message asks for instances of service
+func (m *dnsMessage) asks(service string) bool {
+	for _, question := range m.questions {
+		if strings.EqualFold(question.name, service) && (question.kind == dnsTypePTR || question.kind == dnsTypeANY) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDNS parses questions and resource records of a DNS message
+func parseDNS(data []byte) (*dnsMessage, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("message too short")
+	}
+	message := &dnsMessage{
+		id:       binary.BigEndian.Uint16(data),
+		response: data[2]&0x80 != 0,
+	}
+	questions := int(binary.BigEndian.Uint16(data[4:]))
+	records := int(binary.BigEndian.Uint16(data[6:])) + int(binary.BigEndian.Uint16(data[8:])) + int(binary.BigEndian.Uint16(data[10:]))
+
+	offset := 12
+	for range questions {
+		name, next, err := decodeName(data, offset)
+		if err != nil || next+4 > len(data) {
+			return nil, fmt.Errorf("invalid question")
+		}
+		class := binary.BigEndian.Uint16(data[next+2:])
+		message.unicast = message.unicast || class&dnsCacheBit != 0
+		message.questions = append(message.questions, dnsQuestion{name: name, kind: binary.BigEndian.Uint16(data[next:])})
+		offset = next + 4
+	}
+
+	for range records {
+		name, next, err := decodeName(data, offset)
+		if err != nil || next+10 > len(data) {
+			return nil, fmt.Errorf("invalid record")
+		}
+		record := dnsRecord{name: name, kind: binary.BigEndian.Uint16(data[next:])}
+		length := int(binary.BigEndian.Uint16(data[next+8:]))
+		start := next + 10
+		if start+length > len(data) {
+			return nil, fmt.Errorf("invalid record length")
+		}
+		rdata := data[start : start+length]
+		switch record.kind {
+		case dnsTypePTR:
+			record.target, _, _ = decodeName(data, start)
+		case dnsTypeSRV:
+			if length >= 6 {
+				record.port = binary.BigEndian.Uint16(rdata[4:])
+				record.target, _, _ = decodeName(data, start+6)
+			}
+		case dnsTypeTXT:
+			for i := 0; i < len(rdata); {
+				size := int(rdata[i])
+				if i+1+size > len(rdata) {
+					break
+				}
+				record.text = append(record.text, string(rdata[i+1:i+1+size]))
+				i += 1 + size
+			}
+		}
+		message.records = append(message.records, record)
+		offset = start + length
+	}
+	return message, nil
+}
+
+// decodeName reads a possibly compressed name at offset and returns it with
+// the offset following it
+func decodeName(data []byte, offset int) (string, int, error) {
+	var labels []string
+	next := -1
+	for jumps := 0; ; {
+		if offset >= len(data) {
+			return "", 0, fmt.Errorf("name out of bounds")
+		}
+		length := int(data[offset])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = offset + 1
+			}
+			return strings.Join(labels, ".") + ".", next, nil
+		case length&0xC0 == 0xC0:
+			if offset+1 >= len(data) || jumps > 16 {
+				return "", 0, fmt.Errorf("invalid name pointer")
+			}
+			if next < 0 {
+				next = offset + 2
+			}
+			offset = int(binary.BigEndian.Uint16(data[offset:]) & 0x3FFF)
+			jumps++
+		default:
+			if offset+1+length > len(data) {
+				return "", 0, fmt.Errorf("label out of bounds")
+			}
+			labels = append(labels, string(data[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+	}
+}
+
+// encodeName writes a name as uncompressed labels. The first label of an
+// instance name may contain dots of its own, so only the service part is split.
+func encodeName(name string) []byte {
+	var labels []string
+	if instance, ok := strings.CutSuffix(name, "."+mdnsService); ok {
+		labels = append([]string{instance}, strings.Split(strings.TrimSuffix(mdnsService, "."), ".")...)
+	} else {
+		labels = strings.Split(strings.TrimSuffix(name, "."), ".")
+	}
+	var out []byte
+	for _, label := range labels {
+		if len(label) > 63 {
+			label = label[:63]
+		}
+		out = append(out, byte(len(label)))
+		out = append(out, label...)
+	}
+	return append(out, 0)
+}
+
+func dnsHeader(id uint16, questions, answers, additional int) []byte {
+	header := binary.BigEndian.AppendUint16(nil, id)
+	flags := uint16(0)
+	if answers > 0 {
+		flags = 0x8400 // Authoritative response
+	}
+	header = binary.BigEndian.AppendUint16(header, flags)
+	header = binary.BigEndian.AppendUint16(header, uint16(questions))
+	header = binary.BigEndian.AppendUint16(header, uint16(answers))
+	header = binary.BigEndian.AppendUint16(header, 0)
+	return binary.BigEndian.AppendUint16(header, uint16(additional))
+}
+
+func appendRecord(out []byte, name string, kind, class uint16, rdata []byte) []byte {
+	out = append(out, encodeName(name)...)
+	out = binary.BigEndian.AppendUint16(out, kind)
+	out = binary.BigEndian.AppendUint16(out, class)
+	out = binary.BigEndian.AppendUint32(out, mdnsTTL)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(rdata)))
+	return append(out, rdata...)
+}
+
+func srvData(port int, target string) []byte {
+	data := []byte{0, 0, 0, 0} // Priority and weight
+	data = binary.BigEndian.AppendUint16(data, uint16(port))
+	return append(data, encodeName(target)...)
+}
+
+func txtData(entries ...string) []byte {
+	var data []byte
+	for _, entry := range entries {
+		data = append(data, byte(len(entry)))
+		data = append(data, entry...)
+	}
+	return data
+}
+
+// localIPv4 returns the IPv4 addresses of the interfaces that are up
+func localIPv4() [][]byte {
+	addresses, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	var ips [][]byte
+	for _, address := range addresses {
+		if network, ok := address.(*net.IPNet); ok && !network.IP.IsLoopback() {
+			if ip := network.IP.To4(); ip != nil {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	return ips
+}
@@ -0,0 +1,261 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Kinds of sync actions
+const (
+	ActionLink     = "link"     // Content is elsewhere on the destination and stays there
+	ActionMove     = "move"     // Content is in a file the source no longer has at that path
+	ActionTransfer = "transfer" // Content is not on the destination
+)
+
+// modTimeTolerance absorbs timestamp precision differences between filesystems
+const modTimeTolerance = time.Second
+
+// Endpoint is a directory on an agent
+type Endpoint struct {
+	Label  string
+	Client *Client
+	Root   string
+}
+
+// SyncAction brings one file of the source to the destination. Link and
+// move actions name a file on the destination as their source.
+type SyncAction struct {
+	Kind    string    `json:"kind"`
+	Source  string    `json:"source"`
+	Target  string    `json:"target"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Hash    string    `json:"hash"`
+	Method  string    `json:"method,omitempty"` // How the destination placed the file
+	Error   string    `json:"error,omitempty"`
+}
+
+// SyncPlan is what it takes to bring a destination up to date with a source
+type SyncPlan struct {
+	Source      string            `json:"source"`
+	Destination string            `json:"destination"`
+	Algorithm   string            `json:"algorithm"`
+	Unchanged   int               `json:"unchanged"`
+	Actions     []SyncAction      `json:"actions"`
+	Transfer    int64             `json:"transfer_bytes"` // Bytes sent over the network
+	Avoided     int64             `json:"avoided_bytes"`  // Bytes linked or moved instead
+	Failed      int               `json:"failed"`
+	HashFails   map[string]string `json:"hash_errors,omitempty"` // "label:path" -> error
+}
+
+// PlanSync compares the trees of source and destination and plans how to
+// bring every new or changed file to the destination. Both sides hash the
+// files in question themselves; content the destination already holds
+// anywhere below its root is linked there, or moved when the source no longer
+// has a file at its old path, instead of transferred.
+func PlanSync(ctx context.Context, source, destination Endpoint, algorithm string, moves bool) (*SyncPlan, error) {
+	plan := &SyncPlan{
+		Source:      source.Label + ":" + source.Root,
+		Destination: destination.Label + ":" + destination.Root,
+		Algorithm:   algorithm,
+		Actions:     make([]SyncAction, 0),
+		HashFails:   make(map[string]string),
+	}
+
+	sourceFiles, err := listRelative(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", source.Label, err)
+	}
+	destinationFiles, err := listRelative(ctx, destination)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", destination.Label, err)
+	}
+
+	// Files missing on the destination or differing in size or time
+	pending := make([]string, 0)
+	sizes := make(map[int64]bool)
+	for rel, entry := range sourceFiles {
+		existing, ok := destinationFiles[rel]
+		if ok && sameFile(existing, entry) {
+			plan.Unchanged++
+			continue
+		}
+		pending = append(pending, rel)
+		sizes[entry.Size] = true
+	}
+	sort.Strings(pending)
+	if len(pending) == 0 {
+		return plan, nil
+	}
+
+	// Destination files that are about to be replaced cannot serve as copies,
+	// but are hashed too: a linked file keeps the time of its other name
+	candidates := make([]string, 0)
+	for rel, entry := range destinationFiles {
+		if entry.Size == 0 || !sizes[entry.Size] {
+			continue
+		}
+		candidates = append(candidates, rel)
+	}
+
+	sourceHashes, err := hashRelative(ctx, source, pending, algorithm, plan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash files on %s: %w", source.Label, err)
+	}
+	destinationHashes, err := hashRelative(ctx, destination, candidates, algorithm, plan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash files on %s: %w", destination.Label, err)
+	}
+
+	held := make(map[string][]string)
+	for _, rel := range candidates {
+		if current, ok := sourceFiles[rel]; ok && !sameFile(current, destinationFiles[rel]) {
+			continue
+		}
+		if hash, ok := destinationHashes[rel]; ok {
+			key := fmt.Sprintf("%d:%s", destinationFiles[rel].Size, hash)
+			held[key] = append(held[key], rel)
+		}
+	}
+	for key := range held {
+		sort.Strings(held[key])
+	}
+
+	for _, rel := range pending {
+		entry := sourceFiles[rel]
+		hash, ok := sourceHashes[rel]
+		if !ok {
+			continue // Recorded in HashFails
+		}
+		if existing, ok := destinationHashes[rel]; ok && existing == hash && destinationFiles[rel].Size == entry.Size {
+			plan.Unchanged++
+			continue
+		}
+		action := SyncAction{
+			Kind:    ActionTransfer,
+			Source:  entry.Path,
+			Target:  filepath.Join(destination.Root, rel),
+			Size:    entry.Size,
+			ModTime: entry.ModTime,
+			Hash:    hash,
+		}
+
+		key := fmt.Sprintf("%d:%s", entry.Size, hash)
+		if copies := held[key]; entry.Size > 0 && len(copies) > 0 {
+			action.Kind = ActionLink
+			action.Source = filepath.Join(destination.Root, copies[0])
+			// A copy the source has no file for is moved once; later files
+			// with the same content link to where it went
+			if _, kept := sourceFiles[copies[0]]; moves && !kept {
+				action.Kind = ActionMove
+				held[key] = append([]string{rel}, copies[1:]...)
+			}
+			plan.Avoided += entry.Size
+		} else {
+			plan.Transfer += entry.Size
+		}
+		plan.Actions = append(plan.Actions, action)
+	}
+	return plan, nil
+}
+
+// Execute carries out the plan. Failed actions are recorded in the plan and
+// do not stop the others.
+func (p *SyncPlan) Execute(ctx context.Context, source, destination Endpoint) error {
+	info, err := destination.Client.Info(ctx)
+	if err != nil {
+		return err
+	}
+	if !info.Writable {
+		return fmt.Errorf("agent %s is read-only, start it with --writable to sync to it", destination.Label)
+	}
+	if info.Scope != ScopeOperate {
+		return fmt.Errorf("the credentials for agent %s grant read access only", destination.Label)
+	}
+
+	for i := range p.Actions {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		action := &p.Actions[i]
+		if action.Kind == ActionTransfer {
+			err = transfer(ctx, source, destination, action, p.Algorithm)
+			if err == nil {
+				action.Method = "transferred"
+			}
+		} else {
+			action.Method, err = destination.Client.Place(ctx, action.Source, action.Target, action.Kind == ActionMove)
+		}
+		if err != nil {
+			action.Error = err.Error()
+			p.Failed++
+		}
+	}
+	return nil
+}
+
+// transfer streams a file from the source agent to the destination agent
+func transfer(ctx context.Context, source, destination Endpoint, action *SyncAction, algorithm string) error {
+	content, err := source.Client.Read(ctx, action.Source)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+	return destination.Client.Write(ctx, action.Target, content, action.ModTime, algorithm, action.Hash)
+}
+
+// listRelative lists the files below the root of endpoint by their path
+// relative to the root
+func listRelative(ctx context.Context, endpoint Endpoint) (map[string]Entry, error) {
+	files := make(map[string]Entry)
+	err := endpoint.Client.List(ctx, endpoint.Root, func(entry Entry) error {
+		rel, err := filepath.Rel(endpoint.Root, entry.Path)
+		if err != nil {
+			return err
+		}
+		files[rel] = entry
+		return nil
+	})
+	return files, err
+}
+
+// hashRelative has the agent of endpoint hash files given relative to its
+// root, recording the files it could not hash in plan
+func hashRelative(ctx context.Context, endpoint Endpoint, rels []string, algorithm string, plan *SyncPlan) (map[string]string, error) {
+	hashes := make(map[string]string, len(rels))
+	if len(rels) == 0 {
+		return hashes, nil
+	}
+	paths := make([]string, len(rels))
+	for i, rel := range rels {
+		paths[i] = filepath.Join(endpoint.Root, rel)
+	}
+	response, err := endpoint.Client.Hash(ctx, algorithm, paths)
+	if err != nil {
+		return nil, err
+	}
+	for i, path := range paths {
+		if hash, ok := response.Hashes[path]; ok {
+			hashes[rels[i]] = hash
+		}
+	}
+	for path, message := range response.Errors {
+		plan.HashFails[endpoint.Label+":"+path] = message
+	}
+	return hashes, nil
+}
+
+// sameFile reports whether two entries agree in size and modification time
+func sameFile(a, b Entry) bool {
+	return a.Size == b.Size && absDuration(a.ModTime.Sub(b.ModTime)) <= modTimeTolerance
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,214 @@
+package agent
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Authorization scopes. Read covers listing, hashing and reading files;
+// operate also covers writing and placing them.
+const (
+	ScopeRead    = "read"
+	ScopeOperate = "operate"
+)
+
+// scopeUnitPrefix marks the organizational unit of a client certificate that
+// carries its scope
+const scopeUnitPrefix = "fileops-scope:"
+
+// Files written by GenerateCA
+const (
+	CAFile    = "ca.pem"
+	CAKeyFile = "ca-key.pem"
+)
+
+// LoadServerTLS loads the certificate of an agent. With clientCA, clients
+// must present a certificate signed by it (mutual TLS).
+func LoadServerTLS(certFile, keyFile, clientCA string) (*tls.Config, error) {
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the certificate: %w", err)
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12}
+	if clientCA != "" {
+		pool, err := loadPool(clientCA)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// LoadClientTLS trusts the agents signed by ca (or the system roots when ca
+// is empty) and presents the client certificate when one is given
+func LoadClientTLS(ca, certFile, keyFile string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if ca != "" {
+		pool, err := loadPool(ca)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+	if certFile != "" {
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	return config, nil
+}
+
+// GenerateCA creates a certificate authority in dir, or keeps the one that
+// is already there
+func GenerateCA(dir string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	if certificate, key, err := loadCA(dir); err == nil {
+		return certificate, key, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "fileops agents CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := createCertificate(template, template, key, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := writePEM(dir, CAFile, CAKeyFile, der, key); err != nil {
+		return nil, nil, err
+	}
+	certificate, err := x509.ParseCertificate(der)
+	return certificate, key, err
+}
+
+// GenerateCertificate creates name.pem and name-key.pem in dir, signed by
+// the certificate authority of dir. Server certificates are valid for hosts
+// (names or IP addresses); client certificates carry scope.
+func GenerateCertificate(dir, name string, hosts []string, client bool, scope string) (string, error) {
+	ca, caKey, err := GenerateCA(dir)
+	if err != nil {
+		return "", err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", err
+	}
+
+	template := &x509.Certificate{
+		Subject:   pkix.Name{CommonName: name},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter:  time.Now().AddDate(2, 0, 0),
+		KeyUsage:  x509.KeyUsageDigitalSignature,
+	}
+	if client {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+		template.Subject.OrganizationalUnit = []string{scopeUnitPrefix + scope}
+	} else {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		for _, host := range hosts {
+			if ip := net.ParseIP(host); ip != nil {
+				template.IPAddresses = append(template.IPAddresses, ip)
+			} else {
+				template.DNSNames = append(template.DNSNames, host)
+			}
+		}
+	}
+
+	der, err := createCertificate(template, ca, key, caKey)
+	if err != nil {
+		return "", err
+	}
+	if err := writePEM(dir, name+".pem", name+"-key.pem", der, key); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".pem"), nil
+}
+
+// certificateScope returns the scope a verified client certificate grants.
+// Certificates without a scope grant read access only.
+func certificateScope(certificate *x509.Certificate) string {
+	for _, unit := range certificate.Subject.OrganizationalUnit {
+		if unit == scopeUnitPrefix+ScopeOperate {
+			return ScopeOperate
+		}
+	}
+	return ScopeRead
+}
+
+func createCertificate(template, parent *x509.Certificate, key, parentKey *ecdsa.PrivateKey) ([]byte, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+	if err != nil {
+		return nil, err
+	}
+	template.SerialNumber = serial
+	return x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+}
+
+// writePEM writes a certificate and its private key, the key readable by
+// the owner only
+func writePEM(dir, certName, keyName string, der []byte, key *ecdsa.PrivateKey) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, keyName), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, certName), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644)
+}
+
+func loadCA(dir string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	pair, err := tls.LoadX509KeyPair(filepath.Join(dir, CAFile), filepath.Join(dir, CAKeyFile))
+	if err != nil {
+		if _, statErr := os.Stat(filepath.Join(dir, CAFile)); errors.Is(statErr, os.ErrNotExist) {
+			return nil, nil, statErr
+		}
+		return nil, nil, fmt.Errorf("failed to load the CA of %s: %w", dir, err)
+	}
+	key, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, nil, fmt.Errorf("the CA key of %s is not an ECDSA key", dir)
+	}
+	certificate, err := x509.ParseCertificate(pair.Certificate[0])
+	return certificate, key, err
+}
+
+func loadPool(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA %s: %w", file, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates in %s", file)
+	}
+	return pool, nil
+}
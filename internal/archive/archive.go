@@ -0,0 +1,220 @@
+// Package archive lists the entries of zip and tar archives without
+// extracting them. Compressed tars are read as a stream; archives inside
+// archives are listed too, up to a size that is read into memory.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ErrMalformed is returned for archives that cannot be read
+var ErrMalformed = errors.New("malformed archive")
+
+// Extensions are the file extensions of supported archives, longest first
+var Extensions = []string{
+	".tar.gz", ".tar.bz2", ".tar.zst", ".tgz", ".tbz2", ".tzst", ".tar", ".zip",
+}
+
+// Limits of listing archives inside archives
+const (
+	maxDepth      = 4
+	maxNestedSize = 64 * 1024 * 1024
+)
+
+// Entry is a file stored in an archive
+type Entry struct {
+	Archive string    // Archive file on disk
+	Name    string    // Path inside the archive; nested archives are separated by "/"
+	Size    int64     // Uncompressed size
+	ModTime time.Time // Modification time recorded in the archive
+	Depth   int       // 1 for entries of the archive itself, 2 inside an archive in it, ...
+}
+
+// Path names the entry as if it were a file below its archive, e.g.
+// /backup/photos.zip#2019/IMG_0001.jpg
+func (e *Entry) Path() string {
+	return e.Archive + "#" + e.Name
+}
+
+// Supported reports whether name is an archive by its extension
+func Supported(name string) bool {
+	return format(name) != ""
+}
+
+// format returns the supported extension name ends in, or ""
+func format(name string) string {
+	lower := strings.ToLower(name)
+	for _, ext := range Extensions {
+		if strings.HasSuffix(lower, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// Walk calls fn for every file in the archive at path. Readable archives
+// inside it of at most maxNestedSize are listed instead of being reported
+// themselves. Walk stops at the first error fn returns.
+func Walk(path string, fn func(*Entry) error) error {
+	w := &walker{archive: path, fn: fn}
+	if format(path) == ".zip" {
+		reader, err := zip.OpenReader(path)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		defer reader.Close()
+		return w.zip(&reader.Reader, "", 1)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return w.tar(file, format(path), "", 1)
+}
+
+// walker lists one archive on disk
+type walker struct {
+	archive string
+	fn      func(*Entry) error
+}
+
+// zip lists the files of a zip archive
+func (w *walker) zip(reader *zip.Reader, prefix string, depth int) error {
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		entry := &Entry{
+			Archive: w.archive,
+			Name:    prefix + file.Name,
+			Size:    int64(file.UncompressedSize64),
+			ModTime: file.Modified,
+			Depth:   depth,
+		}
+		if w.nested(entry) {
+			if rc, err := file.Open(); err == nil {
+				inner, ok := w.listNested(rc, entry)
+				rc.Close()
+				if ok {
+					if err := w.emit(inner); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+		}
+		if err := w.fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tar lists the regular files of a tar archive, compressed as ext says
+func (w *walker) tar(r io.Reader, ext, prefix string, depth int) error {
+	var err error
+	switch ext {
+	case ".tar.gz", ".tgz":
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(r); err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		defer gz.Close()
+		r = gz
+	case ".tar.bz2", ".tbz2":
+		r = bzip2.NewReader(r)
+	case ".tar.zst", ".tzst":
+		var zr *zstd.Decoder
+		if zr, err = zstd.NewReader(r); err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	reader := tar.NewReader(r)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		entry := &Entry{
+			Archive: w.archive,
+			Name:    prefix + strings.TrimPrefix(header.Name, "./"),
+			Size:    header.Size,
+			ModTime: header.ModTime,
+			Depth:   depth,
+		}
+		if w.nested(entry) {
+			if inner, ok := w.listNested(reader, entry); ok {
+				if err := w.emit(inner); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if err := w.fn(entry); err != nil {
+			return err
+		}
+	}
+}
+
+// nested reports whether entry is an archive to list
+func (w *walker) nested(entry *Entry) bool {
+	return entry.Depth < maxDepth && entry.Size <= maxNestedSize && Supported(path.Base(entry.Name))
+}
+
+// listNested lists the archive entry whose content r holds. When it cannot
+// be read completely ok is false and the entry counts as a file itself.
+func (w *walker) listNested(r io.Reader, entry *Entry) (entries []*Entry, ok bool) {
+	inner := &walker{archive: w.archive, fn: func(e *Entry) error {
+		entries = append(entries, e)
+		return nil
+	}}
+	prefix := entry.Name + "/"
+	ext := format(entry.Name)
+	if ext != ".zip" {
+		err := inner.tar(r, ext, prefix, entry.Depth+1)
+		return entries, err == nil
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, false
+	}
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, false
+	}
+	err = inner.zip(reader, prefix, entry.Depth+1)
+	return entries, err == nil
+}
+
+// emit passes entries to fn
+func (w *walker) emit(entries []*Entry) error {
+	for _, entry := range entries {
+		if err := w.fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
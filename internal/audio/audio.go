@@ -0,0 +1,154 @@
+// Package audio fingerprints recordings acoustically by handing them to
+// fpcalc, the Chromaprint command line tool, so that the same song encoded as
+// MP3, FLAC or at another bitrate can be recognized
+package audio
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrUnavailable is returned when fpcalc is not installed
+var ErrUnavailable = errors.New("fpcalc (Chromaprint) is required for audio fingerprints")
+
+// ErrInvalid is returned for fingerprints that were not made by Encode
+var ErrInvalid = errors.New("invalid audio fingerprint")
+
+// fingerprintLength is the number of seconds from the start fpcalc listens to
+const fingerprintLength = 120
+
+// maxOffset is the number of fingerprint items (about 0.12s each) two prints
+// are shifted against each other when comparing, for leading silence
+const maxOffset = 24
+
+// extensions are the audio formats fpcalc decodes
+var extensions = map[string]bool{
+	".mp3": true, ".flac": true, ".ogg": true, ".oga": true, ".opus": true, ".m4a": true,
+	".aac": true, ".wav": true, ".wma": true, ".aiff": true, ".aif": true, ".ape": true, ".wv": true,
+}
+
+// losslessExtensions are formats that keep the recording as it was mastered
+var losslessExtensions = map[string]bool{
+	".flac": true, ".wav": true, ".aiff": true, ".aif": true, ".ape": true, ".wv": true,
+}
+
+// IsAudio reports whether path is a recording fpcalc can fingerprint
+func IsAudio(path string) bool {
+	return extensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// Lossless reports whether path is in a lossless format
+func Lossless(path string) bool {
+	return losslessExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// Print is the acoustic fingerprint of a recording
+type Print struct {
+	Duration float64  // Playing time in seconds
+	Points   []uint32 // Chromaprint items, one per about 0.12s
+}
+
+// Fingerprinter runs fpcalc
+type Fingerprinter struct {
+	Path string
+}
+
+// New locates fpcalc on PATH
+func New() (*Fingerprinter, error) {
+	path, err := exec.LookPath("fpcalc")
+	if err != nil {
+		return nil, ErrUnavailable
+	}
+	return &Fingerprinter{Path: path}, nil
+}
+
+// Fingerprint computes the print of the first two minutes of a recording
+func (f *Fingerprinter) Fingerprint(ctx context.Context, path string) (*Print, error) {
+	cmd := exec.CommandContext(ctx, f.Path, "-raw", "-json", "-length", strconv.Itoa(fingerprintLength), path)
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("fpcalc %s: %s", path, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("fpcalc %s: %w", path, err)
+	}
+
+	var parsed struct {
+		Duration    float64  `json:"duration"`
+		Fingerprint []uint32 `json:"fingerprint"`
+	}
+	if err := json.Unmarshal(output, &parsed); err != nil {
+		return nil, fmt.Errorf("fpcalc %s: unexpected output: %w", path, err)
+	}
+	if len(parsed.Fingerprint) == 0 {
+		return nil, fmt.Errorf("fpcalc %s: no fingerprint, the recording may be too short", path)
+	}
+	return &Print{Duration: parsed.Duration, Points: parsed.Fingerprint}, nil
+}
+
+// Encode packs a print into bytes: the duration in milliseconds, then the points
+func (p *Print) Encode() []byte {
+	data := make([]byte, 0, 4+4*len(p.Points))
+	data = binary.BigEndian.AppendUint32(data, uint32(p.Duration*1000))
+	for _, point := range p.Points {
+		data = binary.BigEndian.AppendUint32(data, point)
+	}
+	return data
+}
+
+// Decode unpacks a print packed by Encode
+func Decode(data []byte) (*Print, error) {
+	if len(data) < 8 || len(data)%4 != 0 {
+		return nil, ErrInvalid
+	}
+	p := &Print{
+		Duration: float64(binary.BigEndian.Uint32(data)) / 1000,
+		Points:   make([]uint32, 0, len(data)/4-1),
+	}
+	for i := 4; i < len(data); i += 4 {
+		p.Points = append(p.Points, binary.BigEndian.Uint32(data[i:]))
+	}
+	return p, nil
+}
+
+// Distance is the share of differing bits of two prints at their best
+// alignment: about 0.05 for encodings of the same recording, about 0.5 for
+// unrelated ones. Recordings of clearly different length are 1 apart.
+func Distance(a, b *Print) float64 {
+	if longer, shorter := math.Max(a.Duration, b.Duration), math.Min(a.Duration, b.Duration); longer-shorter > math.Max(3, 0.05*longer) {
+		return 1
+	}
+
+	best := 1.0
+	for offset := -maxOffset; offset <= maxOffset; offset++ {
+		differing, compared := 0, 0
+		for i := max(0, -offset); i < len(a.Points) && i+offset < len(b.Points); i++ {
+			differing += bits.OnesCount32(a.Points[i] ^ b.Points[i+offset])
+			compared += 32
+		}
+		// Compare at least half of the shorter print
+		if compared == 0 || compared < 16*min(len(a.Points), len(b.Points)) {
+			continue
+		}
+		best = math.Min(best, float64(differing)/float64(compared))
+	}
+	return best
+}
+
+// Bitrate estimates the average bitrate in kbit/s of a recording of size bytes
+func Bitrate(size int64, duration float64) float64 {
+	if duration <= 0 {
+		return 0
+	}
+	return float64(size) * 8 / duration / 1000
+}
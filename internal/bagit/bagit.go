@@ -0,0 +1,238 @@
+// Package bagit reads and writes the tag files of BagIt bags (RFC 8493): a
+// directory holding the payload under data/, a manifest with the checksum of
+// every payload file and bag-info.txt with metadata for the receiver.
+package bagit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// Version is the BagIt version of written bags
+const Version = "1.0"
+
+// PayloadDir is the directory of a bag holding the payload
+const PayloadDir = "data"
+
+// Algorithms are the manifest algorithms read and written, strongest first
+var Algorithms = []string{"sha512", "sha256", "sha1", "md5"}
+
+// ErrInvalid is returned for bags whose tag files are missing or malformed
+var ErrInvalid = errors.New("invalid bag")
+
+// Field is one line of bag-info.txt. Labels may repeat.
+type Field struct {
+	Label string
+	Value string
+}
+
+// Manifest maps paths relative to the bag, e.g. data/photos/a.jpg, to their
+// checksum
+type Manifest map[string]string
+
+// Bag is the tag files of a bag
+type Bag struct {
+	Dir          string
+	Version      string
+	Info         []Field
+	Manifests    map[string]Manifest // Payload manifests by algorithm
+	TagManifests map[string]Manifest // Tag manifests by algorithm
+}
+
+// Get returns the first value of a bag-info label, ignoring case
+func (b *Bag) Get(label string) string {
+	for _, field := range b.Info {
+		if strings.EqualFold(field.Label, label) {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+// PayloadOxum returns the octet and file count bag-info.txt declares for
+// the payload, with ok false when it declares none
+func (b *Bag) PayloadOxum() (octets, files int64, ok bool, err error) {
+	value := b.Get("Payload-Oxum")
+	if value == "" {
+		return 0, 0, false, nil
+	}
+	o, f, found := strings.Cut(value, ".")
+	octets, errO := strconv.ParseInt(o, 10, 64)
+	files, errF := strconv.ParseInt(f, 10, 64)
+	if !found || errO != nil || errF != nil {
+		return 0, 0, false, fmt.Errorf("%w: malformed Payload-Oxum %q", ErrInvalid, value)
+	}
+	return octets, files, true, nil
+}
+
+// Open reads the tag files of the bag in dir
+func Open(dir string) (*Bag, error) {
+	bag := &Bag{Dir: dir, Manifests: make(map[string]Manifest), TagManifests: make(map[string]Manifest)}
+
+	declaration, err := readFields(filepath.Join(dir, "bagit.txt"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: no bagit.txt", ErrInvalid)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range declaration {
+		if field.Label == "BagIt-Version" {
+			bag.Version = field.Value
+		}
+	}
+	if bag.Version == "" {
+		return nil, fmt.Errorf("%w: bagit.txt declares no BagIt-Version", ErrInvalid)
+	}
+
+	if bag.Info, err = readFields(filepath.Join(dir, "bag-info.txt")); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	for _, algorithm := range Algorithms {
+		manifest, err := readManifest(filepath.Join(dir, "manifest-"+algorithm+".txt"))
+		if err == nil {
+			bag.Manifests[algorithm] = manifest
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+		tagManifest, err := readManifest(filepath.Join(dir, "tagmanifest-"+algorithm+".txt"))
+		if err == nil {
+			bag.TagManifests[algorithm] = tagManifest
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	if len(bag.Manifests) == 0 {
+		return nil, fmt.Errorf("%w: no payload manifest of a supported algorithm (%s)", ErrInvalid, strings.Join(Algorithms, ", "))
+	}
+	return bag, nil
+}
+
+// Write writes the tag files of a bag whose payload is in place below
+// dir/data: bagit.txt, bag-info.txt, the payload manifest and a tag manifest
+// covering the other three. hash returns the checksum of a file.
+func Write(dir, algorithm string, manifest Manifest, info []Field, hash func(path string) (string, error)) error {
+	files := map[string]string{
+		"bagit.txt":                      formatFields([]Field{{"BagIt-Version", Version}, {"Tag-File-Character-Encoding", "UTF-8"}}),
+		"bag-info.txt":                   formatFields(info),
+		"manifest-" + algorithm + ".txt": formatManifest(manifest),
+	}
+	tagManifest := make(Manifest, len(files))
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := filesystem.WriteFileAtomic(path, []byte(content), 0o644); err != nil {
+			return err
+		}
+		sum, err := hash(path)
+		if err != nil {
+			return err
+		}
+		tagManifest[name] = sum
+	}
+	return filesystem.WriteFileAtomic(filepath.Join(dir, "tagmanifest-"+algorithm+".txt"), []byte(formatManifest(tagManifest)), 0o644)
+}
+
+// formatFields formats tag file fields, one "Label: Value" line each
+func formatFields(fields []Field) string {
+	var b strings.Builder
+	for _, field := range fields {
+		b.WriteString(field.Label + ": " + strings.ReplaceAll(field.Value, "\n", " ") + "\n")
+	}
+	return b.String()
+}
+
+// formatManifest formats a manifest sorted by path
+func formatManifest(manifest Manifest) string {
+	paths := make([]string, 0, len(manifest))
+	for path := range manifest {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var b strings.Builder
+	for _, path := range paths {
+		b.WriteString(manifest[path] + "  " + EncodePath(path) + "\n")
+	}
+	return b.String()
+}
+
+// readFields reads a tag file of "Label: Value" lines. Lines starting with
+// whitespace continue the value of the line before.
+func readFields(path string) ([]Field, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fields := make([]Field, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if (text[0] == ' ' || text[0] == '\t') && len(fields) > 0 {
+			fields[len(fields)-1].Value += " " + strings.TrimSpace(text)
+			continue
+		}
+		label, value, found := strings.Cut(text, ":")
+		if !found {
+			return nil, fmt.Errorf("%w: %s line %d is not a \"Label: Value\" field", ErrInvalid, filepath.Base(path), line)
+		}
+		fields = append(fields, Field{Label: strings.TrimSpace(label), Value: strings.TrimSpace(value)})
+	}
+	return fields, scanner.Err()
+}
+
+// readManifest reads a manifest of "checksum path" lines
+func readManifest(path string) (Manifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	manifest := make(Manifest)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		sum, name, found := strings.Cut(text, " ")
+		name = strings.TrimLeft(name, " *")
+		if !found || sum == "" || name == "" {
+			return nil, fmt.Errorf("%w: %s line %d is not a \"checksum path\" entry", ErrInvalid, filepath.Base(path), line)
+		}
+		name = filepath.ToSlash(DecodePath(name))
+		if strings.HasPrefix(name, "/") || name == ".." || strings.HasPrefix(name, "../") || strings.Contains(name, "/../") {
+			return nil, fmt.Errorf("%w: %s line %d points outside the bag", ErrInvalid, filepath.Base(path), line)
+		}
+		manifest[strings.TrimPrefix(name, "./")] = strings.ToLower(sum)
+	}
+	return manifest, scanner.Err()
+}
+
+// EncodePath percent-encodes the characters a manifest path cannot hold
+func EncodePath(path string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(path)
+}
+
+// DecodePath undoes EncodePath
+func DecodePath(path string) string {
+	return strings.NewReplacer("%0D", "\r", "%0d", "\r", "%0A", "\n", "%0a", "\n", "%25", "%").Replace(path)
+}
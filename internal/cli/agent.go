@@ -0,0 +1,705 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/agent"
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// agentTokenVariable is the environment variable holding the agent token
+const agentTokenVariable = "FILEOPS_AGENT_TOKEN"
+
+// NewAgentCommand creates the agent command
+func NewAgentCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	agentCmd := &cobra.Command{
+		Use:     "agent --listen [address] --root [path...]",
+		Aliases: []string{"agents"},
+		Short:   "Serve file listings and hashes to other machines",
+		Long: `Run a lightweight agent that lets fileops on other machines list, stat and
+hash the files below the given roots. Hashes are computed where the files
+are. Nothing outside the roots is served, not even through symbolic links
+below them. With --writable the agent also accepts files, so that other
+machines can sync into the roots; that requires a token or client
+certificates.
+
+With --token or the FILEOPS_AGENT_TOKEN environment variable every request
+must carry that token; --read-token adds a token that can list, hash and read
+but not write. With --tls-cert the agent speaks HTTPS, and with --tls-ca it
+also requires client certificates signed by that CA (mutual TLS). Client
+certificates carry their scope, read or operate. "fileops agent gen-cert"
+creates a CA and certificates. Without any of them the agent refuses to
+start unless it listens on a loopback address such as 127.0.0.1:7447, or
+--insecure lets anyone who can reach it list, hash and read the roots:
+
+  FILEOPS_AGENT_TOKEN=secret fileops agent --listen :7447 --root /mnt/photos
+  fileops agent gen-cert nas --host nas.local
+  fileops agent gen-cert laptop --client --scope operate
+  fileops agent --root /mnt/photos --tls-cert ~/.fileops/tls/nas.pem \
+    --tls-key ~/.fileops/tls/nas-key.pem --tls-ca ~/.fileops/tls/ca.pem
+
+Clients use the same flags: --tls-ca to trust the agents and --tls-cert and
+--tls-key to present their certificate.
+
+"fileops agent compare" then finds content that exists on several machines,
+"fileops agent sync" copies a tree from one agent to another. With
+--advertise the agent can be found by "fileops agent discover".`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			address, _ := cmd.Flags().GetString("listen")
+			roots, _ := cmd.Flags().GetStringSlice("root")
+			writable, _ := cmd.Flags().GetBool("writable")
+			insecure, _ := cmd.Flags().GetBool("insecure")
+			advertise, _ := cmd.Flags().GetBool("advertise")
+			readToken, _ := cmd.Flags().GetString("read-token")
+			certFile, _ := cmd.Flags().GetString("tls-cert")
+			keyFile, _ := cmd.Flags().GetString("tls-key")
+			clientCA, _ := cmd.Flags().GetString("tls-ca")
+			token := agentToken(cmd)
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			if len(roots) == 0 {
+				return fmt.Errorf("--root is required")
+			}
+			if clientCA != "" && certFile == "" {
+				return fmt.Errorf("--tls-ca requires --tls-cert and --tls-key")
+			}
+			if writable && token == "" && clientCA == "" {
+				return fmt.Errorf("--writable requires a token (--token or $%s) or client certificates (--tls-ca)", agentTokenVariable)
+			}
+			if token == "" && readToken == "" && clientCA == "" {
+				if !insecure && !agent.IsLoopback(address) {
+					return fmt.Errorf("an agent on %s requires a token (--token or $%s), --read-token or client certificates (--tls-ca); listen on a loopback address or pass --insecure to serve without", address, agentTokenVariable)
+				}
+				if insecure {
+					log.Warn("Agent runs without credentials, anyone who can reach it can list, hash and read the files of the roots")
+				}
+			}
+			if token != "" && token == readToken {
+				return fmt.Errorf("--token and --read-token must differ")
+			}
+
+			server, err := agent.NewServer(roots, token, ParseSize(cfg.Performance.ChunkSize, 64*1024*1024))
+			if err != nil {
+				return err
+			}
+			server.SetWritable(writable)
+			server.SetInsecure(insecure)
+			server.SetReadToken(readToken)
+			if certFile != "" {
+				tlsConfig, err := agent.LoadServerTLS(certFile, keyFile, clientCA)
+				if err != nil {
+					return err
+				}
+				server.SetTLS(tlsConfig)
+			}
+
+			if advertise {
+				_, port, err := net.SplitHostPort(address)
+				if err != nil {
+					return fmt.Errorf("invalid listen address %s: %w", address, err)
+				}
+				portNumber, err := strconv.Atoi(port)
+				if err != nil {
+					return fmt.Errorf("invalid listen port %s", port)
+				}
+				go func() {
+					if err := agent.Advertise(ctx, portNumber); err != nil {
+						log.Warn("Agent cannot be discovered on the LAN", "error", err)
+					}
+				}()
+			}
+
+			if !quiet {
+				ui.Printf("🛰️  Agent listening on %s\n", address)
+				ui.Printf("📂 Roots: %v\n", roots)
+				if certFile != "" {
+					ui.Printf("🔒 TLS enabled (client certificates required: %t)\n", clientCA != "")
+				}
+				if writable {
+					ui.Printf("✏️  Writable: other machines can sync into the roots\n")
+				}
+				if advertise {
+					ui.Printf("📣 Advertised on the LAN via mDNS\n")
+				}
+				ui.Printf("Press Ctrl+C to stop\n")
+			}
+			log.Info("🛰️ Starting agent", "address", address, "roots", roots, "writable", writable)
+
+			return server.ListenAndServe(ctx, address)
+		},
+	}
+
+	agentCmd.Flags().String("listen", agent.DefaultAddress, "Address to listen on")
+	agentCmd.Flags().StringSlice("root", nil, "Directory to serve (repeatable)")
+	agentCmd.Flags().Bool("writable", false, "Accept files and changes below the roots from syncs (requires a token or --tls-ca)")
+	agentCmd.Flags().Bool("insecure", false, "Serve other machines without a token or client certificates")
+	agentCmd.Flags().Bool("advertise", false, "Announce the agent on the LAN via mDNS")
+	agentCmd.Flags().String("read-token", "", "Additional token granting read access only")
+	agentCmd.PersistentFlags().String("token", "", "Shared secret of the agents (default $"+agentTokenVariable+")")
+	agentCmd.PersistentFlags().String("tls-cert", "", "Certificate to serve with, or to present to agents")
+	agentCmd.PersistentFlags().String("tls-key", "", "Private key of --tls-cert")
+	agentCmd.PersistentFlags().String("tls-ca", "", "CA that signs the client certificates, or the agents")
+
+	agentCmd.AddCommand(newAgentCompareCommand(ctx, cfg, log))
+	agentCmd.AddCommand(newAgentSyncCommand(ctx, cfg, log))
+	agentCmd.AddCommand(newAgentDiscoverCommand(ctx, cfg, log))
+	agentCmd.AddCommand(newAgentGenCertCommand(log))
+
+	return agentCmd
+}
+
+// newAgentCompareCommand creates the agent compare subcommand
+func newAgentCompareCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "compare [label=]address...",
+		Short: "Find files that exist on several machines",
+		Long: `Compare the files served by two or more agents and report content that exists
+on more than one machine. Only files whose size occurs on several machines
+are hashed, each by its own agent, so no file content crosses the network.
+
+Agents are named by their label, or by their host name when unlabeled:
+
+  fileops agent compare laptop=192.168.1.10:7447 nas=nas.local:7447
+  fileops agent compare laptop nas           # remotes from the configuration
+  fileops agent compare nas=nas.local:7447 backup=backup:7447 --path nas=/volume1/photos
+
+Piped, the output is the duplicates alone as tab-separated values:
+
+  fileops agent compare laptop nas --columns size,paths > shared.tsv`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			algorithm, _ := cmd.Flags().GetString("algorithm")
+			outputFormat, _ := cmd.Flags().GetString("output")
+			paths, _ := cmd.Flags().GetStringSlice("path")
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			switch outputFormat {
+			case "table", "json":
+			default:
+				return fmt.Errorf("unknown output format %q (use table or json)", outputFormat)
+			}
+
+			hosts := make([]agent.Host, 0, len(args))
+			labels := make(map[string]bool)
+			for _, arg := range args {
+				label, address := ParseRootLabel(arg)
+				if name, remote, ok := resolveRemote(cfg, address); ok {
+					address = remote
+					if label == "" {
+						label = name
+					}
+				}
+				client, err := newAgentClient(cmd, address)
+				if err != nil {
+					return err
+				}
+				if label == "" {
+					info, err := client.Info(ctx)
+					if err != nil {
+						return err
+					}
+					label = info.Host
+				}
+				if labels[label] {
+					return fmt.Errorf("label %q is used twice, label the agents (label=address)", label)
+				}
+				labels[label] = true
+				hosts = append(hosts, agent.Host{Label: label, Client: client})
+			}
+			for _, path := range paths {
+				label, root, found := strings.Cut(path, "=")
+				if !found || !labels[label] {
+					return fmt.Errorf("invalid --path %q, expected label=path of one of the agents", path)
+				}
+				for i := range hosts {
+					if hosts[i].Label == label {
+						hosts[i].Roots = append(hosts[i].Roots, root)
+					}
+				}
+			}
+
+			table := outputFormat == "table"
+			if table && !quiet && stdoutIsTerminal() {
+				ui.Printf("🛰️  Comparing %d agents...\n", len(hosts))
+			}
+			log.Info("🛰️ Comparing agents", "agents", args, "algorithm", algorithm)
+
+			comparison, err := agent.Compare(ctx, hosts, algorithm)
+			if err != nil {
+				return err
+			}
+
+			if !table {
+				encoder := json.NewEncoder(ui.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(comparison)
+			}
+			options := tableFlags(cmd)
+			if options.TSV {
+				return comparisonTable(comparison).render(ui.Stdout, options)
+			}
+			if !quiet {
+				return displayComparison(comparison, options)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String("algorithm", cfg.Operations.HashAlgorithm, "Hash algorithm the agents use")
+	cmd.Flags().StringSlice("path", nil, "Compare only this directory of an agent, as label=path (repeatable)")
+	cmd.Flags().String("output", "table", "Output format (table, json)")
+	addTableFlags(cmd, comparisonColumns...)
+
+	return cmd
+}
+
+// newAgentSyncCommand creates the agent sync subcommand
+func newAgentSyncCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync [label=]address/path [label=]address/path",
+		Short: "Copy a tree from one agent to another, reusing content already there",
+		Long: `Bring a directory on the destination agent up to date with a directory on the
+source agent. New and changed files are hashed by both agents first; content
+the destination already holds anywhere below its directory is hardlinked (or
+copied) there by the destination itself, or moved when the source no longer
+has a file at its old path. Only content missing on the destination is
+transferred. Files are never deleted from the destination.
+
+The destination agent must run with --writable:
+
+  fileops agent sync laptop=192.168.1.10:7447/home/me/Photos nas=nas.local:7447/volume1/photos --dry-run
+  fileops agent sync laptop:7447/home/me/Photos nas.local:7447/volume1/photos --no-move
+  fileops agent sync laptop/home/me/Photos nas/volume1/photos   # configured remotes`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			algorithm, _ := cmd.Flags().GetString("algorithm")
+			outputFormat, _ := cmd.Flags().GetString("output")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			noMove, _ := cmd.Flags().GetBool("no-move")
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			switch outputFormat {
+			case "table", "json":
+			default:
+				return fmt.Errorf("unknown output format %q (use table or json)", outputFormat)
+			}
+
+			source, err := parseAgentEndpoint(ctx, cmd, cfg, args[0])
+			if err != nil {
+				return err
+			}
+			destination, err := parseAgentEndpoint(ctx, cmd, cfg, args[1])
+			if err != nil {
+				return err
+			}
+
+			table := outputFormat == "table"
+			if table && !quiet {
+				ui.Printf("🛰️  Planning sync from %s:%s to %s:%s...\n", source.Label, source.Root, destination.Label, destination.Root)
+				if dryRun {
+					ui.Printf("📋 DRY RUN MODE: No files will be changed\n")
+				}
+			}
+			log.Info("🛰️ Syncing between agents", "source", args[0], "destination", args[1], "dry_run", dryRun)
+
+			plan, err := agent.PlanSync(ctx, source, destination, algorithm, !noMove)
+			if err != nil {
+				return err
+			}
+			if !dryRun {
+				if err := plan.Execute(ctx, source, destination); err != nil {
+					return err
+				}
+			}
+
+			if !table {
+				encoder := json.NewEncoder(ui.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(plan); err != nil {
+					return err
+				}
+			} else if !quiet {
+				displayAgentSync(plan, dryRun)
+			}
+			if plan.Failed > 0 {
+				return fmt.Errorf("%d of %d files could not be synced", plan.Failed, len(plan.Actions))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String("algorithm", cfg.Operations.HashAlgorithm, "Hash algorithm the agents use")
+	cmd.Flags().Bool("dry-run", false, "Show the plan without changing the destination")
+	cmd.Flags().Bool("no-move", false, "Link or copy content already on the destination instead of moving it")
+	cmd.Flags().String("output", "table", "Output format (table, json)")
+
+	return cmd
+}
+
+// parseAgentEndpoint parses "[label=]address/path", e.g.
+// "nas=nas.local:7447/volume1/photos" or "nas/volume1/photos" for a configured
+// remote, into an endpoint
+func parseAgentEndpoint(ctx context.Context, cmd *cobra.Command, cfg *config.Config, arg string) (agent.Endpoint, error) {
+	label, target := ParseRootLabel(arg)
+	scheme := ""
+	if before, after, found := strings.Cut(target, "://"); found {
+		scheme, target = before+"://", after
+	}
+	slash := strings.Index(target, "/")
+	if slash <= 0 {
+		return agent.Endpoint{}, fmt.Errorf("invalid agent path %q, expected address/path such as nas.local:7447/volume1/photos", arg)
+	}
+
+	address := scheme + target[:slash]
+	if name, remote, ok := resolveRemote(cfg, address); ok {
+		address = remote
+		if label == "" {
+			label = name
+		}
+	}
+	client, err := newAgentClient(cmd, address)
+	if err != nil {
+		return agent.Endpoint{}, err
+	}
+	if label == "" {
+		info, err := client.Info(ctx)
+		if err != nil {
+			return agent.Endpoint{}, err
+		}
+		label = info.Host
+	}
+	return agent.Endpoint{Label: label, Client: client, Root: filepath.Clean(target[slash:])}, nil
+}
+
+// displayAgentSync prints what a sync did or, on a dry run, would do
+func displayAgentSync(plan *agent.SyncPlan, dryRun bool) {
+	counts := make(map[string]int)
+	for _, action := range plan.Actions {
+		counts[action.Kind]++
+	}
+
+	for i, action := range plan.Actions {
+		if i == 0 {
+			ui.Printf("\n📋 Files to bring to %s:\n", plan.Destination)
+		}
+		if i >= 20 {
+			ui.Printf("  ... and %d more files\n", len(plan.Actions)-20)
+			break
+		}
+		switch action.Kind {
+		case agent.ActionTransfer:
+			ui.Printf("  %-9s %s (%s)\n", action.Kind, action.Target, FormatBytes(action.Size))
+		default:
+			ui.Printf("  %-9s %s ← %s\n", action.Kind, action.Target, action.Source)
+		}
+	}
+	for _, action := range plan.Actions {
+		if action.Error != "" {
+			ui.Printf("  ❌ %s: %s\n", action.Target, action.Error)
+		}
+	}
+
+	verb := "Synced"
+	if dryRun {
+		verb = "Would sync"
+	}
+	ui.Printf("\n📊 %s %d files (%d linked, %d moved, %d transferred), %d unchanged\n",
+		verb, len(plan.Actions)-plan.Failed, counts[agent.ActionLink], counts[agent.ActionMove], counts[agent.ActionTransfer], plan.Unchanged)
+	ui.Printf("🌐 %s over the network, %s found on the destination instead\n", FormatBytes(plan.Transfer), FormatBytes(plan.Avoided))
+	if len(plan.HashFails) > 0 {
+		ui.Printf("⚠️  %d files could not be hashed and were skipped\n", len(plan.HashFails))
+	}
+}
+
+// newAgentDiscoverCommand creates the agent discover subcommand
+func newAgentDiscoverCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "discover",
+		Short: "Find agents on the LAN and store them as named remotes",
+		Long: `Find agents started with --advertise on the local network via multicast DNS
+(Zeroconf/Bonjour). With --save the agents are stored as named remotes in the
+configuration file, so that "fileops agent compare" and "fileops agent sync"
+accept their names in place of addresses:
+
+  fileops agents discover
+  fileops agents discover --save
+  fileops agent compare laptop nas`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+			save, _ := cmd.Flags().GetBool("save")
+			outputFormat, _ := cmd.Flags().GetString("output")
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			switch outputFormat {
+			case "table", "json":
+			default:
+				return fmt.Errorf("unknown output format %q (use table or json)", outputFormat)
+			}
+
+			table := outputFormat == "table"
+			if table && !quiet {
+				ui.Printf("📡 Looking for agents for %s...\n", timeout)
+			}
+			log.Info("📡 Discovering agents", "timeout", timeout)
+
+			agents, err := agent.Discover(ctx, timeout)
+			if err != nil {
+				return err
+			}
+
+			if !table {
+				encoder := json.NewEncoder(ui.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(agents); err != nil {
+					return err
+				}
+			} else if !quiet {
+				if len(agents) == 0 {
+					ui.Printf("\nNo agents answered. Agents must run with --advertise on this network.\n")
+				} else {
+					ui.Printf("\n  %-20s %-24s %s\n", "NAME", "ADDRESS", "HOST")
+					for _, found := range agents {
+						ui.Printf("  %-20s %-24s %s\n", found.Name, found.Address, found.Host)
+					}
+				}
+			}
+
+			if !save || len(agents) == 0 {
+				return nil
+			}
+			path, err := config.File()
+			if err != nil {
+				return fmt.Errorf("failed to locate the configuration file: %w", err)
+			}
+			values := make(map[string]interface{}, len(agents))
+			for _, found := range agents {
+				values["remotes."+remoteName(found.Name)] = found.Address
+			}
+			if err := config.Save(path, values); err != nil {
+				return fmt.Errorf("failed to write %s: %w", path, err)
+			}
+			if table && !quiet {
+				ui.Printf("\n📝 %d remotes written to %s\n", len(values), path)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Duration("timeout", 3*time.Second, "How long to wait for answers")
+	cmd.Flags().Bool("save", false, "Store the agents found as named remotes in the configuration file")
+	cmd.Flags().String("output", "table", "Output format (table, json)")
+
+	return cmd
+}
+
+// resolveRemote looks address up among the configured remotes and returns
+// the name and address of the remote it names
+func resolveRemote(cfg *config.Config, address string) (string, string, bool) {
+	name := strings.ToLower(address)
+	if remote, ok := cfg.Remotes[name]; ok && remote != "" {
+		return name, remote, true
+	}
+	return "", "", false
+}
+
+// remoteName turns an instance name into a configuration key
+func remoteName(instance string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		}
+		return '-'
+	}, instance)
+	return strings.Trim(name, "-")
+}
+
+// newAgentGenCertCommand creates the agent gen-cert subcommand
+func newAgentGenCertCommand(log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gen-cert [name]",
+		Short: "Create TLS certificates for agents and their clients",
+		Long: `Create a certificate signed by a private certificate authority, creating the
+authority first when the directory holds none. Agent certificates are valid
+for the host names and addresses given with --host; client certificates
+(--client) carry a scope: read lets the client list, hash and read files,
+operate also lets it sync into writable agents.
+
+  fileops agent gen-cert nas --host nas.local --host 192.168.1.20
+  fileops agent gen-cert laptop --client --scope operate
+  fileops agent gen-cert monitoring --client --scope read
+
+Copy ca.pem to every machine, and each certificate with its key to the
+machine it names.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, _ := cmd.Flags().GetString("dir")
+			hosts, _ := cmd.Flags().GetStringSlice("host")
+			client, _ := cmd.Flags().GetBool("client")
+			scope, _ := cmd.Flags().GetString("scope")
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			name := "agent"
+			if client {
+				name = "client"
+			}
+			if len(args) > 0 {
+				name = args[0]
+			}
+			if strings.ContainsAny(name, `/\`) || name == "ca" {
+				return fmt.Errorf("invalid certificate name %q", name)
+			}
+			switch scope {
+			case agent.ScopeRead, agent.ScopeOperate:
+			default:
+				return fmt.Errorf("unknown scope %q (use read or operate)", scope)
+			}
+			if client && len(hosts) > 0 {
+				return fmt.Errorf("--host applies to agent certificates only")
+			}
+			if !client && len(hosts) == 0 {
+				host, err := os.Hostname()
+				if err != nil {
+					return fmt.Errorf("--host is required: %w", err)
+				}
+				hosts = []string{host, "localhost", "127.0.0.1"}
+			}
+			if dir == "" {
+				home, err := os.UserHomeDir()
+				if err != nil {
+					return err
+				}
+				dir = filepath.Join(home, ".fileops", "tls")
+			}
+
+			log.Info("🔑 Generating certificate", "name", name, "dir", dir, "client", client, "scope", scope)
+			path, err := agent.GenerateCertificate(dir, name, hosts, client, scope)
+			if err != nil {
+				return fmt.Errorf("failed to generate the certificate: %w", err)
+			}
+
+			if !quiet {
+				ui.Printf("🔑 Certificate: %s\n", path)
+				ui.Printf("🔐 Private key: %s\n", filepath.Join(dir, name+"-key.pem"))
+				ui.Printf("🏛️  CA: %s\n", filepath.Join(dir, agent.CAFile))
+				if client {
+					ui.Printf("🎫 Scope: %s\n", scope)
+				} else {
+					ui.Printf("🌐 Hosts: %s\n", strings.Join(hosts, ", "))
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String("dir", "", "Directory of the CA and certificates (default ~/.fileops/tls)")
+	cmd.Flags().StringSlice("host", nil, "Host name or address the agent is reached by (repeatable)")
+	cmd.Flags().Bool("client", false, "Create a client certificate instead of an agent certificate")
+	cmd.Flags().String("scope", agent.ScopeRead, "Scope of a client certificate (read, operate)")
+
+	return cmd
+}
+
+// newAgentClient creates a client for address with the token and TLS
+// settings of cmd
+func newAgentClient(cmd *cobra.Command, address string) (*agent.Client, error) {
+	client := agent.NewClient(address, agentToken(cmd))
+	ca, _ := cmd.Flags().GetString("tls-ca")
+	certFile, _ := cmd.Flags().GetString("tls-cert")
+	keyFile, _ := cmd.Flags().GetString("tls-key")
+	if ca != "" || certFile != "" {
+		tlsConfig, err := agent.LoadClientTLS(ca, certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		client.SetTLS(tlsConfig)
+	}
+	return client, nil
+}
+
+// agentToken returns the token from --token or the environment
+func agentToken(cmd *cobra.Command) string {
+	if token, _ := cmd.Flags().GetString("token"); token != "" {
+		return token
+	}
+	return os.Getenv(agentTokenVariable)
+}
+
+// comparisonColumns are the columns of the duplicates found across agents
+var comparisonColumns = []tableColumn{
+	textColumn("name"),
+	sizeColumn("size"),
+	textColumn("hosts"),
+	pathColumn("paths"),
+}
+
+// comparisonTable lists the duplicates found across agents, one group per
+// row with its copies as label:path
+func comparisonTable(comparison *agent.Comparison) *table {
+	groups := newTable(comparisonColumns...)
+	for _, group := range comparison.Groups {
+		paths := make([]string, len(group.Files))
+		for i, file := range group.Files {
+			paths[i] = file.Label + ":" + file.Path
+		}
+		groups.addRow(group.Files[0].Name, group.Files[0].Size, group.Labels, paths)
+	}
+	return groups
+}
+
+// displayComparison prints the duplicates found across agents; options
+// shape the table of duplicates
+func displayComparison(comparison *agent.Comparison, options tableOptions) error {
+	ui.Printf("\n🖥️  Agents:\n")
+	for _, host := range comparison.Hosts {
+		ui.Printf("  %-16s %10d files %12s\n", host, comparison.Files[host], FormatBytes(comparison.Bytes[host]))
+	}
+
+	// How much each combination of hosts shares
+	shared := make(map[string]int64)
+	for _, group := range comparison.Groups {
+		shared[strings.Join(group.Labels, " + ")] += group.Files[0].Size
+	}
+	if len(shared) > 0 {
+		combinations := make([]string, 0, len(shared))
+		for combination := range shared {
+			combinations = append(combinations, combination)
+		}
+		sort.Slice(combinations, func(i, j int) bool { return shared[combinations[i]] > shared[combinations[j]] })
+		ui.Printf("\n🔗 Content on several machines:\n")
+		for _, combination := range combinations {
+			ui.Printf("  %-32s %12s\n", combination, FormatBytes(shared[combination]))
+		}
+	}
+
+	if len(comparison.Groups) > 0 {
+		ui.Printf("\n📍 Largest cross-machine duplicates:\n")
+		options.Limit = 20
+		if err := comparisonTable(comparison).render(ui.Stdout, options); err != nil {
+			return err
+		}
+	}
+
+	ui.Printf("\n📊 %d duplicate groups across machines, %s held more than once, %d files hashed\n",
+		len(comparison.Groups), FormatBytes(comparison.Saveable), comparison.Hashed)
+	if len(comparison.HashFails) > 0 {
+		ui.Printf("⚠️  %d files could not be hashed\n", len(comparison.HashFails))
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// NewBagCommand creates the bag command
+func NewBagCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	bagCmd := &cobra.Command{
+		Use:   "bag",
+		Short: "Export files as BagIt bags and validate bags",
+		Long: `Package files in the BagIt format (RFC 8493) for hand-off to archives,
+libraries or backup services, and validate bags received or kept in cold
+storage.
+
+A bag is a directory holding the files under data/, a manifest with the
+checksum of every file (manifest-sha256.txt), bag-info.txt with metadata such
+as the source organization and the Payload-Oxum (total size and file count),
+and a tag manifest covering those files. Bags written here can be read by
+other BagIt tools, and bags from other tools can be validated.`,
+	}
+
+	// Add subcommands
+	bagCmd.AddCommand(
+		newBagExportCommand(ctx, cfg, log),
+		newBagValidateCommand(ctx, cfg, log),
+	)
+
+	return bagCmd
+}
+
+// newBagExportCommand creates the bag export subcommand
+func newBagExportCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export [path...] --dest bag",
+		Short: "Copy files into a new bag",
+		Long: `Copy the files below the paths (those matching --pattern, when given) into a
+new bag at --dest, which must not exist or be empty. Each path is stored under
+data/ by its name, e.g. ~/Photos/2019/a.jpg as data/Photos/2019/a.jpg.
+
+Every file is hashed before and after copying; a copy that does not match its
+source is removed and reported. --info adds bag-info.txt fields; Bagging-Date,
+Payload-Oxum and Bag-Software-Agent are added automatically:
+
+  fileops bag export ~/Photos/2019 ~/Documents/Letters --dest /mnt/transfer/family-2019 \
+    --info Source-Organization="Smith family" --info External-Description="Photos and letters 2019"`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get flags
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			destination, _ := cmd.Flags().GetString("dest")
+			algorithm, _ := cmd.Flags().GetString("algorithm")
+			patterns, _ := cmd.Flags().GetStringSlice("pattern")
+			info, _ := cmd.Flags().GetStringArray("info")
+			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+
+			if destination == "" {
+				return fmt.Errorf("--dest is required")
+			}
+			absDestination, err := filepath.Abs(destination)
+			if err != nil {
+				return fmt.Errorf("invalid destination %s: %w", destination, err)
+			}
+			validPaths, err := absolutePaths(args)
+			if err != nil {
+				return err
+			}
+
+			// Create operation configuration
+			config := domain.OperationConfig{
+				DryRun:          dryRun,
+				Recursive:       true,
+				ExcludePatterns: excludePatterns,
+				TargetPaths:     validPaths,
+				IncludePatterns: patterns,
+				CustomSettings: map[string]interface{}{
+					"destination": absDestination,
+					"algorithm":   algorithm,
+					"info":        info,
+				},
+			}
+
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+			if !quiet {
+				ui.Printf("📦 Exporting a bag...\n")
+				if dryRun {
+					ui.Printf("📋 DRY RUN MODE: No files will be written\n")
+				}
+				ui.Printf("📂 Paths to export: %v\n", validPaths)
+				if len(patterns) > 0 {
+					ui.Printf("🔎 Files: %s\n", strings.Join(patterns, ", "))
+				}
+				ui.Printf("🎯 Bag: %s (manifest-%s.txt)\n\n", absDestination, algorithm)
+			}
+
+			result, err := runBagOperation(ctx, cmd, cfg, log, domain.OperationBagExport, config, "Export")
+			if err != nil || result == nil || quiet {
+				return err
+			}
+
+			if size, _ := result.Details["size"].(int64); size > 0 {
+				ui.Printf("💾 Payload: %s\n", FormatBytes(size))
+			}
+			heading := "📄 Exported files"
+			if dryRun {
+				heading = "📄 Files that would be exported"
+			}
+			listFiles(result, "exported", heading)
+
+			DisplayOperationWarnings(result)
+			DisplayOperationErrors(result)
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	cmd.Flags().Bool("dry-run", false, "List the files that would be exported without writing the bag")
+	cmd.Flags().String("dest", "", "Directory of the new bag (required)")
+	cmd.Flags().String("algorithm", "sha256", "Manifest checksum algorithm (sha256, sha512, sha1, md5)")
+	cmd.Flags().StringSlice("pattern", []string{}, "Only export files whose name matches these patterns (e.g. *.tif)")
+	cmd.Flags().StringArray("info", []string{}, "bag-info.txt field as Label=Value (repeatable)")
+	cmd.Flags().StringSlice("exclude", []string{".git", "node_modules", ".DS_Store"}, "Patterns to exclude")
+
+	return cmd
+}
+
+// newBagValidateCommand creates the bag validate subcommand
+func newBagValidateCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate [bag...]",
+		Short: "Check bags against their manifests",
+		Long: `Check that each bag is complete and unchanged: every file its manifests list
+exists with the listed checksum, every file under data/ is listed, the tag
+files match the tag manifest and the declared Payload-Oxum matches the
+payload. Bags with a problem are reported invalid and make the command fail.
+
+--fast checks completeness and the Payload-Oxum only, without reading the
+payload:
+
+  fileops bag validate /mnt/transfer/family-2019`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get flags
+			fast, _ := cmd.Flags().GetBool("fast")
+
+			validPaths, err := absolutePaths(args)
+			if err != nil {
+				return err
+			}
+
+			// Create operation configuration
+			config := domain.OperationConfig{
+				DryRun:      true,
+				Recursive:   true,
+				TargetPaths: validPaths,
+				CustomSettings: map[string]interface{}{
+					"fast": fast,
+				},
+			}
+
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+			if !quiet {
+				ui.Printf("🔍 Validating bags...\n")
+				ui.Printf("📂 Bags: %v\n\n", validPaths)
+			}
+
+			result, err := runBagOperation(ctx, cmd, cfg, log, domain.OperationBagValidate, config, "Validation")
+			if err != nil || result == nil {
+				return err
+			}
+
+			if !quiet {
+				listFiles(result, "valid", "✅ Valid bags")
+				listFiles(result, "invalid", "❌ Invalid bags")
+				listFiles(result, "missing", "🕳️  Missing files")
+				listFiles(result, "unlisted", "❓ Files not in a manifest")
+				listFiles(result, "mismatched", "⚠️  Files with a different checksum")
+
+				DisplayOperationWarnings(result)
+				DisplayOperationErrors(result)
+			}
+			if invalid, _ := result.Details["invalid"].(domain.PathList); invalid.Count > 0 {
+				return fmt.Errorf("%d invalid bags", invalid.Count)
+			}
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	cmd.Flags().Bool("fast", false, "Only check completeness and the Payload-Oxum, without verifying checksums")
+
+	return cmd
+}
+
+// runBagOperation executes an export or validation with progress display and
+// prints the common part of its result
+func runBagOperation(ctx context.Context, cmd *cobra.Command, cfg *config.Config, log *logger.Logger, operationType domain.OperationType, config domain.OperationConfig, label string) (*domain.OperationResult, error) {
+	if err := applyGlobalFlags(cmd, &config); err != nil {
+		return nil, err
+	}
+
+	// Create engine
+	tracker := progress.NewTracker()
+	operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+	if err != nil {
+		return nil, err
+	}
+
+	quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+	log.Info("📦 Starting "+string(operationType),
+		"paths", config.TargetPaths,
+		"dry_run", config.DryRun)
+
+	// Estimate large exports and ask before writing anything. A declined run
+	// returns no result.
+	if operationType == domain.OperationBagExport && !confirmLargeOperation(cmd, cfg, operationEngine, operationType, config) {
+		ui.Printf("Nothing was changed\n")
+		return nil, nil
+	}
+
+	// Pre-generate operation ID for progress monitoring
+	operationID := engine.NewOperationID(operationType)
+
+	// Start progress monitoring in a separate goroutine BEFORE starting operation
+	progressCtx, progressCancel := context.WithCancel(ctx)
+	defer progressCancel()
+
+	var progressWg sync.WaitGroup
+	if !quiet && cfg.Operations.EnableProgressBar {
+		progressWg.Add(1)
+		go func() {
+			defer progressWg.Done()
+			MonitorProgress(progressCtx, tracker, operationID, string(operationType))
+		}()
+		// Give the monitor a moment to start
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// Execute operation with predefined ID so progress monitoring works
+	result, err := operationEngine.ExecuteOperationWithID(ctx, operationType, config, operationID)
+
+	// Stop progress monitoring
+	progressCancel()
+	progressWg.Wait()
+
+	if err != nil {
+		if !quiet {
+			ui.Printf("\n❌ %s failed: %v\n", label, err)
+			displayStoredResultHint(cfg, operationID)
+		}
+		return nil, fmt.Errorf("%s failed: %w", string(operationType), err)
+	}
+
+	if !quiet {
+		ui.Printf("\n\n✅ %s completed!\n", label)
+		if result.Summary != "" {
+			ui.Printf("📊 %s\n", result.Summary)
+		}
+		ui.Printf("⏱️  Total time: %v\n", result.Duration.Round(time.Millisecond))
+	}
+
+	log.Info("✅ "+label+" completed", "summary", result.Summary)
+	return result, nil
+}
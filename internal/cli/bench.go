@@ -0,0 +1,436 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+	"github.com/spf13/cobra"
+)
+
+// Read patterns measured by the bench command
+var (
+	benchChunkSizes   = []int64{64 * 1024, 256 * 1024, 1024 * 1024, 4 * 1024 * 1024, 16 * 1024 * 1024, 64 * 1024 * 1024}
+	benchParallelism  = []int{1, 2, 4, 8, 16, 32}
+	benchRandomBlock  = int64(64 * 1024)
+	benchRandomPeriod = time.Second
+)
+
+// Hash algorithms the configuration accepts, strongest first
+var benchHashPreference = []string{"blake2b", "sha256", "xxhash64"}
+
+// BenchReport contains the measurements and recommendations of the bench command
+type BenchReport struct {
+	CPUs           int                 `json:"cpus"`
+	Storage        []*StorageBenchmark `json:"storage"`
+	Hashers        []HasherBenchmark   `json:"hashers"`
+	Recommendation BenchRecommendation `json:"recommendation"`
+}
+
+// StorageBenchmark holds the measured throughput of the storage below a path
+type StorageBenchmark struct {
+	Path                string          `json:"path"`
+	FileSize            int64           `json:"file_size"`
+	CacheDropped        bool            `json:"cache_dropped"`
+	WriteBytesPerSecond float64         `json:"write_bytes_per_second"`
+	Sequential          []ReadBenchmark `json:"sequential"`
+	Random              []ReadBenchmark `json:"random"`
+}
+
+// ReadBenchmark holds the read throughput of one access pattern
+type ReadBenchmark struct {
+	ChunkSize      int64   `json:"chunk_size"`
+	Parallelism    int     `json:"parallelism"`
+	BytesPerSecond float64 `json:"bytes_per_second"`
+}
+
+// BenchRecommendation holds the settings suggested by the measurements
+type BenchRecommendation struct {
+	ChunkSize     string `json:"chunk_size"`
+	MaxWorkers    int    `json:"max_workers"`
+	HashAlgorithm string `json:"hash_algorithm"`
+}
+
+// NewBenchCommand creates the bench command
+func NewBenchCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bench [path...]",
+		Short: "Measure storage and hashing throughput and recommend settings",
+		Long: `Measure how fast the storage below each path (default: current directory) and
+this machine's hash algorithms are, and recommend settings for them.
+
+A scratch file is written next to each path and read back sequentially with
+several chunk sizes and at random offsets with an increasing number of
+parallel readers. Where the platform allows it, cached pages are evicted before
+every read so the device itself is measured. The recommendations are:
+
+  performance.chunk_size     smallest chunk size reaching full sequential speed
+  performance.max_workers    parallel readers after which random reads stop scaling
+  operations.hash_algorithm  strongest algorithm that keeps up with the storage
+
+With --write-config they are stored in the configuration file in use
+(~/.fileops/config.yaml when there is none).
+
+The throughput of every device measured is kept in performance.bench_profile
+(~/.fileops/bench.json), from which dry runs estimate how long the I/O of
+the real run takes.`,
+		Example: `  # Measure the archive drive
+  fileops bench /mnt/archive
+
+  # Measure and store the recommended settings
+  fileops bench /mnt/archive --write-config`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			outputFormat, _ := cmd.Flags().GetString("output")
+			sizeStr, _ := cmd.Flags().GetString("size")
+			writeConfig, _ := cmd.Flags().GetBool("write-config")
+
+			size := ParseSize(sizeStr, 256*1024*1024)
+			if size < benchRandomBlock {
+				return fmt.Errorf("--size must be at least %s", FormatBytes(benchRandomBlock))
+			}
+			if len(args) == 0 {
+				args = []string{"."}
+			}
+
+			report := &BenchReport{CPUs: runtime.NumCPU()}
+			for _, path := range args {
+				absPath, err := filepath.Abs(path)
+				if err != nil {
+					return fmt.Errorf("invalid path %s: %w", path, err)
+				}
+				if outputFormat != "json" {
+					ui.Printf("⏱️  Measuring %s with a %s scratch file...\n", absPath, FormatBytes(size))
+				}
+				storage, err := benchmarkStorage(ctx, absPath, size)
+				if err != nil {
+					return fmt.Errorf("failed to measure %s: %w", absPath, err)
+				}
+				report.Storage = append(report.Storage, storage)
+			}
+			report.Hashers = benchmarkHashers(ctx, 32*1024*1024)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			report.Recommendation = recommendSettings(report)
+
+			log.Debug("Benchmark finished", "paths", args)
+
+			if outputFormat == "json" {
+				encoder := json.NewEncoder(ui.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(report); err != nil {
+					return err
+				}
+			} else {
+				displayBenchReport(report)
+			}
+
+			if cfg.Performance.BenchProfile != "" {
+				if err := recordBenchProfile(cfg.Performance.BenchProfile, report); err != nil {
+					log.Warn("Throughput not recorded for the estimates of dry runs", "error", err)
+				} else if outputFormat != "json" {
+					ui.Printf("\n📝 Throughput recorded in %s for the estimates of dry runs\n", cfg.Performance.BenchProfile)
+				}
+			}
+
+			if !writeConfig {
+				return nil
+			}
+			path, err := config.File()
+			if err != nil {
+				return fmt.Errorf("failed to locate the configuration file: %w", err)
+			}
+			err = config.Save(path, map[string]interface{}{
+				"performance.chunk_size":    report.Recommendation.ChunkSize,
+				"performance.max_workers":   report.Recommendation.MaxWorkers,
+				"operations.hash_algorithm": report.Recommendation.HashAlgorithm,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to write %s: %w", path, err)
+			}
+			if outputFormat != "json" {
+				ui.Printf("\n📝 Recommendations written to %s\n", path)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String("output", "table", "Output format (table, json)")
+	cmd.Flags().String("size", "256MB", "Size of the scratch file written to each path")
+	cmd.Flags().Bool("write-config", false, "Store the recommended settings in the configuration file")
+
+	return cmd
+}
+
+// recordBenchProfile stores the throughput of the measured devices in the
+// profile at path, keeping that of the other devices
+func recordBenchProfile(path string, report *BenchReport) error {
+	profile, err := engine.LoadBenchProfile(path)
+	if err != nil {
+		return err
+	}
+	for _, storage := range report.Storage {
+		device, err := filesystem.DeviceID(storage.Path)
+		if err != nil {
+			return err
+		}
+		measured := engine.DeviceThroughput{
+			Path:                storage.Path,
+			Device:              device,
+			WriteBytesPerSecond: storage.WriteBytesPerSecond,
+			Measured:            time.Now(),
+		}
+		for _, read := range storage.Sequential {
+			measured.ReadBytesPerSecond = max(measured.ReadBytesPerSecond, read.BytesPerSecond)
+		}
+		profile.Record(measured)
+	}
+	return profile.Save(path)
+}
+
+// benchmarkStorage writes a scratch file of size bytes into dir and measures
+// reading it back sequentially and at random offsets
+func benchmarkStorage(ctx context.Context, dir string, size int64) (*StorageBenchmark, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+
+	file, err := os.CreateTemp(dir, ".fileops-bench-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	result := &StorageBenchmark{Path: dir, FileSize: size}
+
+	// Random content, so compressing or deduplicating filesystems store all of it
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	buffer := make([]byte, 4*1024*1024)
+	start := time.Now()
+	for written := int64(0); written < size; {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		n := int64(len(buffer))
+		if size-written < n {
+			n = size - written
+		}
+		_, _ = rng.Read(buffer[:n])
+		if _, err := file.Write(buffer[:n]); err != nil {
+			return nil, err
+		}
+		written += n
+	}
+	if err := file.Sync(); err != nil {
+		return nil, err
+	}
+	result.WriteBytesPerSecond = float64(size) / time.Since(start).Seconds()
+	result.CacheDropped = filesystem.DropCachedPages(file) == nil
+
+	for _, chunkSize := range benchChunkSizes {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if chunkSize > size && chunkSize != benchChunkSizes[0] {
+			break
+		}
+		_ = filesystem.DropCachedPages(file)
+		speed, err := readSequential(file, chunkSize)
+		if err != nil {
+			return nil, err
+		}
+		result.Sequential = append(result.Sequential, ReadBenchmark{ChunkSize: chunkSize, Parallelism: 1, BytesPerSecond: speed})
+	}
+
+	for _, parallelism := range benchParallelism {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		_ = filesystem.DropCachedPages(file)
+		speed, err := readRandom(ctx, file, size, parallelism)
+		if err != nil {
+			return nil, err
+		}
+		result.Random = append(result.Random, ReadBenchmark{ChunkSize: benchRandomBlock, Parallelism: parallelism, BytesPerSecond: speed})
+	}
+
+	return result, nil
+}
+
+// readSequential reads file from start to end in chunks and returns the throughput
+func readSequential(file *os.File, chunkSize int64) (float64, error) {
+	buffer := make([]byte, chunkSize)
+	var total int64
+	start := time.Now()
+	for offset := int64(0); ; {
+		n, err := file.ReadAt(buffer, offset)
+		total += int64(n)
+		offset += int64(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return float64(total) / time.Since(start).Seconds(), nil
+}
+
+// readRandom reads blocks at random offsets of file with parallel readers for
+// benchRandomPeriod and returns the combined throughput
+func readRandom(ctx context.Context, file *os.File, size int64, parallelism int) (float64, error) {
+	blocks := size / benchRandomBlock
+	deadline := time.Now().Add(benchRandomPeriod)
+
+	var total atomic.Int64
+	var firstErr error
+	var errOnce sync.Once
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			buffer := make([]byte, benchRandomBlock)
+			for time.Now().Before(deadline) && ctx.Err() == nil {
+				n, err := file.ReadAt(buffer, rng.Int63n(blocks)*benchRandomBlock)
+				if err != nil && err != io.EOF {
+					errOnce.Do(func() { firstErr = err })
+					return
+				}
+				total.Add(int64(n))
+			}
+		}(start.UnixNano() + int64(i))
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return 0, firstErr
+	}
+	return float64(total.Load()) / time.Since(start).Seconds(), nil
+}
+
+// recommendSettings derives configuration settings from the measurements
+func recommendSettings(report *BenchReport) BenchRecommendation {
+	var chunkSize int64
+	workers := 1
+	var storageSpeed float64
+	for _, storage := range report.Storage {
+		// The smallest chunk size within 5% of the fastest sequential read
+		best := 0.0
+		for _, read := range storage.Sequential {
+			if read.BytesPerSecond > best {
+				best = read.BytesPerSecond
+			}
+		}
+		for _, read := range storage.Sequential {
+			if read.BytesPerSecond >= best*0.95 {
+				if read.ChunkSize > chunkSize {
+					chunkSize = read.ChunkSize
+				}
+				break
+			}
+		}
+		storageSpeed = max(storageSpeed, best)
+
+		// The fewest readers within 10% of the fastest random reads
+		best = 0
+		for _, read := range storage.Random {
+			best = max(best, read.BytesPerSecond)
+		}
+		for _, read := range storage.Random {
+			if read.BytesPerSecond >= best*0.9 {
+				workers = max(workers, read.Parallelism)
+				break
+			}
+		}
+	}
+
+	// Hashing is spread over the workers, but not over more cores than there are
+	speeds := make(map[string]float64, len(report.Hashers))
+	for _, hasher := range report.Hashers {
+		speeds[hasher.Algorithm] = hasher.BytesPerSecond * float64(min(workers, report.CPUs))
+	}
+	algorithm := benchHashPreference[len(benchHashPreference)-1]
+	for _, candidate := range benchHashPreference {
+		if speeds[candidate] >= storageSpeed {
+			algorithm = candidate
+			break
+		}
+	}
+
+	return BenchRecommendation{
+		ChunkSize:     formatSizeSetting(chunkSize),
+		MaxWorkers:    workers,
+		HashAlgorithm: algorithm,
+	}
+}
+
+// formatSizeSetting writes a size the way ParseSize reads it, e.g. 4MB
+func formatSizeSetting(bytes int64) string {
+	switch {
+	case bytes >= 1024*1024*1024 && bytes%(1024*1024*1024) == 0:
+		return fmt.Sprintf("%dGB", bytes/(1024*1024*1024))
+	case bytes >= 1024*1024 && bytes%(1024*1024) == 0:
+		return fmt.Sprintf("%dMB", bytes/(1024*1024))
+	case bytes >= 1024 && bytes%1024 == 0:
+		return fmt.Sprintf("%dKB", bytes/1024)
+	default:
+		return fmt.Sprintf("%dB", bytes)
+	}
+}
+
+// displayBenchReport prints the benchmark report in human-readable form
+func displayBenchReport(report *BenchReport) {
+	for _, storage := range report.Storage {
+		ui.Printf("\n📁 %s\n", storage.Path)
+		if !storage.CacheDropped {
+			ui.Printf("  ⚠️  Cached pages could not be evicted, reads may be faster than the device\n")
+		}
+		ui.Printf("  Write:       %s/sec\n", FormatBytes(int64(storage.WriteBytesPerSecond)))
+		ui.Printf("  Sequential read:\n")
+		for _, read := range storage.Sequential {
+			ui.Printf("    %-12s %s/sec\n", formatSizeSetting(read.ChunkSize)+" chunks", FormatBytes(int64(read.BytesPerSecond)))
+		}
+		ui.Printf("  Random %s reads:\n", formatSizeSetting(benchRandomBlock))
+		for _, read := range storage.Random {
+			ui.Printf("    %2d readers   %s/sec\n", read.Parallelism, FormatBytes(int64(read.BytesPerSecond)))
+		}
+	}
+
+	if len(report.Hashers) > 0 {
+		ui.Printf("\n🔢 Hash throughput (one core):\n")
+		for _, hasher := range report.Hashers {
+			if hasher.Error != "" {
+				ui.Printf("  %-10s ❌ %s\n", hasher.Algorithm, hasher.Error)
+				continue
+			}
+			ui.Printf("  %-10s %s/sec\n", hasher.Algorithm, FormatBytes(int64(hasher.BytesPerSecond)))
+		}
+	}
+
+	recommendation := report.Recommendation
+	ui.Printf("\n💡 Recommended settings:\n")
+	ui.Printf("  performance.chunk_size:    %s\n", recommendation.ChunkSize)
+	ui.Printf("  performance.max_workers:   %d\n", recommendation.MaxWorkers)
+	ui.Printf("  operations.hash_algorithm: %s\n", recommendation.HashAlgorithm)
+}
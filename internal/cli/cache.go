@@ -0,0 +1,194 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// NewCacheCommand creates the cache command
+func NewCacheCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Maintain the checksums fileops has recorded",
+		Long: `Maintain the checksums recorded by "fileops checksum" in sidecars
+(<name>.sha256) and per-directory manifests (SHA256SUMS).`,
+	}
+
+	// Add subcommands
+	cacheCmd.AddCommand(
+		newCacheRehashCommand(ctx, cfg, log),
+	)
+
+	return cacheCmd
+}
+
+// newCacheRehashCommand creates the cache rehash subcommand
+func newCacheRehashCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rehash [path...] --from algorithm",
+		Short: "Record checksums again with another algorithm",
+		Long: `Migrate the recorded checksums below the paths to a new algorithm after
+integrity.algorithm changed, so repair and checksum runs keep finding them.
+
+Every file with a --from checksum is read once: the old checksum is verified
+and the --to checksum (integrity.algorithm by default) is recorded in the same
+layout, e.g. photo.jpg.sha256 gets a photo.jpg.blake3 next to it and SHA256SUMS
+a BLAKE3SUMS. Files that no longer match are reported as corrupt and keep only
+their old checksum; run "fileops repair" on them.
+
+Migrations are incremental: files already recorded with --to are skipped, so
+--limit can spread a large migration over several runs. --first migrates the
+paths the next scheduled jobs read before all others. --remove-old removes the
+old sidecars and manifests once everything they list is migrated.
+
+  fileops cache rehash /archive --from sha256 --to blake3 --limit 100000 --first /archive/2024`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get flags
+			from, _ := cmd.Flags().GetString("from")
+			to, _ := cmd.Flags().GetString("to")
+			first, _ := cmd.Flags().GetStringSlice("first")
+			limit, _ := cmd.Flags().GetInt("limit")
+			removeOld, _ := cmd.Flags().GetBool("remove-old")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+
+			if from == "" {
+				return fmt.Errorf("--from is required")
+			}
+			validPaths, err := absolutePaths(args)
+			if err != nil {
+				return err
+			}
+			firstPaths, err := absolutePaths(first)
+			if err != nil {
+				return err
+			}
+
+			// Create operation configuration
+			config := domain.OperationConfig{
+				DryRun:          dryRun,
+				Recursive:       true,
+				ExcludePatterns: excludePatterns,
+				TargetPaths:     validPaths,
+				CustomSettings: map[string]interface{}{
+					"from":       from,
+					"to":         to,
+					"first":      firstPaths,
+					"limit":      limit,
+					"remove_old": removeOld,
+				},
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+
+			// Create engine
+			tracker := progress.NewTracker()
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
+
+			// Get quiet flag from root command
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			log.Info("🔁 Starting checksum migration",
+				"paths", validPaths,
+				"from", from,
+				"to", to,
+				"limit", limit,
+				"dry_run", dryRun)
+
+			// Show initial status
+			if !quiet {
+				ui.Printf("🔍 Scanning recorded checksums...\n")
+				if dryRun {
+					ui.Printf("📋 DRY RUN MODE: No sidecars will be written\n")
+				}
+				ui.Printf("📂 Paths to process: %v\n", validPaths)
+				ui.Printf("🔢 Algorithm: %s → %s\n", from, to)
+				if len(firstPaths) > 0 {
+					ui.Printf("⏫ First: %v\n", firstPaths)
+				}
+				if limit > 0 {
+					ui.Printf("🔢 At most %d files this run\n", limit)
+				}
+				fmt.Fprintln(ui.Stdout)
+			}
+
+			// Pre-generate operation ID for progress monitoring
+			operationID := engine.NewOperationID(domain.OperationRehash)
+
+			// Start progress monitoring in a separate goroutine BEFORE starting operation
+			progressCtx, progressCancel := context.WithCancel(ctx)
+			defer progressCancel()
+
+			var progressWg sync.WaitGroup
+			if !quiet && cfg.Operations.EnableProgressBar {
+				progressWg.Add(1)
+				go func() {
+					defer progressWg.Done()
+					MonitorProgress(progressCtx, tracker, operationID, "rehash")
+				}()
+				// Give the monitor a moment to start
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			// Execute operation with predefined ID so progress monitoring works
+			result, err := operationEngine.ExecuteOperationWithID(ctx, domain.OperationRehash, config, operationID)
+
+			// Stop progress monitoring
+			progressCancel()
+			progressWg.Wait()
+
+			if err != nil {
+				if !quiet {
+					ui.Printf("\n❌ Checksum migration failed: %v\n", err)
+					displayStoredResultHint(cfg, operationID)
+				}
+				return fmt.Errorf("checksum migration failed: %w", err)
+			}
+
+			// Display results
+			if !quiet {
+				ui.Printf("\n\n✅ Checksum migration completed!\n")
+				if result.Summary != "" {
+					ui.Printf("📊 %s\n", result.Summary)
+				}
+				ui.Printf("⏱️  Total time: %v\n", result.Duration.Round(time.Millisecond))
+				if remaining, _ := result.Details["remaining"].(domain.PathList); remaining.Count > 0 {
+					ui.Printf("💡 Run again to migrate the remaining %d files\n", remaining.Count)
+				}
+				listFiles(result, "corrupt", "❌ Corrupt files")
+
+				DisplayOperationWarnings(result)
+				DisplayOperationErrors(result)
+			}
+
+			log.Info("✅ Checksum migration completed", "summary", result.Summary)
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	// Add flags
+	cmd.Flags().String("from", "", "Algorithm of the recorded checksums (required)")
+	cmd.Flags().String("to", cfg.Integrity.Algorithm, "Algorithm to record checksums with")
+	cmd.Flags().StringSlice("first", []string{}, "Paths to migrate before all others, e.g. those the next scheduled jobs read")
+	cmd.Flags().Int("limit", 0, "Migrate at most this many files per run (0 for all)")
+	cmd.Flags().Bool("remove-old", false, "Remove the old sidecars and manifests once migrated")
+	cmd.Flags().Bool("dry-run", false, "Report which checksums would be migrated without writing them")
+	cmd.Flags().StringSlice("exclude", []string{".git", "node_modules"}, "Patterns to exclude")
+
+	return cmd
+}
@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// NewChecksumCommand creates the checksum command
+func NewChecksumCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "checksum [path...]",
+		Short: "Write checksum sidecars and recovery data for archived files",
+		Long: `Record the checksum of every file under the given paths so bit-rot can be
+detected later with "fileops repair".
+
+Layouts:
+  file       One sidecar per file, e.g. photo.jpg.sha256
+  directory  One manifest per directory, e.g. SHA256SUMS
+
+Both are in the format of sha256sum and friends, so "sha256sum -c" can verify
+them without fileops. With --parity a Reed-Solomon recovery file
+(<name>.parity) is written next to each file; --redundancy sets how much of
+the file it can rebuild.
+
+Runs are incremental: checksums are only recorded again for files modified
+after their sidecar was written. A file whose content changed without a newer
+modification time is reported as corrupt instead of being re-recorded. With
+--journal only the paths the filesystem change journal reports as changed are
+checked, see "fileops journal".`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get flags
+			algorithm, _ := cmd.Flags().GetString("algorithm")
+			layout, _ := cmd.Flags().GetString("layout")
+			parity, _ := cmd.Flags().GetBool("parity")
+			redundancy, _ := cmd.Flags().GetInt("redundancy")
+			force, _ := cmd.Flags().GetBool("force")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+			useJournal, _ := cmd.Flags().GetBool("journal")
+
+			// Validate paths
+			validPaths := make([]string, 0, len(args))
+			for _, path := range args {
+				absPath, err := filepath.Abs(path)
+				if err != nil {
+					return fmt.Errorf("invalid path %s: %w", path, err)
+				}
+				if _, err := os.Stat(absPath); os.IsNotExist(err) {
+					return fmt.Errorf("path does not exist: %s", absPath)
+				}
+				validPaths = append(validPaths, absPath)
+			}
+
+			// Create operation configuration
+			config := domain.OperationConfig{
+				DryRun:          dryRun,
+				Recursive:       true,
+				ExcludePatterns: excludePatterns,
+				TargetPaths:     validPaths,
+				CustomSettings: map[string]interface{}{
+					"algorithm":  algorithm,
+					"layout":     layout,
+					"parity":     parity,
+					"redundancy": redundancy,
+					"force":      force,
+				},
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+
+			// Create engine
+			tracker := progress.NewTracker()
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
+
+			// Get quiet flag from root command
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			// Limit the run to the paths the change journal reports as changed
+			var journaled *journalRun
+			if useJournal {
+				var pending bool
+				if journaled, pending = startJournalRun(ctx, cfg, log, "checksum", &config, quiet); !pending {
+					if !dryRun {
+						journaled.finish(log)
+					}
+					if !quiet {
+						ui.Printf("✅ Nothing changed since the last run\n")
+					}
+					return nil
+				}
+			}
+
+			log.Info("🔏 Starting checksum recording",
+				"paths", validPaths,
+				"algorithm", algorithm,
+				"layout", layout,
+				"parity", parity,
+				"dry_run", dryRun)
+
+			// Show initial status
+			if !quiet {
+				ui.Printf("🔍 Scanning files to protect...\n")
+				if dryRun {
+					ui.Printf("📋 DRY RUN MODE: No sidecars will be written\n")
+				}
+				ui.Printf("📂 Paths to process: %v\n", validPaths)
+				ui.Printf("🔢 Algorithm: %s (%s layout)\n", algorithm, layout)
+				if parity {
+					ui.Printf("🛟 Recovery data: %d%%\n", redundancy)
+				}
+				fmt.Fprintln(ui.Stdout)
+			}
+
+			// Pre-generate operation ID for progress monitoring
+			operationID := engine.NewOperationID(domain.OperationChecksum)
+
+			// Start progress monitoring in a separate goroutine BEFORE starting operation
+			progressCtx, progressCancel := context.WithCancel(ctx)
+			defer progressCancel()
+
+			var progressWg sync.WaitGroup
+			if !quiet && cfg.Operations.EnableProgressBar {
+				progressWg.Add(1)
+				go func() {
+					defer progressWg.Done()
+					MonitorProgress(progressCtx, tracker, operationID, "checksum")
+				}()
+				// Give the monitor a moment to start
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			// Execute operation with predefined ID so progress monitoring works
+			result, err := operationEngine.ExecuteOperationWithID(ctx, domain.OperationChecksum, config, operationID)
+
+			// Stop progress monitoring
+			progressCancel()
+			progressWg.Wait()
+
+			if err != nil {
+				if !quiet {
+					ui.Printf("\n❌ Checksum recording failed: %v\n", err)
+					displayStoredResultHint(cfg, operationID)
+				}
+				return fmt.Errorf("checksum recording failed: %w", err)
+			}
+
+			if journaled != nil && !dryRun {
+				journaled.finish(log)
+			}
+
+			// Display results
+			if !quiet {
+				ui.Printf("\n\n✅ Checksum recording completed!\n")
+				if result.Summary != "" {
+					ui.Printf("📊 %s\n", result.Summary)
+				}
+				ui.Printf("⏱️  Total time: %v\n", result.Duration.Round(time.Millisecond))
+
+				displayCostEstimate(result)
+				DisplayOperationWarnings(result)
+				DisplayOperationErrors(result)
+			}
+
+			log.Info("✅ Checksum recording completed", "summary", result.Summary)
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	// Add flags
+	cmd.Flags().String("algorithm", cfg.Integrity.Algorithm, "Checksum algorithm (md5, sha1, sha256, sha512, blake2b, blake3, xxhash64, crc32)")
+	cmd.Flags().String("layout", cfg.Integrity.Layout, "Sidecar layout (file, directory)")
+	cmd.Flags().Bool("parity", false, "Also write Reed-Solomon recovery data used by repair")
+	cmd.Flags().Int("redundancy", cfg.Integrity.Redundancy, "Recovery data size in percent of each file (1-100)")
+	cmd.Flags().Bool("force", false, "Record checksums again even for unmodified files")
+	cmd.Flags().Bool("dry-run", false, "Report which sidecars would be written without writing them")
+	cmd.Flags().StringSlice("exclude", []string{".git", "node_modules"}, "Patterns to exclude")
+	cmd.Flags().Bool("journal", false, "Only process paths the filesystem change journal reports as changed (see \"fileops journal\")")
+
+	return cmd
+}
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -15,8 +16,8 @@ import (
 	"github.com/a4abhishek/fileops/internal/config"
 	"github.com/a4abhishek/fileops/internal/engine"
 	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
 	"github.com/a4abhishek/fileops/pkg/domain"
-	"github.com/a4abhishek/fileops/pkg/filesystem"
 	"github.com/a4abhishek/fileops/pkg/progress"
 	"github.com/spf13/cobra"
 )
@@ -33,7 +34,14 @@ On Linux/macOS, it uses chown functionality. On Windows, it attempts to take
 ownership using Windows-specific APIs.
 
 The operation is performed recursively by default and supports dry-run mode
-for preview before making changes.`,
+for preview before making changes.
+
+As with coreutils chown, --from only changes items currently owned by the
+given user, group or both ("user:group", "user", ":group"), and symbolic
+links are followed to change their target unless -h (--no-dereference)
+changes the link itself:
+
+  fileops chown /srv/share --from olduser --user newuser -h`,
 		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Get flags
@@ -43,6 +51,15 @@ for preview before making changes.`,
 			parallelism, _ := cmd.Flags().GetInt("parallelism")
 			targetUser, _ := cmd.Flags().GetString("user")
 			targetGroup, _ := cmd.Flags().GetString("group")
+			from, _ := cmd.Flags().GetString("from")
+			noDereference, _ := cmd.Flags().GetBool("no-dereference")
+			if cmd.Flags().Changed("dereference") {
+				dereference, _ := cmd.Flags().GetBool("dereference")
+				if dereference && noDereference {
+					return fmt.Errorf("--dereference and --no-dereference cannot be combined")
+				}
+				noDereference = !dereference
+			}
 			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
 
 			// Get current user if not specified
@@ -94,6 +111,11 @@ for preview before making changes.`,
 				}
 			}
 
+			fromUID, fromGID, err := parseOwnerSpec(from)
+			if err != nil {
+				return err
+			}
+
 			// Validate paths
 			validPaths := make([]string, 0, len(args))
 			for _, path := range args {
@@ -112,21 +134,28 @@ for preview before making changes.`,
 				DryRun:          dryRun,
 				Recursive:       recursive,
 				ExcludePatterns: excludePatterns,
-				IncludePatterns: validPaths,
+				TargetPaths:     validPaths,
 				Parallelism:     parallelism,
 				CustomSettings: map[string]interface{}{
-					"target_user":  targetUser,
-					"target_group": targetGroup,
-					"uid":          uid,
-					"gid":          gid,
+					"target_user":    targetUser,
+					"target_group":   targetGroup,
+					"uid":            uid,
+					"gid":            gid,
+					"from_uid":       fromUID,
+					"from_gid":       fromGID,
+					"no_dereference": noDereference,
 				},
 			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
 
 			// Create engine
-			chunkSize := ParseSize(cfg.Performance.ChunkSize, 64*1024*1024)
-			fs := filesystem.NewOSFileSystem(chunkSize)
 			tracker := progress.NewTracker()
-			operationEngine := engine.NewEngine(fs, tracker, log)
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
 
 			log.Info("👑 Starting ownership change",
 				"paths", validPaths,
@@ -144,14 +173,26 @@ for preview before making changes.`,
 				if targetGroup != "" {
 					params["Target group"] = targetGroup
 				}
+				if from != "" {
+					params["Only owned by"] = from
+				}
+				if noDereference {
+					params["Symbolic links"] = "change the link itself"
+				}
 				if len(excludePatterns) > 0 {
 					params["Excluded patterns"] = excludePatterns
 				}
 				DisplayOperationStart("ownership change", fmt.Sprintf("%v", validPaths), dryRun, params)
 			}
 
+			// Estimate large runs and ask before changing anything
+			if !confirmLargeOperation(cmd, cfg, operationEngine, domain.OperationOwnership, config) {
+				ui.Printf("Nothing was changed\n")
+				return nil
+			}
+
 			// Pre-generate operation ID for progress monitoring
-			operationID := fmt.Sprintf("ownership-%s", time.Now().Format("20060102-150405"))
+			operationID := engine.NewOperationID(domain.OperationOwnership)
 
 			// Start progress monitoring in a separate goroutine BEFORE starting operation
 			progressCtx, progressCancel := context.WithCancel(ctx)
@@ -177,7 +218,8 @@ for preview before making changes.`,
 
 			if err != nil {
 				if !quiet {
-					fmt.Printf("\n❌ Ownership change operation failed: %v\n", err)
+					ui.Printf("\n❌ Ownership change operation failed: %v\n", err)
+					displayStoredResultHint(cfg, operationID)
 				}
 				return fmt.Errorf("ownership change operation failed: %w", err)
 			}
@@ -190,56 +232,64 @@ for preview before making changes.`,
 
 			log.Info("✅ Ownership change completed", "summary", result.Summary)
 
-			if changedItems, ok := result.Details["changed_items"].([]string); ok && len(changedItems) > 0 {
+			if changedItems, ok := result.Details["changed_items"].(domain.PathList); ok && changedItems.Count > 0 {
 				if !quiet {
-					fmt.Printf("\n👑 Ownership changed (%d total):\n", len(changedItems))
-					for i, item := range changedItems {
+					ui.Printf("\n👑 Ownership changed (%d total):\n", changedItems.Count)
+					for i, item := range changedItems.Paths {
 						if i >= 20 {
-							fmt.Printf("  ... and %d more items\n", len(changedItems)-20)
+							ui.Printf("  ... and %d more items\n", changedItems.Count-20)
+							displayPathListFile(changedItems)
 							break
 						}
 						if dryRun {
-							fmt.Printf("  [DRY RUN] Would change: %s\n", item)
+							ui.Printf("  [DRY RUN] Would change: %s\n", item)
 						} else {
-							fmt.Printf("  ✓ Changed: %s\n", item)
+							ui.Printf("  ✓ Changed: %s\n", item)
 						}
 					}
 				}
 			} else if !quiet {
 				if dryRun {
-					fmt.Printf("\n👑 No ownership changes needed\n")
+					ui.Printf("\n👑 No ownership changes needed\n")
 				} else {
-					fmt.Printf("\n👑 No items required ownership changes\n")
+					ui.Printf("\n👑 No items required ownership changes\n")
 				}
 			}
 
-			if skippedItems, ok := result.Details["skipped_items"].([]string); ok && len(skippedItems) > 0 {
+			if skippedItems, ok := result.Details["skipped_items"].(domain.PathList); ok && skippedItems.Count > 0 {
 				if !quiet {
-					fmt.Printf("\n⚠️  Skipped items (%d total):\n", len(skippedItems))
-					for i, item := range skippedItems {
+					ui.Printf("\n⚠️  Skipped items (%d total):\n", skippedItems.Count)
+					for i, item := range skippedItems.Paths {
 						if i >= 10 {
-							fmt.Printf("  ... and %d more items\n", len(skippedItems)-10)
+							ui.Printf("  ... and %d more items\n", skippedItems.Count-10)
+							displayPathListFile(skippedItems)
 							break
 						}
-						fmt.Printf("  - %s\n", item)
+						ui.Printf("  - %s\n", item)
 					}
 				}
 			}
 
-			if errors, ok := result.Details["errors"].([]string); ok && len(errors) > 0 {
+			if errors, ok := result.Details["errors"].(domain.PathList); ok && errors.Count > 0 {
 				if !quiet {
-					fmt.Printf("\n❌ Errors encountered (%d total):\n", len(errors))
-					for i, errMsg := range errors {
+					ui.Printf("\n❌ Errors encountered (%d total):\n", errors.Count)
+					for i, errMsg := range errors.Paths {
 						if i >= 5 {
-							fmt.Printf("  ... and %d more errors\n", len(errors)-5)
+							ui.Printf("  ... and %d more errors\n", errors.Count-5)
+							displayPathListFile(errors)
 							break
 						}
-						fmt.Printf("  ! %s\n", errMsg)
+						ui.Printf("  ! %s\n", errMsg)
 					}
 				}
 			}
 
-			return nil
+			if !quiet {
+				DisplayOperationWarnings(result)
+				DisplayOperationErrors(result)
+			}
+
+			return warningPolicy(cmd, result)
 		},
 	}
 
@@ -247,13 +297,38 @@ for preview before making changes.`,
 	cmd.Flags().Bool("dry-run", false, "Preview changes without executing them")
 	cmd.Flags().BoolP("recursive", "r", true, "Process directories recursively")
 	cmd.Flags().StringSlice("exclude", []string{".git", ".svn", "node_modules", "__pycache__"}, "Patterns to exclude")
-	cmd.Flags().Int("parallelism", runtime.NumCPU(), "Number of parallel workers")
+	cmd.Flags().Int("parallelism", cfg.Performance.MaxWorkers, "Number of parallel workers")
 	cmd.Flags().String("user", "", "Target user (defaults to current user)")
 	cmd.Flags().String("group", "", "Target group (defaults to user's primary group)")
+	cmd.Flags().String("from", "", "Only change items currently owned by user:group (either may be omitted)")
+	cmd.Flags().BoolP("no-dereference", "h", false, "Change symbolic links themselves instead of their targets")
+	cmd.Flags().Bool("dereference", true, "Change the targets of symbolic links")
+	cmd.Flags().Bool("help", false, "help for chown") // -h means --no-dereference, as in coreutils
 
 	return cmd
 }
 
+// parseOwnerSpec parses a "user:group" owner as taken by --from, where either
+// side may be a name or numeric id and may be omitted (-1)
+func parseOwnerSpec(spec string) (int, int, error) {
+	if spec == "" {
+		return -1, -1, nil
+	}
+	name, group, _ := strings.Cut(spec, ":")
+	uid, err := lookupOwnerID(name, false)
+	if err != nil {
+		return -1, -1, err
+	}
+	gid, err := lookupOwnerID(group, true)
+	if err != nil {
+		return -1, -1, err
+	}
+	if uid < 0 && gid < 0 {
+		return -1, -1, fmt.Errorf("invalid owner %q, expected user, user:group or :group", spec)
+	}
+	return uid, gid, nil
+}
+
 // ChangeOwnership changes the ownership of a file or directory
 // This function works cross-platform with different behaviors on Windows vs Unix
 func ChangeOwnership(path string, uid, gid int, dryRun bool) error {
@@ -5,15 +5,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"runtime"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/a4abhishek/fileops/internal/config"
 	"github.com/a4abhishek/fileops/internal/engine"
 	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
 	"github.com/a4abhishek/fileops/pkg/domain"
-	"github.com/a4abhishek/fileops/pkg/filesystem"
 	"github.com/a4abhishek/fileops/pkg/progress"
 	"github.com/spf13/cobra"
 )
@@ -22,12 +23,47 @@ import (
 func NewCleanCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "clean [path...]",
-		Short: "Remove empty directories recursively",
+		Short: "Remove empty directories, abandoned partial downloads and build outputs",
 		Long: `Remove empty directories recursively from the specified paths.
 
 This command performs a bottom-up traversal to identify and remove empty directories.
-It supports dry-run mode for safe preview and has configurable exclusion patterns.`,
-		Args: cobra.MinimumNArgs(1),
+It supports dry-run mode for safe preview and has configurable exclusion patterns.
+
+The rule sets to apply are selected with --rules:
+
+  empty-dirs         empty directories (default)
+  partial-downloads  unfinished downloads of browsers and torrent clients
+                     (.part, .crdownload, .!ut, .!qB, ...) and preallocated
+                     files no data was written to
+
+Partial downloads modified within the last --older-than days may still be in
+progress and are only listed. Each file is checked again right before it is
+removed, and with --backup-dir it is moved there instead:
+
+  fileops clean ~/Downloads --rules partial-downloads --older-than 14 --dry-run
+
+With --build-artifacts (the build-artifacts rule) build outputs of projects
+are removed: node_modules, dist, target, build, bin/obj, .tox and similar
+directories, but only next to a manifest (package.json, Cargo.toml, pom.xml,
+build.gradle, pyproject.toml, *.csproj, go.mod, ...) that proves they can be
+regenerated, and only when the git repository they live in ignores them:
+
+  fileops clean ~/src --build-artifacts --dry-run
+
+--skip-build-outputs does the opposite and leaves those directories alone.
+
+--extension (and operations.extensions) sets what happens to files by their
+extension or name pattern: delete removes them wherever they are found, skip
+and keep leave them alone even when another rule matches:
+
+  fileops clean ~/scratch --extension tmp=delete --extension "*.part=keep"
+
+With --profile well-known caches are cleared instead of the given paths. Every
+location comes with the reason why removing it is safe (--list-profiles), and
+the size to be freed is shown for confirmation before anything is deleted:
+
+  fileops clean --profile dev-caches`,
+		Args: cobra.ArbitraryArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Get flags
 			dryRun, _ := cmd.Flags().GetBool("dry-run")
@@ -35,6 +71,37 @@ It supports dry-run mode for safe preview and has configurable exclusion pattern
 			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
 			backupDir, _ := cmd.Flags().GetString("backup-dir")
 			parallelism, _ := cmd.Flags().GetInt("parallelism")
+			rules, _ := cmd.Flags().GetStringSlice("rules")
+			olderThan, _ := cmd.Flags().GetInt("older-than")
+			profiles, _ := cmd.Flags().GetStringSlice("profile")
+			listProfiles, _ := cmd.Flags().GetBool("list-profiles")
+			yes, _ := cmd.Flags().GetBool("yes")
+			buildArtifacts, _ := cmd.Flags().GetBool("build-artifacts")
+			skipBuildOutputs, _ := cmd.Flags().GetBool("skip-build-outputs")
+
+			if buildArtifacts {
+				if !cmd.Flags().Changed("rules") {
+					rules = nil
+				}
+				if !slices.Contains(rules, engine.RuleBuildArtifacts) {
+					rules = append(rules, engine.RuleBuildArtifacts)
+				}
+				if !cmd.Flags().Changed("exclude") {
+					// node_modules is excluded by default for the other rules
+					excludePatterns = slices.DeleteFunc(excludePatterns, func(p string) bool { return p == "node_modules" })
+				}
+			}
+
+			if listProfiles {
+				displayCleanerProfiles()
+				return nil
+			}
+			if len(profiles) == 0 && len(args) == 0 {
+				return fmt.Errorf("requires at least one path or --profile")
+			}
+			if len(profiles) > 0 && len(args) > 0 {
+				return fmt.Errorf("paths cannot be combined with --profile")
+			}
 
 			// Validate paths
 			validPaths := make([]string, 0, len(args))
@@ -54,17 +121,30 @@ It supports dry-run mode for safe preview and has configurable exclusion pattern
 				DryRun:             dryRun,
 				Recursive:          recursive,
 				ExcludePatterns:    excludePatterns,
-				IncludePatterns:    validPaths,
+				TargetPaths:        validPaths,
 				BackupBeforeDelete: backupDir != "",
 				BackupDirectory:    backupDir,
 				Parallelism:        parallelism,
+				CustomSettings: map[string]interface{}{
+					"rules":              rules,
+					"older_than_days":    olderThan,
+					"profiles":           profiles,
+					"skip_build_outputs": skipBuildOutputs,
+				},
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+			if err := applyExtensions(cmd, cfg, &config); err != nil {
+				return err
 			}
 
 			// Create engine
-			chunkSize := ParseSize(cfg.Performance.ChunkSize, 64*1024*1024) // Default 64MB
-			fs := filesystem.NewOSFileSystem(chunkSize)
 			tracker := progress.NewTracker()
-			operationEngine := engine.NewEngine(fs, tracker, log)
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
 
 			// Get quiet flag from root command
 			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
@@ -76,19 +156,43 @@ It supports dry-run mode for safe preview and has configurable exclusion pattern
 
 			// Show initial status
 			if !quiet {
-				fmt.Printf("🔍 Scanning directories...\n")
+				ui.Printf("🔍 Scanning directories...\n")
 				if dryRun {
-					fmt.Printf("📋 DRY RUN MODE: No changes will be made\n")
+					ui.Printf("📋 DRY RUN MODE: No changes will be made\n")
+				}
+				if len(profiles) > 0 {
+					ui.Printf("🧽 Cleaner profiles: %v\n", profiles)
+				} else {
+					ui.Printf("📂 Paths to process: %v\n", validPaths)
 				}
-				fmt.Printf("📂 Paths to process: %v\n", validPaths)
 				if len(excludePatterns) > 0 {
-					fmt.Printf("🚫 Excluding patterns: %v\n", excludePatterns)
+					ui.Printf("🚫 Excluding patterns: %v\n", excludePatterns)
 				}
-				fmt.Printf("⚡ Using %d parallel workers\n\n", parallelism)
+				ui.Printf("⚡ Using %d parallel workers\n\n", parallelism)
+			}
+
+			// Show what the profiles would free and ask before deleting
+			if len(profiles) > 0 && !dryRun && !yes {
+				estimateConfig := config
+				estimateConfig.DryRun = true
+				estimate, err := operationEngine.ExecuteOperation(ctx, domain.OperationCleanup, estimateConfig)
+				if err != nil {
+					return fmt.Errorf("failed to estimate cleanup: %w", err)
+				}
+				if !confirmProfileCleanup(estimate, quiet) {
+					ui.Printf("Nothing was removed\n")
+					return nil
+				}
+			}
+
+			// Estimate large runs and ask before changing anything
+			if !confirmLargeOperation(cmd, cfg, operationEngine, domain.OperationCleanup, config) {
+				ui.Printf("Nothing was changed\n")
+				return nil
 			}
 
 			// Pre-generate operation ID for progress monitoring
-			operationID := fmt.Sprintf("cleanup-%s", time.Now().Format("20060102-150405"))
+			operationID := engine.NewOperationID(domain.OperationCleanup)
 
 			// Start progress monitoring in a separate goroutine BEFORE starting operation
 			progressCtx, progressCancel := context.WithCancel(ctx)
@@ -114,64 +218,114 @@ It supports dry-run mode for safe preview and has configurable exclusion pattern
 
 			if err != nil {
 				if !quiet {
-					fmt.Printf("\n❌ Cleanup operation failed: %v\n", err)
+					ui.Printf("\n❌ Cleanup operation failed: %v\n", err)
+					displayStoredResultHint(cfg, operationID)
 				}
 				return fmt.Errorf("cleanup operation failed: %w", err)
 			}
 
 			// Display results
 			if !quiet {
-				fmt.Printf("\n\n✅ Cleanup completed successfully!\n")
+				ui.Printf("\n\n✅ Cleanup completed successfully!\n")
 
 				// Show operation summary
 				if result.Summary != "" {
-					fmt.Printf("📊 %s\n", result.Summary)
+					ui.Printf("📊 %s\n", result.Summary)
 				}
 
 				// Show timing information
 				duration := result.EndTime.Sub(result.StartTime)
-				fmt.Printf("⏱️  Total time: %v\n", duration.Round(time.Millisecond))
+				ui.Printf("⏱️  Total time: %v\n", duration.Round(time.Millisecond))
 			}
 
 			log.Info("✅ Cleanup completed", "summary", result.Summary)
 
-			if removedDirs, ok := result.Details["removed_directories"].([]string); ok && len(removedDirs) > 0 {
+			if len(profiles) > 0 {
+				if !quiet {
+					displayProfileTargets(result)
+					displayCostEstimate(result)
+					DisplayOperationWarnings(result)
+					DisplayOperationErrors(result)
+				}
+				return warningPolicy(cmd, result)
+			}
+
+			if partials, ok := result.Details["partial_downloads"].(domain.PathList); ok && partials.Count > 0 && !quiet {
+				bytes, _ := result.Details["partial_bytes"].(int64)
+				ui.Printf("\n📥 Partial downloads (%d total, %s):\n", partials.Count, FormatBytes(bytes))
+				for i, path := range partials.Paths {
+					if i >= 20 {
+						ui.Printf("  ... and %d more files\n", partials.Count-20)
+						displayPathListFile(partials)
+						break
+					}
+					if dryRun {
+						ui.Printf("  [DRY RUN] Would remove: %s\n", path)
+					} else {
+						ui.Printf("  ✓ Removed: %s\n", path)
+					}
+				}
+			}
+			if artifacts, ok := result.Details["build_artifacts"].([]engine.BuildArtifact); ok && len(artifacts) > 0 && !quiet {
+				bytes, _ := result.Details["artifact_bytes"].(int64)
+				ui.Printf("\n🏗️  Build outputs (%d total, %s):\n", len(artifacts), FormatBytes(bytes))
+				for _, artifact := range artifacts {
+					ui.Printf("  %-60s %10s  %s: %s\n", artifact.Path, FormatBytes(artifact.Size), artifact.Kind, artifact.Reason)
+				}
+			}
+			if !quiet {
+				if dryRun {
+					listFiles(result, "extension_files", "🏷️  Would be removed by extension")
+				} else {
+					listFiles(result, "extension_files", "🏷️  Removed by extension")
+				}
+				listFiles(result, "active_downloads", fmt.Sprintf("⏳ Partial downloads modified within %d days, kept", olderThan))
+			}
+
+			if removedDirs, ok := result.Details["removed_directories"].(domain.PathList); ok && removedDirs.Count > 0 {
 				if !quiet {
-					fmt.Printf("\n📁 Directories processed (%d total):\n", len(removedDirs))
-					for i, dir := range removedDirs {
+					ui.Printf("\n📁 Directories processed (%d total):\n", removedDirs.Count)
+					for i, dir := range removedDirs.Paths {
 						if i >= 20 {
-							fmt.Printf("  ... and %d more directories\n", len(removedDirs)-20)
+							ui.Printf("  ... and %d more directories\n", removedDirs.Count-20)
+							displayPathListFile(removedDirs)
 							break
 						}
 						if dryRun {
-							fmt.Printf("  [DRY RUN] Would remove: %s\n", dir)
+							ui.Printf("  [DRY RUN] Would remove: %s\n", dir)
 						} else {
-							fmt.Printf("  ✓ Removed: %s\n", dir)
+							ui.Printf("  ✓ Removed: %s\n", dir)
 						}
 					}
 				}
-			} else if !quiet {
+			} else if !quiet && slices.Contains(rules, "empty-dirs") {
 				if dryRun {
-					fmt.Printf("\n📁 No empty directories found to remove\n")
+					ui.Printf("\n📁 No empty directories found to remove\n")
 				} else {
-					fmt.Printf("\n📁 No directories were removed\n")
+					ui.Printf("\n📁 No directories were removed\n")
 				}
 			}
 
-			if skippedDirs, ok := result.Details["skipped_directories"].([]string); ok && len(skippedDirs) > 0 {
+			if skippedDirs, ok := result.Details["skipped_directories"].(domain.PathList); ok && skippedDirs.Count > 0 {
 				if !quiet {
-					fmt.Printf("\n⚠️  Skipped directories (%d total):\n", len(skippedDirs))
-					for i, dir := range skippedDirs {
+					ui.Printf("\n⚠️  Skipped directories (%d total):\n", skippedDirs.Count)
+					for i, dir := range skippedDirs.Paths {
 						if i >= 10 {
-							fmt.Printf("  ... and %d more directories\n", len(skippedDirs)-10)
+							ui.Printf("  ... and %d more directories\n", skippedDirs.Count-10)
+							displayPathListFile(skippedDirs)
 							break
 						}
-						fmt.Printf("  - %s\n", dir)
+						ui.Printf("  - %s\n", dir)
 					}
 				}
 			}
 
-			return nil
+			if !quiet {
+				DisplayOperationWarnings(result)
+				DisplayOperationErrors(result)
+			}
+
+			return warningPolicy(cmd, result)
 		},
 	}
 
@@ -180,7 +334,78 @@ It supports dry-run mode for safe preview and has configurable exclusion pattern
 	cmd.Flags().BoolP("recursive", "r", true, "Process directories recursively")
 	cmd.Flags().StringSlice("exclude", []string{".git", ".svn", "node_modules", "__pycache__"}, "Patterns to exclude")
 	cmd.Flags().String("backup-dir", "", "Directory to store backups before deletion")
-	cmd.Flags().Int("parallelism", runtime.NumCPU(), "Number of parallel workers")
+	cmd.Flags().Int("parallelism", cfg.Performance.MaxWorkers, "Number of parallel workers")
+	cmd.Flags().StringSlice("rules", []string{"empty-dirs"}, "Rule sets to apply: empty-dirs, partial-downloads, build-artifacts")
+	cmd.Flags().Int("older-than", 7, "Days a partial download must be untouched before it is removed")
+	cmd.Flags().StringSlice("profile", []string{}, "Cleaner profiles to apply instead of paths (see --list-profiles)")
+	cmd.Flags().Bool("list-profiles", false, "List the cleaner profiles with the locations they clear")
+	cmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation before clearing profile locations or large runs")
+	cmd.Flags().Bool("build-artifacts", false, "Remove build outputs of projects whose manifest proves them regenerable")
+	cmd.Flags().Bool("skip-build-outputs", false, "Do not descend into build outputs of recognized projects")
+	cmd.Flags().StringSlice("extension", []string{}, "Files to leave alone (skip, keep) or remove (delete) by extension (pattern=action, e.g. raw=skip; repeatable, adds to operations.extensions)")
+	cmd.MarkFlagsMutuallyExclusive("build-artifacts", "skip-build-outputs")
 
 	return cmd
 }
+
+// displayCleanerProfiles lists the cleaner profiles with their targets and safety rationale
+func displayCleanerProfiles() {
+	for _, profile := range engine.CleanerProfiles() {
+		ui.Printf("🧽 %s: %s\n", profile.Name, profile.Description)
+		for _, target := range profile.Targets {
+			ui.Printf("  • %s\n", target.Name)
+			for _, path := range target.Paths {
+				ui.Printf("      %s\n", path)
+			}
+			ui.Printf("    ↳ %s\n", target.Rationale)
+		}
+		fmt.Fprintln(ui.Stdout)
+	}
+}
+
+// displayProfileTargets shows the size freed per cache location
+func displayProfileTargets(result *domain.OperationResult) {
+	targets, ok := result.Details["profile_targets"].([]engine.CleanerTargetResult)
+	if !ok {
+		return
+	}
+
+	var total int64
+	found := 0
+	fmt.Fprintln(ui.Stdout)
+	for _, target := range targets {
+		if target.Items == 0 {
+			continue
+		}
+		found++
+		total += target.Size
+		ui.Printf("  📦 %-28s %10s  (%d files)\n", target.Target, FormatBytes(target.Size), target.Files)
+		ui.Printf("     ↳ %s\n", target.Rationale)
+	}
+	if found == 0 {
+		ui.Printf("  Nothing to clean, none of the locations exist or they are empty\n")
+		return
+	}
+	ui.Printf("\n  💾 Total: %s\n", FormatBytes(total))
+}
+
+// confirmProfileCleanup shows the estimate and asks whether to proceed
+func confirmProfileCleanup(estimate *domain.OperationResult, quiet bool) bool {
+	size, _ := estimate.Details["profile_bytes"].(int64)
+	if !quiet {
+		ui.Printf("📋 The following cache locations will be cleared:\n")
+		displayProfileTargets(estimate)
+		fmt.Fprintln(ui.Stdout)
+	}
+	if size == 0 {
+		return false
+	}
+
+	ui.Fprintf(os.Stderr, "❓ Remove %s? [y/N] ", FormatBytes(size))
+	answer, err := readLine()
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
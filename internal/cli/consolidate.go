@@ -3,9 +3,17 @@ package cli
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
 	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
 	"github.com/spf13/cobra"
 )
 
@@ -17,7 +25,15 @@ func NewConsolidateCommand(ctx context.Context, cfg *config.Config, log *logger.
 		Long: `Consolidate files from multiple source directories into a single destination.
 
 This command moves or copies files from multiple source locations to a unified
-destination directory with conflict resolution and organization options.`,
+destination directory with conflict resolution and organization options.
+
+Sources are read in parallel (each source gets its own pool of readers, so
+separate physical drives are used concurrently) while writes to the destination
+are bounded by --dest-concurrency to avoid thrashing the target volume.
+
+Sidecars (IMG_0042.xmp, IMG_0042.AAE, movie.en.srt, movie.nfo, the .mov of a
+Live Photo) and the RAW of a RAW+JPEG pair go wherever their file goes, and are
+renamed along with it when a conflict is resolved by renaming.`,
 		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Get flags
@@ -25,22 +41,175 @@ destination directory with conflict resolution and organization options.`,
 			if destination == "" {
 				return fmt.Errorf("destination directory is required (use --dest flag)")
 			}
+			move, _ := cmd.Flags().GetBool("move")
+			link, _ := cmd.Flags().GetString("link")
+			preserveStructure, _ := cmd.Flags().GetBool("preserve-structure")
+			conflictResolution, _ := cmd.Flags().GetString("conflict-resolution")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+			sourceConcurrency, _ := cmd.Flags().GetInt("source-concurrency")
+			destConcurrency, _ := cmd.Flags().GetInt("dest-concurrency")
+			spaceCheck, _ := cmd.Flags().GetString("space-check")
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			// Validate paths
+			validPaths := make([]string, 0, len(args))
+			for _, path := range args {
+				absPath, err := filepath.Abs(path)
+				if err != nil {
+					return fmt.Errorf("invalid path %s: %w", path, err)
+				}
+				if _, err := os.Stat(absPath); os.IsNotExist(err) {
+					return fmt.Errorf("path does not exist: %s", absPath)
+				}
+				validPaths = append(validPaths, absPath)
+			}
+
+			absDestination, err := filepath.Abs(destination)
+			if err != nil {
+				return fmt.Errorf("invalid destination %s: %w", destination, err)
+			}
+
+			// Create operation configuration
+			config := domain.OperationConfig{
+				DryRun:          dryRun,
+				Recursive:       true,
+				ExcludePatterns: excludePatterns,
+				TargetPaths:     validPaths,
+				CustomSettings: map[string]interface{}{
+					"destination":             absDestination,
+					"move":                    move,
+					"link":                    link,
+					"preserve_structure":      preserveStructure,
+					"conflict_resolution":     conflictResolution,
+					"source_concurrency":      sourceConcurrency,
+					"destination_concurrency": destConcurrency,
+					"space_check":             spaceCheck,
+				},
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+
+			// Create engine
+			tracker := progress.NewTracker()
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
 
 			log.Info("📦 Starting file consolidation",
-				"sources", args,
-				"destination", destination)
+				"sources", validPaths,
+				"destination", absDestination,
+				"dry_run", dryRun)
+
+			// Show initial status
+			if !quiet {
+				params := map[string]interface{}{
+					"destination":         absDestination,
+					"mode":                map[bool]string{true: "move", false: "copy"}[move],
+					"conflict_resolution": conflictResolution,
+					"source_concurrency":  sourceConcurrency,
+					"dest_concurrency":    destConcurrency,
+				}
+				DisplayOperationStart("consolidation", fmt.Sprintf("%v", validPaths), dryRun, params)
+			}
+
+			// Estimate large runs and ask before changing anything
+			if !confirmLargeOperation(cmd, cfg, operationEngine, domain.OperationConsolidation, config) {
+				ui.Printf("Nothing was changed\n")
+				return nil
+			}
+
+			// Pre-generate operation ID for progress monitoring
+			operationID := engine.NewOperationID(domain.OperationConsolidation)
+
+			// Start progress monitoring in a separate goroutine BEFORE starting operation
+			progressCtx, progressCancel := context.WithCancel(ctx)
+			defer progressCancel()
+
+			var progressWg sync.WaitGroup
+			if !quiet && cfg.Operations.EnableProgressBar {
+				progressWg.Add(1)
+				go func() {
+					defer progressWg.Done()
+					MonitorProgress(progressCtx, tracker, operationID, "consolidation")
+				}()
+				// Give the monitor a moment to start
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			// Execute operation with predefined ID so progress monitoring works
+			result, err := operationEngine.ExecuteOperationWithID(ctx, domain.OperationConsolidation, config, operationID)
+
+			// Stop progress monitoring
+			progressCancel()
+			progressWg.Wait()
+
+			if err != nil {
+				if !quiet {
+					ui.Printf("\n❌ Consolidation operation failed: %v\n", err)
+					displayStoredResultHint(cfg, operationID)
+				}
+				return fmt.Errorf("consolidation operation failed: %w", err)
+			}
+
+			// Display results
+			if !quiet {
+				duration := result.EndTime.Sub(result.StartTime)
+				DisplayOperationComplete("consolidation", duration, result.Summary)
+			}
+
+			log.Info("✅ Consolidation completed", "summary", result.Summary)
+
+			if estimate, ok := result.Details["space_estimate"].(*engine.SpaceEstimate); ok && !quiet {
+				ui.Printf("\n💾 Destination space: %s required, %s available at %s\n",
+					FormatBytes(estimate.Required), FormatBytes(estimate.Available), estimate.Destination)
+			}
+
+			if conflicts, ok := result.Details["conflicts"].([]domain.ConflictResolution); ok && len(conflicts) > 0 && !quiet {
+				ui.Printf("\n⚠️  Conflicts (%d total):\n", len(conflicts))
+				for i, conflict := range conflicts {
+					if i >= 10 {
+						ui.Printf("  ... and %d more conflicts\n", len(conflicts)-10)
+						break
+					}
+					ui.Printf("  - %s -> %s (%s)\n", conflict.SourcePath, conflict.TargetPath, conflict.Resolution)
+				}
+			}
+
+			if conflicts, ok := result.Details["normalization_conflicts"].([]domain.ConflictResolution); ok && len(conflicts) > 0 && !quiet {
+				ui.Printf("\n🔤 Same names in another Unicode normalization (%d total):\n", len(conflicts))
+				for i, conflict := range conflicts {
+					if i >= 10 {
+						ui.Printf("  ... and %d more\n", len(conflicts)-10)
+						break
+					}
+					ui.Printf("  - %s -> %s (%s)\n", conflict.SourcePath, conflict.TargetPath, conflict.Resolution)
+				}
+			}
+
+			if !quiet {
+				displayCostEstimate(result)
+				DisplayOperationWarnings(result)
+				DisplayOperationErrors(result)
+			}
 
-			// TODO: Implement consolidation logic
-			return fmt.Errorf("consolidation feature not implemented yet")
+			return warningPolicy(cmd, result)
 		},
 	}
 
 	// Add flags
 	cmd.Flags().String("dest", "", "Destination directory (required)")
 	cmd.Flags().Bool("move", false, "Move files instead of copying")
+	cmd.Flags().String("link", "", "Hardlink or reflink files on the destination filesystem instead of copying (hardlink, reflink); files on other filesystems are copied")
 	cmd.Flags().Bool("preserve-structure", false, "Preserve source directory structure")
 	cmd.Flags().String("conflict-resolution", "skip", "How to handle conflicts (skip, overwrite, rename)")
 	cmd.Flags().Bool("dry-run", false, "Preview changes without executing them")
+	cmd.Flags().StringSlice("exclude", []string{".DS_Store", "Thumbs.db"}, "Patterns to exclude")
+	cmd.Flags().Int("source-concurrency", 2, "Number of parallel readers per source")
+	cmd.Flags().Int("dest-concurrency", 2, "Maximum number of concurrent writes to the destination")
+	cmd.Flags().String("space-check", "refuse", "Destination free space preflight (refuse, warn, off)")
 
 	// Mark dest as required
 	_ = cmd.MarkFlagRequired("dest")
@@ -0,0 +1,310 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/encryption"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// passphraseEnv holds the passphrase when no key file is given
+const passphraseEnv = "FILEOPS_PASSPHRASE"
+
+// NewEncryptCommand creates the encrypt command
+func NewEncryptCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "encrypt [path...]",
+		Short: "Encrypt files with AES-256-GCM",
+		Long: `Encrypt files under the given paths into <name>.enc, next to the originals or,
+with --dest, into a destination that mirrors the directory structure.
+
+The key comes from --key-file (any file of at least 16 random bytes; create one
+with --generate-key), --passphrase-file, the FILEOPS_PASSPHRASE environment
+variable, or an interactive prompt. Passphrases are stretched with scrypt once
+per run, and every file gets its own key derived from the result. Content is
+encrypted in authenticated chunks, so tampering and truncation are
+detected on decryption.
+
+Files already ending in .enc are skipped. Originals are kept unless
+--remove-source is given. Encrypt before consolidating into cloud storage:
+
+  fileops encrypt ~/Documents/tax --key-file ~/.fileops/key --dest ~/Dropbox/tax`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCrypt(ctx, cmd, args, cfg, log, domain.OperationEncrypt)
+		},
+	}
+
+	addCryptFlags(cmd)
+	cmd.Flags().Bool("generate-key", false, "Create the --key-file with a new random key if it does not exist")
+
+	return cmd
+}
+
+// NewDecryptCommand creates the decrypt command
+func NewDecryptCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "decrypt [path...]",
+		Short: "Decrypt files written by encrypt",
+		Long: `Decrypt the .enc files under the given paths, next to the encrypted files or,
+with --dest, into a destination that mirrors the directory structure.
+
+The key is given the same way as for encrypt. A wrong key or a modified file is
+reported per file and nothing is written for it. Existing files are never
+overwritten.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCrypt(ctx, cmd, args, cfg, log, domain.OperationDecrypt)
+		},
+	}
+
+	addCryptFlags(cmd)
+
+	return cmd
+}
+
+// addCryptFlags adds the flags shared by encrypt and decrypt
+func addCryptFlags(cmd *cobra.Command) {
+	cmd.Flags().String("key-file", "", "Key file")
+	cmd.Flags().String("passphrase-file", "", "File whose first line is the passphrase")
+	cmd.Flags().String("dest", "", "Destination directory (default: next to each file)")
+	cmd.Flags().Bool("remove-source", false, "Delete each source file after it was processed successfully")
+	cmd.Flags().Bool("dry-run", false, "Preview which files would be written")
+	cmd.Flags().StringSlice("exclude", []string{".git", "node_modules"}, "Patterns to exclude")
+}
+
+// runCrypt runs an encryption or decryption operation
+func runCrypt(ctx context.Context, cmd *cobra.Command, args []string, cfg *config.Config, log *logger.Logger, operationType domain.OperationType) error {
+	// Get flags
+	keyFile, _ := cmd.Flags().GetString("key-file")
+	passphraseFile, _ := cmd.Flags().GetString("passphrase-file")
+	destination, _ := cmd.Flags().GetString("dest")
+	removeSource, _ := cmd.Flags().GetBool("remove-source")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+	generateKey, _ := cmd.Flags().GetBool("generate-key")
+	encrypt := operationType == domain.OperationEncrypt
+
+	// Get quiet flag from root command
+	quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+	// Validate paths
+	validPaths := make([]string, 0, len(args))
+	for _, path := range args {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("invalid path %s: %w", path, err)
+		}
+		if _, err := os.Stat(absPath); os.IsNotExist(err) {
+			return fmt.Errorf("path does not exist: %s", absPath)
+		}
+		validPaths = append(validPaths, absPath)
+	}
+
+	settings := map[string]interface{}{
+		"remove_source": removeSource,
+	}
+	if destination != "" {
+		absDestination, err := filepath.Abs(destination)
+		if err != nil {
+			return fmt.Errorf("invalid destination %s: %w", destination, err)
+		}
+		settings["destination"] = absDestination
+	}
+
+	// Resolve the key source; only references to secrets go into the settings
+	switch {
+	case keyFile != "":
+		absKeyFile, err := filepath.Abs(keyFile)
+		if err != nil {
+			return fmt.Errorf("invalid key file %s: %w", keyFile, err)
+		}
+		if generateKey {
+			if err := encryption.GenerateKeyFile(absKeyFile); err == nil {
+				if !quiet {
+					ui.Printf("🔑 Generated key file %s - keep a backup, files cannot be decrypted without it\n", absKeyFile)
+				}
+			} else if !errors.Is(err, os.ErrExist) {
+				return fmt.Errorf("failed to generate key file: %w", err)
+			}
+		}
+		settings["key_file"] = absKeyFile
+	case generateKey:
+		return fmt.Errorf("--generate-key requires --key-file")
+	case passphraseFile != "":
+		absPassphraseFile, err := filepath.Abs(passphraseFile)
+		if err != nil {
+			return fmt.Errorf("invalid passphrase file %s: %w", passphraseFile, err)
+		}
+		settings["passphrase_file"] = absPassphraseFile
+	case os.Getenv(passphraseEnv) == "":
+		passphrase, err := promptPassphrase(encrypt)
+		if err != nil {
+			return err
+		}
+		os.Setenv(passphraseEnv, passphrase)
+	}
+
+	// Create operation configuration
+	config := domain.OperationConfig{
+		DryRun:          dryRun,
+		Recursive:       true,
+		ExcludePatterns: excludePatterns,
+		TargetPaths:     validPaths,
+		CustomSettings:  settings,
+	}
+	if err := applyGlobalFlags(cmd, &config); err != nil {
+		return err
+	}
+
+	// Create engine
+	tracker := progress.NewTracker()
+	operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+	if err != nil {
+		return err
+	}
+
+	label := "Decryption"
+	if encrypt {
+		label = "Encryption"
+	}
+
+	log.Info("🔐 Starting "+strings.ToLower(label),
+		"paths", validPaths,
+		"destination", destination,
+		"dry_run", dryRun)
+
+	// Show initial status
+	if !quiet {
+		ui.Printf("🔍 Scanning files...\n")
+		if dryRun {
+			ui.Printf("📋 DRY RUN MODE: No files will be written\n")
+		}
+		ui.Printf("📂 Paths to process: %v\n", validPaths)
+		if destination != "" {
+			ui.Printf("🎯 Destination: %s\n", settings["destination"])
+		}
+		fmt.Fprintln(ui.Stdout)
+	}
+
+	// Estimate large runs and ask before changing anything
+	if !confirmLargeOperation(cmd, cfg, operationEngine, operationType, config) {
+		ui.Printf("Nothing was changed\n")
+		return nil
+	}
+
+	// Pre-generate operation ID for progress monitoring
+	operationID := engine.NewOperationID(operationType)
+
+	// Start progress monitoring in a separate goroutine BEFORE starting operation
+	progressCtx, progressCancel := context.WithCancel(ctx)
+	defer progressCancel()
+
+	var progressWg sync.WaitGroup
+	if !quiet && cfg.Operations.EnableProgressBar {
+		progressWg.Add(1)
+		go func() {
+			defer progressWg.Done()
+			MonitorProgress(progressCtx, tracker, operationID, string(operationType))
+		}()
+		// Give the monitor a moment to start
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// Execute operation with predefined ID so progress monitoring works
+	result, err := operationEngine.ExecuteOperationWithID(ctx, operationType, config, operationID)
+
+	// Stop progress monitoring
+	progressCancel()
+	progressWg.Wait()
+
+	if err != nil {
+		if !quiet {
+			ui.Printf("\n❌ %s failed: %v\n", label, err)
+			displayStoredResultHint(cfg, operationID)
+		}
+		return fmt.Errorf("%s failed: %w", strings.ToLower(label), err)
+	}
+
+	// Display results
+	if !quiet {
+		ui.Printf("\n\n✅ %s completed!\n", label)
+		if result.Summary != "" {
+			ui.Printf("📊 %s\n", result.Summary)
+		}
+		ui.Printf("⏱️  Total time: %v\n", result.Duration.Round(time.Millisecond))
+
+		if dryRun {
+			listFiles(result, "processed", "📄 Files that would be written")
+		}
+
+		DisplayOperationWarnings(result)
+		DisplayOperationErrors(result)
+	}
+
+	log.Info("✅ "+label+" completed", "summary", result.Summary)
+	return warningPolicy(cmd, result)
+}
+
+// promptPassphrase asks for the passphrase on the terminal, twice when encrypting
+func promptPassphrase(confirm bool) (string, error) {
+	ui.Fprintf(os.Stderr, "🔑 Passphrase: ")
+	passphrase, err := readSecret()
+	fmt.Fprintln(ui.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("passphrase must not be empty")
+	}
+
+	if confirm {
+		ui.Fprintf(os.Stderr, "🔑 Confirm passphrase: ")
+		again, err := readSecret()
+		fmt.Fprintln(ui.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		if again != passphrase {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
+
+// readLine reads one line from standard input without buffering ahead, so
+// consecutive prompts each get their own line
+func readLine() (string, error) {
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if n == 1 {
+			if buf[0] == '\n' {
+				break
+			}
+			line = append(line, buf[0])
+		}
+		if err == io.EOF && len(line) > 0 {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimRight(string(line), "\r"), nil
+}
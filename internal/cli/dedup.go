@@ -5,15 +5,15 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"runtime"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/a4abhishek/fileops/internal/config"
 	"github.com/a4abhishek/fileops/internal/engine"
 	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
 	"github.com/a4abhishek/fileops/pkg/domain"
-	"github.com/a4abhishek/fileops/pkg/filesystem"
 	"github.com/a4abhishek/fileops/pkg/progress"
 	"github.com/spf13/cobra"
 )
@@ -21,15 +21,90 @@ import (
 // NewDedupCommand creates the dedup command
 func NewDedupCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "dedup [path...]",
+		Use:   "dedup [[label=]path...]",
 		Short: "Find and remove duplicate files",
 		Long: `Find and optionally remove duplicate files using advanced hashing algorithms.
 
 This command uses a multi-stage approach for fast and accurate duplicate detection:
 1. Group files by size (instant)
-2. Compute fast hash for size-matching files (xxHash64)
+2. Sample the start, middle and end of large size-matching files (xxHash64)
+   and skip every file whose samples no other file shares; the samples of
+   unchanged files are reused from the previous run (see --no-prefilter)
 3. Compute cryptographic hash for verification (Blake2b/SHA256)
-4. Optional byte-by-byte comparison for absolute certainty`,
+4. Optional byte-by-byte comparison for absolute certainty (--verify)
+
+Every duplicate group has a confidence: low when its files only share their
+size (--size-only, a quick first look), medium for a fast hash (xxhash64,
+crc32), high for a cryptographic hash and certain once compared byte by byte.
+Linking and removing copies requires --min-confidence (default
+operations.min_confidence, medium); copies matched with less are kept:
+
+  fileops dedup /backups --mode hardlink --verify --min-confidence certain
+
+With --mode hardlink identical files are replaced by hardlinks to a single
+copy, turning dated backup folders (e.g. rsync snapshots) into a hardlink farm:
+
+  fileops dedup /backups/2024-01-* /backups/2024-02-* --mode hardlink
+
+The copy that is kept (and the others are linked to) is the one with the
+most hardlinks; --keep oldest, newest or shortest (path) choose differently.
+--prefer-dir keeps copies under curated directories first, in the order
+given, and --keep decides between copies under the same directory:
+
+  fileops dedup /archive /downloads --mode hardlink --prefer-dir /archive --keep oldest
+
+Files listed in a pin file (--pins, default operations.pin_file) are never
+deleted or replaced, whatever group they fall in. It lists one path,
+directory, glob pattern or content hash (e.g. sha256:9f86d0...) per line.
+Files whose extension is set to keep (--extension raw=keep, or
+operations.extensions) are never deleted or replaced either, and those set to
+skip are not compared at all.
+
+Only files on the same filesystem with the same permissions, owner and
+modification time are linked (see --ignore-metadata), and every pair is
+compared byte by byte before a copy is replaced. The reclaimed space counts
+copies whose last link was replaced.
+
+With --mode names files named like copies ("photo (1).jpg", "report - Copy.docx",
+"IMG_1234 2.HEIC", "Copy of notes.txt") are paired with the original next to
+them, even when their content differs slightly (re-encoded images, small
+edits). Pairs below --threshold (default 0.8 in this mode) are ignored; with
+--remove-copies byte-identical copies are deleted, except halves of RAW+JPEG
+pairs (IMG_0042.CR2 next to IMG_0042.JPG) and copies with sidecars (XMP, AAE,
+subtitles, ...) unless --delete-sidecars removes those along with them:
+
+  fileops dedup ~/Pictures ~/Documents --mode names
+
+Paths may be labeled, e.g. with the machine or drive they belong to. Reports
+then say where the copies of each duplicate group exist, and --sync-plan lists
+the files of one label whose content the other label has nowhere, with the
+place to copy them to:
+
+  fileops dedup laptop=/home/me/Photos nas=/mnt/nas/Photos --sync-plan laptop:nas
+
+With --mail the attachments inside mbox and EML archives are compared too,
+without extracting them. They are named after their archive and message,
+e.g. inbox.mbox#12/invoice.pdf, and reported only; use "fileops mail
+extract" to pull them out:
+
+  fileops dedup ~/Documents ~/Mail --mail
+
+With --compressed files are also compared with their compressed variant next
+to them (file.txt with file.txt.gz, .zst, .xz or .bz2), by hashing the
+decompressed stream; nothing is written to disk. xz files need the xz
+command. Matches are reported apart from the duplicate groups, and
+--remove-variants deletes the variant --keep-variant does not keep (the
+compressed one by default, i.e. the plain file is removed) after comparing
+them byte by byte:
+
+  fileops dedup ~/exports --compressed --remove-variants --dry-run
+
+--groups lists the duplicate groups as a table; --columns, --sort and
+--no-trunc shape it. Piped, the output is the groups alone as tab-separated
+values, with sizes in bytes:
+
+  fileops dedup ~/Pictures --groups --sort -saveable
+  fileops dedup ~/Pictures --groups --columns saveable,path | sort -rn | head`,
 		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Get flags
@@ -40,10 +115,39 @@ This command uses a multi-stage approach for fast and accurate duplicate detecti
 			minSize, _ := cmd.Flags().GetInt64("min-size")
 			maxSize, _ := cmd.Flags().GetInt64("max-size")
 			parallelism, _ := cmd.Flags().GetInt("parallelism")
+			mode, _ := cmd.Flags().GetString("mode")
+			ignoreMetadata, _ := cmd.Flags().GetBool("ignore-metadata")
+			removeCopies, _ := cmd.Flags().GetBool("remove-copies")
+			skipBuildOutputs, _ := cmd.Flags().GetBool("skip-build-outputs")
+			noPrefilter, _ := cmd.Flags().GetBool("no-prefilter")
+			syncPlan, _ := cmd.Flags().GetString("sync-plan")
+			keep, _ := cmd.Flags().GetString("keep")
+			preferDirs, _ := cmd.Flags().GetStringSlice("prefer-dir")
+			pinFile, _ := cmd.Flags().GetString("pins")
+			mailArchives, _ := cmd.Flags().GetBool("mail")
+			compressed, _ := cmd.Flags().GetBool("compressed")
+			keepVariant, _ := cmd.Flags().GetString("keep-variant")
+			removeVariants, _ := cmd.Flags().GetBool("remove-variants")
+			listGroups, _ := cmd.Flags().GetBool("groups")
+			verify, _ := cmd.Flags().GetBool("verify")
+			sizeOnly, _ := cmd.Flags().GetBool("size-only")
+			minConfidence, _ := cmd.Flags().GetString("min-confidence")
+			if _, err := domain.ParseConfidenceLevel(minConfidence); err != nil {
+				return fmt.Errorf("invalid --min-confidence: %w", err)
+			}
+			syncFrom, syncTo, _ := strings.Cut(syncPlan, ":")
+			if syncPlan != "" && (syncFrom == "" || syncTo == "") {
+				return fmt.Errorf("invalid --sync-plan %q, expected from:to labels such as laptop:nas", syncPlan)
+			}
+			if mode == engine.DedupModeNames && !cmd.Flags().Changed("threshold") {
+				threshold = 0.8 // Copies are often re-encoded or edited
+			}
 
 			// Validate paths
 			validPaths := make([]string, 0, len(args))
-			for _, path := range args {
+			rootLabels := make(map[string]string)
+			for _, arg := range args {
+				label, path := ParseRootLabel(arg)
 				absPath, err := filepath.Abs(path)
 				if err != nil {
 					return fmt.Errorf("invalid path %s: %w", path, err)
@@ -52,6 +156,26 @@ This command uses a multi-stage approach for fast and accurate duplicate detecti
 					return fmt.Errorf("path does not exist: %s", absPath)
 				}
 				validPaths = append(validPaths, absPath)
+				if label != "" {
+					rootLabels[absPath] = label
+				}
+			}
+
+			if pinFile != "" {
+				absPinFile, err := filepath.Abs(pinFile)
+				if err != nil {
+					return fmt.Errorf("invalid pin file %s: %w", pinFile, err)
+				}
+				pinFile = absPinFile
+			}
+
+			absPreferDirs := make([]string, 0, len(preferDirs))
+			for _, dir := range preferDirs {
+				absDir, err := filepath.Abs(dir)
+				if err != nil {
+					return fmt.Errorf("invalid preferred directory %s: %w", dir, err)
+				}
+				absPreferDirs = append(absPreferDirs, absDir)
 			}
 
 			// Create operation configuration
@@ -59,52 +183,117 @@ This command uses a multi-stage approach for fast and accurate duplicate detecti
 				DryRun:              dryRun,
 				Recursive:           true,
 				ExcludePatterns:     excludePatterns,
-				IncludePatterns:     validPaths,
+				TargetPaths:         validPaths,
+				RootLabels:          rootLabels,
 				HashAlgorithm:       algorithm,
 				SimilarityThreshold: threshold,
 				MinFileSize:         minSize,
 				MaxFileSize:         maxSize,
 				Parallelism:         parallelism,
+				CustomSettings: map[string]interface{}{
+					"mode":                mode,
+					"respect_metadata":    !ignoreMetadata,
+					"remove_copies":       removeCopies,
+					"skip_build_outputs":  skipBuildOutputs,
+					"prefilter":           !noPrefilter,
+					"cache_directory":     cfg.Operations.CacheDirectory,
+					"sync_from":           syncFrom,
+					"sync_to":             syncTo,
+					"keep":                keep,
+					"prefer_dirs":         absPreferDirs,
+					"pin_file":            pinFile,
+					"mail_archives":       mailArchives,
+					"compressed_variants": compressed || removeVariants,
+					"keep_variant":        keepVariant,
+					"remove_variants":     removeVariants,
+					"verify":              verify,
+					"size_only":           sizeOnly,
+					"min_confidence":      minConfidence,
+				},
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+			if err := applyExtensions(cmd, cfg, &config); err != nil {
+				return err
 			}
 
 			// Create engine
-			chunkSize := ParseSize(cfg.Performance.ChunkSize, 64*1024*1024)
-			fs := filesystem.NewOSFileSystem(chunkSize)
 			tracker := progress.NewTracker()
-			operationEngine := engine.NewEngine(fs, tracker, log)
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
 
-			// Get quiet flag from root command
+			// Get quiet flag from root command; piped, --groups prints the
+			// groups alone
 			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+			options := tableFlags(cmd)
+			if listGroups && options.TSV {
+				quiet = true
+			}
 
 			log.Info("🔍 Starting deduplication",
 				"paths", validPaths,
 				"algorithm", algorithm,
 				"threshold", threshold,
+				"mode", mode,
 				"dry_run", dryRun)
 
 			// Show initial status
 			if !quiet {
-				fmt.Printf("🔍 Starting file deduplication...\n")
+				ui.Printf("🔍 Starting file deduplication...\n")
 				if dryRun {
-					fmt.Printf("📋 DRY RUN MODE: No files will be deleted\n")
+					ui.Printf("📋 DRY RUN MODE: No files will be changed\n")
+				}
+				if mode == "hardlink" {
+					ui.Printf("🔗 Mode: replace duplicates by hardlinks\n")
+				}
+				if mode == engine.DedupModeNames {
+					ui.Printf("🏷️  Mode: pair copies with their originals by name\n")
+				}
+				ui.Printf("📂 Paths to scan: %v\n", validPaths)
+				if sizeOnly {
+					ui.Printf("📏 Matching by size alone (low confidence)\n")
+				} else {
+					ui.Printf("🔢 Hash algorithm: %s\n", algorithm)
+				}
+				if verify {
+					ui.Printf("🔬 Comparing duplicates byte by byte\n")
+				}
+				ui.Printf("📊 Similarity threshold: %.2f\n", threshold)
+				if pinFile != "" {
+					ui.Printf("📌 Pin file: %s\n", pinFile)
+				}
+				if mailArchives {
+					ui.Printf("📧 Comparing attachments in mail archives\n")
+				}
+				if compressed || removeVariants {
+					ui.Printf("🗜️  Comparing files with their compressed variants (keeping the %s one)\n", keepVariant)
+				}
+				if len(absPreferDirs) > 0 {
+					ui.Printf("⭐ Keeping copies under: %v, then by %s\n", absPreferDirs, keep)
 				}
-				fmt.Printf("📂 Paths to scan: %v\n", validPaths)
-				fmt.Printf("🔢 Hash algorithm: %s\n", algorithm)
-				fmt.Printf("📊 Similarity threshold: %.2f\n", threshold)
 				if len(excludePatterns) > 0 {
-					fmt.Printf("🚫 Excluding patterns: %v\n", excludePatterns)
+					ui.Printf("🚫 Excluding patterns: %v\n", excludePatterns)
 				}
 				if minSize > 0 {
-					fmt.Printf("📏 Minimum file size: %s\n", FormatBytes(minSize))
+					ui.Printf("📏 Minimum file size: %s\n", FormatBytes(minSize))
 				}
 				if maxSize > 0 {
-					fmt.Printf("📏 Maximum file size: %s\n", FormatBytes(maxSize))
+					ui.Printf("📏 Maximum file size: %s\n", FormatBytes(maxSize))
 				}
-				fmt.Printf("⚡ Using %d parallel workers\n\n", parallelism)
+				ui.Printf("⚡ Using %d parallel workers\n\n", parallelism)
+			}
+
+			// Estimate large runs and ask before changing anything
+			if (mode == "hardlink" || removeCopies || removeVariants) && !confirmLargeOperation(cmd, cfg, operationEngine, domain.OperationDeduplication, config) {
+				ui.Printf("Nothing was changed\n")
+				return nil
 			}
 
 			// Pre-generate operation ID for progress monitoring
-			operationID := fmt.Sprintf("deduplication-%s", time.Now().Format("20060102-150405"))
+			operationID := engine.NewOperationID(domain.OperationDeduplication)
 
 			// Start progress monitoring in a separate goroutine BEFORE starting operation
 			progressCtx, progressCancel := context.WithCancel(ctx)
@@ -130,50 +319,257 @@ This command uses a multi-stage approach for fast and accurate duplicate detecti
 
 			if err != nil {
 				if !quiet {
-					fmt.Printf("\n❌ Deduplication operation failed: %v\n", err)
+					ui.Printf("\n❌ Deduplication operation failed: %v\n", err)
+					displayStoredResultHint(cfg, operationID)
 				}
 				return fmt.Errorf("deduplication operation failed: %w", err)
 			}
 
 			// Display results
 			if !quiet {
-				fmt.Printf("\n\n✅ Deduplication completed successfully!\n")
+				ui.Printf("\n\n✅ Deduplication completed successfully!\n")
 
 				// Show timing information
 				duration := result.EndTime.Sub(result.StartTime)
-				fmt.Printf("⏱️  Total time: %v\n\n", duration.Round(time.Millisecond))
+				ui.Printf("⏱️  Total time: %v\n\n", duration.Round(time.Millisecond))
 
-				fmt.Printf("📊 Deduplication Results:\n")
-				fmt.Printf("  🔢 Algorithm: %s\n", algorithm)
-				fmt.Printf("  📊 Threshold: %.2f\n", threshold)
+				ui.Printf("📊 Deduplication Results:\n")
+				ui.Printf("  🔢 Algorithm: %s\n", algorithm)
+				ui.Printf("  📊 Threshold: %.2f\n", threshold)
 			}
 
 			log.Info("✅ Deduplication completed", "summary", result.Summary)
 
 			if duplicateGroups, ok := result.Details["duplicate_groups"].(int); ok && !quiet {
-				fmt.Printf("  🔍 Duplicate groups found: %d\n", duplicateGroups)
+				ui.Printf("  🔍 Duplicate groups found: %d\n", duplicateGroups)
 			}
 
 			if totalSize, ok := result.Details["total_size"].(int64); ok && !quiet {
-				fmt.Printf("  📦 Total size processed: %s\n", FormatBytes(totalSize))
+				ui.Printf("  📦 Total size processed: %s\n", FormatBytes(totalSize))
 			}
 
-			if saveableSize, ok := result.Details["saveable_size"].(int64); ok {
-				fmt.Printf("  Space that can be saved: %s\n", FormatBytes(saveableSize))
+			if skipped, ok := result.Details["prefilter_skipped"].(int); ok && skipped > 0 && !quiet {
+				ui.Printf("  ⚡ Candidates skipped by the pre-filter: %d\n", skipped)
 			}
 
-			return nil
+			if saveableSize, ok := result.Details["saveable_size"].(int64); ok && !quiet {
+				ui.Printf("  Space that can be saved: %s\n", FormatBytes(saveableSize))
+			}
+
+			if confidence, ok := result.Details["confidence"].(float64); ok && !quiet {
+				ui.Printf("  🎯 Confidence: %s\n", domain.ConfidenceLevel(confidence))
+			}
+
+			if unsure, ok := result.Details["below_confidence"].(domain.PathList); ok && unsure.Count > 0 && !quiet {
+				ui.Printf("  🎯 Copies kept below the minimum confidence (%s): %d\n", minConfidence, unsure.Count)
+			}
+
+			if linked, ok := result.Details["linked"].(domain.PathList); ok && mode == "hardlink" && !dryRun && !quiet {
+				ui.Printf("  🔗 Files replaced by hardlinks: %d\n", linked.Count)
+				if reclaimed, ok := result.Details["reclaimed"].(int64); ok {
+					ui.Printf("  ♻️  Space reclaimed: %s\n", FormatBytes(reclaimed))
+				}
+			}
+
+			if pinned, ok := result.Details["pinned"].(domain.PathList); ok && pinned.Count > 0 && !quiet {
+				ui.Printf("  📌 Deletions suppressed by pins: %d\n", pinned.Count)
+			}
+
+			if paired, ok := result.Details["raw_pairs"].(domain.PathList); ok && paired.Count > 0 && !quiet {
+				ui.Printf("  📷 Copies kept as half of a RAW+JPEG pair: %d\n", paired.Count)
+			}
+
+			if kept, ok := result.Details["with_sidecars"].(domain.PathList); ok && kept.Count > 0 && !quiet {
+				ui.Printf("  📎 Copies kept with their sidecars (use --delete-sidecars): %d\n", kept.Count)
+			}
+
+			if attachments, ok := result.Details["attachments"].(int); ok && !quiet {
+				ui.Printf("  📧 Attachments read from mail archives: %d\n", attachments)
+				listFiles(result, "attachments_on_disk", "📎 Attachments with a copy on disk")
+			}
+
+			if mode == engine.DedupModeNames && !quiet {
+				displayCopyPairs(result)
+			}
+
+			if !quiet {
+				displayCompressedVariants(result)
+			}
+
+			if listGroups {
+				if err := displayDuplicateGroups(result, options, quiet); err != nil {
+					return err
+				}
+			}
+
+			if len(rootLabels) > 0 && !quiet {
+				displayLabeledGroups(result)
+				displaySyncPlan(result, syncFrom, syncTo)
+			}
+
+			if !quiet {
+				displayCostEstimate(result)
+				DisplayOperationWarnings(result)
+				DisplayOperationErrors(result)
+			}
+
+			return warningPolicy(cmd, result)
 		},
 	}
 
 	// Add flags
 	cmd.Flags().Bool("dry-run", false, "Preview changes without executing them")
-	cmd.Flags().String("algorithm", "blake2b", "Hash algorithm (md5, sha1, sha256, sha512, blake2b, xxhash64, crc32)")
+	cmd.Flags().String("algorithm", cfg.Operations.HashAlgorithm, "Hash algorithm (md5, sha1, sha256, sha512, blake2b, blake3, xxhash64, crc32)")
 	cmd.Flags().Float64("threshold", 0.99, "Similarity threshold for duplicate detection (0.0-1.0)")
 	cmd.Flags().StringSlice("exclude", []string{"*.tmp", "*.log", ".DS_Store"}, "Patterns to exclude")
 	cmd.Flags().Int64("min-size", 0, "Minimum file size to process (bytes)")
 	cmd.Flags().Int64("max-size", 0, "Maximum file size to process (0 = no limit)")
-	cmd.Flags().Int("parallelism", runtime.NumCPU(), "Number of parallel workers")
+	cmd.Flags().Int("parallelism", cfg.Performance.MaxWorkers, "Number of parallel workers")
+	cmd.Flags().String("mode", "report", "What to do with duplicates (report, hardlink, names)")
+	cmd.Flags().Bool("ignore-metadata", false, "Hardlink identical files even if permissions, owner or modification time differ")
+	cmd.Flags().Bool("remove-copies", false, "In names mode, delete copies that are identical to their original")
+	cmd.Flags().Bool("no-prefilter", false, "Hash every file that shares its size with another instead of sampling large files first")
+	cmd.Flags().String("keep", engine.DedupKeepLinks, "Which copy of a duplicate group to keep (links, oldest, newest, shortest)")
+	cmd.Flags().StringSlice("prefer-dir", []string{}, "Keep copies under these directories first, in the order given (repeatable)")
+	cmd.Flags().String("pins", cfg.Operations.PinFile, "File of paths, patterns and hashes (sha256:<hex>) that are never deleted or replaced")
+	cmd.Flags().StringSlice("extension", []string{}, "Files to ignore (skip) or never delete or replace (keep) by extension (pattern=action, e.g. raw=skip; repeatable, adds to operations.extensions)")
+	cmd.Flags().Bool("mail", false, "Also compare the attachments inside mbox and EML archives with the files (report mode only)")
+	cmd.Flags().Bool("compressed", false, "Also find files identical to their compressed variant (file.txt and file.txt.gz, .zst, .xz, .bz2)")
+	cmd.Flags().String("keep-variant", engine.DedupVariantKeepCompressed, "Which of a file and its compressed variant to keep (compressed, plain)")
+	cmd.Flags().Bool("remove-variants", false, "Delete the variant --keep-variant does not keep (implies --compressed)")
+	cmd.Flags().String("sync-plan", "", "List files of one labeled path missing on another, e.g. laptop:nas")
+	cmd.Flags().Bool("skip-build-outputs", false, "Skip build outputs of recognized projects (node_modules, target, dist, ...)")
+	cmd.Flags().Bool("groups", false, "List the duplicate groups")
+	cmd.Flags().Bool("verify", false, "Compare duplicates byte by byte, for certain confidence")
+	cmd.Flags().Bool("size-only", false, "Match files by size alone without reading them (report mode only, low confidence)")
+	cmd.Flags().String("min-confidence", cfg.Operations.MinConfidence, "Least confidence of a match to link or remove copies (low, medium, high, certain)")
+	addTableFlags(cmd, groupColumns...)
 
 	return cmd
 }
+
+// groupColumns are the columns of the list of duplicate groups
+var groupColumns = []tableColumn{
+	idColumn("group"),
+	numberColumn("files"),
+	sizeColumn("size"),
+	sizeColumn("saveable"),
+	textColumn("confidence"),
+	pathColumn("path"),
+}
+
+// displayDuplicateGroups lists the duplicate groups, one row per group with
+// the path of its first file. Quiet, only the table is written, as TSV when
+// piped.
+func displayDuplicateGroups(result *domain.OperationResult, options tableOptions, quiet bool) error {
+	groups, _ := result.Details["groups"].([]domain.DuplicateGroup)
+	list := newTable(groupColumns...)
+	for _, group := range groups {
+		list.addRow(group.ID, len(group.Files), group.Files[0].Size, group.SaveablSize, domain.ConfidenceLevel(group.Confidence), group.Files[0].Path)
+	}
+	if !quiet {
+		if len(groups) == 0 {
+			return nil
+		}
+		ui.Printf("\n🔍 Duplicate groups:\n")
+		options.Limit = 50
+	}
+	return list.render(ui.Stdout, options)
+}
+
+// displayCompressedVariants lists the files found identical to their
+// compressed variant
+func displayCompressedVariants(result *domain.OperationResult) {
+	variants, _ := result.Details["compressed_variants"].([]engine.CompressedVariant)
+	if len(variants) == 0 {
+		return
+	}
+	saveable, _ := result.Details["variant_saveable_size"].(int64)
+	ui.Printf("\n🗜️  Files identical to their compressed variant (%d, %s in the variants not kept):\n", len(variants), FormatBytes(saveable))
+	for _, variant := range variants {
+		status := "keep " + filepath.Base(variant.Keep)
+		if variant.Removed {
+			status = "kept " + filepath.Base(variant.Keep) + ", other removed"
+		}
+		ui.Printf("  %s ⇔ %s (%s → %s, %s)\n", variant.Plain, filepath.Base(variant.Compressed),
+			FormatBytes(variant.Size), FormatBytes(variant.CompressedSize), status)
+	}
+	if linked, ok := result.Details["variants_linked"].(domain.PathList); ok && linked.Count > 0 {
+		ui.Printf("  🔗 Not removed because other hardlinks keep their content: %d\n", linked.Count)
+	}
+}
+
+// displayCopyPairs lists the copies found in names mode with their originals
+func displayCopyPairs(result *domain.OperationResult) {
+	pairs, _ := result.Details["copy_pairs"].([]engine.CopyPair)
+	if len(pairs) > 0 {
+		ui.Printf("\n🏷️  Copies and their originals:\n")
+	}
+	for _, pair := range pairs {
+		ui.Printf("  %s\n    ← %s (%s, %s)\n", pair.Original, filepath.Base(pair.Copy), pair.Pattern, describeCopy(pair))
+		if !pair.Identical {
+			ui.Printf("    💡 Keep %s: %s\n", filepath.Base(pair.Keep), pair.Reason)
+		}
+	}
+	if removed, ok := result.Details["removed"].([]string); ok && len(removed) > 0 {
+		ui.Printf("  🗑️  Identical copies removed: %d\n", len(removed))
+		if reclaimed, ok := result.Details["reclaimed"].(int64); ok {
+			ui.Printf("  ♻️  Space reclaimed: %s\n", FormatBytes(reclaimed))
+		}
+	}
+	if screenshots, ok := result.Details["screenshots"].([]string); ok && len(screenshots) > 0 {
+		ui.Printf("  📸 Screenshots found: %d\n", len(screenshots))
+	}
+}
+
+// describeCopy describes how a copy compares to its original
+func describeCopy(pair engine.CopyPair) string {
+	if pair.Identical {
+		return "identical, " + domain.ConfidenceLevel(pair.Confidence) + " confidence"
+	}
+	return fmt.Sprintf("%.0f%% similar by %s", pair.Similarity*100, pair.Method)
+}
+
+// displayLabeledGroups lists the duplicate groups whose copies exist under
+// more than one label
+func displayLabeledGroups(result *domain.OperationResult) {
+	groups, _ := result.Details["groups"].([]domain.DuplicateGroup)
+	shown := 0
+	for _, group := range groups {
+		if len(group.Labels) < 2 {
+			continue
+		}
+		if shown == 0 {
+			ui.Printf("\n📍 Duplicates on several labeled paths:\n")
+		}
+		if shown++; shown > 20 {
+			continue // Counted below
+		}
+		labels := strings.Join(group.Labels[:len(group.Labels)-1], ", ") + " and " + group.Labels[len(group.Labels)-1]
+		ui.Printf("  %s (%s) exists on %s\n", group.Files[0].Name, FormatBytes(group.Files[0].Size), labels)
+	}
+	if shown > 20 {
+		ui.Printf("  ... and %d more groups\n", shown-20)
+	}
+}
+
+// displaySyncPlan lists the files one label would have to send to the other
+func displaySyncPlan(result *domain.OperationResult, from, to string) {
+	plan, ok := result.Details["sync_plan"].([]engine.SyncPlanEntry)
+	if !ok {
+		return
+	}
+	size, _ := result.Details["sync_size"].(int64)
+	if len(plan) == 0 {
+		ui.Printf("\n🔄 Everything on %s already exists on %s\n", from, to)
+		return
+	}
+	ui.Printf("\n🔄 Sync plan %s → %s: %d files, %s\n", from, to, len(plan), FormatBytes(size))
+	for i, entry := range plan {
+		if i >= 20 {
+			ui.Printf("  ... and %d more files (see the stored result for the full plan)\n", len(plan)-20)
+			break
+		}
+		ui.Printf("  %s → %s\n", entry.Source, entry.Target)
+	}
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// applyCommandDefaults sets the flags of cmd that were not given on the
+// command line to the defaults configured for it under commands, keyed by
+// the command path: commands.dedup.exclude, commands.pipeline.run.dry_run.
+// Flags set this way count as given, like flags of the command line.
+func applyCommandDefaults(cmd *cobra.Command, commands map[string]interface{}) error {
+	defaults, key := commandDefaults(cmd, commands)
+	if len(defaults) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(defaults))
+	for name := range defaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := defaults[name]
+		if _, nested := value.(map[string]interface{}); nested {
+			continue // Defaults of a subcommand
+		}
+		flagName := strings.ReplaceAll(name, "_", "-")
+		flag := cmd.Flags().Lookup(flagName)
+		if flag == nil {
+			return fmt.Errorf("invalid commands.%s.%s in the configuration: %s has no --%s flag", key, name, cmd.CommandPath(), flagName)
+		}
+		if flag.Changed {
+			continue // The command line wins
+		}
+		if err := setFlagDefault(cmd.Flags(), flag, value); err != nil {
+			return fmt.Errorf("invalid commands.%s.%s in the configuration: %w", key, name, err)
+		}
+	}
+	return nil
+}
+
+// commandDefaults returns the configured defaults of cmd and their key
+func commandDefaults(cmd *cobra.Command, commands map[string]interface{}) (map[string]interface{}, string) {
+	var path []string
+	for c := cmd; c.HasParent(); c = c.Parent() {
+		path = append([]string{c.Name()}, path...)
+	}
+	if len(path) == 0 {
+		return nil, ""
+	}
+
+	defaults := commands
+	for _, name := range path {
+		next, ok := defaults[name].(map[string]interface{})
+		if !ok {
+			return nil, ""
+		}
+		defaults = next
+	}
+	return defaults, strings.Join(path, ".")
+}
+
+// setFlagDefault sets a flag to a configured value: a scalar, or a list for
+// flags that take several values
+func setFlagDefault(flags *pflag.FlagSet, flag *pflag.Flag, value interface{}) error {
+	if list, ok := value.([]interface{}); ok {
+		values := make([]string, len(list))
+		for i, item := range list {
+			values[i] = fmt.Sprint(item)
+		}
+		slice, ok := flag.Value.(pflag.SliceValue)
+		if !ok {
+			return fmt.Errorf("--%s takes a single value, not a list", flag.Name)
+		}
+		if err := slice.Replace(values); err != nil {
+			return err
+		}
+		flag.Changed = true
+		return nil
+	}
+	return flags.Set(flag.Name, fmt.Sprint(value))
+}
@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+	"github.com/spf13/cobra"
+)
+
+// DoctorReport contains the environment diagnostics gathered by the doctor command
+type DoctorReport struct {
+	OS           string                     `json:"os"`
+	Arch         string                     `json:"arch"`
+	CPUs         int                        `json:"cpus"`
+	GoVersion    string                     `json:"go_version"`
+	MaxOpenFiles *OpenFileLimits            `json:"max_open_files,omitempty"`
+	Filesystems  []*filesystem.Capabilities `json:"filesystems"`
+	Hashers      []HasherBenchmark          `json:"hashers"`
+}
+
+// OpenFileLimits holds the descriptor limits of the current process
+type OpenFileLimits struct {
+	Soft   uint64 `json:"soft"`
+	Hard   uint64 `json:"hard"`
+	Budget int    `json:"budget"` // Descriptors shared by the workers of an operation
+}
+
+// HasherBenchmark holds the measured throughput of a hash algorithm
+type HasherBenchmark struct {
+	Algorithm      string  `json:"algorithm"`
+	BytesPerSecond float64 `json:"bytes_per_second"`
+	Error          string  `json:"error,omitempty"`
+}
+
+// NewDoctorCommand creates the doctor command
+func NewDoctorCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor [path...]",
+		Short: "Diagnose platform capabilities and environment",
+		Long: `Report platform capabilities that influence which fileops modes are available.
+
+For every given path (default: current directory) the filesystem is probed for
+hardlink, symlink, reflink and extended attribute support using scratch files
+in a temporary directory. The report also includes the open file descriptor
+limit and the throughput of each supported hash algorithm on this machine.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			outputFormat, _ := cmd.Flags().GetString("output")
+			skipBenchmark, _ := cmd.Flags().GetBool("skip-benchmark")
+
+			if len(args) == 0 {
+				args = []string{"."}
+			}
+
+			report := &DoctorReport{
+				OS:        runtime.GOOS,
+				Arch:      runtime.GOARCH,
+				CPUs:      runtime.NumCPU(),
+				GoVersion: runtime.Version(),
+			}
+
+			if soft, hard, err := filesystem.MaxOpenFiles(); err == nil {
+				report.MaxOpenFiles = &OpenFileLimits{Soft: soft, Hard: hard, Budget: filesystem.Descriptors().Limit()}
+			}
+
+			for _, path := range args {
+				absPath, err := filepath.Abs(path)
+				if err != nil {
+					return fmt.Errorf("invalid path %s: %w", path, err)
+				}
+				caps, err := filesystem.ProbeCapabilities(absPath)
+				if err != nil {
+					return fmt.Errorf("failed to probe %s: %w", absPath, err)
+				}
+				report.Filesystems = append(report.Filesystems, caps)
+			}
+
+			if !skipBenchmark {
+				report.Hashers = benchmarkHashers(ctx, 32*1024*1024)
+			}
+
+			log.Debug("Doctor report generated", "paths", args)
+
+			if outputFormat == "json" {
+				encoder := json.NewEncoder(ui.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(report)
+			}
+
+			displayDoctorReport(report)
+			return nil
+		},
+	}
+
+	cmd.Flags().String("output", "table", "Output format (table, json)")
+	cmd.Flags().Bool("skip-benchmark", false, "Skip the hash throughput benchmark")
+
+	return cmd
+}
+
+// benchmarkHashers measures the throughput of every supported hash algorithm over size bytes
+func benchmarkHashers(ctx context.Context, size int) []HasherBenchmark {
+	buffer := make([]byte, size)
+	for i := range buffer {
+		buffer[i] = byte(i * 31)
+	}
+
+	results := make([]HasherBenchmark, 0, len(filesystem.SupportedHashAlgorithms))
+	for _, algorithm := range filesystem.SupportedHashAlgorithms {
+		if ctx.Err() != nil {
+			break
+		}
+
+		result := HasherBenchmark{Algorithm: algorithm}
+		hasher, err := filesystem.NewHasher(algorithm)
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		start := time.Now()
+		_, _ = hasher.Write(buffer)
+		_ = hasher.Sum(nil)
+		if elapsed := time.Since(start).Seconds(); elapsed > 0 {
+			result.BytesPerSecond = float64(size) / elapsed
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// displayDoctorReport prints the doctor report in human-readable form
+func displayDoctorReport(report *DoctorReport) {
+	yesNo := func(ok bool) string {
+		if ok {
+			return "✅"
+		}
+		return "❌"
+	}
+
+	ui.Printf("🩺 FileOps environment diagnostics\n\n")
+	ui.Printf("💻 Platform: %s/%s, %d CPUs, %s\n", report.OS, report.Arch, report.CPUs, report.GoVersion)
+	if report.MaxOpenFiles != nil {
+		ui.Printf("📂 Max open files: %d (hard limit %d), %d shared by workers\n", report.MaxOpenFiles.Soft, report.MaxOpenFiles.Hard, report.MaxOpenFiles.Budget)
+	} else {
+		ui.Printf("📂 Max open files: not limited on this platform\n")
+	}
+
+	for _, caps := range report.Filesystems {
+		ui.Printf("\n📁 %s\n", caps.Path)
+		ui.Printf("  %s Writable\n", yesNo(caps.Writable))
+		ui.Printf("  %s Hardlinks\n", yesNo(caps.Hardlink))
+		ui.Printf("  %s Symlinks\n", yesNo(caps.Symlink))
+		ui.Printf("  %s Reflinks (copy-on-write clones)\n", yesNo(caps.Reflink))
+		ui.Printf("  %s Extended attributes\n", yesNo(caps.Xattr))
+		for _, note := range caps.Notes {
+			ui.Printf("  ℹ️  %s\n", note)
+		}
+	}
+
+	if len(report.Hashers) > 0 {
+		ui.Printf("\n🔢 Hash throughput:\n")
+		for _, hasher := range report.Hashers {
+			if hasher.Error != "" {
+				ui.Printf("  %-10s ❌ %s\n", hasher.Algorithm, hasher.Error)
+				continue
+			}
+			ui.Printf("  %-10s %s/sec\n", hasher.Algorithm, FormatBytes(int64(hasher.BytesPerSecond)))
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/lock"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/snapshot"
+	"github.com/a4abhishek/fileops/internal/storage"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// newOperationEngine creates an engine wired with the CLI-wide settings
+func newOperationEngine(cmd *cobra.Command, cfg *config.Config, log *logger.Logger, tracker *progress.Tracker) (*engine.Engine, error) {
+	chunkSize := ParseSize(cfg.Performance.ChunkSize, 64*1024*1024) // Default 64MB
+	osFS := filesystem.NewOSFileSystem(chunkSize)
+	if budget := memoryBudget(cfg.Performance.MemoryLimit); budget > 0 {
+		osFS.SetMemoryBudget(budget)
+	}
+	if securityLabels, _ := cmd.Root().PersistentFlags().GetBool("security-labels"); securityLabels {
+		osFS.SetPreserveSecurityLabels(true)
+	}
+	var fs domain.FileSystem = osFS
+
+	// Hidden fault injection for exercising error handling against real trees
+	if chaos, _ := cmd.Root().PersistentFlags().GetString("chaos"); chaos != "" {
+		faults, err := filesystem.ParseFaultSpec(chaos)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --chaos specification: %w", err)
+		}
+		log.Warn("Fault injection enabled", "spec", chaos)
+		fs = filesystem.NewFaultInjectingFileSystem(fs, faults)
+	}
+
+	// Retry transient failures (outermost, so injected faults are retried too)
+	if cfg.Retry.MaxAttempts > 1 {
+		policy := filesystem.DefaultRetryPolicy()
+		policy.MaxAttempts = cfg.Retry.MaxAttempts
+		if backoff, err := time.ParseDuration(cfg.Retry.InitialBackoff); err == nil {
+			policy.InitialBackoff = backoff
+		}
+		if backoff, err := time.ParseDuration(cfg.Retry.MaxBackoff); err == nil {
+			policy.MaxBackoff = backoff
+		}
+		fs = filesystem.NewRetryingFileSystem(fs, policy)
+	}
+
+	operationEngine := engine.NewEngine(fs, tracker, log)
+	operationEngine.SetEnvironment(captureEnvironment(cmd, cfg))
+	if throttle, _ := cmd.Root().PersistentFlags().GetBool("throttle"); throttle {
+		operationEngine.SetThrottle(engine.NewThrottle(engine.ThrottleLimits{
+			CPUPercent: cfg.Performance.ThrottleCPU,
+			DiskQueue:  cfg.Performance.ThrottleDiskQueue,
+		}))
+	}
+
+	if cfg.Performance.BenchProfile != "" {
+		profile, err := engine.LoadBenchProfile(cfg.Performance.BenchProfile)
+		if err != nil {
+			log.Warn("Dry runs will estimate durations without the bench profile", "error", err)
+		} else {
+			operationEngine.SetBenchProfile(profile)
+		}
+	}
+
+	if cfg.Operations.ProgressDirectory != "" {
+		if err := tracker.SetStateDirectory(cfg.Operations.ProgressDirectory); err != nil {
+			log.Warn("Progress will not be visible to other processes", "error", err)
+		}
+	}
+
+	if cfg.Operations.LockDirectory != "" {
+		lockWait, _ := cmd.Root().PersistentFlags().GetDuration("lock-wait")
+		operationEngine.SetLockManager(lock.NewManager(cfg.Operations.LockDirectory), lockWait)
+	}
+
+	snapshotter, err := snapshot.New(cfg.Snapshot.Method, cfg.Snapshot.Command)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot settings: %w", err)
+	}
+	if snapshotter != nil {
+		operationEngine.SetSnapshotter(snapshotter)
+	}
+
+	if cfg.Operations.RunsDirectory != "" {
+		operationEngine.SetRunsDirectory(cfg.Operations.RunsDirectory)
+		fsyncInterval, _ := time.ParseDuration(cfg.Performance.FsyncInterval)
+		operationEngine.SetBatching(cfg.Performance.BatchSize, fsyncInterval)
+	}
+
+	if report, _ := cmd.Root().PersistentFlags().GetString("report"); report != "" {
+		if _, err := engine.NewPathContext("", "").ExpandPath(report); err != nil {
+			return nil, fmt.Errorf("invalid --report: %w", err)
+		}
+		operationEngine.SetReportPath(report)
+	}
+
+	policy, policyFile, err := loadPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if policy != nil {
+		principal := currentPrincipal()
+		log.Debug("Operation policy in effect", "file", policyFile, "user", principal.User)
+		operationEngine.SetPolicy(policy, principal)
+	}
+
+	if cfg.Operations.ResultsDirectory != "" {
+		repository, err := storage.NewFileRepository(cfg.Operations.ResultsDirectory)
+		if err != nil {
+			log.Warn("Operation results will not be stored", "error", err)
+		} else {
+			operationEngine.SetRepository(repository)
+		}
+	}
+
+	return operationEngine, nil
+}
+
+// apiTokenVariable is the environment variable holding the API token that
+// identifies the caller to the operation policy
+const apiTokenVariable = "FILEOPS_API_TOKEN"
+
+// loadPolicy loads the system policy when it exists, which the configuration
+// of a user cannot override, or else the configured policy file. A policy
+// that cannot be read fails every operation rather than allowing all of them.
+func loadPolicy(cfg *config.Config) (*engine.Policy, string, error) {
+	path := engine.DefaultPolicyFile
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if cfg.Operations.PolicyFile == "" {
+			return nil, "", nil
+		}
+		path = cfg.Operations.PolicyFile
+	}
+	policy, err := engine.LoadPolicy(path)
+	if err != nil {
+		return nil, path, fmt.Errorf("failed to load the operation policy: %w", err)
+	}
+	return policy, path, nil
+}
+
+// currentPrincipal identifies the user running fileops and the API token
+// they present
+func currentPrincipal() engine.Principal {
+	principal := engine.Principal{Token: os.Getenv(apiTokenVariable)}
+	if current, err := user.Current(); err == nil {
+		principal.User = current.Username
+	}
+	return principal
+}
+
+// memoryBudget converts performance.memory_limit, a size or a percentage of
+// the physical memory, to bytes. 0 means unlimited.
+func memoryBudget(limit string) int64 {
+	limit = strings.TrimSpace(limit)
+	if percent, ok := strings.CutSuffix(limit, "%"); ok {
+		value, err := strconv.ParseFloat(percent, 64)
+		total, memErr := filesystem.TotalMemory()
+		if err != nil || memErr != nil || value <= 0 {
+			return 0
+		}
+		return int64(float64(total) * value / 100)
+	}
+	return ParseSize(limit, 0)
+}
+
+// applyExtensions sets the per-extension actions of operations.extensions,
+// overridden by the --extension entries of the command
+func applyExtensions(cmd *cobra.Command, cfg *config.Config, config *domain.OperationConfig) error {
+	entries, _ := cmd.Flags().GetStringSlice("extension")
+	extensions, err := domain.ParseExtensions(append(append([]string{}, cfg.Operations.Extensions...), entries...))
+	if err != nil {
+		return fmt.Errorf("invalid --extension: %w", err)
+	}
+	if len(extensions) > 0 {
+		config.Extensions = extensions
+	}
+	return nil
+}
+
+// applyGlobalFlags copies the global --max-errors, --include, --git, --unicode, --delete-sidecars and modification time settings into the operation configuration
+func applyGlobalFlags(cmd *cobra.Command, config *domain.OperationConfig) error {
+	budget, _ := cmd.Root().PersistentFlags().GetString("max-errors")
+	count, percent, err := ParseErrorBudget(budget)
+	if err != nil {
+		return err
+	}
+	if count != NoErrorBudget {
+		config.MaxErrors = &count
+	}
+	config.MaxErrorPercent = percent
+	config.RollbackOnAbort, _ = cmd.Root().PersistentFlags().GetBool("rollback")
+
+	include, _ := cmd.Root().PersistentFlags().GetStringSlice("include")
+	for _, pattern := range include {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid --include pattern %q", pattern)
+		}
+	}
+	config.IncludePatterns = append(config.IncludePatterns, include...)
+	if oneFileSystem, _ := cmd.Root().PersistentFlags().GetBool("one-file-system"); oneFileSystem {
+		config.OneFileSystem = true
+	}
+
+	config.MaxDuration, _ = cmd.Root().PersistentFlags().GetDuration("max-duration")
+	if config.MaxDuration < 0 {
+		return fmt.Errorf("invalid --max-duration value %v", config.MaxDuration)
+	}
+	config.Window, _ = cmd.Root().PersistentFlags().GetString("window")
+	if config.Window != "" {
+		if _, err := engine.ParseWindow(config.Window); err != nil {
+			return fmt.Errorf("invalid --window value: %w", err)
+		}
+	}
+
+	config.Deterministic, _ = cmd.Root().PersistentFlags().GetBool("deterministic")
+
+	gitFilter, _ := cmd.Root().PersistentFlags().GetString("git")
+	switch gitFilter {
+	case "", engine.GitProtectTracked, engine.GitUntrackedOnly, engine.GitIgnoredOnly:
+		config.GitFilter = gitFilter
+	default:
+		return fmt.Errorf("invalid --git value %q (use %s, %s or %s)", gitFilter, engine.GitProtectTracked, engine.GitUntrackedOnly, engine.GitIgnoredOnly)
+	}
+
+	unicode, _ := cmd.Root().PersistentFlags().GetString("unicode")
+	if !filesystem.ValidNormalization(unicode) {
+		return fmt.Errorf("invalid --unicode value %q (use %s, %s or %s)", unicode, filesystem.NormalizeNFC, filesystem.NormalizeNFD, filesystem.NormalizeNone)
+	}
+	config.UnicodeNormalization = unicode
+
+	granularity, _ := cmd.Root().PersistentFlags().GetString("mtime-granularity")
+	if granularity == "auto" {
+		for _, root := range config.TargetPaths {
+			config.MTimeTolerance.Granularity = max(config.MTimeTolerance.Granularity, filesystem.TimeGranularity(root))
+		}
+	} else {
+		value, err := time.ParseDuration(granularity)
+		if err != nil || value < 0 {
+			return fmt.Errorf("invalid --mtime-granularity value %q (use auto or a duration such as 2s)", granularity)
+		}
+		config.MTimeTolerance.Granularity = value
+	}
+	config.MTimeTolerance.DSTSkew, _ = cmd.Root().PersistentFlags().GetBool("dst-skew")
+	config.SecurityLabels, _ = cmd.Root().PersistentFlags().GetBool("security-labels")
+	config.DeleteSidecars, _ = cmd.Root().PersistentFlags().GetBool("delete-sidecars")
+	return nil
+}
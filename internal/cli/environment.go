@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strings"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Build information, set by main from its linker flags
+var (
+	Version   = "dev"
+	GitCommit = "unknown"
+)
+
+// redactedValue replaces the values of flags holding secrets
+const redactedValue = "[redacted]"
+
+// captureEnvironment records who runs cmd, where and with what settings
+func captureEnvironment(cmd *cobra.Command, cfg *config.Config) *domain.Environment {
+	env := &domain.Environment{
+		User:       currentPrincipal().User,
+		Version:    Version,
+		Command:    cmd.CommandPath(),
+		ConfigFile: cfg.File,
+	}
+	env.Hostname, _ = os.Hostname()
+
+	if cfg.File != "" {
+		if data, err := os.ReadFile(cfg.File); err == nil {
+			sum := sha256.Sum256(data)
+			env.ConfigHash = hex.EncodeToString(sum[:])
+		}
+	}
+
+	// Flags of the command and the inherited global flags that were set
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if !flag.Changed {
+			return
+		}
+		if env.Flags == nil {
+			env.Flags = make(map[string]string)
+		}
+		env.Flags[flag.Name] = flagValue(flag)
+	})
+	return env
+}
+
+// flagValue renders the value of a flag, lists without brackets and secrets
+// redacted
+func flagValue(flag *pflag.Flag) string {
+	if secretFlag(flag.Name) {
+		return redactedValue
+	}
+	if slice, ok := flag.Value.(pflag.SliceValue); ok {
+		return strings.Join(slice.GetSlice(), ",")
+	}
+	return flag.Value.String()
+}
+
+// secretFlag reports whether a flag holds a secret, such as --token
+func secretFlag(name string) bool {
+	if strings.HasSuffix(name, "-file") {
+		return false // The path of a secret, not the secret
+	}
+	for _, secret := range []string{"token", "passphrase", "password", "secret"} {
+		if strings.Contains(name, secret) {
+			return true
+		}
+	}
+	return false
+}
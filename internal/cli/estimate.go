@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/spf13/cobra"
+)
+
+// confirmLargeOperation estimates an operation with a sampled scan before it
+// runs. When it looks larger than operations.confirm_items or
+// operations.confirm_size, the estimate is shown and the user is asked whether
+// to go on; quiet runs are asked without the estimate. Only dry runs,
+// non-interactive runs and --yes are not asked.
+func confirmLargeOperation(cmd *cobra.Command, cfg *config.Config, operationEngine *engine.Engine, operationType domain.OperationType, config domain.OperationConfig) bool {
+	yes, _ := cmd.Flags().GetBool("yes")
+	quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+	if config.DryRun || yes || !stdinIsTerminal() {
+		return true
+	}
+
+	maxItems := cfg.Operations.ConfirmItems
+	maxBytes := ParseSize(cfg.Operations.ConfirmSize, 0)
+	if maxItems <= 0 && maxBytes <= 0 {
+		return true
+	}
+
+	estimate, err := operationEngine.EstimateOperation(operationType, config)
+	if err != nil {
+		return true // The operation itself reports the problem
+	}
+	if (maxItems <= 0 || estimate.TotalItems < maxItems) && (maxBytes <= 0 || estimate.TotalBytes < maxBytes) {
+		return true
+	}
+
+	if quiet {
+		ui.Fprintf(os.Stderr, "❓ This is a large operation (%d items, %s). Proceed? [y/N] ", estimate.TotalItems, FormatBytes(estimate.TotalBytes))
+	} else {
+		displayEstimate(estimate)
+		ui.Fprintf(os.Stderr, "❓ This is a large operation. Proceed? [y/N] ")
+	}
+	answer, err := readLine()
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// displayEstimate prints the estimated scope and duration of an operation
+func displayEstimate(estimate *domain.ProgressInfo) {
+	approx := "~"
+	if exact, _ := estimate.Details["exact"].(bool); exact {
+		approx = ""
+	}
+	directories, _ := estimate.Details["directories"].(int64)
+	sampled, _ := estimate.Details["sampled_directories"].(int)
+
+	ui.Printf("📐 Estimate: %s%d items, %s%s in %s%d directories\n",
+		approx, estimate.TotalItems, approx, FormatBytes(estimate.TotalBytes), approx, directories)
+	if approx != "" {
+		ui.Printf("   Extrapolated from %d sampled directories\n", sampled)
+	}
+	if estimate.EstimatedETA != nil {
+		ui.Printf("⏱️  Expected duration: ~%v\n", estimate.EstimatedETA.Round(time.Second))
+	}
+}
+
+// displayCostEstimate prints what the real run of a dry run is expected to
+// read, write and free, how long its steps take and the space left after it
+func displayCostEstimate(result *domain.OperationResult) {
+	estimate, ok := result.Details["cost_estimate"].(*engine.CostEstimate)
+	if !ok {
+		return
+	}
+
+	ui.Printf("\n📐 Cost of the real run:\n")
+	ui.Printf("   Read:    %s\n", FormatBytes(estimate.ReadBytes))
+	ui.Printf("   Written: %s\n", FormatBytes(estimate.WriteBytes))
+	if estimate.FreedBytes > 0 {
+		ui.Printf("   Freed:   %s\n", FormatBytes(estimate.FreedBytes))
+	}
+	ui.Printf("⏱️  Expected duration: ~%v\n", roughly(estimate.Duration))
+	for _, step := range estimate.Steps {
+		ui.Printf("   %-30s ~%-10v %10s read %10s written\n", step.Step, roughly(step.Duration),
+			FormatBytes(step.ReadBytes), FormatBytes(step.WriteBytes))
+	}
+	if estimate.AssumedBytesPerSecond > 0 {
+		ui.Printf("   Assumes %s/s on devices not measured by fileops bench\n", FormatBytes(estimate.AssumedBytesPerSecond))
+	}
+	for _, volume := range estimate.Volumes {
+		if volume.AvailableAfter < 0 {
+			ui.Printf("❌ %s: %s available, %s short\n", volume.Path, FormatBytes(volume.Available), FormatBytes(-volume.AvailableAfter))
+			continue
+		}
+		ui.Printf("💾 %s: %s available, %s after\n", volume.Path, FormatBytes(volume.Available), FormatBytes(volume.AvailableAfter))
+	}
+}
+
+// roughly rounds an expected duration to seconds, or to milliseconds below
+// a second
+func roughly(duration time.Duration) time.Duration {
+	if duration < time.Second {
+		return duration.Round(time.Millisecond)
+	}
+	return duration.Round(time.Second)
+}
+
+// stdinIsTerminal reports whether the user can answer a question
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"errors"
+
+	"github.com/a4abhishek/fileops/internal/engine"
+)
+
+// Exit codes of the fileops command
+const (
+	ExitFailure  = 1 // The command failed
+	ExitWarnings = 2 // The operation completed with warnings and --fail-on-warnings was set
+	ExitDenied   = 3 // The operation policy does not allow the operation
+)
+
+// ExitError is a command error that exits with a specific status
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+// Error returns the message of the underlying error
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit status for an error returned by a command
+func ExitCode(err error) int {
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	if errors.Is(err, engine.ErrPolicyDenied) {
+		return ExitDenied
+	}
+	return ExitFailure
+}
@@ -0,0 +1,188 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// NewGrantCommand creates the grant command
+func NewGrantCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "grant [path...] --acl g:group:rwX",
+		Short: "Grant users or groups access through ACL entries",
+		Long: `Grant users or groups access to files and directories recursively through
+access control lists, without changing their owner.
+
+Entries use the setfacl syntax "g:group:perms" or "u:user:perms", where perms
+combines r, w, x and X (execute only on directories and on files that are
+executable already). On Linux they are POSIX ACL entries, on Windows allow
+ACEs for the account. Entries an account already has are replaced.
+
+With --inherit, directories also pass the entries on to files and folders
+created in them later (the default ACL on Linux, inheritable ACEs on Windows).
+
+--dry-run shows the current and the desired ACL of every item that would
+change:
+
+  fileops grant /srv/share --acl g:designers:rwX --inherit --dry-run`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get flags
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			recursive, _ := cmd.Flags().GetBool("recursive")
+			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+			acl, _ := cmd.Flags().GetStringSlice("acl")
+			inherit, _ := cmd.Flags().GetBool("inherit")
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			if len(acl) == 0 {
+				return fmt.Errorf("--acl is required, e.g. --acl g:designers:rwX")
+			}
+
+			// Validate paths
+			validPaths := make([]string, 0, len(args))
+			for _, path := range args {
+				absPath, err := filepath.Abs(path)
+				if err != nil {
+					return fmt.Errorf("invalid path %s: %w", path, err)
+				}
+				if _, err := os.Stat(absPath); os.IsNotExist(err) {
+					return fmt.Errorf("path does not exist: %s", absPath)
+				}
+				validPaths = append(validPaths, absPath)
+			}
+
+			// Create operation configuration
+			config := domain.OperationConfig{
+				DryRun:          dryRun,
+				Recursive:       recursive,
+				ExcludePatterns: excludePatterns,
+				TargetPaths:     validPaths,
+				CustomSettings: map[string]interface{}{
+					"acl":     acl,
+					"inherit": inherit,
+				},
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+
+			// Create engine
+			tracker := progress.NewTracker()
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
+
+			log.Info("🔐 Starting ACL grant",
+				"paths", validPaths,
+				"acl", acl,
+				"inherit", inherit,
+				"dry_run", dryRun)
+
+			// Show initial status
+			if !quiet {
+				params := map[string]interface{}{
+					"Entries":   acl,
+					"Recursive": recursive,
+					"Inherit":   inherit,
+				}
+				if len(excludePatterns) > 0 {
+					params["Excluded patterns"] = excludePatterns
+				}
+				DisplayOperationStart("access grant", fmt.Sprintf("%v", validPaths), dryRun, params)
+			}
+
+			// Estimate large runs and ask before changing anything
+			if !confirmLargeOperation(cmd, cfg, operationEngine, domain.OperationGrant, config) {
+				ui.Printf("Nothing was changed\n")
+				return nil
+			}
+
+			// Pre-generate operation ID for progress monitoring
+			operationID := engine.NewOperationID(domain.OperationGrant)
+
+			progressCtx, progressCancel := context.WithCancel(ctx)
+			defer progressCancel()
+
+			var progressWg sync.WaitGroup
+			if !quiet && cfg.Operations.EnableProgressBar {
+				progressWg.Add(1)
+				go func() {
+					defer progressWg.Done()
+					MonitorProgress(progressCtx, tracker, operationID, "grant")
+				}()
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			result, err := operationEngine.ExecuteOperationWithID(ctx, domain.OperationGrant, config, operationID)
+
+			progressCancel()
+			progressWg.Wait()
+
+			if err != nil {
+				if !quiet {
+					ui.Printf("\n❌ ACL grant operation failed: %v\n", err)
+					displayStoredResultHint(cfg, operationID)
+				}
+				return fmt.Errorf("ACL grant operation failed: %w", err)
+			}
+
+			// Display results
+			if !quiet {
+				duration := result.EndTime.Sub(result.StartTime)
+				DisplayOperationComplete("access grant", duration, result.Summary)
+			}
+
+			log.Info("✅ ACL grant completed", "summary", result.Summary)
+
+			if changes, ok := result.Details["acl_changes"].(domain.PathList); ok && changes.Count > 0 {
+				if !quiet {
+					ui.Printf("\n🔐 ACL changes (%d total):\n", changes.Count)
+					for i, change := range changes.Paths {
+						if i >= 20 {
+							ui.Printf("  ... and %d more items\n", changes.Count-20)
+							displayPathListFile(changes)
+							break
+						}
+						if dryRun {
+							ui.Printf("  [DRY RUN] Would change: %s\n", change)
+						} else {
+							ui.Printf("  ✓ Changed: %s\n", change)
+						}
+					}
+				}
+			} else if !quiet {
+				ui.Printf("\n🔐 All items already have the requested entries\n")
+			}
+
+			if !quiet {
+				DisplayOperationWarnings(result)
+				DisplayOperationErrors(result)
+			}
+
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	// Add flags
+	cmd.Flags().Bool("dry-run", false, "Show current and desired ACLs without changing them")
+	cmd.Flags().BoolP("recursive", "r", true, "Process directories recursively")
+	cmd.Flags().StringSlice("exclude", []string{".git", ".svn", "node_modules", "__pycache__"}, "Patterns to exclude")
+	cmd.Flags().StringSlice("acl", nil, "ACL entries to grant, e.g. g:designers:rwX or u:alice:r (repeatable)")
+	cmd.Flags().Bool("inherit", false, "Let directories pass the entries on to items created in them later")
+
+	return cmd
+}
@@ -0,0 +1,451 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+	"github.com/spf13/cobra"
+)
+
+// Output formats of the hash command
+const (
+	hashFormatSum  = "sum"  // "<hash>  <path>", as sha256sum writes
+	hashFormatTag  = "tag"  // "SHA256 (<path>) = <hash>", as sha256sum --tag and cksum write
+	hashFormatJSON = "json" // One JSON object per file
+)
+
+// hashTags names the algorithms in tag lines, as cksum does where it knows them
+var hashTags = map[string]string{
+	"md5":      "MD5",
+	"sha1":     "SHA1",
+	"sha256":   "SHA256",
+	"sha512":   "SHA512",
+	"blake2b":  "BLAKE2b-256",
+	"blake3":   "BLAKE3",
+	"xxhash64": "XXH64",
+	"crc32":    "CRC32",
+}
+
+var (
+	tagLine = regexp.MustCompile(`^([A-Za-z0-9-]+) \((.*)\) = ([0-9a-fA-F]+)$`)
+	sumLine = regexp.MustCompile(`^([0-9a-fA-F]+) [ *](.*)$`)
+)
+
+// hashJob is a file to hash, or with --check to verify, numbered in the
+// order of the output
+type hashJob struct {
+	index      int
+	path       string
+	algorithms []string
+	expected   string // Checksum listed for path (--check)
+	sums       []string
+	size       int64
+	err        error
+}
+
+// hashRecord is a file in the JSON format
+type hashRecord struct {
+	Path string            `json:"path"`
+	Size int64             `json:"size"`
+	Sums map[string]string `json:"sums"`
+}
+
+// NewHashCommand creates the hash command
+func NewHashCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hash [path...]",
+		Short: "Hash files in parallel, like sha256sum and friends",
+		Long: `Hash files with any algorithm fileops supports, several at once, reading
+each file only once. Files are hashed in parallel and printed in the order
+given, so the output matches that of sha256sum and friends. Without paths,
+or with -, standard input is hashed.
+
+Formats:
+  sum   <hash>  <path>, as sha256sum writes (the default for one algorithm)
+  tag   SHA256 (<path>) = <hash>, as sha256sum --tag and cksum write (the
+        default for several algorithms)
+  json  One JSON object per file with its path, size and hashes
+
+Both text formats can be checked with --check, or by sha256sum -c and
+cksum -c. Names holding a backslash or line break are escaped like
+coreutils does. With --recursive, directories are hashed file by file;
+symbolic links below them are not followed.`,
+		Example: `  fileops hash -r /mnt/archive > SHA256SUMS
+  fileops hash --check SHA256SUMS
+  fileops hash -a sha256,blake3 -r ~/Photos
+  fileops hash -a xxhash64 --format json -r /data`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			algorithms, _ := cmd.Flags().GetStringSlice("algorithm")
+			recursive, _ := cmd.Flags().GetBool("recursive")
+			format, _ := cmd.Flags().GetString("format")
+			check, _ := cmd.Flags().GetBool("check")
+			parallelism, _ := cmd.Flags().GetInt("parallelism")
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			for i, algorithm := range algorithms {
+				algorithms[i] = strings.ToLower(algorithm)
+				if _, err := filesystem.NewHasher(algorithms[i]); err != nil {
+					return err
+				}
+			}
+			if format == "" {
+				format = hashFormatSum
+				if len(algorithms) > 1 {
+					format = hashFormatTag
+				}
+			}
+			switch format {
+			case hashFormatSum:
+				if len(algorithms) > 1 {
+					return fmt.Errorf("the sum format holds one algorithm, use --format tag or json for several")
+				}
+			case hashFormatTag, hashFormatJSON:
+			default:
+				return fmt.Errorf("unknown format %q (use sum, tag or json)", format)
+			}
+			if parallelism <= 0 {
+				parallelism = runtime.NumCPU()
+			}
+			if len(args) == 0 {
+				args = []string{"-"}
+			}
+
+			out := bufio.NewWriter(ui.Stdout)
+			defer out.Flush()
+			// Lines show up as they are ready on a terminal
+			flush := stdoutIsTerminal()
+
+			if check {
+				return checkSums(ctx, args, algorithms[0], parallelism, quiet, out, flush, log)
+			}
+
+			var failed int
+			err := hashInOrder(ctx, parallelism,
+				func(emit func(*hashJob)) {
+					for _, path := range args {
+						emitPaths(path, recursive, algorithms, emit)
+					}
+				},
+				func(job *hashJob) {
+					if job.err != nil {
+						failed++
+						ui.Fprintf(os.Stderr, "fileops hash: %v\n", job.err)
+						return
+					}
+					writeHashes(out, format, job)
+					if flush {
+						out.Flush()
+					}
+				})
+			if err != nil {
+				return err
+			}
+			log.Debug("Files hashed", "paths", args, "algorithms", algorithms, "failed", failed)
+			if failed > 0 {
+				return fmt.Errorf("%d files could not be hashed", failed)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceP("algorithm", "a", []string{"sha256"}, "Hash algorithms, comma separated (md5, sha1, sha256, sha512, blake2b, blake3, xxhash64, crc32)")
+	cmd.Flags().BoolP("recursive", "r", false, "Hash the files below directories")
+	cmd.Flags().StringP("format", "f", "", "Output format: sum, tag or json (default sum, tag for several algorithms)")
+	cmd.Flags().BoolP("check", "c", false, "Read checksums from the given files and verify them")
+	cmd.Flags().IntP("parallelism", "j", cfg.Performance.MaxWorkers, "Number of files hashed at once (0 = number of CPUs)")
+
+	return cmd
+}
+
+// emitPaths emits path, or with recursive the files below it, for hashing
+func emitPaths(path string, recursive bool, algorithms []string, emit func(*hashJob)) {
+	if path == "-" {
+		emit(&hashJob{path: path, algorithms: algorithms})
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		emit(&hashJob{path: path, err: err})
+		return
+	}
+	if !info.IsDir() {
+		emit(&hashJob{path: path, algorithms: algorithms})
+		return
+	}
+	if !recursive {
+		emit(&hashJob{path: path, err: fmt.Errorf("%s: is a directory (use --recursive)", path)})
+		return
+	}
+
+	filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		switch {
+		case err != nil:
+			emit(&hashJob{path: file, err: err})
+		case entry.Type().IsRegular():
+			emit(&hashJob{path: file, algorithms: algorithms})
+		}
+		return nil
+	})
+}
+
+// hashInOrder hashes the jobs produce emits on workers and hands them to
+// write in the order they were emitted
+func hashInOrder(ctx context.Context, workers int, produce func(emit func(*hashJob)), write func(*hashJob)) error {
+	jobs := make(chan *hashJob, workers)
+	done := make(chan *hashJob, workers)
+
+	go func() {
+		defer close(jobs)
+		index := 0
+		produce(func(job *hashJob) {
+			job.index = index
+			index++
+			select {
+			case jobs <- job:
+			case <-ctx.Done():
+			}
+		})
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if job.err == nil && ctx.Err() == nil {
+					job.sums, job.size, job.err = hashJobFile(job)
+				}
+				done <- job
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	// Jobs finish out of order; each waits until those before it are written
+	pending := make(map[int]*hashJob)
+	next := 0
+	for job := range done {
+		pending[job.index] = job
+		for {
+			ready, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if ctx.Err() == nil {
+				write(ready)
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// hashJobFile hashes the file of a job, standard input for -
+func hashJobFile(job *hashJob) ([]string, int64, error) {
+	var input io.Reader = os.Stdin
+	if job.path != "-" {
+		file, err := os.Open(job.path)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer file.Close()
+		input = file
+	}
+	counter := &countingReader{reader: input}
+	sums, err := filesystem.HashReader(counter, job.algorithms...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", job.path, err)
+	}
+	return sums, counter.count, nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+	return n, err
+}
+
+// writeHashes writes the hashes of a job in format
+func writeHashes(out io.Writer, format string, job *hashJob) {
+	if format == hashFormatJSON {
+		record := hashRecord{Path: job.path, Size: job.size, Sums: make(map[string]string, len(job.sums))}
+		for i, algorithm := range job.algorithms {
+			record.Sums[algorithm] = job.sums[i]
+		}
+		data, _ := json.Marshal(record)
+		fmt.Fprintf(out, "%s\n", data)
+		return
+	}
+
+	name, escaped := escapeSumPath(job.path)
+	prefix := ""
+	if escaped {
+		prefix = `\`
+	}
+	for i, algorithm := range job.algorithms {
+		if format == hashFormatTag {
+			fmt.Fprintf(out, "%s%s (%s) = %s\n", prefix, hashTags[algorithm], name, job.sums[i])
+		} else {
+			fmt.Fprintf(out, "%s%s  %s\n", prefix, job.sums[i], name)
+		}
+	}
+}
+
+// escapeSumPath escapes backslashes and line breaks in a path the way
+// coreutils does, and reports whether the line needs the leading backslash
+// that marks an escaped name
+func escapeSumPath(path string) (string, bool) {
+	if !strings.ContainsAny(path, "\\\n\r") {
+		return path, false
+	}
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`).Replace(path), true
+}
+
+// unescapeSumPath reverses escapeSumPath
+func unescapeSumPath(name string) string {
+	return strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\r`, "\r").Replace(name)
+}
+
+// checkSums verifies the checksums listed in the given files, in the sum
+// format (of algorithm) or the tag format (of any algorithm)
+func checkSums(ctx context.Context, lists []string, algorithm string, workers int, quiet bool, out *bufio.Writer, flush bool, log *logger.Logger) error {
+	var mismatched, unreadable, malformed int
+	var listErr error
+	err := hashInOrder(ctx, workers,
+		func(emit func(*hashJob)) {
+			for _, list := range lists {
+				if err := readSumList(list, algorithm, emit, &malformed); err != nil && listErr == nil {
+					listErr = err
+				}
+			}
+		},
+		func(job *hashJob) {
+			name, _ := escapeSumPath(job.path)
+			switch {
+			case job.err != nil:
+				unreadable++
+				ui.Fprintf(os.Stderr, "fileops hash: %v\n", job.err)
+				fmt.Fprintf(out, "%s: FAILED open or read\n", name)
+			case job.sums[0] != job.expected:
+				mismatched++
+				fmt.Fprintf(out, "%s: FAILED\n", name)
+			case !quiet:
+				fmt.Fprintf(out, "%s: OK\n", name)
+			}
+			if flush {
+				out.Flush()
+			}
+		})
+	out.Flush()
+	if err != nil {
+		return err
+	}
+	if listErr != nil {
+		return listErr
+	}
+	log.Debug("Checksums verified", "lists", lists, "mismatched", mismatched, "unreadable", unreadable, "malformed", malformed)
+
+	if malformed > 0 {
+		ui.Fprintf(os.Stderr, "fileops hash: WARNING: %d lines are improperly formatted\n", malformed)
+	}
+	if unreadable > 0 {
+		ui.Fprintf(os.Stderr, "fileops hash: WARNING: %d listed files could not be read\n", unreadable)
+	}
+	if mismatched > 0 {
+		ui.Fprintf(os.Stderr, "fileops hash: WARNING: %d computed checksums did NOT match\n", mismatched)
+	}
+	if mismatched > 0 || unreadable > 0 {
+		return fmt.Errorf("%d of the listed files failed verification", mismatched+unreadable)
+	}
+	return nil
+}
+
+// readSumList emits a job for every checksum line of list; - reads
+// standard input
+func readSumList(list, algorithm string, emit func(*hashJob), malformed *int) error {
+	input := io.Reader(os.Stdin)
+	if list != "-" {
+		file, err := os.Open(list)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	}
+
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		job, ok := parseSumLine(line, algorithm)
+		if !ok {
+			*malformed++
+			continue
+		}
+		emit(job)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", list, err)
+	}
+	return nil
+}
+
+// parseSumLine parses a line of the sum format, hashed with algorithm, or of
+// the tag format
+func parseSumLine(line, algorithm string) (*hashJob, bool) {
+	escaped := strings.HasPrefix(line, `\`)
+	if escaped {
+		line = line[1:]
+	}
+
+	var sum, name string
+	if match := tagLine.FindStringSubmatch(line); match != nil {
+		algorithm = ""
+		for known, tag := range hashTags {
+			if strings.EqualFold(match[1], tag) || strings.EqualFold(match[1], known) {
+				algorithm = known
+			}
+		}
+		if algorithm == "" {
+			return nil, false
+		}
+		name, sum = match[2], match[3]
+	} else if match := sumLine.FindStringSubmatch(line); match != nil {
+		sum, name = match[1], match[2]
+	} else {
+		return nil, false
+	}
+
+	if escaped {
+		name = unescapeSumPath(name)
+	}
+	return &hashJob{path: name, algorithms: []string{algorithm}, expected: strings.ToLower(sum)}, true
+}
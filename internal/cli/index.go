@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// NewIndexCommand creates the index command
+func NewIndexCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "index [path...]",
+		Short: "Index document text for full-text search",
+		Long: `Extract the text of documents under the given paths into the content index.
+
+Supported formats: PDF (text layer only, scanned pages are not recognized),
+DOCX, XLSX, PPTX, ODT, ODS, ODP, HTML and plain text files (txt, md, csv,
+json, xml, yaml, log).
+
+Every document is also classified with the content.rules keyword rules so
+"fileops search --category" and "fileops organize --strategy content" can use
+the result. Runs are incremental: only new and modified documents are read, and
+documents that no longer exist are removed from the index. With --journal only
+the paths the filesystem change journal reports as changed are read, see
+"fileops journal".`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get flags
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			force, _ := cmd.Flags().GetBool("force")
+			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+			useJournal, _ := cmd.Flags().GetBool("journal")
+
+			// Validate paths
+			validPaths := make([]string, 0, len(args))
+			for _, path := range args {
+				absPath, err := filepath.Abs(path)
+				if err != nil {
+					return fmt.Errorf("invalid path %s: %w", path, err)
+				}
+				if _, err := os.Stat(absPath); os.IsNotExist(err) {
+					return fmt.Errorf("path does not exist: %s", absPath)
+				}
+				validPaths = append(validPaths, absPath)
+			}
+
+			// Create operation configuration
+			config := domain.OperationConfig{
+				DryRun:          dryRun,
+				Recursive:       true,
+				ExcludePatterns: excludePatterns,
+				TargetPaths:     validPaths,
+				CustomSettings: map[string]interface{}{
+					"index_directory": cfg.Content.IndexDirectory,
+					"max_text_size":   cfg.Content.MaxTextSize,
+					"rules":           cfg.Content.Rules,
+					"force":           force,
+				},
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+
+			// Create engine
+			tracker := progress.NewTracker()
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
+
+			// Get quiet flag from root command
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			// Limit the run to the paths the change journal reports as changed
+			var journaled *journalRun
+			if useJournal {
+				var pending bool
+				if journaled, pending = startJournalRun(ctx, cfg, log, "index", &config, quiet); !pending {
+					if !dryRun {
+						journaled.finish(log)
+					}
+					if !quiet {
+						ui.Printf("✅ Nothing changed since the last run\n")
+					}
+					return nil
+				}
+			}
+
+			log.Info("📇 Starting content indexing",
+				"paths", validPaths,
+				"index_directory", cfg.Content.IndexDirectory,
+				"dry_run", dryRun)
+
+			// Show initial status
+			if !quiet {
+				ui.Printf("🔍 Scanning for documents...\n")
+				if dryRun {
+					ui.Printf("📋 DRY RUN MODE: The index will not be updated\n")
+				}
+				ui.Printf("📂 Paths to process: %v\n", validPaths)
+				ui.Printf("🗂️  Index directory: %s\n\n", cfg.Content.IndexDirectory)
+			}
+
+			// Pre-generate operation ID for progress monitoring
+			operationID := engine.NewOperationID(domain.OperationContentIndex)
+
+			// Start progress monitoring in a separate goroutine BEFORE starting operation
+			progressCtx, progressCancel := context.WithCancel(ctx)
+			defer progressCancel()
+
+			var progressWg sync.WaitGroup
+			if !quiet && cfg.Operations.EnableProgressBar {
+				progressWg.Add(1)
+				go func() {
+					defer progressWg.Done()
+					MonitorProgress(progressCtx, tracker, operationID, "content_index")
+				}()
+				// Give the monitor a moment to start
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			// Execute operation with predefined ID so progress monitoring works
+			result, err := operationEngine.ExecuteOperationWithID(ctx, domain.OperationContentIndex, config, operationID)
+
+			// Stop progress monitoring
+			progressCancel()
+			progressWg.Wait()
+
+			if err != nil {
+				if !quiet {
+					ui.Printf("\n❌ Indexing failed: %v\n", err)
+					displayStoredResultHint(cfg, operationID)
+				}
+				return fmt.Errorf("indexing failed: %w", err)
+			}
+
+			if journaled != nil && !dryRun {
+				journaled.finish(log)
+			}
+
+			// Display results
+			if !quiet {
+				ui.Printf("\n\n✅ Indexing completed!\n")
+				if result.Summary != "" {
+					ui.Printf("📊 %s\n", result.Summary)
+				}
+				ui.Printf("⏱️  Total time: %v\n", result.Duration.Round(time.Millisecond))
+
+				if categories, ok := result.Details["categories"].(map[string]int); ok && len(categories) > 0 {
+					names := make([]string, 0, len(categories))
+					for name := range categories {
+						names = append(names, name)
+					}
+					sort.Strings(names)
+
+					ui.Printf("\n🏷️  Classified documents:\n")
+					for _, name := range names {
+						ui.Printf("  %-20s %d\n", name, categories[name])
+					}
+				}
+
+				DisplayOperationWarnings(result)
+				DisplayOperationErrors(result)
+			}
+
+			log.Info("✅ Indexing completed", "summary", result.Summary)
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	// Add flags
+	cmd.Flags().Bool("dry-run", false, "Report which documents would be indexed without updating the index")
+	cmd.Flags().Bool("force", false, "Re-extract documents even if they are up to date")
+	cmd.Flags().StringSlice("exclude", []string{".git", "node_modules"}, "Patterns to exclude")
+	cmd.Flags().Bool("journal", false, "Only process paths the filesystem change journal reports as changed (see \"fileops journal\")")
+
+	return cmd
+}
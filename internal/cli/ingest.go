@@ -0,0 +1,213 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// NewIngestCommand creates the ingest command
+func NewIngestCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ingest [inbox...] --dest library",
+		Short: "Move files dropped into an inbox into the organized library",
+		Long: `Take the files dropped into one or more inbox folders into the library at
+--dest, the whole "drop folder" workflow in one step:
+
+  1. Wait until each file is fully written: its size and modification time
+     must not change for --settle. Partial downloads (.part, .crdownload, ...)
+     and files still growing stay in the inbox for the next run.
+  2. Run the --scanner hook on it, e.g. "clamscan --no-summary". The file path
+     is appended to the command. Exit status 0 passes the file, 1 moves it to
+     the quarantine folder (--quarantine, default .quarantine in the inbox),
+     anything else leaves it in the inbox and reports an error.
+  3. Hash it and compare it with the library. Files the library already holds
+     stay in the inbox, or are deleted with --duplicates remove.
+  4. Move it into the category folder of the library the organize command
+     would choose with --strategy; files without a category go to Unsorted.
+
+--watch keeps checking the inboxes at that interval until interrupted:
+
+  fileops ingest ~/Inbox --dest ~/Library --strategy date --scanner "clamscan --no-summary" --watch 1m`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get flags
+			destination, _ := cmd.Flags().GetString("dest")
+			strategy, _ := cmd.Flags().GetString("strategy")
+			settle, _ := cmd.Flags().GetDuration("settle")
+			scanner, _ := cmd.Flags().GetString("scanner")
+			quarantine, _ := cmd.Flags().GetString("quarantine")
+			duplicates, _ := cmd.Flags().GetString("duplicates")
+			watch, _ := cmd.Flags().GetDuration("watch")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+
+			if destination == "" {
+				return fmt.Errorf("--dest is required")
+			}
+			absDestination, err := filepath.Abs(destination)
+			if err != nil {
+				return fmt.Errorf("invalid destination %s: %w", destination, err)
+			}
+			if quarantine != "" {
+				if quarantine, err = filepath.Abs(quarantine); err != nil {
+					return fmt.Errorf("invalid quarantine folder: %w", err)
+				}
+			}
+			validPaths, err := absolutePaths(args)
+			if err != nil {
+				return err
+			}
+
+			// Create operation configuration
+			config := domain.OperationConfig{
+				DryRun:          dryRun,
+				Recursive:       true,
+				ExcludePatterns: excludePatterns,
+				TargetPaths:     validPaths,
+				HashAlgorithm:   cfg.Operations.HashAlgorithm,
+				CustomSettings: map[string]interface{}{
+					"destination":     absDestination,
+					"strategy":        strategy,
+					"settle":          settle.String(),
+					"scanner":         scanner,
+					"quarantine":      quarantine,
+					"duplicates":      duplicates,
+					"rules":           cfg.Content.Rules,
+					"max_text_size":   cfg.Content.MaxTextSize,
+					"index_directory": cfg.Content.IndexDirectory,
+				},
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+
+			// Create engine
+			tracker := progress.NewTracker()
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
+
+			// Get quiet flag from root command
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			log.Info("📥 Starting ingestion",
+				"inboxes", validPaths,
+				"destination", absDestination,
+				"strategy", strategy,
+				"scanner", scanner,
+				"dry_run", dryRun)
+
+			// Show initial status
+			if !quiet {
+				ui.Printf("📥 Ingesting inbox files...\n")
+				if dryRun {
+					ui.Printf("📋 DRY RUN MODE: No files will be moved\n")
+				}
+				ui.Printf("📂 Inboxes: %v\n", validPaths)
+				ui.Printf("🎯 Library: %s (%s strategy)\n", absDestination, strategy)
+				if scanner != "" {
+					ui.Printf("🛡️  Scanner: %s\n", scanner)
+				}
+				fmt.Fprintln(ui.Stdout)
+			}
+
+			for {
+				result, err := runIngest(ctx, cfg, log, operationEngine, tracker, config, quiet)
+				if err != nil {
+					return err
+				}
+				if watch <= 0 {
+					return warningPolicy(cmd, result)
+				}
+				if !quiet {
+					ui.Printf("\n👀 Checking the inboxes again in %v (Ctrl+C to stop)\n\n", watch)
+				}
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(watch):
+				}
+			}
+		},
+	}
+
+	// Add flags
+	cmd.Flags().String("dest", "", "Library to move the files into (required)")
+	cmd.Flags().String("strategy", "type", "Organization strategy (content, type, date)")
+	cmd.Flags().Duration("settle", 10*time.Second, "How long a file's size must stay the same before it is taken")
+	cmd.Flags().String("scanner", "", "Command checking each file, e.g. \"clamscan --no-summary\" (exit 1 quarantines)")
+	cmd.Flags().String("quarantine", "", "Folder for files the scanner rejects (default: .quarantine in the inbox)")
+	cmd.Flags().String("duplicates", engine.IngestDuplicatesSkip, "What to do with files the library already holds (skip, remove)")
+	cmd.Flags().Duration("watch", 0, "Keep checking the inboxes at this interval")
+	cmd.Flags().Bool("dry-run", false, "Preview where each file would go without moving anything")
+	cmd.Flags().StringSlice("exclude", []string{".git", ".DS_Store", "Thumbs.db"}, "Patterns to exclude")
+
+	return cmd
+}
+
+// runIngest executes one ingestion run with progress display and prints its result
+func runIngest(ctx context.Context, cfg *config.Config, log *logger.Logger, operationEngine *engine.Engine, tracker *progress.Tracker, config domain.OperationConfig, quiet bool) (*domain.OperationResult, error) {
+	// Pre-generate operation ID for progress monitoring
+	operationID := engine.NewOperationID(domain.OperationIngest)
+
+	// Start progress monitoring in a separate goroutine BEFORE starting operation
+	progressCtx, progressCancel := context.WithCancel(ctx)
+	defer progressCancel()
+
+	var progressWg sync.WaitGroup
+	if !quiet && cfg.Operations.EnableProgressBar {
+		progressWg.Add(1)
+		go func() {
+			defer progressWg.Done()
+			MonitorProgress(progressCtx, tracker, operationID, "ingest")
+		}()
+		// Give the monitor a moment to start
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// Execute operation with predefined ID so progress monitoring works
+	result, err := operationEngine.ExecuteOperationWithID(ctx, domain.OperationIngest, config, operationID)
+
+	// Stop progress monitoring
+	progressCancel()
+	progressWg.Wait()
+
+	if err != nil {
+		if !quiet {
+			ui.Printf("\n❌ Ingestion failed: %v\n", err)
+			displayStoredResultHint(cfg, operationID)
+		}
+		return nil, fmt.Errorf("ingestion failed: %w", err)
+	}
+
+	// Display results
+	if !quiet {
+		ui.Printf("\n\n✅ Ingestion completed!\n")
+		if result.Summary != "" {
+			ui.Printf("📊 %s\n", result.Summary)
+		}
+		ui.Printf("⏱️  Total time: %v\n", result.Duration.Round(time.Millisecond))
+
+		listFiles(result, "quarantined", "🛡️  Quarantined files")
+		listFiles(result, "duplicates", "♊ Already in the library")
+		listFiles(result, "settling", "⏳ Still being written")
+
+		DisplayOperationWarnings(result)
+		DisplayOperationErrors(result)
+	}
+
+	log.Info("✅ Ingestion completed", "summary", result.Summary)
+	return result, nil
+}
@@ -0,0 +1,230 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/journal"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/spf13/cobra"
+)
+
+// journalConsumers are the commands that accept --journal
+var journalConsumers = []string{"index", "checksum"}
+
+// NewJournalCommand creates the journal command
+func NewJournalCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	journalCmd := &cobra.Command{
+		Use:   "journal",
+		Short: "Use filesystem change journals for incremental runs",
+		Long: `Let "fileops index --journal" and "fileops checksum --journal" read the
+changes of a volume from its change journal instead of walking millions of
+unchanged files.
+
+On Windows the USN journal of NTFS and ReFS volumes is read directly (this
+requires administrator rights). On Linux the kernel keeps no such journal, so
+"fileops journal record" watches the whole filesystem with fanotify (root,
+Linux 5.9 or newer) and records every change until it is stopped.
+
+Each command remembers the journal position it reached per path in the
+journal directory. When the journal does not cover the time since the last
+run (the USN journal wrapped or the recorder was not running) the command
+falls back to a full scan and starts over from there.`,
+	}
+
+	// Add subcommands
+	journalCmd.AddCommand(
+		newJournalRecordCommand(ctx, cfg, log),
+		newJournalStatusCommand(ctx, cfg, log),
+	)
+
+	return journalCmd
+}
+
+// newJournalRecordCommand creates the journal record subcommand
+func newJournalRecordCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	return &cobra.Command{
+		Use:   "record [path]",
+		Short: "Record the changes of the filesystem holding path (Linux)",
+		Long: `Watch the whole filesystem holding path with fanotify and record every
+created, modified, renamed and removed file until interrupted. Run it as a
+service so incremental runs never miss a change; changes made while it is
+not running force the next run to scan everything.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := filepath.Abs(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid path %s: %w", args[0], err)
+			}
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("path does not exist: %s", path)
+			}
+
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+			if !quiet {
+				ui.Printf("📓 Recording changes of the filesystem holding %s\n", path)
+				ui.Printf("🗂️  Journal directory: %s\n", cfg.Operations.JournalDirectory)
+				ui.Printf("Press Ctrl+C to stop\n")
+			}
+
+			recorded := 0
+			err = journal.Record(ctx, path, cfg.Operations.JournalDirectory, func(n int) {
+				recorded += n
+				log.Debug("Recorded changes", "changes", n, "total", recorded)
+			})
+			if err != nil {
+				return fmt.Errorf("recording failed: %w", err)
+			}
+			if !quiet {
+				ui.Printf("\n✅ Recording stopped after %d changes\n", recorded)
+			}
+			return nil
+		},
+	}
+}
+
+// newJournalStatusCommand creates the journal status subcommand
+func newJournalStatusCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	return &cobra.Command{
+		Use:   "status [path...]",
+		Short: "Show the journal position and pending changes per command",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, arg := range args {
+				root, err := filepath.Abs(arg)
+				if err != nil {
+					return fmt.Errorf("invalid path %s: %w", arg, err)
+				}
+
+				ui.Printf("📂 %s\n", root)
+				source, err := journal.Open(root, cfg.Operations.JournalDirectory)
+				if err != nil {
+					ui.Printf("  ❌ %v\n", err)
+					continue
+				}
+				if current, err := source.Current(); err != nil {
+					ui.Printf("  ❌ %v\n", err)
+				} else {
+					ui.Printf("  📓 %s journal of %s at %d\n", current.Backend, current.Volume, current.Position)
+				}
+
+				for _, consumer := range journalConsumers {
+					cursor, ok, err := journal.LoadCursor(cfg.Operations.JournalDirectory, consumer, root)
+					switch {
+					case err != nil:
+						ui.Printf("  %-10s ❌ %v\n", consumer, err)
+						continue
+					case !ok:
+						ui.Printf("  %-10s no journaled run yet\n", consumer)
+						continue
+					}
+					changes, _, err := source.Since(ctx, cursor)
+					if err != nil {
+						ui.Printf("  %-10s full scan needed: %v\n", consumer, err)
+						continue
+					}
+					ui.Printf("  %-10s %d changed paths pending\n", consumer, len(journal.Under(changes, root)))
+				}
+				source.Close()
+			}
+			return nil
+		},
+	}
+}
+
+// journalRun holds the journal positions an incremental run reaches
+type journalRun struct {
+	dir      string
+	consumer string
+	cursors  map[string]journal.Cursor
+}
+
+// startJournalRun restricts config to the paths the change journal reports as
+// changed since the last run of consumer. Paths the journal cannot vouch for
+// are scanned completely. It returns false when nothing changed at all.
+func startJournalRun(ctx context.Context, cfg *config.Config, log *logger.Logger, consumer string, config *domain.OperationConfig, quiet bool) (*journalRun, bool) {
+	run := &journalRun{dir: cfg.Operations.JournalDirectory, consumer: consumer, cursors: make(map[string]journal.Cursor)}
+	onlyPaths := make([]string, 0)
+	fullScans := 0
+
+	for _, root := range config.TargetPaths {
+		source, err := journal.Open(root, run.dir)
+		if err != nil {
+			if !quiet {
+				ui.Printf("📓 No change journal for %s, scanning everything: %v\n", root, err)
+			}
+			onlyPaths = append(onlyPaths, root)
+			fullScans++
+			continue
+		}
+
+		// The position is taken before scanning so changes made during the scan are seen next time
+		changes, next, err := func() ([]journal.Change, journal.Cursor, error) {
+			defer source.Close()
+			cursor, ok, err := journal.LoadCursor(run.dir, consumer, root)
+			switch {
+			case err == nil && ok:
+				var changes []journal.Change
+				var next journal.Cursor
+				if changes, next, err = source.Since(ctx, cursor); !errors.Is(err, journal.ErrGap) {
+					return changes, next, err
+				}
+			case err == nil:
+				err = fmt.Errorf("%w: no journaled run yet", journal.ErrGap)
+			default:
+				err = fmt.Errorf("%w (%v)", journal.ErrGap, err)
+			}
+			current, currentErr := source.Current()
+			if currentErr != nil {
+				return nil, current, currentErr
+			}
+			return nil, current, err
+		}()
+		switch {
+		case errors.Is(err, journal.ErrGap):
+			if !quiet {
+				ui.Printf("📓 Scanning all of %s: %v\n", root, err)
+			}
+			onlyPaths = append(onlyPaths, root)
+			fullScans++
+		case err != nil:
+			if !quiet {
+				ui.Printf("📓 Change journal of %s unavailable, scanning everything: %v\n", root, err)
+			}
+			onlyPaths = append(onlyPaths, root)
+			fullScans++
+			continue
+		default:
+			changed := journal.Under(changes, root)
+			if !quiet {
+				ui.Printf("📓 %d changed paths under %s since the last run\n", len(changed), root)
+			}
+			onlyPaths = append(onlyPaths, changed...)
+		}
+		run.cursors[root] = next
+	}
+
+	log.Debug("Journal scope", "consumer", consumer, "paths", len(onlyPaths), "full_scans", fullScans)
+	if len(onlyPaths) == 0 {
+		return run, false
+	}
+	if fullScans < len(config.TargetPaths) {
+		config.OnlyPaths = onlyPaths
+	}
+	return run, true
+}
+
+// finish records the reached journal positions after a successful run
+func (r *journalRun) finish(log *logger.Logger) {
+	for root, cursor := range r.cursors {
+		if err := journal.SaveCursor(r.dir, r.consumer, root, cursor); err != nil {
+			log.Warn("Failed to save journal position", "path", root, "error", err)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// NewMailCommand creates the mail command
+func NewMailCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	mailCmd := &cobra.Command{
+		Use:   "mail",
+		Short: "Work with attachments in mail archives",
+		Long: `Work with the attachments of mail archives: mbox files (.mbox, .mbx, as
+exported by Thunderbird, Apple Mail or Google Takeout) and single messages
+(.eml). Outlook PST files must be converted to mbox first, e.g. with readpst.
+
+"fileops dedup --mail" compares the attachments with the files on disk
+without extracting them.`,
+	}
+
+	// Add subcommands
+	mailCmd.AddCommand(newMailExtractCommand(ctx, cfg, log))
+
+	return mailCmd
+}
+
+// newMailExtractCommand creates the mail extract subcommand
+func newMailExtractCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "extract [path...]",
+		Short: "Extract attachments into organized folders",
+		Long: `Extract the attachments of the mail archives below the paths into folders
+below --dest: by month of the message (--strategy date, e.g. 2024/03), by
+sender (sender, e.g. example.com/alice@example.com), by file type (type) or
+all in one folder (flat). Extracted files get the date of their message.
+
+--pattern selects attachments by name, --from by sender address and --since
+and --until by message date. An attachment extracted before (the same
+content under the same name) is not written again; a different file of the
+same name gets a numbered name. The archives are never changed.
+
+  fileops mail extract ~/Mail/Takeout.mbox --dest ~/Documents/Attachments --pattern "*.pdf" --since 2024-01-01`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get flags
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			destination, _ := cmd.Flags().GetString("dest")
+			strategy, _ := cmd.Flags().GetString("strategy")
+			patterns, _ := cmd.Flags().GetStringSlice("pattern")
+			from, _ := cmd.Flags().GetString("from")
+			since, _ := cmd.Flags().GetString("since")
+			until, _ := cmd.Flags().GetString("until")
+			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+
+			if destination == "" {
+				return fmt.Errorf("--dest is required")
+			}
+			absDestination, err := filepath.Abs(destination)
+			if err != nil {
+				return fmt.Errorf("invalid destination %s: %w", destination, err)
+			}
+
+			// Validate paths
+			validPaths := make([]string, 0, len(args))
+			for _, path := range args {
+				absPath, err := filepath.Abs(path)
+				if err != nil {
+					return fmt.Errorf("invalid path %s: %w", path, err)
+				}
+				if _, err := os.Stat(absPath); os.IsNotExist(err) {
+					return fmt.Errorf("path does not exist: %s", absPath)
+				}
+				validPaths = append(validPaths, absPath)
+			}
+
+			// Create operation configuration
+			config := domain.OperationConfig{
+				DryRun:          dryRun,
+				Recursive:       true,
+				ExcludePatterns: excludePatterns,
+				TargetPaths:     validPaths,
+				CustomSettings: map[string]interface{}{
+					"destination": absDestination,
+					"strategy":    strategy,
+					"patterns":    patterns,
+					"from":        from,
+					"since":       since,
+					"until":       until,
+				},
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+
+			// Create engine
+			tracker := progress.NewTracker()
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
+
+			// Get quiet flag from root command
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			log.Info("📧 Starting attachment extraction",
+				"paths", validPaths,
+				"destination", absDestination,
+				"strategy", strategy,
+				"dry_run", dryRun)
+
+			// Show initial status
+			if !quiet {
+				ui.Printf("📧 Extracting attachments...\n")
+				if dryRun {
+					ui.Printf("📋 DRY RUN MODE: No files will be written\n")
+				}
+				ui.Printf("📂 Paths to process: %v\n", validPaths)
+				ui.Printf("🎯 Destination: %s (by %s)\n", absDestination, strategy)
+				if len(patterns) > 0 {
+					ui.Printf("🔎 Attachments: %s\n", strings.Join(patterns, ", "))
+				}
+				if from != "" {
+					ui.Printf("👤 From: %s\n", from)
+				}
+				if since != "" || until != "" {
+					ui.Printf("📅 Messages from %s to %s\n", valueOr(since, "the first"), valueOr(until, "the last"))
+				}
+				fmt.Fprintln(ui.Stdout)
+			}
+
+			// Pre-generate operation ID for progress monitoring
+			operationID := engine.NewOperationID(domain.OperationMailExtract)
+
+			// Start progress monitoring in a separate goroutine BEFORE starting operation
+			progressCtx, progressCancel := context.WithCancel(ctx)
+			defer progressCancel()
+
+			var progressWg sync.WaitGroup
+			if !quiet && cfg.Operations.EnableProgressBar {
+				progressWg.Add(1)
+				go func() {
+					defer progressWg.Done()
+					MonitorProgress(progressCtx, tracker, operationID, "mail_extract")
+				}()
+				// Give the monitor a moment to start
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			// Execute operation with predefined ID so progress monitoring works
+			result, err := operationEngine.ExecuteOperationWithID(ctx, domain.OperationMailExtract, config, operationID)
+
+			// Stop progress monitoring
+			progressCancel()
+			progressWg.Wait()
+
+			if err != nil {
+				if !quiet {
+					ui.Printf("\n❌ Extraction failed: %v\n", err)
+					displayStoredResultHint(cfg, operationID)
+				}
+				return fmt.Errorf("attachment extraction failed: %w", err)
+			}
+
+			// Display results
+			if !quiet {
+				ui.Printf("\n\n✅ Extraction completed!\n")
+				if result.Summary != "" {
+					ui.Printf("📊 %s\n", result.Summary)
+				}
+				ui.Printf("⏱️  Total time: %v\n", result.Duration.Round(time.Millisecond))
+				if size, _ := result.Details["size"].(int64); size > 0 {
+					ui.Printf("💾 Attachments: %s\n", FormatBytes(size))
+				}
+
+				heading := "📎 Extracted attachments"
+				if dryRun {
+					heading = "📎 Attachments that would be extracted"
+				}
+				listFiles(result, "extracted", heading)
+
+				DisplayOperationWarnings(result)
+				DisplayOperationErrors(result)
+			}
+
+			log.Info("✅ Attachment extraction completed", "summary", result.Summary)
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	// Add flags
+	cmd.Flags().Bool("dry-run", false, "List the attachments that would be extracted without writing them")
+	cmd.Flags().String("dest", "", "Directory to extract the attachments into (required)")
+	cmd.Flags().String("strategy", "date", "Folders to extract into (date, sender, type, flat)")
+	cmd.Flags().StringSlice("pattern", []string{}, "Only extract attachments whose name matches these patterns (e.g. *.pdf)")
+	cmd.Flags().String("from", "", "Only extract attachments of messages whose sender address contains this")
+	cmd.Flags().String("since", "", "Only extract attachments of messages sent on or after this date (YYYY-MM-DD)")
+	cmd.Flags().String("until", "", "Only extract attachments of messages sent on or before this date (YYYY-MM-DD)")
+	cmd.Flags().StringSlice("exclude", []string{".git", "node_modules"}, "Patterns to exclude")
+
+	return cmd
+}
+
+// valueOr returns value, or fallback when it is empty
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
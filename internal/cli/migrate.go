@@ -0,0 +1,202 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// NewMigrateCommand creates the migrate command
+func NewMigrateCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate <old-root> <new-root>",
+		Short: "Move everything to a new drive, verified and resumable",
+		Long: `Copy everything below the old root to the new root, e.g. when moving to a
+new, bigger drive, in one guided run:
+
+  1. Scan the old root.
+  2. Check that the new root has room for what is not there yet.
+  3. Copy the files the new root lacks, keeping the directory structure and
+     modification times. Files already on the new root that an earlier run
+     did not put there are never replaced or removed: they are verified when
+     they look like their original and otherwise reported as differing.
+  4. Verify every copy against its original by checksum
+     (operations.hash_algorithm). Copies of this run that differ are
+     removed again.
+  5. Compare both trees and report what is missing on the new root, what
+     differs and what exists only there; --report writes the comparison to
+     a file.
+
+The old root is only read. Verified files are recorded in ` + engine.MigrationStateFile + `
+in the new root: when a migration is interrupted or some files failed, run
+the same command again and it skips everything verified and continues with
+the rest. The file is removed once both trees match.
+
+  fileops migrate /mnt/old /mnt/new --dry-run
+  fileops migrate /mnt/old /mnt/new --report ~/migration.txt`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+			report, _ := cmd.Flags().GetString("report")
+			sourceConcurrency, _ := cmd.Flags().GetInt("source-concurrency")
+			destConcurrency, _ := cmd.Flags().GetInt("dest-concurrency")
+			parallelism, _ := cmd.Flags().GetInt("parallelism")
+			spaceCheck, _ := cmd.Flags().GetString("space-check")
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			oldRoot, err := filepath.Abs(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid path %s: %w", args[0], err)
+			}
+			if info, err := os.Stat(oldRoot); err != nil || !info.IsDir() {
+				return fmt.Errorf("old root is not a directory: %s", oldRoot)
+			}
+			newRoot, err := filepath.Abs(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid path %s: %w", args[1], err)
+			}
+			if report != "" {
+				if report, err = filepath.Abs(report); err != nil {
+					return fmt.Errorf("invalid report path: %w", err)
+				}
+			}
+
+			config := domain.OperationConfig{
+				DryRun:          dryRun,
+				Recursive:       true,
+				ExcludePatterns: excludePatterns,
+				TargetPaths:     []string{oldRoot},
+				HashAlgorithm:   cfg.Operations.HashAlgorithm,
+				Parallelism:     parallelism,
+				CustomSettings: map[string]interface{}{
+					"destination":             newRoot,
+					"report":                  report,
+					"source_concurrency":      sourceConcurrency,
+					"destination_concurrency": destConcurrency,
+					"space_check":             spaceCheck,
+				},
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+
+			tracker := progress.NewTracker()
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
+
+			log.Info("🚚 Starting migration",
+				"old_root", oldRoot,
+				"new_root", newRoot,
+				"dry_run", dryRun)
+
+			if !quiet {
+				ui.Printf("🚚 Migrating %s to %s\n", oldRoot, newRoot)
+				if dryRun {
+					ui.Printf("📋 DRY RUN MODE: Nothing will be copied\n")
+				}
+				if _, err := os.Stat(filepath.Join(newRoot, engine.MigrationStateFile)); err == nil {
+					ui.Printf("⏯️  Resuming an earlier migration: verified files are skipped\n")
+				}
+				ui.Printf("   Scan → check space → copy → verify → compare\n")
+				fmt.Fprintln(ui.Stdout)
+			}
+
+			if !confirmLargeOperation(cmd, cfg, operationEngine, domain.OperationMigration, config) {
+				ui.Printf("Nothing was changed\n")
+				return nil
+			}
+
+			operationID := engine.NewOperationID(domain.OperationMigration)
+
+			progressCtx, progressCancel := context.WithCancel(ctx)
+			defer progressCancel()
+
+			var progressWg sync.WaitGroup
+			if !quiet && cfg.Operations.EnableProgressBar {
+				progressWg.Add(1)
+				go func() {
+					defer progressWg.Done()
+					MonitorProgress(progressCtx, tracker, operationID, "migration")
+				}()
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			result, err := operationEngine.ExecuteOperationWithID(ctx, domain.OperationMigration, config, operationID)
+
+			progressCancel()
+			progressWg.Wait()
+
+			if err != nil {
+				if !quiet {
+					ui.Printf("\n❌ Migration stopped: %v\n", err)
+					ui.Printf("💡 Run the same command again to resume\n")
+					displayStoredResultHint(cfg, operationID)
+				}
+				return fmt.Errorf("migration failed: %w", err)
+			}
+
+			if !quiet {
+				ui.Printf("\n\n📊 %s\n", result.Summary)
+				ui.Printf("⏱️  Total time: %v\n", result.Duration.Round(time.Millisecond))
+				if copied, ok := result.Details["copied_size"].(int64); ok && copied > 0 {
+					if dryRun {
+						ui.Printf("💾 To copy: %s\n", FormatBytes(copied))
+					} else {
+						ui.Printf("💾 Copied: %s\n", FormatBytes(copied))
+					}
+				}
+				if estimate, ok := result.Details["space_estimate"].(*engine.SpaceEstimate); ok && !estimate.Sufficient {
+					ui.Printf("⚠️  The new root needs %s but has %s available\n",
+						FormatBytes(estimate.Required), FormatBytes(estimate.Available))
+				}
+
+				listFiles(result, "missing", "❓ Missing on the new root")
+				listFiles(result, "differing", "≠  Differing from the old root")
+				listFiles(result, "mismatched", "❌ Copies that failed verification (removed, copied again by the next run)")
+				listFiles(result, "extra", "➕ Only on the new root")
+
+				displayCostEstimate(result)
+				DisplayOperationWarnings(result)
+				DisplayOperationErrors(result)
+
+				if path, ok := result.Details["report"].(string); ok {
+					ui.Printf("\n📄 Report: %s\n", path)
+				}
+				switch {
+				case dryRun:
+				case result.Details["complete"] == true:
+					ui.Printf("\n✅ Every file of %s has a verified copy on %s\n", oldRoot, newRoot)
+				default:
+					ui.Printf("\n⏯️  The migration is incomplete; run the same command again to resume\n")
+				}
+			}
+
+			log.Info("✅ Migration completed", "summary", result.Summary)
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	cmd.Flags().Bool("dry-run", false, "Show what would be copied without copying anything")
+	cmd.Flags().StringSlice("exclude", []string{}, "Patterns to exclude")
+	cmd.Flags().String("report", "", "Write the comparison of both roots to this file")
+	cmd.Flags().Int("source-concurrency", 2, "Number of parallel readers of the old root")
+	cmd.Flags().Int("dest-concurrency", 2, "Maximum number of concurrent writes to the new root")
+	cmd.Flags().Int("parallelism", cfg.Performance.MaxWorkers, "Number of files verified in parallel")
+	cmd.Flags().String("space-check", "refuse", "Free space preflight of the new root (refuse, warn, off)")
+
+	return cmd
+}
@@ -0,0 +1,206 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/monitor"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// volumeColumns are the columns of the monitored volumes
+var volumeColumns = []tableColumn{
+	pathColumn("volume"),
+	percentColumn("used"),
+	sizeColumn("available"),
+	textColumn("threshold"),
+	textColumn("state"),
+	pathColumn("pipeline"),
+}
+
+// NewMonitorCommand creates the monitor command
+func NewMonitorCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "monitor",
+		Short: "Run a pipeline when a volume fills up",
+		Long: `Watch the free space of the volumes configured under monitor.volumes and
+run a volume's pipeline once its usage crosses the threshold, e.g. clear
+caches when /home is 90% full:
+
+  monitor:
+    interval: "5m"
+    volumes:
+      - path: "/home"
+        threshold: "90%"          # or the free space below which to act, e.g. "20GB"
+        pipeline: "~/.fileops/pipelines/clear-caches.yaml"
+        cooldown: "6h"
+        notify: "notify-send fileops"
+
+The volumes are checked every monitor.interval until interrupted. A pipeline
+runs at most once per cooldown; while the volume stays full within the
+cooldown it is not run again. The notify command is run with the message of
+each event as its last argument: the pipeline completed or failed, the volume
+is still full within its cooldown, or it is below its threshold again.
+
+  fileops monitor --status
+  fileops monitor --once --dry-run`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			once, _ := cmd.Flags().GetBool("once")
+			status, _ := cmd.Flags().GetBool("status")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			interval, _ := cmd.Flags().GetDuration("interval")
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			volumes, err := monitorVolumes(cfg.Monitor.Volumes)
+			if err != nil {
+				return err
+			}
+			if len(volumes) == 0 {
+				path, _ := config.File()
+				return fmt.Errorf("no volumes to monitor: configure monitor.volumes in %s", path)
+			}
+			if !quiet || status {
+				displayVolumes(volumes)
+			}
+			if status {
+				return nil
+			}
+
+			operationEngine, err := newOperationEngine(cmd, cfg, log, progress.NewTracker())
+			if err != nil {
+				return err
+			}
+			m, err := monitor.New(operationEngine, volumes, dryRun)
+			if err != nil {
+				return err
+			}
+
+			report := func(event monitor.Event) {
+				icon, message := monitorMessage(event, dryRun)
+				if event.Kind == monitor.EventFailed {
+					log.Error("Monitored volume", "event", event.Kind, "volume", event.Volume.Path, "error", event.Err)
+				} else {
+					log.Info("Monitored volume", "event", event.Kind, "volume", event.Volume.Path, "used_percent", event.Before.UsedPercent)
+				}
+				if !quiet {
+					ui.Printf("%s %s %s\n", time.Now().Format("2006-01-02 15:04:05"), icon, message)
+					if event.Kind == monitor.EventCompleted {
+						for _, step := range event.Steps {
+							if step.Result != nil {
+								DisplayOperationErrors(step.Result)
+							}
+						}
+					}
+				}
+				// The triggered event is followed by completed or failed
+				if event.Kind == monitor.EventTriggered || event.Volume.Notify == "" {
+					return
+				}
+				if err := monitor.Notify(ctx, event.Volume.Notify, event, message); err != nil {
+					log.Warn("Notification failed", "volume", event.Volume.Path, "error", err)
+					if !quiet {
+						ui.Printf("⚠️  Notification failed: %v\n", err)
+					}
+				}
+			}
+
+			if once {
+				m.Check(ctx, report)
+				return nil
+			}
+			if interval <= 0 {
+				interval, _ = time.ParseDuration(cfg.Monitor.Interval)
+			}
+			log.Info("👀 Monitoring volumes", "volumes", len(volumes), "interval", interval, "dry_run", dryRun)
+			if !quiet {
+				ui.Printf("\n👀 Checking every %v (Ctrl+C to stop)\n", interval)
+				if dryRun {
+					ui.Printf("📋 DRY RUN MODE: Pipelines run as dry runs\n")
+				}
+			}
+			return m.Run(ctx, interval, report)
+		},
+	}
+
+	cmd.Flags().Bool("once", false, "Check the volumes once and exit")
+	cmd.Flags().Bool("status", false, "Only show the usage of the volumes")
+	cmd.Flags().Bool("dry-run", false, "Run the pipelines as dry runs")
+	cmd.Flags().Duration("interval", 0, "How often the volumes are checked (default: monitor.interval)")
+
+	return cmd
+}
+
+// monitorVolumes turns the configured volumes into monitored ones
+func monitorVolumes(configured []config.MonitorVolume) ([]monitor.Volume, error) {
+	volumes := make([]monitor.Volume, 0, len(configured))
+	for _, volume := range configured {
+		v := monitor.Volume{Path: volume.Path, Pipeline: volume.Pipeline, Notify: volume.Notify}
+		threshold := strings.TrimSpace(volume.Threshold)
+		if strings.HasSuffix(threshold, "%") {
+			percent, err := strconv.ParseFloat(strings.TrimSuffix(threshold, "%"), 64)
+			if err != nil || percent <= 0 || percent > 100 {
+				return nil, fmt.Errorf("volume %s: invalid threshold %q (use a used share such as 90%% or a free space such as 20GB)", volume.Path, volume.Threshold)
+			}
+			v.UsedPercent = percent
+		} else if v.MinFree = ParseSize(threshold, -1); v.MinFree <= 0 {
+			return nil, fmt.Errorf("volume %s: invalid threshold %q (use a used share such as 90%% or a free space such as 20GB)", volume.Path, volume.Threshold)
+		}
+		if volume.Cooldown != "" {
+			v.Cooldown, _ = time.ParseDuration(volume.Cooldown) // Validated with the configuration
+		}
+		volumes = append(volumes, v)
+	}
+	return volumes, nil
+}
+
+// displayVolumes shows the usage of every volume against its threshold
+func displayVolumes(volumes []monitor.Volume) {
+	rows := newTable(volumeColumns...)
+	for _, volume := range volumes {
+		threshold := fmt.Sprintf("%s free", FormatBytes(volume.MinFree))
+		if volume.UsedPercent > 0 {
+			threshold = fmt.Sprintf("%.0f%% used", volume.UsedPercent)
+		}
+		usage, err := monitor.Measure(volume.Path)
+		state := "ok"
+		switch {
+		case err != nil:
+			state = "unavailable"
+		case volume.Full(usage):
+			state = "full"
+		}
+		rows.addRow(volume.Path, usage.UsedPercent/100, int64(usage.Available), threshold, state, volume.Pipeline)
+	}
+	rows.render(ui.Stdout, tableOptions{Indent: "  "})
+}
+
+// monitorMessage describes an event in one line, as shown and notified, and
+// returns the icon shown with it
+func monitorMessage(event monitor.Event, dryRun bool) (string, string) {
+	volume := event.Volume.Path
+	used := fmt.Sprintf("%.0f%% used, %s available", event.Before.UsedPercent, FormatBytes(int64(event.Before.Available)))
+	switch event.Kind {
+	case monitor.EventTriggered:
+		return "🚨", fmt.Sprintf("%s is full (%s): running %s", volume, used, event.Volume.Pipeline)
+	case monitor.EventCompleted:
+		if event.After == nil || dryRun {
+			return "✅", fmt.Sprintf("%s: %s completed", volume, event.Volume.Pipeline)
+		}
+		freed := int64(event.After.Available) - int64(event.Before.Available)
+		return "✅", fmt.Sprintf("%s: %s freed %s, now %.0f%% used", volume, event.Volume.Pipeline, FormatBytes(max(freed, 0)), event.After.UsedPercent)
+	case monitor.EventCoolingDown:
+		return "⏳", fmt.Sprintf("%s is still full (%s): %s ran within the last %v", volume, used, event.Volume.Pipeline, event.Volume.Cooldown)
+	case monitor.EventRecovered:
+		return "💚", fmt.Sprintf("%s is below its threshold again (%s)", volume, used)
+	default:
+		return "❌", fmt.Sprintf("%s: %v", volume, event.Err)
+	}
+}
@@ -0,0 +1,217 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// snapshotStaleAfter is how old the snapshot of a running operation may get
+// before its process is assumed to have exited
+const snapshotStaleAfter = 10 * time.Second
+
+// NewOpsCommand creates the ops command
+func NewOpsCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	opsCmd := &cobra.Command{
+		Use:   "ops",
+		Short: "Inspect operations running in other processes",
+		Long: `Every operation writes a snapshot of its progress to the progress directory
+(operations.progress_directory) once per second and when it ends, so its
+progress can be followed from another terminal:
+
+  fileops ops status                                  # recent operations
+  fileops ops status --columns id,status --sort updated
+  fileops ops status deduplication-01HN3ZB2V6P8N4K1S7TQ9G3AXF --watch`,
+	}
+
+	// Add subcommands
+	opsCmd.AddCommand(
+		newOpsStatusCommand(ctx, cfg, log),
+	)
+
+	return opsCmd
+}
+
+// newOpsStatusCommand creates the ops status subcommand
+func newOpsStatusCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status [operation-id]",
+		Short: "Show the progress of an operation, or list recent operations",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			watch, _ := cmd.Flags().GetBool("watch")
+			limit, _ := cmd.Flags().GetInt("limit")
+
+			dir := cfg.Operations.ProgressDirectory
+			if dir == "" {
+				return fmt.Errorf("progress is not persisted (operations.progress_directory is empty)")
+			}
+
+			if len(args) == 0 {
+				snapshots, err := progress.ListSnapshots(dir)
+				if err != nil && !errors.Is(err, os.ErrNotExist) {
+					return fmt.Errorf("failed to list operations: %w", err)
+				}
+				if len(snapshots) == 0 {
+					ui.Printf("No operations recorded in %s\n", dir)
+					return nil
+				}
+				if limit > 0 && len(snapshots) > limit {
+					snapshots = snapshots[:limit]
+				}
+				operations := newTable(opsColumns...)
+				for _, snapshot := range snapshots {
+					operations.addRow(snapshot.ID, string(snapshot.OperationType), snapshotState(snapshot),
+						snapshot.UpdatedAt, snapshotLine(snapshot))
+				}
+				options := tableFlags(cmd)
+				options.Indent = ""
+				return operations.render(ui.Stdout, options)
+			}
+
+			for {
+				snapshot, err := progress.LoadSnapshot(dir, args[0])
+				if err != nil {
+					if errors.Is(err, os.ErrNotExist) {
+						return fmt.Errorf("no progress recorded for operation %s", args[0])
+					}
+					return fmt.Errorf("failed to read progress: %w", err)
+				}
+				displaySnapshot(snapshot)
+				if snapshotStale(snapshot) {
+					displayUncommittedChanges(cfg, snapshot.ID)
+				}
+				if !watch || snapshot.Finished() || snapshotStale(snapshot) {
+					return nil
+				}
+
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(time.Second):
+				}
+				fmt.Fprintln(ui.Stdout)
+			}
+		},
+	}
+
+	cmd.Flags().BoolP("watch", "w", false, "Refresh every second until the operation ends")
+	cmd.Flags().Int("limit", 20, "Number of operations to list (0 = all)")
+	addTableFlags(cmd, opsColumns...)
+
+	return cmd
+}
+
+// opsColumns are the columns of the list of recent operations
+var opsColumns = []tableColumn{
+	idColumn("id"),
+	textColumn("type"),
+	textColumn("status"),
+	timeColumn("updated"),
+	textColumn("progress"),
+}
+
+// snapshotStale reports whether a running operation stopped writing snapshots
+func snapshotStale(snapshot *progress.Snapshot) bool {
+	return !snapshot.Finished() && time.Since(snapshot.UpdatedAt) > snapshotStaleAfter
+}
+
+// snapshotState returns the status of an operation, noting lost processes
+func snapshotState(snapshot *progress.Snapshot) string {
+	if snapshotStale(snapshot) {
+		return "lost"
+	}
+	return string(snapshot.Status)
+}
+
+// snapshotLine summarizes the progress of an operation in one line
+func snapshotLine(snapshot *progress.Snapshot) string {
+	if snapshot.Finished() {
+		return fmt.Sprintf("%d items, %s, ended %s", snapshot.ItemsProcessed, FormatBytes(snapshot.BytesProcessed),
+			snapshot.UpdatedAt.Format("2006-01-02 15:04:05"))
+	}
+	line := fmt.Sprintf("%s (%d/%d)", snapshot.CurrentStep, snapshot.StepsCompleted, snapshot.TotalSteps)
+	if snapshot.TotalItems > 0 {
+		line += fmt.Sprintf(", %.1f%%", float64(snapshot.ItemsProcessed)/float64(snapshot.TotalItems)*100)
+	}
+	return line
+}
+
+// displaySnapshot prints the persisted progress of an operation
+func displaySnapshot(snapshot *progress.Snapshot) {
+	ui.Printf("🆔 %s (%s, pid %d)\n", snapshot.ID, snapshot.OperationType, snapshot.PID)
+	ui.Printf("📌 Status: %s\n", snapshot.Status)
+	if snapshotStale(snapshot) {
+		ui.Printf("⚠️  No update for %v, the process has probably exited\n", time.Since(snapshot.UpdatedAt).Round(time.Second))
+	}
+	ui.Printf("🔄 Step: %s (%d/%d)\n", snapshot.CurrentStep, snapshot.StepsCompleted, snapshot.TotalSteps)
+
+	if snapshot.TotalItems > 0 {
+		ui.Printf("📊 Items: %d/%d (%.1f%%)\n", snapshot.ItemsProcessed, snapshot.TotalItems,
+			float64(snapshot.ItemsProcessed)/float64(snapshot.TotalItems)*100)
+	} else {
+		ui.Printf("📊 Items: %d\n", snapshot.ItemsProcessed)
+	}
+	if snapshot.TotalBytes > 0 {
+		ui.Printf("📦 Bytes: %s/%s\n", FormatBytes(snapshot.BytesProcessed), FormatBytes(snapshot.TotalBytes))
+	} else if snapshot.BytesProcessed > 0 {
+		ui.Printf("📦 Bytes: %s\n", FormatBytes(snapshot.BytesProcessed))
+	}
+
+	end := snapshot.UpdatedAt
+	if snapshot.EndTime != nil {
+		end = *snapshot.EndTime
+	}
+	ui.Printf("⏱️  Elapsed: %v\n", end.Sub(snapshot.StartTime).Round(time.Second))
+
+	if !snapshot.Finished() {
+		if snapshot.Speed > 0 || snapshot.ByteSpeed > 0 {
+			ui.Printf("⚡ Speed: %d items/s, %s/s\n", snapshot.Speed, FormatBytes(snapshot.ByteSpeed))
+		}
+		if snapshot.EstimatedETA != nil && *snapshot.EstimatedETA > 0 {
+			ui.Printf("⏳ ETA: %v\n", snapshot.EstimatedETA.Round(time.Second))
+		}
+	}
+
+	if count, ok := snapshot.Details["error_count"].(float64); ok && count > 0 {
+		ui.Printf("⚠️  Errors: %.0f\n", count)
+	}
+	if snapshot.Error != "" {
+		ui.Printf("❌ Last error: %s\n", snapshot.Error)
+	}
+	if changes, ok := snapshot.Details["committed_changes"].(float64); ok {
+		batches, _ := snapshot.Details["committed_batches"].(float64)
+		ui.Printf("🧾 Changes committed to the undo journal: %.0f in %.0f batches\n", changes, batches)
+	}
+}
+
+// displayUncommittedChanges lists the changes of an interrupted run that
+// follow the last committed batch of its undo journal
+func displayUncommittedChanges(cfg *config.Config, id string) {
+	if cfg.Operations.RunsDirectory == "" {
+		return
+	}
+	dir := filepath.Join(cfg.Operations.RunsDirectory, id)
+	changes, err := engine.UncommittedChanges(dir)
+	if err != nil || len(changes) == 0 {
+		return
+	}
+	ui.Printf("🔧 %d changes after the last committed batch may need reconciliation (%s):\n", len(changes), filepath.Join(dir, engine.ArtifactUndo))
+	for _, change := range changes {
+		if change.Target != "" {
+			ui.Printf("  %s %s -> %s\n", change.Action, change.Path, change.Target)
+		} else {
+			ui.Printf("  %s %s\n", change.Action, change.Path)
+		}
+	}
+}
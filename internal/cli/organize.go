@@ -3,45 +3,301 @@ package cli
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/content"
+	"github.com/a4abhishek/fileops/internal/engine"
 	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // NewOrganizeCommand creates the organize command
 func NewOrganizeCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "organize [path]",
-		Short: "Intelligently organize files using AI",
-		Long: `Intelligently organize files using AI-powered classification and content analysis.
+		Short: "Organize files into folders by content, type or date",
+		Long: `Organize files into category folders below the destination (default: the path itself).
 
-This command analyzes file content, metadata, and naming patterns to automatically
-organize files into a logical directory structure based on type, date, project, or custom rules.`,
+Strategies:
+  content  Classify documents (PDF, Office, text) by keywords found in their
+           text, e.g. Invoices, Receipts, Contracts. Rules come from the
+           content.rules configuration or --rules files; documents matching
+           no rule stay where they are. Text from the content index (see
+           "fileops index") is reused when it is up to date.
+  type     Group files by type: images, videos, audio, documents, archives, other
+  date     Group files by modification month: YYYY/MM
+
+With --ocr the content strategy also reads scanned PDFs and photographed
+documents (JPEG, PNG, TIFF, ...) through tesseract, or through the AI service
+when tesseract is not installed. Recognized text is cached in the results
+repository until the file changes. --rename names documents after the date and
+vendor found in their text, e.g. "2024-03-05 ACME Ltd.pdf".
+
+RAW+JPEG pairs (IMG_0042.CR2 next to IMG_0042.JPG) move together, to where
+the JPEG belongs. Sidecars (IMG_0042.xmp, IMG_0042.AAE, movie.en.srt,
+movie.nfo, the .mov of a Live Photo) move with their file and are renamed
+along with it.
+
+--keep-albums leaves curated album folders as they are and only sorts the loose
+files around them, so a date strategy does not tear manual albums apart. A
+folder is an album when it holds a marker file (--album-marker, default
+.album) or its name matches an --album-pattern:
+
+  fileops organize ~/Pictures --strategy date --keep-albums --album-pattern "* - *"
+
+Files whose extension is set to skip or keep (--extension raw=skip, or
+operations.extensions) stay where they are.
+
+Use --dry-run to preview where each file would go.`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Get flags
 			strategy, _ := cmd.Flags().GetString("strategy")
 			dryRun, _ := cmd.Flags().GetBool("dry-run")
 			preserveStructure, _ := cmd.Flags().GetBool("preserve-structure")
+			ruleFiles, _ := cmd.Flags().GetStringSlice("rules")
+			deepAnalysis, _ := cmd.Flags().GetBool("deep-analysis")
+			destination, _ := cmd.Flags().GetString("dest")
+			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+			useOCR, _ := cmd.Flags().GetBool("ocr")
+			ocrEngine, _ := cmd.Flags().GetString("ocr-engine")
+			rename, _ := cmd.Flags().GetBool("rename")
+			keepAlbums, _ := cmd.Flags().GetBool("keep-albums")
+			albumPatterns, _ := cmd.Flags().GetStringSlice("album-pattern")
+			albumMarker, _ := cmd.Flags().GetString("album-marker")
+			if (len(albumPatterns) > 0 || cmd.Flags().Changed("album-marker")) && !keepAlbums {
+				return fmt.Errorf("--album-pattern and --album-marker require --keep-albums")
+			}
+
+			// Validate path
+			absPath, err := filepath.Abs(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid path %s: %w", args[0], err)
+			}
+			if _, err := os.Stat(absPath); os.IsNotExist(err) {
+				return fmt.Errorf("path does not exist: %s", absPath)
+			}
+			if destination == "" {
+				destination = absPath
+			}
+			absDestination, err := filepath.Abs(destination)
+			if err != nil {
+				return fmt.Errorf("invalid destination %s: %w", destination, err)
+			}
 
-			log.Info("🤖 Starting intelligent organization",
-				"path", args[0],
+			rules := cfg.Content.Rules
+			if len(ruleFiles) > 0 {
+				if rules, err = loadRuleFiles(ruleFiles); err != nil {
+					return err
+				}
+			}
+
+			// Create operation configuration
+			settings := map[string]interface{}{
+				"strategy":           strategy,
+				"destination":        absDestination,
+				"preserve_structure": preserveStructure,
+				"max_text_size":      cfg.Content.MaxTextSize,
+				"rules":              rules,
+				"rename":             rename,
+				"ocr":                useOCR,
+				"ocr_engine":         ocrEngine,
+				"ocr_languages":      cfg.OCR.Languages,
+				"tesseract_path":     cfg.OCR.TesseractPath,
+				"ocr_max_pages":      cfg.OCR.MaxPages,
+				"ocr_timeout":        cfg.OCR.Timeout,
+				"keep_albums":        keepAlbums,
+				"album_patterns":     albumPatterns,
+				"album_marker":       albumMarker,
+			}
+			if cfg.AI.Enabled {
+				settings["ocr_service_url"] = cfg.AI.PythonServiceURL
+			}
+			if !deepAnalysis {
+				// Deep analysis re-extracts every document instead of trusting the index
+				settings["index_directory"] = cfg.Content.IndexDirectory
+			}
+			config := domain.OperationConfig{
+				DryRun:          dryRun,
+				Recursive:       true,
+				ExcludePatterns: excludePatterns,
+				TargetPaths:     []string{absPath},
+				CustomSettings:  settings,
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+			if err := applyExtensions(cmd, cfg, &config); err != nil {
+				return err
+			}
+
+			// Create engine
+			tracker := progress.NewTracker()
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
+
+			// Get quiet flag from root command
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			log.Info("🗂️ Starting organization",
+				"path", absPath,
+				"destination", absDestination,
 				"strategy", strategy,
 				"dry_run", dryRun,
 				"preserve_structure", preserveStructure)
 
-			// TODO: Implement AI-powered file organization
-			return fmt.Errorf("organize feature not implemented yet")
+			// Show initial status
+			if !quiet {
+				ui.Printf("🔍 Scanning files to organize...\n")
+				if dryRun {
+					ui.Printf("📋 DRY RUN MODE: No files will be moved\n")
+				}
+				ui.Printf("📂 Path: %s\n", absPath)
+				ui.Printf("🎯 Destination: %s\n", absDestination)
+				ui.Printf("🧭 Strategy: %s\n", strategy)
+				if keepAlbums {
+					ui.Printf("📚 Album folders are left as they are\n")
+				}
+				fmt.Fprintln(ui.Stdout)
+			}
+
+			// Estimate large runs and ask before changing anything
+			if !confirmLargeOperation(cmd, cfg, operationEngine, domain.OperationOrganization, config) {
+				ui.Printf("Nothing was changed\n")
+				return nil
+			}
+
+			// Pre-generate operation ID for progress monitoring
+			operationID := engine.NewOperationID(domain.OperationOrganization)
+
+			// Start progress monitoring in a separate goroutine BEFORE starting operation
+			progressCtx, progressCancel := context.WithCancel(ctx)
+			defer progressCancel()
+
+			var progressWg sync.WaitGroup
+			if !quiet && cfg.Operations.EnableProgressBar {
+				progressWg.Add(1)
+				go func() {
+					defer progressWg.Done()
+					MonitorProgress(progressCtx, tracker, operationID, "organization")
+				}()
+				// Give the monitor a moment to start
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			// Execute operation with predefined ID so progress monitoring works
+			result, err := operationEngine.ExecuteOperationWithID(ctx, domain.OperationOrganization, config, operationID)
+
+			// Stop progress monitoring
+			progressCancel()
+			progressWg.Wait()
+
+			if err != nil {
+				if !quiet {
+					ui.Printf("\n❌ Organization failed: %v\n", err)
+					displayStoredResultHint(cfg, operationID)
+				}
+				return fmt.Errorf("organization failed: %w", err)
+			}
+
+			// Display results
+			if !quiet {
+				ui.Printf("\n\n✅ Organization completed!\n")
+				if result.Summary != "" {
+					ui.Printf("📊 %s\n", result.Summary)
+				}
+				ui.Printf("⏱️  Total time: %v\n", result.Duration.Round(time.Millisecond))
+
+				if suggestions, ok := result.Details["suggestions"].([]domain.OrganizationSuggestion); ok && len(suggestions) > 0 {
+					ui.Printf("\n📁 Files to organize (%d total):\n", len(suggestions))
+					for i, suggestion := range suggestions {
+						if i >= 20 {
+							ui.Printf("  ... and %d more files\n", len(suggestions)-20)
+							break
+						}
+						prefix := "✓ Moved"
+						if dryRun {
+							prefix = "[DRY RUN] Would move"
+						}
+						ui.Printf("  %s %s → %s\n      %s (%s)\n", prefix, suggestion.File.Path,
+							suggestion.SuggestedPath, suggestion.Category, suggestion.Reason)
+					}
+				}
+
+				if albums, ok := result.Details["albums"].([]string); ok && len(albums) > 0 {
+					ui.Printf("\n📚 Album folders left as they are (%d):\n", len(albums))
+					for i, album := range albums {
+						if i >= 10 {
+							ui.Printf("  ... and %d more\n", len(albums)-10)
+							break
+						}
+						ui.Printf("  %s\n", album)
+					}
+				}
+
+				displayCostEstimate(result)
+				DisplayOperationWarnings(result)
+				DisplayOperationErrors(result)
+			}
+
+			log.Info("✅ Organization completed", "summary", result.Summary)
+			return warningPolicy(cmd, result)
 		},
 	}
 
 	// Add flags
-	cmd.Flags().String("strategy", "type", "Organization strategy (type, date, project, smart)")
+	cmd.Flags().String("strategy", "type", "Organization strategy (content, type, date)")
 	cmd.Flags().Bool("dry-run", false, "Preview changes without executing them")
 	cmd.Flags().Bool("preserve-structure", false, "Preserve existing directory structure")
 	cmd.Flags().StringSlice("rules", []string{}, "Custom organization rules file")
 	cmd.Flags().Bool("deep-analysis", false, "Enable deep content analysis (slower but more accurate)")
+	cmd.Flags().String("dest", "", "Destination for the category folders (default: the organized path)")
+	cmd.Flags().StringSlice("exclude", []string{".git", "node_modules"}, "Patterns to exclude")
+	cmd.Flags().StringSlice("extension", []string{}, "Files to leave where they are (skip, keep) by extension (pattern=action, e.g. raw=skip; repeatable, adds to operations.extensions)")
+	cmd.Flags().Bool("ocr", cfg.OCR.Enabled, "Recognize text in scanned PDFs and images (content strategy)")
+	cmd.Flags().String("ocr-engine", cfg.OCR.Engine, "OCR engine (auto, tesseract, service)")
+	cmd.Flags().Bool("rename", false, "Rename documents after their recognized date and vendor (content strategy)")
+	cmd.Flags().Bool("keep-albums", false, "Leave curated album folders as they are and only sort loose files")
+	cmd.Flags().StringSlice("album-pattern", []string{}, "Folder names that are albums (glob, case-insensitive; with --keep-albums)")
+	cmd.Flags().String("album-marker", engine.DefaultAlbumMarker, "File that marks a folder as an album (with --keep-albums)")
 
 	return cmd
 }
+
+// loadRuleFiles reads classification rules from YAML/JSON files with a
+// top-level "rules" list in the format of the content.rules configuration
+func loadRuleFiles(files []string) ([]content.Rule, error) {
+	rules := make([]content.Rule, 0)
+	for _, file := range files {
+		v := viper.New()
+		v.SetConfigFile(file)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("failed to read rules file %s: %w", file, err)
+		}
+
+		var fileRules []content.Rule
+		if err := v.UnmarshalKey("rules", &fileRules); err != nil {
+			return nil, fmt.Errorf("invalid rules file %s: %w", file, err)
+		}
+		for i, rule := range fileRules {
+			if rule.Category == "" || len(rule.Keywords) == 0 {
+				return nil, fmt.Errorf("rules file %s: rule %d needs a category and at least one keyword", file, i+1)
+			}
+		}
+		rules = append(rules, fileRules...)
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no rules found in %v", files)
+	}
+	return rules, nil
+}
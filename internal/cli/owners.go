@@ -0,0 +1,245 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// NewOwnersCommand creates the owners command
+func NewOwnersCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "owners [path...]",
+		Short: "Summarize file counts and bytes per user and group",
+		Long: `Summarize how many files, directories and bytes every user and group
+owns below the given paths. Nothing is changed.
+
+With --not-user or --not-group only items owned by someone else are counted
+and listed, which shows what a chown would change:
+
+  fileops owners /srv/share
+  fileops owners /srv/share --not-user alice --output csv > owners.csv`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get flags
+			notUser, _ := cmd.Flags().GetString("not-user")
+			notGroup, _ := cmd.Flags().GetString("not-group")
+			outputFormat, _ := cmd.Flags().GetString("output")
+			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+			parallelism, _ := cmd.Flags().GetInt("parallelism")
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			switch outputFormat {
+			case "table", "json", "csv":
+			default:
+				return fmt.Errorf("unknown output format %q (use table, json or csv)", outputFormat)
+			}
+
+			notUID, err := lookupOwnerID(notUser, false)
+			if err != nil {
+				return err
+			}
+			notGID, err := lookupOwnerID(notGroup, true)
+			if err != nil {
+				return err
+			}
+
+			// Validate paths
+			validPaths := make([]string, 0, len(args))
+			for _, path := range args {
+				absPath, err := filepath.Abs(path)
+				if err != nil {
+					return fmt.Errorf("invalid path %s: %w", path, err)
+				}
+				if _, err := os.Stat(absPath); os.IsNotExist(err) {
+					return fmt.Errorf("path does not exist: %s", absPath)
+				}
+				validPaths = append(validPaths, absPath)
+			}
+
+			// Create operation configuration
+			config := domain.OperationConfig{
+				DryRun:          true,
+				Recursive:       true,
+				ExcludePatterns: excludePatterns,
+				TargetPaths:     validPaths,
+				Parallelism:     parallelism,
+				CustomSettings: map[string]interface{}{
+					"not_uid": notUID,
+					"not_gid": notGID,
+				},
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+
+			// Create engine
+			tracker := progress.NewTracker()
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
+
+			log.Info("👥 Starting ownership report",
+				"paths", validPaths,
+				"not_user", notUser,
+				"not_group", notGroup)
+
+			// Machine readable output keeps stdout clean
+			table := outputFormat == "table"
+			if table && !quiet {
+				ui.Printf("👥 Summarizing ownership in %v...\n", validPaths)
+			}
+
+			operationID := engine.NewOperationID(domain.OperationOwners)
+
+			progressCtx, progressCancel := context.WithCancel(ctx)
+			defer progressCancel()
+
+			var progressWg sync.WaitGroup
+			if table && !quiet && cfg.Operations.EnableProgressBar {
+				progressWg.Add(1)
+				go func() {
+					defer progressWg.Done()
+					MonitorProgress(progressCtx, tracker, operationID, "owners")
+				}()
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			result, err := operationEngine.ExecuteOperationWithID(ctx, domain.OperationOwners, config, operationID)
+
+			progressCancel()
+			progressWg.Wait()
+
+			if err != nil {
+				if table && !quiet {
+					ui.Printf("\n❌ Ownership report failed: %v\n", err)
+				}
+				return fmt.Errorf("ownership report failed: %w", err)
+			}
+
+			users, _ := result.Details["users"].([]engine.OwnerUsage)
+			groups, _ := result.Details["groups"].([]engine.OwnerUsage)
+
+			switch outputFormat {
+			case "json":
+				encoder := json.NewEncoder(ui.Stdout)
+				encoder.SetIndent("", "  ")
+				report := map[string]interface{}{"users": users, "groups": groups}
+				if notUID >= 0 || notGID >= 0 {
+					report["not_owned"] = result.Details["not_owned"]
+				}
+				if err := encoder.Encode(report); err != nil {
+					return err
+				}
+			case "csv":
+				if err := writeOwnersCSV(users, groups); err != nil {
+					return err
+				}
+			default:
+				if !quiet {
+					displayOwnerUsage("👤 Users", users)
+					displayOwnerUsage("👥 Groups", groups)
+					listFiles(result, "not_owned", "🔑 Owned by someone else")
+					ui.Printf("\n📊 %s\n", result.Summary)
+					DisplayOperationWarnings(result)
+					DisplayOperationErrors(result)
+				}
+			}
+
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	// Add flags
+	cmd.Flags().String("not-user", "", "Only count items not owned by this user (name or uid)")
+	cmd.Flags().String("not-group", "", "Only count items not owned by this group (name or gid)")
+	cmd.Flags().String("output", "table", "Output format (table, json, csv)")
+	cmd.Flags().StringSlice("exclude", []string{}, "Patterns to exclude")
+	cmd.Flags().Int("parallelism", cfg.Performance.MaxWorkers, "Number of paths scanned in parallel")
+
+	return cmd
+}
+
+// lookupOwnerID resolves a user or group name or numeric id; -1 when empty
+func lookupOwnerID(name string, group bool) (int, error) {
+	if name == "" {
+		return -1, nil
+	}
+	if id, err := strconv.Atoi(name); err == nil && id >= 0 {
+		return id, nil
+	}
+	if group {
+		info, err := user.LookupGroup(name)
+		if err != nil {
+			return -1, fmt.Errorf("group not found: %s: %w", name, err)
+		}
+		return strconv.Atoi(info.Gid)
+	}
+	info, err := user.Lookup(name)
+	if err != nil {
+		return -1, fmt.Errorf("user not found: %s: %w", name, err)
+	}
+	return strconv.Atoi(info.Uid)
+}
+
+// ownerLabel returns the name of an owner, or its id when it has none
+func ownerLabel(usage engine.OwnerUsage) string {
+	switch {
+	case usage.ID < 0:
+		return "(unknown)"
+	case usage.Name == "":
+		return strconv.Itoa(usage.ID)
+	}
+	return fmt.Sprintf("%s (%d)", usage.Name, usage.ID)
+}
+
+// displayOwnerUsage prints one table of owners
+func displayOwnerUsage(heading string, usage []engine.OwnerUsage) {
+	if len(usage) == 0 {
+		return
+	}
+	ui.Printf("\n%s:\n", heading)
+	ui.Printf("  %-24s %10s %8s %12s\n", "OWNER", "FILES", "DIRS", "SIZE")
+	for _, entry := range usage {
+		ui.Printf("  %-24s %10d %8d %12s\n", ownerLabel(entry), entry.Files, entry.Dirs, FormatBytes(entry.Bytes))
+	}
+}
+
+// writeOwnersCSV writes users and groups as one CSV table to stdout
+func writeOwnersCSV(users, groups []engine.OwnerUsage) error {
+	w := csv.NewWriter(ui.Stdout)
+	w.Write([]string{"kind", "id", "name", "files", "dirs", "bytes"})
+	for _, table := range []struct {
+		kind  string
+		usage []engine.OwnerUsage
+	}{{"user", users}, {"group", groups}} {
+		for _, entry := range table.usage {
+			w.Write([]string{
+				table.kind,
+				strconv.Itoa(entry.ID),
+				entry.Name,
+				strconv.FormatInt(entry.Files, 10),
+				strconv.FormatInt(entry.Dirs, 10),
+				strconv.FormatInt(entry.Bytes, 10),
+			})
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
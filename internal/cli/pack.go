@@ -0,0 +1,313 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/pack"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// NewPackCommand creates the pack command
+func NewPackCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	packCmd := &cobra.Command{
+		Use:   "pack",
+		Short: "Archive files in a deduplicating chunk store (experimental)",
+		Long: `Archive files in a content-addressed chunk store (experimental).
+
+Files are split into variable-sized chunks with content-defined chunking
+(FastCDC), so an insertion or change only affects the chunks around it. Each
+chunk is stored once under its BLAKE3 hash, which deduplicates data both
+within a snapshot and across snapshots of similar datasets. Every pack run
+records a snapshot index that restore uses to reassemble the files, verifying
+each chunk against its hash.
+
+The store layout may still change between releases.`,
+	}
+
+	// Add subcommands
+	packCmd.AddCommand(
+		newPackCreateCommand(ctx, cfg, log),
+		newPackListCommand(ctx, cfg, log),
+		newPackRestoreCommand(ctx, cfg, log),
+	)
+
+	return packCmd
+}
+
+// newPackCreateCommand creates the pack create subcommand
+func newPackCreateCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "create [store] [path...]",
+		Short: "Pack files into a new snapshot",
+		Long: `Pack files into a new snapshot of the store, creating the store if needed.
+
+Each path is stored under its base name, so restoring a snapshot of
+/data/photos recreates a photos directory below the destination.
+
+With --format tar.zst the snapshot is written as a single zstd-compressed tar
+archive in the store instead of deduplicated chunks. Every file is compressed
+as a separate zstd frame and the snapshot catalog records its offset, so
+restore extracts single files without decompressing the whole archive. The
+archive is also readable by standard tools (tar --zstd -xf) and embeds the
+catalog as its last member, ` + pack.CatalogName + `.`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get flags
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+			format, _ := cmd.Flags().GetString("format")
+
+			store, err := filepath.Abs(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid store %s: %w", args[0], err)
+			}
+
+			// Validate paths
+			validPaths := make([]string, 0, len(args)-1)
+			for _, path := range args[1:] {
+				absPath, err := filepath.Abs(path)
+				if err != nil {
+					return fmt.Errorf("invalid path %s: %w", path, err)
+				}
+				if _, err := os.Stat(absPath); os.IsNotExist(err) {
+					return fmt.Errorf("path does not exist: %s", absPath)
+				}
+				validPaths = append(validPaths, absPath)
+			}
+
+			// Create operation configuration
+			config := domain.OperationConfig{
+				DryRun:          dryRun,
+				Recursive:       true,
+				ExcludePatterns: excludePatterns,
+				TargetPaths:     validPaths,
+				CustomSettings: map[string]interface{}{
+					"store":  store,
+					"format": format,
+				},
+			}
+
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+			if !quiet {
+				ui.Printf("📦 Packing files...\n")
+				if dryRun {
+					ui.Printf("📋 DRY RUN MODE: No chunks will be written\n")
+				}
+				ui.Printf("📂 Paths to pack: %v\n", validPaths)
+				ui.Printf("🗄️  Store: %s\n\n", store)
+			}
+
+			result, err := runPackOperation(ctx, cmd, cfg, log, domain.OperationPack, config, "Pack")
+			if err != nil || quiet {
+				return err
+			}
+
+			if size, ok := result.Details["size"].(int64); ok {
+				ui.Printf("  📄 Data packed: %s\n", FormatBytes(size))
+			}
+			if storedSize, ok := result.Details["stored_size"].(int64); ok {
+				ui.Printf("  💾 New data stored: %s\n", FormatBytes(storedSize))
+			}
+
+			DisplayOperationWarnings(result)
+			DisplayOperationErrors(result)
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	cmd.Flags().Bool("dry-run", false, "Chunk the files and report what would be stored without writing")
+	cmd.Flags().String("format", pack.FormatChunks, "Snapshot format (chunks, tar.zst)")
+	cmd.Flags().StringSlice("exclude", []string{".git", "node_modules"}, "Patterns to exclude")
+
+	return cmd
+}
+
+// newPackListCommand creates the pack list subcommand
+func newPackListCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list [store]",
+		Short: "List the snapshots of a store",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := pack.OpenStore(args[0], false)
+			if err != nil {
+				return err
+			}
+			snapshots, err := store.Snapshots()
+			if err != nil {
+				return err
+			}
+			if len(snapshots) == 0 {
+				fmt.Fprintln(ui.Stdout, "No snapshots")
+				return nil
+			}
+
+			ui.Printf("%-20s %-20s %-8s %8s %12s %12s\n", "ID", "CREATED", "FORMAT", "FILES", "SIZE", "STORED")
+			for _, snapshot := range snapshots {
+				format := snapshot.Format
+				if format == "" {
+					format = pack.FormatChunks
+				}
+				ui.Printf("%-20s %-20s %-8s %8d %12s %12s\n",
+					snapshot.ID,
+					snapshot.Created.Format("2006-01-02 15:04:05"),
+					format,
+					snapshot.Files,
+					FormatBytes(snapshot.Size),
+					FormatBytes(snapshot.StoredSize))
+			}
+			return nil
+		},
+	}
+}
+
+// newPackRestoreCommand creates the pack restore subcommand
+func newPackRestoreCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore [store] [snapshot]",
+		Short: "Restore a snapshot",
+		Long: `Restore the files of a snapshot below the destination directory.
+
+The snapshot defaults to latest. Existing files are never overwritten; use
+--path to restore only some files or directories of the snapshot.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get flags
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			destination, _ := cmd.Flags().GetString("dest")
+			paths, _ := cmd.Flags().GetStringSlice("path")
+
+			if destination == "" {
+				return fmt.Errorf("--dest is required")
+			}
+			absDestination, err := filepath.Abs(destination)
+			if err != nil {
+				return fmt.Errorf("invalid destination %s: %w", destination, err)
+			}
+			store, err := filepath.Abs(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid store %s: %w", args[0], err)
+			}
+			snapshot := "latest"
+			if len(args) > 1 {
+				snapshot = args[1]
+			}
+
+			// Create operation configuration
+			config := domain.OperationConfig{
+				DryRun: dryRun,
+				CustomSettings: map[string]interface{}{
+					"store":       store,
+					"snapshot":    snapshot,
+					"destination": absDestination,
+					"paths":       paths,
+				},
+			}
+
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+			if !quiet {
+				ui.Printf("📦 Restoring snapshot %s...\n", snapshot)
+				if dryRun {
+					ui.Printf("📋 DRY RUN MODE: No files will be written\n")
+				}
+				ui.Printf("🗄️  Store: %s\n", store)
+				ui.Printf("🎯 Destination: %s\n\n", absDestination)
+			}
+
+			result, err := runPackOperation(ctx, cmd, cfg, log, domain.OperationPackRestore, config, "Restore")
+			if err != nil || quiet {
+				return err
+			}
+
+			if dryRun {
+				listFiles(result, "restored", "📄 Files that would be restored")
+			}
+
+			DisplayOperationWarnings(result)
+			DisplayOperationErrors(result)
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	cmd.Flags().Bool("dry-run", false, "Preview the restore without writing files")
+	cmd.Flags().String("dest", "", "Directory to restore into (required)")
+	cmd.Flags().StringSlice("path", []string{}, "Restore only these snapshot paths and their contents")
+
+	return cmd
+}
+
+// runPackOperation executes a pack or restore operation with progress display
+// and prints the common part of its result
+func runPackOperation(ctx context.Context, cmd *cobra.Command, cfg *config.Config, log *logger.Logger, operationType domain.OperationType, config domain.OperationConfig, label string) (*domain.OperationResult, error) {
+	if err := applyGlobalFlags(cmd, &config); err != nil {
+		return nil, err
+	}
+
+	// Create engine
+	tracker := progress.NewTracker()
+	operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+	if err != nil {
+		return nil, err
+	}
+
+	quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+	log.Info("📦 Starting "+string(operationType),
+		"store", config.CustomSettings["store"],
+		"dry_run", config.DryRun)
+
+	// Pre-generate operation ID for progress monitoring
+	operationID := engine.NewOperationID(operationType)
+
+	// Start progress monitoring in a separate goroutine BEFORE starting operation
+	progressCtx, progressCancel := context.WithCancel(ctx)
+	defer progressCancel()
+
+	var progressWg sync.WaitGroup
+	if !quiet && cfg.Operations.EnableProgressBar {
+		progressWg.Add(1)
+		go func() {
+			defer progressWg.Done()
+			MonitorProgress(progressCtx, tracker, operationID, string(operationType))
+		}()
+		// Give the monitor a moment to start
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// Execute operation with predefined ID so progress monitoring works
+	result, err := operationEngine.ExecuteOperationWithID(ctx, operationType, config, operationID)
+
+	// Stop progress monitoring
+	progressCancel()
+	progressWg.Wait()
+
+	if err != nil {
+		if !quiet {
+			ui.Printf("\n❌ %s failed: %v\n", label, err)
+			displayStoredResultHint(cfg, operationID)
+		}
+		return nil, fmt.Errorf("%s failed: %w", string(operationType), err)
+	}
+
+	if !quiet {
+		ui.Printf("\n\n✅ %s completed!\n", label)
+		if result.Summary != "" {
+			ui.Printf("📊 %s\n", result.Summary)
+		}
+		ui.Printf("⏱️  Total time: %v\n", result.Duration.Round(time.Millisecond))
+	}
+
+	log.Info("✅ "+label+" completed", "summary", result.Summary)
+	return result, nil
+}
@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package cli
+
+// readSecret reads a line from standard input. Echo cannot be turned off on
+// this platform; use --key-file or FILEOPS_PASSPHRASE to avoid typing secrets.
+func readSecret() (string, error) {
+	return readLine()
+}
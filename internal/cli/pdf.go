@@ -0,0 +1,306 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// NewPDFCommand creates the pdf command
+func NewPDFCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	pdfCmd := &cobra.Command{
+		Use:   "pdf",
+		Short: "Merge and split PDF documents",
+		Long: `Merge and split PDF documents without external tools.
+
+Pages are copied with everything they use (fonts, images, annotations), but
+document-level parts such as bookmarks, form fields and the document
+information are not carried over. Encrypted PDFs are not supported. Sources
+are never changed.`,
+	}
+
+	// Add subcommands
+	pdfCmd.AddCommand(
+		newPDFMergeCommand(ctx, cfg, log),
+		newPDFSplitCommand(ctx, cfg, log),
+	)
+
+	return pdfCmd
+}
+
+// newPDFMergeCommand creates the pdf merge subcommand
+func newPDFMergeCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "merge [path...]",
+		Short: "Merge the PDFs matching a pattern into one file",
+		Long: `Merge the PDFs below the paths whose name matches --pattern (ignoring case)
+into the --output file, in order of their paths. The output must not exist.
+
+  fileops pdf merge ~/Documents/Invoices/2024 --pattern "invoice-*.pdf" --output ~/invoices-2024.pdf`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get flags
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			pattern, _ := cmd.Flags().GetString("pattern")
+			output, _ := cmd.Flags().GetString("output")
+			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+
+			if output == "" {
+				return fmt.Errorf("--output is required")
+			}
+			absOutput, err := filepath.Abs(output)
+			if err != nil {
+				return fmt.Errorf("invalid output %s: %w", output, err)
+			}
+			validPaths, err := absolutePaths(args)
+			if err != nil {
+				return err
+			}
+
+			// Create operation configuration
+			config := domain.OperationConfig{
+				DryRun:          dryRun,
+				Recursive:       true,
+				ExcludePatterns: excludePatterns,
+				TargetPaths:     validPaths,
+				CustomSettings: map[string]interface{}{
+					"pattern": pattern,
+					"output":  absOutput,
+				},
+			}
+
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+			if !quiet {
+				ui.Printf("📑 Merging PDFs...\n")
+				if dryRun {
+					ui.Printf("📋 DRY RUN MODE: No files will be written\n")
+				}
+				ui.Printf("📂 Paths to process: %v\n", validPaths)
+				ui.Printf("🔎 Pattern: %s\n", pattern)
+				ui.Printf("🎯 Output: %s\n\n", absOutput)
+			}
+
+			result, err := runPDFOperation(ctx, cmd, cfg, log, domain.OperationPDFMerge, config, "Merge")
+			if err != nil || result == nil || quiet {
+				return err
+			}
+
+			if size, _ := result.Details["output_size"].(int64); size > 0 {
+				ui.Printf("💾 Output size: %s\n", FormatBytes(size))
+			}
+			heading := "📄 Merged files"
+			if dryRun {
+				heading = "📄 Files that would be merged"
+			}
+			listFiles(result, "merged", heading)
+
+			DisplayOperationWarnings(result)
+			DisplayOperationErrors(result)
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	cmd.Flags().Bool("dry-run", false, "List the PDFs that would be merged without writing the output")
+	cmd.Flags().String("pattern", engine.DefaultPDFPattern, "File name pattern of the PDFs to merge")
+	cmd.Flags().StringP("output", "o", "", "File to write the merged PDF to (required)")
+	cmd.Flags().StringSlice("exclude", []string{".git", "node_modules"}, "Patterns to exclude")
+
+	return cmd
+}
+
+// newPDFSplitCommand creates the pdf split subcommand
+func newPDFSplitCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "split [path...]",
+		Short: "Split PDFs by page ranges",
+		Long: `Split the PDFs below the paths whose name matches --pattern into one file per
+page range. --ranges takes a list such as "1-3,4-10,11-end"; --every splits
+into chunks of that many pages instead. PDFs with fewer than --min-pages pages
+are left alone.
+
+Each part is named after its source and pages, e.g. report_pages-1-3.pdf, and
+written next to the source or into --dest. A PDF is skipped when one of its
+parts already exists.
+
+  fileops pdf split ~/Scans --every 20 --min-pages 100 --dest ~/Scans/parts`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get flags
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			pattern, _ := cmd.Flags().GetString("pattern")
+			ranges, _ := cmd.Flags().GetString("ranges")
+			every, _ := cmd.Flags().GetInt("every")
+			minPages, _ := cmd.Flags().GetInt("min-pages")
+			destination, _ := cmd.Flags().GetString("dest")
+			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+
+			if (ranges == "") == (every <= 0) {
+				return fmt.Errorf("exactly one of --ranges and --every is required")
+			}
+			if destination != "" {
+				absDestination, err := filepath.Abs(destination)
+				if err != nil {
+					return fmt.Errorf("invalid destination %s: %w", destination, err)
+				}
+				destination = absDestination
+			}
+			validPaths, err := absolutePaths(args)
+			if err != nil {
+				return err
+			}
+
+			// Create operation configuration
+			config := domain.OperationConfig{
+				DryRun:          dryRun,
+				Recursive:       true,
+				ExcludePatterns: excludePatterns,
+				TargetPaths:     validPaths,
+				CustomSettings: map[string]interface{}{
+					"pattern":        pattern,
+					"ranges":         ranges,
+					"pages_per_file": every,
+					"min_pages":      minPages,
+					"destination":    destination,
+				},
+			}
+
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+			if !quiet {
+				ui.Printf("✂️  Splitting PDFs...\n")
+				if dryRun {
+					ui.Printf("📋 DRY RUN MODE: No files will be written\n")
+				}
+				ui.Printf("📂 Paths to process: %v\n", validPaths)
+				if ranges != "" {
+					ui.Printf("📄 Page ranges: %s\n\n", ranges)
+				} else {
+					ui.Printf("📄 Pages per file: %d\n\n", every)
+				}
+			}
+
+			result, err := runPDFOperation(ctx, cmd, cfg, log, domain.OperationPDFSplit, config, "Split")
+			if err != nil || result == nil || quiet {
+				return err
+			}
+
+			heading := "📄 Created files"
+			if dryRun {
+				heading = "📄 Files that would be created"
+			}
+			listFiles(result, "created", heading)
+
+			DisplayOperationWarnings(result)
+			DisplayOperationErrors(result)
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	cmd.Flags().Bool("dry-run", false, "List the files that would be created without writing them")
+	cmd.Flags().String("pattern", engine.DefaultPDFPattern, "File name pattern of the PDFs to split")
+	cmd.Flags().String("ranges", "", "Comma-separated page ranges, one file each (e.g. 1-3,4-end)")
+	cmd.Flags().Int("every", 0, "Split into files of this many pages")
+	cmd.Flags().Int("min-pages", 0, "Only split PDFs with at least this many pages")
+	cmd.Flags().String("dest", "", "Directory for the parts (default: next to their source)")
+	cmd.Flags().StringSlice("exclude", []string{".git", "node_modules"}, "Patterns to exclude")
+
+	return cmd
+}
+
+// absolutePaths returns the arguments as absolute paths that exist
+func absolutePaths(args []string) ([]string, error) {
+	validPaths := make([]string, 0, len(args))
+	for _, path := range args {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path %s: %w", path, err)
+		}
+		if _, err := os.Stat(absPath); os.IsNotExist(err) {
+			return nil, fmt.Errorf("path does not exist: %s", absPath)
+		}
+		validPaths = append(validPaths, absPath)
+	}
+	return validPaths, nil
+}
+
+// runPDFOperation executes a merge or split with progress display and prints
+// the common part of its result
+func runPDFOperation(ctx context.Context, cmd *cobra.Command, cfg *config.Config, log *logger.Logger, operationType domain.OperationType, config domain.OperationConfig, label string) (*domain.OperationResult, error) {
+	if err := applyGlobalFlags(cmd, &config); err != nil {
+		return nil, err
+	}
+
+	// Create engine
+	tracker := progress.NewTracker()
+	operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+	if err != nil {
+		return nil, err
+	}
+
+	quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+	log.Info("📑 Starting "+string(operationType),
+		"paths", config.TargetPaths,
+		"dry_run", config.DryRun)
+
+	// Estimate large runs and ask before writing anything. A declined run
+	// returns no result.
+	if !confirmLargeOperation(cmd, cfg, operationEngine, operationType, config) {
+		ui.Printf("Nothing was changed\n")
+		return nil, nil
+	}
+
+	// Pre-generate operation ID for progress monitoring
+	operationID := engine.NewOperationID(operationType)
+
+	// Start progress monitoring in a separate goroutine BEFORE starting operation
+	progressCtx, progressCancel := context.WithCancel(ctx)
+	defer progressCancel()
+
+	var progressWg sync.WaitGroup
+	if !quiet && cfg.Operations.EnableProgressBar {
+		progressWg.Add(1)
+		go func() {
+			defer progressWg.Done()
+			MonitorProgress(progressCtx, tracker, operationID, string(operationType))
+		}()
+		// Give the monitor a moment to start
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// Execute operation with predefined ID so progress monitoring works
+	result, err := operationEngine.ExecuteOperationWithID(ctx, operationType, config, operationID)
+
+	// Stop progress monitoring
+	progressCancel()
+	progressWg.Wait()
+
+	if err != nil {
+		if !quiet {
+			ui.Printf("\n❌ %s failed: %v\n", label, err)
+			displayStoredResultHint(cfg, operationID)
+		}
+		return nil, fmt.Errorf("%s failed: %w", string(operationType), err)
+	}
+
+	if !quiet {
+		ui.Printf("\n\n✅ %s completed!\n", label)
+		if result.Summary != "" {
+			ui.Printf("📊 %s\n", result.Summary)
+		}
+		ui.Printf("⏱️  Total time: %v\n", result.Duration.Round(time.Millisecond))
+	}
+
+	log.Info("✅ "+label+" completed", "summary", result.Summary)
+	return result, nil
+}
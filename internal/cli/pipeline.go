@@ -3,9 +3,15 @@ package cli
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
 	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/pipeline"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
 	"github.com/spf13/cobra"
 )
 
@@ -17,7 +23,24 @@ func NewPipelineCommand(ctx context.Context, cfg *config.Config, log *logger.Log
 		Long: `Manage and run operation pipelines to chain multiple file operations.
 
 Pipelines allow you to define complex workflows that combine multiple operations
-like cleanup, deduplication, organization, and consolidation in a single execution.`,
+like cleanup, deduplication, organization, and consolidation in a single execution.
+
+A step can process the file set of an earlier step's result instead of its
+own paths, e.g. organize only the files deduplication kept:
+
+  name: tidy-downloads
+  steps:
+    - name: dedup
+      operation: deduplication
+      paths: [~/Downloads]
+    - name: organize
+      operation: organization
+      input: steps.dedup.unique_files
+      settings:
+        destination: ~/Sorted
+        strategy: type
+
+Steps whose input set is empty are skipped.`,
 	}
 
 	// Add subcommands
@@ -32,29 +55,82 @@ like cleanup, deduplication, organization, and consolidation in a single executi
 
 // newPipelineRunCommand creates the pipeline run subcommand
 func newPipelineRunCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "run [pipeline-file]",
 		Short: "Run a pipeline from file",
 		Long: `Run a pipeline defined in a YAML configuration file.
 
-The pipeline file should define a sequence of operations with their configurations
-and dependencies. Operations will be executed in the specified order with proper
-error handling and rollback capabilities.`,
+The steps run in order; a step with an input processes only the files listed
+under that key of the earlier step's result (steps.<name>.<result list>).
+The pipeline stops at the first failing step.`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// Get flags
 			dryRun, _ := cmd.Flags().GetBool("dry-run")
-			parallel, _ := cmd.Flags().GetBool("parallel")
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			p, err := pipeline.Load(args[0])
+			if err != nil {
+				return err
+			}
+			if window, _ := cmd.Root().PersistentFlags().GetString("window"); window != "" {
+				p.Window = window
+			}
+			if maxDuration, _ := cmd.Root().PersistentFlags().GetDuration("max-duration"); maxDuration > 0 {
+				p.MaxDuration = maxDuration
+			}
+			operationEngine, err := newOperationEngine(cmd, cfg, log, progress.NewTracker())
+			if err != nil {
+				return err
+			}
 
 			log.Info("⚙️ Starting pipeline execution",
 				"file", args[0],
-				"dry_run", dryRun,
-				"parallel", parallel)
+				"pipeline", p.Name,
+				"dry_run", dryRun)
+			if !quiet {
+				ui.Printf("⚙️  Running pipeline %s (%d steps)\n", p.Name, len(p.Steps))
+				if dryRun {
+					ui.Printf("📋 DRY RUN MODE: No files will be changed\n")
+				}
+			}
 
-			// TODO: Implement pipeline execution
-			return fmt.Errorf("pipeline execution not implemented yet")
+			var last *domain.OperationResult
+			_, err = p.Run(ctx, operationEngine, dryRun, func(step pipeline.StepResult) {
+				if step.Skipped {
+					if !quiet {
+						ui.Printf("\n⏭️  %s: skipped, %s is empty\n", step.Step, step.Step.Input)
+					}
+					return
+				}
+				last = step.Result
+				if quiet {
+					return
+				}
+				duration := step.Result.EndTime.Sub(step.Result.StartTime)
+				ui.Printf("\n✅ %s (%s): %s\n", step.Step, step.Result.ID, step.Result.Summary)
+				ui.Printf("⏱️  Time: %v\n", duration.Round(time.Millisecond))
+				DisplayOperationWarnings(step.Result)
+				DisplayOperationErrors(step.Result)
+			})
+			if err != nil {
+				if !quiet {
+					ui.Printf("\n❌ Pipeline %s failed: %v\n", p.Name, err)
+				}
+				return err
+			}
+			if !quiet {
+				ui.Printf("\n✅ Pipeline %s completed successfully!\n", p.Name)
+			}
+			if last == nil {
+				return nil
+			}
+			return warningPolicy(cmd, last)
 		},
 	}
+
+	cmd.Flags().Bool("dry-run", false, "Run every step as a dry run")
+
+	return cmd
 }
 
 // newPipelineListCommand creates the pipeline list subcommand
@@ -82,8 +158,15 @@ func newPipelineValidateCommand(ctx context.Context, cfg *config.Config, log *lo
 		RunE: func(cmd *cobra.Command, args []string) error {
 			log.Info("🔍 Validating pipeline configuration", "file", args[0])
 
-			// TODO: Implement pipeline validation
-			return fmt.Errorf("pipeline validation not implemented yet")
+			p, err := pipeline.Load(args[0])
+			if err != nil {
+				return err
+			}
+			if err := p.Validate(engine.NewEngine(nil, nil, log).GetSupportedOperations()); err != nil {
+				return err
+			}
+			ui.Printf("✅ Pipeline %s is valid (%d steps)\n", p.Name, len(p.Steps))
+			return nil
 		},
 	}
 }
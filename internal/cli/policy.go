@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// NewPolicyCommand creates the policy command
+func NewPolicyCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	policyCmd := &cobra.Command{
+		Use:   "policy",
+		Short: "Inspect the operation policy of shared servers",
+		Long: `An operation policy restricts, per user and API token, which operations may
+run, below which paths, and whether they may delete, move or rewrite files.
+The engine checks every operation against it before touching anything;
+refused operations exit with status 3.
+
+The policy is read from operations.policy_file, or from
+/etc/fileops/policy.yaml when that exists:
+
+  roles:
+    viewer:
+      operations: [stats, checksum, deduplication]
+      paths: [/srv/shared]
+    operator:
+      operations: ["*"]
+      paths: [/srv/shared, /srv/media]
+      destructive: true
+  users:
+    alice: operator
+    "*": viewer
+  tokens:
+    "sha256:9f86d0...": operator   # fileops policy hash-token
+
+Callers present an API token in the FILEOPS_API_TOKEN environment variable.`,
+	}
+
+	// Add subcommands
+	policyCmd.AddCommand(
+		newPolicyShowCommand(cfg),
+		newPolicyHashTokenCommand(),
+	)
+
+	return policyCmd
+}
+
+// newPolicyShowCommand creates the policy show subcommand
+func newPolicyShowCommand(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show",
+		Short: "Show what the policy allows the current user",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			policy, path, err := loadPolicy(cfg)
+			if err != nil {
+				return err
+			}
+			if policy == nil {
+				ui.Printf("📜 No operation policy, every operation is allowed\n")
+				return nil
+			}
+
+			principal := currentPrincipal()
+			ui.Printf("📜 Policy: %s\n", path)
+			ui.Printf("👤 User: %s\n", principal.User)
+			if principal.Token != "" {
+				ui.Printf("🎫 Token: %s\n", engine.HashToken(principal.Token))
+			}
+
+			name, role, ok := policy.RoleOf(principal)
+			if !ok {
+				ui.Printf("\n⛔ No role, no operation is allowed\n")
+				return nil
+			}
+			ui.Printf("\n🎭 Role: %s\n", name)
+			ui.Printf("  Operations:  %s\n", strings.Join(role.Operations, ", "))
+			if len(role.Paths) == 0 {
+				ui.Printf("  Paths:       any\n")
+			} else {
+				ui.Printf("  Paths:       %s\n", strings.Join(role.Paths, ", "))
+			}
+			ui.Printf("  Destructive: %t\n", role.Destructive)
+			return nil
+		},
+	}
+}
+
+// newPolicyHashTokenCommand creates the policy hash-token subcommand
+func newPolicyHashTokenCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "hash-token [token]",
+		Short: "Print a token in the form the policy lists it",
+		Long: `Print the SHA-256 of an API token for the tokens section of the policy. The
+token is read from standard input when not given, keeping it out of the
+shell history:
+
+  fileops policy hash-token < token.txt`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var token string
+			if len(args) > 0 {
+				token = args[0]
+			} else {
+				line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+				if err != nil && line == "" {
+					return fmt.Errorf("failed to read the token: %w", err)
+				}
+				token = strings.TrimSpace(line)
+			}
+			if token == "" {
+				return fmt.Errorf("the token is empty")
+			}
+			fmt.Fprintln(ui.Stdout, engine.HashToken(token))
+			return nil
+		},
+	}
+}
@@ -0,0 +1,215 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/reclaim"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// planColumns are the columns of a reclamation plan
+var planColumns = []tableColumn{
+	textColumn("strategy"),
+	textColumn("risk"),
+	sizeColumn("frees"),
+	textColumn("action"),
+}
+
+// NewReclaimCommand creates the reclaim command
+func NewReclaimCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reclaim <path> --target <size>",
+		Short: "Plan and free a given amount of space with the least risk to data",
+		Long: `Analyze a path and propose a plan that frees --target, combining what
+fileops can free in the order of the risk to data:
+
+  1. caches      Partial downloads and build outputs of projects below the
+                 path, and the locations of the cleaner profiles given with
+                 --profile. They are regenerated on demand.
+  2. duplicates  Identical copies, replaced by hardlinks to one copy, so every
+                 path keeps its content. Largest groups first.
+  3. archive     Files of at least --min-size untouched for --older-than days,
+                 moved below --archive-to (on another drive). Largest first.
+                 Only planned with --archive-to.
+
+Duplicates and old files are taken only as far as the target needs. The plan
+is shown with what each strategy could free at most, and executed once it is
+approved. --dry-run only shows the plan:
+
+  fileops reclaim ~ --target 200GB --profile dev-caches --dry-run
+  fileops reclaim /data --target 1TB --archive-to /mnt/archive`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			targetSize, _ := cmd.Flags().GetString("target")
+			archiveTo, _ := cmd.Flags().GetString("archive-to")
+			minSize, _ := cmd.Flags().GetString("min-size")
+			olderThan, _ := cmd.Flags().GetInt("older-than")
+			profiles, _ := cmd.Flags().GetStringSlice("profile")
+			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+			outputFormat, _ := cmd.Flags().GetString("output")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			yes, _ := cmd.Flags().GetBool("yes")
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+			oneFileSystem, _ := cmd.Root().PersistentFlags().GetBool("one-file-system")
+
+			if outputFormat != "table" && outputFormat != "json" {
+				return fmt.Errorf("unknown output format %q (use table or json)", outputFormat)
+			}
+			target := ParseSize(targetSize, 0)
+			if target <= 0 {
+				return fmt.Errorf("--target must be a size such as 200GB")
+			}
+			root, err := filepath.Abs(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid path %s: %w", args[0], err)
+			}
+			if _, err := os.Stat(root); err != nil {
+				return fmt.Errorf("path does not exist: %s", root)
+			}
+			if archiveTo != "" {
+				if archiveTo, err = filepath.Abs(archiveTo); err != nil {
+					return fmt.Errorf("invalid archive path: %w", err)
+				}
+			}
+
+			operationEngine, err := newOperationEngine(cmd, cfg, log, progress.NewTracker())
+			if err != nil {
+				return err
+			}
+
+			log.Info("♻️ Planning space reclamation", "path", root, "target", target, "archive_to", archiveTo)
+
+			table := outputFormat == "table" && !quiet
+			if table {
+				ui.Printf("♻️  Planning how to free %s below %s...\n", FormatBytes(target), root)
+			}
+
+			plan, err := reclaim.Analyze(ctx, operationEngine, root, reclaim.Options{
+				Target:        target,
+				Profiles:      profiles,
+				ArchiveTo:     archiveTo,
+				MinSize:       ParseSize(minSize, 100*1024*1024),
+				OlderThan:     time.Duration(olderThan) * 24 * time.Hour,
+				Exclude:       excludePatterns,
+				OneFileSystem: oneFileSystem,
+				HashAlgorithm: cfg.Operations.HashAlgorithm,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to plan reclamation: %w", err)
+			}
+
+			if outputFormat == "json" {
+				encoder := json.NewEncoder(ui.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(plan); err != nil {
+					return err
+				}
+			} else if !quiet {
+				displayReclaimPlan(plan, archiveTo != "")
+			}
+
+			if len(plan.Actions) == 0 || dryRun {
+				return nil
+			}
+			if !yes && !confirmReclaimPlan(plan) {
+				ui.Printf("Nothing was changed\n")
+				return nil
+			}
+
+			_, err = plan.Execute(ctx, operationEngine, func(action reclaim.Action, result *domain.OperationResult) {
+				if table {
+					ui.Printf("✅ %s: %s\n", action.Strategy, result.Summary)
+					DisplayOperationWarnings(result)
+					DisplayOperationErrors(result)
+				}
+			})
+			if err != nil {
+				if table {
+					ui.Printf("\n❌ Reclamation stopped: %v\n", err)
+				}
+				return fmt.Errorf("reclamation failed: %w", err)
+			}
+
+			if table {
+				ui.Printf("\n♻️  About %s freed\n", FormatBytes(plan.Bytes))
+			}
+			log.Info("✅ Space reclamation completed", "bytes", plan.Bytes)
+			return nil
+		},
+	}
+
+	cmd.Flags().String("target", "", "Space to free, e.g. 200GB (required)")
+	cmd.Flags().String("archive-to", "", "Directory on another drive to move old large files to")
+	cmd.Flags().String("min-size", "100MB", "Size from which a file counts as large")
+	cmd.Flags().Int("older-than", 365, "Days a large file must be untouched to be archived")
+	cmd.Flags().StringSlice("profile", []string{}, "Cleaner profiles whose locations count as caches (see clean --list-profiles)")
+	cmd.Flags().StringSlice("exclude", []string{}, "Patterns to exclude")
+	cmd.Flags().String("output", "table", "Output format of the plan (table, json)")
+	cmd.Flags().Bool("dry-run", false, "Only show the plan")
+	cmd.Flags().BoolP("yes", "y", false, "Execute the plan without asking")
+	_ = cmd.MarkFlagRequired("target")
+
+	return cmd
+}
+
+// displayReclaimPlan shows the actions of a plan and what each strategy
+// could free at most
+func displayReclaimPlan(plan *reclaim.Plan, archiving bool) {
+	if len(plan.Actions) == 0 {
+		ui.Printf("\n🤷 Nothing to free: no caches, duplicates or old large files found\n")
+		if !archiving {
+			ui.Printf("💡 Old large files are only considered with --archive-to\n")
+		}
+		return
+	}
+
+	ui.Printf("\n📋 Plan:\n")
+	actions := newTable(planColumns...)
+	for _, action := range plan.Actions {
+		actions.addRow(action.Strategy, action.Risk, action.Bytes, action.Description)
+	}
+	actions.render(ui.Stdout, tableOptions{Indent: "  "})
+
+	available := make([]string, 0, len(plan.Available))
+	for _, strategy := range []string{reclaim.StrategyCaches, reclaim.StrategyDuplicates, reclaim.StrategyArchive} {
+		if bytes, ok := plan.Available[strategy]; ok {
+			available = append(available, fmt.Sprintf("%s %s", strategy, FormatBytes(bytes)))
+		}
+	}
+	ui.Printf("\n📊 Freeable at most: %s\n", strings.Join(available, ", "))
+
+	if plan.Reached() {
+		ui.Printf("🎯 The plan frees %s of the %s target\n", FormatBytes(plan.Bytes), FormatBytes(plan.Target))
+	} else {
+		ui.Printf("⚠️  The plan frees only %s of the %s target\n", FormatBytes(plan.Bytes), FormatBytes(plan.Target))
+		if !archiving {
+			ui.Printf("💡 Add --archive-to to move old large files to another drive\n")
+		}
+	}
+}
+
+// confirmReclaimPlan asks whether to execute the plan
+func confirmReclaimPlan(plan *reclaim.Plan) bool {
+	if !stdinIsTerminal() {
+		ui.Fprintf(os.Stderr, "💡 Not executed: pass --yes to execute the plan without a terminal\n")
+		return false
+	}
+	ui.Fprintf(os.Stderr, "\n❓ Execute the plan and free %s? [y/N] ", FormatBytes(plan.Bytes))
+	answer, err := readLine()
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// NewRepairCommand creates the repair command
+func NewRepairCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "repair [path...]",
+		Short: "Detect and repair bit-rot using checksum sidecars and recovery data",
+		Long: `Verify every file under the given paths against the checksums written by
+"fileops checksum" (per-file sidecars or per-directory manifests of any
+supported algorithm).
+
+Corrupt files that have recovery data (<name>.parity) are rebuilt in place and
+verified again; their modification time is kept. Files modified after their
+checksum was recorded are reported as modified, not corrupt. Files listed in a
+manifest that no longer exist are reported as missing.
+
+Use --dry-run to only verify.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get flags
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+
+			// Validate paths
+			validPaths := make([]string, 0, len(args))
+			for _, path := range args {
+				absPath, err := filepath.Abs(path)
+				if err != nil {
+					return fmt.Errorf("invalid path %s: %w", path, err)
+				}
+				if _, err := os.Stat(absPath); os.IsNotExist(err) {
+					return fmt.Errorf("path does not exist: %s", absPath)
+				}
+				validPaths = append(validPaths, absPath)
+			}
+
+			// Create operation configuration
+			config := domain.OperationConfig{
+				DryRun:          dryRun,
+				Recursive:       true,
+				ExcludePatterns: excludePatterns,
+				TargetPaths:     validPaths,
+				CustomSettings:  map[string]interface{}{},
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+
+			// Create engine
+			tracker := progress.NewTracker()
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
+
+			// Get quiet flag from root command
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			log.Info("🩺 Starting verification",
+				"paths", validPaths,
+				"dry_run", dryRun)
+
+			// Show initial status
+			if !quiet {
+				ui.Printf("🔍 Verifying files...\n")
+				if dryRun {
+					ui.Printf("📋 DRY RUN MODE: Corrupt files will not be repaired\n")
+				}
+				ui.Printf("📂 Paths to process: %v\n\n", validPaths)
+			}
+
+			// Pre-generate operation ID for progress monitoring
+			operationID := engine.NewOperationID(domain.OperationRepair)
+
+			// Start progress monitoring in a separate goroutine BEFORE starting operation
+			progressCtx, progressCancel := context.WithCancel(ctx)
+			defer progressCancel()
+
+			var progressWg sync.WaitGroup
+			if !quiet && cfg.Operations.EnableProgressBar {
+				progressWg.Add(1)
+				go func() {
+					defer progressWg.Done()
+					MonitorProgress(progressCtx, tracker, operationID, "repair")
+				}()
+				// Give the monitor a moment to start
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			// Execute operation with predefined ID so progress monitoring works
+			result, err := operationEngine.ExecuteOperationWithID(ctx, domain.OperationRepair, config, operationID)
+
+			// Stop progress monitoring
+			progressCancel()
+			progressWg.Wait()
+
+			if err != nil {
+				if !quiet {
+					ui.Printf("\n❌ Verification failed: %v\n", err)
+					displayStoredResultHint(cfg, operationID)
+				}
+				return fmt.Errorf("verification failed: %w", err)
+			}
+
+			// Display results
+			if !quiet {
+				ui.Printf("\n\n✅ Verification completed!\n")
+				if result.Summary != "" {
+					ui.Printf("📊 %s\n", result.Summary)
+				}
+				ui.Printf("⏱️  Total time: %v\n", result.Duration.Round(time.Millisecond))
+
+				listFiles(result, "repaired", "🛠️  Repaired")
+				if dryRun {
+					listFiles(result, "corrupt", "💥 Corrupt")
+				} else {
+					listFiles(result, "unrepairable", "💥 Corrupt and not repairable")
+				}
+				listFiles(result, "modified", "✏️  Modified since checksum (run \"fileops checksum\" to record)")
+				listFiles(result, "missing", "👻 Missing")
+
+				DisplayOperationWarnings(result)
+				DisplayOperationErrors(result)
+			}
+
+			log.Info("✅ Verification completed", "summary", result.Summary)
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	// Add flags
+	cmd.Flags().Bool("dry-run", false, "Only verify; do not repair corrupt files")
+	cmd.Flags().StringSlice("exclude", []string{".git", "node_modules"}, "Patterns to exclude")
+
+	return cmd
+}
+
+// listFiles prints up to 20 paths of a result detail list under a heading
+func listFiles(result *domain.OperationResult, key, heading string) {
+	files, ok := result.Details[key].(domain.PathList)
+	if !ok || files.Count == 0 {
+		return
+	}
+	ui.Printf("\n%s (%d):\n", heading, files.Count)
+	for i, file := range files.Paths {
+		if i >= 20 {
+			ui.Printf("  ... and %d more files\n", files.Count-20)
+			displayPathListFile(files)
+			break
+		}
+		ui.Printf("  %s\n", file)
+	}
+}
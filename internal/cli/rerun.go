@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/storage"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// NewRerunCommand creates the rerun command
+func NewRerunCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rerun <operation-id>",
+		Short: "Run a previous operation again with the same settings",
+		Long: `Run a previous operation again on all of its paths, with the configuration
+stored in its result (operations.results_directory). Periodic manual jobs
+then take one command:
+
+  fileops rerun consolidation-01HN3Z8Q4K7B2M9T5XWJ6R0CDE
+  fileops rerun consolidation-01HN3Z8Q4K7B2M9T5XWJ6R0CDE --dry-run
+  fileops rerun deduplication-01HN3ZB2V6P8N4K1S7TQ9G3AXF --set mode=hardlink
+  fileops rerun consolidation-01HN3Z8Q4K7B2M9T5XWJ6R0CDE --show
+
+--dry-run and --set override the stored settings; --set takes the names of
+the operation settings shown by --show, with JSON or plain string values.
+Unlike retry, which repeats only the failed items, rerun repeats everything.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			overrides, _ := cmd.Flags().GetStringSlice("set")
+			show, _ := cmd.Flags().GetBool("show")
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			if cfg.Operations.ResultsDirectory == "" {
+				return fmt.Errorf("operation results are not stored (operations.results_directory is empty)")
+			}
+
+			repository, err := storage.NewFileRepository(cfg.Operations.ResultsDirectory)
+			if err != nil {
+				return err
+			}
+			defer repository.Close()
+
+			previous, err := repository.GetResult(args[0])
+			if err != nil {
+				if errors.Is(err, storage.ErrNotFound) {
+					return fmt.Errorf("no stored result for operation %s", args[0])
+				}
+				return fmt.Errorf("failed to load operation result: %w", err)
+			}
+			if previous.Config == nil {
+				return fmt.Errorf("operation %s was stored without its configuration and cannot be rerun", previous.ID)
+			}
+
+			// Same operation and settings, with the overrides applied
+			rerunConfig := *previous.Config
+			if cmd.Flags().Changed("dry-run") {
+				rerunConfig.DryRun, _ = cmd.Flags().GetBool("dry-run")
+			}
+			rerunConfig.CustomSettings = make(map[string]interface{}, len(previous.Config.CustomSettings)+len(overrides)+1)
+			for key, value := range previous.Config.CustomSettings {
+				rerunConfig.CustomSettings[key] = value
+			}
+			for _, override := range overrides {
+				key, value, found := strings.Cut(override, "=")
+				if !found || key == "" {
+					return fmt.Errorf("invalid --set %q, expected setting=value", override)
+				}
+				rerunConfig.CustomSettings[key] = parseSettingValue(value)
+			}
+			rerunConfig.CustomSettings["rerun_of"] = previous.ID
+
+			if show {
+				encoder := json.NewEncoder(ui.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(rerunConfig)
+			}
+
+			// Create engine
+			tracker := progress.NewTracker()
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
+
+			log.Info("🔂 Rerunning operation",
+				"operation", previous.ID,
+				"type", previous.OperationType,
+				"paths", rerunConfig.TargetPaths)
+
+			if !quiet {
+				ui.Printf("🔂 Rerunning %s (%s) on %v\n", previous.ID, previous.OperationType, rerunConfig.TargetPaths)
+				if rerunConfig.DryRun {
+					ui.Printf("📋 DRY RUN MODE: No changes will be made\n")
+				}
+				if len(overrides) > 0 {
+					sort.Strings(overrides)
+					ui.Printf("⚙️  Overrides: %s\n", strings.Join(overrides, ", "))
+				}
+				fmt.Fprintln(ui.Stdout)
+			}
+
+			// Pre-generate operation ID for progress monitoring
+			operationID := engine.NewOperationID(previous.OperationType)
+
+			progressCtx, progressCancel := context.WithCancel(ctx)
+			defer progressCancel()
+
+			var progressWg sync.WaitGroup
+			if !quiet && cfg.Operations.EnableProgressBar {
+				progressWg.Add(1)
+				go func() {
+					defer progressWg.Done()
+					MonitorProgress(progressCtx, tracker, operationID, string(previous.OperationType))
+				}()
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			result, err := operationEngine.ExecuteOperationWithID(ctx, previous.OperationType, rerunConfig, operationID)
+
+			progressCancel()
+			progressWg.Wait()
+
+			if err != nil {
+				if !quiet {
+					ui.Printf("\n❌ Rerun failed: %v\n", err)
+					displayStoredResultHint(cfg, operationID)
+				}
+				return fmt.Errorf("rerun of %s failed: %w", previous.ID, err)
+			}
+
+			if !quiet {
+				ui.Printf("\n\n✅ Rerun completed as %s!\n", operationID)
+				if result.Summary != "" {
+					ui.Printf("📊 %s\n", result.Summary)
+				}
+				ui.Printf("⏱️  Total time: %v\n", result.Duration.Round(time.Millisecond))
+				DisplayOperationWarnings(result)
+				DisplayOperationErrors(result)
+			}
+
+			log.Info("✅ Rerun completed", "operation", previous.ID, "rerun", operationID, "summary", result.Summary)
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	cmd.Flags().Bool("dry-run", false, "Preview the rerun without making changes (default: as the original operation)")
+	cmd.Flags().StringSlice("set", nil, "Override an operation setting, as setting=value (repeatable)")
+	cmd.Flags().Bool("show", false, "Print the configuration the rerun would use and exit")
+
+	return cmd
+}
+
+// parseSettingValue reads a --set value as JSON (numbers, booleans, lists),
+// falling back to the plain string
+func parseSettingValue(value string) interface{} {
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(value), &parsed); err == nil {
+		return parsed
+	}
+	return value
+}
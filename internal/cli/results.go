@@ -0,0 +1,294 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/storage"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/spf13/cobra"
+)
+
+// NewResultsCommand creates the results command
+func NewResultsCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "results",
+		Short: "Inspect stored operation results",
+		Long: `Inspect the results operations store in the results directory
+(operations.results_directory).
+
+Every result records the environment of its run: the host, the user, the
+fileops version, the configuration file and its SHA-256, and the flags that
+were set on the command line or by configured defaults. Secrets such as
+--token are stored redacted.`,
+	}
+
+	cmd.AddCommand(newResultsShowCommand(cfg, log))
+	cmd.AddCommand(newResultsDiffCommand(cfg, log))
+	return cmd
+}
+
+// newResultsShowCommand creates the results show command
+func newResultsShowCommand(cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show <operation-id>",
+		Short: "Show a stored operation result and the environment it ran in",
+		Example: `  fileops results show deduplication-01HN3ZB2V6P8N4K1S7TQ9G3AXF
+  fileops results show deduplication-01HN3ZB2V6P8N4K1S7TQ9G3AXF --output json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			outputFormat, _ := cmd.Flags().GetString("output")
+			if outputFormat != "table" && outputFormat != "json" {
+				return fmt.Errorf("unknown output format %q (use table or json)", outputFormat)
+			}
+
+			if cfg.Operations.ResultsDirectory == "" {
+				return fmt.Errorf("operation results are not stored (operations.results_directory is empty)")
+			}
+
+			repository, err := storage.NewFileRepository(cfg.Operations.ResultsDirectory)
+			if err != nil {
+				return err
+			}
+			defer repository.Close()
+
+			result, err := repository.GetResult(args[0])
+			if err != nil {
+				if errors.Is(err, storage.ErrNotFound) {
+					return fmt.Errorf("no stored result for operation %s", args[0])
+				}
+				return fmt.Errorf("failed to load operation result: %w", err)
+			}
+			log.Debug("Operation result loaded", "id", result.ID)
+
+			if outputFormat == "json" {
+				encoder := json.NewEncoder(ui.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(result)
+			}
+
+			displayResult(result)
+			return nil
+		},
+	}
+
+	cmd.Flags().String("output", "table", "Output format (table, json)")
+	return cmd
+}
+
+// newResultsDiffCommand creates the results diff command
+func newResultsDiffCommand(cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff <operation-id> <operation-id>",
+		Short: "Compare two stored results of the same operation type",
+		Long: `Compare two stored results of the same operation type, the older with the
+newer. For deduplication results, show the duplicate groups that appeared
+and those that were resolved since, matched by content, the groups whose
+copies changed, and the trend of reclaimable space over every stored run on
+the same paths in between. For other operations, show the paths added to
+and removed from each list of the results.`,
+		Example: `  fileops results diff deduplication-01HN3ZB2V6P8N4K1S7TQ9G3AXF deduplication-01HP0R5WQ3M7C2D8E4F6G9H1JK
+  fileops results diff deduplication-01HN3ZB2V6P8N4K1S7TQ9G3AXF deduplication-01HP0R5WQ3M7C2D8E4F6G9H1JK --output json`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			outputFormat, _ := cmd.Flags().GetString("output")
+			if outputFormat != "table" && outputFormat != "json" {
+				return fmt.Errorf("unknown output format %q (use table or json)", outputFormat)
+			}
+
+			if cfg.Operations.ResultsDirectory == "" {
+				return fmt.Errorf("operation results are not stored (operations.results_directory is empty)")
+			}
+
+			repository, err := storage.NewFileRepository(cfg.Operations.ResultsDirectory)
+			if err != nil {
+				return err
+			}
+			defer repository.Close()
+
+			results := make([]*domain.OperationResult, 0, len(args))
+			for _, id := range args {
+				result, err := repository.GetResult(id)
+				if err != nil {
+					if errors.Is(err, storage.ErrNotFound) {
+						return fmt.Errorf("no stored result for operation %s", id)
+					}
+					return fmt.Errorf("failed to load operation result: %w", err)
+				}
+				results = append(results, result)
+			}
+			history, err := repository.ListResults(map[string]interface{}{"operation_type": string(results[0].OperationType)})
+			if err != nil {
+				return fmt.Errorf("failed to list operation results: %w", err)
+			}
+
+			diff, err := engine.DiffResults(results[0], results[1], history)
+			if err != nil {
+				return err
+			}
+			log.Debug("Operation results compared", "old", diff.Old, "new", diff.New)
+
+			if outputFormat == "json" {
+				encoder := json.NewEncoder(ui.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(diff)
+			}
+
+			displayResultDiff(diff)
+			return nil
+		},
+	}
+
+	cmd.Flags().String("output", "table", "Output format (table, json)")
+	return cmd
+}
+
+// trendColumns are the columns of the reclaimable space trend
+var trendColumns = []tableColumn{
+	timeColumn("started"),
+	idColumn("operation"),
+	numberColumn("groups"),
+	sizeColumn("reclaimable"),
+}
+
+// diffGroupLimit is the number of groups of each kind listed
+const diffGroupLimit = 20
+
+// displayResultDiff prints the difference between two results
+func displayResultDiff(diff *engine.ResultDiff) {
+	ui.Printf("🔀 %s: %s → %s\n", diff.OperationType, diff.Old, diff.New)
+
+	if len(diff.Trend) > 0 {
+		first, last := diff.Trend[0], diff.Trend[len(diff.Trend)-1]
+		change := last.Reclaimable - first.Reclaimable
+		sign := "+"
+		if change < 0 {
+			sign, change = "-", -change
+		}
+		ui.Printf("💾 Reclaimable: %s → %s (%s%s)\n", FormatBytes(first.Reclaimable), FormatBytes(last.Reclaimable), sign, FormatBytes(change))
+		ui.Printf("📊 Groups: %d new, %d resolved, %d with other copies, %d unchanged\n",
+			len(diff.Appeared), len(diff.Resolved), len(diff.Changed), diff.Unchanged)
+
+		if len(diff.Appeared) > 0 {
+			ui.Printf("\n🆕 New duplicate groups (%d, %s reclaimable):\n", len(diff.Appeared), FormatBytes(diff.AppearedSize()))
+			displayDiffGroups(diff.Appeared)
+		}
+		if len(diff.Resolved) > 0 {
+			ui.Printf("\n✅ Resolved duplicate groups (%d, %s reclaimable before):\n", len(diff.Resolved), FormatBytes(diff.ResolvedSize()))
+			displayDiffGroups(diff.Resolved)
+		}
+		if len(diff.Changed) > 0 {
+			ui.Printf("\n🔁 Groups with other copies (%d):\n", len(diff.Changed))
+			for i, change := range diff.Changed {
+				if i == diffGroupLimit {
+					ui.Printf("  ... and %d more\n", len(diff.Changed)-i)
+					break
+				}
+				ui.Printf("  %s: %d → %d copies\n", change.New.ID, len(change.Old.Files), len(change.New.Files))
+				for _, path := range change.Added {
+					ui.Printf("    + %s\n", path)
+				}
+				for _, path := range change.Removed {
+					ui.Printf("    - %s\n", path)
+				}
+			}
+		}
+		if len(diff.Trend) > 2 {
+			ui.Printf("\n📈 Trend:\n")
+			rows := newTable(trendColumns...)
+			for _, point := range diff.Trend {
+				rows.addRow(point.Time, point.ID, point.Groups, point.Reclaimable)
+			}
+			_ = rows.render(ui.Stdout, tableOptions{Indent: "  "})
+		}
+		return
+	}
+
+	if len(diff.Lists) == 0 {
+		ui.Printf("✅ The lists of both results hold the same paths\n")
+		return
+	}
+	for _, list := range diff.Lists {
+		ui.Printf("\n📄 %s: %d added, %d removed\n", list.Key, len(list.Added), len(list.Removed))
+		for i, path := range list.Added {
+			if i == diffGroupLimit {
+				ui.Printf("  ... and %d more\n", len(list.Added)-i)
+				break
+			}
+			ui.Printf("  + %s\n", path)
+		}
+		for i, path := range list.Removed {
+			if i == diffGroupLimit {
+				ui.Printf("  ... and %d more\n", len(list.Removed)-i)
+				break
+			}
+			ui.Printf("  - %s\n", path)
+		}
+	}
+}
+
+// displayDiffGroups lists duplicate groups, largest first
+func displayDiffGroups(groups []domain.DuplicateGroup) {
+	rows := newTable(groupColumns...)
+	for _, group := range groups {
+		rows.addRow(group.ID, len(group.Files), group.Files[0].Size, group.SaveablSize, domain.ConfidenceLevel(group.Confidence), group.Files[0].Path)
+	}
+	_ = rows.render(ui.Stdout, tableOptions{Indent: "  ", Limit: diffGroupLimit})
+}
+
+// displayResult prints a stored result with its environment
+func displayResult(result *domain.OperationResult) {
+	ui.Printf("📋 %s (%s, %s)\n", result.ID, result.OperationType, result.Status)
+	if result.Summary != "" {
+		ui.Printf("   %s\n", result.Summary)
+	}
+	ui.Printf("🕐 Started: %s\n", result.StartTime.Format(time.RFC3339))
+	ui.Printf("⏱️  Duration: %v\n", result.Duration.Round(time.Millisecond))
+	ui.Printf("📊 Items processed: %d (%s)\n", result.ItemsProcessed, FormatBytes(result.BytesProcessed))
+	if len(result.Errors) > 0 || len(result.Warnings) > 0 {
+		ui.Printf("⚠️  Errors: %d, warnings: %d\n", len(result.Errors), len(result.Warnings))
+	}
+	if result.Artifacts != "" {
+		ui.Printf("📁 Artifacts: %s\n", result.Artifacts)
+	}
+	displaySlowPaths(result.SlowPaths)
+
+	env := result.Environment
+	if env == nil {
+		ui.Printf("\n💡 No environment was recorded for this operation\n")
+		return
+	}
+	ui.Printf("\n🖥️  Environment:\n")
+	ui.Printf("  Host:    %s\n", env.Hostname)
+	ui.Printf("  User:    %s\n", env.User)
+	ui.Printf("  Version: %s\n", env.Version)
+	if env.Command != "" {
+		ui.Printf("  Command: %s\n", env.Command)
+	}
+	if env.ConfigFile != "" {
+		ui.Printf("  Config:  %s (sha256 %s)\n", env.ConfigFile, env.ConfigHash)
+	} else {
+		ui.Printf("  Config:  defaults\n")
+	}
+
+	if len(env.Flags) == 0 {
+		return
+	}
+	names := make([]string, 0, len(env.Flags))
+	for name := range env.Flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ui.Printf("\n🚩 Flags:\n")
+	for _, name := range names {
+		ui.Printf("  --%s=%s\n", name, env.Flags[name])
+	}
+}
@@ -0,0 +1,199 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/storage"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// NewRetryCommand creates the retry command
+func NewRetryCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "retry <operation-id>",
+		Short: "Re-attempt the failed items of a previous operation",
+		Long: `Re-attempt only the items that failed in a previous operation.
+
+Every operation stores its result, including a per-item error report, in the
+results directory (operations.results_directory). The retry command loads that
+result and runs the same operation with the same settings, restricted to the
+items whose errors were marked recoverable. Items that no longer exist or that
+collided with existing files are skipped unless --all is given.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listOnly, _ := cmd.Flags().GetBool("list")
+			includeAll, _ := cmd.Flags().GetBool("all")
+
+			if cfg.Operations.ResultsDirectory == "" {
+				return fmt.Errorf("operation results are not stored (operations.results_directory is empty)")
+			}
+
+			repository, err := storage.NewFileRepository(cfg.Operations.ResultsDirectory)
+			if err != nil {
+				return err
+			}
+			defer repository.Close()
+
+			previous, err := repository.GetResult(args[0])
+			if err != nil {
+				if errors.Is(err, storage.ErrNotFound) {
+					return fmt.Errorf("no stored result for operation %s", args[0])
+				}
+				return fmt.Errorf("failed to load operation result: %w", err)
+			}
+
+			failedItems := retryItems(previous, includeAll)
+
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			if listOnly {
+				displayRetryItems(previous, includeAll)
+				return nil
+			}
+
+			if len(failedItems) == 0 {
+				if !quiet {
+					ui.Printf("✅ Nothing to retry: operation %s has no recoverable failed items\n", previous.ID)
+				}
+				return nil
+			}
+
+			if previous.Config == nil {
+				return fmt.Errorf("operation %s was stored without its configuration and cannot be retried", previous.ID)
+			}
+
+			// Same operation and settings, restricted to the failed items
+			retryConfig := *previous.Config
+			retryConfig.OnlyPaths = failedItems
+			if cmd.Flags().Changed("dry-run") {
+				retryConfig.DryRun, _ = cmd.Flags().GetBool("dry-run")
+			}
+			retryConfig.CustomSettings = make(map[string]interface{}, len(previous.Config.CustomSettings)+1)
+			for key, value := range previous.Config.CustomSettings {
+				retryConfig.CustomSettings[key] = value
+			}
+			retryConfig.CustomSettings["retry_of"] = previous.ID
+
+			// Create engine
+			tracker := progress.NewTracker()
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
+
+			log.Info("🔁 Retrying failed items",
+				"operation", previous.ID,
+				"type", previous.OperationType,
+				"items", len(failedItems))
+
+			if !quiet {
+				ui.Printf("🔁 Retrying %d failed items of %s (%s)\n", len(failedItems), previous.ID, previous.OperationType)
+				if retryConfig.DryRun {
+					ui.Printf("📋 DRY RUN MODE: No changes will be made\n")
+				}
+				fmt.Fprintln(ui.Stdout)
+			}
+
+			// Pre-generate operation ID for progress monitoring
+			operationID := engine.NewOperationID(previous.OperationType)
+
+			progressCtx, progressCancel := context.WithCancel(ctx)
+			defer progressCancel()
+
+			var progressWg sync.WaitGroup
+			if !quiet && cfg.Operations.EnableProgressBar {
+				progressWg.Add(1)
+				go func() {
+					defer progressWg.Done()
+					MonitorProgress(progressCtx, tracker, operationID, string(previous.OperationType))
+				}()
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			result, err := operationEngine.ExecuteOperationWithID(ctx, previous.OperationType, retryConfig, operationID)
+
+			progressCancel()
+			progressWg.Wait()
+
+			if err != nil {
+				if !quiet {
+					ui.Printf("\n❌ Retry failed: %v\n", err)
+					displayStoredResultHint(cfg, operationID)
+				}
+				return fmt.Errorf("retry of %s failed: %w", previous.ID, err)
+			}
+
+			if !quiet {
+				ui.Printf("\n\n✅ Retry completed!\n")
+				if result.Summary != "" {
+					ui.Printf("📊 %s\n", result.Summary)
+				}
+				ui.Printf("⏱️  Total time: %v\n", result.Duration.Round(time.Millisecond))
+				DisplayOperationWarnings(result)
+				DisplayOperationErrors(result)
+			}
+
+			log.Info("✅ Retry completed", "operation", previous.ID, "retry", operationID, "summary", result.Summary)
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	cmd.Flags().Bool("dry-run", false, "Preview the retry without making changes (default: as the original operation)")
+	cmd.Flags().Bool("list", false, "Only list the failed items of the operation")
+	cmd.Flags().Bool("all", false, "Also retry items whose errors are not recoverable")
+
+	return cmd
+}
+
+// retryItems returns the distinct failed items of result eligible for a retry
+func retryItems(result *domain.OperationResult, includeAll bool) []string {
+	seen := make(map[string]bool)
+	items := make([]string, 0)
+	for _, opErr := range result.Errors {
+		if opErr.File == "" || seen[opErr.File] || (!opErr.Recoverable && !includeAll) {
+			continue
+		}
+		seen[opErr.File] = true
+		items = append(items, opErr.File)
+	}
+	return items
+}
+
+// displayRetryItems prints the per-item error report of a stored result
+func displayRetryItems(result *domain.OperationResult, includeAll bool) {
+	ui.Printf("📋 %s (%s, %s): %s\n", result.ID, result.OperationType, result.Status, result.Summary)
+	if len(result.Errors) == 0 {
+		ui.Printf("✅ No errors recorded\n")
+		return
+	}
+
+	for _, opErr := range result.Errors {
+		marker := "✗"
+		if opErr.Recoverable {
+			marker = "↻"
+		}
+		file := opErr.File
+		if file == "" {
+			file = "(operation)"
+		}
+		ui.Printf("  %s %s\n      %s\n", marker, file, opErr.Error)
+	}
+	ui.Printf("\n💡 %d items would be retried\n", len(retryItems(result, includeAll)))
+}
+
+// displayStoredResultHint tells the user where to find the errors of a failed operation
+func displayStoredResultHint(cfg *config.Config, operationID string) {
+	if cfg.Operations.ResultsDirectory != "" {
+		ui.Printf("💡 Failed items were recorded; inspect them with: fileops retry %s --list\n", operationID)
+	}
+}
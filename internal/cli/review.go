@@ -0,0 +1,521 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/thumbnail"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// reviewSnippetSize is how much of a text file its preview shows
+const reviewSnippetSize = 2048
+
+// reviewFolderEntries is how many entries of a folder its preview lists
+const reviewFolderEntries = 20
+
+// reviewCookie carries the token of the review page after its first request
+const reviewCookie = "fileops_review"
+
+// NewReviewCommand creates the review command
+func NewReviewCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "review <plan.json>",
+		Short: "Review planned deletions and moves in the browser before applying them",
+		Long: `Serve a local web page listing the deletions and moves of a plan, each with
+a preview (thumbnails of images and videos, the beginning of text files, the
+entries of folders) and a toggle to approve or reject it. Saving writes the
+decisions back to the plan; "fileops review apply" then carries out the
+approved changes:
+
+  fileops triage ~/Downloads --plan-out plan.json
+  fileops review plan.json
+  fileops review apply plan.json
+
+The page is only served on the loopback interface, to whoever opens the
+printed address, until interrupted.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			address, _ := cmd.Flags().GetString("address")
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			path, err := filepath.Abs(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid path %s: %w", args[0], err)
+			}
+			plan, err := engine.LoadReviewPlan(path)
+			if err != nil {
+				return err
+			}
+			server, err := newReviewServer(cfg, path, plan)
+			if err != nil {
+				return err
+			}
+			server.saved = func(approved, rejected int) {
+				log.Info("📝 Review saved", "plan", path, "approved", approved, "rejected", rejected)
+				if !quiet {
+					ui.Printf("📝 Saved: %d approved, %d rejected\n", approved, rejected)
+				}
+			}
+
+			listener, err := net.Listen("tcp", address)
+			if err != nil {
+				return err
+			}
+			url := fmt.Sprintf("http://%s/?token=%s", listener.Addr(), server.token)
+			log.Info("🔎 Serving review", "plan", path, "address", listener.Addr().String(), "changes", len(plan.Changes))
+			ui.Printf("🔎 Reviewing %d changes of %s at\n\n   %s\n\n", len(plan.Changes), path, url)
+			if !quiet {
+				ui.Printf("Save the decisions on the page, then: fileops review apply %s (Ctrl+C to stop)\n", args[0])
+			}
+			return server.serve(ctx, listener)
+		},
+	}
+
+	cmd.Flags().String("address", "127.0.0.1:0", "Address to serve the page on (default: a free port on the loopback interface)")
+
+	cmd.AddCommand(newReviewApplyCommand(ctx, cfg, log))
+
+	return cmd
+}
+
+// newReviewApplyCommand creates the review apply subcommand
+func newReviewApplyCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apply <plan.json>",
+		Short: "Carry out the approved changes of a plan",
+		Long: `Delete and move the items of a plan that were not rejected in the review.
+Items that changed since they were planned are skipped, moves never replace
+existing files, and with --backup-dir deleted items are moved there instead.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			backupDir, _ := cmd.Flags().GetString("backup-dir")
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			path, err := filepath.Abs(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid path %s: %w", args[0], err)
+			}
+			plan, err := engine.LoadReviewPlan(path)
+			if err != nil {
+				return err
+			}
+			if backupDir != "" {
+				if backupDir, err = filepath.Abs(backupDir); err != nil {
+					return fmt.Errorf("invalid path %s: %w", backupDir, err)
+				}
+			}
+
+			config := domain.OperationConfig{
+				DryRun:             dryRun,
+				TargetPaths:        planPaths(plan),
+				BackupBeforeDelete: backupDir != "",
+				BackupDirectory:    backupDir,
+				CustomSettings:     map[string]interface{}{"plan": path},
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+
+			tracker := progress.NewTracker()
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
+
+			approved := len(plan.Approved())
+			log.Info("📝 Applying plan", "plan", path, "approved", approved, "dry_run", dryRun)
+
+			if !quiet {
+				ui.Printf("📝 Applying %d of the %d changes of %s\n", approved, len(plan.Changes), path)
+				if dryRun {
+					ui.Printf("📋 DRY RUN MODE: No files will be changed\n")
+				}
+				if backupDir != "" {
+					ui.Printf("📦 Moving deleted items to: %s\n", backupDir)
+				}
+				fmt.Fprintln(ui.Stdout)
+			}
+
+			if !confirmLargeOperation(cmd, cfg, operationEngine, domain.OperationApplyPlan, config) {
+				ui.Printf("Nothing was changed\n")
+				return nil
+			}
+
+			operationID := engine.NewOperationID(domain.OperationApplyPlan)
+			result, err := operationEngine.ExecuteOperationWithID(ctx, domain.OperationApplyPlan, config, operationID)
+			if err != nil {
+				if !quiet {
+					ui.Printf("\n❌ Applying the plan failed: %v\n", err)
+					displayStoredResultHint(cfg, operationID)
+				}
+				return fmt.Errorf("applying the plan failed: %w", err)
+			}
+
+			if !quiet {
+				ui.Printf("✅ %s\n", result.Summary)
+				if freed, ok := result.Details["bytes_freed"].(int64); ok && freed > 0 && backupDir == "" && !dryRun {
+					ui.Printf("💾 Space freed: %s\n", FormatBytes(freed))
+				}
+				DisplayOperationWarnings(result)
+				DisplayOperationErrors(result)
+			}
+
+			log.Info("✅ Plan applied", "summary", result.Summary)
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	cmd.Flags().Bool("dry-run", false, "Check the changes without executing them")
+	cmd.Flags().String("backup-dir", "", "Move deleted items here instead of deleting them")
+
+	return cmd
+}
+
+// planPaths returns the paths a plan changes: its roots and the directories
+// its moves lead to outside of them, so locks and role paths cover both
+func planPaths(plan *engine.ReviewPlan) []string {
+	paths := append([]string{}, plan.Roots...)
+	seen := make(map[string]bool)
+	for _, change := range plan.Changes {
+		if change.Action != engine.PlanMove || change.Rejected {
+			continue
+		}
+		dir := filepath.Dir(change.Target)
+		if seen[dir] || withinAnyRoot(change.Target, plan.Roots) {
+			continue
+		}
+		seen[dir] = true
+		paths = append(paths, dir)
+	}
+	return paths
+}
+
+// withinAnyRoot reports whether path lies below one of roots
+func withinAnyRoot(path string, roots []string) bool {
+	for _, root := range roots {
+		if strings.HasPrefix(path, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// reviewServer serves the review page of one plan and writes the decisions
+// made there back to the plan file
+type reviewServer struct {
+	path      string
+	plan      *engine.ReviewPlan
+	token     string
+	generator *thumbnail.Generator
+	cacheDir  string
+	saved     func(approved, rejected int)
+	mu        sync.Mutex
+}
+
+// newReviewServer creates the server of a plan with a random token
+func newReviewServer(cfg *config.Config, path string, plan *engine.ReviewPlan) (*reviewServer, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	size := cfg.Thumbnails.Size
+	if size <= 0 {
+		size = 256
+	}
+	return &reviewServer{
+		path:      path,
+		plan:      plan,
+		token:     hex.EncodeToString(token),
+		generator: thumbnail.NewGenerator(size, cfg.Thumbnails.Quality),
+		cacheDir:  cfg.Thumbnails.CacheDirectory,
+		saved:     func(int, int) {},
+	}, nil
+}
+
+// handler returns the routes of the review page
+func (s *reviewServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handlePage)
+	mux.HandleFunc("GET /preview/{index}", s.handlePreview)
+	mux.HandleFunc("POST /save", s.handleSave)
+	return s.authorize(mux)
+}
+
+// serve serves the page on listener until ctx is cancelled
+func (s *reviewServer) serve(ctx context.Context, listener net.Listener) error {
+	server := &http.Server{Handler: s.handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// authorize admits requests carrying the token, in the address printed at
+// start or in the cookie set by the first request. The strict cookie keeps
+// other sites from saving decisions.
+func (s *reviewServer) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given := r.URL.Query().Get("token")
+		if given == "" {
+			if cookie, err := r.Cookie(reviewCookie); err == nil {
+				given = cookie.Value
+			}
+		} else {
+			http.SetCookie(w, &http.Cookie{Name: reviewCookie, Value: given, Path: "/", HttpOnly: true, SameSite: http.SameSiteStrictMode})
+		}
+		if subtle.ConstantTimeCompare([]byte(given), []byte(s.token)) != 1 {
+			http.Error(w, "invalid or missing token, open the address printed by fileops review", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// reviewRow is a change as the page shows it
+type reviewRow struct {
+	engine.ReviewChange
+	Index int
+	Image bool // Previewed by a thumbnail rather than as text
+}
+
+// handlePage renders the list of changes
+func (s *reviewServer) handlePage(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	rows := make([]reviewRow, 0, len(s.plan.Changes))
+	for i, change := range s.plan.Changes {
+		rows = append(rows, reviewRow{ReviewChange: change, Index: i, Image: !change.IsDir && s.generator.Supports(change.Path)})
+	}
+	page := struct {
+		Path string
+		Plan *engine.ReviewPlan
+		Rows []reviewRow
+	}{Path: s.path, Plan: s.plan, Rows: rows}
+	var buf bytes.Buffer
+	err := reviewTemplate.Execute(&buf, page)
+	s.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// handlePreview serves the preview of one change: a thumbnail, the beginning
+// of a text file or the entries of a folder
+func (s *reviewServer) handlePreview(w http.ResponseWriter, r *http.Request) {
+	index, err := strconv.Atoi(r.PathValue("index"))
+	s.mu.Lock()
+	if err != nil || index < 0 || index >= len(s.plan.Changes) {
+		s.mu.Unlock()
+		http.NotFound(w, r)
+		return
+	}
+	change := s.plan.Changes[index]
+	s.mu.Unlock()
+
+	w.Header().Set("Cache-Control", "no-store")
+	if change.IsDir {
+		entries, err := os.ReadDir(change.Path)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for i, entry := range entries {
+			if i == reviewFolderEntries {
+				fmt.Fprintf(w, "... and %d more\n", len(entries)-i)
+				break
+			}
+			name := entry.Name()
+			if entry.IsDir() {
+				name += "/"
+			}
+			fmt.Fprintln(w, name)
+		}
+		return
+	}
+
+	if s.generator.Supports(change.Path) {
+		target := thumbnail.CachePath(s.cacheDir, change.Path, s.generator.Size)
+		if thumbnail.UpToDate(change.Path, target) || s.generator.Generate(r.Context(), change.Path, target) == nil {
+			w.Header().Set("Content-Type", "image/jpeg")
+			http.ServeFile(w, r, target)
+			return
+		}
+		http.Error(w, "no preview", http.StatusNotFound)
+		return
+	}
+
+	snippet, err := textSnippet(change.Path)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	switch {
+	case err != nil:
+		fmt.Fprintf(w, "No preview: %v\n", err)
+	case snippet == nil:
+		fmt.Fprintf(w, "No preview (binary file, %s)\n", FormatBytes(change.Size))
+	default:
+		w.Write(snippet)
+	}
+}
+
+// textSnippet returns the beginning of a file, or nil when it is not text
+func textSnippet(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, reviewSnippetSize))
+	if err != nil {
+		return nil, err
+	}
+	// The limit may cut a character in two
+	for i := 0; i < utf8.UTFMax && len(data) > 0 && !utf8.Valid(data); i++ {
+		data = data[:len(data)-1]
+	}
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+		return nil, nil
+	}
+	return data, nil
+}
+
+// handleSave records which changes were rejected and writes the plan
+func (s *reviewServer) handleSave(w http.ResponseWriter, r *http.Request) {
+	var decisions struct {
+		Rejected []int `json:"rejected"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64<<20)).Decode(&decisions); err != nil {
+		http.Error(w, "invalid decisions: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	rejected := make(map[int]bool, len(decisions.Rejected))
+	for _, index := range decisions.Rejected {
+		rejected[index] = true
+	}
+	for i := range s.plan.Changes {
+		s.plan.Changes[i].Rejected = rejected[i]
+	}
+	err := s.plan.Save(s.path)
+	approved, count := len(s.plan.Approved()), len(s.plan.Changes)
+	s.mu.Unlock()
+	if err != nil {
+		http.Error(w, "failed to write the plan: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.saved(approved, count-approved)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"approved": approved, "rejected": count - approved})
+}
+
+// reviewTemplate renders the review page. Toggling a change only marks it;
+// saving sends the rejected changes to the server, which writes the plan.
+var reviewTemplate = template.Must(template.New("review").Funcs(template.FuncMap{
+	"bytes": FormatBytes,
+	"date":  func(t time.Time) string { return t.Format("2006-01-02 15:04") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>fileops review</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+.change { display: flex; gap: 1em; border: 1px solid #ddd; border-radius: 6px; padding: 0.8em; margin-bottom: 0.8em; }
+.change.rejected { opacity: 0.5; background: #f6f6f6; }
+.preview { width: 260px; min-width: 260px; height: 160px; }
+.preview img { max-width: 256px; max-height: 156px; }
+.preview iframe { width: 256px; height: 156px; border: 1px solid #eee; background: #fff; }
+.action { font-weight: bold; text-transform: uppercase; font-size: 0.8em; padding: 2px 6px; border-radius: 4px; }
+.delete { background: #fde2e2; color: #a40000; }
+.move { background: #e2ecfd; color: #0040a4; }
+.meta { font-size: 0.85em; color: #555; word-break: break-all; }
+.bar { position: sticky; top: 0; background: #fff; padding: 0.5em 0; border-bottom: 1px solid #ddd; margin-bottom: 1em; }
+button { font-size: 1em; padding: 0.4em 1.2em; }
+</style>
+</head>
+<body>
+<h1>Planned changes</h1>
+<p>{{.Path}} &middot; {{range $i, $root := .Plan.Roots}}{{if $i}}, {{end}}{{$root}}{{end}} &middot; {{len .Rows}} changes</p>
+<div class="bar"><span id="counts"></span>
+<button onclick="setAll(true)">Approve all</button> <button onclick="setAll(false)">Reject all</button>
+<button onclick="save()">Save decisions</button> <span id="status"></span></div>
+{{range .Rows}}<div class="change{{if .Rejected}} rejected{{end}}" data-size="{{.Size}}">
+<div class="preview">{{if .Image}}<img loading="lazy" src="/preview/{{.Index}}" alt="{{.Path}}">{{else}}<iframe loading="lazy" src="/preview/{{.Index}}"></iframe>{{end}}</div>
+<div>
+<label><input type="checkbox" value="{{.Index}}" onchange="update()"{{if not .Rejected}} checked{{end}}> apply</label>
+<span class="action {{.Action}}">{{.Action}}</span>
+<div class="meta"><b>{{.Path}}{{if .IsDir}}/{{end}}</b>{{if .Target}}<br>&rarr; {{.Target}}{{end}}<br>
+{{bytes .Size}} &middot; modified {{date .ModTime}}{{if .Reason}} &middot; {{.Reason}}{{end}}</div>
+</div>
+</div>
+{{end}}<script>
+const boxes = Array.from(document.querySelectorAll('input[type="checkbox"]'));
+function formatBytes(bytes) {
+  const units = ["B", "KB", "MB", "GB", "TB", "PB"];
+  let i = 0;
+  while (bytes >= 1024 && i < units.length - 1) { bytes /= 1024; i++; }
+  return (i ? bytes.toFixed(1) : bytes) + " " + units[i];
+}
+function update() {
+  let approved = 0, size = 0;
+  for (const box of boxes) {
+    const change = box.closest(".change");
+    change.classList.toggle("rejected", !box.checked);
+    if (box.checked) { approved++; size += Number(change.dataset.size); }
+  }
+  document.getElementById("counts").textContent = approved + " of " + boxes.length + " approved (" + formatBytes(size) + ")";
+}
+function setAll(checked) {
+  for (const box of boxes) box.checked = checked;
+  update();
+}
+async function save() {
+  const rejected = boxes.filter(box => !box.checked).map(box => Number(box.value));
+  const status = document.getElementById("status");
+  const response = await fetch("/save", {method: "POST", headers: {"Content-Type": "application/json"}, body: JSON.stringify({rejected: rejected})});
+  if (!response.ok) {
+    status.textContent = "Saving failed: " + await response.text();
+    return;
+  }
+  const saved = await response.json();
+  status.textContent = "Saved " + saved.approved + " approved, " + saved.rejected + " rejected. Apply with: fileops review apply {{.Path}}";
+}
+update();
+</script>
+</body>
+</html>
+`))
@@ -3,9 +3,11 @@ package cli
 import (
 	"context"
 	"fmt"
+	"runtime"
 
 	"github.com/a4abhishek/fileops/internal/config"
 	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
 	"github.com/spf13/cobra"
 )
 
@@ -36,6 +38,14 @@ Features:
   # Run a pipeline
   fileops pipeline run cleanup-and-organize.yaml`,
 		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := applyCommandDefaults(cmd, cfg.Commands); err != nil {
+				return err
+			}
+			theme, _ := cmd.Flags().GetString("theme")
+			locale, _ := cmd.Flags().GetString("locale")
+			return ui.Configure(theme, locale)
+		},
 	}
 
 	// Global flags
@@ -43,16 +53,78 @@ Features:
 	rootCmd.PersistentFlags().String("log-level", cfg.Logging.Level, "log level (debug, info, warn, error)")
 	rootCmd.PersistentFlags().Bool("verbose", false, "verbose output")
 	rootCmd.PersistentFlags().Bool("quiet", false, "quiet output (errors only)")
+	rootCmd.PersistentFlags().String("chaos", "", "fault injection spec for testing, e.g. error=0.05,latency=10ms")
+	_ = rootCmd.PersistentFlags().MarkHidden("chaos")
+	rootCmd.PersistentFlags().String("max-errors", "", "abort once more than this many items fail, as a count or percentage (e.g. 50 or 5%, 0 aborts on the first failure)")
+	rootCmd.PersistentFlags().Bool("rollback", false, "when --max-errors aborts a run, move the files it moved back (deleted files can only be restored from a snapshot taken before the run, see snapshot.method)")
+	rootCmd.PersistentFlags().StringSlice("include", []string{}, "only process files whose names match these patterns, e.g. *.pdf (directories are still searched)")
+	rootCmd.PersistentFlags().BoolP("one-file-system", "x", false, "do not cross filesystem boundaries (mount points) while walking")
+	rootCmd.PersistentFlags().Bool("throttle", cfg.Performance.Throttle, "use fewer workers while the CPUs or disks of the machine are busy (performance.throttle_cpu, performance.throttle_disk_queue) and more again once it is idle")
+	rootCmd.PersistentFlags().Duration("max-duration", 0, "pause after running this long in the --window until it opens again, or without a window stop (e.g. 2h)")
+	rootCmd.PersistentFlags().String("window", "", "only run between these local times, pausing outside them and resuming when the window opens, e.g. 22:00-06:00")
+	rootCmd.PersistentFlags().String("git", "", "inside git repositories, act only on: protect (everything but tracked files), untracked, ignored")
+	rootCmd.PersistentFlags().String("unicode", cfg.Operations.UnicodeNormalization, "normalization of file names when comparing and writing them: nfc, nfd, none (byte for byte)")
+	rootCmd.PersistentFlags().String("mtime-granularity", cfg.Operations.MTimeGranularity, "modification times this close count as unchanged, e.g. 2s (auto detects FAT, exFAT and SMB)")
+	rootCmd.PersistentFlags().Bool("dst-skew", cfg.Operations.MTimeDSTSkew, "also count modification times one hour apart as unchanged (FAT after a daylight saving change)")
+	rootCmd.PersistentFlags().Bool("security-labels", cfg.Operations.SecurityLabels, "copy SELinux contexts and file capabilities along with files (Linux)")
+	rootCmd.PersistentFlags().Bool("delete-sidecars", false, "delete the sidecars of deleted files (XMP, AAE, subtitles, .nfo, Live Photo videos); files with sidecars are kept otherwise")
+	rootCmd.PersistentFlags().BoolP("yes", "y", false, "do not ask for confirmation before large operations")
+	rootCmd.PersistentFlags().Bool("fail-on-warnings", false, fmt.Sprintf("exit with status %d when an operation completes with warnings", ExitWarnings))
+	rootCmd.PersistentFlags().Duration("lock-wait", 0, "how long to wait for a conflicting operation on overlapping paths (0 = refuse immediately)")
+	rootCmd.PersistentFlags().String("report", "", "write the result of each operation as JSON to this file, e.g. reports/{{.Operation}}-{{.ID}}.json")
+	rootCmd.PersistentFlags().String("theme", cfg.Output.Theme, "icons in messages: emoji, ascii ([OK], [WARN], ->) or minimal (none), for terminals without emoji")
+	rootCmd.PersistentFlags().String("locale", cfg.Output.Locale, "language of messages, e.g. de or es (default from LC_ALL, LC_MESSAGES or LANG)")
+	rootCmd.PersistentFlags().Bool("deterministic", false, "sorted lists, group IDs from content hashes and reports without IDs and times, so runs over the same data report byte for byte the same")
 
 	// Add subcommands
 	rootCmd.AddCommand(
 		NewCleanCommand(ctx, cfg, log),
 		NewDedupCommand(ctx, cfg, log),
+		NewReclaimCommand(ctx, cfg, log),
+		NewMonitorCommand(ctx, cfg, log),
 		NewConsolidateCommand(ctx, cfg, log),
+		NewMigrateCommand(ctx, cfg, log),
+		NewTierCommand(ctx, cfg, log),
 		NewSimilarImagesCommand(ctx, cfg, log),
+		NewSimilarAudioCommand(ctx, cfg, log),
 		NewOrganizeCommand(ctx, cfg, log),
+		NewIngestCommand(ctx, cfg, log),
+		NewTriageCommand(ctx, cfg, log),
+		NewReviewCommand(ctx, cfg, log),
+		NewVersionsCommand(ctx, cfg, log),
 		NewPipelineCommand(ctx, cfg, log),
 		NewChownCommand(ctx, cfg, log),
+		NewOwnersCommand(ctx, cfg, log),
+		NewGrantCommand(ctx, cfg, log),
+		NewStatsCommand(ctx, cfg, log),
+		NewThumbnailsCommand(ctx, cfg, log),
+		NewStripMetadataCommand(ctx, cfg, log),
+		NewTranscodeCommand(ctx, cfg, log),
+		NewPDFCommand(ctx, cfg, log),
+		NewMailCommand(ctx, cfg, log),
+		NewBagCommand(ctx, cfg, log),
+		NewIndexCommand(ctx, cfg, log),
+		NewSearchCommand(ctx, cfg, log),
+		NewChecksumCommand(ctx, cfg, log),
+		NewHashCommand(ctx, cfg, log),
+		NewVerifyTreeCommand(ctx, cfg, log),
+		NewCacheCommand(ctx, cfg, log),
+		NewJournalCommand(ctx, cfg, log),
+		NewRepairCommand(ctx, cfg, log),
+		NewEncryptCommand(ctx, cfg, log),
+		NewDecryptCommand(ctx, cfg, log),
+		NewPackCommand(ctx, cfg, log),
+		NewUploadCommand(ctx, cfg, log),
+		NewRetryCommand(ctx, cfg, log),
+		NewRerunCommand(ctx, cfg, log),
+		NewResultsCommand(ctx, cfg, log),
+		NewSchemaCommand(ctx, cfg, log),
+		NewOpsCommand(ctx, cfg, log),
+		NewDoctorCommand(ctx, cfg, log),
+		NewBenchCommand(ctx, cfg, log),
+		NewSimulateCommand(ctx, cfg, log),
+		NewAgentCommand(ctx, cfg, log),
+		NewPolicyCommand(ctx, cfg, log),
 		newVersionCommand(),
 	)
 
@@ -65,9 +137,9 @@ func newVersionCommand() *cobra.Command {
 		Use:   "version",
 		Short: "Show version information",
 		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println("FileOps v1.0.0")
-			fmt.Println("Build: development")
-			fmt.Println("Go version:", "go1.21+")
+			fmt.Fprintln(ui.Stdout, "FileOps", Version)
+			fmt.Fprintln(ui.Stdout, "Commit:", GitCommit)
+			fmt.Fprintln(ui.Stdout, "Go version:", runtime.Version())
 		},
 	}
 }
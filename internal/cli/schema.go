@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+// schemaDocuments names the documents of each kind
+var schemaDocuments = map[schema.Kind]string{
+	schema.Result:     "stored results, --report files, report.json of runs, results show --output json",
+	schema.ReviewPlan: "plans written by --plan-out, read by review",
+	schema.RunPlan:    "plan.json of runs",
+	schema.Progress:   "progress snapshots read by ops",
+	schema.Event:      "events published to engine subscribers",
+	schema.Agent:      "responses of the agent API",
+}
+
+// schemaColumns are the columns of the schema versions table
+var schemaColumns = []tableColumn{
+	textColumn("kind"),
+	numberColumn("version"),
+	textColumn("documents"),
+}
+
+// NewSchemaCommand creates the schema command
+func NewSchemaCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Show and convert the versions of JSON outputs",
+		Long: `Every JSON document fileops writes for other tools carries the version of
+its layout in a schema_version field: results and reports, review and run
+plans, progress snapshots, events and agent API responses.
+
+Within a version fields are only added. Renaming or removing a field, or
+changing its meaning, increments the version, and convert translates
+documents between any two versions, so tools keep working on older files
+and on the output of newer fileops. Documents without a schema_version are
+version 0.`,
+	}
+
+	cmd.AddCommand(newSchemaVersionsCommand())
+	cmd.AddCommand(newSchemaConvertCommand(log))
+	return cmd
+}
+
+// newSchemaVersionsCommand creates the schema versions command
+func newSchemaVersionsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "versions",
+		Short: "List the current schema version of every kind of document",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			versions := newTable(schemaColumns...)
+			for _, kind := range schema.Kinds() {
+				version, _ := schema.Current(kind)
+				versions.addRow(string(kind), version, schemaDocuments[kind])
+			}
+			options := tableFlags(cmd)
+			options.Indent = ""
+			return versions.render(ui.Stdout, options)
+		},
+	}
+	addTableFlags(cmd, schemaColumns...)
+	return cmd
+}
+
+// newSchemaConvertCommand creates the schema convert command
+func newSchemaConvertCommand(log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "convert <file>",
+		Short: "Convert JSON documents to another schema version",
+		Long: `Convert JSON documents to the current schema version, or with --to to an
+earlier one for tools that expect it. The file holds one document or
+several, e.g. one per line; - reads standard input. The kind of each
+document is told by its fields unless --kind is given.
+
+A single document is written indented, several one per line.`,
+		Example: `  fileops schema convert old-report.json > report.json
+  fileops schema convert --to 0 reports/dedup.json
+  cat events.ndjson | fileops schema convert --kind event -`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kindName, _ := cmd.Flags().GetString("kind")
+			to, _ := cmd.Flags().GetInt("to")
+			if kindName != "" {
+				if _, err := schema.Current(schema.Kind(kindName)); err != nil {
+					return err
+				}
+			}
+
+			input := io.Reader(os.Stdin)
+			if args[0] != "-" {
+				file, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				input = file
+			}
+
+			var docs []map[string]interface{}
+			decoder := json.NewDecoder(input)
+			for {
+				var doc map[string]interface{}
+				if err := decoder.Decode(&doc); err != nil {
+					if errors.Is(err, io.EOF) {
+						break
+					}
+					return fmt.Errorf("invalid JSON in %s: %w", args[0], err)
+				}
+
+				kind := schema.Kind(kindName)
+				if kind == "" {
+					var ok bool
+					if kind, ok = schema.Detect(doc); !ok {
+						return fmt.Errorf("cannot tell the kind of document %d of %s, use --kind", len(docs)+1, args[0])
+					}
+				}
+				version := to
+				if version < 0 {
+					version, _ = schema.Current(kind)
+				}
+				if err := schema.Convert(kind, doc, version); err != nil {
+					return fmt.Errorf("document %d of %s: %w", len(docs)+1, args[0], err)
+				}
+				docs = append(docs, doc)
+			}
+			log.Debug("Documents converted", "file", args[0], "documents", len(docs))
+
+			encoder := json.NewEncoder(ui.Stdout)
+			if len(docs) == 1 {
+				encoder.SetIndent("", "  ")
+			}
+			for _, doc := range docs {
+				if err := encoder.Encode(doc); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String("kind", "", "kind of the documents: result, review-plan, run-plan, progress, event or agent (default: told by their fields)")
+	cmd.Flags().Int("to", -1, "schema version to convert to (default: current)")
+	return cmd
+}
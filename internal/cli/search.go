@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/content"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// NewSearchCommand creates the search command
+func NewSearchCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "search <query...>",
+		Short: "Search the text of indexed documents",
+		Long: `Search the content index built by "fileops index" for documents containing
+all words of the query. Words match case-insensitively as prefixes, so
+"invoic" finds "Invoice" and "invoicing". Matches in the file name rank higher.`,
+		Example: `  fileops search acme invoice
+  fileops search --category Contracts lease
+  fileops search --output json "2024 tax"
+  fileops search --columns path,score --sort -score lease | head`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			limit, _ := cmd.Flags().GetInt("limit")
+			category, _ := cmd.Flags().GetString("category")
+			outputFormat, _ := cmd.Flags().GetString("output")
+
+			index, err := content.OpenIndex(cfg.Content.IndexDirectory)
+			if err != nil {
+				return fmt.Errorf("failed to open content index: %w", err)
+			}
+
+			query := strings.Join(args, " ")
+			hits := index.Search(query, content.SearchOptions{Category: category, Limit: limit})
+
+			log.Debug("Content search", "query", query, "category", category, "hits", len(hits))
+
+			if outputFormat == "json" {
+				encoder := json.NewEncoder(ui.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(hits)
+			}
+
+			options := tableFlags(cmd)
+			results := newTable(searchColumns...)
+			for _, hit := range hits {
+				results.addRow(hit.Path, hit.Category, hit.Score, hit.Snippet)
+			}
+			if options.TSV {
+				return results.render(ui.Stdout, options)
+			}
+
+			if index.Len() == 0 {
+				ui.Printf("📭 The content index is empty. Run \"fileops index <path>\" first.\n")
+				return nil
+			}
+			if len(hits) == 0 {
+				ui.Printf("🔍 No documents match %q\n", query)
+				return nil
+			}
+
+			ui.Printf("🔍 %d documents match %q:\n\n", len(hits), query)
+			if err := results.render(ui.Stdout, options); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Int("limit", 20, "Maximum number of results (0 = unlimited)")
+	cmd.Flags().String("category", "", "Only search documents of this category (e.g. Invoices)")
+	cmd.Flags().String("output", "table", "Output format (table, json)")
+	addTableFlags(cmd, searchColumns...)
+
+	return cmd
+}
+
+// searchColumns are the columns of search results
+var searchColumns = []tableColumn{
+	pathColumn("path"),
+	textColumn("category"),
+	tableColumn{name: "score", right: true, format: func(value interface{}) string {
+		score, _ := value.(float64)
+		return fmt.Sprintf("%.2f", score)
+	}},
+	textColumn("snippet"),
+}
@@ -0,0 +1,263 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// NewSimilarAudioCommand creates the similar-audio command
+func NewSimilarAudioCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "similar-audio [path...]",
+		Short: "Find the same song in other formats and bitrates",
+		Long: `Find recordings of the same song: the MP3 next to the FLAC it was encoded
+from, or the same album ripped at two bitrates. Recordings are compared by
+their acoustic fingerprint (Chromaprint), computed by fpcalc, which must be
+installed (e.g. the chromaprint or libchromaprint-tools package). MP3, FLAC,
+Ogg, Opus, M4A, AAC, WAV, WMA, AIFF, APE and WavPack files are compared.
+
+Each group starts with the recording of the highest quality: lossless
+formats first, then the highest bitrate. --keep-best keeps only that one and
+deletes the others, or moves them below --move-to:
+
+  fileops similar-audio ~/Music
+  fileops similar-audio ~/Music --keep-best --move-to ~/Music-Duplicates --dry-run`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			threshold, _ := cmd.Flags().GetFloat64("threshold")
+			recursive, _ := cmd.Flags().GetBool("recursive")
+			outputFormat, _ := cmd.Flags().GetString("output")
+			formats, _ := cmd.Flags().GetStringSlice("formats")
+			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+			parallelism, _ := cmd.Flags().GetInt("parallelism")
+			keepBest, _ := cmd.Flags().GetBool("keep-best")
+			moveTo, _ := cmd.Flags().GetString("move-to")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+			switch outputFormat {
+			case "table", "json", "csv":
+			default:
+				return fmt.Errorf("unknown output format %q (use table, json or csv)", outputFormat)
+			}
+			if moveTo != "" && !keepBest {
+				return fmt.Errorf("--move-to requires --keep-best")
+			}
+
+			validPaths := make([]string, 0, len(args))
+			for _, path := range args {
+				absPath, err := filepath.Abs(path)
+				if err != nil {
+					return fmt.Errorf("invalid path %s: %w", path, err)
+				}
+				if _, err := os.Stat(absPath); os.IsNotExist(err) {
+					return fmt.Errorf("path does not exist: %s", absPath)
+				}
+				validPaths = append(validPaths, absPath)
+			}
+			if moveTo != "" {
+				absMoveTo, err := filepath.Abs(moveTo)
+				if err != nil {
+					return fmt.Errorf("invalid path %s: %w", moveTo, err)
+				}
+				moveTo = absMoveTo
+			}
+
+			config := domain.OperationConfig{
+				DryRun:              dryRun,
+				Recursive:           recursive,
+				ExcludePatterns:     excludePatterns,
+				TargetPaths:         validPaths,
+				SimilarityThreshold: threshold,
+				Parallelism:         parallelism,
+				CustomSettings: map[string]interface{}{
+					"method":        engine.AudioSimilarityMethod,
+					"formats":       formats,
+					"remove_others": keepBest,
+					"destination":   moveTo,
+				},
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+
+			tracker := progress.NewTracker()
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
+
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+			table := outputFormat == "table"
+
+			log.Info("🎵 Starting audio similarity detection",
+				"paths", validPaths,
+				"threshold", threshold,
+				"keep_best", keepBest,
+				"dry_run", dryRun)
+
+			if table && !quiet {
+				ui.Printf("🎵 Finding the same songs in other formats...\n")
+				if dryRun {
+					ui.Printf("📋 DRY RUN MODE: No files will be changed\n")
+				}
+				ui.Printf("📂 Paths to scan: %v\n", validPaths)
+				if keepBest && moveTo != "" {
+					ui.Printf("📦 Moving all but the best recording of each song to: %s\n", moveTo)
+				} else if keepBest {
+					ui.Printf("🗑️  Deleting all but the best recording of each song\n")
+				}
+				fmt.Fprintln(ui.Stdout)
+			}
+
+			if keepBest && !confirmLargeOperation(cmd, cfg, operationEngine, domain.OperationSimilarity, config) {
+				ui.Printf("Nothing was changed\n")
+				return nil
+			}
+
+			operationID := engine.NewOperationID(domain.OperationSimilarity)
+
+			progressCtx, progressCancel := context.WithCancel(ctx)
+			defer progressCancel()
+
+			var progressWg sync.WaitGroup
+			if table && !quiet && cfg.Operations.EnableProgressBar {
+				progressWg.Add(1)
+				go func() {
+					defer progressWg.Done()
+					MonitorProgress(progressCtx, tracker, operationID, "similarity")
+				}()
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			result, err := operationEngine.ExecuteOperationWithID(ctx, domain.OperationSimilarity, config, operationID)
+
+			progressCancel()
+			progressWg.Wait()
+
+			if err != nil {
+				if table && !quiet {
+					ui.Printf("\n❌ Audio similarity detection failed: %v\n", err)
+					displayStoredResultHint(cfg, operationID)
+				}
+				return fmt.Errorf("audio similarity detection failed: %w", err)
+			}
+
+			groups, _ := result.Details["groups"].([]domain.SimilarityGroup)
+
+			switch outputFormat {
+			case "json":
+				encoder := json.NewEncoder(ui.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(groups); err != nil {
+					return err
+				}
+			case "csv":
+				if err := writeAudioCSV(groups); err != nil {
+					return err
+				}
+			default:
+				if !quiet {
+					ui.Printf("\n\n✅ Audio similarity detection completed!\n")
+					ui.Printf("📊 %s\n", result.Summary)
+					if reclaimed, ok := result.Details["reclaimed"].(int64); ok && reclaimed > 0 && moveTo == "" && !dryRun {
+						ui.Printf("♻️  Space reclaimed: %s\n", FormatBytes(reclaimed))
+					}
+					displayAudioGroups(groups)
+					DisplayOperationWarnings(result)
+					DisplayOperationErrors(result)
+				}
+			}
+
+			log.Info("✅ Audio similarity detection completed", "summary", result.Summary)
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	cmd.Flags().Float64("threshold", 0, "Share of equal fingerprint bits from which recordings match (0.0-1.0; 0 = 0.85)")
+	cmd.Flags().BoolP("recursive", "r", true, "Process directories recursively")
+	cmd.Flags().String("output", "table", "Output format (table, json, csv)")
+	cmd.Flags().StringSlice("formats", []string{}, "Formats (extensions) to compare; default: all audio formats")
+	cmd.Flags().StringSlice("exclude", []string{}, "Patterns to exclude")
+	cmd.Flags().Int("parallelism", cfg.Performance.MaxWorkers, "Number of parallel workers")
+	cmd.Flags().Bool("keep-best", false, "Keep only the highest quality recording of each song and delete the others")
+	cmd.Flags().String("move-to", "", "With --keep-best, move the other recordings below this directory instead of deleting them")
+	cmd.Flags().Bool("dry-run", false, "Preview the removals without executing them")
+
+	return cmd
+}
+
+// displayAudioGroups lists the groups with the format, bitrate and length of
+// every recording; the first, starred one is the best
+func displayAudioGroups(groups []domain.SimilarityGroup) {
+	for i, group := range groups {
+		if i == 20 {
+			ui.Printf("\n  ... and %d more groups\n", len(groups)-20)
+			break
+		}
+		ui.Printf("\n🎵 %s (%.0f%% similar):\n", group.ID, group.Similarity*100)
+		for j, file := range group.Files {
+			marker := "  "
+			if j == 0 {
+				marker = "⭐"
+			}
+			quality := file.Metadata["bitrate"] + " kbps"
+			if file.Metadata["lossless"] != "" {
+				quality = "lossless"
+			}
+			ui.Printf("  %s %s (%s, %s, %s, %s)\n", marker, file.Path, file.Metadata["format"], quality,
+				formatSeconds(file.Metadata["duration"]), FormatBytes(file.Size))
+		}
+	}
+}
+
+// formatSeconds renders a duration in seconds as minutes:seconds
+func formatSeconds(seconds string) string {
+	value, err := strconv.ParseFloat(seconds, 64)
+	if err != nil {
+		return "?"
+	}
+	total := int(value + 0.5)
+	return fmt.Sprintf("%d:%02d", total/60, total%60)
+}
+
+// writeAudioCSV writes one row per recording of every group
+func writeAudioCSV(groups []domain.SimilarityGroup) error {
+	w := csv.NewWriter(ui.Stdout)
+	if err := w.Write([]string{"group", "similarity", "path", "format", "bitrate", "lossless", "duration", "size", "modified"}); err != nil {
+		return err
+	}
+	for _, group := range groups {
+		for _, file := range group.Files {
+			if err := w.Write([]string{
+				group.ID,
+				strconv.FormatFloat(group.Similarity, 'f', 3, 64),
+				file.Path,
+				file.Metadata["format"],
+				file.Metadata["bitrate"],
+				strconv.FormatBool(file.Metadata["lossless"] != ""),
+				file.Metadata["duration"],
+				strconv.FormatInt(file.Size, 10),
+				file.ModTime.Format(time.RFC3339),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -2,46 +2,484 @@ package cli
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
 	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/thumbnail"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
 	"github.com/spf13/cobra"
 )
 
 // NewSimilarImagesCommand creates the similar-images command
 func NewSimilarImagesCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "similar-images [path]",
-		Short: "Find similar images using AI",
-		Long: `Find similar or duplicate images using AI-powered perceptual hashing.
+		Use:   "similar-images [path...]",
+		Short: "Find similar images and review them in an HTML gallery",
+		Long: `Find similar images: re-encoded, resized or slightly edited copies of the same
+picture, compared by a perceptual fingerprint of their content. JPEG, PNG and
+GIF images are compared; other formats are reported as unsupported. A JPEG
+shot together with a RAW file (IMG_0042.JPG and IMG_0042.CR2) is one photo:
+it is kept by default and removed only together with its RAW. Images with
+sidecars (IMG_0042.xmp, IMG_0042.AAE, ...) take them along when moved with
+--move-to; deleting them requires --delete-sidecars.
 
-This command analyzes images in the specified directory and identifies visually
-similar images, even if they have different file formats, sizes, or compression levels.`,
-		Args: cobra.ExactArgs(1),
+--gallery writes the groups as an HTML page with thumbnails side by side,
+annotated with resolution, size and date. Pick the image to keep in each
+group (or keep all), then save the decisions; the page downloads a
+decisions.json that apply carries out:
+
+  fileops similar-images ~/Pictures --gallery review.html
+  fileops similar-images apply ~/Downloads/decisions.json --dry-run
+  fileops similar-images apply ~/Downloads/decisions.json --move-to ~/Rejected
+
+--method picks how files are compared. dhash, the perceptual difference hash
+above, is built in; the engine's other registered similarity detectors take
+part the same way, and --formats then selects the files to compare.`,
+		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Get flags
 			threshold, _ := cmd.Flags().GetFloat64("threshold")
 			recursive, _ := cmd.Flags().GetBool("recursive")
 			outputFormat, _ := cmd.Flags().GetString("output")
+			formats, _ := cmd.Flags().GetStringSlice("formats")
+			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+			parallelism, _ := cmd.Flags().GetInt("parallelism")
+			gallery, _ := cmd.Flags().GetString("gallery")
+			method, _ := cmd.Flags().GetString("method")
+
+			switch outputFormat {
+			case "table", "json", "csv":
+			default:
+				return fmt.Errorf("unknown output format %q (use table, json or csv)", outputFormat)
+			}
+
+			// Validate paths
+			validPaths := make([]string, 0, len(args))
+			for _, path := range args {
+				absPath, err := filepath.Abs(path)
+				if err != nil {
+					return fmt.Errorf("invalid path %s: %w", path, err)
+				}
+				if _, err := os.Stat(absPath); os.IsNotExist(err) {
+					return fmt.Errorf("path does not exist: %s", absPath)
+				}
+				validPaths = append(validPaths, absPath)
+			}
+
+			config := domain.OperationConfig{
+				Recursive:           recursive,
+				ExcludePatterns:     excludePatterns,
+				TargetPaths:         validPaths,
+				SimilarityThreshold: threshold,
+				Parallelism:         parallelism,
+				CustomSettings: map[string]interface{}{
+					"formats": formats,
+					"method":  method,
+				},
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+
+			tracker := progress.NewTracker()
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
+
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+			table := outputFormat == "table"
 
 			log.Info("🖼️ Starting image similarity detection",
-				"path", args[0],
+				"paths", validPaths,
 				"threshold", threshold,
+				"method", method,
 				"recursive", recursive,
 				"output_format", outputFormat)
 
-			// TODO: Implement AI image similarity detection
-			return fmt.Errorf("similar-images feature not implemented yet")
+			if table && !quiet {
+				ui.Printf("🖼️  Finding similar images...\n")
+				ui.Printf("📂 Paths to scan: %v\n", validPaths)
+				if method != engine.DefaultSimilarityMethod {
+					ui.Printf("🔬 Similarity method: %s\n", method)
+				}
+				if threshold > 0 {
+					ui.Printf("📊 Similarity threshold: %.2f\n\n", threshold)
+				} else {
+					ui.Printf("📊 Similarity threshold: default of the method\n\n")
+				}
+			}
+
+			operationID := engine.NewOperationID(domain.OperationSimilarity)
+
+			progressCtx, progressCancel := context.WithCancel(ctx)
+			defer progressCancel()
+
+			var progressWg sync.WaitGroup
+			if table && !quiet && cfg.Operations.EnableProgressBar {
+				progressWg.Add(1)
+				go func() {
+					defer progressWg.Done()
+					MonitorProgress(progressCtx, tracker, operationID, "similarity")
+				}()
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			result, err := operationEngine.ExecuteOperationWithID(ctx, domain.OperationSimilarity, config, operationID)
+
+			progressCancel()
+			progressWg.Wait()
+
+			if err != nil {
+				if table && !quiet {
+					ui.Printf("\n❌ Image similarity detection failed: %v\n", err)
+					displayStoredResultHint(cfg, operationID)
+				}
+				return fmt.Errorf("image similarity detection failed: %w", err)
+			}
+
+			groups, _ := result.Details["groups"].([]domain.SimilarityGroup)
+
+			if gallery != "" {
+				if err := writeSimilarityGallery(ctx, cfg, gallery, operationID, validPaths, groups); err != nil {
+					return fmt.Errorf("failed to write gallery: %w", err)
+				}
+			}
+
+			switch outputFormat {
+			case "json":
+				encoder := json.NewEncoder(ui.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(groups); err != nil {
+					return err
+				}
+			case "csv":
+				if err := writeSimilarityCSV(groups); err != nil {
+					return err
+				}
+			default:
+				if !quiet {
+					ui.Printf("\n\n✅ Image similarity detection completed!\n")
+					ui.Printf("📊 %s\n", result.Summary)
+					if reviewable, ok := result.Details["reviewable_size"].(int64); ok && reviewable > 0 {
+						ui.Printf("  Space held by the other images of each group: %s\n", FormatBytes(reviewable))
+					}
+					displaySimilarityGroups(groups)
+					if gallery != "" {
+						ui.Printf("\n🖼️  Review gallery: %s\n", gallery)
+						ui.Printf("   Save the decisions there, then: fileops similar-images apply decisions.json\n")
+					}
+					DisplayOperationWarnings(result)
+					DisplayOperationErrors(result)
+				}
+			}
+
+			log.Info("✅ Image similarity detection completed", "summary", result.Summary)
+			return warningPolicy(cmd, result)
 		},
 	}
 
+	cmd.AddCommand(newSimilarImagesApplyCommand(ctx, cfg, log))
+
 	// Add flags
-	cmd.Flags().Float64("threshold", 0.85, "Similarity threshold (0.0-1.0)")
+	cmd.Flags().Float64("threshold", 0, "Similarity threshold (0.0-1.0; 0 = default of the method, 0.85 for dhash)")
 	cmd.Flags().BoolP("recursive", "r", true, "Process directories recursively")
 	cmd.Flags().String("output", "table", "Output format (table, json, csv)")
-	cmd.Flags().StringSlice("formats", []string{"jpg", "jpeg", "png", "gif", "bmp", "tiff"}, "Image formats to process")
-	cmd.Flags().Bool("group-similar", false, "Group similar images in subdirectories")
+	cmd.Flags().StringSlice("formats", []string{}, "Formats (extensions) to compare; default: those the method reads (jpg, jpeg, png, gif for dhash)")
+	cmd.Flags().String("method", engine.DefaultSimilarityMethod, "Similarity method, a registered similarity detector")
+	cmd.Flags().StringSlice("exclude", []string{}, "Patterns to exclude")
+	cmd.Flags().Int("parallelism", cfg.Performance.MaxWorkers, "Number of parallel workers")
+	cmd.Flags().String("gallery", "", "Write an HTML review gallery of the groups to this file")
+
+	return cmd
+}
+
+// newSimilarImagesApplyCommand creates the similar-images apply subcommand
+func newSimilarImagesApplyCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apply <decisions.json>",
+		Short: "Remove the images a gallery review decided against",
+		Long: `Carry out the decisions saved from a review gallery: in every group the
+images not kept are deleted, or moved below --move-to. An image is only
+removed while the image kept in its place still exists, and only below the
+paths that were reviewed.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			moveTo, _ := cmd.Flags().GetString("move-to")
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			path, err := filepath.Abs(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid path %s: %w", args[0], err)
+			}
+			decisions, err := engine.LoadSimilarityDecisions(path)
+			if err != nil {
+				return err
+			}
+			if moveTo != "" {
+				if moveTo, err = filepath.Abs(moveTo); err != nil {
+					return fmt.Errorf("invalid path %s: %w", moveTo, err)
+				}
+			}
+
+			config := domain.OperationConfig{
+				DryRun:      dryRun,
+				TargetPaths: decisions.Roots,
+				CustomSettings: map[string]interface{}{
+					"decisions":   path,
+					"destination": moveTo,
+				},
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+
+			tracker := progress.NewTracker()
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
+
+			log.Info("🖼️ Applying similar image decisions", "decisions", path, "move_to", moveTo, "dry_run", dryRun)
+
+			if !quiet {
+				ui.Printf("🖼️  Applying review decisions from %s\n", path)
+				if dryRun {
+					ui.Printf("📋 DRY RUN MODE: No files will be changed\n")
+				}
+				if moveTo != "" {
+					ui.Printf("📦 Moving rejected images to: %s\n", moveTo)
+				}
+				fmt.Fprintln(ui.Stdout)
+			}
+
+			if !confirmLargeOperation(cmd, cfg, operationEngine, domain.OperationSimilarity, config) {
+				ui.Printf("Nothing was changed\n")
+				return nil
+			}
+
+			operationID := engine.NewOperationID(domain.OperationSimilarity)
+			result, err := operationEngine.ExecuteOperationWithID(ctx, domain.OperationSimilarity, config, operationID)
+			if err != nil {
+				if !quiet {
+					ui.Printf("\n❌ Applying decisions failed: %v\n", err)
+					displayStoredResultHint(cfg, operationID)
+				}
+				return fmt.Errorf("applying decisions failed: %w", err)
+			}
+
+			if !quiet {
+				ui.Printf("✅ %s\n", result.Summary)
+				if reclaimed, ok := result.Details["reclaimed"].(int64); ok && reclaimed > 0 && moveTo == "" && !dryRun {
+					ui.Printf("♻️  Space reclaimed: %s\n", FormatBytes(reclaimed))
+				}
+				DisplayOperationWarnings(result)
+				DisplayOperationErrors(result)
+			}
+
+			log.Info("✅ Similar image decisions applied", "summary", result.Summary)
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	cmd.Flags().Bool("dry-run", false, "Preview the removals without executing them")
+	cmd.Flags().String("move-to", "", "Move rejected images below this directory instead of deleting them")
 
 	return cmd
 }
+
+// displaySimilarityGroups lists the groups, the image kept by default first
+func displaySimilarityGroups(groups []domain.SimilarityGroup) {
+	for i, group := range groups {
+		if i == 20 {
+			ui.Printf("\n  ... and %d more groups\n", len(groups)-20)
+			break
+		}
+		ui.Printf("\n🖼️  %s (%.0f%% similar):\n", group.ID, group.Similarity*100)
+		for j, file := range group.Files {
+			marker := "  "
+			if j == 0 {
+				marker = "⭐"
+			}
+			raw := ""
+			if file.Metadata["raw"] != "" {
+				raw = ", + RAW"
+			}
+			ui.Printf("  %s %s (%sx%s, %s%s)\n", marker, file.Path, file.Metadata["width"], file.Metadata["height"], FormatBytes(file.Size), raw)
+		}
+	}
+}
+
+// writeSimilarityCSV writes one row per image of every group
+func writeSimilarityCSV(groups []domain.SimilarityGroup) error {
+	w := csv.NewWriter(ui.Stdout)
+	if err := w.Write([]string{"group", "similarity", "path", "width", "height", "size", "modified"}); err != nil {
+		return err
+	}
+	for _, group := range groups {
+		for _, file := range group.Files {
+			if err := w.Write([]string{
+				group.ID,
+				strconv.FormatFloat(group.Similarity, 'f', 3, 64),
+				file.Path,
+				file.Metadata["width"],
+				file.Metadata["height"],
+				strconv.FormatInt(file.Size, 10),
+				file.ModTime.Format(time.RFC3339),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// galleryImage is an image of the review gallery
+type galleryImage struct {
+	domain.FileInfo
+	Thumbnail template.URL // data: URL of the thumbnail, empty when none could be rendered
+}
+
+// galleryGroup is a similarity group of the review gallery
+type galleryGroup struct {
+	ID         string
+	Similarity float64
+	Images     []galleryImage
+}
+
+// writeSimilarityGallery writes the groups as a standalone HTML page. The
+// thumbnails are embedded so the page can be opened anywhere.
+func writeSimilarityGallery(ctx context.Context, cfg *config.Config, path, operationID string, roots []string, groups []domain.SimilarityGroup) error {
+	size := cfg.Thumbnails.Size
+	if size <= 0 {
+		size = 256
+	}
+	generator := thumbnail.NewGenerator(size, cfg.Thumbnails.Quality)
+
+	// Review is what the page needs to write the decisions
+	type reviewGroup struct {
+		ID    string   `json:"id"`
+		Paths []string `json:"paths"`
+	}
+	review := struct {
+		Operation string        `json:"operation"`
+		Roots     []string      `json:"roots"`
+		Groups    []reviewGroup `json:"groups"`
+	}{Operation: operationID, Roots: roots}
+	page := struct {
+		Roots  []string
+		Groups []galleryGroup
+		Review interface{}
+	}{Roots: roots}
+
+	for _, group := range groups {
+		entry := galleryGroup{ID: group.ID, Similarity: group.Similarity}
+		paths := make([]string, 0, len(group.Files))
+		for _, file := range group.Files {
+			paths = append(paths, file.Path)
+			image := galleryImage{FileInfo: file}
+			target := thumbnail.CachePath(cfg.Thumbnails.CacheDirectory, file.Path, size)
+			if thumbnail.UpToDate(file.Path, target) || generator.Generate(ctx, file.Path, target) == nil {
+				if data, err := os.ReadFile(target); err == nil {
+					image.Thumbnail = template.URL("data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(data))
+				}
+			}
+			entry.Images = append(entry.Images, image)
+		}
+		page.Groups = append(page.Groups, entry)
+		review.Groups = append(review.Groups, reviewGroup{ID: group.ID, Paths: paths})
+	}
+	page.Review = review
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := galleryTemplate.Execute(file, page); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// galleryTemplate renders the review gallery. Saving builds the decisions
+// in the browser and downloads them as decisions.json.
+var galleryTemplate = template.Must(template.New("gallery").Funcs(template.FuncMap{
+	"bytes":   FormatBytes,
+	"percent": func(ratio float64) string { return fmt.Sprintf("%.0f%%", ratio*100) },
+	"date":    func(t time.Time) string { return t.Format("2006-01-02 15:04") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>fileops similar images</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+.group { border: 1px solid #ddd; border-radius: 6px; padding: 1em; margin-bottom: 1.5em; }
+.images { display: flex; flex-wrap: wrap; gap: 1em; }
+label.image { display: block; width: 260px; padding: 6px; border: 2px solid transparent; border-radius: 6px; cursor: pointer; }
+label.image:has(input:checked) { border-color: #4a90d9; background: #eef5fc; }
+label.image img { max-width: 256px; max-height: 256px; display: block; margin-bottom: 4px; }
+.missing { width: 256px; height: 128px; background: #eee; display: flex; align-items: center; justify-content: center; color: #888; }
+.meta { font-size: 0.85em; color: #555; word-break: break-all; }
+.bar { position: sticky; top: 0; background: #fff; padding: 0.5em 0; border-bottom: 1px solid #ddd; margin-bottom: 1em; }
+button { font-size: 1em; padding: 0.4em 1.2em; }
+</style>
+</head>
+<body>
+<h1>Similar images</h1>
+<p>{{range $i, $root := .Roots}}{{if $i}}, {{end}}{{$root}}{{end}} &middot; {{len .Groups}} groups</p>
+<div class="bar">Pick the image to keep in each group, then <button onclick="save()">Save decisions</button>
+and run <code>fileops similar-images apply decisions.json</code></div>
+{{range .Groups}}<div class="group" data-group="{{.ID}}">
+<h3>{{.ID}} &middot; {{percent .Similarity}} similar</h3>
+<div class="images">
+{{$group := .ID}}{{range $i, $image := .Images}}<label class="image">
+{{if .Thumbnail}}<img src="{{.Thumbnail}}" alt="{{.Name}}">{{else}}<div class="missing">no preview</div>{{end}}
+<input type="radio" name="{{$group}}" value="{{.Path}}"{{if eq $i 0}} checked{{end}}> keep
+<div class="meta"><b>{{index .Metadata "width"}}&times;{{index .Metadata "height"}}</b> &middot; {{bytes .Size}} &middot; {{date .ModTime}}{{if index .Metadata "raw"}} &middot; <b>+ RAW</b>{{end}}<br>{{.Path}}</div>
+</label>
+{{end}}<label class="image"><input type="radio" name="{{$group}}" value=""> keep all</label>
+</div>
+</div>
+{{end}}<script>
+const review = {{.Review}};
+function save() {
+  const decisions = [];
+  for (const group of review.groups) {
+    const checked = document.querySelector('input[name="' + CSS.escape(group.id) + '"]:checked');
+    if (!checked || checked.value === "") continue;
+    decisions.push({
+      group: group.id,
+      keep: checked.value,
+      remove: group.paths.filter(path => path !== checked.value),
+    });
+  }
+  const blob = new Blob([JSON.stringify({operation: review.operation, roots: review.roots, decisions: decisions}, null, 2)], {type: "application/json"});
+  const link = document.createElement("a");
+  link.href = URL.createObjectURL(blob);
+  link.download = "decisions.json";
+  link.click();
+}
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,267 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/simulate"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// NewSimulateCommand creates the simulate command
+func NewSimulateCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	simulateCmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "Try settings and performance on synthetic data",
+		Long: `Generate synthetic directory trees and run operations against them, so
+settings and performance can be validated before real data is touched.
+
+A dataset is a directory holding the tree (data/) and a manifest describing it,
+including how many duplicates it contains:
+
+  fileops simulate gen /tmp/sim --files 10000 --depth 3 --duplicate-ratio 0.2
+  fileops simulate run deduplication /tmp/sim
+  fileops simulate run organization --files 5000 --set strategy=date
+
+Without a dataset, run generates one in a temporary directory and removes it
+afterwards. The same flags and --seed always generate the same tree.`,
+	}
+
+	simulateCmd.AddCommand(
+		newSimulateGenCommand(ctx, cfg, log),
+		newSimulateRunCommand(ctx, cfg, log),
+	)
+
+	return simulateCmd
+}
+
+// newSimulateGenCommand creates the simulate gen subcommand
+func newSimulateGenCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gen [dir]",
+		Short: "Generate a synthetic dataset",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+			spec, err := simulationSpec(cmd)
+			if err != nil {
+				return err
+			}
+
+			log.Info("🧪 Generating synthetic dataset", "dir", args[0], "files", spec.Files, "depth", spec.Depth)
+			start := time.Now()
+			manifest, err := simulate.Generate(ctx, args[0], spec)
+			if err != nil {
+				return fmt.Errorf("failed to generate dataset: %w", err)
+			}
+			if !quiet {
+				displayManifest(manifest)
+				ui.Printf("⏱️  Generated in %v\n", time.Since(start).Round(time.Millisecond))
+			}
+			return nil
+		},
+	}
+
+	addSimulationFlags(cmd)
+
+	return cmd
+}
+
+// newSimulateRunCommand creates the simulate run subcommand
+func newSimulateRunCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run [operation] [dataset]",
+		Short: "Run an operation against a synthetic dataset",
+		Long: `Run an operation against a dataset made by simulate gen, or against a new one
+generated with the given flags, and report its throughput.
+
+The operation takes the configured defaults (hash algorithm, threshold,
+workers) and the settings given with --set, as setting=value. Organization
+defaults to organizing the tree in place. Deduplication is checked against the
+manifest and fails when it finds a different number of duplicate groups, so
+CI can run it as a test.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			overrides, _ := cmd.Flags().GetStringSlice("set")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			keep, _ := cmd.Flags().GetBool("keep")
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			operationType := domain.OperationType(args[0])
+			operationEngine, err := newOperationEngine(cmd, cfg, log, progress.NewTracker())
+			if err != nil {
+				return err
+			}
+			supported := operationEngine.GetSupportedOperations()
+			if !containsOperation(supported, operationType) {
+				names := make([]string, 0, len(supported))
+				for _, operation := range supported {
+					names = append(names, string(operation))
+				}
+				sort.Strings(names)
+				return fmt.Errorf("unknown operation %q (use one of %s)", operationType, strings.Join(names, ", "))
+			}
+
+			var manifest *simulate.Manifest
+			if len(args) == 2 {
+				if manifest, err = simulate.LoadManifest(args[1]); err != nil {
+					if errors.Is(err, os.ErrNotExist) {
+						return fmt.Errorf("%s is not a dataset made by simulate gen", args[1])
+					}
+					return err
+				}
+			} else {
+				spec, err := simulationSpec(cmd)
+				if err != nil {
+					return err
+				}
+				dir, err := os.MkdirTemp("", "fileops-simulate-")
+				if err != nil {
+					return err
+				}
+				if keep {
+					defer ui.Printf("📁 Dataset kept in %s\n", dir)
+				} else {
+					defer os.RemoveAll(dir)
+				}
+				if manifest, err = simulate.Generate(ctx, dir, spec); err != nil {
+					return fmt.Errorf("failed to generate dataset: %w", err)
+				}
+			}
+			if !quiet {
+				displayManifest(manifest)
+			}
+
+			settings := make(map[string]interface{}, len(overrides)+1)
+			if operationType == domain.OperationOrganization {
+				settings["destination"] = manifest.Root
+			}
+			for _, override := range overrides {
+				key, value, found := strings.Cut(override, "=")
+				if !found || key == "" {
+					return fmt.Errorf("invalid --set %q, expected setting=value", override)
+				}
+				settings[key] = parseSettingValue(value)
+			}
+
+			config := domain.OperationConfig{
+				DryRun:              dryRun,
+				Recursive:           true,
+				TargetPaths:         []string{manifest.Root},
+				HashAlgorithm:       cfg.Operations.HashAlgorithm,
+				SimilarityThreshold: cfg.Operations.DuplicateThreshold,
+				Parallelism:         cfg.Performance.MaxWorkers,
+				CustomSettings:      settings,
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+
+			log.Info("🧪 Running operation on synthetic dataset", "operation", operationType, "root", manifest.Root)
+			result, err := operationEngine.ExecuteOperation(ctx, operationType, config)
+			if err != nil {
+				return fmt.Errorf("%s failed on the synthetic dataset: %w", operationType, err)
+			}
+
+			if !quiet {
+				seconds := result.Duration.Seconds()
+				ui.Printf("\n✅ %s (%s): %s\n", operationType, result.ID, result.Summary)
+				ui.Printf("⏱️  Time: %v\n", result.Duration.Round(time.Millisecond))
+				if seconds > 0 {
+					ui.Printf("⚡ Throughput: %.0f files/s, %s/s\n",
+						float64(manifest.Files)/seconds, FormatBytes(int64(float64(manifest.Bytes)/seconds)))
+				}
+				DisplayOperationWarnings(result)
+				DisplayOperationErrors(result)
+			}
+
+			if operationType == domain.OperationDeduplication {
+				if err := checkSimulatedDuplicates(result, manifest, quiet); err != nil {
+					return err
+				}
+			}
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	addSimulationFlags(cmd)
+	cmd.Flags().StringSlice("set", nil, "Set an operation setting, as setting=value (repeatable)")
+	cmd.Flags().Bool("dry-run", false, "Run the operation as a dry run")
+	cmd.Flags().Bool("keep", false, "Keep a generated dataset instead of removing it")
+
+	return cmd
+}
+
+// addSimulationFlags adds the flags describing a dataset to generate
+func addSimulationFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("depth", 3, "Levels of directories below the root")
+	cmd.Flags().Int("fanout", 4, "Subdirectories of every directory above the last level")
+	cmd.Flags().Int("files", 1000, "Number of files")
+	cmd.Flags().String("min-size", "1KB", "Smallest file size")
+	cmd.Flags().String("max-size", "1MB", "Largest file size")
+	cmd.Flags().String("size-dist", simulate.DistributionLogNormal, "File size distribution (uniform, lognormal)")
+	cmd.Flags().Float64("duplicate-ratio", 0.1, "Share of files that copy another file (0.0-1.0)")
+	cmd.Flags().Int64("seed", 1, "Seed of the generator; the same seed generates the same tree")
+}
+
+// simulationSpec reads the dataset flags
+func simulationSpec(cmd *cobra.Command) (simulate.Spec, error) {
+	spec := simulate.Spec{}
+	spec.Depth, _ = cmd.Flags().GetInt("depth")
+	spec.Fanout, _ = cmd.Flags().GetInt("fanout")
+	spec.Files, _ = cmd.Flags().GetInt("files")
+	minSize, _ := cmd.Flags().GetString("min-size")
+	maxSize, _ := cmd.Flags().GetString("max-size")
+	spec.MinSize = ParseSize(minSize, -1)
+	spec.MaxSize = ParseSize(maxSize, -1)
+	spec.Distribution, _ = cmd.Flags().GetString("size-dist")
+	spec.DuplicateRatio, _ = cmd.Flags().GetFloat64("duplicate-ratio")
+	spec.Seed, _ = cmd.Flags().GetInt64("seed")
+	if err := spec.Validate(); err != nil {
+		return spec, fmt.Errorf("invalid dataset: %w", err)
+	}
+	return spec, nil
+}
+
+// displayManifest prints what a dataset contains
+func displayManifest(manifest *simulate.Manifest) {
+	ui.Printf("🧪 Dataset %s: %d files (%s) in %d directories, depth %d\n", manifest.Root,
+		manifest.Files, FormatBytes(manifest.Bytes), manifest.Directories, manifest.Spec.Depth)
+	ui.Printf("🔍 Duplicates: %d copies in %d groups (%s saveable)\n",
+		manifest.Duplicates, manifest.DuplicateGroups, FormatBytes(manifest.DuplicateBytes))
+}
+
+// checkSimulatedDuplicates compares what deduplication found with the manifest
+func checkSimulatedDuplicates(result *domain.OperationResult, manifest *simulate.Manifest, quiet bool) error {
+	found, ok := result.Details["duplicate_groups"].(int)
+	if !ok {
+		return nil
+	}
+	if found != manifest.DuplicateGroups {
+		return fmt.Errorf("deduplication found %d duplicate groups, the dataset has %d", found, manifest.DuplicateGroups)
+	}
+	if !quiet {
+		ui.Printf("🎯 Found all %d duplicate groups of the dataset\n", found)
+	}
+	return nil
+}
+
+// containsOperation reports whether operations holds operation
+func containsOperation(operations []domain.OperationType, operation domain.OperationType) bool {
+	for _, candidate := range operations {
+		if candidate == operation {
+			return true
+		}
+	}
+	return false
+}
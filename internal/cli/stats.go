@@ -0,0 +1,313 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// NewStatsCommand creates the stats command
+func NewStatsCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stats [path...]",
+		Short: "Show storage composition and growth across stored scans",
+		Long: `Scan the given paths into an inventory and show how storage developed across
+all stored inventories of the same paths: total size and duplicate ratio per
+scan, composition by category (images, videos, documents, ...) and the
+directories that grew most. Nothing is changed.
+
+Inventories are kept in the cache directory, one per run. Run stats regularly
+(e.g. from cron) to build up a history; --stored reports from the stored
+inventories without scanning:
+
+  fileops stats /data
+  fileops stats /data --stored --compare previous
+  fileops stats /data --stored --output html > storage.html
+
+--columns and --sort shape the composition table. Piped, the table output is
+the composition alone as tab-separated values, with sizes in bytes:
+
+  fileops stats /data --stored --sort -size --columns category,size | head -5
+
+With --archives the files inside zip and tar archives (also gzip, bzip2 and
+zstd compressed tars, and archives inside archives) are listed without
+extracting them and shown by category next to the files on disk, with
+their uncompressed size:
+
+  fileops stats /backup --archives`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get flags
+			outputFormat, _ := cmd.Flags().GetString("output")
+			stored, _ := cmd.Flags().GetBool("stored")
+			noSave, _ := cmd.Flags().GetBool("no-save")
+			compare, _ := cmd.Flags().GetString("compare")
+			top, _ := cmd.Flags().GetInt("top")
+			archives, _ := cmd.Flags().GetBool("archives")
+			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			switch outputFormat {
+			case "table", "json", "html":
+			default:
+				return fmt.Errorf("unknown output format %q (use table, json or html)", outputFormat)
+			}
+
+			// Validate paths; stored inventories may outlive their paths
+			validPaths := make([]string, 0, len(args))
+			for _, path := range args {
+				absPath, err := filepath.Abs(path)
+				if err != nil {
+					return fmt.Errorf("invalid path %s: %w", path, err)
+				}
+				if _, err := os.Stat(absPath); os.IsNotExist(err) && !stored {
+					return fmt.Errorf("path does not exist: %s", absPath)
+				}
+				validPaths = append(validPaths, absPath)
+			}
+
+			// Create operation configuration
+			config := domain.OperationConfig{
+				DryRun:          true,
+				Recursive:       true,
+				ExcludePatterns: excludePatterns,
+				TargetPaths:     validPaths,
+				CustomSettings: map[string]interface{}{
+					"inventory_directory": cfg.Operations.CacheDirectory,
+					"scan":                !stored,
+					"save":                !noSave,
+					"compare":             compare,
+					"top":                 top,
+					"archives":            archives,
+				},
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+
+			// Create engine
+			tracker := progress.NewTracker()
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
+
+			log.Info("📈 Starting storage statistics",
+				"paths", validPaths,
+				"stored", stored,
+				"compare", compare)
+
+			// Machine readable and piped output keeps stdout clean
+			table := outputFormat == "table" && stdoutIsTerminal()
+			if table && !quiet && !stored {
+				ui.Printf("📈 Scanning %v...\n", validPaths)
+			}
+
+			operationID := engine.NewOperationID(domain.OperationStats)
+
+			progressCtx, progressCancel := context.WithCancel(ctx)
+			defer progressCancel()
+
+			var progressWg sync.WaitGroup
+			if table && !quiet && !stored && cfg.Operations.EnableProgressBar {
+				progressWg.Add(1)
+				go func() {
+					defer progressWg.Done()
+					MonitorProgress(progressCtx, tracker, operationID, "stats")
+				}()
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			result, err := operationEngine.ExecuteOperationWithID(ctx, domain.OperationStats, config, operationID)
+
+			progressCancel()
+			progressWg.Wait()
+
+			if err != nil {
+				if table && !quiet {
+					ui.Printf("\n❌ Storage statistics failed: %v\n", err)
+				}
+				return fmt.Errorf("storage statistics failed: %w", err)
+			}
+
+			stats, _ := result.Details["stats"].(*engine.StorageStats)
+			if stats == nil {
+				return fmt.Errorf("storage statistics returned no report")
+			}
+
+			switch outputFormat {
+			case "json":
+				encoder := json.NewEncoder(ui.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(stats); err != nil {
+					return err
+				}
+			case "html":
+				if err := statsTemplate.Execute(ui.Stdout, stats); err != nil {
+					return err
+				}
+			default:
+				options := tableFlags(cmd)
+				if options.TSV {
+					// Piped, the composition alone, like du
+					if err := compositionTable(stats).render(ui.Stdout, options); err != nil {
+						return err
+					}
+				} else if !quiet {
+					if err := displayStorageStats(stats, options); err != nil {
+						return err
+					}
+					ui.Printf("\n📊 %s\n", result.Summary)
+					DisplayOperationWarnings(result)
+					DisplayOperationErrors(result)
+				}
+			}
+
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	// Add flags
+	cmd.Flags().String("output", "table", "Output format (table, json, html)")
+	cmd.Flags().Bool("stored", false, "Report from stored inventories without scanning")
+	cmd.Flags().Bool("no-save", false, "Do not store the inventory of this scan")
+	cmd.Flags().String("compare", "first", "Measure directory growth since the first or the previous inventory")
+	cmd.Flags().Int("top", 10, "Number of fastest growing directories to show")
+	cmd.Flags().Bool("archives", false, "Also count the files inside zip and tar archives, without extracting them")
+	cmd.Flags().StringSlice("exclude", []string{}, "Patterns to exclude")
+	addTableFlags(cmd, compositionColumns...)
+
+	return cmd
+}
+
+// compositionColumns are the columns of the storage composition
+var compositionColumns = []tableColumn{
+	textColumn("category"),
+	numberColumn("files"),
+	sizeColumn("size"),
+	percentColumn("share"),
+	numberColumn("archived"),
+	sizeColumn("uncompressed"),
+}
+
+// compositionTable lists the storage of every category in the latest inventory
+func compositionTable(stats *engine.StorageStats) *table {
+	latest := stats.Snapshots[len(stats.Snapshots)-1]
+	composition := newTable(compositionColumns...)
+	for _, usage := range stats.Categories {
+		share := 0.0
+		if latest.Bytes > 0 {
+			share = float64(usage.Bytes) / float64(latest.Bytes)
+		}
+		composition.addRow(usage.Category, usage.Files, usage.Bytes, share, usage.ArchivedFiles, usage.ArchivedBytes)
+	}
+	return composition
+}
+
+// displayStorageStats prints the statistics as tables; options shape the
+// composition table
+func displayStorageStats(stats *engine.StorageStats, options tableOptions) error {
+	ui.Printf("\n🕒 Inventories:\n")
+	inventories := newTable(timeColumn("scanned"), numberColumn("files"), sizeColumn("size"),
+		sizeColumn("duplicates"), percentColumn("ratio"))
+	for _, snapshot := range stats.Snapshots {
+		inventories.addRow(snapshot.Time, snapshot.Files, snapshot.Bytes, snapshot.DuplicateBytes, snapshot.DuplicateRatio)
+	}
+	if err := inventories.render(ui.Stdout, tableOptions{Indent: options.Indent, NoTrunc: options.NoTrunc}); err != nil {
+		return err
+	}
+
+	latest := stats.Snapshots[len(stats.Snapshots)-1]
+	if len(stats.Categories) > 0 {
+		if len(options.Columns) == 0 && latest.Archives == 0 {
+			options.Columns = []string{"category", "files", "size", "share"}
+		}
+		ui.Printf("\n🗂️  Composition:\n")
+		if err := compositionTable(stats).render(ui.Stdout, options); err != nil {
+			return err
+		}
+		if latest.Archives > 0 {
+			ui.Printf("  📦 %d files (%s uncompressed) inside %d archives\n", latest.ArchivedFiles, FormatBytes(latest.ArchivedBytes), latest.Archives)
+		}
+	}
+	if latest.Offloaded > 0 {
+		ui.Printf("  🧊 %d files (%s) offloaded, stub files left in their place\n", latest.Offloaded, FormatBytes(latest.OffloadedBytes))
+	}
+
+	if len(stats.Growth) > 0 {
+		ui.Printf("\n📈 Largest growth since %s:\n", stats.Since.Format("2006-01-02 15:04"))
+		growth := newTable(pathColumn("directory"), sizeColumn("before"), sizeColumn("after"), sizeColumn("growth"))
+		for _, directory := range stats.Growth {
+			growth.addRow(directory.Path, directory.Before, directory.After, directory.Delta)
+		}
+		if err := growth.render(ui.Stdout, tableOptions{Indent: options.Indent, NoTrunc: options.NoTrunc}); err != nil {
+			return err
+		}
+	} else if len(stats.Snapshots) > 1 {
+		ui.Printf("\n📈 No directory grew since %s\n", stats.Since.Format("2006-01-02 15:04"))
+	}
+	return nil
+}
+
+// statsTemplate renders the statistics as a standalone HTML page
+var statsTemplate = template.Must(template.New("stats").Funcs(template.FuncMap{
+	"bytes":   FormatBytes,
+	"percent": func(ratio float64) string { return fmt.Sprintf("%.1f%%", ratio*100) },
+	"share": func(part, total int64) string {
+		if total == 0 {
+			return "0%"
+		}
+		return fmt.Sprintf("%.1f%%", float64(part)/float64(total)*100)
+	},
+	"date":   func(t time.Time) string { return t.Format("2006-01-02 15:04") },
+	"latest": func(snapshots []engine.SnapshotStats) engine.SnapshotStats { return snapshots[len(snapshots)-1] },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>fileops stats</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { padding: 4px 12px; text-align: right; border-bottom: 1px solid #ddd; }
+th:first-child, td:first-child { text-align: left; }
+.bar { background: #4a90d9; height: 10px; }
+</style>
+</head>
+<body>
+<h1>Storage statistics</h1>
+<p>{{range $i, $root := .Roots}}{{if $i}}, {{end}}{{$root}}{{end}}</p>
+{{$latest := latest .Snapshots}}
+<h2>Inventories</h2>
+<table>
+<tr><th>Scanned</th><th>Files</th><th>Size</th><th>Duplicates</th><th>Ratio</th></tr>
+{{range .Snapshots}}<tr><td>{{date .Time}}</td><td>{{.Files}}</td><td>{{bytes .Bytes}}</td><td>{{bytes .DuplicateBytes}}</td><td>{{percent .DuplicateRatio}}</td></tr>
+{{end}}</table>
+<h2>Composition</h2>
+<table>
+<tr><th>Category</th><th>Files</th><th>Size</th><th>Share</th><th></th>{{if $latest.Archives}}<th>In archives</th><th>Uncompressed</th>{{end}}</tr>
+{{range .Categories}}<tr><td>{{.Category}}</td><td>{{.Files}}</td><td>{{bytes .Bytes}}</td><td>{{share .Bytes $latest.Bytes}}</td><td style="width:200px"><div class="bar" style="width:{{share .Bytes $latest.Bytes}}"></div></td>{{if $latest.Archives}}<td>{{.ArchivedFiles}}</td><td>{{bytes .ArchivedBytes}}</td>{{end}}</tr>
+{{end}}</table>
+{{if $latest.Archives}}<p>{{$latest.ArchivedFiles}} files ({{bytes $latest.ArchivedBytes}} uncompressed) inside {{$latest.Archives}} archives</p>
+{{end}}
+{{if .Growth}}<h2>Largest growth since {{date .Since}}</h2>
+<table>
+<tr><th>Directory</th><th>Before</th><th>After</th><th>Growth</th></tr>
+{{range .Growth}}<tr><td>{{.Path}}</td><td>{{bytes .Before}}</td><td>{{bytes .After}}</td><td>+{{bytes .Delta}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/metadata"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// NewStripMetadataCommand creates the strip-metadata command
+func NewStripMetadataCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "strip-metadata [path...]",
+		Short: "Remove privacy-sensitive metadata before sharing files",
+		Long: `Remove metadata that can identify people or places from images and documents.
+
+Supported formats:
+  JPEG, PNG       EXIF (including GPS location, camera serials, authors), XMP,
+                  IPTC, comments and PNG text chunks
+  PDF             document information (author, title, producer, dates) and XMP
+  DOCX/XLSX/PPTX  core, extended and custom document properties
+
+Fields listed with --keep are preserved (default: Orientation, ColorSpace and
+ICC, which affect how images are displayed). Use --dry-run to preview which
+fields would be removed. File modification times and permissions are preserved.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get flags
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			keep, _ := cmd.Flags().GetStringSlice("keep")
+			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+
+			// Validate paths
+			validPaths := make([]string, 0, len(args))
+			for _, path := range args {
+				absPath, err := filepath.Abs(path)
+				if err != nil {
+					return fmt.Errorf("invalid path %s: %w", path, err)
+				}
+				if _, err := os.Stat(absPath); os.IsNotExist(err) {
+					return fmt.Errorf("path does not exist: %s", absPath)
+				}
+				validPaths = append(validPaths, absPath)
+			}
+
+			// Create operation configuration
+			config := domain.OperationConfig{
+				DryRun:          dryRun,
+				Recursive:       true,
+				ExcludePatterns: excludePatterns,
+				TargetPaths:     validPaths,
+				CustomSettings: map[string]interface{}{
+					"keep": keep,
+				},
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+
+			// Create engine
+			tracker := progress.NewTracker()
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
+
+			// Get quiet flag from root command
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			log.Info("🕵️ Starting metadata stripping",
+				"paths", validPaths,
+				"keep", strings.Join(keep, ","),
+				"dry_run", dryRun)
+
+			// Show initial status
+			if !quiet {
+				ui.Printf("🔍 Scanning for images and documents...\n")
+				if dryRun {
+					ui.Printf("📋 DRY RUN MODE: No files will be modified\n")
+				}
+				ui.Printf("📂 Paths to process: %v\n", validPaths)
+				ui.Printf("✋ Keeping: %s\n\n", strings.Join(keep, ", "))
+			}
+
+			// Estimate large runs and ask before changing anything
+			if !confirmLargeOperation(cmd, cfg, operationEngine, domain.OperationStripMetadata, config) {
+				ui.Printf("Nothing was changed\n")
+				return nil
+			}
+
+			// Pre-generate operation ID for progress monitoring
+			operationID := engine.NewOperationID(domain.OperationStripMetadata)
+
+			// Start progress monitoring in a separate goroutine BEFORE starting operation
+			progressCtx, progressCancel := context.WithCancel(ctx)
+			defer progressCancel()
+
+			var progressWg sync.WaitGroup
+			if !quiet && cfg.Operations.EnableProgressBar {
+				progressWg.Add(1)
+				go func() {
+					defer progressWg.Done()
+					MonitorProgress(progressCtx, tracker, operationID, "strip_metadata")
+				}()
+				// Give the monitor a moment to start
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			// Execute operation with predefined ID so progress monitoring works
+			result, err := operationEngine.ExecuteOperationWithID(ctx, domain.OperationStripMetadata, config, operationID)
+
+			// Stop progress monitoring
+			progressCancel()
+			progressWg.Wait()
+
+			if err != nil {
+				if !quiet {
+					ui.Printf("\n❌ Metadata stripping failed: %v\n", err)
+					displayStoredResultHint(cfg, operationID)
+				}
+				return fmt.Errorf("metadata stripping failed: %w", err)
+			}
+
+			// Display results
+			if !quiet {
+				ui.Printf("\n\n✅ Metadata stripping completed!\n")
+				if result.Summary != "" {
+					ui.Printf("📊 %s\n", result.Summary)
+				}
+				ui.Printf("⏱️  Total time: %v\n", result.Duration.Round(time.Millisecond))
+
+				if reports, ok := result.Details["reports"].([]*metadata.Report); ok && len(reports) > 0 {
+					ui.Printf("\n🧾 Files with metadata (%d total):\n", len(reports))
+					for i, report := range reports {
+						if i >= 20 {
+							ui.Printf("  ... and %d more files\n", len(reports)-20)
+							break
+						}
+						prefix := "✓ Stripped"
+						if dryRun {
+							prefix = "[DRY RUN] Would strip"
+						}
+						ui.Printf("  %s %s\n      %s\n", prefix, report.Path, strings.Join(report.Removed, ", "))
+					}
+				}
+
+				DisplayOperationWarnings(result)
+				DisplayOperationErrors(result)
+			}
+
+			log.Info("✅ Metadata stripping completed", "summary", result.Summary)
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	// Add flags
+	cmd.Flags().Bool("dry-run", false, "Preview which metadata would be removed without modifying files")
+	cmd.Flags().StringSlice("keep", metadata.DefaultKeep, "Metadata fields to keep (e.g. Orientation,Copyright,GPS,Title)")
+	cmd.Flags().StringSlice("exclude", []string{".git", "node_modules"}, "Patterns to exclude")
+
+	return cmd
+}
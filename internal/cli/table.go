@@ -0,0 +1,401 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// minColumnWidth is the narrowest a column is truncated to
+const minColumnWidth = 8
+
+// tableColumn is a column of a table. Cells hold raw values (numbers, sizes
+// in bytes, times), which sort by value and are written unformatted as TSV.
+type tableColumn struct {
+	name   string                   // Header, and the name --columns and --sort use
+	right  bool                     // Right-aligned, for numbers
+	wide   bool                     // Truncated first when the table is too wide, for paths
+	fixed  bool                     // Never truncated, for IDs and times
+	format func(interface{}) string // Renders a cell for the terminal
+}
+
+// textColumn is a column of text
+func textColumn(name string) tableColumn {
+	return tableColumn{name: name}
+}
+
+// idColumn is a column of identifiers, which are never shortened
+func idColumn(name string) tableColumn {
+	return tableColumn{name: name, fixed: true}
+}
+
+// pathColumn is a column of paths, shortened in the middle when the table
+// does not fit the terminal
+func pathColumn(name string) tableColumn {
+	return tableColumn{name: name, wide: true}
+}
+
+// numberColumn is a right-aligned column of counts
+func numberColumn(name string) tableColumn {
+	return tableColumn{name: name, right: true}
+}
+
+// sizeColumn is a column of sizes in bytes, shown like 1.5 MB
+func sizeColumn(name string) tableColumn {
+	return tableColumn{name: name, right: true, format: func(value interface{}) string {
+		size, _ := value.(int64)
+		return FormatBytes(size)
+	}}
+}
+
+// percentColumn is a column of ratios, shown like 12.5%
+func percentColumn(name string) tableColumn {
+	return tableColumn{name: name, right: true, format: func(value interface{}) string {
+		ratio, _ := value.(float64)
+		return fmt.Sprintf("%.1f%%", ratio*100)
+	}}
+}
+
+// timeColumn is a column of times, shown to the minute
+func timeColumn(name string) tableColumn {
+	return tableColumn{name: name, fixed: true, format: func(value interface{}) string {
+		t, _ := value.(time.Time)
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format("2006-01-02 15:04")
+	}}
+}
+
+// table collects rows and renders them aligned on a terminal or as TSV
+type table struct {
+	columns []tableColumn
+	rows    [][]interface{}
+}
+
+// tableOptions select, order and lay out the rows of a table
+type tableOptions struct {
+	Columns []string // Columns to show, in this order; all when empty
+	Sort    string   // Column to sort by, descending with a leading '-'
+	NoTrunc bool     // Never shorten cells to fit the terminal
+	TSV     bool     // Tab-separated values with raw cells, for scripts
+	Indent  string   // Prefix of every line on the terminal
+	Limit   int      // Rows shown on the terminal (0 = all); TSV has all
+}
+
+// newTable creates a table with the given columns
+func newTable(columns ...tableColumn) *table {
+	return &table{columns: columns}
+}
+
+// addRow appends a row, one value per column
+func (t *table) addRow(values ...interface{}) {
+	t.rows = append(t.rows, values)
+}
+
+// Len returns the number of rows
+func (t *table) Len() int {
+	return len(t.rows)
+}
+
+// addTableFlags adds the flags that shape table output
+func addTableFlags(cmd *cobra.Command, columns ...tableColumn) {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.name
+	}
+	cmd.Flags().StringSlice("columns", nil, "Columns to show, in order (of "+strings.Join(names, ", ")+")")
+	cmd.Flags().String("sort", "", "Column to sort by; prefix with - to sort descending (e.g. -size)")
+	cmd.Flags().Bool("no-trunc", false, "Do not shorten cells to fit the terminal")
+}
+
+// tableFlags reads the table flags. Output that is not a terminal is written
+// as TSV, so it can be piped into cut, sort or awk.
+func tableFlags(cmd *cobra.Command) tableOptions {
+	options := tableOptions{Indent: "  ", TSV: !stdoutIsTerminal()}
+	options.Columns, _ = cmd.Flags().GetStringSlice("columns")
+	options.Sort, _ = cmd.Flags().GetString("sort")
+	options.NoTrunc, _ = cmd.Flags().GetBool("no-trunc")
+	return options
+}
+
+// render writes the table to w
+func (t *table) render(w io.Writer, options tableOptions) error {
+	columns, err := t.selectColumns(options.Columns)
+	if err != nil {
+		return err
+	}
+	if err := t.sortRows(options.Sort); err != nil {
+		return err
+	}
+
+	if options.TSV {
+		return t.renderTSV(w, columns)
+	}
+
+	rows := t.rows
+	if options.Limit > 0 && len(rows) > options.Limit {
+		rows = rows[:options.Limit]
+	}
+	cells := make([][]string, len(rows)+1)
+	cells[0] = make([]string, len(columns))
+	for i, c := range columns {
+		cells[0][i] = strings.ToUpper(t.columns[c].name)
+	}
+	for r, row := range rows {
+		cells[r+1] = make([]string, len(columns))
+		for i, c := range columns {
+			cells[r+1][i] = t.columns[c].cell(row[c])
+		}
+	}
+
+	widths := make([]int, len(columns))
+	for _, row := range cells {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+	if !options.NoTrunc {
+		t.fit(widths, columns, terminalWidth()-utf8.RuneCountInString(options.Indent))
+	}
+
+	var line strings.Builder
+	for _, row := range cells {
+		line.Reset()
+		line.WriteString(options.Indent)
+		for i, cell := range row {
+			column := t.columns[columns[i]]
+			cell = truncateCell(cell, widths[i], column.wide)
+			pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+			switch {
+			case column.right:
+				line.WriteString(pad + cell)
+			case i < len(row)-1:
+				line.WriteString(cell + pad)
+			default:
+				line.WriteString(cell)
+			}
+			if i < len(row)-1 {
+				line.WriteString("  ")
+			}
+		}
+		if _, err := fmt.Fprintln(w, line.String()); err != nil {
+			return err
+		}
+	}
+	if len(rows) < len(t.rows) {
+		_, err := fmt.Fprintf(w, "%s... and %d more (pipe the output for all rows)\n", options.Indent, len(t.rows)-len(rows))
+		return err
+	}
+	return nil
+}
+
+// renderTSV writes a header of column names and the raw cells
+func (t *table) renderTSV(w io.Writer, columns []int) error {
+	fields := make([]string, len(columns))
+	for i, c := range columns {
+		fields[i] = t.columns[c].name
+	}
+	if _, err := fmt.Fprintln(w, strings.Join(fields, "\t")); err != nil {
+		return err
+	}
+	for _, row := range t.rows {
+		for i, c := range columns {
+			fields[i] = rawCell(row[c])
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(fields, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selectColumns returns the indexes of the named columns, or of all
+func (t *table) selectColumns(names []string) ([]int, error) {
+	if len(names) == 0 {
+		all := make([]int, len(t.columns))
+		for i := range all {
+			all[i] = i
+		}
+		return all, nil
+	}
+	selected := make([]int, 0, len(names))
+	for _, name := range names {
+		c := t.columnIndex(strings.TrimSpace(name))
+		if c < 0 {
+			return nil, fmt.Errorf("unknown column %q (use %s)", name, t.columnNames())
+		}
+		selected = append(selected, c)
+	}
+	return selected, nil
+}
+
+// sortRows sorts the rows by a column, stably so equal rows keep their order
+func (t *table) sortRows(by string) error {
+	if by == "" {
+		return nil
+	}
+	descending := strings.HasPrefix(by, "-")
+	c := t.columnIndex(strings.TrimPrefix(by, "-"))
+	if c < 0 {
+		return fmt.Errorf("unknown sort column %q (use %s)", by, t.columnNames())
+	}
+	sort.SliceStable(t.rows, func(i, j int) bool {
+		if descending {
+			return lessCell(t.rows[j][c], t.rows[i][c])
+		}
+		return lessCell(t.rows[i][c], t.rows[j][c])
+	})
+	return nil
+}
+
+// fit narrows the widths until the table fits available characters,
+// shortening the widest column by a character at a time: wide columns first,
+// then other text columns. Numbers and fixed columns are never shortened.
+func (t *table) fit(widths []int, columns []int, available int) {
+	if available <= 0 {
+		return
+	}
+	total := 2 * (len(widths) - 1)
+	for _, width := range widths {
+		total += width
+	}
+	for _, wideOnly := range []bool{true, false} {
+		for total > available {
+			widest := -1
+			for i, width := range widths {
+				column := t.columns[columns[i]]
+				if column.right || column.fixed || (wideOnly && !column.wide) || width <= minColumnWidth {
+					continue
+				}
+				if widest < 0 || width > widths[widest] {
+					widest = i
+				}
+			}
+			if widest < 0 {
+				break
+			}
+			widths[widest]--
+			total--
+		}
+	}
+}
+
+// columnIndex returns the index of the named column, or -1
+func (t *table) columnIndex(name string) int {
+	for i, column := range t.columns {
+		if strings.EqualFold(column.name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// columnNames lists the column names for error messages
+func (t *table) columnNames() string {
+	names := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		names[i] = column.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// cell renders a value for the terminal
+func (c tableColumn) cell(value interface{}) string {
+	if c.format != nil {
+		return c.format(value)
+	}
+	return rawCell(value)
+}
+
+// rawCell renders a value for TSV. Tabs and newlines would break the row.
+func rawCell(value interface{}) string {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		text = v
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []string:
+		text = strings.Join(v, ",")
+	default:
+		text = fmt.Sprint(v)
+	}
+	return strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace(text)
+}
+
+// lessCell orders two values of a column: numbers and times by value,
+// anything else by its text
+func lessCell(a, b interface{}) bool {
+	switch x := a.(type) {
+	case int:
+		if y, ok := b.(int); ok {
+			return x < y
+		}
+	case int64:
+		if y, ok := b.(int64); ok {
+			return x < y
+		}
+	case float64:
+		if y, ok := b.(float64); ok {
+			return x < y
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return x.Before(y)
+		}
+	}
+	return rawCell(a) < rawCell(b)
+}
+
+// truncateCell shortens a cell to width characters, paths in the middle so
+// that both the root and the file name stay visible
+func truncateCell(cell string, width int, middle bool) string {
+	runes := []rune(cell)
+	if len(runes) <= width {
+		return cell
+	}
+	ellipsis := ui.Apply("…")
+	cut := width - utf8.RuneCountInString(ellipsis)
+	if cut < 1 {
+		return string(runes[:width])
+	}
+	if !middle {
+		return string(runes[:cut]) + ellipsis
+	}
+	head := cut / 2
+	tail := cut - head
+	return string(runes[:head]) + ellipsis + string(runes[len(runes)-tail:])
+}
+
+// stdoutIsTerminal reports whether output goes to a terminal rather than to
+// a pipe or a file
+func stdoutIsTerminal() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// terminalWidth returns the width of the terminal on standard output,
+// $COLUMNS when it cannot be asked, or 0 when unknown
+func terminalWidth() int {
+	if width := terminalColumns(); width > 0 {
+		return width
+	}
+	width, _ := strconv.Atoi(os.Getenv("COLUMNS"))
+	return width
+}
@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package cli
+
+// terminalColumns cannot ask the terminal for its width on this platform;
+// tables fit $COLUMNS instead
+func terminalColumns() int {
+	return 0
+}
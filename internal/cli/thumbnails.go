@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// NewThumbnailsCommand creates the thumbnails command
+func NewThumbnailsCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "thumbnails [path...]",
+		Short: "Generate a thumbnail cache for images and videos",
+		Long: `Generate preview thumbnails for all images and videos under the given paths.
+
+Thumbnails are written as JPEG files into the cache directory, named after a
+hash of the source path. Runs are incremental: a thumbnail is only rendered
+again when the modification time of its source changed. Corrupt or unreadable
+files are reported and skipped without stopping the run.
+
+JPEG, PNG and GIF images are rendered natively. Videos and other image formats
+(WebP, HEIC, TIFF, BMP) are rendered through ffmpeg when it is installed.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get flags
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			cacheDir, _ := cmd.Flags().GetString("cache-dir")
+			size, _ := cmd.Flags().GetInt("size")
+			quality, _ := cmd.Flags().GetInt("quality")
+			force, _ := cmd.Flags().GetBool("force")
+			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+			parallelism, _ := cmd.Flags().GetInt("parallelism")
+
+			if cacheDir == "" {
+				cacheDir = cfg.Thumbnails.CacheDirectory
+			}
+			absCacheDir, err := filepath.Abs(cacheDir)
+			if err != nil {
+				return fmt.Errorf("invalid cache directory %s: %w", cacheDir, err)
+			}
+
+			// Validate paths
+			validPaths := make([]string, 0, len(args))
+			for _, path := range args {
+				absPath, err := filepath.Abs(path)
+				if err != nil {
+					return fmt.Errorf("invalid path %s: %w", path, err)
+				}
+				if _, err := os.Stat(absPath); os.IsNotExist(err) {
+					return fmt.Errorf("path does not exist: %s", absPath)
+				}
+				validPaths = append(validPaths, absPath)
+			}
+
+			// Create operation configuration
+			config := domain.OperationConfig{
+				DryRun:          dryRun,
+				Recursive:       true,
+				ExcludePatterns: excludePatterns,
+				TargetPaths:     validPaths,
+				Parallelism:     parallelism,
+				CustomSettings: map[string]interface{}{
+					"cache_directory": absCacheDir,
+					"size":            size,
+					"quality":         quality,
+					"force":           force,
+				},
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+
+			// Create engine
+			tracker := progress.NewTracker()
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
+
+			// Get quiet flag from root command
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			log.Info("🖼️ Starting thumbnail generation",
+				"paths", validPaths,
+				"cache_directory", absCacheDir,
+				"dry_run", dryRun)
+
+			// Show initial status
+			if !quiet {
+				ui.Printf("🔍 Scanning for images and videos...\n")
+				if dryRun {
+					ui.Printf("📋 DRY RUN MODE: No thumbnails will be written\n")
+				}
+				ui.Printf("📂 Paths to process: %v\n", validPaths)
+				ui.Printf("🗂️  Cache directory: %s\n", absCacheDir)
+				ui.Printf("📐 Size: %dpx, quality %d\n\n", size, quality)
+			}
+
+			// Pre-generate operation ID for progress monitoring
+			operationID := engine.NewOperationID(domain.OperationThumbnail)
+
+			// Start progress monitoring in a separate goroutine BEFORE starting operation
+			progressCtx, progressCancel := context.WithCancel(ctx)
+			defer progressCancel()
+
+			var progressWg sync.WaitGroup
+			if !quiet && cfg.Operations.EnableProgressBar {
+				progressWg.Add(1)
+				go func() {
+					defer progressWg.Done()
+					MonitorProgress(progressCtx, tracker, operationID, "thumbnail")
+				}()
+				// Give the monitor a moment to start
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			// Execute operation with predefined ID so progress monitoring works
+			result, err := operationEngine.ExecuteOperationWithID(ctx, domain.OperationThumbnail, config, operationID)
+
+			// Stop progress monitoring
+			progressCancel()
+			progressWg.Wait()
+
+			if err != nil {
+				if !quiet {
+					ui.Printf("\n❌ Thumbnail operation failed: %v\n", err)
+					displayStoredResultHint(cfg, operationID)
+				}
+				return fmt.Errorf("thumbnail operation failed: %w", err)
+			}
+
+			// Display results
+			if !quiet {
+				ui.Printf("\n\n✅ Thumbnail generation completed!\n")
+				if result.Summary != "" {
+					ui.Printf("📊 %s\n", result.Summary)
+				}
+				ui.Printf("⏱️  Total time: %v\n", result.Duration.Round(time.Millisecond))
+
+				if skipped, ok := result.Details["skipped"].(domain.PathList); ok && skipped.Count > 0 {
+					ui.Printf("\n⚠️  %d files need ffmpeg and were skipped\n", skipped.Count)
+				}
+
+				DisplayOperationWarnings(result)
+				DisplayOperationErrors(result)
+			}
+
+			log.Info("✅ Thumbnail generation completed", "summary", result.Summary)
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	// Add flags
+	cmd.Flags().Bool("dry-run", false, "Report which thumbnails would be generated without writing them")
+	cmd.Flags().String("cache-dir", "", "Thumbnail cache directory (default: thumbnails.cache_directory)")
+	cmd.Flags().Int("size", cfg.Thumbnails.Size, "Longest edge of the thumbnails in pixels")
+	cmd.Flags().Int("quality", cfg.Thumbnails.Quality, "JPEG quality (1-100)")
+	cmd.Flags().Bool("force", false, "Regenerate thumbnails even if they are up to date")
+	cmd.Flags().StringSlice("exclude", []string{".git", "node_modules"}, "Patterns to exclude")
+	cmd.Flags().Int("parallelism", cfg.Performance.MaxWorkers, "Number of parallel workers")
+
+	return cmd
+}
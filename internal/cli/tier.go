@@ -0,0 +1,220 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// tierColumns are the columns of the tier composition
+var tierColumns = []tableColumn{
+	textColumn("tier"),
+	textColumn("category"),
+	numberColumn("files"),
+	sizeColumn("size"),
+}
+
+// NewTierCommand creates the tier command
+func NewTierCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tier <hot-root> --cold <dir>",
+		Short: "Move files untouched for a long time to a slower volume",
+		Long: `Move the files below the hot root that were neither modified nor read for
+--older-than days to the cold tier, a directory on a slower or cheaper
+volume, keeping their paths relative to the hot root. Every file is copied,
+verified by checksum and recorded in ` + engine.TierManifestFile + ` in the cold
+tier before the original is replaced by what --leave asks for:
+
+  symlink  a symlink to the moved file (default); programs keep reading it
+  stub     a small stub file naming where the file went and its checksum
+  none     nothing
+
+"fileops tier restore" moves files back on demand. Both show what each tier
+holds by category afterwards; --dry-run shows it without moving anything:
+
+  fileops tier ~/Projects --cold /mnt/archive/projects --older-than 180 --dry-run
+  fileops tier restore ~/Projects/old-client --cold /mnt/archive/projects`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			olderThan, _ := cmd.Flags().GetInt("older-than")
+			leave, _ := cmd.Flags().GetString("leave")
+			config, err := tierConfig(cmd, cfg, args)
+			if err != nil {
+				return err
+			}
+			config.CustomSettings["older_than"] = olderThan
+			config.CustomSettings["leave"] = leave
+			return runTiering(ctx, cmd, cfg, log, config, false)
+		},
+	}
+
+	cmd.PersistentFlags().String("cold", "", "Directory of the cold tier (required)")
+	cmd.PersistentFlags().Bool("dry-run", false, "Show what would be moved without moving anything")
+	cmd.Flags().Int("older-than", 90, "Days a file must be neither modified nor read to be moved")
+	cmd.Flags().String("leave", engine.TierLeaveSymlink, "What replaces a moved file (symlink, stub, none)")
+	cmd.Flags().StringSlice("exclude", []string{}, "Patterns to exclude")
+
+	cmd.AddCommand(newTierRestoreCommand(ctx, cfg, log))
+
+	return cmd
+}
+
+// newTierRestoreCommand creates the tier restore subcommand
+func newTierRestoreCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore <path...> --cold <dir>",
+		Short: "Move files back from the cold tier",
+		Long: `Move the files of the cold tier that came from the given paths (files or
+directories of the hot tier) back where they were, replacing the symlinks or
+stubs left in their place. Each file is checked against the checksum taken
+when it was moved. Files changed in the hot tier since are not overwritten.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := tierConfig(cmd, cfg, args)
+			if err != nil {
+				return err
+			}
+			config.CustomSettings["restore"] = true
+			return runTiering(ctx, cmd, cfg, log, config, true)
+		},
+	}
+}
+
+// tierConfig builds the configuration shared by tiering and restoring
+func tierConfig(cmd *cobra.Command, cfg *config.Config, args []string) (domain.OperationConfig, error) {
+	cold, _ := cmd.Flags().GetString("cold")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+
+	if cold == "" {
+		return domain.OperationConfig{}, fmt.Errorf("--cold is required")
+	}
+	absCold, err := filepath.Abs(cold)
+	if err != nil {
+		return domain.OperationConfig{}, fmt.Errorf("invalid cold tier %s: %w", cold, err)
+	}
+	paths := make([]string, 0, len(args))
+	for _, path := range args {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return domain.OperationConfig{}, fmt.Errorf("invalid path %s: %w", path, err)
+		}
+		paths = append(paths, absPath)
+	}
+
+	config := domain.OperationConfig{
+		DryRun:          dryRun,
+		Recursive:       true,
+		ExcludePatterns: excludePatterns,
+		TargetPaths:     paths,
+		HashAlgorithm:   cfg.Operations.HashAlgorithm,
+		CustomSettings:  map[string]interface{}{"destination": absCold},
+	}
+	if err := applyGlobalFlags(cmd, &config); err != nil {
+		return domain.OperationConfig{}, err
+	}
+	return config, nil
+}
+
+// runTiering runs a tiering operation and shows its result
+func runTiering(ctx context.Context, cmd *cobra.Command, cfg *config.Config, log *logger.Logger, config domain.OperationConfig, restore bool) error {
+	cold := config.CustomSettings["destination"]
+	quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+	tracker := progress.NewTracker()
+	operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+	if err != nil {
+		return err
+	}
+
+	log.Info("🧊 Starting tiering",
+		"paths", config.TargetPaths,
+		"cold", cold,
+		"restore", restore,
+		"dry_run", config.DryRun)
+
+	if !quiet {
+		if restore {
+			ui.Printf("🔥 Restoring %v from the cold tier %s\n", config.TargetPaths, cold)
+		} else {
+			ui.Printf("🧊 Moving files of %s untouched for %v days to %s\n", config.TargetPaths[0], config.CustomSettings["older_than"], cold)
+		}
+		if config.DryRun {
+			ui.Printf("📋 DRY RUN MODE: No files will be moved\n")
+		}
+		fmt.Fprintln(ui.Stdout)
+	}
+
+	if !confirmLargeOperation(cmd, cfg, operationEngine, domain.OperationTiering, config) {
+		ui.Printf("Nothing was changed\n")
+		return nil
+	}
+
+	operationID := engine.NewOperationID(domain.OperationTiering)
+
+	progressCtx, progressCancel := context.WithCancel(ctx)
+	defer progressCancel()
+
+	var progressWg sync.WaitGroup
+	if !quiet && cfg.Operations.EnableProgressBar {
+		progressWg.Add(1)
+		go func() {
+			defer progressWg.Done()
+			MonitorProgress(progressCtx, tracker, operationID, "tiering")
+		}()
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	result, err := operationEngine.ExecuteOperationWithID(ctx, domain.OperationTiering, config, operationID)
+
+	progressCancel()
+	progressWg.Wait()
+
+	if err != nil {
+		if !quiet {
+			ui.Printf("\n❌ Tiering failed: %v\n", err)
+			displayStoredResultHint(cfg, operationID)
+		}
+		return fmt.Errorf("tiering failed: %w", err)
+	}
+
+	if !quiet {
+		ui.Printf("\n\n📊 %s\n", result.Summary)
+		ui.Printf("⏱️  Total time: %v\n", result.Duration.Round(time.Millisecond))
+		if size, ok := result.Details["moved_size"].(int64); ok && size > 0 {
+			ui.Printf("💾 Size: %s\n", FormatBytes(size))
+		}
+		if tiers, ok := result.Details["tiers"].([]engine.TierUsage); ok {
+			displayTiers(tiers)
+		}
+		displayCostEstimate(result)
+		DisplayOperationWarnings(result)
+		DisplayOperationErrors(result)
+	}
+
+	log.Info("✅ Tiering completed", "summary", result.Summary)
+	return warningPolicy(cmd, result)
+}
+
+// displayTiers shows what each tier holds by category
+func displayTiers(tiers []engine.TierUsage) {
+	ui.Printf("\n🗂️  Tiers:\n")
+	rows := newTable(tierColumns...)
+	for _, tier := range tiers {
+		rows.addRow(tier.Tier, "all", tier.Files, tier.Bytes)
+		for _, category := range tier.Categories {
+			rows.addRow("", category.Category, category.Files, category.Bytes)
+		}
+	}
+	rows.render(ui.Stdout, tableOptions{Indent: "  "})
+}
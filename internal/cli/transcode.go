@@ -0,0 +1,269 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/transcode"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// NewTranscodeCommand creates the transcode command
+func NewTranscodeCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "transcode [path...]",
+		Short: "Transcode old videos through ffmpeg",
+		Long: `Transcode videos matching --formats (default: AVI, WMV, MPEG, FLV, 3GP, VOB,
+DivX) and, with --codec, only those whose video codec is listed (e.g. mpeg4,
+msmpeg4v3, wmv3 as reported by ffprobe). ffmpeg and ffprobe must be installed.
+
+Each video is handed to ffmpeg with --args, templates over {{.Input}},
+{{.Output}}, {{.Name}}, {{.Stem}}, {{.Ext}} and {{.Dir}}. The default converts
+to H.265 video and AAC audio:
+
+  ` + strings.Join(transcode.DefaultArguments, " ") + `
+
+Outputs get the --ext extension and are written next to their source, or below
+--dest keeping their place relative to the path. ffmpeg writes to a temporary
+file first; it is put in place only when its duration matches the source's
+within --tolerance. --replace then removes the source, or moves it to
+--backup-dir:
+
+  fileops transcode ~/Videos --formats avi --codec mpeg4,msmpeg4v3 --replace`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get flags
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			formats, _ := cmd.Flags().GetStringSlice("formats")
+			codecs, _ := cmd.Flags().GetStringSlice("codec")
+			arguments, _ := cmd.Flags().GetString("args")
+			extension, _ := cmd.Flags().GetString("ext")
+			destination, _ := cmd.Flags().GetString("dest")
+			replace, _ := cmd.Flags().GetBool("replace")
+			backupDir, _ := cmd.Flags().GetString("backup-dir")
+			tolerance, _ := cmd.Flags().GetDuration("tolerance")
+			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+
+			// Validate paths
+			validPaths := make([]string, 0, len(args))
+			for _, path := range args {
+				absPath, err := filepath.Abs(path)
+				if err != nil {
+					return fmt.Errorf("invalid path %s: %w", path, err)
+				}
+				if _, err := os.Stat(absPath); os.IsNotExist(err) {
+					return fmt.Errorf("path does not exist: %s", absPath)
+				}
+				validPaths = append(validPaths, absPath)
+			}
+			if destination != "" {
+				absDestination, err := filepath.Abs(destination)
+				if err != nil {
+					return fmt.Errorf("invalid destination %s: %w", destination, err)
+				}
+				destination = absDestination
+			}
+
+			ffmpegArgs := transcode.DefaultArguments
+			if arguments != "" {
+				ffmpegArgs = splitArguments(arguments)
+			}
+			if _, err := transcode.ParseArguments(ffmpegArgs); err != nil {
+				return fmt.Errorf("invalid --args: %w", err)
+			}
+
+			// Create operation configuration
+			config := domain.OperationConfig{
+				DryRun:             dryRun,
+				Recursive:          true,
+				ExcludePatterns:    excludePatterns,
+				TargetPaths:        validPaths,
+				BackupBeforeDelete: backupDir != "",
+				BackupDirectory:    backupDir,
+				CustomSettings: map[string]interface{}{
+					"formats":     formats,
+					"codecs":      codecs,
+					"arguments":   ffmpegArgs,
+					"extension":   extension,
+					"destination": destination,
+					"replace":     replace,
+					"tolerance":   tolerance.String(),
+				},
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+
+			// Create engine
+			tracker := progress.NewTracker()
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
+
+			// Get quiet flag from root command
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			log.Info("🎬 Starting transcoding",
+				"paths", validPaths,
+				"formats", strings.Join(formats, ","),
+				"replace", replace,
+				"dry_run", dryRun)
+
+			// Show initial status
+			if !quiet {
+				ui.Printf("🔍 Scanning for videos...\n")
+				if dryRun {
+					ui.Printf("📋 DRY RUN MODE: No files will be written or removed\n")
+				}
+				ui.Printf("📂 Paths to process: %v\n", validPaths)
+				ui.Printf("🎞️  Formats: %s\n", strings.Join(formats, ", "))
+				if len(codecs) > 0 {
+					ui.Printf("🔬 Codecs: %s\n", strings.Join(codecs, ", "))
+				}
+				ui.Printf("⚙️  ffmpeg %s\n\n", strings.Join(ffmpegArgs, " "))
+			}
+
+			// Estimate large runs and ask before changing anything
+			if !confirmLargeOperation(cmd, cfg, operationEngine, domain.OperationTranscode, config) {
+				ui.Printf("Nothing was changed\n")
+				return nil
+			}
+
+			// Pre-generate operation ID for progress monitoring
+			operationID := engine.NewOperationID(domain.OperationTranscode)
+
+			// Start progress monitoring in a separate goroutine BEFORE starting operation
+			progressCtx, progressCancel := context.WithCancel(ctx)
+			defer progressCancel()
+
+			var progressWg sync.WaitGroup
+			if !quiet && cfg.Operations.EnableProgressBar {
+				progressWg.Add(1)
+				go func() {
+					defer progressWg.Done()
+					MonitorProgress(progressCtx, tracker, operationID, "transcode")
+				}()
+				// Give the monitor a moment to start
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			// Execute operation with predefined ID so progress monitoring works
+			result, err := operationEngine.ExecuteOperationWithID(ctx, domain.OperationTranscode, config, operationID)
+
+			// Stop progress monitoring
+			progressCancel()
+			progressWg.Wait()
+
+			if err != nil {
+				if !quiet {
+					ui.Printf("\n❌ Transcoding failed: %v\n", err)
+					displayStoredResultHint(cfg, operationID)
+				}
+				return fmt.Errorf("transcoding failed: %w", err)
+			}
+
+			// Display results
+			if !quiet {
+				ui.Printf("\n\n✅ Transcoding completed!\n")
+				if result.Summary != "" {
+					ui.Printf("📊 %s\n", result.Summary)
+				}
+				ui.Printf("⏱️  Total time: %v\n", result.Duration.Round(time.Millisecond))
+
+				sourceSize, _ := result.Details["source_size"].(int64)
+				outputSize, _ := result.Details["output_size"].(int64)
+				if dryRun {
+					ui.Printf("💾 Videos to transcode: %s\n", FormatBytes(sourceSize))
+				} else if outputSize > 0 {
+					ui.Printf("💾 %s transcoded to %s\n", FormatBytes(sourceSize), FormatBytes(outputSize))
+				}
+
+				if transcoded, ok := result.Details["transcoded"].(domain.PathList); ok && len(transcoded.Paths) > 0 {
+					prefix := "✓ Transcoded"
+					if dryRun {
+						prefix = "[DRY RUN] Would transcode"
+					}
+					ui.Printf("\n🎬 Videos (%d total):\n", transcoded.Count)
+					for i, path := range transcoded.Paths {
+						if i >= 20 {
+							ui.Printf("  ... and %d more videos\n", transcoded.Count-20)
+							break
+						}
+						ui.Printf("  %s %s\n", prefix, path)
+					}
+				}
+
+				DisplayOperationWarnings(result)
+				DisplayOperationErrors(result)
+			}
+
+			log.Info("✅ Transcoding completed", "summary", result.Summary)
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	// Add flags
+	cmd.Flags().Bool("dry-run", false, "List the videos that would be transcoded without running ffmpeg")
+	cmd.Flags().StringSlice("formats", engine.DefaultTranscodeFormats, "Video extensions to transcode")
+	cmd.Flags().StringSlice("codec", []string{}, "Only transcode videos with these video codecs (ffprobe names, e.g. mpeg4)")
+	cmd.Flags().String("args", "", "ffmpeg arguments with {{.Input}} and {{.Output}} templates (default: H.265/AAC)")
+	cmd.Flags().String("ext", "mp4", "Extension of the transcoded videos")
+	cmd.Flags().String("dest", "", "Directory for the transcoded videos (default: next to their source)")
+	cmd.Flags().Bool("replace", false, "Remove sources once their transcoded video is verified")
+	cmd.Flags().String("backup-dir", "", "With --replace, move sources here instead of deleting them")
+	cmd.Flags().Duration("tolerance", time.Second, "How far the transcoded duration may differ from the source's")
+	cmd.Flags().StringSlice("exclude", []string{".git", "node_modules"}, "Patterns to exclude")
+
+	return cmd
+}
+
+// splitArguments splits a command line at spaces outside {{...}} templates
+// and quotes
+func splitArguments(line string) []string {
+	args := make([]string, 0)
+	var current strings.Builder
+	depth, quote, started := 0, rune(0), false
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+			continue
+		case depth == 0 && (r == '"' || r == '\''):
+			quote, started = r, true
+			continue
+		case strings.HasPrefix(line[i:], "{{"):
+			depth++
+		case strings.HasPrefix(line[i:], "}}") && depth > 0:
+			depth--
+		case depth == 0 && (r == ' ' || r == '\t'):
+			if started {
+				args = append(args, current.String())
+				current.Reset()
+				started = false
+			}
+			continue
+		}
+		current.WriteRune(r)
+		started = true
+	}
+	if started {
+		args = append(args, current.String())
+	}
+	return args
+}
@@ -0,0 +1,324 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// NewTriageCommand creates the triage command
+func NewTriageCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "triage [path]",
+		Short: "Sort a downloads folder into keep, archive and delete buckets",
+		Long: `Triage the entries of a downloads folder by type and age.
+
+Every file and folder at the top level gets a suggested bucket:
+
+  delete   unfinished downloads untouched for a week, installers (.dmg, .exe,
+           .deb, ...) older than --installers-after days and archives that
+           were already extracted into a folder next to them
+  archive  anything not modified for --archive-after days; moved to
+           <archive-dir>/<type>/
+  keep     everything else
+
+The suggestions are listed for review; change buckets by number before the
+actions are applied. Files that changed since they were listed are skipped,
+and with --backup-dir deleted items are moved there instead.
+
+  fileops triage ~/Downloads --archive-after 60
+
+With --plan-out nothing is changed; the deletions and moves are written to a
+plan to review in the browser with previews and apply afterwards:
+
+  fileops triage ~/Downloads --plan-out plan.json
+  fileops review plan.json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get flags
+			archiveDir, _ := cmd.Flags().GetString("archive-dir")
+			archiveAfter, _ := cmd.Flags().GetInt("archive-after")
+			installersAfter, _ := cmd.Flags().GetInt("installers-after")
+			backupDir, _ := cmd.Flags().GetString("backup-dir")
+			yes, _ := cmd.Flags().GetBool("yes")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			planOut, _ := cmd.Flags().GetString("plan-out")
+			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+
+			// Validate path
+			absPath, err := filepath.Abs(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid path %s: %w", args[0], err)
+			}
+			if _, err := os.Stat(absPath); os.IsNotExist(err) {
+				return fmt.Errorf("path does not exist: %s", absPath)
+			}
+			if archiveDir == "" {
+				archiveDir = filepath.Join(absPath, "Archive")
+			}
+			absArchiveDir, err := filepath.Abs(archiveDir)
+			if err != nil {
+				return fmt.Errorf("invalid archive directory %s: %w", archiveDir, err)
+			}
+
+			// Create operation configuration; the first run only plans
+			config := domain.OperationConfig{
+				DryRun:             true,
+				ExcludePatterns:    excludePatterns,
+				TargetPaths:        []string{absPath},
+				BackupBeforeDelete: backupDir != "",
+				BackupDirectory:    backupDir,
+				CustomSettings: map[string]interface{}{
+					"destination":          absArchiveDir,
+					"archive_after_days":   archiveAfter,
+					"installer_after_days": installersAfter,
+				},
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+
+			// Create engine
+			tracker := progress.NewTracker()
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
+
+			// Get quiet flag from root command
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			log.Info("🗂️ Starting triage",
+				"path", absPath,
+				"archive_dir", absArchiveDir,
+				"dry_run", dryRun)
+
+			if !quiet {
+				ui.Printf("🗂️  Triaging %s...\n", absPath)
+				ui.Printf("📦 Archive directory: %s\n", absArchiveDir)
+			}
+
+			planResult, err := operationEngine.ExecuteOperation(ctx, domain.OperationTriage, config)
+			if err != nil {
+				if !quiet {
+					ui.Printf("\n❌ Triage failed: %v\n", err)
+				}
+				return fmt.Errorf("triage failed: %w", err)
+			}
+			plan, _ := planResult.Details["plan"].([]engine.TriageItem)
+			if len(plan) == 0 {
+				if !quiet {
+					ui.Printf("\n📭 Nothing to triage\n")
+				}
+				return nil
+			}
+
+			if !quiet || !yes {
+				displayTriagePlan(plan)
+			}
+			if planOut != "" {
+				reviewPlan := engine.TriageReviewPlan(planResult.ID, absPath, absArchiveDir, plan)
+				if err := reviewPlan.Save(planOut); err != nil {
+					return fmt.Errorf("failed to write plan: %w", err)
+				}
+				if !quiet {
+					ui.Printf("\n📝 %d changes written to %s\n", len(reviewPlan.Changes), planOut)
+					ui.Printf("   Review them with: fileops review %s\n", planOut)
+				}
+				return nil
+			}
+			if dryRun {
+				if !quiet {
+					ui.Printf("\n📊 %s\n", planResult.Summary)
+					DisplayOperationWarnings(planResult)
+					DisplayOperationErrors(planResult)
+				}
+				return warningPolicy(cmd, planResult)
+			}
+			if !yes && !reviewTriagePlan(plan) {
+				ui.Printf("Nothing was changed\n")
+				return nil
+			}
+
+			decisions := make(map[string]string, len(plan))
+			for _, item := range plan {
+				decisions[item.Path] = item.Bucket
+			}
+			config.DryRun = false
+			config.CustomSettings["decisions"] = decisions
+
+			// Pre-generate operation ID for progress monitoring
+			operationID := engine.NewOperationID(domain.OperationTriage)
+
+			// Start progress monitoring in a separate goroutine BEFORE starting operation
+			progressCtx, progressCancel := context.WithCancel(ctx)
+			defer progressCancel()
+
+			var progressWg sync.WaitGroup
+			if !quiet && cfg.Operations.EnableProgressBar {
+				progressWg.Add(1)
+				go func() {
+					defer progressWg.Done()
+					MonitorProgress(progressCtx, tracker, operationID, "triage")
+				}()
+				// Give the monitor a moment to start
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			// Execute operation with predefined ID so progress monitoring works
+			result, err := operationEngine.ExecuteOperationWithID(ctx, domain.OperationTriage, config, operationID)
+
+			// Stop progress monitoring
+			progressCancel()
+			progressWg.Wait()
+
+			if err != nil {
+				if !quiet {
+					ui.Printf("\n❌ Triage failed: %v\n", err)
+					displayStoredResultHint(cfg, operationID)
+				}
+				return fmt.Errorf("triage failed: %w", err)
+			}
+
+			// Display results
+			if !quiet {
+				ui.Printf("\n\n✅ Triage completed!\n")
+				if result.Summary != "" {
+					ui.Printf("📊 %s\n", result.Summary)
+				}
+				ui.Printf("⏱️  Total time: %v\n", result.Duration.Round(time.Millisecond))
+				if freed, ok := result.Details["bytes_freed"].(int64); ok && freed > 0 {
+					ui.Printf("  💾 Space freed: %s\n", FormatBytes(freed))
+				}
+
+				DisplayOperationWarnings(result)
+				DisplayOperationErrors(result)
+			}
+
+			log.Info("✅ Triage completed", "summary", result.Summary)
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	// Add flags
+	cmd.Flags().String("archive-dir", "", "Where archived files are moved (default: <path>/Archive)")
+	cmd.Flags().Int("archive-after", 90, "Days without modification after which files are archived")
+	cmd.Flags().Int("installers-after", 30, "Days after which installers are deleted")
+	cmd.Flags().String("backup-dir", "", "Move deleted items here instead of deleting them")
+	cmd.Flags().BoolP("yes", "y", false, "Apply the suggestions without review")
+	cmd.Flags().Bool("dry-run", false, "Only list the suggestions")
+	cmd.Flags().String("plan-out", "", "Write the suggested deletions and moves to a plan for fileops review instead of applying them")
+	cmd.Flags().StringSlice("exclude", []string{}, "Patterns to exclude")
+
+	return cmd
+}
+
+// triageBuckets are the buckets in display order with their headings
+var triageBuckets = []struct{ name, heading string }{
+	{engine.TriageDelete, "🗑️  Delete"},
+	{engine.TriageArchive, "📦 Archive"},
+	{engine.TriageKeep, "📌 Keep"},
+}
+
+// displayTriagePlan lists the items grouped by bucket, numbered in plan order
+func displayTriagePlan(plan []engine.TriageItem) {
+	for _, bucket := range triageBuckets {
+		var count int
+		var size int64
+		for _, item := range plan {
+			if item.Bucket == bucket.name {
+				count++
+				size += item.Size
+			}
+		}
+		if count == 0 {
+			continue
+		}
+
+		ui.Printf("\n%s (%d, %s):\n", bucket.heading, count, FormatBytes(size))
+		for i, item := range plan {
+			if item.Bucket != bucket.name {
+				continue
+			}
+			name := filepath.Base(item.Path)
+			if item.IsDir {
+				name += "/"
+			}
+			ui.Printf("  %3d. %-40s %10s  %-10s %s\n", i+1, name, FormatBytes(item.Size), item.Category, item.Reason)
+		}
+	}
+}
+
+// reviewTriagePlan lets the user move items between buckets until the plan is
+// accepted. It returns false when the review is aborted.
+func reviewTriagePlan(plan []engine.TriageItem) bool {
+	buckets := map[string]string{"k": engine.TriageKeep, "a": engine.TriageArchive, "d": engine.TriageDelete}
+	for {
+		ui.Fprintf(os.Stderr, "\n❓ Enter to apply, k/a/d <numbers> to keep/archive/delete (e.g. \"k 2 5-7\"), q to quit: ")
+		line, err := readLine()
+		if err != nil {
+			return false
+		}
+		fields := strings.Fields(strings.ToLower(line))
+		if len(fields) == 0 {
+			return true
+		}
+		if fields[0] == "q" {
+			return false
+		}
+
+		bucket, ok := buckets[fields[0]]
+		if !ok || len(fields) < 2 {
+			ui.Fprintf(os.Stderr, "⚠️  Unknown command %q\n", line)
+			continue
+		}
+		indexes, err := parseSelection(fields[1:], len(plan))
+		if err != nil {
+			ui.Fprintf(os.Stderr, "⚠️  %v\n", err)
+			continue
+		}
+		for _, index := range indexes {
+			plan[index].Bucket = bucket
+			plan[index].Reason = "changed in review"
+		}
+		displayTriagePlan(plan)
+	}
+}
+
+// parseSelection turns item numbers and ranges ("3", "5-7") into plan indexes
+func parseSelection(fields []string, count int) ([]int, error) {
+	indexes := make([]int, 0, len(fields))
+	for _, field := range fields {
+		first, last, isRange := strings.Cut(field, "-")
+		from, err := strconv.Atoi(first)
+		if err != nil {
+			return nil, fmt.Errorf("invalid item number %q", field)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(last); err != nil {
+				return nil, fmt.Errorf("invalid item range %q", field)
+			}
+		}
+		if from < 1 || to > count || from > to {
+			return nil, fmt.Errorf("items must be between 1 and %d", count)
+		}
+		for number := from; number <= to; number++ {
+			indexes = append(indexes, number-1)
+		}
+	}
+	return indexes, nil
+}
@@ -0,0 +1,213 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/cloud"
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// NewUploadCommand creates the upload command
+func NewUploadCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "upload [path...] --to [target]",
+		Short: "Upload files to S3, GCS, Azure Blob storage or rclone remotes",
+		Long: `Upload files to cloud object storage.
+
+The target is s3://bucket/prefix, gs://bucket/prefix, az://container/prefix
+or rclone:remote:path. Each path is stored under its base name below the
+prefix. Credentials are read from the environment:
+
+  s3://    AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY (AWS_SESSION_TOKEN, AWS_REGION)
+  gs://    GCS_ACCESS_KEY_ID, GCS_SECRET_ACCESS_KEY (HMAC keys, XML API)
+  az://    AZURE_STORAGE_ACCOUNT and AZURE_STORAGE_KEY or AZURE_STORAGE_SAS_TOKEN
+  rclone:  any remote of the rclone configuration (rclone config), through
+           the rclone command, which handles transfers and retries itself
+
+Files larger than the part size are uploaded in parts, every request is
+verified by the service against its MD5 and transient failures are retried.
+After the upload the stored object is checked against the local size and
+SHA-256, which is kept as object metadata. Objects that already match are
+skipped, so rerunning an interrupted upload only transfers what is missing.
+
+With --remove-source local files are deleted once their object is verified;
+with --stub they are replaced by small stub files naming their object and
+its SHA-256, which stats, dedup, checksum and repair recognize:
+
+  fileops upload ~/archive --to s3://backups/2024 --remove-source
+  fileops upload ~/videos --to rclone:drive:videos --stub`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get flags
+			target, _ := cmd.Flags().GetString("to")
+			endpoint, _ := cmd.Flags().GetString("endpoint")
+			region, _ := cmd.Flags().GetString("region")
+			partSize, _ := cmd.Flags().GetString("part-size")
+			parallelism, _ := cmd.Flags().GetInt("parallelism")
+			rclonePath, _ := cmd.Flags().GetString("rclone-path")
+			noVerify, _ := cmd.Flags().GetBool("no-verify")
+			removeSource, _ := cmd.Flags().GetBool("remove-source")
+			leaveStub, _ := cmd.Flags().GetBool("stub")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+
+			if target == "" {
+				return fmt.Errorf("--to is required")
+			}
+
+			// Validate paths
+			validPaths := make([]string, 0, len(args))
+			for _, path := range args {
+				absPath, err := filepath.Abs(path)
+				if err != nil {
+					return fmt.Errorf("invalid path %s: %w", path, err)
+				}
+				if _, err := os.Stat(absPath); os.IsNotExist(err) {
+					return fmt.Errorf("path does not exist: %s", absPath)
+				}
+				validPaths = append(validPaths, absPath)
+			}
+
+			// Create operation configuration
+			config := domain.OperationConfig{
+				DryRun:          dryRun,
+				Recursive:       true,
+				ExcludePatterns: excludePatterns,
+				TargetPaths:     validPaths,
+				Parallelism:     parallelism,
+				CustomSettings: map[string]interface{}{
+					"target":        target,
+					"endpoint":      endpoint,
+					"region":        region,
+					"part_size":     ParseSize(partSize, cloud.DefaultPartSize),
+					"rclone_path":   rclonePath,
+					"verify":        !noVerify,
+					"remove_source": removeSource,
+					"stub":          leaveStub,
+				},
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+
+			// Create engine
+			tracker := progress.NewTracker()
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
+
+			// Get quiet flag from root command
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			log.Info("☁️ Starting upload",
+				"paths", validPaths,
+				"target", target,
+				"dry_run", dryRun)
+
+			// Show initial status
+			if !quiet {
+				ui.Printf("☁️  Uploading files...\n")
+				if dryRun {
+					ui.Printf("📋 DRY RUN MODE: Nothing will be uploaded\n")
+				}
+				ui.Printf("📂 Paths to upload: %v\n", validPaths)
+				ui.Printf("🎯 Target: %s\n", target)
+				if leaveStub {
+					ui.Printf("🧊 Local files are replaced by stubs after verification\n")
+				} else if removeSource {
+					ui.Printf("🗑️  Local files are removed after verification\n")
+				}
+				fmt.Fprintln(ui.Stdout)
+			}
+
+			// Estimate large runs and ask before changing anything
+			if !confirmLargeOperation(cmd, cfg, operationEngine, domain.OperationUpload, config) {
+				ui.Printf("Nothing was changed\n")
+				return nil
+			}
+
+			// Pre-generate operation ID for progress monitoring
+			operationID := engine.NewOperationID(domain.OperationUpload)
+
+			// Start progress monitoring in a separate goroutine BEFORE starting operation
+			progressCtx, progressCancel := context.WithCancel(ctx)
+			defer progressCancel()
+
+			var progressWg sync.WaitGroup
+			if !quiet && cfg.Operations.EnableProgressBar {
+				progressWg.Add(1)
+				go func() {
+					defer progressWg.Done()
+					MonitorProgress(progressCtx, tracker, operationID, "upload")
+				}()
+				// Give the monitor a moment to start
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			// Execute operation with predefined ID so progress monitoring works
+			result, err := operationEngine.ExecuteOperationWithID(ctx, domain.OperationUpload, config, operationID)
+
+			// Stop progress monitoring
+			progressCancel()
+			progressWg.Wait()
+
+			if err != nil {
+				if !quiet {
+					ui.Printf("\n❌ Upload failed: %v\n", err)
+					displayStoredResultHint(cfg, operationID)
+				}
+				return fmt.Errorf("upload failed: %w", err)
+			}
+
+			// Display results
+			if !quiet {
+				ui.Printf("\n\n✅ Upload completed!\n")
+				if result.Summary != "" {
+					ui.Printf("📊 %s\n", result.Summary)
+				}
+				ui.Printf("⏱️  Total time: %v\n", result.Duration.Round(time.Millisecond))
+				if transferred, ok := result.Details["bytes"].(int64); ok && !dryRun {
+					ui.Printf("  📤 Data transferred: %s\n", FormatBytes(transferred))
+				}
+
+				if dryRun {
+					listFiles(result, "uploaded", "📄 Objects that would be uploaded")
+				}
+				listFiles(result, "normalization_differences", "🔤 Objects stored under another Unicode normalization of their key")
+
+				DisplayOperationWarnings(result)
+				DisplayOperationErrors(result)
+			}
+
+			log.Info("✅ Upload completed", "summary", result.Summary)
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	// Add flags
+	cmd.Flags().String("to", "", "Target: s3://bucket/prefix, gs://bucket/prefix, az://container/prefix or rclone:remote:path (required)")
+	cmd.Flags().String("endpoint", "", "Service endpoint for S3-compatible stores or emulators")
+	cmd.Flags().String("region", "", "Bucket region (S3)")
+	cmd.Flags().String("part-size", "16MB", "Size of the parts of multi-part uploads (at least 5MB)")
+	cmd.Flags().Int("parallelism", 4, "Number of files uploaded in parallel")
+	cmd.Flags().String("rclone-path", "", "rclone binary for rclone: targets (default: looked up on PATH)")
+	cmd.Flags().Bool("no-verify", false, "Do not check stored objects after uploading")
+	cmd.Flags().Bool("remove-source", false, "Remove local files once their upload is verified")
+	cmd.Flags().Bool("stub", false, "Replace local files by stub files once their upload is verified")
+	cmd.Flags().Bool("dry-run", false, "Preview the upload without transferring files")
+	cmd.Flags().StringSlice("exclude", []string{".git", "node_modules"}, "Patterns to exclude")
+
+	return cmd
+}
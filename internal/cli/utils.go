@@ -8,8 +8,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/a4abhishek/fileops/internal/ui"
 	"github.com/a4abhishek/fileops/pkg/domain"
 	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
 )
 
 // ParseSize parses a size string (e.g., "64MB", "1GB") to bytes
@@ -53,6 +55,52 @@ func ParseSize(sizeStr string, defaultSize int64) int64 {
 	return defaultSize
 }
 
+// ParseErrorBudget parses an error budget given as an absolute count ("50")
+// or a percentage of processed items ("5%"). A count of 0 aborts on the
+// first failure; NoErrorBudget is returned as the count when there is none.
+func ParseErrorBudget(budget string) (count int, percent float64, err error) {
+	budget = strings.TrimSpace(budget)
+	if budget == "" {
+		return NoErrorBudget, 0, nil
+	}
+
+	if strings.HasSuffix(budget, "%") {
+		percent, err = strconv.ParseFloat(strings.TrimSuffix(budget, "%"), 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return NoErrorBudget, 0, fmt.Errorf("invalid error budget %q: percentage must be between 0 and 100", budget)
+		}
+		return NoErrorBudget, percent, nil
+	}
+
+	count, err = strconv.Atoi(budget)
+	if err != nil || count < 0 {
+		return NoErrorBudget, 0, fmt.Errorf("invalid error budget %q: expected a count or a percentage like 5%%", budget)
+	}
+	return count, 0, nil
+}
+
+// NoErrorBudget is the count ParseErrorBudget returns when no count is given
+const NoErrorBudget = -1
+
+// ParseRootLabel splits a "label=path" argument such as "nas=/mnt/nas" into
+// its label and path. Arguments without a label, and existing paths that
+// happen to contain "=", are returned as the path with an empty label.
+func ParseRootLabel(arg string) (label, path string) {
+	name, rest, found := strings.Cut(arg, "=")
+	if !found || name == "" || rest == "" {
+		return "", arg
+	}
+	if _, err := os.Stat(arg); err == nil {
+		return "", arg
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return "", arg
+		}
+	}
+	return name, rest
+}
+
 // FormatBytes formats a byte count into a human-readable string
 func FormatBytes(bytes int64) string {
 	const unit = 1024
@@ -82,17 +130,17 @@ func DisplayOperationStart(operation, paths string, dryRun bool, params map[stri
 		icon = "⚙️"
 	}
 
-	fmt.Printf("%s Starting %s...\n", icon, operation)
+	ui.Printf("%s Starting %s...\n", icon, operation)
 	if dryRun {
-		fmt.Printf("📋 DRY RUN MODE: No changes will be made\n")
+		ui.Printf("📋 DRY RUN MODE: No changes will be made\n")
 	}
-	fmt.Printf("📂 Target paths: %s\n", paths)
+	ui.Printf("📂 Target paths: %s\n", paths)
 
 	// Display operation-specific parameters
 	for key, value := range params {
-		fmt.Printf("📊 %s: %v\n", capitalizeFirst(strings.ReplaceAll(key, "_", " ")), value)
+		ui.Printf("📊 %s: %v\n", capitalizeFirst(strings.ReplaceAll(key, "_", " ")), value)
 	}
-	fmt.Println()
+	fmt.Fprintln(ui.Stdout)
 }
 
 // DisplayOperationComplete shows completion summary
@@ -110,77 +158,167 @@ func DisplayOperationComplete(operation string, duration time.Duration, summary
 		icon = "⚙️"
 	}
 
-	fmt.Printf("\n\n%s ✅ %s completed successfully!\n", icon, capitalizeFirst(operation))
+	ui.Printf("\n\n%s ✅ %s completed successfully!\n", icon, capitalizeFirst(operation))
 	if summary != "" {
-		fmt.Printf("📊 %s\n", summary)
+		ui.Printf("📊 %s\n", summary)
 	}
-	fmt.Printf("⏱️  Total time: %v\n", duration.Round(time.Millisecond))
+	ui.Printf("⏱️  Total time: %v\n", duration.Round(time.Millisecond))
 }
 
-// MonitorProgress displays generic real-time progress updates
+// DisplayOperationErrors prints the per-item errors of a result and how to retry them
+func DisplayOperationErrors(result *domain.OperationResult) {
+	if len(result.Errors) == 0 {
+		return
+	}
+
+	recoverable := 0
+	ui.Printf("\n❗ Errors (%d total):\n", len(result.Errors))
+	for i, opErr := range result.Errors {
+		if opErr.Recoverable {
+			recoverable++
+		}
+		if i >= 10 {
+			continue
+		}
+		marker := "✗"
+		if opErr.Recoverable {
+			marker = "↻"
+		}
+		ui.Printf("  %s %s\n", marker, opErr.Error)
+	}
+	if len(result.Errors) > 10 {
+		ui.Printf("  ... and %d more errors\n", len(result.Errors)-10)
+	}
+
+	if recoverable > 0 {
+		ui.Printf("💡 %d recoverable items (↻) can be retried with: fileops retry %s\n", recoverable, result.ID)
+	}
+}
+
+// warningLabels describe each kind of warning in a summary line
+var warningLabels = map[domain.WarningKind]string{
+	domain.WarningPermissionDenied:     "skipped, permission denied",
+	domain.WarningSymlinkNotFollowed:   "linked directories not followed",
+	domain.WarningMetadataNotPreserved: "copied without their permissions",
+	domain.WarningInsufficientSpace:    "destinations low on space",
+	domain.WarningSecurityLabelsLost:   "would lose SELinux contexts or file capabilities",
+}
+
+// DisplayOperationWarnings prints the warnings of a result, counted by kind,
+// and the directories that were slow to read
+func DisplayOperationWarnings(result *domain.OperationResult) {
+	defer displaySlowPaths(result.SlowPaths)
+	if len(result.Warnings) == 0 {
+		return
+	}
+
+	ui.Printf("\n⚠️  Warnings (%d total):\n", len(result.Warnings))
+	counts := make(map[domain.WarningKind]int)
+	kinds := make([]domain.WarningKind, 0)
+	for _, warning := range result.Warnings {
+		if counts[warning.Kind] == 0 {
+			kinds = append(kinds, warning.Kind)
+		}
+		counts[warning.Kind]++
+	}
+	if len(kinds) > 1 || counts[kinds[0]] > 1 {
+		for _, kind := range kinds {
+			label, ok := warningLabels[kind]
+			if !ok {
+				label = string(kind)
+			}
+			label = ui.T(label)
+			ui.Printf("  %d %s\n", counts[kind], label)
+		}
+	}
+	for i, warning := range result.Warnings {
+		if i >= 10 {
+			ui.Printf("  ... and %d more warnings\n", len(result.Warnings)-10)
+			break
+		}
+		ui.Printf("  - %s\n", warning.Message)
+	}
+}
+
+// displaySlowPaths prints the directories the walks waited for longest, to
+// tell a failing disk or a slow network share
+func displaySlowPaths(slow []domain.PathLatency) {
+	if len(slow) == 0 {
+		return
+	}
+	ui.Printf("\n🐢 Slow paths (%d):\n", len(slow))
+	for _, stats := range slow {
+		line := fmt.Sprintf("  %s: %v waiting, %d entries at ~%v each (up to %v)", stats.Path, stats.Total.Round(time.Millisecond),
+			stats.Entries, stats.Mean().Round(time.Millisecond), stats.Max.Round(time.Millisecond))
+		if stats.Errors > 0 {
+			line += fmt.Sprintf(", %d failed", stats.Errors)
+		}
+		ui.Printf("%s\n", line)
+	}
+}
+
+// warningPolicy fails a command whose operation raised warnings when
+// --fail-on-warnings is set
+func warningPolicy(cmd *cobra.Command, result *domain.OperationResult) error {
+	failOnWarnings, _ := cmd.Root().PersistentFlags().GetBool("fail-on-warnings")
+	if !failOnWarnings || len(result.Warnings) == 0 {
+		return nil
+	}
+	return &ExitError{Code: ExitWarnings, Err: fmt.Errorf("operation %s completed with %d warnings", result.ID, len(result.Warnings))}
+}
+
+// MonitorProgress displays generic real-time progress updates as the status
+// line of the terminal, below anything logged while the operation runs
 func MonitorProgress(ctx context.Context, tracker *progress.Tracker, operationID, operationType string) {
 	ticker := time.NewTicker(500 * time.Millisecond)
 	defer ticker.Stop()
-
-	var lastItemsProcessed int64
-	var lastBytesProcessed int64
-	var lastUpdate time.Time
+	// Clear the progress line on exit
+	defer ui.ClearStatus()
 
 	for {
 		select {
 		case <-ctx.Done():
-			// Clear the progress line on exit
-			fmt.Print("\r\033[K")
-			_ = os.Stdout.Sync()
 			return
 		case <-ticker.C:
 			if info := tracker.GetProgress(operationID); info != nil {
 				// Check if operation is completed
 				if info.Status == domain.StatusCompleted || info.Status == domain.StatusFailed {
-					// Clear the progress line and exit
-					fmt.Print("\r\033[K")
-					_ = os.Stdout.Sync()
 					return
 				}
 
-				// Calculate processing speeds
-				var itemsPerSec, bytesPerSec float64
-				if !lastUpdate.IsZero() {
-					duration := time.Since(lastUpdate).Seconds()
-					if duration > 0 {
-						if info.ItemsProcessed > lastItemsProcessed {
-							itemsPerSec = float64(info.ItemsProcessed-lastItemsProcessed) / duration
-						}
-						if info.BytesProcessed > lastBytesProcessed {
-							bytesPerSec = float64(info.BytesProcessed-lastBytesProcessed) / duration
-						}
-					}
-				}
+				// Speeds are averaged by the tracker over a sliding window
+				itemsPerSec, bytesPerSec := float64(info.Speed), float64(info.ByteSpeed)
 
 				// Operation-specific progress display
+				var line string
 				switch operationType {
 				case "cleanup":
-					displayCleanupProgress(info, itemsPerSec)
+					line = cleanupProgress(info, itemsPerSec)
 				case "deduplication":
-					displayDedupProgress(info, itemsPerSec, bytesPerSec)
+					line = dedupProgress(info, itemsPerSec, bytesPerSec)
 				case "ownership":
-					displayOwnershipProgress(info, itemsPerSec)
+					line = ownershipProgress(info, itemsPerSec)
 				default:
-					displayGenericProgress(info, itemsPerSec, bytesPerSec)
+					line = genericProgress(info, itemsPerSec, bytesPerSec)
 				}
-
-				lastItemsProcessed = info.ItemsProcessed
-				lastBytesProcessed = info.BytesProcessed
-				lastUpdate = time.Now()
+				ui.SetStatus(fitStatus(line))
 			}
 		}
 	}
 }
 
-func displayCleanupProgress(info *domain.ProgressInfo, itemsPerSec float64) {
-	// Clear the current line and move cursor to beginning
-	fmt.Print("\r\033[K")
+// fitStatus shortens a progress line to the width of the terminal; a line
+// that wraps could not be cleared before the next one is drawn
+func fitStatus(line string) string {
+	// Icons take two columns
+	if width := terminalWidth(); width > 2 {
+		return truncateCell(line, width-2, false)
+	}
+	return line
+}
 
+func cleanupProgress(info *domain.ProgressInfo, itemsPerSec float64) string {
+	var line strings.Builder
 	stepIcon := "🔄"
 	stepAction := "Processing"
 
@@ -202,30 +340,26 @@ func displayCleanupProgress(info *domain.ProgressInfo, itemsPerSec float64) {
 
 	if info.TotalItems > 0 {
 		percentage := float64(info.ItemsProcessed) / float64(info.TotalItems) * 100
-		fmt.Printf("%s %s: %.1f%% (%d/%d items",
-			stepIcon, stepAction, percentage, info.ItemsProcessed, info.TotalItems)
+		line.WriteString(ui.Sprintf("%s %s: %.1f%% (%d/%d items",
+			stepIcon, stepAction, percentage, info.ItemsProcessed, info.TotalItems))
 	} else {
-		fmt.Printf("%s %s: %d items", stepIcon, stepAction, info.ItemsProcessed)
+		line.WriteString(ui.Sprintf("%s %s: %d items", stepIcon, stepAction, info.ItemsProcessed))
 	}
 
 	if itemsPerSec > 0 {
-		fmt.Printf(", %.0f items/sec", itemsPerSec)
+		line.WriteString(ui.Sprintf(", %.0f items/sec", itemsPerSec))
 	}
 
 	if info.EstimatedETA != nil && *info.EstimatedETA > 0 {
-		fmt.Printf(", ETA: %v", info.EstimatedETA.Round(time.Second))
+		line.WriteString(ui.Sprintf(", ETA: %v", info.EstimatedETA.Round(time.Second)))
 	}
 
-	fmt.Print(")")
-
-	// Force flush the output
-	_ = os.Stdout.Sync()
+	line.WriteString(")")
+	return line.String()
 }
 
-func displayOwnershipProgress(info *domain.ProgressInfo, itemsPerSec float64) {
-	// Clear the current line and move cursor to beginning
-	fmt.Print("\r\033[K")
-
+func ownershipProgress(info *domain.ProgressInfo, itemsPerSec float64) string {
+	var line strings.Builder
 	stepIcon := "👑"
 	stepAction := "Processing"
 
@@ -247,90 +381,93 @@ func displayOwnershipProgress(info *domain.ProgressInfo, itemsPerSec float64) {
 
 	if info.TotalItems > 0 {
 		percentage := float64(info.ItemsProcessed) / float64(info.TotalItems) * 100
-		fmt.Printf("%s %s: %.1f%% (%d/%d items",
-			stepIcon, stepAction, percentage, info.ItemsProcessed, info.TotalItems)
+		line.WriteString(ui.Sprintf("%s %s: %.1f%% (%d/%d items",
+			stepIcon, stepAction, percentage, info.ItemsProcessed, info.TotalItems))
 	} else {
-		fmt.Printf("%s %s: %d items", stepIcon, stepAction, info.ItemsProcessed)
+		line.WriteString(ui.Sprintf("%s %s: %d items", stepIcon, stepAction, info.ItemsProcessed))
 	}
 
 	if itemsPerSec > 0 {
-		fmt.Printf(", %.0f items/sec", itemsPerSec)
+		line.WriteString(ui.Sprintf(", %.0f items/sec", itemsPerSec))
 	}
 
 	if info.EstimatedETA != nil && *info.EstimatedETA > 0 {
-		fmt.Printf(", ETA: %v", info.EstimatedETA.Round(time.Second))
+		line.WriteString(ui.Sprintf(", ETA: %v", info.EstimatedETA.Round(time.Second)))
 	}
 
-	fmt.Print(")")
-
-	// Force flush the output for WSL compatibility
-	_ = os.Stdout.Sync()
+	line.WriteString(")")
+	return line.String()
 }
 
-func displayDedupProgress(info *domain.ProgressInfo, itemsPerSec, bytesPerSec float64) {
+func dedupProgress(info *domain.ProgressInfo, itemsPerSec, bytesPerSec float64) string {
+	var line strings.Builder
 	if info.TotalItems > 0 {
 		percentage := float64(info.ItemsProcessed) / float64(info.TotalItems) * 100
-		fmt.Printf("\r🔍 Scanning: %.1f%% (%d/%d files",
-			percentage, info.ItemsProcessed, info.TotalItems)
+		line.WriteString(ui.Sprintf("🔍 Scanning: %.1f%% (%d/%d files",
+			percentage, info.ItemsProcessed, info.TotalItems))
 	} else {
-		fmt.Printf("\r🔍 Processing: %d files", info.ItemsProcessed)
+		line.WriteString(ui.Sprintf("🔍 Processing: %d files", info.ItemsProcessed))
 	}
 
 	if info.BytesProcessed > 0 {
-		fmt.Printf(", %s processed", FormatBytes(info.BytesProcessed))
+		line.WriteString(ui.Sprintf(", %s processed", FormatBytes(info.BytesProcessed)))
 	}
 
 	if itemsPerSec > 0 {
-		fmt.Printf(", %.0f files/sec", itemsPerSec)
+		line.WriteString(ui.Sprintf(", %.0f files/sec", itemsPerSec))
 	}
 	if bytesPerSec > 0 {
-		fmt.Printf(", %s/sec", FormatBytes(int64(bytesPerSec)))
+		line.WriteString(ui.Sprintf(", %s/sec", FormatBytes(int64(bytesPerSec))))
 	}
 
 	if info.CurrentStep != "" {
-		fmt.Printf(" - %s", info.CurrentStep)
+		line.WriteString(ui.Sprintf(" - %s", info.CurrentStep))
 	}
 
 	if info.EstimatedETA != nil && *info.EstimatedETA > 0 {
-		fmt.Printf(", ETA: %v", info.EstimatedETA.Round(time.Second))
+		line.WriteString(ui.Sprintf(", ETA: %v", info.EstimatedETA.Round(time.Second)))
 	}
 
-	fmt.Print(")")
-
-	// Force flush the output for WSL compatibility
-	_ = os.Stdout.Sync()
+	line.WriteString(")")
+	return line.String()
 }
 
-func displayGenericProgress(info *domain.ProgressInfo, itemsPerSec, bytesPerSec float64) {
+func genericProgress(info *domain.ProgressInfo, itemsPerSec, bytesPerSec float64) string {
+	var line strings.Builder
 	if info.TotalItems > 0 {
 		percentage := float64(info.ItemsProcessed) / float64(info.TotalItems) * 100
-		fmt.Printf("\r⚙️  Progress: %.1f%% (%d/%d items",
-			percentage, info.ItemsProcessed, info.TotalItems)
+		line.WriteString(ui.Sprintf("⚙️  Progress: %.1f%% (%d/%d items",
+			percentage, info.ItemsProcessed, info.TotalItems))
 	} else {
-		fmt.Printf("\r⚙️  Processing: %d items", info.ItemsProcessed)
+		line.WriteString(ui.Sprintf("⚙️  Processing: %d items", info.ItemsProcessed))
 	}
 
 	if info.BytesProcessed > 0 {
-		fmt.Printf(", %s processed", FormatBytes(info.BytesProcessed))
+		line.WriteString(ui.Sprintf(", %s processed", FormatBytes(info.BytesProcessed)))
 	}
 
 	if itemsPerSec > 0 {
-		fmt.Printf(", %.0f items/sec", itemsPerSec)
+		line.WriteString(ui.Sprintf(", %.0f items/sec", itemsPerSec))
 	}
 	if bytesPerSec > 0 {
-		fmt.Printf(", %s/sec", FormatBytes(int64(bytesPerSec)))
+		line.WriteString(ui.Sprintf(", %s/sec", FormatBytes(int64(bytesPerSec))))
 	}
 
 	if info.CurrentStep != "" {
-		fmt.Printf(" - %s", info.CurrentStep)
+		line.WriteString(ui.Sprintf(" - %s", info.CurrentStep))
 	}
 
 	if info.EstimatedETA != nil && *info.EstimatedETA > 0 {
-		fmt.Printf(", ETA: %v", info.EstimatedETA.Round(time.Second))
+		line.WriteString(ui.Sprintf(", ETA: %v", info.EstimatedETA.Round(time.Second)))
 	}
 
-	fmt.Print(")")
+	line.WriteString(")")
+	return line.String()
+}
 
-	// Force flush the output for WSL compatibility
-	_ = os.Stdout.Sync()
+// displayPathListFile points to the file holding all paths of a long list
+func displayPathListFile(list domain.PathList) {
+	if list.File != "" {
+		ui.Printf("  📄 All %d paths: %s\n", list.Count, list.File)
+	}
 }
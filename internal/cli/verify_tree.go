@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// NewVerifyTreeCommand creates the verify-tree command
+func NewVerifyTreeCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify-tree <first> <second>",
+		Short: "Prove two directory trees identical by their Merkle hashes",
+		Long: `Hash both trees in parallel into Merkle trees and compare them: the hash of a
+directory covers the names, kinds and content hashes of everything below it,
+so equal root hashes prove the trees identical, e.g. after a migration or a
+restore. Modification times, permissions and owners are not compared.
+
+When the trees differ, the comparison descends only into directories whose
+hashes differ and lists the first entries that diverge: files with other
+content, entries only in one tree (a missing directory once, not every file
+below it) and entries that are a file in one tree and a directory or link in
+the other. Trees that differ make the command fail.`,
+		Example: `  fileops verify-tree /mnt/old-nas/photos /mnt/new-nas/photos
+  fileops verify-tree --algorithm sha256 ~/Documents /media/backup/Documents`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get flags
+			algorithm, _ := cmd.Flags().GetString("algorithm")
+			parallelism, _ := cmd.Flags().GetInt("parallelism")
+
+			validPaths, err := absolutePaths(args)
+			if err != nil {
+				return err
+			}
+
+			// Create operation configuration
+			config := domain.OperationConfig{
+				DryRun:      true,
+				Recursive:   true,
+				Parallelism: parallelism,
+				TargetPaths: validPaths,
+				CustomSettings: map[string]interface{}{
+					"algorithm": algorithm,
+				},
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+
+			// Create engine
+			tracker := progress.NewTracker()
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
+
+			// Get quiet flag from root command
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			log.Info("🌳 Starting tree verification",
+				"first", validPaths[0],
+				"second", validPaths[1],
+				"algorithm", algorithm)
+
+			// Show initial status
+			if !quiet {
+				ui.Printf("🌳 Verifying trees...\n")
+				ui.Printf("📂 First:  %s\n", validPaths[0])
+				ui.Printf("📂 Second: %s\n", validPaths[1])
+				ui.Printf("🔢 Algorithm: %s\n", algorithm)
+				fmt.Fprintln(ui.Stdout)
+			}
+
+			// Pre-generate operation ID for progress monitoring
+			operationID := engine.NewOperationID(domain.OperationVerifyTree)
+
+			// Start progress monitoring in a separate goroutine BEFORE starting operation
+			progressCtx, progressCancel := context.WithCancel(ctx)
+			defer progressCancel()
+
+			var progressWg sync.WaitGroup
+			if !quiet && cfg.Operations.EnableProgressBar {
+				progressWg.Add(1)
+				go func() {
+					defer progressWg.Done()
+					MonitorProgress(progressCtx, tracker, operationID, "verify-tree")
+				}()
+				// Give the monitor a moment to start
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			// Execute operation with predefined ID so progress monitoring works
+			result, err := operationEngine.ExecuteOperationWithID(ctx, domain.OperationVerifyTree, config, operationID)
+
+			// Stop progress monitoring
+			progressCancel()
+			progressWg.Wait()
+
+			if err != nil {
+				if !quiet {
+					ui.Printf("\n❌ Tree verification failed: %v\n", err)
+					displayStoredResultHint(cfg, operationID)
+				}
+				return fmt.Errorf("tree verification failed: %w", err)
+			}
+
+			equal, _ := result.Details["equal"].(bool)
+			if !quiet {
+				if equal {
+					ui.Printf("\n\n✅ Trees are identical\n")
+				} else {
+					ui.Printf("\n\n❌ Trees differ\n")
+				}
+				if result.Summary != "" {
+					ui.Printf("📊 %s\n", result.Summary)
+				}
+				ui.Printf("⏱️  Total time: %v\n", result.Duration.Round(time.Millisecond))
+
+				listFiles(result, "different", "⚠️  Different content")
+				listFiles(result, "only_in_first", "➖ Only in the first tree")
+				listFiles(result, "only_in_second", "➕ Only in the second tree")
+				listFiles(result, "kind_differs", "🔀 Of another kind in the second tree (file, directory or link)")
+
+				DisplayOperationWarnings(result)
+				DisplayOperationErrors(result)
+			}
+
+			log.Info("✅ Tree verification completed", "summary", result.Summary)
+			if !equal {
+				return fmt.Errorf("%s and %s differ", validPaths[0], validPaths[1])
+			}
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	// Add flags
+	cmd.Flags().String("algorithm", "blake3", "Hash algorithm (md5, sha1, sha256, sha512, blake2b, blake3, xxhash64, crc32)")
+	cmd.Flags().Int("parallelism", cfg.Performance.MaxWorkers, "Number of files hashed at once, across both trees")
+
+	return cmd
+}
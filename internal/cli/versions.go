@@ -0,0 +1,224 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/config"
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// NewVersionsCommand creates the versions command
+func NewVersionsCommand(ctx context.Context, cfg *config.Config, log *logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "versions [path...]",
+		Short: "Find version families of documents and archive old versions",
+		Long: `Find files that are versions of the same document, such as
+report_v1.docx, report_v2.docx, report final.docx and report_final_FINAL.docx.
+
+Names are compared after removing version numbers, words like "final",
+"draft" or "copy", dates and copy numbers; names that differ by a typo are
+grouped as well. Only files in the same folder with the same extension form
+a family, and the newest file by modification time is kept.
+
+Without --archive the families are only reported. With --archive all but the
+newest version of every family are moved to --archive-dir, keeping their
+folder structure:
+
+  fileops versions ~/Documents
+  fileops versions ~/Documents --archive --archive-dir ~/Archive/Versions`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get flags
+			archive, _ := cmd.Flags().GetBool("archive")
+			archiveDir, _ := cmd.Flags().GetString("archive-dir")
+			yes, _ := cmd.Flags().GetBool("yes")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			excludePatterns, _ := cmd.Flags().GetStringSlice("exclude")
+
+			// Validate paths
+			validPaths := make([]string, 0, len(args))
+			for _, path := range args {
+				absPath, err := filepath.Abs(path)
+				if err != nil {
+					return fmt.Errorf("invalid path %s: %w", path, err)
+				}
+				if _, err := os.Stat(absPath); os.IsNotExist(err) {
+					return fmt.Errorf("path does not exist: %s", absPath)
+				}
+				validPaths = append(validPaths, absPath)
+			}
+			if archiveDir == "" {
+				archiveDir = filepath.Join(validPaths[0], "Old Versions")
+			}
+			absArchiveDir, err := filepath.Abs(archiveDir)
+			if err != nil {
+				return fmt.Errorf("invalid archive directory %s: %w", archiveDir, err)
+			}
+
+			// Create operation configuration; the first run only reports
+			config := domain.OperationConfig{
+				DryRun:          true,
+				Recursive:       true,
+				ExcludePatterns: excludePatterns,
+				TargetPaths:     validPaths,
+				CustomSettings: map[string]interface{}{
+					"archive": archive,
+				},
+			}
+			if archive {
+				config.CustomSettings["destination"] = absArchiveDir
+			}
+			if err := applyGlobalFlags(cmd, &config); err != nil {
+				return err
+			}
+
+			// Create engine
+			tracker := progress.NewTracker()
+			operationEngine, err := newOperationEngine(cmd, cfg, log, tracker)
+			if err != nil {
+				return err
+			}
+
+			// Get quiet flag from root command
+			quiet, _ := cmd.Root().PersistentFlags().GetBool("quiet")
+
+			log.Info("📚 Starting version family search",
+				"paths", validPaths,
+				"archive", archive,
+				"dry_run", dryRun)
+
+			if !quiet {
+				ui.Printf("📚 Looking for version families in %v...\n", validPaths)
+				if archive {
+					ui.Printf("📦 Archive directory: %s\n", absArchiveDir)
+				}
+			}
+
+			report, err := operationEngine.ExecuteOperation(ctx, domain.OperationVersions, config)
+			if err != nil {
+				if !quiet {
+					ui.Printf("\n❌ Version search failed: %v\n", err)
+				}
+				return fmt.Errorf("version search failed: %w", err)
+			}
+			families, _ := report.Details["families"].([]engine.VersionFamily)
+			if len(families) == 0 {
+				if !quiet {
+					ui.Printf("\n📭 No version families found\n")
+				}
+				return nil
+			}
+
+			if !quiet || !yes {
+				displayVersionFamilies(families)
+			}
+			if !archive || dryRun {
+				if !quiet {
+					ui.Printf("\n📊 %s\n", report.Summary)
+					DisplayOperationWarnings(report)
+					DisplayOperationErrors(report)
+				}
+				return warningPolicy(cmd, report)
+			}
+			if !yes {
+				oldBytes, _ := report.Details["old_bytes"].(int64)
+				oldVersions, _ := report.Details["old_versions"].(int)
+				ui.Fprintf(os.Stderr, "\n❓ Move %d older versions (%s) to %s? [y/N] ", oldVersions, FormatBytes(oldBytes), absArchiveDir)
+				answer, err := readLine()
+				if err != nil || !strings.EqualFold(strings.TrimSpace(answer), "y") {
+					ui.Printf("Nothing was moved\n")
+					return nil
+				}
+			}
+			config.DryRun = false
+
+			// Pre-generate operation ID for progress monitoring
+			operationID := engine.NewOperationID(domain.OperationVersions)
+
+			// Start progress monitoring in a separate goroutine BEFORE starting operation
+			progressCtx, progressCancel := context.WithCancel(ctx)
+			defer progressCancel()
+
+			var progressWg sync.WaitGroup
+			if !quiet && cfg.Operations.EnableProgressBar {
+				progressWg.Add(1)
+				go func() {
+					defer progressWg.Done()
+					MonitorProgress(progressCtx, tracker, operationID, "versions")
+				}()
+				// Give the monitor a moment to start
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			// Execute operation with predefined ID so progress monitoring works
+			result, err := operationEngine.ExecuteOperationWithID(ctx, domain.OperationVersions, config, operationID)
+
+			// Stop progress monitoring
+			progressCancel()
+			progressWg.Wait()
+
+			if err != nil {
+				if !quiet {
+					ui.Printf("\n❌ Archiving old versions failed: %v\n", err)
+					displayStoredResultHint(cfg, operationID)
+				}
+				return fmt.Errorf("archiving old versions failed: %w", err)
+			}
+
+			// Display results
+			if !quiet {
+				ui.Printf("\n\n✅ Old versions archived!\n")
+				if result.Summary != "" {
+					ui.Printf("📊 %s\n", result.Summary)
+				}
+				ui.Printf("⏱️  Total time: %v\n", result.Duration.Round(time.Millisecond))
+				if moved, ok := result.Details["archived_bytes"].(int64); ok && moved > 0 {
+					ui.Printf("  📦 Moved to the archive: %s\n", FormatBytes(moved))
+				}
+
+				DisplayOperationWarnings(result)
+				DisplayOperationErrors(result)
+			}
+
+			log.Info("✅ Old versions archived", "summary", result.Summary)
+			return warningPolicy(cmd, result)
+		},
+	}
+
+	// Add flags
+	cmd.Flags().Bool("archive", false, "Move all but the newest version of every family to the archive directory")
+	cmd.Flags().String("archive-dir", "", "Where old versions are moved (default: <first path>/Old Versions)")
+	cmd.Flags().BoolP("yes", "y", false, "Archive without asking for confirmation")
+	cmd.Flags().Bool("dry-run", false, "Only list the families")
+	cmd.Flags().StringSlice("exclude", []string{}, "Patterns to exclude")
+
+	return cmd
+}
+
+// displayVersionFamilies lists every family, oldest version first
+func displayVersionFamilies(families []engine.VersionFamily) {
+	for _, family := range families {
+		ui.Printf("\n📄 %s (%d versions in %s)\n", family.Name, len(family.Files), family.Dir)
+		for _, file := range family.Files {
+			marker := "  "
+			if file.Path == family.Keep {
+				marker = "✅"
+			}
+			ui.Printf("  %s %-40s %10s  %s\n", marker, filepath.Base(file.Path), FormatBytes(file.Size), file.ModTime.Format("2006-01-02 15:04"))
+		}
+		if family.Note != "" {
+			ui.Printf("  ⚠️  %s\n", family.Note)
+		}
+	}
+}
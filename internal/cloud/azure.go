@@ -0,0 +1,261 @@
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// azureVersion is the Blob service API version requests are made against
+const azureVersion = "2021-08-06"
+
+// azureSHA256Header stores the SHA-256 of the uploaded file as blob metadata
+const azureSHA256Header = "X-Ms-Meta-Fileops_sha256"
+
+// azureBackend talks to the Azure Blob REST API with a shared key or SAS token
+type azureBackend struct {
+	opts      Options
+	endpoint  *url.URL
+	account   string
+	container string
+	key       []byte
+	sas       url.Values
+}
+
+// newAzureBackend creates a backend for a container of the storage account in
+// AZURE_STORAGE_ACCOUNT, authenticated by AZURE_STORAGE_KEY or AZURE_STORAGE_SAS_TOKEN
+func newAzureBackend(container string, opts Options) (*azureBackend, error) {
+	backend := &azureBackend{opts: opts, account: os.Getenv("AZURE_STORAGE_ACCOUNT"), container: container}
+	if backend.account == "" {
+		return nil, fmt.Errorf("credentials missing: set AZURE_STORAGE_ACCOUNT")
+	}
+
+	if key := os.Getenv("AZURE_STORAGE_KEY"); key != "" {
+		decoded, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AZURE_STORAGE_KEY: %w", err)
+		}
+		backend.key = decoded
+	} else if token := os.Getenv("AZURE_STORAGE_SAS_TOKEN"); token != "" {
+		sas, err := url.ParseQuery(strings.TrimPrefix(token, "?"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid AZURE_STORAGE_SAS_TOKEN: %w", err)
+		}
+		backend.sas = sas
+	} else {
+		return nil, fmt.Errorf("credentials missing: set AZURE_STORAGE_KEY or AZURE_STORAGE_SAS_TOKEN")
+	}
+
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", backend.account)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint %s", endpoint)
+	}
+	backend.endpoint = u
+	return backend, nil
+}
+
+// Upload stores a file with a single Put Blob or, above the part size, as
+// blocks committed with Put Block List
+func (b *azureBackend) Upload(ctx context.Context, key string, file *os.File, sums Sums) error {
+	header := http.Header{}
+	header.Set(azureSHA256Header, hex.EncodeToString(sums.SHA256))
+
+	if sums.Size <= b.opts.PartSize {
+		data, err := readPart(file, 0, sums.Size)
+		if err != nil {
+			return err
+		}
+		header.Set("X-Ms-Blob-Type", "BlockBlob")
+		header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sums.MD5))
+		_, err = b.request(ctx, http.MethodPut, key, nil, header, data)
+		return err
+	}
+
+	blockIDs := make([]string, 0, partCount(sums.Size, b.opts.PartSize))
+	for offset := int64(0); offset < sums.Size; offset += b.opts.PartSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		data, err := readPart(file, offset, min(b.opts.PartSize, sums.Size-offset))
+		if err != nil {
+			return err
+		}
+		// Block IDs must have the same length within a blob
+		blockID := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("fileops-%08d", len(blockIDs))))
+		sum := md5.Sum(data)
+		blockHeader := http.Header{}
+		blockHeader.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+		query := url.Values{"comp": {"block"}, "blockid": {blockID}}
+		if _, err := b.request(ctx, http.MethodPut, key, query, blockHeader, data); err != nil {
+			return fmt.Errorf("block %d: %w", len(blockIDs)+1, err)
+		}
+		blockIDs = append(blockIDs, blockID)
+	}
+
+	// Uncommitted blocks are discarded by the service after a week
+	blockList := struct {
+		XMLName xml.Name `xml:"BlockList"`
+		Latest  []string `xml:"Latest"`
+	}{Latest: blockIDs}
+	body, err := xml.Marshal(blockList)
+	if err != nil {
+		return err
+	}
+	header.Set("X-Ms-Blob-Content-Md5", base64.StdEncoding.EncodeToString(sums.MD5))
+	_, err = b.request(ctx, http.MethodPut, key, url.Values{"comp": {"blocklist"}}, header, body)
+	return err
+}
+
+// Stat returns the size and checksums of a stored blob
+func (b *azureBackend) Stat(ctx context.Context, key string) (*Object, error) {
+	response, err := b.request(ctx, http.MethodHead, key, nil, nil, nil)
+	if err != nil {
+		var responseErr *ResponseError
+		if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+		}
+		return nil, err
+	}
+
+	object := &Object{Key: key}
+	object.Size, _ = strconv.ParseInt(response.header.Get("Content-Length"), 10, 64)
+	object.SHA256, _ = hex.DecodeString(response.header.Get(azureSHA256Header))
+	object.SHA256Claimed = true
+	object.MD5, _ = base64.StdEncoding.DecodeString(response.header.Get("Content-MD5"))
+	return object, nil
+}
+
+// Delete removes a blob
+func (b *azureBackend) Delete(ctx context.Context, key string) error {
+	_, err := b.request(ctx, http.MethodDelete, key, nil, nil, nil)
+	return err
+}
+
+// request sends an authorized request, retrying transient failures
+func (b *azureBackend) request(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) (*response, error) {
+	var result *response
+	err := withRetry(ctx, b.opts.Retry, func() error {
+		req, err := b.newRequest(ctx, method, key, query, header, body)
+		if err != nil {
+			return err
+		}
+		resp, err := b.opts.Client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode >= 300 {
+			// HEAD responses carry the error code only in a header
+			responseErr := parseS3Error(resp.StatusCode, data).(*ResponseError)
+			if responseErr.Code == "" {
+				responseErr.Code = resp.Header.Get("X-Ms-Error-Code")
+			}
+			return responseErr
+		}
+		result = &response{header: resp.Header, body: data}
+		return nil
+	})
+	return result, err
+}
+
+// newRequest builds and authorizes a request
+func (b *azureBackend) newRequest(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) (*http.Request, error) {
+	u := *b.endpoint
+	path := "/" + b.container + "/" + key
+	u.Path = strings.TrimSuffix(b.endpoint.Path, "/") + path
+	u.RawPath = strings.TrimSuffix(b.endpoint.EscapedPath(), "/") + uriEncode(path, false)
+
+	all := url.Values{}
+	for name, values := range query {
+		all[name] = values
+	}
+	for name, values := range b.sas {
+		all[name] = values
+	}
+	u.RawQuery = canonicalQuery(all)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("X-Ms-Date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("X-Ms-Version", azureVersion)
+	if b.key != nil {
+		b.sign(req, query)
+	}
+	return req, nil
+}
+
+// sign adds a Shared Key authorization header to req
+func (b *azureBackend) sign(req *http.Request, query url.Values) {
+	contentLength := ""
+	if req.ContentLength > 0 {
+		contentLength = strconv.FormatInt(req.ContentLength, 10)
+	}
+
+	var msHeaders []string
+	for name := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-ms-") {
+			msHeaders = append(msHeaders, lower+":"+strings.TrimSpace(req.Header.Get(name)))
+		}
+	}
+	sort.Strings(msHeaders)
+
+	resource := "/" + b.account + req.URL.EscapedPath()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		values := append([]string{}, query[name]...)
+		sort.Strings(values)
+		resource += "\n" + strings.ToLower(name) + ":" + strings.Join(values, ",")
+	}
+
+	stringToSign := strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // Date, superseded by x-ms-date
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+		strings.Join(msHeaders, "\n"),
+		resource,
+	}, "\n")
+
+	signature := base64.StdEncoding.EncodeToString(hmacSHA256(b.key, stringToSign))
+	req.Header.Set("Authorization", "SharedKey "+b.account+":"+signature)
+}
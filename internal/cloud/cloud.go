@@ -0,0 +1,253 @@
+package cloud
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// DefaultPartSize is the size of the parts of multi-part uploads
+const DefaultPartSize = 16 * 1024 * 1024
+
+// MinPartSize is the smallest part size S3 accepts for all but the last part
+const MinPartSize = 5 * 1024 * 1024
+
+// ErrNotFound is returned by Stat for objects that do not exist
+var ErrNotFound = errors.New("object not found")
+
+// ErrChecksumMismatch is returned when an uploaded object does not match the local file
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// Sums identifies the content of a file
+type Sums struct {
+	Size   int64
+	MD5    []byte
+	SHA256 []byte
+}
+
+// Object describes a stored object. Hashes are empty when the service does
+// not report them. On S3 and Azure, SHA256 is the metadata fileops sent with
+// the upload, which the service stores without checking it against the
+// content (SHA256Claimed).
+type Object struct {
+	Key           string
+	Size          int64
+	MD5           []byte
+	SHA256        []byte
+	SHA256Claimed bool
+}
+
+// Consistent reports whether the size and all hashes the service reports
+// agree with sums
+func (o *Object) Consistent(sums Sums) bool {
+	if o.Size != sums.Size {
+		return false
+	}
+	if len(o.SHA256) > 0 && string(o.SHA256) != string(sums.SHA256) {
+		return false
+	}
+	return len(o.MD5) == 0 || string(o.MD5) == string(sums.MD5)
+}
+
+// Matches reports whether the object is known to hold the content described
+// by sums, which requires at least one hash
+func (o *Object) Matches(sums Sums) bool {
+	return o.Consistent(sums) && (len(o.SHA256) > 0 || len(o.MD5) > 0)
+}
+
+// Verified reports whether the object matches sums by a hash the service
+// computed from the stored content, rather than one it was told
+func (o *Object) Verified(sums Sums) bool {
+	return o.Consistent(sums) && (len(o.MD5) > 0 || (len(o.SHA256) > 0 && !o.SHA256Claimed))
+}
+
+// Backend uploads files to a bucket or container
+type Backend interface {
+	// Upload stores the content of file under key. Large files are uploaded
+	// in parts, each verified by the service against its MD5.
+	Upload(ctx context.Context, key string, file *os.File, sums Sums) error
+	// Stat returns the stored object, or ErrNotFound
+	Stat(ctx context.Context, key string) (*Object, error)
+	// Delete removes an object
+	Delete(ctx context.Context, key string) error
+}
+
+// Options configures a backend
+type Options struct {
+	Endpoint string // Service endpoint, for S3-compatible stores and emulators
+	Region   string
+	PartSize int64
+	Retry    filesystem.RetryPolicy
+	Client   *http.Client
+
+	RclonePath string // rclone binary; looked up on PATH when empty
+}
+
+// Open returns the backend for a target URL and the key prefix it names.
+// Supported are s3://bucket/prefix, gs://bucket/prefix (through the
+// S3-compatible XML API with HMAC keys), az://container/prefix and
+// rclone:remote:path for any configured rclone remote. Credentials are read
+// from the environment, or from the rclone configuration.
+func Open(target string, opts Options) (Backend, string, error) {
+	if remote, ok := strings.CutPrefix(target, "rclone:"); ok {
+		if !strings.Contains(remote, ":") {
+			return nil, "", fmt.Errorf("invalid target %s: use rclone:remote:path", target)
+		}
+		backend, err := newRcloneBackend(strings.TrimSuffix(remote, "/"), opts)
+		return backend, "", err
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid target %s: %w", target, err)
+	}
+	if u.Host == "" {
+		return nil, "", fmt.Errorf("invalid target %s: bucket or container missing", target)
+	}
+	prefix := strings.Trim(u.Path, "/")
+
+	if opts.PartSize <= 0 {
+		opts.PartSize = DefaultPartSize
+	}
+	if opts.PartSize < MinPartSize {
+		return nil, "", fmt.Errorf("part size must be at least %d bytes", MinPartSize)
+	}
+	if opts.Retry.MaxAttempts <= 0 {
+		opts.Retry = filesystem.RetryPolicy{
+			MaxAttempts:    5,
+			InitialBackoff: 500 * time.Millisecond,
+			MaxBackoff:     30 * time.Second,
+			Multiplier:     2.0,
+		}
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 10 * time.Minute}
+	}
+
+	switch u.Scheme {
+	case "s3":
+		backend, err := newS3Backend(u.Host, opts, "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY")
+		return backend, prefix, err
+	case "gs":
+		if opts.Endpoint == "" {
+			opts.Endpoint = "https://storage.googleapis.com"
+		}
+		if opts.Region == "" {
+			opts.Region = "auto"
+		}
+		backend, err := newS3Backend(u.Host, opts, "GCS_ACCESS_KEY_ID", "GCS_SECRET_ACCESS_KEY")
+		return backend, prefix, err
+	case "az":
+		backend, err := newAzureBackend(u.Host, opts)
+		return backend, prefix, err
+	default:
+		return nil, "", fmt.Errorf("unsupported target %s (use s3://, gs://, az:// or rclone:)", target)
+	}
+}
+
+// Location returns the address of the object stored under key for a target
+// opened with Open, in the form of the target, e.g. s3://bucket/prefix/name
+func Location(target, key string) string {
+	if remote, ok := strings.CutPrefix(target, "rclone:"); ok {
+		return "rclone:" + strings.TrimSuffix(remote, "/") + "/" + key
+	}
+	if u, err := url.Parse(target); err == nil {
+		return u.Scheme + "://" + u.Host + "/" + key
+	}
+	return target + "/" + key
+}
+
+// Checksum computes the sums of a file
+func Checksum(file *os.File) (Sums, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return Sums{}, err
+	}
+	md5Hash := md5.New()
+	sha256Hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(md5Hash, sha256Hash), file)
+	if err != nil {
+		return Sums{}, err
+	}
+	return Sums{Size: size, MD5: md5Hash.Sum(nil), SHA256: sha256Hash.Sum(nil)}, nil
+}
+
+// ResponseError is an error response of the service
+type ResponseError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *ResponseError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// retryable reports whether a failed request is worth repeating
+func retryable(err error) bool {
+	var responseErr *ResponseError
+	if errors.As(err, &responseErr) {
+		return responseErr.StatusCode >= 500 || responseErr.StatusCode == http.StatusTooManyRequests ||
+			responseErr.StatusCode == http.StatusRequestTimeout || responseErr.Code == "RequestTimeout"
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	// Connection resets, timeouts and other transport failures
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// withRetry runs fn until it succeeds, fails permanently or runs out of attempts
+func withRetry(ctx context.Context, policy filesystem.RetryPolicy, fn func() error) error {
+	backoff := policy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !retryable(err) || attempt >= policy.MaxAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = time.Duration(float64(backoff) * policy.Multiplier)
+		if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
+
+// readPart reads the part of a file starting at offset
+func readPart(file *os.File, offset int64, size int64) ([]byte, error) {
+	data := make([]byte, size)
+	n, err := file.ReadAt(data, offset)
+	if err == io.EOF && int64(n) == size {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// partCount returns the number of parts of a file
+func partCount(size, partSize int64) int {
+	if size == 0 {
+		return 1
+	}
+	return int((size + partSize - 1) / partSize)
+}
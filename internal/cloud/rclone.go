@@ -0,0 +1,103 @@
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// ErrRcloneUnavailable is returned for rclone targets when rclone is not installed
+var ErrRcloneUnavailable = errors.New("rclone not found")
+
+// rcloneBackend runs the rclone command line tool, so any provider with an
+// rclone remote can be used. rclone handles authentication, multi-part
+// transfers, retries and checksum verification itself.
+type rcloneBackend struct {
+	path   string
+	remote string // remote:path without the key prefix
+}
+
+// newRcloneBackend creates a backend for an rclone remote such as "gdrive:" or "b2:bucket"
+func newRcloneBackend(remote string, opts Options) (*rcloneBackend, error) {
+	binary := opts.RclonePath
+	if binary == "" {
+		binary = "rclone"
+	}
+	resolved, err := exec.LookPath(binary)
+	if err != nil {
+		return nil, fmt.Errorf("%w: install rclone or set its path", ErrRcloneUnavailable)
+	}
+	return &rcloneBackend{path: resolved, remote: remote}, nil
+}
+
+// object returns the rclone path of a key
+func (b *rcloneBackend) object(key string) string {
+	if strings.HasSuffix(b.remote, ":") {
+		return b.remote + key
+	}
+	return b.remote + "/" + key
+}
+
+// Upload copies a file to the remote with rclone copyto
+func (b *rcloneBackend) Upload(ctx context.Context, key string, file *os.File, sums Sums) error {
+	_, err := b.run(ctx, "copyto", file.Name(), b.object(key))
+	return err
+}
+
+// Stat returns the size and the hashes the remote supports of an object
+func (b *rcloneBackend) Stat(ctx context.Context, key string) (*Object, error) {
+	output, err := b.run(ctx, "lsjson", "--stat", "--hash", "--files-only", b.object(key))
+	if err != nil {
+		return nil, err
+	}
+
+	var entry struct {
+		Name   string            `json:"Name"`
+		Size   int64             `json:"Size"`
+		Hashes map[string]string `json:"Hashes"`
+	}
+	if err := json.Unmarshal(output, &entry); err != nil || entry.Name == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+
+	object := &Object{Key: key, Size: entry.Size}
+	object.MD5, _ = hex.DecodeString(entry.Hashes["md5"])
+	object.SHA256, _ = hex.DecodeString(entry.Hashes["sha256"])
+	return object, nil
+}
+
+// Delete removes an object with rclone deletefile
+func (b *rcloneBackend) Delete(ctx context.Context, key string) error {
+	_, err := b.run(ctx, "deletefile", b.object(key))
+	return err
+}
+
+// run executes an rclone command and returns its standard output. Exit codes
+// 3 and 4 (directory or file not found) become ErrNotFound.
+func (b *rcloneBackend) run(ctx context.Context, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, b.path, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && (exitErr.ExitCode() == 3 || exitErr.ExitCode() == 4) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, path.Base(args[len(args)-1]))
+		}
+		return nil, fmt.Errorf("rclone %s: %w: %s", args[0], err, lastLine(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// lastLine returns the last non-empty line of rclone's log output
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,357 @@
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// s3SHA256Header stores the SHA-256 of the uploaded file as user metadata
+const s3SHA256Header = "X-Amz-Meta-Fileops-Sha256"
+
+// s3Backend talks to the S3 REST API, signing requests with Signature Version 4
+type s3Backend struct {
+	opts      Options
+	endpoint  *url.URL
+	bucket    string
+	pathStyle bool
+	accessKey string
+	secretKey string
+	token     string
+}
+
+// newS3Backend creates an S3 backend with the credentials from the given environment variables
+func newS3Backend(bucket string, opts Options, accessKeyEnv, secretKeyEnv string) (*s3Backend, error) {
+	backend := &s3Backend{
+		opts:      opts,
+		bucket:    bucket,
+		accessKey: os.Getenv(accessKeyEnv),
+		secretKey: os.Getenv(secretKeyEnv),
+	}
+	if backend.accessKey == "" || backend.secretKey == "" {
+		return nil, fmt.Errorf("credentials missing: set %s and %s", accessKeyEnv, secretKeyEnv)
+	}
+	if accessKeyEnv == "AWS_ACCESS_KEY_ID" {
+		backend.token = os.Getenv("AWS_SESSION_TOKEN")
+	}
+
+	if backend.opts.Region == "" {
+		backend.opts.Region = os.Getenv("AWS_REGION")
+	}
+	if backend.opts.Region == "" {
+		backend.opts.Region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if backend.opts.Region == "" {
+		backend.opts.Region = "us-east-1"
+	}
+
+	endpoint := opts.Endpoint
+	backend.pathStyle = endpoint != "" || strings.Contains(bucket, ".")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", backend.opts.Region)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint %s", endpoint)
+	}
+	if !backend.pathStyle {
+		u.Host = bucket + "." + u.Host
+	}
+	backend.endpoint = u
+	return backend, nil
+}
+
+// Upload stores a file with a single PUT or, above the part size, a multi-part upload
+func (b *s3Backend) Upload(ctx context.Context, key string, file *os.File, sums Sums) error {
+	header := http.Header{}
+	header.Set(s3SHA256Header, hex.EncodeToString(sums.SHA256))
+
+	if sums.Size <= b.opts.PartSize {
+		data, err := readPart(file, 0, sums.Size)
+		if err != nil {
+			return err
+		}
+		header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sums.MD5))
+		_, err = b.request(ctx, http.MethodPut, key, nil, header, data)
+		return err
+	}
+
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	response, err := b.request(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, header, nil)
+	if err != nil {
+		return err
+	}
+	if err := xml.Unmarshal(response.body, &initiated); err != nil || initiated.UploadID == "" {
+		return fmt.Errorf("invalid response starting the upload of %s", key)
+	}
+
+	if err := b.uploadParts(ctx, key, initiated.UploadID, file, sums.Size); err != nil {
+		// Abort so the service drops the parts; a failure here only leaves garbage
+		b.request(context.Background(), http.MethodDelete, key, url.Values{"uploadId": {initiated.UploadID}}, nil, nil)
+		return err
+	}
+	return nil
+}
+
+// s3Part is a part of a completed multi-part upload
+type s3Part struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// uploadParts uploads all parts of a file and completes the upload
+func (b *s3Backend) uploadParts(ctx context.Context, key, uploadID string, file *os.File, size int64) error {
+	parts := make([]s3Part, 0, partCount(size, b.opts.PartSize))
+	for number, offset := 1, int64(0); offset < size; number, offset = number+1, offset+b.opts.PartSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		data, err := readPart(file, offset, min(b.opts.PartSize, size-offset))
+		if err != nil {
+			return err
+		}
+		sum := md5.Sum(data)
+		header := http.Header{}
+		header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+		query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+		response, err := b.request(ctx, http.MethodPut, key, query, header, data)
+		if err != nil {
+			return fmt.Errorf("part %d: %w", number, err)
+		}
+		parts = append(parts, s3Part{PartNumber: number, ETag: response.header.Get("ETag")})
+	}
+
+	complete := struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []s3Part `xml:"Part"`
+	}{Parts: parts}
+	body, err := xml.Marshal(complete)
+	if err != nil {
+		return err
+	}
+	response, err := b.request(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, nil, body)
+	if err != nil {
+		return err
+	}
+	// Completion can fail after the status line was sent as 200
+	if bytes.Contains(response.body, []byte("<Error>")) {
+		return parseS3Error(http.StatusInternalServerError, response.body)
+	}
+	return nil
+}
+
+// Stat returns the size and checksums of a stored object
+func (b *s3Backend) Stat(ctx context.Context, key string) (*Object, error) {
+	response, err := b.request(ctx, http.MethodHead, key, nil, nil, nil)
+	if err != nil {
+		var responseErr *ResponseError
+		if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+		}
+		return nil, err
+	}
+
+	object := &Object{Key: key}
+	object.Size, _ = strconv.ParseInt(response.header.Get("Content-Length"), 10, 64)
+	object.SHA256, _ = hex.DecodeString(response.header.Get(s3SHA256Header))
+	object.SHA256Claimed = true
+	// The ETag of single-part uploads is the MD5 unless they are encrypted
+	// with KMS or a customer key
+	encryption := response.header.Get("X-Amz-Server-Side-Encryption")
+	plainETag := !strings.HasPrefix(encryption, "aws:kms") &&
+		response.header.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm") == ""
+	if etag := strings.Trim(response.header.Get("ETag"), `"`); plainETag && len(etag) == 32 {
+		object.MD5, _ = hex.DecodeString(etag)
+	}
+	if goog := response.header.Get("X-Goog-Hash"); goog != "" {
+		object.MD5 = nil
+		for _, hash := range strings.Split(goog, ",") {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(hash), "md5="); ok {
+				object.MD5, _ = base64.StdEncoding.DecodeString(value)
+			}
+		}
+	}
+	return object, nil
+}
+
+// Delete removes an object
+func (b *s3Backend) Delete(ctx context.Context, key string) error {
+	_, err := b.request(ctx, http.MethodDelete, key, nil, nil, nil)
+	return err
+}
+
+// response is a successful response with its body read
+type response struct {
+	header http.Header
+	body   []byte
+}
+
+// request sends a signed request, retrying transient failures
+func (b *s3Backend) request(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) (*response, error) {
+	var result *response
+	err := withRetry(ctx, b.opts.Retry, func() error {
+		req, err := b.newRequest(ctx, method, key, query, header, body)
+		if err != nil {
+			return err
+		}
+		resp, err := b.opts.Client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode >= 300 {
+			return parseS3Error(resp.StatusCode, data)
+		}
+		result = &response{header: resp.Header, body: data}
+		return nil
+	})
+	return result, err
+}
+
+// newRequest builds and signs a request
+func (b *s3Backend) newRequest(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) (*http.Request, error) {
+	u := *b.endpoint
+	path := "/" + key
+	if b.pathStyle {
+		path = "/" + b.bucket + path
+	}
+	u.Path = strings.TrimSuffix(b.endpoint.Path, "/") + path
+	u.RawPath = strings.TrimSuffix(b.endpoint.EscapedPath(), "/") + uriEncode(path, false)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	b.sign(req, body, time.Now().UTC())
+	return req, nil
+}
+
+// sign adds a Signature Version 4 authorization header to req
+func (b *s3Backend) sign(req *http.Request, body []byte, now time.Time) {
+	payloadHash := sha256.Sum256(body)
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	if b.token != "" {
+		req.Header.Set("X-Amz-Security-Token", b.token)
+	}
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		values := req.Header.Values(name)
+		for i := range values {
+			values[i] = strings.Join(strings.Fields(values[i]), " ")
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.Join(values, ",") + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + b.opts.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+b.secretKey), date)
+	signingKey = hmacSHA256(signingKey, b.opts.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		b.accessKey, scope, signedHeaders, signature))
+	req.Header.Del("Host") // Sent from req.Host
+}
+
+// hmacSHA256 computes the HMAC-SHA256 of data
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// uriEncode percent-encodes everything but unreserved characters and, unless
+// encodeSlash is set, slashes
+func uriEncode(s string, encodeSlash bool) string {
+	var encoded strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			encoded.WriteByte(c)
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", c)
+		}
+	}
+	return encoded.String()
+}
+
+// canonicalQuery encodes a query string sorted by name as required for signing
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		for _, value := range query[name] {
+			pairs = append(pairs, uriEncode(name, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// parseS3Error converts an error response body into a ResponseError
+func parseS3Error(statusCode int, body []byte) error {
+	var errorBody struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	xml.Unmarshal(body, &errorBody)
+	return &ResponseError{StatusCode: statusCode, Code: errorBody.Code, Message: errorBody.Message}
+}
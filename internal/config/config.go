@@ -6,32 +6,109 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
+	"github.com/a4abhishek/fileops/internal/content"
+	"github.com/a4abhishek/fileops/internal/snapshot"
+	"github.com/a4abhishek/fileops/internal/ui"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
 	"github.com/spf13/viper"
 )
 
 // Config represents the application configuration
 type Config struct {
-	Performance Performance `mapstructure:"performance"`
-	Operations  Operations  `mapstructure:"operations"`
-	AI          AI          `mapstructure:"ai"`
-	Logging     Logging     `mapstructure:"logging"`
-	Plugins     Plugins     `mapstructure:"plugins"`
+	Performance Performance            `mapstructure:"performance"`
+	Operations  Operations             `mapstructure:"operations"`
+	Retry       Retry                  `mapstructure:"retry"`
+	Thumbnails  Thumbnails             `mapstructure:"thumbnails"`
+	Content     Content                `mapstructure:"content"`
+	OCR         OCR                    `mapstructure:"ocr"`
+	Integrity   Integrity              `mapstructure:"integrity"`
+	Snapshot    Snapshot               `mapstructure:"snapshot"`
+	AI          AI                     `mapstructure:"ai"`
+	Logging     Logging                `mapstructure:"logging"`
+	Output      Output                 `mapstructure:"output"`
+	Plugins     Plugins                `mapstructure:"plugins"`
+	Monitor     Monitor                `mapstructure:"monitor"`
+	Remotes     map[string]string      `mapstructure:"remotes"`  // Agent addresses by name
+	Commands    map[string]interface{} `mapstructure:"commands"` // Flag defaults by command, e.g. dedup.exclude
+	File        string                 `mapstructure:"-"`        // Configuration file read; empty when none was found
 }
 
 type Performance struct {
-	MaxWorkers  int    `mapstructure:"max_workers"`
-	MemoryLimit string `mapstructure:"memory_limit"`
-	ChunkSize   string `mapstructure:"chunk_size"`
-	CacheSize   string `mapstructure:"cache_size"`
+	MaxWorkers        int     `mapstructure:"max_workers"`
+	MemoryLimit       string  `mapstructure:"memory_limit"`
+	ChunkSize         string  `mapstructure:"chunk_size"`
+	CacheSize         string  `mapstructure:"cache_size"`
+	BatchSize         int     `mapstructure:"batch_size"`
+	FsyncInterval     string  `mapstructure:"fsync_interval"`
+	Throttle          bool    `mapstructure:"throttle"`            // Give up workers while the machine is busy
+	ThrottleCPU       float64 `mapstructure:"throttle_cpu"`        // Percent of all CPUs busy above which workers are given up
+	ThrottleDiskQueue int64   `mapstructure:"throttle_disk_queue"` // I/O requests in flight on the busiest disk above which workers are given up
+	BenchProfile      string  `mapstructure:"bench_profile"`       // Throughput measured by bench, for the estimates of dry runs
 }
 
 type Operations struct {
-	HashAlgorithm       string  `mapstructure:"hash_algorithm"`
-	DuplicateThreshold  float64 `mapstructure:"duplicate_threshold"`
-	SimilarityThreshold float64 `mapstructure:"similarity_threshold"`
-	EnableProgressBar   bool    `mapstructure:"enable_progress_bar"`
-	BackupBeforeDelete  bool    `mapstructure:"backup_before_delete"`
+	HashAlgorithm        string   `mapstructure:"hash_algorithm"`
+	DuplicateThreshold   float64  `mapstructure:"duplicate_threshold"`
+	SimilarityThreshold  float64  `mapstructure:"similarity_threshold"`
+	EnableProgressBar    bool     `mapstructure:"enable_progress_bar"`
+	BackupBeforeDelete   bool     `mapstructure:"backup_before_delete"`
+	LockDirectory        string   `mapstructure:"lock_directory"`
+	ResultsDirectory     string   `mapstructure:"results_directory"`
+	JournalDirectory     string   `mapstructure:"journal_directory"`
+	CacheDirectory       string   `mapstructure:"cache_directory"`
+	ProgressDirectory    string   `mapstructure:"progress_directory"`
+	RunsDirectory        string   `mapstructure:"runs_directory"`
+	ConfirmItems         int64    `mapstructure:"confirm_items"`
+	ConfirmSize          string   `mapstructure:"confirm_size"`
+	UnicodeNormalization string   `mapstructure:"unicode_normalization"`
+	MTimeGranularity     string   `mapstructure:"mtime_granularity"`
+	MTimeDSTSkew         bool     `mapstructure:"mtime_dst_skew"`
+	SecurityLabels       bool     `mapstructure:"security_labels"`
+	PolicyFile           string   `mapstructure:"policy_file"`
+	PinFile              string   `mapstructure:"pin_file"`
+	MinConfidence        string   `mapstructure:"min_confidence"`
+	Extensions           []string `mapstructure:"extensions"`
+}
+
+type Retry struct {
+	MaxAttempts    int    `mapstructure:"max_attempts"`
+	InitialBackoff string `mapstructure:"initial_backoff"`
+	MaxBackoff     string `mapstructure:"max_backoff"`
+}
+
+type Thumbnails struct {
+	CacheDirectory string `mapstructure:"cache_directory"`
+	Size           int    `mapstructure:"size"`
+	Quality        int    `mapstructure:"quality"`
+}
+
+type Content struct {
+	IndexDirectory string         `mapstructure:"index_directory"`
+	MaxTextSize    int            `mapstructure:"max_text_size"`
+	Rules          []content.Rule `mapstructure:"rules"`
+}
+
+type OCR struct {
+	Enabled       bool     `mapstructure:"enabled"`
+	Engine        string   `mapstructure:"engine"`
+	Languages     []string `mapstructure:"languages"`
+	TesseractPath string   `mapstructure:"tesseract_path"`
+	MaxPages      int      `mapstructure:"max_pages"`
+	Timeout       string   `mapstructure:"timeout"`
+}
+
+type Integrity struct {
+	Algorithm  string `mapstructure:"algorithm"`
+	Layout     string `mapstructure:"layout"`
+	Redundancy int    `mapstructure:"redundancy"`
+}
+
+type Snapshot struct {
+	Method  string `mapstructure:"method"`
+	Command string `mapstructure:"command"`
 }
 
 type AI struct {
@@ -49,6 +126,24 @@ type Logging struct {
 	Console bool   `mapstructure:"console"`
 }
 
+type Output struct {
+	Theme  string `mapstructure:"theme"`
+	Locale string `mapstructure:"locale"`
+}
+
+type Monitor struct {
+	Interval string          `mapstructure:"interval"`
+	Volumes  []MonitorVolume `mapstructure:"volumes"`
+}
+
+type MonitorVolume struct {
+	Path      string `mapstructure:"path"`
+	Threshold string `mapstructure:"threshold"` // Used share ("90%") or least free space ("20GB")
+	Pipeline  string `mapstructure:"pipeline"`
+	Cooldown  string `mapstructure:"cooldown"`
+	Notify    string `mapstructure:"notify"`
+}
+
 type Plugins struct {
 	Enabled          []string `mapstructure:"enabled"`
 	CustomPluginsDir string   `mapstructure:"custom_plugins_dir"`
@@ -58,17 +153,61 @@ type Plugins struct {
 func defaultConfig() *Config {
 	return &Config{
 		Performance: Performance{
-			MaxWorkers:  0, // Auto-detect
-			MemoryLimit: "80%",
-			ChunkSize:   "64MB",
-			CacheSize:   "1GB",
+			MaxWorkers:        0, // Auto-detect
+			MemoryLimit:       "80%",
+			ChunkSize:         "64MB",
+			CacheSize:         "1GB",
+			BatchSize:         500,
+			FsyncInterval:     "2s",
+			Throttle:          false,
+			ThrottleCPU:       70,
+			ThrottleDiskQueue: 8,
+			BenchProfile:      "~/.fileops/bench.json",
 		},
 		Operations: Operations{
-			HashAlgorithm:       "blake2b",
-			DuplicateThreshold:  0.99,
-			SimilarityThreshold: 0.85,
-			EnableProgressBar:   true,
-			BackupBeforeDelete:  true,
+			HashAlgorithm:        "blake2b",
+			DuplicateThreshold:   0.99,
+			SimilarityThreshold:  0.85,
+			EnableProgressBar:    true,
+			BackupBeforeDelete:   true,
+			LockDirectory:        "~/.fileops/locks",
+			ResultsDirectory:     "~/.fileops/results",
+			JournalDirectory:     "~/.fileops/journal",
+			CacheDirectory:       "~/.fileops/cache",
+			ProgressDirectory:    "~/.fileops/progress",
+			RunsDirectory:        "~/.fileops/runs",
+			ConfirmItems:         100000,
+			ConfirmSize:          "100GB",
+			UnicodeNormalization: "nfc",
+			MTimeGranularity:     "auto",
+			MinConfidence:        "medium",
+		},
+		Retry: Retry{
+			MaxAttempts:    3,
+			InitialBackoff: "100ms",
+			MaxBackoff:     "5s",
+		},
+		Thumbnails: Thumbnails{
+			CacheDirectory: "~/.fileops/thumbnails",
+			Size:           256,
+			Quality:        80,
+		},
+		Content: Content{
+			IndexDirectory: "~/.fileops/index",
+			MaxTextSize:    1024 * 1024,
+			Rules:          content.DefaultRules,
+		},
+		OCR: OCR{
+			Enabled:   false,
+			Engine:    "auto",
+			Languages: []string{"eng"},
+			MaxPages:  10,
+			Timeout:   "2m",
+		},
+		Integrity: Integrity{
+			Algorithm:  "sha256",
+			Layout:     "file",
+			Redundancy: 10,
 		},
 		AI: AI{
 			Enabled:          true,
@@ -83,10 +222,16 @@ func defaultConfig() *Config {
 			Format:  "json",
 			Console: true,
 		},
+		Output: Output{
+			Theme: ui.ThemeEmoji,
+		},
 		Plugins: Plugins{
 			Enabled:          []string{"dedup", "cleanup", "organize"},
 			CustomPluginsDir: "./plugins",
 		},
+		Monitor: Monitor{
+			Interval: "5m",
+		},
 	}
 }
 
@@ -117,6 +262,7 @@ func Load() (*Config, error) {
 		}
 		// Config file not found is okay, we'll use defaults
 	}
+	cfg.File = viper.ConfigFileUsed()
 
 	// Unmarshal into struct
 	if err := viper.Unmarshal(cfg); err != nil {
@@ -137,12 +283,60 @@ func setDefaults(cfg *Config) {
 	viper.SetDefault("performance.memory_limit", cfg.Performance.MemoryLimit)
 	viper.SetDefault("performance.chunk_size", cfg.Performance.ChunkSize)
 	viper.SetDefault("performance.cache_size", cfg.Performance.CacheSize)
+	viper.SetDefault("performance.batch_size", cfg.Performance.BatchSize)
+	viper.SetDefault("performance.fsync_interval", cfg.Performance.FsyncInterval)
+	viper.SetDefault("performance.throttle", cfg.Performance.Throttle)
+	viper.SetDefault("performance.throttle_cpu", cfg.Performance.ThrottleCPU)
+	viper.SetDefault("performance.throttle_disk_queue", cfg.Performance.ThrottleDiskQueue)
+	viper.SetDefault("performance.bench_profile", cfg.Performance.BenchProfile)
 
 	viper.SetDefault("operations.hash_algorithm", cfg.Operations.HashAlgorithm)
 	viper.SetDefault("operations.duplicate_threshold", cfg.Operations.DuplicateThreshold)
 	viper.SetDefault("operations.similarity_threshold", cfg.Operations.SimilarityThreshold)
 	viper.SetDefault("operations.enable_progress_bar", cfg.Operations.EnableProgressBar)
 	viper.SetDefault("operations.backup_before_delete", cfg.Operations.BackupBeforeDelete)
+	viper.SetDefault("operations.lock_directory", cfg.Operations.LockDirectory)
+	viper.SetDefault("operations.results_directory", cfg.Operations.ResultsDirectory)
+	viper.SetDefault("operations.journal_directory", cfg.Operations.JournalDirectory)
+	viper.SetDefault("operations.cache_directory", cfg.Operations.CacheDirectory)
+	viper.SetDefault("operations.progress_directory", cfg.Operations.ProgressDirectory)
+	viper.SetDefault("operations.runs_directory", cfg.Operations.RunsDirectory)
+	viper.SetDefault("operations.confirm_items", cfg.Operations.ConfirmItems)
+	viper.SetDefault("operations.confirm_size", cfg.Operations.ConfirmSize)
+	viper.SetDefault("operations.unicode_normalization", cfg.Operations.UnicodeNormalization)
+	viper.SetDefault("operations.mtime_granularity", cfg.Operations.MTimeGranularity)
+	viper.SetDefault("operations.mtime_dst_skew", cfg.Operations.MTimeDSTSkew)
+	viper.SetDefault("operations.security_labels", cfg.Operations.SecurityLabels)
+	viper.SetDefault("operations.policy_file", cfg.Operations.PolicyFile)
+	viper.SetDefault("operations.pin_file", cfg.Operations.PinFile)
+	viper.SetDefault("operations.min_confidence", cfg.Operations.MinConfidence)
+	viper.SetDefault("operations.extensions", cfg.Operations.Extensions)
+
+	viper.SetDefault("retry.max_attempts", cfg.Retry.MaxAttempts)
+	viper.SetDefault("retry.initial_backoff", cfg.Retry.InitialBackoff)
+	viper.SetDefault("retry.max_backoff", cfg.Retry.MaxBackoff)
+
+	viper.SetDefault("thumbnails.cache_directory", cfg.Thumbnails.CacheDirectory)
+	viper.SetDefault("thumbnails.size", cfg.Thumbnails.Size)
+	viper.SetDefault("thumbnails.quality", cfg.Thumbnails.Quality)
+
+	viper.SetDefault("content.index_directory", cfg.Content.IndexDirectory)
+	viper.SetDefault("content.max_text_size", cfg.Content.MaxTextSize)
+	viper.SetDefault("content.rules", cfg.Content.Rules)
+
+	viper.SetDefault("ocr.enabled", cfg.OCR.Enabled)
+	viper.SetDefault("ocr.engine", cfg.OCR.Engine)
+	viper.SetDefault("ocr.languages", cfg.OCR.Languages)
+	viper.SetDefault("ocr.tesseract_path", cfg.OCR.TesseractPath)
+	viper.SetDefault("ocr.max_pages", cfg.OCR.MaxPages)
+	viper.SetDefault("ocr.timeout", cfg.OCR.Timeout)
+
+	viper.SetDefault("integrity.algorithm", cfg.Integrity.Algorithm)
+	viper.SetDefault("integrity.layout", cfg.Integrity.Layout)
+	viper.SetDefault("integrity.redundancy", cfg.Integrity.Redundancy)
+
+	viper.SetDefault("snapshot.method", cfg.Snapshot.Method)
+	viper.SetDefault("snapshot.command", cfg.Snapshot.Command)
 
 	viper.SetDefault("ai.enabled", cfg.AI.Enabled)
 	viper.SetDefault("ai.model_cache", cfg.AI.ModelCache)
@@ -155,8 +349,14 @@ func setDefaults(cfg *Config) {
 	viper.SetDefault("logging.format", cfg.Logging.Format)
 	viper.SetDefault("logging.console", cfg.Logging.Console)
 
+	viper.SetDefault("output.theme", cfg.Output.Theme)
+	viper.SetDefault("output.locale", cfg.Output.Locale)
+
 	viper.SetDefault("plugins.enabled", cfg.Plugins.Enabled)
 	viper.SetDefault("plugins.custom_plugins_dir", cfg.Plugins.CustomPluginsDir)
+
+	viper.SetDefault("monitor.interval", cfg.Monitor.Interval)
+	viper.SetDefault("monitor.volumes", cfg.Monitor.Volumes)
 }
 
 // postProcess handles post-processing and validation
@@ -173,19 +373,107 @@ func postProcess(cfg *Config) error {
 		}
 	}
 
+	if cfg.Operations.LockDirectory != "" {
+		if expanded, err := expandPath(cfg.Operations.LockDirectory); err == nil {
+			cfg.Operations.LockDirectory = expanded
+		}
+	}
+
+	if cfg.Operations.ResultsDirectory != "" {
+		if expanded, err := expandPath(cfg.Operations.ResultsDirectory); err == nil {
+			cfg.Operations.ResultsDirectory = expanded
+		}
+	}
+
+	if cfg.Operations.JournalDirectory != "" {
+		if expanded, err := expandPath(cfg.Operations.JournalDirectory); err == nil {
+			cfg.Operations.JournalDirectory = expanded
+		}
+	}
+
+	if cfg.Operations.CacheDirectory != "" {
+		if expanded, err := expandPath(cfg.Operations.CacheDirectory); err == nil {
+			cfg.Operations.CacheDirectory = expanded
+		}
+	}
+
+	if cfg.Operations.ProgressDirectory != "" {
+		if expanded, err := expandPath(cfg.Operations.ProgressDirectory); err == nil {
+			cfg.Operations.ProgressDirectory = expanded
+		}
+	}
+
+	if cfg.Operations.RunsDirectory != "" {
+		if expanded, err := expandPath(cfg.Operations.RunsDirectory); err == nil {
+			cfg.Operations.RunsDirectory = expanded
+		}
+	}
+
+	if cfg.Performance.BenchProfile != "" {
+		if expanded, err := expandPath(cfg.Performance.BenchProfile); err == nil {
+			cfg.Performance.BenchProfile = expanded
+		}
+	}
+
+	if cfg.Operations.PolicyFile != "" {
+		if expanded, err := expandPath(cfg.Operations.PolicyFile); err == nil {
+			cfg.Operations.PolicyFile = expanded
+		}
+	}
+	if cfg.Operations.PinFile != "" {
+		if expanded, err := expandPath(cfg.Operations.PinFile); err == nil {
+			cfg.Operations.PinFile = expanded
+		}
+	}
+
+	if cfg.Thumbnails.CacheDirectory != "" {
+		if expanded, err := expandPath(cfg.Thumbnails.CacheDirectory); err == nil {
+			cfg.Thumbnails.CacheDirectory = expanded
+		}
+	}
+
+	if cfg.Content.IndexDirectory != "" {
+		if expanded, err := expandPath(cfg.Content.IndexDirectory); err == nil {
+			cfg.Content.IndexDirectory = expanded
+		}
+	}
+
 	if cfg.Plugins.CustomPluginsDir != "" {
 		if expanded, err := expandPath(cfg.Plugins.CustomPluginsDir); err == nil {
 			cfg.Plugins.CustomPluginsDir = expanded
 		}
 	}
 
+	for i := range cfg.Monitor.Volumes {
+		volume := &cfg.Monitor.Volumes[i]
+		for _, path := range []*string{&volume.Path, &volume.Pipeline} {
+			if expanded, err := expandPath(*path); err == nil {
+				*path = expanded
+			}
+		}
+	}
+
 	// Validate hash algorithm
-	validHashAlgorithms := []string{"blake2b", "sha256", "xxhash64", "crc32"}
+	validHashAlgorithms := []string{"blake2b", "blake3", "sha256", "xxhash64", "crc32"}
 	if !contains(validHashAlgorithms, cfg.Operations.HashAlgorithm) {
 		return fmt.Errorf("invalid hash algorithm: %s, must be one of %v",
 			cfg.Operations.HashAlgorithm, validHashAlgorithms)
 	}
 
+	if !filesystem.ValidNormalization(cfg.Operations.UnicodeNormalization) {
+		return fmt.Errorf("invalid unicode_normalization: %s, must be one of nfc, nfd, none", cfg.Operations.UnicodeNormalization)
+	}
+
+	if _, err := domain.ParseConfidenceLevel(cfg.Operations.MinConfidence); err != nil {
+		return fmt.Errorf("invalid min_confidence: %w", err)
+	}
+
+	if cfg.Operations.MTimeGranularity != "auto" {
+		if granularity, err := time.ParseDuration(cfg.Operations.MTimeGranularity); err != nil || granularity < 0 {
+			return fmt.Errorf("invalid mtime_granularity %q, must be auto or a duration such as 2s", cfg.Operations.MTimeGranularity)
+		}
+	}
+
 	// Validate thresholds
 	if cfg.Operations.DuplicateThreshold < 0.0 || cfg.Operations.DuplicateThreshold > 1.0 {
 		return fmt.Errorf("duplicate_threshold must be between 0.0 and 1.0")
@@ -195,6 +483,101 @@ func postProcess(cfg *Config) error {
 		return fmt.Errorf("similarity_threshold must be between 0.0 and 1.0")
 	}
 
+	// Validate retry settings
+	if cfg.Performance.BatchSize < 1 {
+		return fmt.Errorf("performance.batch_size must be at least 1")
+	}
+	if interval, err := time.ParseDuration(cfg.Performance.FsyncInterval); err != nil || interval < 0 {
+		return fmt.Errorf("invalid performance.fsync_interval %q, must be a duration such as 2s (0 = by batch size only)", cfg.Performance.FsyncInterval)
+	}
+	if cfg.Performance.ThrottleCPU < 0 || cfg.Performance.ThrottleCPU > 100 {
+		return fmt.Errorf("performance.throttle_cpu must be between 0 and 100")
+	}
+	if cfg.Performance.ThrottleDiskQueue < 0 {
+		return fmt.Errorf("performance.throttle_disk_queue cannot be negative")
+	}
+
+	if cfg.Retry.MaxAttempts < 1 {
+		return fmt.Errorf("retry.max_attempts must be at least 1")
+	}
+	for _, value := range []string{cfg.Retry.InitialBackoff, cfg.Retry.MaxBackoff} {
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("invalid retry backoff %q: %w", value, err)
+		}
+	}
+
+	// Validate thumbnail settings
+	if cfg.Thumbnails.Size < 16 {
+		return fmt.Errorf("thumbnails.size must be at least 16 pixels")
+	}
+	if cfg.Thumbnails.Quality < 1 || cfg.Thumbnails.Quality > 100 {
+		return fmt.Errorf("thumbnails.quality must be between 1 and 100")
+	}
+
+	// Validate content settings
+	if cfg.Content.MaxTextSize < 1024 {
+		return fmt.Errorf("content.max_text_size must be at least 1024 bytes")
+	}
+	for i, rule := range cfg.Content.Rules {
+		if rule.Category == "" || len(rule.Keywords) == 0 {
+			return fmt.Errorf("content.rules[%d] needs a category and at least one keyword", i)
+		}
+	}
+
+	// Validate OCR settings
+	validOCREngines := []string{"auto", "tesseract", "service"}
+	if !contains(validOCREngines, cfg.OCR.Engine) {
+		return fmt.Errorf("invalid OCR engine: %s, must be one of %v", cfg.OCR.Engine, validOCREngines)
+	}
+	if cfg.OCR.MaxPages < 1 {
+		return fmt.Errorf("ocr.max_pages must be at least 1")
+	}
+	if _, err := time.ParseDuration(cfg.OCR.Timeout); err != nil {
+		return fmt.Errorf("invalid ocr.timeout %q: %w", cfg.OCR.Timeout, err)
+	}
+
+	// Validate integrity settings
+	if _, err := filesystem.NewHasher(cfg.Integrity.Algorithm); err != nil {
+		return fmt.Errorf("invalid integrity.algorithm: %w", err)
+	}
+	validLayouts := []string{"file", "directory"}
+	if !contains(validLayouts, cfg.Integrity.Layout) {
+		return fmt.Errorf("invalid integrity.layout: %s, must be one of %v", cfg.Integrity.Layout, validLayouts)
+	}
+	if cfg.Integrity.Redundancy < 1 || cfg.Integrity.Redundancy > 100 {
+		return fmt.Errorf("integrity.redundancy must be between 1 and 100 percent")
+	}
+
+	// Validate output settings
+	if !ui.ValidTheme(cfg.Output.Theme) {
+		return fmt.Errorf("invalid output.theme: %s, must be one of %s, %s, %s", cfg.Output.Theme, ui.ThemeEmoji, ui.ThemeASCII, ui.ThemeMinimal)
+	}
+
+	// Validate per-extension actions
+	if _, err := domain.ParseExtensions(cfg.Operations.Extensions); err != nil {
+		return fmt.Errorf("invalid operations.extensions: %w", err)
+	}
+
+	// Validate snapshot settings
+	if _, err := snapshot.New(cfg.Snapshot.Method, cfg.Snapshot.Command); err != nil {
+		return fmt.Errorf("invalid snapshot settings: %w", err)
+	}
+
+	// Validate monitor settings
+	if interval, err := time.ParseDuration(cfg.Monitor.Interval); err != nil || interval <= 0 {
+		return fmt.Errorf("invalid monitor.interval %q, must be a duration such as 5m", cfg.Monitor.Interval)
+	}
+	for i, volume := range cfg.Monitor.Volumes {
+		if volume.Path == "" || volume.Threshold == "" || volume.Pipeline == "" {
+			return fmt.Errorf("monitor.volumes[%d] needs a path, a threshold and a pipeline", i)
+		}
+		if volume.Cooldown != "" {
+			if _, err := time.ParseDuration(volume.Cooldown); err != nil {
+				return fmt.Errorf("invalid monitor.volumes[%d].cooldown %q: %w", i, volume.Cooldown, err)
+			}
+		}
+	}
+
 	// Validate log level
 	validLogLevels := []string{"debug", "info", "warn", "error", "fatal"}
 	if !contains(validLogLevels, strings.ToLower(cfg.Logging.Level)) {
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// File returns the configuration file in use, or ~/.fileops/config.yaml
+// when no configuration file was found
+func File() (string, error) {
+	if used := viper.ConfigFileUsed(); used != "" {
+		return used, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".fileops", "config.yaml"), nil
+}
+
+// Save sets settings given by their dotted section.key names (e.g.
+// "performance.chunk_size") in the YAML file at path. Values are replaced where
+// they stand, so the layout and comments of the file are kept; missing settings
+// are added to their section. The file is created when it does not exist.
+func Save(path string, values map[string]interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	text := string(data)
+	for _, key := range keys {
+		section, name, ok := strings.Cut(key, ".")
+		if !ok || strings.Contains(name, ".") {
+			return fmt.Errorf("cannot set %s: settings are named section.key", key)
+		}
+		text, err = setValue(text, section, name, values[key])
+		if err != nil {
+			return fmt.Errorf("cannot set %s in %s: %w", key, path, err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return filesystem.WriteFileAtomic(path, []byte(text), 0o644)
+}
+
+// setValue returns text with section.name set to value
+func setValue(text, section, name string, value interface{}) (string, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &document); err != nil {
+		return "", err
+	}
+	lines := strings.Split(text, "\n")
+
+	var root *yaml.Node
+	if len(document.Content) > 0 {
+		root = document.Content[0]
+		if root.Kind != yaml.MappingNode {
+			return "", fmt.Errorf("the file is not a mapping")
+		}
+	}
+
+	sectionKey, sectionValue := lookup(root, section)
+	if sectionValue == nil {
+		if len(lines) > 0 && lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, section+":", fmt.Sprintf("  %s: %s", name, formatValue(value, yaml.Style(0))), "")
+		return strings.Join(lines, "\n"), nil
+	}
+	if sectionValue.Kind != yaml.MappingNode {
+		return "", fmt.Errorf("%s is not a section", section)
+	}
+
+	key, current := lookup(sectionValue, name)
+	if current == nil {
+		// Add the setting below the last line of the section
+		indent := strings.Repeat(" ", sectionKey.Column+1)
+		if len(sectionValue.Content) > 0 {
+			indent = strings.Repeat(" ", sectionValue.Content[0].Column-1)
+		}
+		last := lastLine(sectionValue)
+		line := fmt.Sprintf("%s%s: %s", indent, name, formatValue(value, yaml.Style(0)))
+		lines = append(lines[:last], append([]string{line}, lines[last:]...)...)
+		return strings.Join(lines, "\n"), nil
+	}
+	if current.Kind != yaml.ScalarNode || current.Line != key.Line {
+		return "", fmt.Errorf("%s.%s is not a single-line value", section, name)
+	}
+
+	// Replace the value token, keeping what precedes and follows it
+	line := lines[current.Line-1]
+	start := current.Column - 1
+	end := start + tokenLength(line[start:], current.Style)
+	formatted, rest := formatValue(value, current.Style), line[end:]
+
+	// Keep a trailing comment in its column
+	if gap := len(rest) - len(strings.TrimLeft(rest, " ")); gap > 0 && strings.HasPrefix(strings.TrimLeft(rest, " "), "#") {
+		gap += (end - start) - len(formatted)
+		rest = strings.Repeat(" ", max(gap, 1)) + strings.TrimLeft(rest, " ")
+	}
+	lines[current.Line-1] = line[:start] + formatted + rest
+	return strings.Join(lines, "\n"), nil
+}
+
+// lookup returns the key and value nodes of name in a mapping
+func lookup(mapping *yaml.Node, name string) (*yaml.Node, *yaml.Node) {
+	if mapping == nil {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == name {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// lastLine returns the last line number taken by a node and its children
+func lastLine(node *yaml.Node) int {
+	last := node.Line
+	for _, child := range node.Content {
+		last = max(last, lastLine(child))
+	}
+	return last
+}
+
+// tokenLength returns the length of the scalar written at the start of s
+func tokenLength(s string, style yaml.Style) int {
+	switch {
+	case style&yaml.DoubleQuotedStyle != 0:
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\\' {
+				i++
+			} else if s[i] == '"' {
+				return i + 1
+			}
+		}
+	case style&yaml.SingleQuotedStyle != 0:
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\'' {
+				if i+1 < len(s) && s[i+1] == '\'' {
+					i++
+					continue
+				}
+				return i + 1
+			}
+		}
+	default:
+		if comment := strings.Index(s, " #"); comment >= 0 {
+			s = s[:comment]
+		}
+		return len(strings.TrimRight(s, " \t"))
+	}
+	return len(s)
+}
+
+// formatValue writes value as a YAML scalar, quoting strings when the value it
+// replaces was quoted
+func formatValue(value interface{}, style yaml.Style) string {
+	if s, ok := value.(string); ok && style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+		return strconv.Quote(s)
+	}
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,104 @@
+package content
+
+import (
+	"strings"
+)
+
+// Rule assigns a category to documents containing its keywords
+type Rule struct {
+	Category   string   `mapstructure:"category" yaml:"category" json:"category"`
+	Keywords   []string `mapstructure:"keywords" yaml:"keywords" json:"keywords"`
+	MinMatches int      `mapstructure:"min_matches" yaml:"min_matches" json:"min_matches"` // Distinct keywords required (default 1)
+}
+
+// DefaultRules classify common household and business paperwork
+var DefaultRules = []Rule{
+	{Category: "Invoices", Keywords: []string{"invoice", "invoice number", "bill to", "amount due", "due date", "vat", "tax invoice"}, MinMatches: 2},
+	{Category: "Receipts", Keywords: []string{"receipt", "thank you for your purchase", "subtotal", "cash", "change due", "paid"}, MinMatches: 2},
+	{Category: "Contracts", Keywords: []string{"agreement", "contract", "hereinafter", "party", "parties", "terms and conditions", "signature", "witness"}, MinMatches: 3},
+	{Category: "Statements", Keywords: []string{"statement", "account number", "opening balance", "closing balance", "transactions"}, MinMatches: 2},
+}
+
+// Classification is the outcome of matching a document against the rules
+type Classification struct {
+	Category   string   `json:"category"`
+	Matched    []string `json:"matched"`    // Keywords found in the document
+	Confidence float64  `json:"confidence"` // Fraction of the rule's keywords that matched
+}
+
+// Classifier matches document text against keyword rules
+type Classifier struct {
+	rules []Rule
+}
+
+// NewClassifier creates a classifier; rules earlier in the list win ties
+func NewClassifier(rules []Rule) *Classifier {
+	if len(rules) == 0 {
+		rules = DefaultRules
+	}
+	return &Classifier{rules: rules}
+}
+
+// Classify returns the best matching category for text, or nil if no rule applies
+func (c *Classifier) Classify(text string) *Classification {
+	lower := strings.ToLower(text)
+
+	var best *Classification
+	for _, rule := range c.rules {
+		if rule.Category == "" || len(rule.Keywords) == 0 {
+			continue
+		}
+		minMatches := rule.MinMatches
+		if minMatches <= 0 {
+			minMatches = 1
+		}
+
+		matched := make([]string, 0)
+		for _, keyword := range rule.Keywords {
+			if containsWord(lower, strings.ToLower(keyword)) {
+				matched = append(matched, keyword)
+			}
+		}
+		if len(matched) < minMatches {
+			continue
+		}
+
+		confidence := float64(len(matched)) / float64(len(rule.Keywords))
+		if best == nil || confidence > best.Confidence {
+			best = &Classification{Category: rule.Category, Matched: matched, Confidence: confidence}
+		}
+	}
+	return best
+}
+
+// containsWord reports whether phrase occurs in text on word boundaries
+func containsWord(text, phrase string) bool {
+	if phrase == "" {
+		return false
+	}
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], phrase)
+		if i < 0 {
+			return false
+		}
+		start := offset + i
+		end := start + len(phrase)
+		if (start == 0 || !isWordByte(text[start-1])) && (end == len(text) || !isWordByte(text[end])) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+// isWordByte reports whether b is part of a word
+func isWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= '0' && b <= '9' || b == '_' || b >= 0x80
+}
+
+// Tokenize splits text into lower-case search terms
+func Tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 0x7f)
+	})
+	return fields
+}
@@ -0,0 +1,306 @@
+package content
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrUnsupported is returned for files whose text cannot be extracted
+var ErrUnsupported = errors.New("unsupported document type")
+
+// plainTextExtensions are read as is
+var plainTextExtensions = map[string]bool{
+	".txt": true, ".md": true, ".csv": true, ".tsv": true, ".log": true,
+	".json": true, ".xml": true, ".yaml": true, ".yml": true, ".ini": true,
+}
+
+// markupExtensions are read with their tags removed
+var markupExtensions = map[string]bool{".html": true, ".htm": true}
+
+// officeTextParts lists the parts of zip based documents that carry text
+var officeTextParts = map[string]*regexp.Regexp{
+	".docx": regexp.MustCompile(`^word/(document|header\d*|footer\d*)\.xml$`),
+	".xlsx": regexp.MustCompile(`^xl/sharedStrings\.xml$`),
+	".pptx": regexp.MustCompile(`^ppt/slides/slide\d+\.xml$`),
+	".odt":  regexp.MustCompile(`^content\.xml$`),
+	".ods":  regexp.MustCompile(`^content\.xml$`),
+	".odp":  regexp.MustCompile(`^content\.xml$`),
+}
+
+var (
+	xmlTag          = regexp.MustCompile(`<[^>]*>`)
+	xmlBlockEnd     = regexp.MustCompile(`</(w:p|a:p|text:p|text:h|si|tr)>|<w:br/>|<w:tab/>`)
+	pdfStream       = regexp.MustCompile(`stream\r?\n`)
+	pdfTextShow     = regexp.MustCompile(`(?s)(\((?:\\.|[^\\)])*\))\s*(?:Tj|'|")|\[((?:\\.|[^\]])*)\]\s*TJ|(T\*|Td|TD|ET)`)
+	pdfArrayElement = regexp.MustCompile(`\((?:\\.|[^\\)])*\)|-?\d+(?:\.\d+)?`)
+	whitespaceRun   = regexp.MustCompile(`[ \t]+`)
+)
+
+// Extractor pulls plain text out of documents
+type Extractor struct {
+	MaxTextSize int // Upper bound for the extracted text in bytes
+}
+
+// NewExtractor creates an extractor keeping at most maxTextSize bytes of text per document
+func NewExtractor(maxTextSize int) *Extractor {
+	if maxTextSize <= 0 {
+		maxTextSize = 1024 * 1024
+	}
+	return &Extractor{MaxTextSize: maxTextSize}
+}
+
+// Supported reports whether text can be extracted from path
+func Supported(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	_, office := officeTextParts[ext]
+	return plainTextExtensions[ext] || markupExtensions[ext] || office || ext == ".pdf"
+}
+
+// Extract returns the text content of the document at path. Scanned documents
+// without a text layer yield an empty string.
+func (e *Extractor) Extract(path string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+
+	var text string
+	var err error
+	switch {
+	case plainTextExtensions[ext]:
+		text, err = e.readText(path)
+	case markupExtensions[ext]:
+		text, err = e.readText(path)
+		text = html.UnescapeString(xmlTag.ReplaceAllString(text, " "))
+	case ext == ".pdf":
+		text, err = e.extractPDF(path)
+	default:
+		parts, ok := officeTextParts[ext]
+		if !ok {
+			return "", fmt.Errorf("%w: %s", ErrUnsupported, path)
+		}
+		text, err = e.extractZipXML(path, parts)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return e.truncate(normalizeSpace(text)), nil
+}
+
+// readText reads up to MaxTextSize bytes of a text file
+func (e *Extractor) readText(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, int64(e.MaxTextSize)))
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(data) {
+		data = bytes.ToValidUTF8(data, []byte(" "))
+	}
+	return string(data), nil
+}
+
+// extractZipXML collects the text of the matching XML parts of an Office/OpenDocument package
+func (e *Extractor) extractZipXML(path string, parts *regexp.Regexp) (string, error) {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return "", fmt.Errorf("not a valid document: %w", err)
+	}
+	defer reader.Close()
+
+	// Slides and sheets are numbered; keep them in document order
+	files := make([]*zip.File, 0)
+	for _, file := range reader.File {
+		if parts.MatchString(file.Name) {
+			files = append(files, file)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return naturalLess(files[i].Name, files[j].Name) })
+
+	var text strings.Builder
+	for _, file := range files {
+		rc, err := file.Open()
+		if err != nil {
+			return "", err
+		}
+		data, err := io.ReadAll(io.LimitReader(rc, int64(e.MaxTextSize)*4))
+		rc.Close()
+		if err != nil {
+			return "", err
+		}
+
+		content := xmlBlockEnd.ReplaceAllString(string(data), "\n")
+		text.WriteString(html.UnescapeString(xmlTag.ReplaceAllString(content, "")))
+		text.WriteString("\n")
+		if text.Len() > e.MaxTextSize {
+			break
+		}
+	}
+	return text.String(), nil
+}
+
+// extractPDF decodes the content streams of a PDF and collects the strings
+// shown by text operators. Fonts with custom encodings are not mapped, so
+// such text may come out garbled or empty.
+func (e *Extractor) extractPDF(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return "", fmt.Errorf("not a PDF file")
+	}
+
+	var text strings.Builder
+	for _, loc := range pdfStream.FindAllIndex(data, -1) {
+		if loc[0] > 0 && data[loc[0]-1] == 'd' {
+			continue // "endstream"
+		}
+
+		// The stream dictionary lies between the object header and the keyword
+		dict := data[:loc[0]]
+		if header := bytes.LastIndex(dict, []byte(" obj")); header >= 0 {
+			dict = dict[header:]
+		}
+		start := loc[1]
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		stream := data[start : start+end]
+
+		// Images, fonts and object streams never hold page text
+		if bytes.Contains(dict, []byte("/Subtype")) || bytes.Contains(dict, []byte("/ObjStm")) ||
+			bytes.Contains(dict, []byte("/Length1")) {
+			continue
+		}
+		// Only plain and Flate-compressed streams can contain readable text
+		if bytes.Contains(dict, []byte("/Filter")) {
+			if !bytes.Contains(dict, []byte("/FlateDecode")) || bytes.Contains(dict, []byte("/DCTDecode")) {
+				continue
+			}
+			decoded, err := inflate(stream)
+			if err != nil {
+				continue
+			}
+			stream = decoded
+		}
+
+		pdfStreamText(&text, stream)
+		if text.Len() > e.MaxTextSize {
+			break
+		}
+	}
+	return text.String(), nil
+}
+
+// pdfStreamText appends the text shown by a content stream to text
+func pdfStreamText(text *strings.Builder, stream []byte) {
+	for _, match := range pdfTextShow.FindAllSubmatch(stream, -1) {
+		switch {
+		case match[1] != nil:
+			text.WriteString(pdfLiteral(match[1]))
+		case match[2] != nil:
+			for _, element := range pdfArrayElement.FindAll(match[2], -1) {
+				if element[0] == '(' {
+					text.WriteString(pdfLiteral(element))
+				} else if adjustment, err := strconv.ParseFloat(string(element), 64); err == nil && adjustment <= -200 {
+					// Large positive gaps between glyph runs separate words
+					text.WriteString(" ")
+				}
+			}
+		case match[3] != nil:
+			text.WriteString("\n")
+		}
+	}
+}
+
+// pdfLiteral decodes a PDF literal string including its parentheses
+func pdfLiteral(literal []byte) string {
+	literal = literal[1 : len(literal)-1]
+	var out strings.Builder
+	for i := 0; i < len(literal); i++ {
+		c := literal[i]
+		if c != '\\' || i+1 >= len(literal) {
+			out.WriteByte(c)
+			continue
+		}
+		i++
+		switch literal[i] {
+		case 'n', 'r':
+			out.WriteByte('\n')
+		case 't':
+			out.WriteByte('\t')
+		case 'b', 'f':
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			value := 0
+			for j := 0; j < 3 && i < len(literal) && literal[i] >= '0' && literal[i] <= '7'; j++ {
+				value = value*8 + int(literal[i]-'0')
+				i++
+			}
+			i--
+			out.WriteByte(byte(value))
+		default:
+			out.WriteByte(literal[i])
+		}
+	}
+	return out.String()
+}
+
+// inflate decompresses a FlateDecode stream
+func inflate(data []byte) ([]byte, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// truncate cuts text to MaxTextSize bytes on a rune boundary
+func (e *Extractor) truncate(text string) string {
+	if len(text) <= e.MaxTextSize {
+		return text
+	}
+	cut := e.MaxTextSize
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut]
+}
+
+// normalizeSpace collapses runs of blanks and drops empty lines
+func normalizeSpace(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r", "\n"), "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		line = strings.TrimSpace(whitespaceRun.ReplaceAllString(line, " "))
+		if line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// naturalLess orders names so that slide2 sorts before slide10
+func naturalLess(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
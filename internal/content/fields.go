@@ -0,0 +1,201 @@
+package content
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Fields are facts recognized in a document's text
+type Fields struct {
+	Date   time.Time `json:"date,omitempty"`
+	Vendor string    `json:"vendor,omitempty"`
+}
+
+var (
+	// Dates that follow a label are preferred over the first date in the text
+	labeledDate = regexp.MustCompile(`(?i)\b(?:invoice date|date of issue|issue date|issued|receipt date|date)\s*[:.]?\s*`)
+	isoDate     = regexp.MustCompile(`\b(\d{4})[-/.](\d{1,2})[-/.](\d{1,2})\b`)
+	numericDate = regexp.MustCompile(`\b(\d{1,2})[/.\-](\d{1,2})[/.\-](\d{4})\b`)
+	textDate    = regexp.MustCompile(`(?i)\b(?:(\d{1,2})(?:st|nd|rd|th)?\.?\s+([a-z]{3,9})\.?,?\s+(\d{4})|([a-z]{3,9})\.?\s+(\d{1,2})(?:st|nd|rd|th)?,?\s+(\d{4}))\b`)
+
+	vendorLabel = regexp.MustCompile(`(?im)^\s*(?:from|vendor|seller|supplier|merchant|sold by|issued by)\s*[:\-]\s*(.+)$`)
+	companyForm = regexp.MustCompile(`(?i)\b(?:inc|ltd|llc|gmbh|ag|s\.?a|b\.?v|plc|co|corp|corporation|limited|company)\b\.?`)
+)
+
+// vendorStopWords disqualify a line from being a vendor name
+var vendorStopWords = []string{
+	"invoice", "receipt", "statement", "bill to", "ship to", "date", "page", "total",
+	"tax", "amount", "number", "order", "customer", "agreement", "contract", "www.", "http",
+	"subtotal", "cash", "change", "card", "paid", "balance", "vat", "qty",
+}
+
+var months = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+	"may": time.May, "jun": time.June, "jul": time.July, "aug": time.August,
+	"sep": time.September, "sept": time.September, "oct": time.October,
+	"nov": time.November, "dec": time.December,
+}
+
+// ExtractFields recognizes the document date and the issuing vendor in text.
+// Fields that cannot be recognized are left empty.
+func ExtractFields(text string) Fields {
+	return Fields{Date: findDate(text), Vendor: findVendor(text)}
+}
+
+// findDate returns the labeled date if present, else the first plausible date
+func findDate(text string) time.Time {
+	for _, loc := range labeledDate.FindAllStringIndex(text, -1) {
+		if strings.HasSuffix(strings.ToLower(text[:loc[0]]), "due ") {
+			continue // The due date is not the document date
+		}
+		rest := text[loc[1]:]
+		if len(rest) > 40 {
+			rest = rest[:40]
+		}
+		if date, pos := firstDate(rest); !date.IsZero() && pos == 0 {
+			return date
+		}
+	}
+	date, _ := firstDate(text)
+	return date
+}
+
+// firstDate returns the earliest valid date in text and its offset
+func firstDate(text string) (time.Time, int) {
+	var best time.Time
+	bestPos := -1
+	consider := func(date time.Time, pos int) {
+		if !date.IsZero() && (bestPos < 0 || pos < bestPos) {
+			best, bestPos = date, pos
+		}
+	}
+
+	for _, m := range isoDate.FindAllStringSubmatchIndex(text, -1) {
+		if date := makeDate(atoi(text[m[2]:m[3]]), atoi(text[m[4]:m[5]]), atoi(text[m[6]:m[7]])); !date.IsZero() {
+			consider(date, m[0])
+			break
+		}
+	}
+	for _, m := range numericDate.FindAllStringSubmatchIndex(text, -1) {
+		first, second, year := atoi(text[m[2]:m[3]]), atoi(text[m[4]:m[5]]), atoi(text[m[6]:m[7]])
+		// Day first unless that is impossible (US style 03/25/2024)
+		date := makeDate(year, second, first)
+		if date.IsZero() {
+			date = makeDate(year, first, second)
+		}
+		if !date.IsZero() {
+			consider(date, m[0])
+			break
+		}
+	}
+	for _, m := range textDate.FindAllStringSubmatch(text, -1) {
+		var date time.Time
+		if m[1] != "" {
+			date = makeDate(atoi(m[3]), monthOf(m[2]), atoi(m[1]))
+		} else {
+			date = makeDate(atoi(m[6]), monthOf(m[4]), atoi(m[5]))
+		}
+		if !date.IsZero() {
+			consider(date, strings.Index(text, m[0]))
+			break
+		}
+	}
+	return best, bestPos
+}
+
+// makeDate returns the date or the zero time if it is not a plausible document date
+func makeDate(year, month, day int) time.Time {
+	if year < 1970 || year > time.Now().Year()+1 || month < 1 || month > 12 || day < 1 || day > 31 {
+		return time.Time{}
+	}
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if date.Day() != day {
+		return time.Time{} // e.g. February 30
+	}
+	return date
+}
+
+// monthOf parses an English month name or abbreviation, returning 0 when unknown
+func monthOf(name string) int {
+	name = strings.ToLower(name)
+	if month, ok := months[name]; ok {
+		return int(month)
+	}
+	if len(name) >= 3 {
+		if month, ok := months[name[:3]]; ok && strings.HasPrefix(strings.ToLower(month.String()), name) {
+			return int(month)
+		}
+	}
+	return 0
+}
+
+// atoi parses a run of digits
+func atoi(digits string) int {
+	value := 0
+	for _, c := range digits {
+		value = value*10 + int(c-'0')
+	}
+	return value
+}
+
+// findVendor returns the labeled vendor, a line with a company form (Ltd, GmbH, ...)
+// near the top, or the first name-like line of the document
+func findVendor(text string) string {
+	if m := vendorLabel.FindStringSubmatch(text); m != nil {
+		if vendor := cleanVendor(m[1]); vendor != "" {
+			return vendor
+		}
+	}
+
+	lines := strings.Split(text, "\n")
+	if len(lines) > 15 {
+		lines = lines[:15]
+	}
+	for _, line := range lines {
+		if companyForm.MatchString(line) && isVendorLine(line) {
+			return cleanVendor(line)
+		}
+	}
+	for _, line := range lines {
+		if isVendorLine(line) {
+			return cleanVendor(line)
+		}
+	}
+	return ""
+}
+
+// isVendorLine reports whether line looks like a business name
+func isVendorLine(line string) bool {
+	line = strings.TrimSpace(line)
+	if len(line) < 3 || len(line) > 60 {
+		return false
+	}
+	lower := strings.ToLower(line)
+	for _, word := range vendorStopWords {
+		if strings.Contains(lower, word) {
+			return false
+		}
+	}
+
+	letters, digits := 0, 0
+	for _, r := range line {
+		switch {
+		case unicode.IsLetter(r):
+			letters++
+		case unicode.IsDigit(r):
+			digits++
+		}
+	}
+	return letters >= 3 && digits*3 < letters
+}
+
+// cleanVendor trims a vendor name to at most five words
+func cleanVendor(name string) string {
+	words := strings.Fields(strings.Trim(name, " \t.,:;-"))
+	if len(words) > 5 {
+		words = words[:5]
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,276 @@
+package content
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// indexFile is the name of the index inside the index directory
+const indexFile = "content-index.json"
+
+// snippetRadius is the number of bytes shown around the first match
+const snippetRadius = 60
+
+// Document is an indexed file and its extracted text
+type Document struct {
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"mod_time"`
+	Category string    `json:"category,omitempty"`
+	Matched  []string  `json:"matched,omitempty"`
+	Text     string    `json:"text"`
+}
+
+// Hit is a search result
+type Hit struct {
+	Path     string  `json:"path"`
+	Category string  `json:"category,omitempty"`
+	Score    float64 `json:"score"`
+	Snippet  string  `json:"snippet"`
+}
+
+// SearchOptions restrict a search
+type SearchOptions struct {
+	Category string // Only documents of this category
+	Limit    int    // Maximum number of hits (0 = unlimited)
+}
+
+// Index is a persistent full-text index of document contents
+type Index struct {
+	mu        sync.RWMutex
+	path      string
+	documents map[string]*Document
+	dirty     bool
+}
+
+// OpenIndex loads the index stored in dir, creating an empty one if none exists
+func OpenIndex(dir string) (*Index, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create index directory: %w", err)
+	}
+
+	index := &Index{
+		path:      filepath.Join(dir, indexFile),
+		documents: make(map[string]*Document),
+	}
+
+	data, err := os.ReadFile(index.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var documents []*Document
+	if err := json.Unmarshal(data, &documents); err != nil {
+		return nil, fmt.Errorf("corrupt content index %s: %w", index.path, err)
+	}
+	for _, doc := range documents {
+		index.documents[doc.Path] = doc
+	}
+	return index, nil
+}
+
+// Len returns the number of indexed documents
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.documents)
+}
+
+// Get returns the indexed document for path
+func (idx *Index) Get(path string) (*Document, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	doc, ok := idx.documents[path]
+	return doc, ok
+}
+
+// UpToDate reports whether path is indexed with the given size and
+// modification time, comparing times within tolerance
+func (idx *Index) UpToDate(path string, size int64, modTime time.Time, tolerance domain.TimeTolerance) bool {
+	doc, ok := idx.Get(path)
+	return ok && doc.Size == size && tolerance.Equal(doc.ModTime, modTime)
+}
+
+// Put adds or replaces a document
+func (idx *Index) Put(doc *Document) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.documents[doc.Path] = doc
+	idx.dirty = true
+}
+
+// Remove drops a document from the index
+func (idx *Index) Remove(path string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if _, ok := idx.documents[path]; ok {
+		delete(idx.documents, path)
+		idx.dirty = true
+	}
+}
+
+// Prune removes documents below root that no longer exist and returns their paths
+func (idx *Index) Prune(root string) []string {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	removed := make([]string, 0)
+	prefix := strings.TrimSuffix(root, string(filepath.Separator)) + string(filepath.Separator)
+	for path := range idx.documents {
+		if path != root && !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			delete(idx.documents, path)
+			removed = append(removed, path)
+		}
+	}
+	if len(removed) > 0 {
+		idx.dirty = true
+	}
+	sort.Strings(removed)
+	return removed
+}
+
+// Save writes the index to disk if it changed
+func (idx *Index) Save() error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if !idx.dirty {
+		return nil
+	}
+
+	documents := make([]*Document, 0, len(idx.documents))
+	for _, doc := range idx.documents {
+		documents = append(documents, doc)
+	}
+	sort.Slice(documents, func(i, j int) bool { return documents[i].Path < documents[j].Path })
+
+	data, err := json.Marshal(documents)
+	if err != nil {
+		return err
+	}
+
+	if err := filesystem.WriteFileAtomic(idx.path, data, 0644); err != nil {
+		return err
+	}
+	idx.dirty = false
+	return nil
+}
+
+// Search returns documents containing all terms of query, best matches first.
+// Terms are matched case-insensitively as word prefixes.
+func (idx *Index) Search(query string, options SearchOptions) []Hit {
+	terms := Tokenize(query)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	hits := make([]Hit, 0)
+	for _, doc := range idx.documents {
+		if options.Category != "" && !strings.EqualFold(doc.Category, options.Category) {
+			continue
+		}
+
+		score, ok := scoreDocument(doc, terms)
+		if !ok {
+			continue
+		}
+		hits = append(hits, Hit{
+			Path:     doc.Path,
+			Category: doc.Category,
+			Score:    score,
+			Snippet:  snippet(doc.Text, terms[0]),
+		})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Path < hits[j].Path
+	})
+	if options.Limit > 0 && len(hits) > options.Limit {
+		hits = hits[:options.Limit]
+	}
+	return hits
+}
+
+// scoreDocument counts term occurrences in the document, weighting file name
+// matches higher. It reports false unless every term occurs.
+func scoreDocument(doc *Document, terms []string) (float64, bool) {
+	words := Tokenize(doc.Text)
+	nameWords := Tokenize(filepath.Base(doc.Path))
+
+	var score float64
+	for _, term := range terms {
+		count := 0
+		for _, word := range words {
+			if strings.HasPrefix(word, term) {
+				count++
+			}
+		}
+		for _, word := range nameWords {
+			if strings.HasPrefix(word, term) {
+				count += 5
+			}
+		}
+		if count == 0 {
+			return 0, false
+		}
+		score += float64(count)
+	}
+
+	// Normalize so short documents with many matches rank above long ones
+	return score / (1 + float64(len(words))/1000), true
+}
+
+// snippet returns the text surrounding the first occurrence of term on a single line
+func snippet(text, term string) string {
+	lower := strings.ToLower(text)
+	pos := strings.Index(lower, term)
+	if pos < 0 || len(lower) != len(text) {
+		pos = 0 // Case folding changed byte offsets; fall back to the start
+	}
+
+	start := pos - snippetRadius
+	if start < 0 {
+		start = 0
+	}
+	end := pos + len(term) + snippetRadius
+	if end > len(text) {
+		end = len(text)
+	}
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+
+	result := strings.Join(strings.Fields(text[start:end]), " ")
+	if start > 0 {
+		result = "…" + result
+	}
+	if end < len(text) {
+		result += "…"
+	}
+	return result
+}
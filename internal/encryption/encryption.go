@@ -0,0 +1,361 @@
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Extension is appended to the names of encrypted files
+const Extension = ".enc"
+
+// Encrypted files start with the magic, the key derivation method, a random
+// salt and the scrypt work factor. Files encrypted with a passphrase then hold
+// the salt the passphrase was stretched with into the master key of their run;
+// the key of each file is derived from the master key or key file and the
+// salt of the file with HKDF. The content follows in chunks sealed with
+// AES-256-GCM; the header is authenticated with every chunk and the last chunk
+// is marked so truncation is detected.
+var magic = []byte("FOPSENC1")
+
+const (
+	kdfKeyFile      byte = 2
+	kdfScryptMaster byte = 3 // Passphrase stretched once per run
+
+	saltSize         = 16
+	headerSize       = 8 + 1 + saltSize + 1
+	masterHeaderSize = headerSize + saltSize
+	chunkSize        = 64 * 1024
+	scryptLogN       = 15
+	minKeyBytes      = 16
+)
+
+// ErrNotEncrypted is returned when decrypting a file that was not written by Encrypt
+var ErrNotEncrypted = errors.New("not an encrypted file")
+
+// ErrWrongKey is returned when a file cannot be authenticated with the given key,
+// because the key is wrong or the file was tampered with
+var ErrWrongKey = errors.New("wrong key or corrupt file")
+
+// Key is the secret a file is encrypted with
+type Key struct {
+	kdf    byte
+	secret []byte
+
+	mu         sync.Mutex
+	masterSalt []byte            // Salt of the master key files are encrypted with
+	masters    map[string][]byte // Stretched passphrase by salt and work factor
+}
+
+// PassphraseKey creates a key from a passphrase. The passphrase is stretched
+// with scrypt once for all files the key encrypts, and once for all files of
+// a run it decrypts.
+func PassphraseKey(passphrase string) (*Key, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase must not be empty")
+	}
+	masterSalt := make([]byte, saltSize)
+	if _, err := rand.Read(masterSalt); err != nil {
+		return nil, err
+	}
+	return &Key{kdf: kdfScryptMaster, secret: []byte(passphrase), masterSalt: masterSalt}, nil
+}
+
+// LoadKeyFile creates a key from the content of a key file, e.g. 32 bytes
+// from /dev/urandom
+func LoadKeyFile(path string) (*Key, error) {
+	secret, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	if len(secret) < minKeyBytes {
+		return nil, fmt.Errorf("key file %s must contain at least %d bytes", path, minKeyBytes)
+	}
+	return &Key{kdf: kdfKeyFile, secret: secret}, nil
+}
+
+// GenerateKeyFile writes 32 random bytes to a new key file readable only by the owner
+func GenerateKeyFile(path string) error {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(secret); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// derive returns the AES-256 key for a file with the given header
+func (k *Key) derive(header []byte) ([]byte, error) {
+	kdf := header[len(magic)]
+	salt := header[len(magic)+1 : len(magic)+1+saltSize]
+	logN := header[headerSize-1]
+
+	if kdf != kdfKeyFile && kdf != kdfScryptMaster {
+		return nil, fmt.Errorf("%w: unknown key derivation %d", ErrNotEncrypted, kdf)
+	}
+	passphrase := kdf == kdfScryptMaster
+	if passphrase != (k.kdf == kdfScryptMaster) {
+		if passphrase {
+			return nil, fmt.Errorf("%w: file is protected by a passphrase", ErrWrongKey)
+		}
+		return nil, fmt.Errorf("%w: file is protected by a key file", ErrWrongKey)
+	}
+	if passphrase && (logN < 10 || logN > 22) {
+		return nil, fmt.Errorf("%w: invalid scrypt work factor", ErrNotEncrypted)
+	}
+
+	if passphrase {
+		master, err := k.master(header[headerSize:masterHeaderSize], logN)
+		if err != nil {
+			return nil, err
+		}
+		return expand(master, salt)
+	}
+	return expand(k.secret, salt)
+}
+
+// master returns the passphrase stretched with scrypt, computed once per salt
+// and work factor
+func (k *Key) master(salt []byte, logN byte) ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	id := fmt.Sprintf("%x/%d", salt, logN)
+	if master, ok := k.masters[id]; ok {
+		return master, nil
+	}
+	master, err := scrypt.Key(k.secret, salt, 1<<logN, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	if k.masters == nil {
+		k.masters = make(map[string][]byte)
+	}
+	k.masters[id] = master
+	return master, nil
+}
+
+// expand derives the key of a file from a master key or key file and the
+// salt of the file
+func expand(secret, salt []byte) ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, []byte("fileops encryption")), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Encrypt writes the encrypted content of src to dst
+func Encrypt(dst io.Writer, src io.Reader, key *Key) error {
+	header := make([]byte, 0, masterHeaderSize)
+	header = append(header, magic...)
+	header = append(header, key.kdf)
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	header = append(header, salt...)
+	header = append(header, scryptLogN)
+	if key.kdf == kdfScryptMaster {
+		header = append(header, key.masterSalt...)
+	}
+
+	aead, err := newAEAD(key, header)
+	if err != nil {
+		return err
+	}
+	if _, err := dst.Write(header); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReaderSize(src, chunkSize)
+	buf := make([]byte, chunkSize)
+	sealed := make([]byte, 0, chunkSize+aead.Overhead())
+	for counter := uint64(0); ; counter++ {
+		n, last, err := readChunk(reader, buf)
+		if err != nil {
+			return err
+		}
+		sealed = aead.Seal(sealed[:0], nonce(counter, last), buf[:n], header)
+		if _, err := dst.Write(sealed); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// Decrypt writes the decrypted content of src to dst. Nothing written before an
+// error is authenticated; callers must discard partial output.
+func Decrypt(dst io.Writer, src io.Reader, key *Key) error {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(src, header); err != nil || !bytes.Equal(header[:len(magic)], magic) {
+		return ErrNotEncrypted
+	}
+	if header[len(magic)] == kdfScryptMaster {
+		header = append(header, make([]byte, saltSize)...)
+		if _, err := io.ReadFull(src, header[headerSize:]); err != nil {
+			return ErrNotEncrypted
+		}
+	}
+
+	aead, err := newAEAD(key, header)
+	if err != nil {
+		return err
+	}
+
+	reader := bufio.NewReaderSize(src, chunkSize+aead.Overhead())
+	chunk := make([]byte, chunkSize+aead.Overhead())
+	opened := make([]byte, 0, chunkSize)
+	for counter := uint64(0); ; counter++ {
+		n, last, err := readChunk(reader, chunk)
+		if err != nil {
+			return err
+		}
+		opened, err = aead.Open(opened[:0], nonce(counter, last), chunk[:n], header)
+		if err != nil {
+			return ErrWrongKey
+		}
+		if _, err := dst.Write(opened); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// readChunk fills buf and reports whether it is the last chunk of the stream
+func readChunk(reader *bufio.Reader, buf []byte) (int, bool, error) {
+	n, err := io.ReadFull(reader, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return n, true, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	if _, err := reader.Peek(1); err == io.EOF {
+		return n, true, nil
+	} else if err != nil {
+		return 0, false, err
+	}
+	return n, false, nil
+}
+
+// IsEncrypted reports whether path starts with the header written by Encrypt
+func IsEncrypted(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	prefix := make([]byte, len(magic))
+	if _, err := io.ReadFull(file, prefix); err != nil {
+		return false
+	}
+	return bytes.Equal(prefix, magic)
+}
+
+// EncryptFile encrypts src into dst, keeping the permissions and modification time
+func EncryptFile(src, dst string, key *Key) error {
+	return transformFile(src, dst, func(w io.Writer, r io.Reader) error { return Encrypt(w, r, key) })
+}
+
+// DecryptFile decrypts src into dst, keeping the permissions and modification time
+func DecryptFile(src, dst string, key *Key) error {
+	return transformFile(src, dst, func(w io.Writer, r io.Reader) error { return Decrypt(w, r, key) })
+}
+
+// transformFile writes dst through a temporary file so a failure never leaves
+// a partial file behind. An existing dst is not replaced.
+func transformFile(src, dst string, transform func(io.Writer, io.Reader) error) error {
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("%w: %s", os.ErrExist, dst)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".fileops-crypt-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	if err := transform(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := os.Chtimes(tmpName, info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, dst)
+}
+
+// newAEAD creates the cipher for a file from its header
+func newAEAD(key *Key, header []byte) (cipher.AEAD, error) {
+	derived, err := key.derive(header)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// nonce returns the nonce of a chunk: a big-endian counter and a last-chunk flag.
+// Every file has its own salt and thus its own key, so nonces never repeat.
+func nonce(counter uint64, last bool) []byte {
+	n := make([]byte, 12)
+	binary.BigEndian.PutUint64(n[3:11], counter)
+	if last {
+		n[11] = 1
+	}
+	return n
+}
@@ -0,0 +1,319 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/events"
+	"github.com/a4abhishek/fileops/pkg/schema"
+)
+
+// Files of the artifacts directory of a run, <runs>/<operation-id>/
+const (
+	ArtifactPlan   = "plan.json"     // What was asked: operation type, configuration, command line
+	ArtifactReport = "report.json"   // The operation result
+	ArtifactUndo   = "undo.journal"  // Moves and deletions in order, one JSON object per line, in fsynced batches
+	ArtifactErrors = "errors.ndjson" // Per-item errors, one JSON object per line
+	ArtifactLog    = "log"           // Events of the run as text
+)
+
+// RunPlan records how an operation was started so the run can be repeated
+type RunPlan struct {
+	SchemaVersion    int                    `json:"schema_version"` // See package schema
+	ID               string                 `json:"id"`
+	OperationType    domain.OperationType   `json:"operation_type"`
+	Config           domain.OperationConfig `json:"config"`
+	Started          time.Time              `json:"started"`
+	Command          []string               `json:"command"`
+	WorkingDirectory string                 `json:"working_directory"`
+	User             string                 `json:"user,omitempty"`
+	Host             string                 `json:"host,omitempty"`
+}
+
+// Defaults of the undo journal batching, see SetBatching
+const (
+	DefaultBatchSize     = 500
+	DefaultFsyncInterval = 2 * time.Second
+)
+
+// UndoEntry is a change recorded in the undo journal. Moves can be reverted
+// by moving Target back to Path; deletions are recorded for auditing.
+// Changes are grouped into batches, each ended by a commit entry written
+// when the journal has been synced up to it.
+type UndoEntry struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"` // move, delete or commit
+	Path   string    `json:"path,omitempty"`
+	Target string    `json:"target,omitempty"`
+	Bytes  int64     `json:"bytes,omitempty"`
+	Batch  int       `json:"batch"`
+}
+
+// runArtifacts writes the artifacts of one run while it executes
+type runArtifacts struct {
+	dir           string
+	unsubscribe   func()
+	batchSize     int
+	fsyncInterval time.Duration
+	deterministic bool // Report without what differs between runs
+
+	mu         sync.Mutex
+	undo       *os.File
+	errors     *os.File
+	log        *os.File
+	batch      int       // Batch the next change belongs to, from 1
+	pending    int       // Changes of the batch written so far
+	committed  int64     // Changes of all committed batches
+	batchStart time.Time // When the first change of the batch was written
+}
+
+// SetRunsDirectory keeps the artifacts of every run in a directory of its
+// own below dir
+func (e *Engine) SetRunsDirectory(dir string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.runsDirectory = dir
+}
+
+// SetBatching sets how many moves and deletions the undo journal of a run
+// groups into a batch, and how old a batch may get, before the journal is
+// synced and the batch committed. After a crash at most the changes of the
+// last, uncommitted batch need to be reconciled. An interval of 0 ends
+// batches by size only.
+func (e *Engine) SetBatching(size int, interval time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.batchSize = size
+	e.fsyncInterval = interval
+}
+
+// startRun creates the artifacts directory of a run, writes its plan and
+// starts recording its events. It returns nil when runs are not kept or the
+// directory cannot be created; the run then proceeds without artifacts.
+func (e *Engine) startRun(id string, operationType domain.OperationType, config domain.OperationConfig, principal Principal) *runArtifacts {
+	e.mu.RLock()
+	root, batchSize, fsyncInterval := e.runsDirectory, e.batchSize, e.fsyncInterval
+	e.mu.RUnlock()
+	if root == "" {
+		return nil
+	}
+
+	run, err := createRun(filepath.Join(root, id))
+	if err == nil {
+		run.batchSize, run.fsyncInterval = batchSize, fsyncInterval
+		run.deterministic = config.Deterministic
+		host, _ := os.Hostname()
+		cwd, _ := os.Getwd()
+		err = writeArtifact(filepath.Join(run.dir, ArtifactPlan), RunPlan{
+			SchemaVersion:    schema.RunPlanVersion,
+			ID:               id,
+			OperationType:    operationType,
+			Config:           config,
+			Started:          time.Now(),
+			Command:          os.Args,
+			WorkingDirectory: cwd,
+			User:             principal.User,
+			Host:             host,
+		})
+	}
+	if err != nil {
+		e.logger.Warn("Run artifacts will not be kept", "id", id, "error", err)
+		if run != nil {
+			run.close()
+		}
+		return nil
+	}
+
+	run.unsubscribe = e.events.Subscribe(func(event events.Event) {
+		if event.OperationID != id {
+			return
+		}
+		if batch, ok := run.record(event); ok {
+			// Published after record released the lock, as the run receives it too
+			batch.OperationID, batch.OperationType = id, operationType
+			e.events.Publish(batch)
+		}
+	}, append(loggedEvents, events.StepStarted)...)
+	return run
+}
+
+func createRun(dir string) (*runArtifacts, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	run := &runArtifacts{dir: dir, batch: 1}
+	for name, file := range map[string]**os.File{ArtifactUndo: &run.undo, ArtifactErrors: &run.errors, ArtifactLog: &run.log} {
+		opened, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			run.close()
+			return nil, err
+		}
+		*file = opened
+	}
+	return run, nil
+}
+
+// record writes an event to the log and, for changes and errors, to the
+// undo journal and error list. It returns the BatchCommitted event to publish
+// when a change completed a batch.
+func (r *runArtifacts) record(event events.Event) (events.Event, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var committed events.Event
+	var ok bool
+	switch event.Type {
+	case events.FileMoved:
+		r.appendChange(UndoEntry{Time: event.Time, Action: "move", Path: event.Path, Target: event.Target})
+		committed, ok = r.commitIfDue(event.Time)
+	case events.FileDeleted:
+		r.appendChange(UndoEntry{Time: event.Time, Action: "delete", Path: event.Path, Bytes: event.Bytes})
+		committed, ok = r.commitIfDue(event.Time)
+	case events.ErrorOccurred:
+		appendLine(r.errors, domain.OperationError{File: event.Path, Operation: string(event.OperationType), Error: event.Error, Timestamp: event.Time})
+	}
+
+	line := []string{event.Time.Format(time.RFC3339Nano), string(event.Type)}
+	for _, field := range []struct{ key, value string }{
+		{"step", event.Step}, {"path", event.Path}, {"target", event.Target},
+		{"status", string(event.Status)}, {"warning", string(event.Warning)},
+		{"message", event.Message}, {"error", event.Error},
+	} {
+		if field.value != "" {
+			line = append(line, fmt.Sprintf("%s=%q", field.key, field.value))
+		}
+	}
+	if event.Bytes != 0 {
+		line = append(line, fmt.Sprintf("bytes=%d", event.Bytes))
+	}
+	if event.Duration != 0 {
+		line = append(line, "duration="+event.Duration.String())
+	}
+	if event.Batch != 0 {
+		line = append(line, fmt.Sprintf("batch=%d", event.Batch))
+	}
+	fmt.Fprintln(r.log, strings.Join(line, " "))
+	return committed, ok
+}
+
+// appendChange writes a change to the undo journal as part of the open batch
+func (r *runArtifacts) appendChange(entry UndoEntry) {
+	if r.pending == 0 {
+		r.batchStart = entry.Time
+	}
+	entry.Batch = r.batch
+	appendLine(r.undo, entry)
+	r.pending++
+}
+
+// commitIfDue commits the open batch once it holds batchSize changes or is
+// older than fsyncInterval
+func (r *runArtifacts) commitIfDue(now time.Time) (events.Event, bool) {
+	if r.pending < r.batchSize && (r.fsyncInterval <= 0 || now.Sub(r.batchStart) < r.fsyncInterval) {
+		return events.Event{}, false
+	}
+	return r.commit(now)
+}
+
+// commit ends the open batch with a commit entry and syncs the journal, so
+// every change up to it survives a crash
+func (r *runArtifacts) commit(now time.Time) (events.Event, bool) {
+	if r.pending == 0 {
+		return events.Event{}, false
+	}
+	appendLine(r.undo, UndoEntry{Time: now, Action: "commit", Batch: r.batch})
+	if err := r.undo.Sync(); err != nil {
+		fmt.Fprintf(r.log, "%s sync_failed error=%q\n", now.Format(time.RFC3339Nano), err.Error())
+	}
+	r.committed += int64(r.pending)
+	event := events.Event{Type: events.BatchCommitted, Time: now, Batch: r.batch, Items: r.committed}
+	r.batch++
+	r.pending = 0
+	return event, true
+}
+
+// finish writes the report of the run and points the result at the run.
+// result is nil when the operation failed without one; err is then kept.
+func (r *runArtifacts) finish(result *domain.OperationResult, err error) {
+	if r == nil {
+		return
+	}
+	if result != nil {
+		result.Artifacts = r.dir
+		if r.deterministic {
+			writeArtifact(filepath.Join(r.dir, ArtifactReport), reproducible(result))
+		} else {
+			writeArtifact(filepath.Join(r.dir, ArtifactReport), result)
+		}
+	} else if err != nil {
+		writeArtifact(filepath.Join(r.dir, ArtifactReport), map[string]interface{}{schema.Field: schema.ResultVersion, "status": domain.StatusFailed, "error": err.Error()})
+	}
+	r.close()
+}
+
+// close stops recording, commits the last batch and closes the files of the run
+func (r *runArtifacts) close() {
+	if r == nil {
+		return
+	}
+	if r.unsubscribe != nil {
+		r.unsubscribe()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.undo != nil {
+		r.commit(time.Now())
+	}
+	for _, file := range []*os.File{r.undo, r.errors, r.log} {
+		if file != nil {
+			file.Close()
+		}
+	}
+}
+
+// UncommittedChanges returns the changes of the undo journal in the artifacts
+// directory dir that follow its last commit entry: after a crash, those of
+// them that happened are not known to be recorded and need reconciliation
+func UncommittedChanges(dir string) ([]UndoEntry, error) {
+	file, err := os.Open(filepath.Join(dir, ArtifactUndo))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	pending := make([]UndoEntry, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry UndoEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue // Torn last line of a crashed run
+		}
+		if entry.Action == "commit" {
+			pending = pending[:0]
+			continue
+		}
+		pending = append(pending, entry)
+	}
+	return pending, scanner.Err()
+}
+
+func appendLine(file *os.File, value interface{}) {
+	if data, err := json.Marshal(value); err == nil {
+		file.Write(append(data, '\n'))
+	}
+}
+
+func writeArtifact(path string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/a4abhishek/fileops/internal/audio"
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// AudioSimilarityMethod compares recordings by their Chromaprint fingerprint
+const AudioSimilarityMethod = "chromaprint"
+
+// defaultAudioSimilarity is the share of equal fingerprint bits from which
+// two recordings count as the same song. Encodings of one recording share
+// about 95%, unrelated ones about half.
+const defaultAudioSimilarity = 0.85
+
+// losslessQuality ranks lossless recordings above any lossy bitrate
+const losslessQuality = 1e6
+
+// chromaprintDetector recognizes the same song in other formats and bitrates
+// by its acoustic fingerprint, computed by fpcalc
+type chromaprintDetector struct {
+	once          sync.Once
+	fingerprinter *audio.Fingerprinter
+	err           error
+}
+
+// Available fails when fpcalc is not installed
+func (d *chromaprintDetector) Available() error {
+	d.once.Do(func() {
+		d.fingerprinter, d.err = audio.New()
+	})
+	return d.err
+}
+
+// Accepts reports whether path is a recording
+func (d *chromaprintDetector) Accepts(path string) bool {
+	return audio.IsAudio(path)
+}
+
+// Fingerprint computes the acoustic fingerprint of a recording
+func (d *chromaprintDetector) Fingerprint(ctx context.Context, path string) (Fingerprint, error) {
+	if !audio.IsAudio(path) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, path)
+	}
+	if err := d.Available(); err != nil {
+		return nil, err
+	}
+	recording, err := d.fingerprinter.Fingerprint(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return recording.Encode(), nil
+}
+
+// Distance is the share of differing fingerprint bits
+func (d *chromaprintDetector) Distance(a, b Fingerprint) float64 {
+	recordingA, errA := audio.Decode(a)
+	recordingB, errB := audio.Decode(b)
+	if errA != nil || errB != nil {
+		return 1
+	}
+	return audio.Distance(recordingA, recordingB)
+}
+
+// DefaultSimilarity is the share of equal bits from which recordings match
+func (d *chromaprintDetector) DefaultSimilarity() float64 {
+	return defaultAudioSimilarity
+}
+
+// Quality ranks lossless recordings first, then by bitrate
+func (d *chromaprintDetector) Quality(file *domain.FileInfo, fingerprint Fingerprint) float64 {
+	recording, err := audio.Decode(fingerprint)
+	if err != nil {
+		return 0
+	}
+	quality := audio.Bitrate(file.Size, recording.Duration)
+	if audio.Lossless(file.Path) {
+		quality += losslessQuality
+	}
+	return quality
+}
+
+// Describe records the format, duration and average bitrate of a recording
+func (d *chromaprintDetector) Describe(file *domain.FileInfo, fingerprint Fingerprint) map[string]string {
+	recording, err := audio.Decode(fingerprint)
+	if err != nil {
+		return nil
+	}
+	description := map[string]string{
+		"format":   strings.TrimPrefix(strings.ToLower(filepath.Ext(file.Path)), "."),
+		"duration": strconv.FormatFloat(recording.Duration, 'f', 1, 64),
+		"bitrate":  strconv.Itoa(int(audio.Bitrate(file.Size, recording.Duration))),
+	}
+	if audio.Lossless(file.Path) {
+		description["lossless"] = "true"
+	}
+	return description
+}
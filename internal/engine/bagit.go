@@ -0,0 +1,489 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/bagit"
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// BagExportFactory creates BagIt export operations
+type BagExportFactory struct {
+	engine *Engine
+}
+
+// Create creates a new BagIt export operation
+func (bf *BagExportFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewBagExportOperation(id, config, bf.engine), nil
+}
+
+// Validate validates the BagIt export configuration
+func (bf *BagExportFactory) Validate(config domain.OperationConfig) error {
+	if len(config.TargetPaths) == 0 {
+		return fmt.Errorf("at least one path is required")
+	}
+	if destination := settingString(config, "destination", ""); destination == "" || !filepath.IsAbs(destination) {
+		return fmt.Errorf("an absolute bag directory is required")
+	}
+	algorithm := settingString(config, "algorithm", "sha256")
+	supported := false
+	for _, candidate := range bagit.Algorithms {
+		supported = supported || candidate == algorithm
+	}
+	if !supported {
+		return fmt.Errorf("unsupported manifest algorithm %q, must be one of %v", algorithm, bagit.Algorithms)
+	}
+	for _, pattern := range settingStrings(config, "patterns") {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q", pattern)
+		}
+	}
+	if _, err := bagInfoFields(settingStrings(config, "info")); err != nil {
+		return err
+	}
+	names := make(map[string]string)
+	for _, root := range config.TargetPaths {
+		name := filepath.Base(root)
+		if other, ok := names[name]; ok {
+			return fmt.Errorf("%s and %s would both be stored as data/%s", other, root, name)
+		}
+		names[name] = root
+	}
+	return nil
+}
+
+// bagInfoFields parses "Label=Value" settings into bag-info.txt fields
+func bagInfoFields(values []string) ([]bagit.Field, error) {
+	fields := make([]bagit.Field, 0, len(values))
+	for _, value := range values {
+		label, text, found := strings.Cut(value, "=")
+		label = strings.TrimSpace(label)
+		if !found || label == "" || strings.ContainsAny(label, ": \t") {
+			return nil, fmt.Errorf("invalid bag-info field %q, expected Label=Value such as Source-Organization=Example", value)
+		}
+		fields = append(fields, bagit.Field{Label: label, Value: strings.TrimSpace(text)})
+	}
+	return fields, nil
+}
+
+// BagExportOperation copies the selected files into a new BagIt bag for
+// hand-off to an archive or backup service. Every file is hashed before and
+// after copying, so the manifest describes what was read from the source.
+// The sources are left as they are.
+type BagExportOperation struct {
+	*BaseOperation
+	exported *pathList
+	size     int64
+}
+
+// NewBagExportOperation creates a new BagIt export operation
+func NewBagExportOperation(id string, config domain.OperationConfig, engine *Engine) *BagExportOperation {
+	base := NewBaseOperation(id, domain.OperationBagExport, config, engine)
+	return &BagExportOperation{
+		BaseOperation: base,
+		exported:      base.newPathList("exported"),
+	}
+}
+
+// bagFile is a file to export with its path inside the bag
+type bagFile struct {
+	info *domain.FileInfo
+	name string // Relative to the bag, e.g. data/photos/a.jpg
+}
+
+// Execute copies the selected files into the bag and writes its tag files
+func (be *BagExportOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	tracker := be.engine.progressTracker.StartOperation(be.id, domain.OperationBagExport, 3)
+	be.SetTracker(tracker)
+
+	destination := settingString(config, "destination", "")
+	algorithm := settingString(config, "algorithm", "sha256")
+	patterns := settingStrings(config, "patterns")
+	info, _ := bagInfoFields(settingStrings(config, "info"))
+
+	if be.engine.fileSystem.Exists(destination) {
+		empty, err := be.engine.fileSystem.IsEmpty(destination)
+		if err != nil || !empty {
+			return nil, fmt.Errorf("%s: %w", destination, os.ErrExist)
+		}
+	}
+
+	be.UpdateStep("Scanning files")
+
+	files := make([]bagFile, 0)
+	var totalSize int64
+	for _, root := range config.TargetPaths {
+		err := be.Walk(ctx, root, config, func(path string, info *domain.FileInfo, err error) error {
+			if err != nil {
+				be.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+				return nil // Continue walking
+			}
+			if info == nil {
+				return nil
+			}
+			if path != root && be.isExcluded(path, config) || info.IsDir && path == destination {
+				if info.IsDir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !os.FileMode(info.Mode).IsRegular() || !matchesAny(filepath.Base(path), patterns) {
+				return nil
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return nil
+			}
+			name := filepath.Join(bagit.PayloadDir, filepath.Base(root), rel)
+			if rel == "." {
+				name = filepath.Join(bagit.PayloadDir, filepath.Base(root))
+			}
+			files = append(files, bagFile{info: info, name: filepath.ToSlash(name)})
+			totalSize += info.Size
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk path %s: %w", root, err)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+
+	be.UpdateStep("Copying payload")
+	be.SetProgress(0, int64(len(files)), 0, totalSize)
+
+	manifest := make(bagit.Manifest, len(files))
+	for _, file := range files {
+		if err := be.CheckContext(ctx); err != nil {
+			return nil, err
+		}
+		if config.DryRun {
+			be.exported.Add(file.info.Path)
+			be.size += file.info.Size
+		} else if sum, err := be.copyFile(file, destination, algorithm); err != nil {
+			be.AddFileError(file.info.Path, fmt.Errorf("failed to export %s: %w", file.info.Path, err))
+		} else {
+			manifest[file.name] = sum
+			be.exported.Add(file.info.Path)
+			be.size += file.info.Size
+		}
+		be.IncrementProgress(1, file.info.Size)
+	}
+
+	be.UpdateStep("Writing tag files")
+
+	if !config.DryRun && be.exported.Len() > 0 {
+		info = append(info,
+			bagit.Field{Label: "Bagging-Date", Value: time.Now().Format(time.DateOnly)},
+			bagit.Field{Label: "Bag-Software-Agent", Value: "fileops"},
+			bagit.Field{Label: "Payload-Oxum", Value: fmt.Sprintf("%d.%d", be.size, be.exported.Len())},
+		)
+		err := bagit.Write(destination, algorithm, manifest, info, func(path string) (string, error) {
+			return be.engine.fileSystem.ComputeHash(path, algorithm)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to write the tag files of %s: %w", destination, err)
+		}
+		be.engine.logger.Info("Exported bag", "bag", destination, "files", be.exported.Len(), "bytes", be.size)
+	}
+
+	details := map[string]interface{}{
+		"exported":    be.exported.Result(),
+		"size":        be.size,
+		"destination": destination,
+		"algorithm":   algorithm,
+		"dry_run":     config.DryRun,
+	}
+
+	summary := fmt.Sprintf("Bag export: %d files (%d bytes) exported to %s", be.exported.Len(), be.size, destination)
+	if config.DryRun {
+		summary = fmt.Sprintf("Bag export (dry run): %d files (%d bytes) would be exported to %s", be.exported.Len(), be.size, destination)
+	}
+
+	return be.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// copyFile copies a file into the bag and returns its checksum. The copy is
+// removed again when it does not hash like its source.
+func (be *BagExportOperation) copyFile(file bagFile, destination, algorithm string) (string, error) {
+	sum, err := be.engine.fileSystem.ComputeHash(file.info.Path, algorithm)
+	if err != nil {
+		return "", err
+	}
+	target := filepath.Join(destination, filepath.FromSlash(file.name))
+	if err := be.engine.fileSystem.Copy(file.info.Path, target); err != nil {
+		return "", err
+	}
+	copied, err := be.engine.fileSystem.ComputeHash(target, algorithm)
+	if err == nil && copied != sum {
+		err = fmt.Errorf("the copy does not match its source (%s %s, copy %s)", algorithm, sum, copied)
+	}
+	if err != nil {
+		be.engine.fileSystem.Remove(target)
+		return "", err
+	}
+	return sum, nil
+}
+
+// Validate validates the BagIt export operation configuration
+func (be *BagExportOperation) Validate(config domain.OperationConfig) error {
+	return be.ValidateConfig()
+}
+
+// EstimateProgress provides an estimate of the operation's scope
+func (be *BagExportOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	return be.estimateScope(config, 3)
+}
+
+// BagValidateFactory creates BagIt validation operations
+type BagValidateFactory struct {
+	engine *Engine
+}
+
+// Create creates a new BagIt validation operation
+func (bf *BagValidateFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewBagValidateOperation(id, config, bf.engine), nil
+}
+
+// Validate validates the BagIt validation configuration
+func (bf *BagValidateFactory) Validate(config domain.OperationConfig) error {
+	if len(config.TargetPaths) == 0 {
+		return fmt.Errorf("at least one bag is required")
+	}
+	return nil
+}
+
+// BagValidateOperation checks bags against their manifests: every listed
+// file must exist with its checksum, every payload file must be listed and
+// the declared Payload-Oxum must match. With "fast" on only completeness and
+// the Payload-Oxum are checked, without reading the payload.
+type BagValidateOperation struct {
+	*BaseOperation
+	valid      *pathList
+	invalid    *pathList
+	missing    *pathList // Listed in a manifest, not in the bag
+	unlisted   *pathList // In the payload, not in a manifest
+	mismatched *pathList // Checksum differs from the manifest
+}
+
+// NewBagValidateOperation creates a new BagIt validation operation
+func NewBagValidateOperation(id string, config domain.OperationConfig, engine *Engine) *BagValidateOperation {
+	base := NewBaseOperation(id, domain.OperationBagValidate, config, engine)
+	return &BagValidateOperation{
+		BaseOperation: base,
+		valid:         base.newPathList("valid"),
+		invalid:       base.newPathList("invalid"),
+		missing:       base.newPathList("missing"),
+		unlisted:      base.newPathList("unlisted"),
+		mismatched:    base.newPathList("mismatched"),
+	}
+}
+
+// openedBag is a bag read for validation with its payload files
+type openedBag struct {
+	bag     *bagit.Bag
+	payload map[string]*domain.FileInfo // By path relative to the bag
+	size    int64
+}
+
+// Execute validates the configured bags
+func (bv *BagValidateOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	tracker := bv.engine.progressTracker.StartOperation(bv.id, domain.OperationBagValidate, 3)
+	bv.SetTracker(tracker)
+
+	fast := settingBool(config, "fast", false)
+
+	bv.UpdateStep("Reading bags")
+
+	bags := make([]*openedBag, 0, len(config.TargetPaths))
+	var totalFiles, totalSize int64
+	for _, dir := range config.TargetPaths {
+		if err := bv.CheckContext(ctx); err != nil {
+			return nil, err
+		}
+		opened, err := bv.open(ctx, dir, config)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			bv.AddFileError(dir, fmt.Errorf("failed to read bag %s: %w", dir, err))
+			bv.invalid.Add(dir)
+			continue
+		}
+		bags = append(bags, opened)
+		totalFiles += int64(len(opened.payload))
+		totalSize += opened.size
+	}
+
+	bv.UpdateStep("Verifying payloads")
+	if !fast {
+		bv.SetProgress(0, totalFiles, 0, totalSize)
+	}
+
+	for _, opened := range bags {
+		ok, err := bv.check(ctx, opened, fast)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			bv.valid.Add(opened.bag.Dir)
+		} else {
+			bv.invalid.Add(opened.bag.Dir)
+		}
+	}
+
+	bv.UpdateStep("Completing validation")
+
+	details := map[string]interface{}{
+		"valid":      bv.valid.Result(),
+		"invalid":    bv.invalid.Result(),
+		"missing":    bv.missing.Result(),
+		"unlisted":   bv.unlisted.Result(),
+		"mismatched": bv.mismatched.Result(),
+		"fast":       fast,
+	}
+
+	summary := fmt.Sprintf("Bag validation: %d valid, %d invalid (%d missing, %d unlisted, %d mismatched files)",
+		bv.valid.Len(), bv.invalid.Len(), bv.missing.Len(), bv.unlisted.Len(), bv.mismatched.Len())
+	if fast {
+		summary = fmt.Sprintf("Bag validation (fast, checksums not verified): %d complete, %d invalid (%d missing, %d unlisted files)",
+			bv.valid.Len(), bv.invalid.Len(), bv.missing.Len(), bv.unlisted.Len())
+	}
+
+	return bv.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// open reads the tag files of the bag in dir and lists its payload
+func (bv *BagValidateOperation) open(ctx context.Context, dir string, config domain.OperationConfig) (*openedBag, error) {
+	bag, err := bagit.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	opened := &openedBag{bag: bag, payload: make(map[string]*domain.FileInfo)}
+	payloadDir := filepath.Join(dir, bagit.PayloadDir)
+	if !bv.engine.fileSystem.Exists(payloadDir) {
+		return nil, fmt.Errorf("%w: no %s directory", bagit.ErrInvalid, bagit.PayloadDir)
+	}
+	walkConfig := config
+	walkConfig.ExcludePatterns = nil // Every payload file counts
+	err = bv.Walk(ctx, payloadDir, walkConfig, func(path string, info *domain.FileInfo, err error) error {
+		if err != nil {
+			bv.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+			return nil // Continue walking
+		}
+		if info == nil || info.IsDir {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil
+		}
+		opened.payload[filepath.ToSlash(rel)] = info
+		opened.size += info.Size
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return opened, nil
+}
+
+// check validates one bag and reports whether it is valid
+func (bv *BagValidateOperation) check(ctx context.Context, opened *openedBag, fast bool) (bool, error) {
+	bag := opened.bag
+	valid := true
+	fail := func(path string, list *pathList, err error) {
+		valid = false
+		if list != nil {
+			list.Add(path)
+		}
+		bv.AddFileError(path, fmt.Errorf("%w: %w", bagit.ErrInvalid, err))
+	}
+
+	// Completeness: the manifests and the payload list the same files
+	listed := make(map[string]bool)
+	for algorithm, manifest := range bag.Manifests {
+		for name := range manifest {
+			listed[name] = true
+			if !strings.HasPrefix(name, bagit.PayloadDir+"/") {
+				fail(filepath.Join(bag.Dir, name), nil, fmt.Errorf("manifest-%s.txt lists %s outside the payload", algorithm, name))
+			}
+		}
+		for name := range opened.payload {
+			if _, ok := manifest[name]; !ok {
+				fail(filepath.Join(bag.Dir, name), bv.unlisted, fmt.Errorf("%s is not listed in manifest-%s.txt", name, algorithm))
+			}
+		}
+	}
+	for name := range listed {
+		if _, ok := opened.payload[name]; !ok && strings.HasPrefix(name, bagit.PayloadDir+"/") {
+			fail(filepath.Join(bag.Dir, name), bv.missing, fmt.Errorf("%s is listed but missing", name))
+		}
+	}
+
+	octets, count, declared, err := bag.PayloadOxum()
+	if err != nil {
+		fail(bag.Dir, nil, err)
+	} else if declared && (octets != opened.size || count != int64(len(opened.payload))) {
+		fail(bag.Dir, nil, fmt.Errorf("Payload-Oxum declares %d.%d, the payload is %d.%d", octets, count, opened.size, len(opened.payload)))
+	}
+	if fast {
+		return valid, nil
+	}
+
+	// Checksums, of the tag files first
+	for algorithm, manifest := range bag.TagManifests {
+		for name, expected := range manifest {
+			path := filepath.Join(bag.Dir, filepath.FromSlash(name))
+			if sum, err := bv.engine.fileSystem.ComputeHash(path, algorithm); err != nil {
+				fail(path, bv.missing, fmt.Errorf("tag file %s: %w", name, err))
+			} else if sum != expected {
+				fail(path, bv.mismatched, fmt.Errorf("tag file %s does not match tagmanifest-%s.txt", name, algorithm))
+			}
+		}
+	}
+	names := make([]string, 0, len(opened.payload))
+	for name := range opened.payload {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := bv.CheckContext(ctx); err != nil {
+			return false, err
+		}
+		info := opened.payload[name]
+		for algorithm, manifest := range bag.Manifests {
+			expected, ok := manifest[name]
+			if !ok {
+				continue
+			}
+			sum, err := bv.engine.fileSystem.ComputeHash(info.Path, algorithm)
+			if err != nil {
+				valid = false
+				bv.AddFileError(info.Path, fmt.Errorf("failed to hash %s: %w", info.Path, err))
+				break
+			}
+			if sum != expected {
+				fail(info.Path, bv.mismatched, fmt.Errorf("%s does not match manifest-%s.txt", name, algorithm))
+				break
+			}
+		}
+		bv.IncrementProgress(1, info.Size)
+	}
+	return valid, nil
+}
+
+// Validate validates the BagIt validation operation configuration
+func (bv *BagValidateOperation) Validate(config domain.OperationConfig) error {
+	return bv.ValidateConfig()
+}
+
+// EstimateProgress provides an estimate of the operation's scope
+func (bv *BagValidateOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	return bv.estimateScope(config, 3)
+}
@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// DeviceThroughput is the throughput fileops bench measured on one device
+type DeviceThroughput struct {
+	Path                string    `json:"path"`
+	Device              uint64    `json:"device"`
+	ReadBytesPerSecond  float64   `json:"read_bytes_per_second"`
+	WriteBytesPerSecond float64   `json:"write_bytes_per_second"`
+	Measured            time.Time `json:"measured"`
+}
+
+// BenchProfile holds the last measurement of every device fileops bench
+// measured, from which dry runs estimate how long the real run takes
+type BenchProfile struct {
+	Devices []DeviceThroughput `json:"devices"`
+}
+
+// LoadBenchProfile reads the profile at path. A missing file is an empty
+// profile.
+func LoadBenchProfile(path string) (*BenchProfile, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &BenchProfile{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var profile BenchProfile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("invalid bench profile %s: %w", path, err)
+	}
+	return &profile, nil
+}
+
+// Save writes the profile to path
+func (p *BenchProfile) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return filesystem.WriteFileAtomic(path, append(data, '\n'), 0644)
+}
+
+// Record stores a measurement, replacing the earlier one of the same device
+func (p *BenchProfile) Record(measured DeviceThroughput) {
+	for i, device := range p.Devices {
+		if device.Device == measured.Device {
+			p.Devices[i] = measured
+			return
+		}
+	}
+	p.Devices = append(p.Devices, measured)
+}
+
+// Throughput returns the measurement of a device
+func (p *BenchProfile) Throughput(device uint64) (DeviceThroughput, bool) {
+	if p != nil {
+		for _, measured := range p.Devices {
+			if measured.Device == device {
+				return measured, true
+			}
+		}
+	}
+	return DeviceThroughput{}, false
+}
@@ -0,0 +1,262 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/a4abhishek/fileops/internal/integrity"
+	"github.com/a4abhishek/fileops/internal/stub"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// ChecksumFactory creates checksum sidecar operations
+type ChecksumFactory struct {
+	engine *Engine
+}
+
+// Create creates a new checksum operation
+func (cf *ChecksumFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewChecksumOperation(id, config, cf.engine), nil
+}
+
+// Validate validates the checksum configuration
+func (cf *ChecksumFactory) Validate(config domain.OperationConfig) error {
+	if len(config.TargetPaths) == 0 {
+		return fmt.Errorf("at least one path is required")
+	}
+	if _, err := filesystem.NewHasher(settingString(config, "algorithm", "sha256")); err != nil {
+		return err
+	}
+	switch settingString(config, "layout", integrity.LayoutFile) {
+	case integrity.LayoutFile, integrity.LayoutDirectory:
+	default:
+		return fmt.Errorf("layout must be %q or %q", integrity.LayoutFile, integrity.LayoutDirectory)
+	}
+	if redundancy := settingInt(config, "redundancy", 10); redundancy > 100 {
+		return fmt.Errorf("redundancy must be between 1 and 100 percent")
+	}
+	return nil
+}
+
+// ChecksumOperation records the checksum of every file in a sidecar or a
+// per-directory manifest and optionally writes recovery data next to it. Runs
+// are incremental: a recorded checksum is only replaced when the file was
+// modified after it was written, so bit-rot is never silently accepted.
+// Stub files left for offloaded content are skipped; their checksum is the one
+// they carry.
+type ChecksumOperation struct {
+	*BaseOperation
+	written   *pathList
+	upToDate  *pathList
+	parity    *pathList
+	corrupt   *pathList
+	offloaded *pathList
+}
+
+// NewChecksumOperation creates a new checksum operation
+func NewChecksumOperation(id string, config domain.OperationConfig, engine *Engine) *ChecksumOperation {
+	base := NewBaseOperation(id, domain.OperationChecksum, config, engine)
+	return &ChecksumOperation{
+		BaseOperation: base,
+		written:       base.newPathList("written"),
+		upToDate:      base.newPathList("up_to_date"),
+		parity:        base.newPathList("parity"),
+		corrupt:       base.newPathList("corrupt"),
+		offloaded:     base.newPathList("offloaded"),
+	}
+}
+
+// Execute checksums all files below the configured paths
+func (co *ChecksumOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	tracker := co.engine.progressTracker.StartOperation(co.id, domain.OperationChecksum, 3)
+	co.SetTracker(tracker)
+
+	algorithm := settingString(config, "algorithm", "sha256")
+	layout := settingString(config, "layout", integrity.LayoutFile)
+	withParity := settingBool(config, "parity", false)
+	redundancy := settingInt(config, "redundancy", 10)
+	force := settingBool(config, "force", false)
+
+	co.UpdateStep("Scanning files")
+
+	files, totalSize, err := co.scan(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	co.UpdateStep("Computing checksums")
+	co.SetProgress(0, int64(len(files)), 0, totalSize)
+
+	catalog := integrity.NewCatalog()
+	manifests := make(map[string]map[string]string) // Directory -> updated manifest entries
+
+	for _, info := range files {
+		if err := co.CheckContext(ctx); err != nil {
+			co.writeManifests(manifests, algorithm, config)
+			return nil, err
+		}
+
+		recorded, found, err := catalog.Lookup(info.Path, algorithm)
+		if err != nil {
+			co.AddFileError(info.Path, fmt.Errorf("failed to read checksum of %s: %w", info.Path, err))
+			co.IncrementProgress(1, info.Size)
+			continue
+		}
+		current := found && !force && !config.MTimeTolerance.After(info.ModTime, recorded.Recorded)
+		needParity := withParity && !parityCurrent(info, config.MTimeTolerance)
+		if current && !needParity {
+			co.upToDate.Add(info.Path)
+			co.IncrementProgress(1, info.Size)
+			continue
+		}
+
+		co.planRead(info.Path, info.Size)
+		sum, err := co.engine.fileSystem.ComputeHash(info.Path, algorithm)
+		if err != nil {
+			co.AddFileError(info.Path, fmt.Errorf("failed to hash %s: %w", info.Path, err))
+			co.IncrementProgress(1, info.Size)
+			continue
+		}
+		if current && sum != recorded.Sum {
+			// Protecting rotten data would make the damage permanent
+			co.AddFileError(info.Path, fmt.Errorf("%w: %s does not match %s, run repair", integrity.ErrCorrupt, info.Path, recorded.Source))
+			co.corrupt.Add(info.Path)
+			co.IncrementProgress(1, info.Size)
+			continue
+		}
+
+		if current {
+			co.upToDate.Add(info.Path)
+		} else {
+			co.written.Add(info.Path)
+			if layout == integrity.LayoutDirectory {
+				dir := filepath.Dir(info.Path)
+				if manifests[dir] == nil {
+					if manifests[dir], err = catalog.Manifest(dir, algorithm); err != nil {
+						manifests[dir] = make(map[string]string)
+					}
+				}
+				manifests[dir][filepath.Base(info.Path)] = sum
+			} else if !config.DryRun {
+				if err := integrity.WriteSidecar(info.Path, algorithm, sum); err != nil {
+					co.AddFileError(info.Path, fmt.Errorf("failed to write checksum of %s: %w", info.Path, err))
+				}
+			}
+		}
+
+		if needParity {
+			co.parity.Add(info.Path)
+			co.planRead(info.Path, info.Size)
+			co.planWrite(info.Path, info.Size*int64(redundancy)/100)
+			if !config.DryRun {
+				if err := integrity.CreateParity(info.Path, redundancy); err != nil {
+					co.AddFileError(info.Path, fmt.Errorf("failed to create recovery data for %s: %w", info.Path, err))
+				}
+			}
+		}
+		co.IncrementProgress(1, info.Size)
+	}
+
+	co.UpdateStep("Writing manifests")
+	co.writeManifests(manifests, algorithm, config)
+
+	details := map[string]interface{}{
+		"written":    co.written.Result(),
+		"up_to_date": co.upToDate.Result(),
+		"parity":     co.parity.Result(),
+		"corrupt":    co.corrupt.Result(),
+		"offloaded":  co.offloaded.Result(),
+		"algorithm":  algorithm,
+		"layout":     layout,
+		"dry_run":    config.DryRun,
+	}
+
+	summary := fmt.Sprintf("Checksums: %d written, %d up to date, %d recovery files created, %d corrupt",
+		co.written.Len(), co.upToDate.Len(), co.parity.Len(), co.corrupt.Len())
+	if config.DryRun {
+		summary = fmt.Sprintf("Checksums (dry run): %d would be written, %d up to date, %d recovery files would be created, %d corrupt",
+			co.written.Len(), co.upToDate.Len(), co.parity.Len(), co.corrupt.Len())
+	}
+	if offloaded := co.offloaded.Len(); offloaded > 0 {
+		summary += fmt.Sprintf(", %d offloaded skipped", offloaded)
+	}
+
+	return co.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// writeManifests writes the updated per-directory manifests, dropping entries
+// of files that no longer exist
+func (co *ChecksumOperation) writeManifests(manifests map[string]map[string]string, algorithm string, config domain.OperationConfig) {
+	if config.DryRun {
+		return
+	}
+	for dir, sums := range manifests {
+		for name := range sums {
+			if _, err := os.Stat(filepath.Join(dir, name)); os.IsNotExist(err) {
+				delete(sums, name)
+			}
+		}
+		if err := integrity.WriteManifest(dir, algorithm, sums); err != nil {
+			co.AddFileError(dir, fmt.Errorf("failed to write checksum manifest in %s: %w", dir, err))
+		}
+	}
+}
+
+// Validate validates the checksum operation configuration
+func (co *ChecksumOperation) Validate(config domain.OperationConfig) error {
+	return co.ValidateConfig()
+}
+
+// EstimateProgress provides an estimate of the operation's scope
+func (co *ChecksumOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	return co.estimateScope(config, 3)
+}
+
+// scan collects the files to protect below all roots, skipping existing
+// sidecars, manifests, recovery files and stubs
+func (co *ChecksumOperation) scan(ctx context.Context, config domain.OperationConfig) ([]*domain.FileInfo, int64, error) {
+	files := make([]*domain.FileInfo, 0)
+	var totalSize int64
+
+	for _, root := range config.TargetPaths {
+		err := co.Walk(ctx, root, config, func(path string, info *domain.FileInfo, err error) error {
+			if err != nil {
+				co.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+				return nil // Continue walking
+			}
+			if info == nil {
+				return nil
+			}
+			if co.isExcluded(path, config) {
+				if info.IsDir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir || integrity.IsSidecar(path) {
+				return nil
+			}
+			if stub.Is(path, info.Size) {
+				co.offloaded.Add(path)
+				return nil
+			}
+			files = append(files, info)
+			totalSize += info.Size
+			return nil
+		})
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to walk path %s: %w", root, err)
+		}
+	}
+	return files, totalSize, nil
+}
+
+// parityCurrent reports whether the recovery data of a file was written after
+// its last modification
+func parityCurrent(info *domain.FileInfo, tolerance domain.TimeTolerance) bool {
+	parity, err := os.Stat(integrity.ParityPath(info.Path))
+	return err == nil && !tolerance.After(info.ModTime, parity.ModTime())
+}
@@ -0,0 +1,369 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// CleanerTarget is a well-known cache or junk location of a cleaner profile
+type CleanerTarget struct {
+	Name      string
+	Rationale string   // Why removing it is safe
+	Paths     []string // Directories whose contents are removed; "~/" is the home directory, globs allowed
+
+	// find computes the items to remove instead of Paths
+	find func(config domain.OperationConfig) ([]string, error)
+	// roots returns the directories find looks into, for locking
+	roots func(config domain.OperationConfig) []string
+}
+
+// CleanerProfile is a named group of targets selected with clean --profile
+type CleanerProfile struct {
+	Name        string
+	Description string
+	Targets     []CleanerTarget
+}
+
+// CleanerTargetResult reports what was (or would be) removed from a target
+type CleanerTargetResult struct {
+	Profile   string `json:"profile"`
+	Target    string `json:"target"`
+	Rationale string `json:"rationale"`
+	Items     int    `json:"items"`
+	Files     int64  `json:"files"`
+	Size      int64  `json:"size"`
+}
+
+// dockerOrphanAge is how long an unreferenced overlay2 directory must be
+// untouched; pulls and builds create directories before registering them
+const dockerOrphanAge = 7 * 24 * time.Hour
+
+// cleanerProfiles are the built-in profiles. Targets only name caches that
+// their owner rebuilds on demand; nothing holding user data or settings.
+var cleanerProfiles = []CleanerProfile{
+	{
+		Name:        "dev-caches",
+		Description: "Package manager and build caches of development tools",
+		Targets: []CleanerTarget{
+			{
+				Name:      "npm cache",
+				Rationale: "Content-addressed download cache; npm fetches missing packages again on the next install. Installed node_modules are not touched.",
+				Paths:     []string{"~/.npm/_cacache"},
+			},
+			{
+				Name:      "Yarn cache",
+				Rationale: "Copies of downloaded packages that Yarn refills on demand. Projects and their node_modules are not touched.",
+				Paths:     []string{"~/.cache/yarn", "~/Library/Caches/Yarn"},
+			},
+			{
+				Name:      "pip cache",
+				Rationale: "Downloaded wheels and HTTP responses; installed packages live in site-packages and are not touched.",
+				Paths:     []string{"~/.cache/pip", "~/Library/Caches/pip"},
+			},
+			{
+				Name:      "Go build cache",
+				Rationale: "Compiler output that go build regenerates; the module cache is kept.",
+				Paths:     []string{"~/.cache/go-build", "~/Library/Caches/go-build"},
+			},
+			{
+				Name:      "Xcode DerivedData",
+				Rationale: "Intermediate build products and indexes Xcode rebuilds per project. Quit Xcode first, open projects write here.",
+				Paths:     []string{"~/Library/Developer/Xcode/DerivedData"},
+			},
+		},
+	},
+	{
+		Name:        "docker",
+		Description: "Leftovers of the Docker overlay2 storage driver",
+		Targets: []CleanerTarget{
+			{
+				Name: "Docker overlay leftovers",
+				Rationale: "Layer directories no image, container or build cache refers to, left behind by interrupted pulls and removals. " +
+					"Only directories untouched for 7 days are considered; requires access to the Docker data root.",
+				find:  dockerOverlayOrphans,
+				roots: func(config domain.OperationConfig) []string { return []string{dockerOverlayDir(config)} },
+			},
+		},
+	},
+	{
+		Name:        "thumbnails",
+		Description: "Thumbnail caches of desktop environments",
+		Targets: []CleanerTarget{
+			{
+				Name:      "Thumbnail cache",
+				Rationale: "Previews of images and videos that file managers generate again when a folder is opened.",
+				Paths:     []string{"~/.cache/thumbnails", "~/.thumbnails"},
+			},
+		},
+	},
+	{
+		Name:        "browser-caches",
+		Description: "HTTP caches of web browsers",
+		Targets: []CleanerTarget{
+			{
+				Name: "Chrome and Chromium cache",
+				Rationale: "Downloaded web content the browser fetches again while browsing. Profiles, history, cookies and passwords are stored elsewhere " +
+					"and not touched. Close the browser first.",
+				Paths: []string{
+					"~/.cache/google-chrome/*/Cache", "~/.cache/google-chrome/*/Code Cache",
+					"~/.cache/chromium/*/Cache", "~/.cache/chromium/*/Code Cache",
+					"~/Library/Caches/Google/Chrome/*/Cache", "~/Library/Caches/Google/Chrome/*/Code Cache",
+				},
+			},
+			{
+				Name: "Firefox cache",
+				Rationale: "Downloaded web content the browser fetches again while browsing. Profiles, history, cookies and passwords are stored elsewhere " +
+					"and not touched. Close the browser first.",
+				Paths: []string{"~/.cache/mozilla/firefox/*/cache2", "~/Library/Caches/Firefox/Profiles/*/cache2"},
+			},
+		},
+	},
+}
+
+// CleanerProfiles returns the built-in cleaner profiles
+func CleanerProfiles() []CleanerProfile {
+	return cleanerProfiles
+}
+
+// findCleanerProfile returns the profile with the given name
+func findCleanerProfile(name string) (CleanerProfile, bool) {
+	for _, profile := range cleanerProfiles {
+		if profile.Name == name {
+			return profile, true
+		}
+	}
+	return CleanerProfile{}, false
+}
+
+// cleanerHome returns the home directory target paths are relative to
+func cleanerHome(config domain.OperationConfig) string {
+	if home := settingString(config, "home", ""); home != "" {
+		return home
+	}
+	home, _ := os.UserHomeDir()
+	return home
+}
+
+// directories returns the existing directories matching the target paths.
+// Symlinked directories are skipped so a link cannot redirect the removal.
+func (t CleanerTarget) directories(config domain.OperationConfig) []string {
+	home := cleanerHome(config)
+	dirs := make([]string, 0)
+	for _, pattern := range t.Paths {
+		if rest, ok := strings.CutPrefix(pattern, "~/"); ok {
+			if home == "" {
+				continue
+			}
+			pattern = filepath.Join(home, rest)
+		}
+		matches, _ := filepath.Glob(pattern)
+		for _, match := range matches {
+			if info, err := os.Lstat(match); err == nil && info.IsDir() {
+				dirs = append(dirs, match)
+			}
+		}
+	}
+	return dirs
+}
+
+// items returns the paths to remove: the entries of the target directories,
+// which are kept themselves, or what find computes
+func (t CleanerTarget) items(config domain.OperationConfig) ([]string, error) {
+	if t.find != nil {
+		return t.find(config)
+	}
+	items := make([]string, 0)
+	for _, dir := range t.directories(config) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			items = append(items, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return items, nil
+}
+
+// cleanerProfileRoots returns the directories the configured profiles remove from
+func cleanerProfileRoots(config domain.OperationConfig) []string {
+	roots := make([]string, 0)
+	for _, name := range settingStrings(config, "profiles") {
+		profile, _ := findCleanerProfile(name)
+		for _, target := range profile.Targets {
+			if target.roots != nil {
+				roots = append(roots, target.roots(config)...)
+			} else {
+				roots = append(roots, target.directories(config)...)
+			}
+		}
+	}
+	return roots
+}
+
+// cleanProfiles estimates the size of every target of the configured
+// profiles and removes their items unless running dry
+func (co *CleanupOperation) cleanProfiles(ctx context.Context, config domain.OperationConfig) error {
+	for _, name := range settingStrings(config, "profiles") {
+		profile, _ := findCleanerProfile(name)
+		for _, target := range profile.Targets {
+			if err := co.CheckContext(ctx); err != nil {
+				return err
+			}
+
+			items, err := target.items(config)
+			if err != nil {
+				co.AddFileError(target.Name, fmt.Errorf("failed to list %s: %w", target.Name, err))
+				continue
+			}
+
+			result := CleanerTargetResult{Profile: profile.Name, Target: target.Name, Rationale: target.Rationale}
+			for _, item := range items {
+				if co.isExcluded(item, config) || !co.engine.gitAllows(item, true, config.GitFilter) {
+					continue
+				}
+				if action := config.ExtensionAction(item); action == domain.ExtensionSkip || action == domain.ExtensionKeep {
+					continue
+				}
+				files, size := co.measure(ctx, item)
+				co.planFree(item, size)
+				if !config.DryRun {
+					if err := co.engine.fileSystem.RemoveAll(item); err != nil {
+						co.AddFileError(item, fmt.Errorf("failed to remove %s: %w", item, err))
+						continue
+					}
+					co.RecordDeletion(item, size)
+					co.engine.logger.Info("Removed cache item", "target", target.Name, "path", item)
+				}
+				result.Items++
+				result.Files += files
+				result.Size += size
+				co.IncrementProgress(1, size)
+			}
+			co.profileResults = append(co.profileResults, result)
+		}
+	}
+	return nil
+}
+
+// measure returns the number and total size of the files below path. As with
+// measureDir, exclusions and filters do not apply: removing path takes all of it.
+func (co *CleanupOperation) measure(ctx context.Context, path string) (int64, int64) {
+	var files, size int64
+	_ = co.engine.Walk(ctx, path, domain.OperationConfig{}, func(_ string, info *domain.FileInfo, err error) error {
+		if err == nil && info != nil && !info.IsDir {
+			files++
+			size += info.Size
+		}
+		return nil
+	})
+	return files, size
+}
+
+// dockerOverlayDir returns the overlay2 directory of the Docker data root
+func dockerOverlayDir(config domain.OperationConfig) string {
+	return filepath.Join(settingString(config, "docker_root", "/var/lib/docker"), "overlay2")
+}
+
+// overlayIDPattern matches the layer directories of the overlay2 driver
+var overlayIDPattern = regexp.MustCompile(`^[0-9a-f]{64}(-init)?$`)
+
+// dockerOverlayOrphans returns the overlay2 layer directories that neither
+// the layer database nor the BuildKit cache refers to. Unreadable metadata is
+// an error rather than an empty reference list, so nothing in use is removed.
+func dockerOverlayOrphans(config domain.OperationConfig) ([]string, error) {
+	root := settingString(config, "docker_root", "/var/lib/docker")
+	overlay := dockerOverlayDir(config)
+	entries, err := os.ReadDir(overlay)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Images reference their layers by cache-id, containers by mount-id and
+	// init-id. Layers without a layer database are of an unknown layout or a
+	// damaged install, not orphans.
+	referenced := make(map[string]bool)
+	layerDB := filepath.Join(root, "image", "overlay2", "layerdb")
+	if _, err := os.Stat(layerDB); err != nil {
+		for _, entry := range entries {
+			if overlayIDPattern.MatchString(entry.Name()) {
+				return nil, fmt.Errorf("overlay2 has layers but the layer database cannot be read: %w", err)
+			}
+		}
+		return nil, nil
+	}
+	for _, kind := range []struct{ dir, files string }{
+		{"sha256", "cache-id"},
+		{"mounts", "mount-id init-id"},
+	} {
+		records, err := os.ReadDir(filepath.Join(layerDB, kind.dir))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read the layer database: %w", err)
+		}
+		for _, record := range records {
+			for _, file := range strings.Fields(kind.files) {
+				data, err := os.ReadFile(filepath.Join(layerDB, kind.dir, record.Name(), file))
+				if os.IsNotExist(err) {
+					continue
+				}
+				if err != nil {
+					return nil, fmt.Errorf("failed to read the layer database: %w", err)
+				}
+				referenced[strings.TrimSpace(string(data))] = true
+			}
+		}
+	}
+
+	// BuildKit keeps its references in bolt databases; look for the IDs verbatim
+	var buildkit [][]byte
+	databases, _ := filepath.Glob(filepath.Join(root, "buildkit", "*.db"))
+	for _, database := range databases {
+		data, err := os.ReadFile(database)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the BuildKit cache database: %w", err)
+		}
+		buildkit = append(buildkit, data)
+	}
+
+	cutoff := time.Now().Add(-dockerOrphanAge)
+	orphans := make([]string, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		id := strings.TrimSuffix(name, "-init")
+		if !entry.IsDir() || !overlayIDPattern.MatchString(name) || referenced[name] || referenced[id] {
+			continue
+		}
+		if info, err := entry.Info(); err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		inBuildkit := false
+		for _, data := range buildkit {
+			if bytes.Contains(data, []byte(id)) {
+				inBuildkit = true
+				break
+			}
+		}
+		if inBuildkit {
+			continue
+		}
+		orphans = append(orphans, filepath.Join(overlay, name))
+		// Each layer has a short name in l/ that links to it
+		if link, err := os.ReadFile(filepath.Join(overlay, name, "link")); err == nil {
+			if short := strings.TrimSpace(string(link)); short != "" && !strings.ContainsAny(short, `/\.`) {
+				orphans = append(orphans, filepath.Join(overlay, "l", short))
+			}
+		}
+	}
+	return orphans, nil
+}
@@ -21,74 +21,214 @@ func (cf *CleanupFactory) Create(id string, config domain.OperationConfig) (doma
 
 // Validate validates the cleanup configuration
 func (cf *CleanupFactory) Validate(config domain.OperationConfig) error {
-	// Cleanup-specific validation can be added here
-	return nil
+	for _, name := range settingStrings(config, "profiles") {
+		if _, ok := findCleanerProfile(name); !ok {
+			return fmt.Errorf("unknown cleaner profile %q", name)
+		}
+	}
+	for _, rule := range cleanupRules(config) {
+		if rule != RuleEmptyDirs && rule != RulePartialDownloads && rule != RuleBuildArtifacts {
+			return fmt.Errorf("unknown cleanup rule %q (use %s, %s or %s)", rule, RuleEmptyDirs, RulePartialDownloads, RuleBuildArtifacts)
+		}
+	}
+	if hasRule(config, RuleBuildArtifacts) && settingBool(config, "skip_build_outputs", false) {
+		return fmt.Errorf("the %s rule cannot be combined with skipping build outputs", RuleBuildArtifacts)
+	}
+	return validateExtensions(config)
 }
 
-// CleanupOperation implements directory cleanup functionality
+// CleanupOperation implements directory cleanup functionality. The rule sets
+// in the "rules" setting select what is removed: empty directories and/or
+// abandoned partial downloads and/or build outputs of projects. Files whose
+// extension is configured with the delete action are removed as well.
 type CleanupOperation struct {
 	*BaseOperation
-	removedDirs   []string
-	skippedDirs   []string
-	totalDirs     int64
-	processedDirs int64
+	removedDirs     *pathList
+	skippedDirs     *pathList
+	totalDirs       int64
+	processedDirs   int64
+	removedPartials *pathList
+	activePartials  *pathList
+	partialBytes    int64
+	profileResults  []CleanerTargetResult
+	artifacts       []BuildArtifact
+	artifactBytes   int64
+
+	removedByExtension *pathList
+	extensionBytes     int64
 }
 
 // NewCleanupOperation creates a new cleanup operation
 func NewCleanupOperation(id string, config domain.OperationConfig, engine *Engine) *CleanupOperation {
 	base := NewBaseOperation(id, domain.OperationCleanup, config, engine)
 	return &CleanupOperation{
-		BaseOperation: base,
-		removedDirs:   make([]string, 0),
-		skippedDirs:   make([]string, 0),
+		BaseOperation:      base,
+		removedDirs:        base.newPathList("removed_directories"),
+		skippedDirs:        base.newPathList("skipped_directories"),
+		removedPartials:    base.newPathList("partial_downloads"),
+		activePartials:     base.newPathList("active_downloads"),
+		removedByExtension: base.newPathList("extension_files"),
+		artifacts:          make([]BuildArtifact, 0),
 	}
 }
 
 // Execute performs the cleanup operation
 func (co *CleanupOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	if len(settingStrings(config, "profiles")) > 0 {
+		return co.executeProfiles(ctx, config)
+	}
+
 	// Start tracking progress
-	tracker := co.engine.progressTracker.StartOperation(co.id, domain.OperationCleanup, 4)
+	steps := 1
+	if hasRule(config, RulePartialDownloads) {
+		steps++
+	}
+	if hasRule(config, RuleBuildArtifacts) {
+		steps++
+	}
+	deleteByExtension := hasExtensionAction(config, domain.ExtensionDelete)
+	if deleteByExtension {
+		steps++
+	}
+	if hasRule(config, RuleEmptyDirs) {
+		steps += 3
+	}
+	tracker := co.engine.progressTracker.StartOperation(co.id, domain.OperationCleanup, steps)
 	co.SetTracker(tracker)
 
-	tracker.UpdateStep("Scanning directories")
+	// Partial downloads go first so that directories they leave empty are removed too
+	if hasRule(config, RulePartialDownloads) {
+		co.UpdateStep("Detecting partial downloads")
 
-	// First pass: count total directories for progress tracking
-	if err := co.countDirectories(ctx, config); err != nil {
-		return nil, fmt.Errorf("failed to count directories: %w", err)
+		partials, err := co.findPartialDownloads(ctx, config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find partial downloads: %w", err)
+		}
+		if err := co.processPartialDownloads(ctx, config, partials); err != nil {
+			return nil, fmt.Errorf("failed to remove partial downloads: %w", err)
+		}
 	}
 
-	tracker.UpdateStep("Identifying empty directories")
-	tracker.SetTotals(co.totalDirs, 0)
+	if hasRule(config, RuleBuildArtifacts) {
+		co.UpdateStep("Detecting build outputs")
 
-	// Find empty directories (bottom-up approach)
-	emptyDirs, err := co.findEmptyDirectories(ctx, config)
-	if err != nil {
-		return nil, fmt.Errorf("failed to find empty directories: %w", err)
+		artifacts, err := co.findBuildArtifacts(ctx, config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find build outputs: %w", err)
+		}
+		if err := co.processBuildArtifacts(ctx, config, artifacts); err != nil {
+			return nil, fmt.Errorf("failed to remove build outputs: %w", err)
+		}
 	}
 
-	tracker.UpdateStep("Processing empty directories")
+	if deleteByExtension {
+		co.UpdateStep("Detecting files by extension")
 
-	// Process empty directories
-	if err := co.processEmptyDirectories(ctx, config, emptyDirs); err != nil {
-		return nil, fmt.Errorf("failed to process empty directories: %w", err)
+		files, err := co.findExtensionDeletions(ctx, config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find files by extension: %w", err)
+		}
+		if err := co.processExtensionDeletions(ctx, config, files); err != nil {
+			return nil, fmt.Errorf("failed to remove files by extension: %w", err)
+		}
 	}
 
-	tracker.UpdateStep("Completing cleanup")
+	if hasRule(config, RuleEmptyDirs) {
+		co.UpdateStep("Scanning directories")
+
+		// First pass: count total directories for progress tracking
+		if err := co.countDirectories(ctx, config); err != nil {
+			return nil, fmt.Errorf("failed to count directories: %w", err)
+		}
+
+		co.UpdateStep("Identifying empty directories")
+		co.SetTotals(co.totalDirs, 0)
+
+		// Find empty directories (bottom-up approach)
+		emptyDirs, err := co.findEmptyDirectories(ctx, config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find empty directories: %w", err)
+		}
+
+		co.UpdateStep("Processing empty directories")
+
+		// Process empty directories
+		if err := co.processEmptyDirectories(ctx, config, emptyDirs); err != nil {
+			return nil, fmt.Errorf("failed to process empty directories: %w", err)
+		}
+	}
+
+	co.UpdateStep("Completing cleanup")
 
 	// Create result
 	details := map[string]interface{}{
-		"removed_directories": co.removedDirs,
-		"skipped_directories": co.skippedDirs,
+		"removed_directories": co.removedDirs.Result(),
+		"skipped_directories": co.skippedDirs.Result(),
 		"total_directories":   co.totalDirs,
+		"partial_downloads":   co.removedPartials.Result(),
+		"active_downloads":    co.activePartials.Result(),
+		"partial_bytes":       co.partialBytes,
+		"build_artifacts":     co.artifacts,
+		"artifact_bytes":      co.artifactBytes,
+		"extension_files":     co.removedByExtension.Result(),
+		"extension_bytes":     co.extensionBytes,
+		"rules":               cleanupRules(config),
 		"dry_run":             config.DryRun,
 	}
 
-	summary := fmt.Sprintf("Cleanup completed: %d directories removed, %d skipped",
-		len(co.removedDirs), len(co.skippedDirs))
+	verb, prefix := "removed", "Cleanup completed"
+	if config.DryRun {
+		verb, prefix = "would be removed", "Cleanup (dry run)"
+	}
+	parts := make([]string, 0, 4)
+	if hasRule(config, RulePartialDownloads) {
+		parts = append(parts, fmt.Sprintf("%d partial downloads %s, %d still active",
+			co.removedPartials.Len(), verb, co.activePartials.Len()))
+	}
+	if hasRule(config, RuleBuildArtifacts) {
+		parts = append(parts, fmt.Sprintf("%d build output directories %s", len(co.artifacts), verb))
+	}
+	if deleteByExtension {
+		parts = append(parts, fmt.Sprintf("%d files %s by extension", co.removedByExtension.Len(), verb))
+	}
+	if hasRule(config, RuleEmptyDirs) {
+		parts = append(parts, fmt.Sprintf("%d directories %s, %d skipped",
+			co.removedDirs.Len(), verb, co.skippedDirs.Len()))
+	}
+	summary := prefix + ": " + strings.Join(parts, ", ")
+
+	return co.CreateResult(domain.StatusCompleted, summary, details), nil
+}
 
+// executeProfiles clears the targets of the configured cleaner profiles
+func (co *CleanupOperation) executeProfiles(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	tracker := co.engine.progressTracker.StartOperation(co.id, domain.OperationCleanup, 2)
+	co.SetTracker(tracker)
+
+	co.UpdateStep("Clearing cache locations")
+	if err := co.cleanProfiles(ctx, config); err != nil {
+		return nil, fmt.Errorf("failed to clean profiles: %w", err)
+	}
+
+	co.UpdateStep("Completing cleanup")
+
+	var items int
+	var size int64
+	for _, result := range co.profileResults {
+		items += result.Items
+		size += result.Size
+	}
+
+	details := map[string]interface{}{
+		"profiles":        settingStrings(config, "profiles"),
+		"profile_targets": co.profileResults,
+		"profile_bytes":   size,
+		"dry_run":         config.DryRun,
+	}
+
+	summary := fmt.Sprintf("Cleanup completed: %d items removed from %d cache locations", items, len(co.profileResults))
 	if config.DryRun {
-		summary = fmt.Sprintf("Cleanup (dry run): %d directories would be removed, %d skipped",
-			len(co.removedDirs), len(co.skippedDirs))
+		summary = fmt.Sprintf("Cleanup (dry run): %d items would be removed from %d cache locations", items, len(co.profileResults))
 	}
 
 	return co.CreateResult(domain.StatusCompleted, summary, details), nil
@@ -101,19 +241,17 @@ func (co *CleanupOperation) Validate(config domain.OperationConfig) error {
 
 // EstimateProgress provides an estimate of the operation's scope
 func (co *CleanupOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
-	// This is a simplified estimate - in a real implementation, we might do a quick scan
-	return &domain.ProgressInfo{
-		ID:            co.id,
-		OperationType: domain.OperationCleanup,
-		Status:        domain.StatusPending,
-		TotalSteps:    4,
-		TotalItems:    100, // Estimated
-	}, nil
+	estimate, err := co.estimateScope(config, 4)
+	if err != nil {
+		return nil, err
+	}
+	estimate.TotalItems = estimate.Details["directories"].(int64) // Cleanup processes directories
+	return estimate, nil
 }
 
 // countDirectories counts the total number of directories for progress tracking
 func (co *CleanupOperation) countDirectories(ctx context.Context, config domain.OperationConfig) error {
-	pathsToProcess := config.IncludePatterns
+	pathsToProcess := config.TargetPaths
 	if len(pathsToProcess) == 0 {
 		// If no paths specified, we can't proceed
 		return fmt.Errorf("no paths specified for cleanup")
@@ -126,7 +264,7 @@ func (co *CleanupOperation) countDirectories(ctx context.Context, config domain.
 			return err
 		}
 
-		err := co.engine.fileSystem.Walk(ctx, rootPath, func(path string, info *domain.FileInfo, err error) error {
+		err := co.engine.Walk(ctx, rootPath, config, func(path string, info *domain.FileInfo, err error) error {
 			if err != nil {
 				return nil // Skip errors during counting
 			}
@@ -155,7 +293,7 @@ func (co *CleanupOperation) countDirectories(ctx context.Context, config domain.
 // findEmptyDirectories finds all empty directories using bottom-up traversal
 func (co *CleanupOperation) findEmptyDirectories(ctx context.Context, config domain.OperationConfig) ([]string, error) {
 	emptyDirs := make([]string, 0)
-	pathsToProcess := config.IncludePatterns
+	pathsToProcess := config.TargetPaths
 
 	for _, rootPath := range pathsToProcess {
 		rootEmptyDirs, err := co.findEmptyDirectoriesInPath(ctx, rootPath, config)
@@ -174,9 +312,9 @@ func (co *CleanupOperation) findEmptyDirectoriesInPath(ctx context.Context, root
 	dirContents := make(map[string][]string)
 
 	// First, build a map of directory contents
-	err := co.engine.fileSystem.Walk(ctx, rootPath, func(path string, info *domain.FileInfo, err error) error {
+	err := co.Walk(ctx, rootPath, config, func(path string, info *domain.FileInfo, err error) error {
 		if err != nil {
-			co.AddError(fmt.Errorf("error accessing %s: %w", path, err))
+			co.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
 			return nil // Continue processing
 		}
 
@@ -237,7 +375,7 @@ func (co *CleanupOperation) processEmptyDirectories(ctx context.Context, config
 
 		if co.shouldProcessDirectory(dir, config) {
 			if config.DryRun {
-				co.removedDirs = append(co.removedDirs, dir)
+				co.removedDirs.Add(dir)
 				co.engine.logger.Info("Would remove empty directory", "path", dir)
 			} else {
 				// Create backup if requested
@@ -248,15 +386,16 @@ func (co *CleanupOperation) processEmptyDirectories(ctx context.Context, config
 
 				// Remove the directory
 				if err := co.engine.fileSystem.Remove(dir); err != nil {
-					co.AddError(fmt.Errorf("failed to remove directory %s: %w", dir, err))
-					co.skippedDirs = append(co.skippedDirs, dir)
+					co.AddFileError(dir, fmt.Errorf("failed to remove directory %s: %w", dir, err))
+					co.skippedDirs.Add(dir)
 				} else {
-					co.removedDirs = append(co.removedDirs, dir)
+					co.removedDirs.Add(dir)
+					co.RecordDeletion(dir, 0)
 					co.engine.logger.Info("Removed empty directory", "path", dir)
 				}
 			}
 		} else {
-			co.skippedDirs = append(co.skippedDirs, dir)
+			co.skippedDirs.Add(dir)
 		}
 
 		co.IncrementProgress(1, 0)
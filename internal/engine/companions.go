@@ -0,0 +1,250 @@
+package engine
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// rawExtensions are the camera RAW formats shot alongside a JPEG
+var rawExtensions = map[string]bool{
+	".cr2": true, ".cr3": true, ".crw": true, ".nef": true, ".nrw": true, ".arw": true,
+	".srf": true, ".sr2": true, ".dng": true, ".raf": true, ".orf": true, ".rw2": true,
+	".pef": true, ".srw": true, ".x3f": true, ".3fr": true, ".iiq": true,
+}
+
+// sidecarExtensions are files that describe another file and mean nothing
+// without it: XMP edits, Apple photo adjustments, subtitles and media info
+var sidecarExtensions = map[string]bool{
+	".xmp": true, ".aae": true, ".srt": true, ".vtt": true, ".ass": true,
+	".ssa": true, ".sub": true, ".idx": true, ".nfo": true,
+}
+
+// subtitleExtensions may carry a language before the extension (movie.en.srt)
+var subtitleExtensions = map[string]bool{
+	".srt": true, ".vtt": true, ".ass": true, ".ssa": true, ".sub": true, ".idx": true,
+}
+
+// liveStillExtensions are the stills of a Live Photo, whose motion part is a
+// .mov of the same name
+var liveStillExtensions = map[string]bool{".heic": true, ".jpg": true, ".jpeg": true}
+
+// errSidecarsLeft is returned for files not deleted because their sidecars
+// would be left behind and deleting sidecars was not asked for
+var errSidecarsLeft = errors.New("not deleted, its sidecars would be left behind")
+
+// isRawPhoto reports whether path has a camera RAW extension
+func isRawPhoto(path string) bool {
+	return rawExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// isPairJPEG reports whether path is a JPEG that may have a RAW sibling
+func isPairJPEG(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".jpg" || ext == ".jpeg"
+}
+
+// companions finds the files that belong with a primary file in the same
+// directory:
+//
+//   - the RAW+JPEG siblings cameras write side by side (IMG_0042.CR2 and
+//     IMG_0042.JPG). Such pairs are one photo: neither half is removed as a
+//     duplicate of the other or left behind when the other is moved.
+//   - sidecars: IMG_0042.xmp or IMG_0042.JPG.xmp, Apple's IMG_0042.AAE or
+//     IMG_O0042.AAE, movie.srt or movie.en.srt, movie.nfo, and the .mov of a
+//     Live Photo. Sidecars travel with their primary when it is moved and are
+//     only deleted with it when deleting sidecars was asked for.
+//
+// Directory listings are cached; it is safe for concurrent use.
+type companions struct {
+	mu   sync.Mutex
+	dirs map[string][]string // Names in each listed directory
+}
+
+func newCompanions() *companions {
+	return &companions{dirs: make(map[string][]string)}
+}
+
+// sibling returns the other half of the RAW+JPEG pair path belongs to
+func (p *companions) sibling(path string) (string, bool) {
+	raw, jpeg := isRawPhoto(path), isPairJPEG(path)
+	if !raw && !jpeg {
+		return "", false
+	}
+	dir, name := filepath.Split(path)
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	for _, other := range p.names(filepath.Clean(dir)) {
+		if other == name || !strings.EqualFold(strings.TrimSuffix(other, filepath.Ext(other)), stem) {
+			continue
+		}
+		if (raw && isPairJPEG(other)) || (jpeg && isRawPhoto(other)) {
+			return filepath.Join(dir, other), true
+		}
+	}
+	return "", false
+}
+
+// sidecars returns the sidecars of path
+func (p *companions) sidecars(path string) []string {
+	if sidecarExtensions[strings.ToLower(filepath.Ext(path))] {
+		return nil
+	}
+	dir, name := filepath.Split(path)
+	sidecars := make([]string, 0)
+	for _, other := range p.names(filepath.Clean(dir)) {
+		if other != name && sidecarOf(name, other) {
+			sidecars = append(sidecars, filepath.Join(dir, other))
+		}
+	}
+	return sidecars
+}
+
+// primary returns the file the sidecar path belongs to. A sidecar shared by
+// both halves of a RAW+JPEG pair belongs to the JPEG.
+func (p *companions) primary(path string) (string, bool) {
+	if !isSidecar(path) {
+		return "", false
+	}
+	dir, name := filepath.Split(path)
+	primary := ""
+	for _, other := range p.names(filepath.Clean(dir)) {
+		if other == name || sidecarExtensions[strings.ToLower(filepath.Ext(other))] || !sidecarOf(other, name) {
+			continue
+		}
+		if primary == "" || p.follows(filepath.Join(dir, primary)) {
+			primary = other
+		}
+	}
+	if primary == "" {
+		return "", false
+	}
+	return filepath.Join(dir, primary), true
+}
+
+// group returns the files that go wherever path goes: the RAW of a JPEG,
+// the sidecars of both and the sidecars of a Live Photo's .mov
+func (p *companions) group(path string) []string {
+	group := make([]string, 0)
+	seen := map[string]bool{path: true}
+	add := func(paths ...string) {
+		for _, companion := range paths {
+			if !seen[companion] {
+				seen[companion] = true
+				group = append(group, companion)
+			}
+		}
+	}
+	if raw, ok := p.sibling(path); ok && isRawPhoto(raw) {
+		add(raw)
+	}
+	add(p.sidecars(path)...)
+	for i := 0; i < len(group); i++ {
+		add(p.sidecars(group[i])...)
+	}
+	return group
+}
+
+// follows reports whether path goes wherever another file goes: the RAW half
+// of a RAW+JPEG pair or a sidecar
+func (p *companions) follows(path string) bool {
+	_, ok := p.leader(path)
+	return ok
+}
+
+// leader returns the file path goes with: the JPEG of a RAW or the primary
+// of a sidecar
+func (p *companions) leader(path string) (string, bool) {
+	if jpeg, ok := p.sibling(path); ok && isRawPhoto(path) {
+		return jpeg, true
+	}
+	return p.primary(path)
+}
+
+// hasSidecars reports whether paths include a sidecar rather than only the
+// halves of a RAW+JPEG pair
+func hasSidecars(paths []string) bool {
+	for _, path := range paths {
+		if isSidecar(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSidecar reports whether path may be the sidecar of another file
+func isSidecar(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return sidecarExtensions[ext] || ext == ".mov"
+}
+
+// sidecarOf reports whether the file named sidecar belongs to the one named
+// primary
+func sidecarOf(primary, sidecar string) bool {
+	ext := strings.ToLower(filepath.Ext(sidecar))
+	base := strings.TrimSuffix(sidecar, filepath.Ext(sidecar))
+	stem := strings.TrimSuffix(primary, filepath.Ext(primary))
+	switch {
+	case ext == ".mov":
+		return liveStillExtensions[strings.ToLower(filepath.Ext(primary))] && strings.EqualFold(base, stem)
+	case !sidecarExtensions[ext]:
+		return false
+	case strings.EqualFold(base, stem) || strings.EqualFold(base, primary):
+		return true
+	case ext == ".aae":
+		// Photos exports the adjustments of IMG_0042 as IMG_O0042.AAE
+		if prefix, number, ok := strings.Cut(stem, "_"); ok {
+			return strings.EqualFold(base, prefix+"_O"+number)
+		}
+	case subtitleExtensions[ext]:
+		language := filepath.Ext(base)
+		return len(language) >= 3 && len(language) <= 7 &&
+			strings.EqualFold(strings.TrimSuffix(base, language), stem)
+	}
+	return false
+}
+
+// companionTarget returns where companion goes when primary moves to target:
+// next to it, renamed along with it
+func companionTarget(primary, companion, target string) string {
+	stem := strings.TrimSuffix(filepath.Base(primary), filepath.Ext(primary))
+	newStem := strings.TrimSuffix(filepath.Base(target), filepath.Ext(target))
+	name := filepath.Base(companion)
+	if prefix, number, ok := strings.Cut(stem, "_"); ok && strings.EqualFold(name, prefix+"_O"+number+filepath.Ext(name)) {
+		// IMG_O0042.AAE follows IMG_0042 as IMG_O0042_1.AAE
+		if newPrefix, newNumber, ok := strings.Cut(newStem, "_"); ok {
+			stem, newStem = prefix+"_O"+number, newPrefix+"_O"+newNumber
+		}
+	}
+	if len(name) >= len(stem) && strings.EqualFold(name[:len(stem)], stem) {
+		name = newStem + name[len(stem):]
+	}
+	return filepath.Join(filepath.Dir(target), name)
+}
+
+// names lists dir once
+func (p *companions) names(dir string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if names, ok := p.dirs[dir]; ok {
+		return names
+	}
+	names := make([]string, 0)
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+	p.dirs[dir] = names
+	return names
+}
+
+// forget drops the cached listing of dir after files were moved in or out
+func (p *companions) forget(dir string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.dirs, filepath.Clean(dir))
+}
@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// ErrNoInput is returned when an operation is fed an empty file set
+var ErrNoInput = errors.New("the input file set is empty")
+
+// ResultFiles returns the complete file set a result lists under key, e.g.
+// the unique_files of a deduplication or the partial_downloads of a cleanup.
+// Lists too long for the result are read from the file they were stored in.
+func ResultFiles(result *domain.OperationResult, key string) ([]string, error) {
+	value, ok := result.Details[key]
+	if !ok {
+		return nil, fmt.Errorf("%s result %s has no %s", result.OperationType, result.ID, key)
+	}
+
+	var list domain.PathList
+	switch value := value.(type) {
+	case domain.PathList:
+		list = value
+	case []string:
+		return value, nil
+	default:
+		// Results loaded from the repository hold the decoded JSON
+		data, err := json.Marshal(value)
+		if err == nil {
+			err = json.Unmarshal(data, &list)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s of %s result %s is not a file list", key, result.OperationType, result.ID)
+		}
+	}
+
+	if list.Count <= len(list.Paths) {
+		return list.Paths, nil
+	}
+	if list.File == "" {
+		return nil, fmt.Errorf("only %d of the %d %s of result %s were stored", len(list.Paths), list.Count, key, result.ID)
+	}
+	file, err := os.Open(list.File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s of result %s: %w", key, result.ID, err)
+	}
+	defer file.Close()
+
+	paths := make([]string, 0, list.Count)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			paths = append(paths, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s of result %s: %w", key, result.ID, err)
+	}
+	return paths, nil
+}
+
+// FeedFiles restricts config to files, typically the ResultFiles of an
+// earlier operation, so an operation processes only what another produced.
+// Files outside the target paths are not visited; without target paths the
+// files themselves are the targets.
+func FeedFiles(config domain.OperationConfig, files []string) (domain.OperationConfig, error) {
+	if len(files) == 0 {
+		return config, ErrNoInput
+	}
+	if len(config.TargetPaths) == 0 {
+		config.TargetPaths = append([]string{}, files...)
+	}
+	config.OnlyPaths = append([]string{}, files...)
+	return config, nil
+}
@@ -0,0 +1,326 @@
+package engine
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Variant keep policies, choosing which of a file and its identical
+// compressed variant is kept
+const (
+	DedupVariantKeepCompressed = "compressed" // Keep file.txt.gz, the smaller one
+	DedupVariantKeepPlain      = "plain"      // Keep file.txt, readable without decompressing
+)
+
+// variantFormats maps the extensions of compressed variants to their format
+var variantFormats = map[string]string{
+	".gz":  "gzip",
+	".zst": "zstd",
+	".xz":  "xz",
+	".bz2": "bzip2",
+}
+
+// CompressedVariant is a file next to a compressed file with the same
+// content once decompressed, e.g. file.txt and file.txt.gz
+type CompressedVariant struct {
+	Plain          string `json:"plain"`
+	Compressed     string `json:"compressed"`
+	Format         string `json:"format"`
+	Size           int64  `json:"size"` // Size of the plain file
+	CompressedSize int64  `json:"compressed_size"`
+	Keep           string `json:"keep"`
+	Removed        bool   `json:"removed,omitempty"`
+}
+
+// compareVariants finds the compressed files among inodes whose plain
+// variant was scanned too and compares their decompressed content with it.
+// With remove_variants the variant the keep policy does not keep is deleted.
+func (do *DeduplicationOperation) compareVariants(ctx context.Context, config domain.OperationConfig, inodes []*dedupInode, algorithm string) ([]CompressedVariant, error) {
+	byPath := make(map[string]*dedupInode)
+	for _, inode := range inodes {
+		if inode.attachment {
+			continue
+		}
+		for _, path := range inode.paths {
+			byPath[path] = inode
+		}
+	}
+
+	// Each compressed inode is compared with the first plain file found for it
+	type candidate struct {
+		plain, compressed         *dedupInode
+		plainPath, compressedPath string
+		format                    string
+	}
+	candidates := make(map[*dedupInode]*candidate)
+	order := make([]*dedupInode, 0)
+	unhashed := make([]*dedupInode, 0)
+	queued := make(map[*dedupInode]bool)
+	for _, inode := range inodes {
+		if inode.attachment {
+			continue
+		}
+		for _, path := range inode.paths {
+			ext := strings.ToLower(filepath.Ext(path))
+			format, ok := variantFormats[ext]
+			if !ok {
+				continue
+			}
+			plainPath := path[:len(path)-len(ext)]
+			plain, ok := byPath[plainPath]
+			if !ok || plain == inode {
+				continue
+			}
+			candidates[inode] = &candidate{plain: plain, compressed: inode, plainPath: plainPath, compressedPath: path, format: format}
+			order = append(order, inode)
+			if plain.hash == "" && !queued[plain] {
+				queued[plain] = true
+				unhashed = append(unhashed, plain)
+			}
+			break
+		}
+	}
+
+	var total int64
+	for _, inode := range unhashed {
+		total += inode.info.Size
+	}
+	for _, inode := range order {
+		total += inode.info.Size
+	}
+	do.SetProgress(0, int64(len(unhashed)+len(order)), 0, total)
+
+	// Plain files without a same sized duplicate were not hashed yet
+	if err := do.hashAll(ctx, config, unhashed, algorithm); err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	matched := make(map[*dedupInode]bool)
+	err := do.forEach(ctx, config, order, func(inode *dedupInode) {
+		defer do.IncrementProgress(1, inode.info.Size)
+		c := candidates[inode]
+		if c.plain.hash == "" {
+			return // Hashing the plain file failed
+		}
+		hash, err := hashDecompressed(ctx, c.compressedPath, c.format, algorithm, c.plain.info.Size)
+		if errors.Is(err, errLargerThanPlain) {
+			return
+		}
+		if err != nil {
+			do.AddFileError(c.compressedPath, fmt.Errorf("failed to decompress %s: %w", c.compressedPath, err))
+			return
+		}
+		if hash == c.plain.hash {
+			mu.Lock()
+			matched[inode] = true
+			mu.Unlock()
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keep := settingString(config, "keep_variant", DedupVariantKeepCompressed)
+	remove := settingBool(config, "remove_variants", false)
+	variants := make([]CompressedVariant, 0, len(matched))
+	for _, inode := range order {
+		if !matched[inode] {
+			continue
+		}
+		c := candidates[inode]
+		variant := CompressedVariant{
+			Plain:          c.plainPath,
+			Compressed:     c.compressedPath,
+			Format:         c.format,
+			Size:           c.plain.info.Size,
+			CompressedSize: c.compressed.info.Size,
+			Keep:           c.compressedPath,
+		}
+		other, otherInode := c.plainPath, c.plain
+		if keep == DedupVariantKeepPlain {
+			variant.Keep, other, otherInode = c.plainPath, c.compressedPath, c.compressed
+		}
+		do.variantSaveable += otherInode.info.Size
+
+		if remove && do.kept(other, algorithm, "") {
+			do.pinned.Add(other)
+		} else if remove && filesystem.HashConfidence(algorithm) < do.minConfidence {
+			// Matched by the hash of the decompressed content
+			do.unsure.Add(other)
+		} else if remove && len(otherInode.paths) > 1 {
+			// Other links keep the content, deleting one name frees nothing
+			do.variantsLinked.Add(other)
+		} else if remove && config.DryRun {
+			do.planFree(other, otherInode.info.Size)
+		} else if remove {
+			if err := do.removeVariant(ctx, c.plainPath, c.compressedPath, c.format, other, otherInode.info.Size); err != nil {
+				do.AddFileError(other, fmt.Errorf("failed to remove %s: %w", other, err))
+			} else {
+				variant.Removed = true
+			}
+		}
+		variants = append(variants, variant)
+	}
+	sort.Slice(variants, func(i, j int) bool { return variants[i].Plain < variants[j].Plain })
+	return variants, nil
+}
+
+// errLargerThanPlain stops decompressing a variant that cannot match
+var errLargerThanPlain = errors.New("decompressed content is larger than the plain file")
+
+// hashDecompressed streams the decompressed content of path into a hasher.
+// It gives up as soon as the content exceeds limit bytes.
+func hashDecompressed(ctx context.Context, path, format, algorithm string, limit int64) (string, error) {
+	hasher, err := filesystem.NewHasher(algorithm)
+	if err != nil {
+		return "", err
+	}
+	reader, err := openDecompressed(ctx, path, format)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	n, err := io.Copy(hasher, io.LimitReader(reader, limit+1))
+	if err != nil {
+		return "", err
+	}
+	if n > limit {
+		return "", errLargerThanPlain
+	}
+	return fmt.Sprintf("%x", hasher.Sum(nil)), nil
+}
+
+// removeVariant deletes path, one of plain and its compressed variant, after
+// comparing the decompressed content with the plain file byte by byte
+func (do *DeduplicationOperation) removeVariant(ctx context.Context, plain, compressed, format, path string, size int64) error {
+	reader, err := openDecompressed(ctx, compressed, format)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(plain)
+	if err != nil {
+		reader.Close()
+		return err
+	}
+	same, err := sameStream(bufio.NewReader(reader), bufio.NewReader(file))
+	file.Close()
+	reader.Close()
+	if err != nil {
+		return err
+	}
+	if !same {
+		return errors.New("content changed since it was hashed")
+	}
+	if err := do.engine.fileSystem.Remove(path); err != nil {
+		return err
+	}
+	do.RecordDeletion(path, size)
+	return nil
+}
+
+// openDecompressed opens path and decompresses it as format. xz has no
+// decoder in the standard library and is read through the xz command.
+func openDecompressed(ctx context.Context, path, format string) (io.ReadCloser, error) {
+	if format == "xz" {
+		xz, err := exec.LookPath("xz")
+		if err != nil {
+			return nil, errors.New("xz is not installed")
+		}
+		cmd := exec.CommandContext(ctx, xz, "--decompress", "--stdout", "--", path)
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, err
+		}
+		return &commandReader{ReadCloser: stdout, cmd: cmd}, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case "gzip":
+		reader, err := gzip.NewReader(bufio.NewReader(file))
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &decompressedFile{Reader: reader, file: file}, nil
+	case "zstd":
+		decoder, err := zstd.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &decompressedFile{Reader: decoder, file: file, close: decoder.Close}, nil
+	case "bzip2":
+		return &decompressedFile{Reader: bzip2.NewReader(bufio.NewReader(file)), file: file}, nil
+	}
+	file.Close()
+	return nil, fmt.Errorf("unsupported compression %q", format)
+}
+
+// decompressedFile closes the decoder and the file below it
+type decompressedFile struct {
+	io.Reader
+	file  *os.File
+	close func()
+}
+
+// Close releases the decoder and closes the file
+func (d *decompressedFile) Close() error {
+	if d.close != nil {
+		d.close()
+	}
+	return d.file.Close()
+}
+
+// commandReader reads the output of a decompressing command. The command
+// failing (e.g. on a truncated file) is an error of the last read.
+type commandReader struct {
+	io.ReadCloser
+	cmd  *exec.Cmd
+	done bool
+}
+
+// Read reads the output and waits for the command at its end
+func (c *commandReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	if err == io.EOF && !c.done {
+		c.done = true
+		if waitErr := c.cmd.Wait(); waitErr != nil {
+			return n, fmt.Errorf("%s: %w", filepath.Base(c.cmd.Path), waitErr)
+		}
+	}
+	return n, err
+}
+
+// Close stops the command once its output is no longer needed
+func (c *commandReader) Close() error {
+	if !c.done {
+		c.done = true
+		c.cmd.Process.Kill()
+		c.cmd.Wait()
+	}
+	return nil
+}
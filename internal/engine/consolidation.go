@@ -0,0 +1,465 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// ConsolidationFactory creates consolidation operations
+type ConsolidationFactory struct {
+	engine *Engine
+}
+
+// Create creates a new consolidation operation
+func (cf *ConsolidationFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewConsolidationOperation(id, config, cf.engine), nil
+}
+
+// Validate validates the consolidation configuration
+func (cf *ConsolidationFactory) Validate(config domain.OperationConfig) error {
+	if len(config.TargetPaths) == 0 {
+		return fmt.Errorf("at least one source path is required")
+	}
+	if settingString(config, "destination", "") == "" {
+		return fmt.Errorf("destination parameter is required")
+	}
+
+	switch settingString(config, "conflict_resolution", "skip") {
+	case "skip", "overwrite", "rename":
+	default:
+		return fmt.Errorf("unsupported conflict resolution: %s", config.CustomSettings["conflict_resolution"])
+	}
+
+	switch link := settingString(config, "link", ""); link {
+	case "":
+	case LinkHardlink, LinkReflink:
+		if settingBool(config, "move", false) {
+			return fmt.Errorf("files are either moved or linked, not both")
+		}
+	default:
+		return fmt.Errorf("unsupported link strategy: %s", link)
+	}
+
+	return nil
+}
+
+// ConsolidationOperation implements file consolidation functionality
+type ConsolidationOperation struct {
+	*BaseOperation
+	movedFiles   *pathList
+	copiedFiles  *pathList
+	skippedFiles *pathList
+	mu           sync.Mutex
+}
+
+// NewConsolidationOperation creates a new consolidation operation
+func NewConsolidationOperation(id string, config domain.OperationConfig, engine *Engine) *ConsolidationOperation {
+	base := NewBaseOperation(id, domain.OperationConsolidation, config, engine)
+	return &ConsolidationOperation{
+		BaseOperation: base,
+		movedFiles:    base.newPathList("moved_files"),
+		copiedFiles:   base.newPathList("copied_files"),
+		skippedFiles:  base.newPathList("skipped_files"),
+	}
+}
+
+// Execute performs the consolidation operation
+func (co *ConsolidationOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	// Start tracking progress
+	tracker := co.engine.progressTracker.StartOperation(co.id, domain.OperationConsolidation, 5)
+	co.SetTracker(tracker)
+
+	co.UpdateStep("Planning consolidation")
+
+	plan, jobs, err := co.buildPlan(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan consolidation: %w", err)
+	}
+
+	co.UpdateStep("Checking destination space")
+
+	strategy := "copy"
+	if settingBool(config, "move", false) {
+		strategy = "move"
+	} else if link := settingString(config, "link", ""); link != "" {
+		strategy = link
+	}
+	spaceMode := settingString(config, "space_check", SpaceCheckRefuse)
+	if config.DryRun && spaceMode == SpaceCheckRefuse {
+		spaceMode = SpaceCheckWarn // Dry runs only report
+	}
+
+	spaceEstimate, err := co.engine.CheckDiskSpace(plan.Destination, jobs, strategy, spaceMode)
+	if err != nil {
+		return nil, err
+	}
+	if spaceEstimate != nil && !spaceEstimate.Sufficient {
+		co.AddWarning(spaceEstimate.Destination, domain.WarningInsufficientSpace, fmt.Sprintf("insufficient space at %s: %d bytes required, %d bytes available",
+			spaceEstimate.Destination, spaceEstimate.Required, spaceEstimate.Available))
+	}
+
+	co.UpdateStep("Transferring files")
+	co.SetProgress(0, int64(len(jobs)), 0, plan.TotalSize)
+
+	scheduler := NewCopyScheduler(
+		settingInt(config, "source_concurrency", 2),
+		settingInt(config, "destination_concurrency", 2),
+		config.DryRun,
+	)
+
+	// Stop scheduling new transfers as soon as the error budget is exhausted
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	gate := co.workerGate(settingInt(config, "destination_concurrency", 2))
+	err = scheduler.Run(runCtx, jobs, func(ctx context.Context, job CopyJob) error {
+		if err := co.CheckContext(ctx); err != nil {
+			return err
+		}
+		if config.DryRun {
+			if job.Link == "" || !sameDevice(job.Source, job.Target) {
+				co.planTransfer(job.Source, job.Target, job.Size, job.Move)
+			}
+			return nil
+		}
+		gate.Enter(ctx)
+		defer gate.Leave()
+		return co.transfer(job)
+	}, func(job CopyJob, err error) {
+		co.recordTransfer(job, err, config.DryRun)
+		if co.ErrorBudgetErr() != nil {
+			cancel()
+		}
+	})
+	if budgetErr := co.ErrorBudgetErr(); budgetErr != nil {
+		return nil, budgetErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	co.UpdateStep("Completing consolidation")
+
+	details := map[string]interface{}{
+		"moved_files":             co.movedFiles.Result(),
+		"copied_files":            co.copiedFiles.Result(),
+		"skipped_files":           co.skippedFiles.Result(),
+		"conflicts":               plan.Conflicts,
+		"normalization_conflicts": plan.NormalizationConflicts,
+		"destination":             plan.Destination,
+		"total_files":             plan.TotalFiles,
+		"total_size":              plan.TotalSize,
+		"dry_run":                 config.DryRun,
+	}
+	if spaceEstimate != nil {
+		details["space_estimate"] = spaceEstimate
+	}
+
+	verb := "copied"
+	transferred := co.copiedFiles.Len()
+	if settingBool(config, "move", false) {
+		verb = "moved"
+		transferred = co.movedFiles.Len()
+	}
+
+	summary := fmt.Sprintf("Consolidation completed: %d files %s, %d skipped", transferred, verb, co.skippedFiles.Len())
+	if config.DryRun {
+		summary = fmt.Sprintf("Consolidation (dry run): %d files would be %s, %d skipped", transferred, verb, co.skippedFiles.Len())
+	}
+
+	return co.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// buildPlan walks all sources and produces the list of transfers to perform
+func (co *ConsolidationOperation) buildPlan(ctx context.Context, config domain.OperationConfig) (*domain.ConsolidationPlan, []CopyJob, error) {
+	destination := settingString(config, "destination", "")
+	preserveStructure := settingBool(config, "preserve_structure", false)
+	move := settingBool(config, "move", false)
+	link := settingString(config, "link", "")
+	resolution := settingString(config, "conflict_resolution", "skip")
+
+	operation := "copy"
+	if move {
+		operation = "move"
+	}
+
+	plan := &domain.ConsolidationPlan{
+		ID:          co.id,
+		Strategy:    map[bool]string{true: "preserve-structure", false: "flatten"}[preserveStructure],
+		Destination: destination,
+		Operations:  make([]domain.ConsolidationOperation, 0),
+		Conflicts:   make([]domain.ConflictResolution, 0),
+	}
+
+	jobs := make([]CopyJob, 0)
+
+	var labels labelDestination
+	if move {
+		labels = newLabelDestination(destination)
+	}
+
+	// Targets are compared by their normalized names, so a name written
+	// differently by another system still conflicts
+	policy := config.UnicodeNormalization
+	plannedTargets := make(map[string]string) // Normalized target -> source planned there
+	names := filesystem.NewNameIndex()
+	existing := func(target string) (string, bool) {
+		if policy == filesystem.NormalizeNone {
+			return target, co.engine.fileSystem.Exists(target)
+		}
+		return names.Lookup(target)
+	}
+	taken := func(target string) bool {
+		_, exists := existing(target)
+		return exists || plannedTargets[targetKey(target, policy)] != ""
+	}
+
+	related := newCompanions()
+	for _, root := range config.TargetPaths {
+		err := co.Walk(ctx, root, config, func(path string, info *domain.FileInfo, err error) error {
+			if err != nil {
+				co.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+				return nil // Continue walking
+			}
+
+			if info == nil {
+				return nil
+			}
+
+			// Never consolidate the destination into itself
+			if path == destination || strings.HasPrefix(path, destination+string(filepath.Separator)) {
+				if info.IsDir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if info.IsDir || co.isExcluded(path, config) {
+				return nil
+			}
+
+			// RAW halves and sidecars are planned with the file they belong to
+			if leader, ok := related.leader(path); ok && !co.isExcluded(leader, config) {
+				return nil
+			}
+			group := make([]string, 0)
+			for _, companion := range related.group(path) {
+				if !co.isExcluded(companion, config) {
+					group = append(group, companion)
+				}
+			}
+
+			target := filepath.Join(destination, filesystem.NormalizeName(info.Name, policy))
+			if preserveStructure {
+				rel, err := filepath.Rel(root, path)
+				if err != nil {
+					rel = info.Name
+				}
+				target = filepath.Join(destination, filesystem.NormalizeName(filepath.Join(filepath.Base(root), rel), policy))
+			}
+
+			// The name the target conflicts with, planned or on disk
+			conflictName, conflictPath := "", target
+			if other := plannedTargets[targetKey(target, policy)]; other != "" {
+				conflictName = filepath.Base(other)
+			} else if onDisk, exists := existing(target); exists {
+				conflictName, conflictPath = filepath.Base(onDisk), onDisk
+			}
+
+			if conflictName != "" {
+				conflict := domain.ConflictResolution{
+					SourcePath: path,
+					TargetPath: conflictPath,
+					Resolution: resolution,
+				}
+				conflicts := &plan.Conflicts
+				if filesystem.NormalizationOnly(conflictName, info.Name) {
+					conflicts = &plan.NormalizationConflicts
+				}
+				switch resolution {
+				case "skip":
+					*conflicts = append(*conflicts, conflict)
+					co.skippedFiles.Add(path)
+					for _, companion := range group {
+						co.skippedFiles.Add(companion)
+					}
+					return nil
+				case "rename":
+					target = co.uniqueTarget(target, func(target string) bool {
+						if taken(target) {
+							return true
+						}
+						for _, companion := range group {
+							if taken(companionTarget(path, companion, target)) {
+								return true
+							}
+						}
+						return false
+					})
+					conflict.NewName = filepath.Base(target)
+				default:
+					target = conflictPath // Overwrite the entry under the name it has
+				}
+				*conflicts = append(*conflicts, conflict)
+			}
+
+			plannedTargets[targetKey(target, policy)] = path
+			if move {
+				co.checkSecurityLabels(info, labels, config)
+			}
+			plan.Operations = append(plan.Operations, domain.ConsolidationOperation{
+				SourcePath: path,
+				TargetPath: target,
+				Operation:  operation,
+				Reason:     "consolidate into " + destination,
+			})
+			plan.TotalFiles++
+			plan.TotalSize += info.Size
+
+			jobs = append(jobs, CopyJob{
+				Source:     path,
+				SourceRoot: root,
+				Target:     target,
+				Size:       info.Size,
+				Move:       move,
+				Link:       link,
+			})
+
+			for _, companion := range group {
+				companionInfo, err := co.engine.fileSystem.Stat(companion)
+				if err != nil {
+					co.AddFileError(companion, fmt.Errorf("error accessing %s: %w", companion, err))
+					continue
+				}
+				companionPath := companionTarget(path, companion, target)
+				plannedTargets[targetKey(companionPath, policy)] = companion
+				plan.Operations = append(plan.Operations, domain.ConsolidationOperation{
+					SourcePath: companion,
+					TargetPath: companionPath,
+					Operation:  operation,
+					Reason:     "travels with " + info.Name,
+				})
+				plan.TotalFiles++
+				plan.TotalSize += companionInfo.Size
+
+				jobs = append(jobs, CopyJob{
+					Source:     companion,
+					SourceRoot: root,
+					Target:     companionPath,
+					Size:       companionInfo.Size,
+					Move:       move,
+					Link:       link,
+				})
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to walk path %s: %w", root, err)
+		}
+	}
+
+	return plan, jobs, nil
+}
+
+// transfer copies or moves a single file into place
+func (co *ConsolidationOperation) transfer(job CopyJob) error {
+	if err := co.engine.fileSystem.CreateDir(filepath.Dir(job.Target)); err != nil {
+		return err
+	}
+
+	if job.Link != "" && co.link(job) == nil {
+		return nil
+	}
+	if !job.Move {
+		return co.copyWarning(job.Target, co.engine.fileSystem.Copy(job.Source, job.Target))
+	}
+
+	return co.move(job.Source, job.Target)
+}
+
+// link links the target of a job to its source by the strategy of the job,
+// atomically replacing a target that is overwritten
+func (co *ConsolidationOperation) link(job CopyJob) error {
+	fileSystem := co.engine.fileSystem
+	tmp := job.Target + ".fileops-link"
+	if err := fileSystem.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if job.Link == LinkReflink {
+		if err := filesystem.CloneFile(job.Source, tmp); err != nil {
+			return err
+		}
+		info, err := os.Stat(job.Source)
+		if err == nil {
+			err = os.Chmod(tmp, info.Mode().Perm())
+		}
+		if err != nil {
+			_ = fileSystem.Remove(tmp)
+			return err
+		}
+	} else if err := fileSystem.Link(job.Source, tmp); err != nil {
+		return err
+	}
+	if err := fileSystem.Move(tmp, job.Target); err != nil {
+		_ = fileSystem.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// recordTransfer records the outcome of a single transfer
+func (co *ConsolidationOperation) recordTransfer(job CopyJob, err error, dryRun bool) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+
+	if errors.Is(err, ErrErrorBudgetExceeded) || errors.Is(err, context.Canceled) {
+		// Aborted before the transfer started; not a failure of this file
+		co.skippedFiles.Add(job.Source)
+		return
+	}
+
+	if err != nil {
+		co.AddFileError(job.Source, fmt.Errorf("failed to transfer %s: %w", job.Source, err))
+		co.skippedFiles.Add(job.Source)
+		co.IncrementProgress(1, 0)
+		return
+	}
+
+	if job.Move {
+		co.movedFiles.Add(job.Source)
+	} else {
+		co.copiedFiles.Add(job.Source)
+	}
+	co.IncrementProgress(1, job.Size)
+
+	if dryRun {
+		co.engine.logger.Debug("Would transfer file", "source", job.Source, "target", job.Target)
+	}
+}
+
+// targetKey returns the form in which targets are compared under policy
+func targetKey(target, policy string) string {
+	if policy == filesystem.NormalizeNone {
+		return target
+	}
+	return filesystem.NormalizeName(target, filesystem.NormalizeNFC)
+}
+
+// Validate validates the consolidation operation configuration
+func (co *ConsolidationOperation) Validate(config domain.OperationConfig) error {
+	return co.ValidateConfig()
+}
+
+// EstimateProgress provides an estimate of the operation's scope
+func (co *ConsolidationOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	return co.estimateScope(config, 5)
+}
@@ -0,0 +1,209 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/a4abhishek/fileops/internal/content"
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// ContentIndexFactory creates document indexing operations
+type ContentIndexFactory struct {
+	engine *Engine
+}
+
+// Create creates a new content index operation
+func (cf *ContentIndexFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewContentIndexOperation(id, config, cf.engine), nil
+}
+
+// Validate validates the content index configuration
+func (cf *ContentIndexFactory) Validate(config domain.OperationConfig) error {
+	if len(config.TargetPaths) == 0 {
+		return fmt.Errorf("at least one path is required")
+	}
+	if settingString(config, "index_directory", "") == "" {
+		return fmt.Errorf("index_directory parameter is required")
+	}
+	return nil
+}
+
+// ContentIndexOperation extracts the text of documents into the full-text index
+// and classifies them. Runs are incremental: unchanged documents are skipped and
+// documents that no longer exist are dropped.
+type ContentIndexOperation struct {
+	*BaseOperation
+	indexed  *pathList
+	upToDate *pathList
+	failed   *pathList
+	removed  *pathList
+	category map[string]int
+}
+
+// NewContentIndexOperation creates a new content index operation
+func NewContentIndexOperation(id string, config domain.OperationConfig, engine *Engine) *ContentIndexOperation {
+	base := NewBaseOperation(id, domain.OperationContentIndex, config, engine)
+	return &ContentIndexOperation{
+		BaseOperation: base,
+		indexed:       base.newPathList("indexed"),
+		upToDate:      base.newPathList("up_to_date"),
+		failed:        base.newPathList("failed"),
+		removed:       base.newPathList("removed"),
+		category:      make(map[string]int),
+	}
+}
+
+// Execute indexes all supported documents below the configured paths
+func (co *ContentIndexOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	tracker := co.engine.progressTracker.StartOperation(co.id, domain.OperationContentIndex, 3)
+	co.SetTracker(tracker)
+
+	index, err := content.OpenIndex(settingString(config, "index_directory", ""))
+	if err != nil {
+		return nil, err
+	}
+	extractor := content.NewExtractor(settingInt(config, "max_text_size", 0))
+	classifier := content.NewClassifier(settingRules(config))
+	force := settingBool(config, "force", false)
+
+	co.UpdateStep("Scanning documents")
+
+	documents, totalSize, err := co.scan(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	for _, root := range config.TargetPaths {
+		co.removed.Add(index.Prune(root)...)
+	}
+
+	co.UpdateStep("Extracting text")
+	co.SetProgress(0, int64(len(documents)), 0, totalSize)
+
+	for _, info := range documents {
+		if err := co.CheckContext(ctx); err != nil {
+			// Keep what was indexed so far; the next run resumes from there
+			if !config.DryRun {
+				index.Save()
+			}
+			return nil, err
+		}
+
+		if !force && index.UpToDate(info.Path, info.Size, info.ModTime, config.MTimeTolerance) {
+			co.upToDate.Add(info.Path)
+			co.IncrementProgress(1, info.Size)
+			continue
+		}
+
+		text, err := extractor.Extract(info.Path)
+		if err != nil {
+			co.AddFileError(info.Path, fmt.Errorf("failed to extract text from %s: %w", info.Path, err))
+			co.failed.Add(info.Path)
+			co.IncrementProgress(1, info.Size)
+			continue
+		}
+
+		doc := &content.Document{Path: info.Path, Size: info.Size, ModTime: info.ModTime, Text: text}
+		if classification := classifier.Classify(text); classification != nil {
+			doc.Category = classification.Category
+			doc.Matched = classification.Matched
+			co.category[doc.Category]++
+		}
+		index.Put(doc)
+		co.indexed.Add(info.Path)
+		co.IncrementProgress(1, info.Size)
+	}
+
+	co.UpdateStep("Saving index")
+
+	if !config.DryRun {
+		if err := index.Save(); err != nil {
+			return nil, fmt.Errorf("failed to save content index: %w", err)
+		}
+	}
+
+	details := map[string]interface{}{
+		"indexed":         co.indexed.Result(),
+		"up_to_date":      co.upToDate.Result(),
+		"failed":          co.failed.Result(),
+		"removed":         co.removed.Result(),
+		"categories":      co.category,
+		"documents":       index.Len(),
+		"index_directory": settingString(config, "index_directory", ""),
+		"dry_run":         config.DryRun,
+	}
+
+	summary := fmt.Sprintf("Content index: %d indexed, %d up to date, %d removed, %d failed (%d documents total)",
+		co.indexed.Len(), co.upToDate.Len(), co.removed.Len(), co.failed.Len(), index.Len())
+	if config.DryRun {
+		summary = fmt.Sprintf("Content index (dry run): %d would be indexed, %d up to date, %d would be removed, %d failed",
+			co.indexed.Len(), co.upToDate.Len(), co.removed.Len(), co.failed.Len())
+	}
+
+	return co.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// Validate validates the content index operation configuration
+func (co *ContentIndexOperation) Validate(config domain.OperationConfig) error {
+	return co.ValidateConfig()
+}
+
+// EstimateProgress provides an estimate of the operation's scope
+func (co *ContentIndexOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	return co.estimateScope(config, 3)
+}
+
+// scan collects the documents with extractable text below all roots
+func (co *ContentIndexOperation) scan(ctx context.Context, config domain.OperationConfig) ([]*domain.FileInfo, int64, error) {
+	documents := make([]*domain.FileInfo, 0)
+	var totalSize int64
+
+	for _, root := range config.TargetPaths {
+		err := co.Walk(ctx, root, config, func(path string, info *domain.FileInfo, err error) error {
+			if err != nil {
+				co.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+				return nil // Continue walking
+			}
+			if info == nil {
+				return nil
+			}
+			if co.isExcluded(path, config) {
+				if info.IsDir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.IsDir && content.Supported(path) {
+				documents = append(documents, info)
+				totalSize += info.Size
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to walk path %s: %w", root, err)
+		}
+	}
+	return documents, totalSize, nil
+}
+
+// settingRules reads the classification rules, also accepting the generic
+// maps produced by a JSON round trip
+func settingRules(config domain.OperationConfig) []content.Rule {
+	switch value := config.CustomSettings["rules"].(type) {
+	case []content.Rule:
+		return value
+	case []interface{}:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil
+		}
+		var rules []content.Rule
+		if err := json.Unmarshal(data, &rules); err != nil {
+			return nil
+		}
+		return rules
+	}
+	return nil
+}
@@ -0,0 +1,441 @@
+package engine
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/a4abhishek/fileops/internal/thumbnail"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// defaultCopySimilarity is the content similarity from which a differing copy
+// is still paired with its original
+const defaultCopySimilarity = 0.8
+
+// sketchSize is the number of shingle hashes kept per file for content similarity
+const sketchSize = 256
+
+// CopyPair is a file named like a copy together with its likely original
+type CopyPair struct {
+	Original   string  `json:"original"`
+	Copy       string  `json:"copy"`
+	Pattern    string  `json:"pattern"`
+	Identical  bool    `json:"identical"`
+	Similarity float64 `json:"similarity"`
+	Confidence float64 `json:"confidence,omitempty"` // How sure an identical copy is identical, see domain.ConfidenceLevel
+	Method     string  `json:"method"`               // hash, image or content
+	Keep       string  `json:"keep"`                 // Suggested file to keep
+	Reason     string  `json:"reason"`
+	Size       int64   `json:"size"` // Size of the copy
+}
+
+// copyNamePattern recognizes the name a program gives a copy and captures the original stem
+type copyNamePattern struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// copyNamePatterns are matched against the name without extension
+var copyNamePatterns = []copyNamePattern{
+	{"conflicted copy", regexp.MustCompile(`(?i)^(.+?) \([^()]*conflicted copy[^()]*\)$`)},      // Dropbox
+	{"numbered", regexp.MustCompile(`^(.+?) ?\(\d{1,3}\)$`)},                                    // Browsers, Windows
+	{"copy suffix", regexp.MustCompile(`(?i)^(.+) - (?:copy|kopie|copie|copia)(?: \(\d+\))?$`)}, // Windows Explorer
+	{"copy prefix", regexp.MustCompile(`(?i)^copy (?:\(\d+\) )?of (.+)$`)},                      // Older Windows
+	{"copy suffix", regexp.MustCompile(`(?i)^(.+) copy(?: \d+)?$`)},                             // macOS Finder
+	{"numbered", regexp.MustCompile(`^(.+?) \d{1,2}$`)},                                         // macOS Photos, iCloud
+}
+
+// screenshotPattern matches the names screenshot tools give their files
+var screenshotPattern = regexp.MustCompile(`(?i)^(screenshot|screen shot|screen recording|bildschirmfoto|captura de pantalla|capture d.écran|schermata)[ _-]`)
+
+// isScreenshot reports whether a file name looks like a screenshot
+func isScreenshot(name string) bool {
+	return screenshotPattern.MatchString(name)
+}
+
+// copyOriginalStems returns the candidate original names of a file named like
+// a copy, innermost last ("a (1) - Copy.txt" gives "a (1).txt", then "a.txt")
+func copyOriginalStems(name string) ([]string, string) {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	candidates := make([]string, 0, 2)
+	pattern := ""
+	for depth := 0; depth < 3; depth++ {
+		matched := false
+		for _, p := range copyNamePatterns {
+			if m := p.re.FindStringSubmatch(stem); m != nil && strings.TrimSpace(m[1]) != "" {
+				stem = m[1]
+				if pattern == "" {
+					pattern = p.name
+				}
+				candidates = append(candidates, stem+ext)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			break
+		}
+	}
+	return candidates, pattern
+}
+
+// executeNames pairs files named like copies with their originals in the same
+// directory and compares their content. Identical copies can be removed;
+// similar ones are only reported.
+func (do *DeduplicationOperation) executeNames(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	tracker := do.engine.progressTracker.StartOperation(do.id, domain.OperationDeduplication, 3)
+	do.SetTracker(tracker)
+
+	algorithm := config.HashAlgorithm
+	if algorithm == "" {
+		algorithm = "blake2b" // Default
+	}
+	threshold := config.SimilarityThreshold
+	if threshold <= 0 {
+		threshold = defaultCopySimilarity
+	}
+	removeCopies := settingBool(config, "remove_copies", false)
+	verify := settingBool(config, "verify", false)
+
+	do.UpdateStep("Scanning files")
+
+	inodes, err := do.scan(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Files by directory and lower-case name
+	byName := make(map[string]map[string]*domain.FileInfo)
+	screenshots := make([]string, 0)
+	for _, inode := range inodes {
+		for _, path := range inode.paths {
+			dir := filepath.Dir(path)
+			if byName[dir] == nil {
+				byName[dir] = make(map[string]*domain.FileInfo)
+			}
+			info := *inode.info
+			info.Path, info.Name = path, filepath.Base(path)
+			byName[dir][strings.ToLower(info.Name)] = &info
+			if isScreenshot(info.Name) {
+				screenshots = append(screenshots, path)
+			}
+		}
+	}
+	sort.Strings(screenshots)
+
+	type candidate struct {
+		original, copy *domain.FileInfo
+		pattern        string
+	}
+	candidates := make([]candidate, 0)
+	for _, files := range byName {
+		for _, info := range files {
+			stems, pattern := copyOriginalStems(info.Name)
+			for _, stem := range stems {
+				if original, ok := files[strings.ToLower(stem)]; ok && original.Path != info.Path {
+					candidates = append(candidates, candidate{original: original, copy: info, pattern: pattern})
+					break
+				}
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].copy.Path < candidates[j].copy.Path })
+
+	do.UpdateStep("Comparing copies with their originals")
+	do.SetProgress(0, int64(len(candidates)), 0, 0)
+
+	pairs := make([]CopyPair, 0)
+	removed := make([]string, 0)
+	related := newCompanions()
+	var reclaimable, reclaimed int64
+	for _, c := range candidates {
+		if err := do.CheckContext(ctx); err != nil {
+			return nil, err
+		}
+		do.IncrementProgress(1, c.copy.Size)
+
+		pair, err := do.comparePair(c.original, c.copy, algorithm, verify)
+		if err != nil {
+			do.AddFileError(c.copy.Path, fmt.Errorf("failed to compare %s: %w", c.copy.Path, err))
+			continue
+		}
+		if !pair.Identical && pair.Similarity < threshold {
+			continue // Same name pattern, different file
+		}
+		pair.Pattern = c.pattern
+
+		if pair.Identical {
+			reclaimable += pair.Size
+			_, halfOfPair := related.sibling(pair.Copy)
+			sidecars := related.group(pair.Copy)
+			if removeCopies && do.kept(pair.Copy, algorithm, "") {
+				do.pinned.Add(pair.Copy)
+			} else if removeCopies && pair.Confidence < do.minConfidence {
+				do.unsure.Add(pair.Copy)
+			} else if removeCopies && halfOfPair {
+				// Removing one half of a RAW+JPEG pair would break the photo
+				do.rawPairs.Add(pair.Copy)
+			} else if removeCopies && len(sidecars) > 0 && !config.DeleteSidecars {
+				// Its sidecars would be left without their file
+				do.withSidecars.Add(pair.Copy)
+			} else if removeCopies && !config.DryRun {
+				if err := do.removeCopy(pair); err != nil {
+					do.AddFileError(pair.Copy, fmt.Errorf("failed to remove %s: %w", pair.Copy, err))
+				} else {
+					removed = append(removed, pair.Copy)
+					reclaimed += pair.Size
+					for _, sidecar := range sidecars {
+						size, err := do.removeSidecar(sidecar)
+						if err != nil {
+							do.AddFileError(sidecar, fmt.Errorf("failed to remove %s with %s: %w", sidecar, pair.Copy, err))
+							continue
+						}
+						removed = append(removed, sidecar)
+						reclaimed += size
+					}
+				}
+			}
+		}
+		pairs = append(pairs, pair)
+	}
+
+	do.UpdateStep("Completed")
+
+	identical := 0
+	for _, pair := range pairs {
+		if pair.Identical {
+			identical++
+		}
+	}
+
+	details := map[string]interface{}{
+		"mode":             DedupModeNames,
+		"copy_pairs":       pairs,
+		"screenshots":      screenshots,
+		"removed":          removed,
+		"pinned":           do.pinned.Result(),
+		"raw_pairs":        do.rawPairs.Result(),
+		"with_sidecars":    do.withSidecars.Result(),
+		"below_confidence": do.unsure.Result(),
+		"saveable_size":    reclaimable,
+		"reclaimed":        reclaimed,
+		"hash_algorithm":   algorithm,
+		"dry_run":          config.DryRun,
+	}
+
+	summary := fmt.Sprintf("Name patterns: %d copies paired with their originals (%d identical, %d similar), %d removed, %d screenshots",
+		len(pairs), identical, len(pairs)-identical, len(removed), len(screenshots))
+	if config.DryRun && removeCopies {
+		summary = fmt.Sprintf("Name patterns (dry run): %d copies paired with their originals, %d identical copies would be removed, %d screenshots",
+			len(pairs), identical-do.pinned.Len()-do.rawPairs.Len()-do.withSidecars.Len()-do.unsure.Len(), len(screenshots))
+	}
+	if pinned := do.pinned.Len(); pinned > 0 {
+		summary += fmt.Sprintf(", %d pinned copies kept", pinned)
+	}
+	if paired := do.rawPairs.Len(); paired > 0 {
+		summary += fmt.Sprintf(", %d copies kept as half of a RAW+JPEG pair", paired)
+	}
+	if kept := do.withSidecars.Len(); kept > 0 {
+		summary += fmt.Sprintf(", %d copies with sidecars kept (--delete-sidecars removes them)", kept)
+	}
+	if unsure := do.unsure.Len(); unsure > 0 {
+		summary += fmt.Sprintf(", %d copies below the minimum confidence kept", unsure)
+	}
+
+	return do.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// comparePair compares a copy with its original by hash and, when they
+// differ, by image fingerprint or content sketch. With verify, copies with
+// the hash of their original are compared byte by byte too.
+func (do *DeduplicationOperation) comparePair(original, copy *domain.FileInfo, algorithm string, verify bool) (CopyPair, error) {
+	pair := CopyPair{Original: original.Path, Copy: copy.Path, Size: copy.Size}
+
+	if original.Size == copy.Size {
+		originalHash, err := do.engine.fileSystem.ComputeHash(original.Path, algorithm)
+		if err != nil {
+			return pair, err
+		}
+		copyHash, err := do.engine.fileSystem.ComputeHash(copy.Path, algorithm)
+		if err != nil {
+			return pair, err
+		}
+		same := originalHash == copyHash
+		pair.Confidence = filesystem.HashConfidence(algorithm)
+		if same && verify {
+			if same, err = sameContent(original.Path, copy.Path); err != nil {
+				return pair, err
+			}
+			pair.Confidence = domain.ConfidenceCertain
+		}
+		if same {
+			pair.Identical, pair.Similarity, pair.Method = true, 1, "hash"
+			pair.Keep, pair.Reason = original.Path, "identical copy, keep the original name"
+			return pair, nil
+		}
+		pair.Confidence = 0
+	}
+
+	if a, err := thumbnail.Fingerprint(original.Path); err == nil {
+		if b, err := thumbnail.Fingerprint(copy.Path); err == nil {
+			pair.Similarity, pair.Method = thumbnail.Similarity(a, b), "image"
+		}
+	}
+	if pair.Method == "" {
+		similarity, err := contentSimilarity(original.Path, copy.Path)
+		if err != nil {
+			return pair, err
+		}
+		pair.Similarity, pair.Method = similarity, "content"
+	}
+
+	// The newer file is most likely the edited version
+	pair.Keep, pair.Reason = original.Path, "original is at least as recent"
+	if copy.ModTime.After(original.ModTime) {
+		pair.Keep, pair.Reason = copy.Path, "copy is newer, likely the edited version"
+	}
+	return pair, nil
+}
+
+// removeCopy deletes an identical copy after comparing it byte by byte
+func (do *DeduplicationOperation) removeCopy(pair CopyPair) error {
+	same, err := sameContent(pair.Original, pair.Copy)
+	if err != nil {
+		return err
+	}
+	if !same {
+		return errors.New("content changed since it was hashed")
+	}
+	if err := do.engine.fileSystem.Remove(pair.Copy); err != nil {
+		return err
+	}
+	do.RecordDeletion(pair.Copy, pair.Size)
+	return nil
+}
+
+// removeSidecar deletes the sidecar of a removed copy
+func (do *DeduplicationOperation) removeSidecar(path string) (int64, error) {
+	info, err := do.engine.fileSystem.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	if err := do.engine.fileSystem.Remove(path); err != nil {
+		return 0, err
+	}
+	do.RecordDeletion(path, info.Size)
+	return info.Size, nil
+}
+
+// contentSimilarity estimates the share of content two files have in common
+// from bottom-k sketches of their 8-byte shingles. Insertions and deletions
+// only affect the shingles around them.
+func contentSimilarity(a, b string) (float64, error) {
+	sketchA, err := contentSketch(a)
+	if err != nil {
+		return 0, err
+	}
+	sketchB, err := contentSketch(b)
+	if err != nil {
+		return 0, err
+	}
+	if len(sketchA) == 0 || len(sketchB) == 0 {
+		return 0, nil
+	}
+
+	// Jaccard estimate: shared hashes among the smallest of the union
+	union := make([]uint64, 0, len(sketchA)+len(sketchB))
+	inA := make(map[uint64]bool, len(sketchA))
+	for _, hash := range sketchA {
+		inA[hash] = true
+		union = append(union, hash)
+	}
+	inB := make(map[uint64]bool, len(sketchB))
+	for _, hash := range sketchB {
+		inB[hash] = true
+		if !inA[hash] {
+			union = append(union, hash)
+		}
+	}
+	sort.Slice(union, func(i, j int) bool { return union[i] < union[j] })
+	k := min(sketchSize, len(union))
+	shared := 0
+	for _, hash := range union[:k] {
+		if inA[hash] && inB[hash] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(k), nil
+}
+
+// contentSketch returns the sketchSize smallest distinct hashes of the
+// 8-byte windows of a file
+func contentSketch(path string) ([]uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	kept := &maxHeap{}
+	members := make(map[uint64]bool, sketchSize)
+	reader := bufio.NewReaderSize(file, 256*1024)
+	var window uint64
+	for n := 0; ; n++ {
+		c, err := reader.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		window = window<<8 | uint64(c)
+		if n < 7 {
+			continue
+		}
+
+		hash := mix64(window)
+		if members[hash] || (kept.Len() == sketchSize && hash >= (*kept)[0]) {
+			continue
+		}
+		heap.Push(kept, hash)
+		members[hash] = true
+		if kept.Len() > sketchSize {
+			delete(members, heap.Pop(kept).(uint64))
+		}
+	}
+	return *kept, nil
+}
+
+// mix64 scrambles a shingle into a uniformly distributed hash (splitmix64 finalizer)
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// maxHeap keeps the largest of the retained hashes on top
+type maxHeap []uint64
+
+func (h maxHeap) Len() int           { return len(h) }
+func (h maxHeap) Less(i, j int) bool { return h[i] > h[j] }
+func (h maxHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x any)        { *h = append(*h, x.(uint64)) }
+func (h *maxHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// copyReadAhead is how much of a file a source worker reads ahead while it
+// waits for a destination slot
+const copyReadAhead = 64 << 20
+
+// CopyJob describes a single file transfer planned by a consolidation
+type CopyJob struct {
+	Source     string
+	SourceRoot string
+	Target     string
+	Size       int64
+	Move       bool
+	Link       string // LinkHardlink or LinkReflink to link Target to Source instead of copying
+}
+
+// Link strategies of a CopyJob. Where the filesystem of the destination
+// cannot link the source, the file is copied.
+const (
+	LinkHardlink = "hardlink"
+	LinkReflink  = "reflink"
+)
+
+// CopyFunc performs the actual transfer of a single job
+type CopyFunc func(ctx context.Context, job CopyJob) error
+
+// CopyScheduler runs copy jobs from multiple sources in parallel.
+//
+// Each source root gets its own pool of reader workers so that independent
+// physical drives are read concurrently, while a shared destination semaphore
+// bounds the number of simultaneous writes to the target volume. A reader
+// reads the first readAhead bytes of its file into the page cache before it
+// waits for a destination slot, so only the write stage of a copy holds one.
+type CopyScheduler struct {
+	sourceConcurrency      int
+	destinationConcurrency int
+	readAhead              int64
+}
+
+// NewCopyScheduler creates a scheduler with the given per-source and
+// destination limits. Dry runs, which copy nothing, read nothing ahead.
+func NewCopyScheduler(sourceConcurrency, destinationConcurrency int, dryRun bool) *CopyScheduler {
+	if sourceConcurrency <= 0 {
+		sourceConcurrency = 2 // Default
+	}
+	if destinationConcurrency <= 0 {
+		destinationConcurrency = 2 // Default
+	}
+	readAhead := int64(copyReadAhead)
+	if dryRun {
+		readAhead = 0
+	}
+	return &CopyScheduler{
+		sourceConcurrency:      sourceConcurrency,
+		destinationConcurrency: destinationConcurrency,
+		readAhead:              readAhead,
+	}
+}
+
+// Run executes all jobs, calling onDone after each job finishes (successfully or not).
+// Jobs are grouped by SourceRoot; the order of jobs within a source is preserved
+// as far as the per-source worker count allows.
+func (cs *CopyScheduler) Run(ctx context.Context, jobs []CopyJob, copyFn CopyFunc, onDone func(job CopyJob, err error)) error {
+	// Group jobs by source so each source is drained by its own workers
+	bySource := make(map[string][]CopyJob)
+	order := make([]string, 0)
+	for _, job := range jobs {
+		if _, exists := bySource[job.SourceRoot]; !exists {
+			order = append(order, job.SourceRoot)
+		}
+		bySource[job.SourceRoot] = append(bySource[job.SourceRoot], job)
+	}
+
+	destSlots := make(chan struct{}, cs.destinationConcurrency)
+
+	var wg sync.WaitGroup
+	for _, source := range order {
+		queue := make(chan CopyJob)
+
+		for i := 0; i < cs.sourceConcurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for job := range queue {
+					cs.read(ctx, job)
+
+					// Serialize writes to the destination through the shared slots
+					select {
+					case destSlots <- struct{}{}:
+					case <-ctx.Done():
+						onDone(job, ctx.Err())
+						continue
+					}
+					err := copyFn(ctx, job)
+					<-destSlots
+					onDone(job, err)
+				}
+			}()
+		}
+
+		wg.Add(1)
+		go func(sourceJobs []CopyJob) {
+			defer wg.Done()
+			defer close(queue)
+			for _, job := range sourceJobs {
+				select {
+				case queue <- job:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(bySource[source])
+	}
+
+	wg.Wait()
+	return ctx.Err()
+}
+
+// read reads the start of the source of a job into the page cache, where the
+// copy finds it. Moves and links within a device read nothing. Errors are
+// left to the copy to report.
+func (cs *CopyScheduler) read(ctx context.Context, job CopyJob) {
+	if cs.readAhead <= 0 || ctx.Err() != nil || ((job.Move || job.Link != "") && sameDevice(job.Source, job.Target)) {
+		return
+	}
+	if err := filesystem.Descriptors().Acquire(ctx, 1); err != nil {
+		return
+	}
+	defer filesystem.Descriptors().Release(1)
+
+	file, err := os.Open(job.Source)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	_, _ = io.CopyN(io.Discard, file, cs.readAhead)
+}
+
+// sameDevice reports whether target, or the nearest directory above it that
+// exists, is on the device of source
+func sameDevice(source, target string) bool {
+	sourceDevice, err := filesystem.DeviceID(source)
+	if err != nil {
+		return false
+	}
+	existing, err := filesystem.NearestExisting(filepath.Dir(target))
+	if err != nil {
+		return false
+	}
+	targetDevice, err := filesystem.DeviceID(existing)
+	return err == nil && sourceDevice == targetDevice
+}
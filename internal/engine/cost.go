@@ -0,0 +1,254 @@
+package engine
+
+import (
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// CostEstimate is what a dry run expects the real run to cost
+type CostEstimate struct {
+	ReadBytes  int64         `json:"read_bytes"`
+	WriteBytes int64         `json:"write_bytes"`
+	FreedBytes int64         `json:"freed_bytes"`
+	Duration   time.Duration `json:"duration"`
+	Steps      []StepCost    `json:"steps"`
+	Volumes    []VolumeCost  `json:"volumes,omitempty"`
+	// Throughput assumed for devices fileops bench has not measured, 0 when
+	// it measured every device read or written
+	AssumedBytesPerSecond int64 `json:"assumed_bytes_per_second,omitempty"`
+}
+
+// StepCost is the expected cost of one step of an operation
+type StepCost struct {
+	Step       string        `json:"step"`
+	ReadBytes  int64         `json:"read_bytes"`
+	WriteBytes int64         `json:"write_bytes"`
+	Duration   time.Duration `json:"duration"`
+}
+
+// VolumeCost is the space available on a volume the operation writes to or
+// frees space on, before and after it
+type VolumeCost struct {
+	Path           string `json:"path"`
+	WriteBytes     int64  `json:"write_bytes"`
+	FreedBytes     int64  `json:"freed_bytes"`
+	Available      int64  `json:"available"`
+	AvailableAfter int64  `json:"available_after"`
+}
+
+// ioPlan collects what the real run of a dry run reads, writes and deletes,
+// by step and device, and how long the steps of the dry run took
+type ioPlan struct {
+	mu      sync.Mutex
+	steps   []*plannedStep
+	devices map[string]uint64 // Device of every directory seen
+	volumes map[uint64]string // A directory of every device written to or freed
+	planned bool
+}
+
+// plannedStep holds the bytes one step reads, writes and frees per device
+type plannedStep struct {
+	name     string
+	duration time.Duration
+	read     map[uint64]int64
+	write    map[uint64]int64
+	freed    map[uint64]int64
+}
+
+func newIOPlan() *ioPlan {
+	return &ioPlan{devices: make(map[string]uint64), volumes: make(map[uint64]string)}
+}
+
+// step returns the record of a step, adding it when it is new
+func (p *ioPlan) step(name string) *plannedStep {
+	for _, step := range p.steps {
+		if step.name == name {
+			return step
+		}
+	}
+	step := &plannedStep{
+		name:  name,
+		read:  make(map[uint64]int64),
+		write: make(map[uint64]int64),
+		freed: make(map[uint64]int64),
+	}
+	p.steps = append(p.steps, step)
+	return step
+}
+
+// device returns the device holding path, or of the nearest directory above
+// it that exists, so targets that are yet to be created can be planned. 0 is
+// an unknown device.
+func (p *ioPlan) device(path string) uint64 {
+	dir := filepath.Dir(path)
+	if device, ok := p.devices[dir]; ok {
+		return device
+	}
+	var device uint64
+	if existing, err := filesystem.NearestExisting(dir); err == nil {
+		device, _ = filesystem.DeviceID(existing)
+	}
+	p.devices[dir] = device
+	return device
+}
+
+// timeStep adds the time the dry run spent in a step
+func (p *ioPlan) timeStep(name string, duration time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.step(name).duration += duration
+}
+
+// add records bytes read, written and freed in a step
+func (p *ioPlan) add(name string, read, write, freed map[string]int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	step := p.step(name)
+	for path, bytes := range read {
+		step.read[p.device(path)] += bytes
+	}
+	for path, bytes := range write {
+		device := p.device(path)
+		step.write[device] += bytes
+		p.volume(device, path)
+	}
+	for path, bytes := range freed {
+		device := p.device(path)
+		step.freed[device] += bytes
+		p.volume(device, path)
+	}
+	p.planned = true
+}
+
+// volume remembers a directory of a device whose free space changes
+func (p *ioPlan) volume(device uint64, path string) {
+	if _, ok := p.volumes[device]; !ok {
+		p.volumes[device] = filepath.Dir(path)
+	}
+}
+
+// planRead records that the real run reads bytes of path. Only dry runs plan.
+func (bo *BaseOperation) planRead(path string, bytes int64) {
+	bo.planIO(map[string]int64{path: bytes}, nil, nil)
+}
+
+// planWrite records that the real run writes bytes to path
+func (bo *BaseOperation) planWrite(path string, bytes int64) {
+	bo.planIO(nil, map[string]int64{path: bytes}, nil)
+}
+
+// planFree records that the real run frees the bytes path takes up
+func (bo *BaseOperation) planFree(path string, bytes int64) {
+	bo.planIO(nil, nil, map[string]int64{path: bytes})
+}
+
+// planTransfer records that the real run copies, or moves, bytes from source
+// to target. A move within a device is a rename that reads and writes nothing.
+func (bo *BaseOperation) planTransfer(source, target string, bytes int64, move bool) {
+	if !bo.config.DryRun {
+		return
+	}
+	if move {
+		bo.plan.mu.Lock()
+		renamed := bo.plan.device(source) == bo.plan.device(target)
+		bo.plan.mu.Unlock()
+		if renamed {
+			return
+		}
+		bo.planIO(map[string]int64{source: bytes}, map[string]int64{target: bytes}, map[string]int64{source: bytes})
+		return
+	}
+	bo.planIO(map[string]int64{source: bytes}, map[string]int64{target: bytes}, nil)
+}
+
+// planIO records I/O of the real run in the current step
+func (bo *BaseOperation) planIO(read, write, freed map[string]int64) {
+	if !bo.config.DryRun {
+		return
+	}
+	bo.mu.RLock()
+	step := bo.step
+	bo.mu.RUnlock()
+	bo.plan.add(step, read, write, freed)
+}
+
+// estimateCost turns the plan of a dry run into the expected cost of the real
+// run. A step takes as long as its I/O takes at the speed fileops bench
+// measured on the devices involved (defaultBytesPerSecond on devices it did
+// not measure), but at least as long as it took in the dry run, which did
+// the rest of its work. nil means nothing was planned.
+func (e *Engine) estimateCost(plan *ioPlan) *CostEstimate {
+	e.mu.RLock()
+	profile := e.benchProfile
+	e.mu.RUnlock()
+
+	plan.mu.Lock()
+	defer plan.mu.Unlock()
+	if !plan.planned {
+		return nil
+	}
+
+	estimate := &CostEstimate{}
+	seconds := func(device uint64, bytes int64, write bool) float64 {
+		measured, ok := profile.Throughput(device)
+		speed := measured.ReadBytesPerSecond
+		if write {
+			speed = measured.WriteBytesPerSecond
+		}
+		if !ok || speed <= 0 {
+			estimate.AssumedBytesPerSecond = defaultBytesPerSecond
+			speed = defaultBytesPerSecond
+		}
+		return float64(bytes) / speed
+	}
+
+	written := make(map[uint64]int64)
+	freed := make(map[uint64]int64)
+	for _, step := range plan.steps {
+		cost := StepCost{Step: step.name}
+		var io float64
+		for device, bytes := range step.read {
+			cost.ReadBytes += bytes
+			io += seconds(device, bytes, false)
+		}
+		for device, bytes := range step.write {
+			cost.WriteBytes += bytes
+			io += seconds(device, bytes, true)
+			written[device] += bytes
+		}
+		for device, bytes := range step.freed {
+			estimate.FreedBytes += bytes
+			freed[device] += bytes
+		}
+		cost.Duration = max(step.duration, time.Duration(io*float64(time.Second)))
+
+		estimate.ReadBytes += cost.ReadBytes
+		estimate.WriteBytes += cost.WriteBytes
+		estimate.Duration += cost.Duration
+		estimate.Steps = append(estimate.Steps, cost)
+	}
+
+	for device, dir := range plan.volumes {
+		space, err := filesystem.GetDiskSpace(dir)
+		if err != nil {
+			e.logger.Warn("Unable to determine free space", "path", dir, "error", err)
+			continue
+		}
+		available := int64(space.Available)
+		estimate.Volumes = append(estimate.Volumes, VolumeCost{
+			Path:           space.Path,
+			WriteBytes:     written[device],
+			FreedBytes:     freed[device],
+			Available:      available,
+			AvailableAfter: available - written[device] + freed[device],
+		})
+	}
+	sort.Slice(estimate.Volumes, func(i, j int) bool {
+		return estimate.Volumes[i].Path < estimate.Volumes[j].Path
+	})
+	return estimate
+}
@@ -0,0 +1,267 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/a4abhishek/fileops/internal/encryption"
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// defaultPassphraseEnv is the environment variable holding the passphrase when
+// no key file is configured. Secrets are never stored in the settings because
+// the settings are saved with the operation result.
+const defaultPassphraseEnv = "FILEOPS_PASSPHRASE"
+
+// CryptFactory creates encryption and decryption operations
+type CryptFactory struct {
+	engine  *Engine
+	decrypt bool
+}
+
+// Create creates a new encryption or decryption operation
+func (cf *CryptFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewCryptOperation(id, config, cf.engine, cf.decrypt), nil
+}
+
+// Validate validates the encryption configuration
+func (cf *CryptFactory) Validate(config domain.OperationConfig) error {
+	if len(config.TargetPaths) == 0 {
+		return fmt.Errorf("at least one path is required")
+	}
+	if _, err := cryptKey(config); err != nil {
+		return err
+	}
+	return nil
+}
+
+// CryptOperation encrypts files into <name>.enc or decrypts them back, either
+// next to the originals or into a destination that mirrors the directory
+// structure below each path
+type CryptOperation struct {
+	*BaseOperation
+	decrypt   bool
+	processed *pathList
+	skipped   *pathList
+	removed   *pathList
+}
+
+// NewCryptOperation creates a new encryption (or, with decrypt, decryption) operation
+func NewCryptOperation(id string, config domain.OperationConfig, engine *Engine, decrypt bool) *CryptOperation {
+	operationType := domain.OperationEncrypt
+	if decrypt {
+		operationType = domain.OperationDecrypt
+	}
+	base := NewBaseOperation(id, operationType, config, engine)
+	return &CryptOperation{
+		BaseOperation: base,
+		decrypt:       decrypt,
+		processed:     base.newPathList("processed"),
+		skipped:       base.newPathList("skipped"),
+		removed:       base.newPathList("removed"),
+	}
+}
+
+// Execute encrypts or decrypts all matching files below the configured paths
+func (co *CryptOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	tracker := co.engine.progressTracker.StartOperation(co.id, co.operationType, 3)
+	co.SetTracker(tracker)
+
+	key, err := cryptKey(config)
+	if err != nil {
+		return nil, err
+	}
+	destination := settingString(config, "destination", "")
+	removeSource := settingBool(config, "remove_source", false)
+
+	co.UpdateStep("Scanning files")
+
+	jobs, totalSize, err := co.scan(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if co.decrypt {
+		co.UpdateStep("Decrypting files")
+	} else {
+		co.UpdateStep("Encrypting files")
+	}
+	co.SetProgress(0, int64(len(jobs)), 0, totalSize)
+
+	for _, job := range jobs {
+		if err := co.CheckContext(ctx); err != nil {
+			return nil, err
+		}
+
+		target := co.targetPath(job.root, job.info.Path, destination)
+		if config.DryRun {
+			if _, err := os.Lstat(target); err == nil {
+				co.AddFileError(job.info.Path, fmt.Errorf("%w: %s", os.ErrExist, target))
+			} else {
+				co.processed.Add(target)
+			}
+			co.IncrementProgress(1, job.info.Size)
+			continue
+		}
+
+		if co.decrypt {
+			err = encryption.DecryptFile(job.info.Path, target, key)
+		} else {
+			err = encryption.EncryptFile(job.info.Path, target, key)
+		}
+		if err != nil {
+			co.AddFileError(job.info.Path, fmt.Errorf("failed to process %s: %w", job.info.Path, err))
+			co.IncrementProgress(1, job.info.Size)
+			continue
+		}
+		co.processed.Add(target)
+
+		if removeSource {
+			if err := co.engine.fileSystem.Remove(job.info.Path); err != nil {
+				co.AddFileError(job.info.Path, fmt.Errorf("failed to remove %s: %w", job.info.Path, err))
+			} else {
+				co.removed.Add(job.info.Path)
+				co.RecordDeletion(job.info.Path, job.info.Size)
+			}
+		}
+		co.IncrementProgress(1, job.info.Size)
+	}
+
+	co.UpdateStep("Completed")
+
+	details := map[string]interface{}{
+		"processed":   co.processed.Result(),
+		"skipped":     co.skipped.Result(),
+		"removed":     co.removed.Result(),
+		"destination": destination,
+		"dry_run":     config.DryRun,
+	}
+
+	verb := "Encrypted"
+	if co.decrypt {
+		verb = "Decrypted"
+	}
+	summary := fmt.Sprintf("%s %d files, skipped %d, removed %d originals",
+		verb, co.processed.Len(), co.skipped.Len(), co.removed.Len())
+	if config.DryRun {
+		summary = fmt.Sprintf("Would process %d files (dry run), skipped %d", co.processed.Len(), co.skipped.Len())
+	}
+
+	return co.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// targetPath returns where the encrypted or decrypted copy of path is written
+func (co *CryptOperation) targetPath(root, path, destination string) string {
+	target := path
+	if destination != "" {
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." {
+			rel = filepath.Base(path)
+		}
+		target = filepath.Join(destination, rel)
+	}
+	if co.decrypt {
+		return strings.TrimSuffix(target, encryption.Extension)
+	}
+	return target + encryption.Extension
+}
+
+// Validate validates the encryption operation configuration
+func (co *CryptOperation) Validate(config domain.OperationConfig) error {
+	return co.ValidateConfig()
+}
+
+// EstimateProgress provides an estimate of the operation's scope
+func (co *CryptOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	return co.estimateScope(config, 3)
+}
+
+// cryptJob is a file to process with the root it was found under
+type cryptJob struct {
+	root string
+	info *domain.FileInfo
+}
+
+// scan collects the files to encrypt (or the .enc files to decrypt) below all
+// roots that match the name patterns, skipping the destination
+func (co *CryptOperation) scan(ctx context.Context, config domain.OperationConfig) ([]cryptJob, int64, error) {
+	jobs := make([]cryptJob, 0)
+	var totalSize int64
+	destination := settingString(config, "destination", "")
+	patterns := settingStrings(config, "patterns")
+
+	for _, root := range config.TargetPaths {
+		err := co.Walk(ctx, root, config, func(path string, info *domain.FileInfo, err error) error {
+			if err != nil {
+				co.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+				return nil // Continue walking
+			}
+			if info == nil {
+				return nil
+			}
+			if co.isExcluded(path, config) || (destination != "" && path == destination && path != root) {
+				if info.IsDir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir || strings.HasPrefix(info.Name, ".fileops-") || !matchesAny(info.Name, patterns) {
+				return nil
+			}
+
+			encrypted := strings.HasSuffix(info.Name, encryption.Extension)
+			if co.decrypt != encrypted {
+				if !co.decrypt {
+					co.skipped.Add(path) // Already encrypted
+				}
+				return nil
+			}
+			jobs = append(jobs, cryptJob{root: root, info: info})
+			totalSize += info.Size
+			return nil
+		})
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to walk path %s: %w", root, err)
+		}
+	}
+	return jobs, totalSize, nil
+}
+
+// matchesAny reports whether name matches one of the patterns; no patterns match everything
+func matchesAny(name string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// cryptKey loads the key from the key_file or passphrase_file setting, or from
+// the environment variable named by passphrase_env
+func cryptKey(config domain.OperationConfig) (*encryption.Key, error) {
+	if keyFile := settingString(config, "key_file", ""); keyFile != "" {
+		return encryption.LoadKeyFile(keyFile)
+	}
+	if passphraseFile := settingString(config, "passphrase_file", ""); passphraseFile != "" {
+		data, err := os.ReadFile(passphraseFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read passphrase file: %w", err)
+		}
+		passphrase, _, _ := strings.Cut(string(data), "\n")
+		return encryption.PassphraseKey(strings.TrimRight(passphrase, "\r"))
+	}
+
+	env := settingString(config, "passphrase_env", defaultPassphraseEnv)
+	passphrase := os.Getenv(env)
+	if passphrase == "" {
+		return nil, fmt.Errorf("no key: set key_file, passphrase_file or the %s environment variable", env)
+	}
+	return encryption.PassphraseKey(passphrase)
+}
@@ -0,0 +1,988 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/a4abhishek/fileops/internal/mail"
+	"github.com/a4abhishek/fileops/internal/stub"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// Deduplication modes
+const (
+	DedupModeReport   = "report"   // Only report duplicate groups
+	DedupModeHardlink = "hardlink" // Replace duplicates by hardlinks to one copy
+	DedupModeNames    = "names"    // Pair files named like copies with their originals
+)
+
+// Keep policies, choosing the copy of a duplicate group that is kept (and
+// that the others are linked to)
+const (
+	DedupKeepLinks    = "links"    // The copy with the most hardlinks, i.e. fewest changes
+	DedupKeepOldest   = "oldest"   // The copy modified first
+	DedupKeepNewest   = "newest"   // The copy modified last
+	DedupKeepShortest = "shortest" // The copy with the shortest path
+)
+
+// DeduplicationFactory creates deduplication operations
+type DeduplicationFactory struct {
+	engine *Engine
+}
+
+// Create creates a new deduplication operation
+func (df *DeduplicationFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewDeduplicationOperation(id, config, df.engine), nil
+}
+
+// Validate validates the deduplication configuration
+func (df *DeduplicationFactory) Validate(config domain.OperationConfig) error {
+	if len(config.TargetPaths) == 0 {
+		return fmt.Errorf("at least one path is required")
+	}
+	if config.HashAlgorithm != "" {
+		if _, err := filesystem.NewHasher(config.HashAlgorithm); err != nil {
+			return err
+		}
+	}
+	switch settingString(config, "mode", DedupModeReport) {
+	case DedupModeReport, DedupModeHardlink, DedupModeNames:
+	default:
+		return fmt.Errorf("mode must be %q, %q or %q", DedupModeReport, DedupModeHardlink, DedupModeNames)
+	}
+	if path := settingString(config, "pin_file", ""); path != "" {
+		if _, err := LoadPins(path); err != nil {
+			return fmt.Errorf("invalid pin file: %w", err)
+		}
+	}
+	switch settingString(config, "keep", DedupKeepLinks) {
+	case DedupKeepLinks, DedupKeepOldest, DedupKeepNewest, DedupKeepShortest:
+	default:
+		return fmt.Errorf("keep must be %q, %q, %q or %q", DedupKeepLinks, DedupKeepOldest, DedupKeepNewest, DedupKeepShortest)
+	}
+	switch settingString(config, "keep_variant", DedupVariantKeepCompressed) {
+	case DedupVariantKeepCompressed, DedupVariantKeepPlain:
+	default:
+		return fmt.Errorf("keep variant must be %q or %q", DedupVariantKeepCompressed, DedupVariantKeepPlain)
+	}
+	if settingBool(config, "compressed_variants", false) && settingString(config, "mode", DedupModeReport) == DedupModeNames {
+		return fmt.Errorf("compressed variants are not compared in %s mode", DedupModeNames)
+	}
+	if settingBool(config, "remove_variants", false) && !settingBool(config, "compressed_variants", false) {
+		return fmt.Errorf("removing variants requires comparing compressed variants")
+	}
+	if _, err := domain.ParseConfidenceLevel(settingString(config, "min_confidence", "medium")); err != nil {
+		return fmt.Errorf("invalid minimum confidence: %w", err)
+	}
+	if settingBool(config, "size_only", false) {
+		if settingString(config, "mode", DedupModeReport) != DedupModeReport {
+			return fmt.Errorf("files matched by size alone are only reported, not in %s mode", settingString(config, "mode", DedupModeReport))
+		}
+		if settingBool(config, "mail_archives", false) || settingBool(config, "compressed_variants", false) || settingString(config, "sync_from", "") != "" {
+			return fmt.Errorf("matching by size alone cannot be combined with mail archives, compressed variants or sync plans")
+		}
+	}
+	if settingBool(config, "mail_archives", false) && settingString(config, "mode", DedupModeReport) != DedupModeReport {
+		return fmt.Errorf("attachments in mail archives are only compared in %s mode", DedupModeReport)
+	}
+	from, to := settingString(config, "sync_from", ""), settingString(config, "sync_to", "")
+	if from != "" || to != "" {
+		if settingString(config, "mode", DedupModeReport) != DedupModeReport {
+			return fmt.Errorf("sync plans are only generated in %s mode", DedupModeReport)
+		}
+		for _, label := range []string{from, to} {
+			if _, ok := labelRoot(config, label); !ok {
+				return fmt.Errorf("no root is labeled %q", label)
+			}
+		}
+		if from == to {
+			return fmt.Errorf("a sync plan needs two different labels")
+		}
+	}
+	return validateExtensions(config)
+}
+
+// SyncPlanEntry is a file of one labeled root whose content the other
+// labeled root has nowhere, and where to copy it there
+type SyncPlanEntry struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Size   int64  `json:"size"`
+}
+
+// DeduplicationOperation finds files with identical content. In hardlink mode
+// the duplicates are replaced by hardlinks to a single copy, which turns dated
+// backup folders (e.g. rsync snapshots) into a hardlink farm.
+type DeduplicationOperation struct {
+	*BaseOperation
+	duplicateGroups []domain.DuplicateGroup
+	groupIDs        map[string]int // Groups by deterministic ID, see addGroup
+	rootLabels      map[string]string
+	preferDirs      []string // Directories whose copies are kept first
+	keep            string   // Keep policy
+	pins            *Pins    // Files never deleted or replaced (see kept)
+	pinned          *pathList
+	rawPairs        *pathList // Copies kept as half of a RAW+JPEG pair
+	archives        *pathList // Mail archives whose attachments were compared
+	onDisk          *pathList // Attachments with a copy among the files
+	attachments     int
+	withSidecars    *pathList // Copies kept because deleting their sidecars was not asked for
+	unsure          *pathList // Copies not linked or removed because their match was below min_confidence
+	minConfidence   float64
+	variantsLinked  *pathList // Variants not removed because other links keep their content
+	variantSaveable int64
+	totalSize       int64
+	saveableSize    int64
+	linked          *pathList
+	reclaimed       int64
+	unique          *pathList // Files without a duplicate and the copies kept of each group
+	offloaded       *pathList // Stub files of offloaded content, never compared
+
+	prefilterSkipped int // Candidates dropped without being hashed
+	prefilterCached  int // Partial hashes taken from the previous run
+}
+
+// NewDeduplicationOperation creates a new deduplication operation
+func NewDeduplicationOperation(id string, config domain.OperationConfig, engine *Engine) *DeduplicationOperation {
+	base := NewBaseOperation(id, domain.OperationDeduplication, config, engine)
+	return &DeduplicationOperation{
+		BaseOperation:   base,
+		duplicateGroups: make([]domain.DuplicateGroup, 0),
+		linked:          base.newPathList("linked"),
+		unique:          base.newPathList("unique_files"),
+		pinned:          base.newPathList("pinned"),
+		rawPairs:        base.newPathList("raw_pairs"),
+		withSidecars:    base.newPathList("with_sidecars"),
+		archives:        base.newPathList("mail_archives"),
+		onDisk:          base.newPathList("attachments_on_disk"),
+		variantsLinked:  base.newPathList("variants_linked"),
+		unsure:          base.newPathList("below_confidence"),
+		offloaded:       base.newPathList("offloaded"),
+	}
+}
+
+// dedupInode is one stored copy of a file with all scanned paths that refer to it
+type dedupInode struct {
+	info  *domain.FileInfo
+	paths []string
+	roots []string // Root each path was found under
+	hash  string
+
+	key        string // Size (and device and metadata, when linking) it is grouped by
+	partial    uint64
+	hasPartial bool
+	attachment bool // Inside a mail archive, hashed while the archive was read
+}
+
+// Execute performs the deduplication operation
+func (do *DeduplicationOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	if path := settingString(config, "pin_file", ""); path != "" {
+		pins, err := LoadPins(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load pin file: %w", err)
+		}
+		do.pins = pins
+	}
+	do.minConfidence, _ = domain.ParseConfidenceLevel(settingString(config, "min_confidence", "medium"))
+
+	if settingString(config, "mode", DedupModeReport) == DedupModeNames {
+		return do.executeNames(ctx, config)
+	}
+
+	// Start tracking progress
+	mailArchives := settingBool(config, "mail_archives", false)
+	compressedVariants := settingBool(config, "compressed_variants", false)
+	steps := 4
+	if mailArchives {
+		steps++
+	}
+	if compressedVariants {
+		steps++
+	}
+	tracker := do.engine.progressTracker.StartOperation(do.id, domain.OperationDeduplication, steps)
+	do.SetTracker(tracker)
+	do.rootLabels = config.RootLabels
+	do.preferDirs = settingStrings(config, "prefer_dirs")
+	do.keep = settingString(config, "keep", DedupKeepLinks)
+	syncFrom, syncTo := settingString(config, "sync_from", ""), settingString(config, "sync_to", "")
+	onTarget := make(map[*dedupInode]bool) // Content the sync target has a copy of
+
+	algorithm := config.HashAlgorithm
+	if algorithm == "" {
+		algorithm = "blake2b" // Default
+	}
+	mode := settingString(config, "mode", DedupModeReport)
+	hardlink := mode == DedupModeHardlink
+	// Linking files with different metadata would change the metadata of one of them
+	respectMetadata := settingBool(config, "respect_metadata", true)
+	sizeOnly := settingBool(config, "size_only", false)
+	verify := settingBool(config, "verify", false)
+
+	do.UpdateStep("Scanning files")
+
+	inodes, err := do.scan(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	if mailArchives {
+		do.UpdateStep("Reading mail archives")
+		attachments, err := do.readAttachments(ctx, config, inodes, algorithm)
+		if err != nil {
+			return nil, err
+		}
+		inodes = append(inodes, attachments...)
+	}
+
+	// Only files of the same size (on the same device and with the same
+	// metadata, when linking) can be duplicates
+	candidates := make(map[string][]*dedupInode)
+	for _, inode := range inodes {
+		info := inode.info
+		do.totalSize += info.Size
+		key := fmt.Sprintf("%d", info.Size)
+		if hardlink {
+			key += fmt.Sprintf(":%d", info.Device)
+			if respectMetadata {
+				key += fmt.Sprintf(":%o:%d:%d:%d", info.Mode, info.UID, info.GID, info.ModTime.UnixNano())
+			}
+		}
+		inode.key = key
+		candidates[key] = append(candidates[key], inode)
+	}
+
+	toHash := make([]*dedupInode, 0)
+	for _, group := range candidates {
+		if len(group) > 1 {
+			toHash = append(toHash, group...)
+		}
+	}
+
+	// Sample large candidates first so only those with a possible duplicate are read in full
+	if settingBool(config, "prefilter", true) && !sizeOnly {
+		do.UpdateStep("Pre-filtering candidates")
+		do.SetProgress(0, int64(len(toHash)), 0, 0)
+		if toHash, err = do.prefilter(ctx, config, toHash); err != nil {
+			return nil, err
+		}
+	}
+
+	var hashSize int64
+	for _, inode := range toHash {
+		hashSize += inode.info.Size
+	}
+
+	do.UpdateStep("Hashing candidates")
+	if sizeOnly {
+		toHash = nil // Grouped by size alone
+	}
+	do.SetProgress(0, int64(len(toHash)), 0, hashSize)
+	for _, inode := range toHash {
+		if !inode.attachment {
+			do.planRead(inode.paths[0], inode.info.Size)
+		}
+	}
+
+	if err := do.hashAll(ctx, config, toHash, algorithm); err != nil {
+		return nil, err
+	}
+
+	if hardlink {
+		do.UpdateStep("Linking duplicates")
+	} else {
+		do.UpdateStep("Grouping duplicates")
+	}
+
+	duplicates := make(map[*dedupInode]bool) // Copies other than the one kept of their group
+	keys := make([]string, 0, len(candidates))
+	for key := range candidates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// How sure the groups are identical, raised for the groups linked below
+	confidence := filesystem.HashConfidence(algorithm)
+	switch {
+	case verify:
+		confidence = domain.ConfidenceCertain
+	case sizeOnly:
+		confidence = domain.ConfidenceLow
+	}
+
+	for _, key := range keys {
+		for _, group := range matchGroups(candidates[key], sizeOnly) {
+			if err := do.CheckContext(ctx); err != nil {
+				return nil, err
+			}
+			if verify {
+				if group = do.verifyGroup(group); len(group) < 2 {
+					continue
+				}
+			}
+			labels := do.addGroup(group, algorithm, confidence)
+			for _, inode := range group[1:] {
+				duplicates[inode] = true
+			}
+			if syncTo != "" && slices.Contains(labels, syncTo) {
+				for _, inode := range group {
+					onTarget[inode] = true
+				}
+			}
+			if hardlink && confidence < do.minConfidence {
+				for _, inode := range group[1:] {
+					do.unsure.Add(inode.paths...)
+				}
+			} else if hardlink && !config.DryRun {
+				if do.link(group, algorithm) {
+					do.duplicateGroups[len(do.duplicateGroups)-1].Confidence = domain.ConfidenceCertain
+				}
+			} else if hardlink {
+				for _, inode := range group[1:] {
+					linked := true
+					for _, path := range inode.paths {
+						if do.kept(path, algorithm, inode.hash) {
+							do.pinned.Add(path)
+							linked = false
+						}
+					}
+					if linked {
+						do.planFree(inode.paths[0], inode.info.Size)
+					}
+				}
+			}
+		}
+	}
+
+	// One path per content, for operations fed with the unique files
+	for _, inode := range inodes {
+		if !duplicates[inode] && !inode.attachment {
+			do.unique.Add(inode.paths[0])
+		}
+	}
+
+	var variants []CompressedVariant
+	if compressedVariants {
+		do.UpdateStep("Comparing compressed variants")
+		if variants, err = do.compareVariants(ctx, config, inodes, algorithm); err != nil {
+			return nil, err
+		}
+	}
+
+	details := map[string]interface{}{
+		"duplicate_groups":  len(do.duplicateGroups),
+		"groups":            do.duplicateGroups,
+		"total_size":        do.totalSize,
+		"saveable_size":     do.saveableSize,
+		"hash_algorithm":    algorithm,
+		"mode":              mode,
+		"linked":            do.linked.Result(),
+		"reclaimed":         do.reclaimed,
+		"prefilter_skipped": do.prefilterSkipped,
+		"prefilter_cached":  do.prefilterCached,
+		"pinned":            do.pinned.Result(),
+		"unique_files":      do.unique.Result(),
+		"dry_run":           config.DryRun,
+		"confidence":        confidence,
+		"min_confidence":    do.minConfidence,
+		"below_confidence":  do.unsure.Result(),
+		"offloaded":         do.offloaded.Result(),
+	}
+	if mailArchives {
+		details["mail_archives"] = do.archives.Result()
+		details["attachments"] = do.attachments
+		details["attachments_on_disk"] = do.onDisk.Result()
+	}
+
+	summary := fmt.Sprintf("Deduplication: %d duplicate groups among %d files", len(do.duplicateGroups), len(inodes))
+	if mailArchives {
+		summary += fmt.Sprintf(" (%d of them attachments in %d mail archives)", do.attachments, do.archives.Len())
+	}
+	if syncFrom != "" {
+		plan, size := do.syncPlan(config, inodes, onTarget, syncFrom, syncTo)
+		details["sync_plan"] = plan
+		details["sync_size"] = size
+		summary += fmt.Sprintf(", %d files (%d bytes) on %s missing on %s", len(plan), size, syncFrom, syncTo)
+	}
+	if hardlink {
+		summary = fmt.Sprintf("Deduplication: %d files hardlinked in %d duplicate groups", do.linked.Len(), len(do.duplicateGroups))
+		if config.DryRun {
+			summary = fmt.Sprintf("Deduplication (dry run): %d duplicate groups would be hardlinked", len(do.duplicateGroups))
+		}
+		if pinned := do.pinned.Len(); pinned > 0 {
+			summary += fmt.Sprintf(", %d pinned files kept", pinned)
+		}
+		if unsure := do.unsure.Len(); unsure > 0 {
+			summary += fmt.Sprintf(", %d files below the minimum confidence kept", unsure)
+		}
+	}
+	if compressedVariants {
+		removed := 0
+		for _, variant := range variants {
+			if variant.Removed {
+				removed++
+			}
+		}
+		details["compressed_variants"] = variants
+		details["variant_saveable_size"] = do.variantSaveable
+		details["variants_linked"] = do.variantsLinked.Result()
+		summary += fmt.Sprintf(", %d files identical to their compressed variant", len(variants))
+		if removed > 0 {
+			summary += fmt.Sprintf(" (%d variants removed)", removed)
+		}
+	}
+	if offloaded := do.offloaded.Len(); offloaded > 0 {
+		summary += fmt.Sprintf(", %d stub files of offloaded content skipped", offloaded)
+	}
+
+	return do.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// hashAll computes the content hash of the given inodes in parallel
+func (do *DeduplicationOperation) hashAll(ctx context.Context, config domain.OperationConfig, inodes []*dedupInode, algorithm string) error {
+	return do.forEach(ctx, config, inodes, func(inode *dedupInode) {
+		if inode.attachment {
+			do.IncrementProgress(1, inode.info.Size) // Hashed while reading its archive
+			return
+		}
+		hash, err := do.engine.fileSystem.ComputeHash(inode.info.Path, algorithm)
+		if err != nil {
+			do.AddFileError(inode.info.Path, fmt.Errorf("failed to hash %s: %w", inode.info.Path, err))
+		} else {
+			inode.hash = hash
+		}
+		do.IncrementProgress(1, inode.info.Size)
+	})
+}
+
+// forEach runs work for the given inodes on config.Parallelism workers
+func (do *DeduplicationOperation) forEach(ctx context.Context, config domain.OperationConfig, inodes []*dedupInode, work func(inode *dedupInode)) error {
+	workers := config.Parallelism
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	// Stop handing out work once the context is cancelled or the error budget is used up
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	gate := do.workerGate(workers)
+	jobs := make(chan *dedupInode)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for inode := range jobs {
+				gate.Enter(runCtx)
+				work(inode)
+				gate.Leave()
+				if do.ErrorBudgetErr() != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, inode := range inodes {
+		if err := do.CheckContext(runCtx); err != nil {
+			break
+		}
+		select {
+		case jobs <- inode:
+		case <-runCtx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := do.ErrorBudgetErr(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// matchGroups splits candidates of the same size into groups of the same
+// hash, or takes them as one group when matching by size alone
+func matchGroups(candidates []*dedupInode, sizeOnly bool) [][]*dedupInode {
+	if sizeOnly {
+		if len(candidates) < 2 {
+			return nil
+		}
+		return [][]*dedupInode{candidates}
+	}
+
+	byHash := make(map[string][]*dedupInode)
+	hashes := make([]string, 0)
+	for _, inode := range candidates {
+		if inode.hash == "" {
+			continue // Not hashed (single candidate or failed)
+		}
+		if _, ok := byHash[inode.hash]; !ok {
+			hashes = append(hashes, inode.hash)
+		}
+		byHash[inode.hash] = append(byHash[inode.hash], inode)
+	}
+
+	groups := make([][]*dedupInode, 0, len(hashes))
+	for _, hash := range hashes {
+		if group := byHash[hash]; len(group) > 1 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// verifyGroup compares every copy of a group with the first byte by byte and
+// returns the copies that are identical to it
+func (do *DeduplicationOperation) verifyGroup(group []*dedupInode) []*dedupInode {
+	verified := group[:1:1]
+	for _, inode := range group[1:] {
+		same, err := sameContent(group[0].paths[0], inode.paths[0])
+		if err != nil {
+			do.AddFileError(inode.paths[0], fmt.Errorf("failed to compare %s: %w", inode.paths[0], err))
+			continue
+		}
+		if !same {
+			do.AddFileError(inode.paths[0], fmt.Errorf("hash collision: %s differs from %s", inode.paths[0], group[0].paths[0]))
+			continue
+		}
+		verified = append(verified, inode)
+	}
+	return verified
+}
+
+// addGroup records a group of identical files and returns the labels of the
+// roots holding them
+func (do *DeduplicationOperation) addGroup(group []*dedupInode, algorithm string, confidence float64) []string {
+	sortInodes(group, do.preferDirs, do.keep)
+	size := group[0].info.Size
+
+	files := make([]domain.FileInfo, 0)
+	labels := make([]string, 0)
+	for _, inode := range group {
+		for i, path := range inode.paths {
+			file := *inode.info
+			file.Path = path
+			file.Name = filepath.Base(path)
+			if inode.hash != "" {
+				file.Hash = inode.hash
+				file.HashType = algorithm
+			}
+			file.Label = do.rootLabels[inode.roots[i]]
+			// An attachment is no copy a sync could rely on
+			if file.Label != "" && !inode.attachment && !slices.Contains(labels, file.Label) {
+				labels = append(labels, file.Label)
+			}
+			files = append(files, file)
+		}
+	}
+	sort.Strings(labels)
+
+	// Only files take space that can be saved
+	stored := 0
+	for _, inode := range group {
+		if !inode.attachment {
+			stored++
+		}
+	}
+	if stored > 0 {
+		for _, inode := range group {
+			if inode.attachment {
+				do.onDisk.Add(inode.paths[0])
+			}
+		}
+	}
+
+	saveable := size * int64(max(stored-1, 0))
+	do.saveableSize += saveable
+	id := fmt.Sprintf("group-%d", len(do.duplicateGroups)+1)
+	if do.config.Deterministic {
+		// Copies with equal content but different metadata form more than one group
+		if do.groupIDs == nil {
+			do.groupIDs = make(map[string]int)
+		}
+		base := groupID(group[0].hash)
+		if group[0].hash == "" {
+			base = groupID(group[0].key) // Matched by size alone
+		}
+		do.groupIDs[base]++
+		id = base
+		if n := do.groupIDs[base]; n > 1 {
+			id = fmt.Sprintf("%s-%d", base, n)
+		}
+	}
+	do.duplicateGroups = append(do.duplicateGroups, domain.DuplicateGroup{
+		ID:          id,
+		Files:       files,
+		TotalSize:   size * int64(len(group)),
+		SaveablSize: saveable,
+		HashType:    files[0].HashType,
+		Confidence:  confidence,
+		Labels:      labels,
+	})
+	return labels
+}
+
+// syncPlan lists the files found under the roots labeled from whose content
+// no root labeled to has, with their place below the first root labeled to.
+// Files without a possible duplicate were never hashed and are missing there
+// by definition.
+func (do *DeduplicationOperation) syncPlan(config domain.OperationConfig, inodes []*dedupInode, onTarget map[*dedupInode]bool, from, to string) ([]SyncPlanEntry, int64) {
+	targetRoot, _ := labelRoot(config, to)
+	plan := make([]SyncPlanEntry, 0)
+	var size int64
+	for _, inode := range inodes {
+		if onTarget[inode] || inode.attachment {
+			continue
+		}
+		source, sourceRoot, linked := "", "", false
+		for i, root := range inode.roots {
+			switch do.rootLabels[root] {
+			case to:
+				linked = true // Hardlinked into the target already
+			case from:
+				if source == "" {
+					source, sourceRoot = inode.paths[i], root
+				}
+			}
+		}
+		if source == "" || linked {
+			continue
+		}
+		rel, err := filepath.Rel(sourceRoot, source)
+		if err != nil {
+			continue
+		}
+		plan = append(plan, SyncPlanEntry{Source: source, Target: filepath.Join(targetRoot, rel), Size: inode.info.Size})
+		size += inode.info.Size
+	}
+	return plan, size
+}
+
+// kept reports whether path must never be deleted or replaced: it is pinned
+// or its extension is configured to be kept
+func (do *DeduplicationOperation) kept(path, algorithm, hash string) bool {
+	return do.config.ExtensionAction(path) == domain.ExtensionKeep || do.pins.Pinned(do.engine.fileSystem, path, algorithm, hash)
+}
+
+// link replaces every copy in the group by a hardlink to the first one,
+// except pinned copies. The content is compared byte by byte before a copy
+// is replaced; link reports whether every copy compared identical.
+func (do *DeduplicationOperation) link(group []*dedupInode, algorithm string) bool {
+	master := group[0].paths[0]
+	verified := true
+	for _, inode := range group[1:] {
+		same, err := sameContent(master, inode.paths[0])
+		if err != nil {
+			do.AddFileError(inode.paths[0], fmt.Errorf("failed to compare %s: %w", inode.paths[0], err))
+			verified = false
+			continue
+		}
+		if !same {
+			do.AddFileError(inode.paths[0], fmt.Errorf("hash collision: %s differs from %s, not linked", inode.paths[0], master))
+			verified = false
+			continue
+		}
+
+		replaced := 0
+		for _, path := range inode.paths {
+			if do.kept(path, algorithm, inode.hash) {
+				do.pinned.Add(path)
+				continue
+			}
+			err := do.replaceWithLink(master, path, inode.info)
+			if errors.Is(err, syscall.EMLINK) {
+				// The master reached the link limit. This path is untouched and
+				// becomes the master of the copies that follow; the paths of this
+				// copy left to link are hardlinks to it already.
+				master = path
+				break
+			}
+			if err != nil {
+				do.AddFileError(path, fmt.Errorf("failed to link %s: %w", path, err))
+				continue
+			}
+			replaced++
+			do.linked.Add(path)
+		}
+
+		// The space is only freed once no other link keeps the copy alive
+		if replaced == len(inode.paths) && (inode.info.Links == 0 || inode.info.Links == uint64(len(inode.paths))) {
+			do.reclaimed += inode.info.Size
+		}
+	}
+	return verified
+}
+
+// replaceWithLink atomically replaces path by a hardlink to target, unless
+// path changed since it was scanned
+func (do *DeduplicationOperation) replaceWithLink(target, path string, scanned *domain.FileInfo) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if info.Size() != scanned.Size || !info.ModTime().Equal(scanned.ModTime) {
+		return fmt.Errorf("file changed since it was scanned")
+	}
+
+	fileSystem := do.engine.fileSystem
+	tmp := filepath.Join(filepath.Dir(path), fmt.Sprintf(".fileops-link-%d", os.Getpid()))
+	if err := fileSystem.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := fileSystem.Link(target, tmp); err != nil {
+		if errors.Is(err, syscall.EMLINK) {
+			return syscall.EMLINK
+		}
+		return err
+	}
+	if err := fileSystem.Move(tmp, path); err != nil {
+		_ = fileSystem.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// sameContent compares two files byte by byte
+func sameContent(a, b string) (bool, error) {
+	if err := filesystem.Descriptors().Acquire(context.Background(), 2); err != nil {
+		return false, err
+	}
+	defer filesystem.Descriptors().Release(2)
+
+	fa, err := os.Open(a)
+	if err != nil {
+		return false, filesystem.DescriptorError(err)
+	}
+	defer fa.Close()
+	fb, err := os.Open(b)
+	if err != nil {
+		return false, filesystem.DescriptorError(err)
+	}
+	defer fb.Close()
+
+	return sameStream(fa, fb)
+}
+
+// sameStream compares two readers to their end
+func sameStream(a, b io.Reader) (bool, error) {
+	bufA := make([]byte, 64*1024)
+	bufB := make([]byte, 64*1024)
+	for {
+		na, errA := io.ReadFull(a, bufA)
+		nb, errB := io.ReadFull(b, bufB)
+		if na != nb || !bytes.Equal(bufA[:na], bufB[:nb]) {
+			return false, nil
+		}
+		if errA == io.EOF || errA == io.ErrUnexpectedEOF {
+			return errB == io.EOF || errB == io.ErrUnexpectedEOF, nil
+		}
+		if errA != nil {
+			return false, errA
+		}
+		if errB != nil {
+			return false, errB
+		}
+	}
+}
+
+// sortInodes orders a group so the copy to keep comes first: the one under
+// the earliest preferred directory, then the one the keep policy picks
+// (by default the one with the most links, i.e. fewest changes needed), then
+// by path so the oldest dated snapshot is kept
+func sortInodes(group []*dedupInode, preferDirs []string, keep string) {
+	rank := make(map[*dedupInode]int, len(group))
+	for _, inode := range group {
+		sort.SliceStable(inode.paths, func(i, j int) bool {
+			ri, rj := preferRank(inode.paths[i], preferDirs), preferRank(inode.paths[j], preferDirs)
+			if ri != rj {
+				return ri < rj
+			}
+			return inode.paths[i] < inode.paths[j]
+		})
+		rank[inode] = preferRank(inode.paths[0], preferDirs)
+	}
+	sort.Slice(group, func(i, j int) bool {
+		a, b := group[i], group[j]
+		if rank[a] != rank[b] {
+			return rank[a] < rank[b]
+		}
+		switch keep {
+		case DedupKeepOldest:
+			if !a.info.ModTime.Equal(b.info.ModTime) {
+				return a.info.ModTime.Before(b.info.ModTime)
+			}
+		case DedupKeepNewest:
+			if !a.info.ModTime.Equal(b.info.ModTime) {
+				return a.info.ModTime.After(b.info.ModTime)
+			}
+		case DedupKeepShortest:
+			if len(a.paths[0]) != len(b.paths[0]) {
+				return len(a.paths[0]) < len(b.paths[0])
+			}
+		}
+		if a.info.Links != b.info.Links {
+			return a.info.Links > b.info.Links
+		}
+		return a.paths[0] < b.paths[0]
+	})
+}
+
+// preferRank returns the position of the first preferred directory holding
+// path, or len(preferDirs) when none does
+func preferRank(path string, preferDirs []string) int {
+	for i, dir := range preferDirs {
+		if path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator)) {
+			return i
+		}
+	}
+	return len(preferDirs)
+}
+
+// Validate validates the deduplication operation configuration
+func (do *DeduplicationOperation) Validate(config domain.OperationConfig) error {
+	return do.ValidateConfig()
+}
+
+// EstimateProgress provides an estimate of the operation's scope
+func (do *DeduplicationOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	return do.estimateScope(config, 4)
+}
+
+// scan collects the regular files below all roots, merging paths that already
+// share an inode
+func (do *DeduplicationOperation) scan(ctx context.Context, config domain.OperationConfig) ([]*dedupInode, error) {
+	inodes := make([]*dedupInode, 0)
+	seen := make(map[[2]uint64]*dedupInode)
+	visited := make(map[string]bool) // Overlapping roots visit paths twice
+
+	for _, root := range config.TargetPaths {
+		err := do.Walk(ctx, root, config, func(path string, info *domain.FileInfo, err error) error {
+			if err != nil {
+				do.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+				return nil // Continue walking
+			}
+			if info == nil {
+				return nil
+			}
+			if do.isExcluded(path, config) {
+				if info.IsDir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !os.FileMode(info.Mode).IsRegular() || info.Size == 0 || visited[path] {
+				return nil
+			}
+			if config.ExtensionAction(path) == domain.ExtensionSkip {
+				return nil
+			}
+			if (config.MinFileSize > 0 && info.Size < config.MinFileSize) ||
+				(config.MaxFileSize > 0 && info.Size > config.MaxFileSize) {
+				return nil
+			}
+			visited[path] = true
+			if stub.Is(path, info.Size) {
+				// Stubs of the same content are identical but each stands for its own file
+				do.offloaded.Add(path)
+				return nil
+			}
+
+			if info.Inode != 0 {
+				key := [2]uint64{info.Device, info.Inode}
+				if inode, ok := seen[key]; ok {
+					inode.paths = append(inode.paths, path)
+					inode.roots = append(inode.roots, root)
+					return nil
+				}
+				inode := &dedupInode{info: info, paths: []string{path}, roots: []string{root}}
+				seen[key] = inode
+				inodes = append(inodes, inode)
+				return nil
+			}
+			inodes = append(inodes, &dedupInode{info: info, paths: []string{path}, roots: []string{root}})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk path %s: %w", root, err)
+		}
+	}
+	return inodes, nil
+}
+
+// readAttachments reads the attachments of the mail archives among inodes,
+// hashing each so it is grouped with identical files without being
+// extracted. Attachments are named by their archive and message, e.g.
+// inbox.mbox#12/invoice.pdf.
+func (do *DeduplicationOperation) readAttachments(ctx context.Context, config domain.OperationConfig, inodes []*dedupInode, algorithm string) ([]*dedupInode, error) {
+	archives := make([]*dedupInode, 0)
+	var totalSize int64
+	for _, inode := range inodes {
+		if mail.Supported(inode.info.Path) {
+			archives = append(archives, inode)
+			totalSize += inode.info.Size
+		}
+	}
+	do.SetProgress(0, int64(len(archives)), 0, totalSize)
+
+	attachments := make([]*dedupInode, 0)
+	for _, archive := range archives {
+		if err := do.CheckContext(ctx); err != nil {
+			return nil, err
+		}
+		err := mail.Walk(archive.info.Path, func(a *mail.Attachment) error {
+			size := int64(len(a.Data))
+			if (config.MinFileSize > 0 && size < config.MinFileSize) ||
+				(config.MaxFileSize > 0 && size > config.MaxFileSize) {
+				return nil
+			}
+			hasher, err := filesystem.NewHasher(algorithm)
+			if err != nil {
+				return err
+			}
+			hasher.Write(a.Data)
+			var partial uint64
+			if size >= prefilterMinSize {
+				partial, _ = partialHashOf(bytes.NewReader(a.Data), size)
+			}
+
+			info := *archive.info
+			info.Path, info.Name, info.Size = a.Path(), a.Filename, size
+			info.Inode, info.Links = 0, 0
+			if !a.Date.IsZero() {
+				info.ModTime = a.Date
+			}
+			attachments = append(attachments, &dedupInode{
+				info:       &info,
+				paths:      []string{info.Path},
+				roots:      archive.roots[:1],
+				hash:       fmt.Sprintf("%x", hasher.Sum(nil)),
+				partial:    partial,
+				hasPartial: true,
+				attachment: true,
+			})
+			do.attachments++
+			return nil
+		})
+		if err != nil {
+			do.AddFileError(archive.info.Path, fmt.Errorf("failed to read mail archive %s: %w", archive.info.Path, err))
+		} else {
+			do.archives.Add(archive.info.Path)
+		}
+		do.IncrementProgress(1, archive.info.Size)
+	}
+	return attachments, nil
+}
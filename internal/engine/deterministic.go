@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// groupID derives the ID of a group from the content hash its files share,
+// so that it does not depend on the order groups were found in
+func groupID(hash string) string {
+	if len(hash) > 16 {
+		hash = hash[:16]
+	}
+	return "group-" + hash
+}
+
+// sortIssues orders errors and warnings by file and message, as parallel
+// workers report them in no particular order
+func sortIssues(result *domain.OperationResult) {
+	sort.SliceStable(result.Errors, func(i, j int) bool {
+		a, b := result.Errors[i], result.Errors[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Error < b.Error
+	})
+	sort.SliceStable(result.Warnings, func(i, j int) bool {
+		a, b := result.Warnings[i], result.Warnings[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Message < b.Message
+	})
+}
+
+// volatileDetails are result details that depend on the run rather than the
+// data: retries of transient failures, hits of caches earlier runs filled and
+// the timed cost estimates of dry runs
+var volatileDetails = []string{"retry_stats", "prefilter_cached", "cost_estimate"}
+
+// reproducible returns a copy of result without what differs between two runs
+// over the same data: the operation ID, times, durations, the artifacts
+// directory, the environment, the slow paths and the volatile details
+func reproducible(result *domain.OperationResult) *domain.OperationResult {
+	report := *result
+	report.ID = ""
+	report.StartTime, report.EndTime = time.Time{}, time.Time{}
+	report.Duration = 0
+	report.Artifacts = ""
+	report.Environment = nil
+	report.SlowPaths = nil
+
+	report.Errors = append([]domain.OperationError(nil), result.Errors...)
+	for i := range report.Errors {
+		report.Errors[i].Timestamp = time.Time{}
+	}
+	report.Warnings = append([]domain.OperationWarning(nil), result.Warnings...)
+	for i := range report.Warnings {
+		report.Warnings[i].Timestamp = time.Time{}
+	}
+
+	report.Details = make(map[string]interface{}, len(result.Details))
+	for key, value := range result.Details {
+		if !slices.Contains(volatileDetails, key) {
+			report.Details[key] = value
+		}
+	}
+	return &report
+}
@@ -0,0 +1,28 @@
+// Package engine runs file operations. Embedders create an engine, configure
+// it with its Set methods and run operations with Execute:
+//
+//	e := engine.NewEngine(nil, nil, log)
+//	e.SetRepository(repository)
+//	low := domain.PriorityLow
+//	result, err := e.Execute(ctx, domain.OperationDeduplication, domain.OperationConfig{
+//		TargetPaths:   []string{"/data"},
+//		Recursive:     true,
+//		HashAlgorithm: "blake2b",
+//	}, engine.ExecuteOptions{
+//		ID:       engine.NewOperationID(domain.OperationDeduplication),
+//		Priority: &low,
+//		Progress: func(event events.Event) {
+//			if event.Type == events.StepStarted {
+//				fmt.Println(event.Step)
+//			}
+//		},
+//	})
+//
+// ExecuteOperation and ExecuteOperationWithID are shorthands for Execute
+// with default options and with only an ID.
+//
+// Similarity runs compare files with a SimilarityDetector picked by the
+// method setting. New methods are registered like operation factories:
+//
+//	e.RegisterSimilarityDetector("audio", audioDetector{})
+package engine
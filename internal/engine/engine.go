@@ -2,14 +2,34 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/a4abhishek/fileops/internal/archive"
+	"github.com/a4abhishek/fileops/internal/bagit"
+	"github.com/a4abhishek/fileops/internal/encryption"
+	"github.com/a4abhishek/fileops/internal/integrity"
+	"github.com/a4abhishek/fileops/internal/lock"
 	"github.com/a4abhishek/fileops/internal/logger"
+	"github.com/a4abhishek/fileops/internal/mail"
+	"github.com/a4abhishek/fileops/internal/metadata"
+	"github.com/a4abhishek/fileops/internal/pack"
+	"github.com/a4abhishek/fileops/internal/pdf"
+	"github.com/a4abhishek/fileops/internal/snapshot"
+	"github.com/a4abhishek/fileops/internal/thumbnail"
+	"github.com/a4abhishek/fileops/internal/transcode"
 	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/events"
 	"github.com/a4abhishek/fileops/pkg/filesystem"
 	"github.com/a4abhishek/fileops/pkg/progress"
+	"github.com/a4abhishek/fileops/pkg/schema"
 )
 
 // Engine is the core operation engine that orchestrates file operations
@@ -17,7 +37,24 @@ type Engine struct {
 	fileSystem      domain.FileSystem
 	progressTracker *progress.Tracker
 	logger          *logger.Logger
+	events          *events.Bus
 	operations      map[domain.OperationType]OperationFactory
+	detectors       map[string]SimilarityDetector
+	locks           *lock.Manager
+	lockWait        time.Duration
+	repository      domain.Repository
+	policy          *Policy
+	principal       Principal
+	environment     *domain.Environment
+	snapshotter     snapshot.Snapshotter
+	throttle        *Throttle
+	benchProfile    *BenchProfile
+	runsDirectory   string
+	runningIDs      map[string]bool // IDs of the operations running, see claimOperationID
+	batchSize       int
+	fsyncInterval   time.Duration
+	reportPath      string
+	git             gitState
 	mu              sync.RWMutex
 }
 
@@ -40,14 +77,56 @@ func NewEngine(fs domain.FileSystem, tracker *progress.Tracker, log *logger.Logg
 		fileSystem:      fs,
 		progressTracker: tracker,
 		logger:          log,
+		events:          events.NewBus(),
 		operations:      make(map[domain.OperationType]OperationFactory),
+		detectors:       make(map[string]SimilarityDetector),
+		runningIDs:      make(map[string]bool),
+		batchSize:       DefaultBatchSize,
+		fsyncInterval:   DefaultFsyncInterval,
 	}
 
+	// Progress and logging follow the events operations publish
+	engine.events.Subscribe(tracker.HandleEvent)
+	engine.events.Subscribe(engine.logEvent, loggedEvents...)
+
 	// Register built-in operation factories
 	engine.RegisterOperation(domain.OperationCleanup, &CleanupFactory{engine: engine})
 	engine.RegisterOperation(domain.OperationDeduplication, &DeduplicationFactory{engine: engine})
 	engine.RegisterOperation(domain.OperationConsolidation, &ConsolidationFactory{engine: engine})
 	engine.RegisterOperation(domain.OperationOwnership, &OwnershipFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationThumbnail, &ThumbnailFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationStripMetadata, &StripMetadataFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationOrganization, &OrganizationFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationContentIndex, &ContentIndexFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationChecksum, &ChecksumFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationRepair, &RepairFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationEncrypt, &CryptFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationDecrypt, &CryptFactory{engine: engine, decrypt: true})
+	engine.RegisterOperation(domain.OperationPack, &PackFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationPackRestore, &PackRestoreFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationUpload, &UploadFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationTriage, &TriageFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationVersions, &VersionsFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationOwners, &OwnersFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationGrant, &GrantFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationStats, &StatsFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationSimilarity, &SimilarityFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationTranscode, &TranscodeFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationPDFMerge, &PDFMergeFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationPDFSplit, &PDFSplitFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationMailExtract, &MailExtractFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationBagExport, &BagExportFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationBagValidate, &BagValidateFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationRehash, &RehashFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationIngest, &IngestFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationMigration, &MigrationFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationTiering, &TieringFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationApplyPlan, &ApplyPlanFactory{engine: engine})
+	engine.RegisterOperation(domain.OperationVerifyTree, &VerifyTreeFactory{engine: engine})
+
+	// Register built-in similarity methods
+	engine.RegisterSimilarityDetector(DefaultSimilarityMethod, dhashDetector{})
+	engine.RegisterSimilarityDetector(AudioSimilarityMethod, &chromaprintDetector{})
 
 	return engine
 }
@@ -59,29 +138,165 @@ func (e *Engine) RegisterOperation(operationType domain.OperationType, factory O
 	e.operations[operationType] = factory
 }
 
+// SetLockManager enables advisory locking of target paths. Operations whose
+// paths overlap a running operation wait up to wait before being refused.
+func (e *Engine) SetLockManager(manager *lock.Manager, wait time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.locks = manager
+	e.lockWait = wait
+}
+
+// SetRepository enables persisting operation results (including their
+// per-item errors) so failed items can be inspected and retried later
+func (e *Engine) SetRepository(repository domain.Repository) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.repository = repository
+}
+
+// SetPolicy restricts the operations the engine runs to those policy allows
+// principal
+func (e *Engine) SetPolicy(policy *Policy, principal Principal) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.policy = policy
+	e.principal = principal
+}
+
+// SetThrottle makes the worker pools of every operation give up workers
+// while the load of the machine is high; nil runs them at full strength
+func (e *Engine) SetThrottle(throttle *Throttle) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.throttle = throttle
+}
+
+// SetBenchProfile sets the device throughput dry runs estimate the duration
+// of the real run from
+func (e *Engine) SetBenchProfile(profile *BenchProfile) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.benchProfile = profile
+}
+
+// SetEnvironment records env in the result of every operation
+func (e *Engine) SetEnvironment(env *domain.Environment) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.environment = env
+}
+
+// SetSnapshotter snapshots the filesystems of the target paths before every
+// destructive run, so it can be rolled back at once
+func (e *Engine) SetSnapshotter(snapshotter snapshot.Snapshotter) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.snapshotter = snapshotter
+}
+
+// ExecuteOptions are the options of a single run of Execute. The zero value
+// runs with a new ID, normal priority and the engine's repository.
+type ExecuteOptions struct {
+	// ID of the operation; empty for a new one from NewOperationID. Supplied
+	// IDs must pass ValidateOperationID and not belong to a running operation
+	// or stored result.
+	ID string
+	// Priority below normal runs the operation with a single worker, leaving
+	// the machine to other work; higher priorities, and nil, run as configured
+	Priority *domain.Priority
+	// Progress receives the events of this run only: steps, progress,
+	// changes, errors and warnings. Like every event handler it runs on the
+	// publishing goroutine and must return quickly.
+	Progress events.Handler
+	// Repository stores the result and long path lists of this run instead
+	// of the repository set with SetRepository
+	Repository domain.Repository
+}
+
 // ExecuteOperation executes an operation with the given configuration
 func (e *Engine) ExecuteOperation(ctx context.Context, operationType domain.OperationType, config domain.OperationConfig) (*domain.OperationResult, error) {
-	return e.ExecuteOperationWithID(ctx, operationType, config, "")
+	return e.Execute(ctx, operationType, config, ExecuteOptions{})
 }
 
-// ExecuteOperationWithID executes an operation with a specific operation ID (or generates one if empty)
+// ExecuteOperationWithID executes an operation with a specific operation ID,
+// or a new one if empty (see ExecuteOptions.ID)
 func (e *Engine) ExecuteOperationWithID(ctx context.Context, operationType domain.OperationType, config domain.OperationConfig, operationID string) (*domain.OperationResult, error) {
+	return e.Execute(ctx, operationType, config, ExecuteOptions{ID: operationID})
+}
+
+// Execute runs an operation with the given configuration and options. Every
+// other Execute method of the engine runs through it.
+func (e *Engine) Execute(ctx context.Context, operationType domain.OperationType, config domain.OperationConfig, options ExecuteOptions) (*domain.OperationResult, error) {
 	e.mu.RLock()
 	factory, exists := e.operations[operationType]
+	repository := e.repository
 	e.mu.RUnlock()
 
 	if !exists {
 		return nil, fmt.Errorf("operation type %s not supported", operationType)
 	}
+	if options.Repository != nil {
+		repository = options.Repository
+	}
+
+	// Generate operation ID if not provided
+	operationID := options.ID
+	if operationID == "" {
+		operationID = NewOperationID(operationType)
+	} else if err := ValidateOperationID(operationID); err != nil {
+		return nil, err
+	}
+	release, err := e.claimOperationID(operationID, repository)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// Resolve templated destinations, e.g. /backups/{{.Date}}/{{.Operation}}.
+	// Results keep the templates so reruns resolve them anew.
+	requested := config
+	paths := NewPathContext(operationType, operationID)
+	config, err = paths.expandDestinations(config)
+	if err != nil {
+		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	}
 
 	// Validate configuration
 	if err := factory.Validate(config); err != nil {
 		return nil, fmt.Errorf("configuration validation failed: %w", err)
 	}
 
-	// Generate operation ID if not provided
-	if operationID == "" {
-		operationID = generateOperationID(operationType)
+	// Refuse what the policy does not allow before anything is touched
+	e.mu.RLock()
+	policy, principal := e.policy, e.principal
+	e.mu.RUnlock()
+	if policy != nil {
+		if err := policy.Check(principal, operationType, config); err != nil {
+			return nil, err
+		}
+	}
+
+	// Lock target paths so overlapping runs cannot delete each other's originals
+	e.mu.RLock()
+	locks, lockWait := e.locks, e.lockWait
+	e.mu.RUnlock()
+	if locks != nil && !config.DryRun {
+		held, err := locks.Acquire(ctx, operationID, lockRoots(config), lockWait)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock target paths: %w", err)
+		}
+		defer func() { _ = held.Release() }()
+	}
+
+	// Snapshot what a destructive run may change; no snapshot, no run
+	snapshots, err := e.takeSnapshots(ctx, operationType, operationID, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Priority != nil && *options.Priority < domain.PriorityNormal {
+		config.Parallelism = 1
 	}
 
 	// Create operation
@@ -89,22 +304,88 @@ func (e *Engine) ExecuteOperationWithID(ctx context.Context, operationType domai
 	if err != nil {
 		return nil, fmt.Errorf("failed to create operation: %w", err)
 	}
+	if base, ok := operation.(interface{ setRepository(domain.Repository) }); ok {
+		base.setRepository(repository)
+	}
 
-	e.logger.Info("Starting operation", "id", operationID, "type", operationType)
+	if options.Progress != nil {
+		unsubscribe := e.events.Subscribe(func(event events.Event) {
+			if event.OperationID == operationID {
+				options.Progress(event)
+			}
+		})
+		defer unsubscribe()
+	}
 
-	// Start progress tracking
-	tracker := e.progressTracker.StartOperation(operationID, operationType, 5) // Default 5 steps
+	// Keep the plan, report, undo journal, errors and log of the run
+	run := e.startRun(operationID, operationType, requested, principal)
+
+	e.events.Publish(events.Event{
+		Type:          events.OperationStarted,
+		OperationID:   operationID,
+		OperationType: operationType,
+		TotalSteps:    5, // Default, operations start their own tracking
+	})
+
+	// Remember the moves of the run to move the files back if it aborts
+	var moves *moveLog
+	if config.RollbackOnAbort && !config.DryRun {
+		moves = &moveLog{}
+		unsubscribe := e.events.Subscribe(func(event events.Event) {
+			if event.OperationID == operationID {
+				moves.add(event.Path, event.Target)
+			}
+		}, events.FileMoved)
+		defer unsubscribe()
+	}
 
 	// Execute operation
+	retriesBefore := e.retryStats()
 	result, err := operation.Execute(ctx, config)
+
+	if err != nil && moves != nil && errors.Is(err, ErrErrorBudgetExceeded) {
+		err = e.rollBack(moves, err)
+	}
 	if err != nil {
-		tracker.Fail(err.Error())
-		e.logger.Error("Operation failed", "id", operationID, "error", err)
+		e.events.Publish(events.Event{
+			Type:          events.OperationFinished,
+			OperationID:   operationID,
+			OperationType: operationType,
+			Status:        domain.StatusFailed,
+			Error:         err.Error(),
+		})
+		if failed, ok := operation.(interface {
+			FailedResult(error) *domain.OperationResult
+		}); ok {
+			result := failed.FailedResult(err)
+			recordSnapshots(result, snapshots)
+			run.finish(result, err)
+			e.saveResult(repository, result, requested)
+			e.writeReport(paths, result, config.Deterministic)
+		} else {
+			run.finish(nil, err)
+		}
 		return nil, err
 	}
 
-	tracker.Complete()
-	e.logger.Info("Operation completed", "id", operationID, "duration", result.Duration)
+	if retryStats := diffRetryStats(retriesBefore, e.retryStats()); len(retryStats) > 0 {
+		if result.Details == nil {
+			result.Details = make(map[string]interface{})
+		}
+		result.Details["retry_stats"] = retryStats
+	}
+	recordSnapshots(result, snapshots)
+
+	e.events.Publish(events.Event{
+		Type:          events.OperationFinished,
+		OperationID:   operationID,
+		OperationType: operationType,
+		Status:        domain.StatusCompleted,
+		Duration:      result.Duration,
+	})
+	run.finish(result, nil)
+	e.saveResult(repository, result, requested)
+	e.writeReport(paths, result, config.Deterministic)
 
 	return result, nil
 }
@@ -112,11 +393,32 @@ func (e *Engine) ExecuteOperationWithID(ctx context.Context, operationType domai
 // ExecuteOperationWithProgress executes an operation and returns the operation ID for progress tracking
 func (e *Engine) ExecuteOperationWithProgress(ctx context.Context, operationType domain.OperationType, config domain.OperationConfig) (string, *domain.OperationResult, error) {
 	// Generate operation ID
-	operationID := generateOperationID(operationType)
-	result, err := e.ExecuteOperationWithID(ctx, operationType, config, operationID)
+	operationID := NewOperationID(operationType)
+	result, err := e.Execute(ctx, operationType, config, ExecuteOptions{ID: operationID})
 	return operationID, result, err
 }
 
+// EstimateOperation estimates the items, bytes and duration of an operation
+// without running it, from a sampled scan of its paths
+func (e *Engine) EstimateOperation(operationType domain.OperationType, config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	e.mu.RLock()
+	factory, exists := e.operations[operationType]
+	e.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("operation type %s not supported", operationType)
+	}
+	if err := factory.Validate(config); err != nil {
+		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	}
+
+	operation, err := factory.Create(NewOperationID(operationType), config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create operation: %w", err)
+	}
+	return operation.EstimateProgress(config)
+}
+
 // GetSupportedOperations returns list of supported operation types
 func (e *Engine) GetSupportedOperations() []domain.OperationType {
 	e.mu.RLock()
@@ -134,6 +436,11 @@ func (e *Engine) GetProgressTracker() *progress.Tracker {
 	return e.progressTracker
 }
 
+// Events returns the bus operations publish their events on
+func (e *Engine) Events() *events.Bus {
+	return e.events
+}
+
 // GetFileSystem returns the file system interface
 func (e *Engine) GetFileSystem() domain.FileSystem {
 	return e.fileSystem
@@ -144,32 +451,147 @@ func (e *Engine) GetLogger() *logger.Logger {
 	return e.logger
 }
 
-// generateOperationID generates a unique operation ID
-func generateOperationID(operationType domain.OperationType) string {
-	timestamp := time.Now().Format("20060102-150405")
-	return fmt.Sprintf("%s-%s", operationType, timestamp)
+// retryStats returns the retry counters of the file system, if it retries
+func (e *Engine) retryStats() map[string]filesystem.RetryStats {
+	if retrying, ok := e.fileSystem.(interface {
+		RetryStats() map[string]filesystem.RetryStats
+	}); ok {
+		return retrying.RetryStats()
+	}
+	return nil
+}
+
+// diffRetryStats returns the retry activity between two snapshots, omitting idle methods
+func diffRetryStats(before, after map[string]filesystem.RetryStats) map[string]filesystem.RetryStats {
+	diff := make(map[string]filesystem.RetryStats)
+	for op, current := range after {
+		previous := before[op]
+		delta := filesystem.RetryStats{
+			Calls:     current.Calls - previous.Calls,
+			Retries:   current.Retries - previous.Retries,
+			Recovered: current.Recovered - previous.Recovered,
+			Exhausted: current.Exhausted - previous.Exhausted,
+		}
+		if delta.Retries > 0 {
+			diff[op] = delta
+		}
+	}
+	return diff
+}
+
+// saveResult stores result with the configuration it ran with in repository, if any
+func (e *Engine) saveResult(repository domain.Repository, result *domain.OperationResult, config domain.OperationConfig) {
+	if repository == nil || result == nil {
+		return
+	}
+
+	result.Config = &config
+	e.mu.RLock()
+	result.Environment = e.environment
+	e.mu.RUnlock()
+	if err := repository.SaveResult(result); err != nil {
+		e.logger.Warn("Failed to store operation result", "id", result.ID, "error", err)
+	}
+}
+
+// takeSnapshots snapshots the filesystems of the paths a destructive run may
+// modify, if a snapshotter is set
+func (e *Engine) takeSnapshots(ctx context.Context, operationType domain.OperationType, operationID string, config domain.OperationConfig) ([]snapshot.Snapshot, error) {
+	e.mu.RLock()
+	snapshotter := e.snapshotter
+	e.mu.RUnlock()
+	if snapshotter == nil || !destructive(operationType, config) {
+		return nil, nil
+	}
+
+	snapshots, err := snapshotter.Snapshot(ctx, lockRoots(config), "fileops-"+operationID)
+	for _, taken := range snapshots {
+		e.logger.Info("Snapshot taken", "root", taken.Root, "id", taken.ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot target paths: %w", err)
+	}
+	return snapshots, nil
 }
 
+// recordSnapshots adds the snapshots taken before a run to its result
+func recordSnapshots(result *domain.OperationResult, snapshots []snapshot.Snapshot) {
+	if result == nil || len(snapshots) == 0 {
+		return
+	}
+	if result.Details == nil {
+		result.Details = make(map[string]interface{})
+	}
+	result.Details["snapshots"] = snapshots
+
+	ids := make([]string, len(snapshots))
+	for i, taken := range snapshots {
+		ids[i] = taken.ID
+	}
+	result.Summary += fmt.Sprintf(" (rollback: snapshot %s)", strings.Join(ids, ", "))
+}
+
+// lockRoots returns the paths an operation may modify
+func lockRoots(config domain.OperationConfig) []string {
+	roots := append([]string{}, config.TargetPaths...)
+	if destination, ok := config.CustomSettings["destination"].(string); ok && destination != "" {
+		roots = append(roots, destination)
+	}
+	if store, ok := config.CustomSettings["store"].(string); ok && store != "" {
+		roots = append(roots, store)
+	}
+	if output, ok := config.CustomSettings["output"].(string); ok && output != "" {
+		roots = append(roots, filepath.Dir(output))
+	}
+	return append(roots, cleanerProfileRoots(config)...)
+}
+
+// ErrErrorBudgetExceeded is returned when an operation aborts because too many items failed
+var ErrErrorBudgetExceeded = errors.New("error budget exceeded")
+
+// minErrorBudgetSample is the number of processed items before a percentage budget is enforced
+const minErrorBudgetSample = 20
+
 // BaseOperation provides common functionality for all operations
 type BaseOperation struct {
 	id            string
 	operationType domain.OperationType
 	config        domain.OperationConfig
 	engine        *Engine
+	repository    domain.Repository // Stores the result and long path lists of the run
 	tracker       *progress.OperationTracker
 	startTime     time.Time
+	sessionStart  time.Time  // Start of the current run in the window, for the time limit
+	scheduleMu    sync.Mutex // Held while the operation waits for its window
+	step          string
+	stepStart     time.Time
 	cancelled     bool
+	errorCount    int64
+	errors        []domain.OperationError
+	warnings      []domain.OperationWarning
+	warned        map[string]bool // Kind and file of every warning, to report each once
+	plan          *ioPlan         // I/O the real run of a dry run performs
+	pathStats     *pathStats      // Time the walks waited for each directory
+	errorsMu      sync.Mutex
 	mu            sync.RWMutex
 }
 
 // NewBaseOperation creates a new base operation
 func NewBaseOperation(id string, operationType domain.OperationType, config domain.OperationConfig, engine *Engine) *BaseOperation {
+	engine.mu.RLock()
+	repository := engine.repository
+	engine.mu.RUnlock()
+	now := time.Now()
 	return &BaseOperation{
 		id:            id,
 		operationType: operationType,
 		config:        config,
 		engine:        engine,
-		startTime:     time.Now(),
+		repository:    repository,
+		startTime:     now,
+		sessionStart:  now,
+		plan:          newIOPlan(),
+		pathStats:     newPathStats(),
 	}
 }
 
@@ -242,6 +664,16 @@ func (bo *BaseOperation) CheckContext(ctx context.Context) error {
 		return fmt.Errorf("operation cancelled")
 	}
 
+	// Abort once too many items have failed
+	if err := bo.ErrorBudgetErr(); err != nil {
+		return err
+	}
+
+	// Wait for the window, or stop at the time limit
+	if err := bo.checkSchedule(ctx); err != nil {
+		return err
+	}
+
 	// Handle pause/resume
 	if bo.tracker != nil && bo.tracker.IsPaused() {
 		bo.tracker.WaitForResume()
@@ -250,34 +682,255 @@ func (bo *BaseOperation) CheckContext(ctx context.Context) error {
 	return nil
 }
 
-// UpdateProgress updates the operation progress
-func (bo *BaseOperation) UpdateProgress(step string, itemsProcessed, totalItems, bytesProcessed, totalBytes int64) {
-	if bo.tracker != nil {
-		bo.tracker.UpdateStep(step)
-		bo.tracker.UpdateProgress(itemsProcessed, totalItems, bytesProcessed, totalBytes)
+// publish sends an event of this operation to the subscribers of the engine
+func (bo *BaseOperation) publish(event events.Event) {
+	event.OperationID = bo.id
+	event.OperationType = bo.operationType
+	bo.engine.events.Publish(event)
+}
+
+// UpdateStep completes the current step and starts the next one
+func (bo *BaseOperation) UpdateStep(step string) {
+	now := time.Now()
+	bo.mu.Lock()
+	previous, started := bo.step, bo.stepStart
+	bo.step, bo.stepStart = step, now
+	bo.mu.Unlock()
+
+	if previous != "" && bo.config.DryRun {
+		bo.plan.timeStep(previous, now.Sub(started))
+	}
+	if previous != "" {
+		bo.publish(events.Event{Type: events.StepCompleted, Time: now, Step: previous, Duration: now.Sub(started)})
 	}
+	bo.publish(events.Event{Type: events.StepStarted, Time: now, Step: step})
+}
+
+// UpdateProgress starts the next step with the given progress
+func (bo *BaseOperation) UpdateProgress(step string, itemsProcessed, totalItems, bytesProcessed, totalBytes int64) {
+	bo.UpdateStep(step)
+	bo.SetProgress(itemsProcessed, totalItems, bytesProcessed, totalBytes)
+}
+
+// SetProgress replaces the progress counters
+func (bo *BaseOperation) SetProgress(itemsProcessed, totalItems, bytesProcessed, totalBytes int64) {
+	bo.publish(events.Event{
+		Type:       events.ProgressUpdated,
+		Items:      itemsProcessed,
+		TotalItems: totalItems,
+		Bytes:      bytesProcessed,
+		TotalBytes: totalBytes,
+	})
 }
 
 // IncrementProgress increments the progress counters
 func (bo *BaseOperation) IncrementProgress(items, bytes int64) {
-	if bo.tracker != nil {
-		bo.tracker.IncrementProgress(items, bytes)
-	}
+	bo.publish(events.Event{Type: events.FileProcessed, Items: items, Bytes: bytes})
 }
 
 // SetTotals sets the total counters
 func (bo *BaseOperation) SetTotals(totalItems, totalBytes int64) {
-	if bo.tracker != nil {
-		bo.tracker.SetTotals(totalItems, totalBytes)
+	bo.publish(events.Event{Type: events.ProgressUpdated, Items: -1, TotalItems: totalItems, TotalBytes: totalBytes})
+}
+
+// setRepository makes the run store its long path lists in repository
+func (bo *BaseOperation) setRepository(repository domain.Repository) {
+	bo.mu.Lock()
+	defer bo.mu.Unlock()
+	bo.repository = repository
+}
+
+// RecordDeletion reports that the operation deleted path, freeing size bytes
+func (bo *BaseOperation) RecordDeletion(path string, size int64) {
+	bo.publish(events.Event{Type: events.FileDeleted, Path: path, Bytes: size})
+}
+
+// RecordMove reports that the operation moved source to target
+func (bo *BaseOperation) RecordMove(source, target string) {
+	bo.publish(events.Event{Type: events.FileMoved, Path: source, Target: target})
+}
+
+// ErrorBudgetErr returns an ErrErrorBudgetExceeded error once the configured
+// error budget (absolute count or percentage of processed items) is used up
+func (bo *BaseOperation) ErrorBudgetErr() error {
+	count := atomic.LoadInt64(&bo.errorCount)
+	if count == 0 {
+		return nil
+	}
+
+	if limit := bo.config.MaxErrors; limit != nil && count > int64(*limit) {
+		return fmt.Errorf("%w: %d errors (limit %d)", ErrErrorBudgetExceeded, count, *limit)
 	}
+
+	if bo.config.MaxErrorPercent > 0 && bo.tracker != nil {
+		processed, _ := bo.tracker.Counts()
+		if processed >= minErrorBudgetSample {
+			percent := float64(count) / float64(processed) * 100
+			if percent > bo.config.MaxErrorPercent {
+				return fmt.Errorf("%w: %d errors in %d items (%.1f%%, limit %.1f%%)",
+					ErrErrorBudgetExceeded, count, processed, percent, bo.config.MaxErrorPercent)
+			}
+		}
+	}
+
+	return nil
 }
 
-// AddError adds an error to the operation
+// AddError adds an error that is not tied to a single item to the operation
 func (bo *BaseOperation) AddError(err error) {
-	if bo.tracker != nil {
-		bo.tracker.AddError(err.Error())
+	bo.recordError("", err)
+}
+
+// AddFileError adds an error for a single item so it can be reported and retried
+func (bo *BaseOperation) AddFileError(file string, err error) {
+	bo.recordError(file, err)
+}
+
+// recordError counts err against the error budget and keeps it for the result
+func (bo *BaseOperation) recordError(file string, err error) {
+	atomic.AddInt64(&bo.errorCount, 1)
+
+	bo.errorsMu.Lock()
+	bo.errors = append(bo.errors, domain.OperationError{
+		File:        file,
+		Operation:   bo.operationType.String(),
+		Error:       err.Error(),
+		Timestamp:   time.Now(),
+		Recoverable: file != "" && isRecoverable(err),
+	})
+	bo.errorsMu.Unlock()
+
+	bo.publish(events.Event{Type: events.ErrorOccurred, Path: file, Error: err.Error()})
+}
+
+// AddWarning records a condition that did not make file fail. Each kind of
+// warning is recorded once per file.
+func (bo *BaseOperation) AddWarning(file string, kind domain.WarningKind, message string) {
+	bo.errorsMu.Lock()
+	key := string(kind) + "\x00" + file
+	if bo.warned[key] {
+		bo.errorsMu.Unlock()
+		return
 	}
-	bo.engine.logger.Error("Operation error", "id", bo.id, "error", err)
+	if bo.warned == nil {
+		bo.warned = make(map[string]bool)
+	}
+	bo.warned[key] = true
+	bo.warnings = append(bo.warnings, domain.OperationWarning{
+		File:      file,
+		Operation: bo.operationType.String(),
+		Kind:      kind,
+		Message:   message,
+		Timestamp: time.Now(),
+	})
+	bo.errorsMu.Unlock()
+
+	bo.publish(events.Event{Type: events.WarningRaised, Path: file, Warning: kind, Message: message})
+}
+
+// Walk walks root like Engine.Walk. Items that cannot be read for lack of
+// permission and linked directories (which are not traversed) are reported as
+// warnings; unreadable items do not reach fn. Directories that were slow to
+// read or failed end up in the slow paths of the result.
+func (bo *BaseOperation) Walk(ctx context.Context, root string, config domain.OperationConfig, fn domain.WalkFunc) error {
+	return bo.engine.walk(ctx, root, config, func(path string, info *domain.FileInfo, err error) error {
+		if err != nil && errors.Is(err, fs.ErrPermission) {
+			bo.AddWarning(path, domain.WarningPermissionDenied, fmt.Sprintf("skipped %s: permission denied", path))
+			return nil
+		}
+		if err == nil && info != nil && fs.FileMode(info.Mode)&fs.ModeSymlink != 0 {
+			if target, statErr := os.Stat(path); statErr == nil && target.IsDir() {
+				bo.AddWarning(path, domain.WarningSymlinkNotFollowed, fmt.Sprintf("%s links to a directory that was not traversed", path))
+			}
+		}
+		return fn(path, info, err)
+	}, bo.pathStats)
+}
+
+// copyWarning turns a copy that lost only the metadata of path into a warning
+// and returns every other error
+func (bo *BaseOperation) copyWarning(path string, err error) error {
+	if errors.Is(err, filesystem.ErrSecurityLabelsNotPreserved) {
+		bo.AddWarning(path, domain.WarningSecurityLabelsLost, fmt.Sprintf("copied %s without its SELinux context or file capabilities: %v", path, err))
+		return nil
+	}
+	if errors.Is(err, filesystem.ErrMetadataNotPreserved) {
+		bo.AddWarning(path, domain.WarningMetadataNotPreserved, fmt.Sprintf("copied %s without its permissions: %v", path, err))
+		return nil
+	}
+	return err
+}
+
+// move renames source to target, creating the directory of target. Only
+// when they are on different filesystems is source copied and removed, a
+// directory with its contents.
+func (bo *BaseOperation) move(source, target string) error {
+	fileSystem := bo.engine.fileSystem
+	if err := fileSystem.CreateDir(filepath.Dir(target)); err != nil {
+		return err
+	}
+	err := fileSystem.Move(source, target)
+	if filesystem.IsCrossDevice(err) {
+		if err = bo.copyWarning(target, fileSystem.Copy(source, target)); err == nil {
+			err = fileSystem.RemoveAll(source)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	bo.RecordMove(source, target)
+	return nil
+}
+
+// measureDir returns the total size and newest modification time below dir.
+// Exclusions and filters do not apply: moving or removing dir takes all of it.
+func (bo *BaseOperation) measureDir(ctx context.Context, dir string) (int64, time.Time) {
+	var size int64
+	var newest time.Time
+	_ = bo.engine.Walk(ctx, dir, domain.OperationConfig{}, func(_ string, info *domain.FileInfo, err error) error {
+		if err != nil || info == nil {
+			return nil
+		}
+		if !info.IsDir {
+			size += info.Size
+		}
+		if info.ModTime.After(newest) {
+			newest = info.ModTime
+		}
+		return nil
+	})
+	return size, newest
+}
+
+// uniqueTarget returns the first variant of target (name_1.ext, name_2.ext,
+// ...) that is not taken
+func (bo *BaseOperation) uniqueTarget(target string, taken func(string) bool) string {
+	ext := filepath.Ext(target)
+	base := strings.TrimSuffix(target, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s_%d%s", base, i, ext)
+		if !taken(candidate) {
+			return candidate
+		}
+	}
+}
+
+// freeTarget returns target, or the first variant of it that does not exist
+func (bo *BaseOperation) freeTarget(target string) string {
+	if !bo.engine.fileSystem.Exists(target) {
+		return target
+	}
+	return bo.uniqueTarget(target, bo.engine.fileSystem.Exists)
+}
+
+// isExcluded checks a path against the configured exclude patterns
+func (bo *BaseOperation) isExcluded(path string, config domain.OperationConfig) bool {
+	for _, pattern := range config.ExcludePatterns {
+		if matched, _ := filepath.Match(pattern, filepath.Base(path)); matched {
+			return true
+		}
+	}
+	return false
 }
 
 // CreateResult creates an operation result
@@ -286,6 +939,7 @@ func (bo *BaseOperation) CreateResult(status domain.OperationStatus, summary str
 	duration := endTime.Sub(bo.startTime)
 
 	result := &domain.OperationResult{
+		SchemaVersion: schema.ResultVersion,
 		ID:            bo.id,
 		OperationType: bo.operationType,
 		Status:        status,
@@ -301,26 +955,66 @@ func (bo *BaseOperation) CreateResult(status domain.OperationStatus, summary str
 		result.ItemsProcessed = progress.ItemsProcessed
 		result.BytesProcessed = progress.BytesProcessed
 
-		// Add any errors from the tracker
-		if errorCount, exists := progress.Details["error_count"]; exists && errorCount.(int) > 0 {
-			if allErrors, exists := progress.Details["all_errors"]; exists {
-				errorStrings := allErrors.([]string)
-				result.Errors = make([]domain.OperationError, len(errorStrings))
-				for i, errStr := range errorStrings {
-					result.Errors[i] = domain.OperationError{
-						Operation:   bo.operationType.String(),
-						Error:       errStr,
-						Timestamp:   time.Now(),
-						Recoverable: false,
-					}
-				}
+	}
+
+	bo.errorsMu.Lock()
+	if len(bo.errors) > 0 {
+		result.Errors = append([]domain.OperationError(nil), bo.errors...)
+	}
+	if len(bo.warnings) > 0 {
+		result.Warnings = append([]domain.OperationWarning(nil), bo.warnings...)
+	}
+	bo.errorsMu.Unlock()
+	result.SlowPaths = bo.pathStats.slowPaths()
+	if bo.config.Deterministic {
+		sortIssues(result)
+	}
+
+	// What the real run of a successful dry run costs
+	if bo.config.DryRun && status == domain.StatusCompleted {
+		bo.mu.RLock()
+		step, started := bo.step, bo.stepStart
+		bo.mu.RUnlock()
+		if step != "" {
+			bo.plan.timeStep(step, endTime.Sub(started))
+		}
+		if estimate := bo.engine.estimateCost(bo.plan); estimate != nil {
+			if result.Details == nil {
+				result.Details = make(map[string]interface{})
 			}
+			result.Details["cost_estimate"] = estimate
 		}
 	}
 
 	return result
 }
 
+// FailedResult builds the result of an operation that aborted with err,
+// keeping the per-item errors collected up to that point
+func (bo *BaseOperation) FailedResult(err error) *domain.OperationResult {
+	return bo.CreateResult(domain.StatusFailed, err.Error(), map[string]interface{}{})
+}
+
+// permanentErrors are failure causes that a retry cannot fix
+var permanentErrors = []error{
+	os.ErrNotExist, os.ErrExist, thumbnail.ErrCorrupt, metadata.ErrMalformed,
+	integrity.ErrCorrupt, integrity.ErrUnrepairable, integrity.ErrStaleParity,
+	encryption.ErrWrongKey, encryption.ErrNotEncrypted, pack.ErrCorruptChunk,
+	transcode.ErrDurationMismatch, pdf.ErrMalformed, pdf.ErrEncrypted, mail.ErrMalformed, archive.ErrMalformed, bagit.ErrInvalid, errOutsideReview, errSidecarsLeft,
+}
+
+// isRecoverable reports whether retrying a failed item may succeed. Items that
+// vanished, collided with an existing file or are corrupt are not recoverable;
+// transient I/O errors and problems such as missing permissions (which can be fixed) are.
+func isRecoverable(err error) bool {
+	for _, permanent := range permanentErrors {
+		if errors.Is(err, permanent) {
+			return false
+		}
+	}
+	return true
+}
+
 // ValidateConfig provides common configuration validation
 func (bo *BaseOperation) ValidateConfig() error {
 	config := bo.config
@@ -344,8 +1038,25 @@ func (bo *BaseOperation) ValidateConfig() error {
 		return fmt.Errorf("similarity threshold must be between 0.0 and 1.0")
 	}
 
+	if !validGitFilter(config.GitFilter) {
+		return fmt.Errorf("git filter must be %q, %q or %q", GitProtectTracked, GitUntrackedOnly, GitIgnoredOnly)
+	}
+
+	if !filesystem.ValidNormalization(config.UnicodeNormalization) {
+		return fmt.Errorf("unicode normalization must be %q, %q or %q", filesystem.NormalizeNFC, filesystem.NormalizeNFD, filesystem.NormalizeNone)
+	}
+
+	if config.Window != "" {
+		if _, err := ParseWindow(config.Window); err != nil {
+			return err
+		}
+	}
+	if config.MaxDuration < 0 {
+		return fmt.Errorf("max duration cannot be negative")
+	}
+
 	// Validate hash algorithm
-	validAlgorithms := []string{"md5", "sha1", "sha256", "sha512", "blake2b", "xxhash64", "crc32"}
+	validAlgorithms := []string{"md5", "sha1", "sha256", "sha512", "blake2b", "blake3", "xxhash64", "crc32"}
 	if config.HashAlgorithm != "" {
 		valid := false
 		for _, alg := range validAlgorithms {
@@ -394,7 +1105,7 @@ func (om *OperationManager) SubmitOperation(ctx context.Context, operationType d
 	}
 
 	// Generate operation ID
-	operationID := generateOperationID(operationType)
+	operationID := NewOperationID(operationType)
 
 	// Start operation in background
 	go func() {
@@ -404,7 +1115,7 @@ func (om *OperationManager) SubmitOperation(ctx context.Context, operationType d
 			om.mu.Unlock()
 		}()
 
-		result, err := om.engine.ExecuteOperation(ctx, operationType, config)
+		result, err := om.engine.ExecuteOperationWithID(ctx, operationType, config, operationID)
 		if err != nil {
 			om.engine.logger.Error("Background operation failed", "id", operationID, "error", err)
 		} else {
@@ -0,0 +1,222 @@
+package engine
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// Limits of the sampled scan behind an estimate. The first estimateDirs
+// directories are read breadth first; the trees below the directories left
+// over are extrapolated from random descents into them.
+const (
+	estimateDirs     = 10000
+	estimateProbes   = 5000
+	estimateMaxDepth = 64
+	estimateBudget   = 3 * time.Second
+)
+
+// Rates assumed for operations that have no completed run to learn from
+const (
+	defaultItemsPerSecond = 1000
+	defaultBytesPerSecond = 100 * 1024 * 1024
+)
+
+// scopeCount holds what was found (or is expected) below some directories
+type scopeCount struct {
+	files, dirs, bytes float64
+}
+
+func (c *scopeCount) add(other scopeCount, weight float64) {
+	c.files += other.files * weight
+	c.dirs += other.dirs * weight
+	c.bytes += other.bytes * weight
+}
+
+// scopeSampler reads directories the way the operation will see them
+type scopeSampler struct {
+	config   domain.OperationConfig
+	device   uint64
+	deadline time.Time
+	reads    int
+}
+
+// read counts the files of one directory and returns its subdirectories
+func (s *scopeSampler) read(dir string) (scopeCount, []string) {
+	s.reads++
+	count := scopeCount{dirs: 1}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return count, nil
+	}
+
+	subdirs := make([]string, 0)
+	for _, entry := range entries {
+		if s.excluded(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() {
+			if s.config.OneFileSystem && s.device != 0 {
+				if device, err := filesystem.DeviceID(path); err == nil && device != s.device {
+					continue
+				}
+			}
+			subdirs = append(subdirs, path)
+			continue
+		}
+		if !s.config.Includes(path) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if info.Size() < s.config.MinFileSize || (s.config.MaxFileSize > 0 && info.Size() > s.config.MaxFileSize) {
+			continue
+		}
+		count.files++
+		count.bytes += float64(info.Size())
+	}
+	return count, subdirs
+}
+
+// excluded reports whether a name matches one of the exclude patterns
+func (s *scopeSampler) excluded(name string) bool {
+	for _, pattern := range s.config.ExcludePatterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// probe estimates the tree below dir by descending into random subdirectories
+// and weighting every level by the branching seen above it (Knuth's estimator)
+func (s *scopeSampler) probe(dir string, rng *rand.Rand) scopeCount {
+	var total scopeCount
+	weight := 1.0
+	for depth := 0; depth < estimateMaxDepth; depth++ {
+		count, subdirs := s.read(dir)
+		total.add(count, weight)
+		if len(subdirs) == 0 {
+			break
+		}
+		weight *= float64(len(subdirs))
+		dir = subdirs[rng.Intn(len(subdirs))]
+	}
+	return total
+}
+
+// sample estimates the files, directories and bytes below root. exact is true
+// when the whole tree was read.
+func (s *scopeSampler) sample(root string, rng *rand.Rand) (count scopeCount, exact bool) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return count, true
+	}
+	if !info.IsDir() {
+		return scopeCount{files: 1, bytes: float64(info.Size())}, true
+	}
+	if s.config.OneFileSystem {
+		s.device, _ = filesystem.DeviceID(root)
+	}
+
+	queue := []string{root}
+	for len(queue) > 0 && s.reads < estimateDirs && time.Now().Before(s.deadline) {
+		found, subdirs := s.read(queue[0])
+		queue = append(queue[1:], subdirs...)
+		count.add(found, 1)
+	}
+	if len(queue) == 0 {
+		return count, true
+	}
+
+	// Every left over directory is probed once before any is probed again
+	rng.Shuffle(len(queue), func(i, j int) { queue[i], queue[j] = queue[j], queue[i] })
+	var probed scopeCount
+	probes := 0
+	for probes < estimateProbes && (probes == 0 || time.Now().Before(s.deadline)) {
+		probed.add(s.probe(queue[probes%len(queue)], rng), 1)
+		probes++
+	}
+	count.add(probed, float64(len(queue))/float64(probes))
+	return count, false
+}
+
+// estimateScope estimates the files and bytes below the roots of config with
+// a sampled scan, and how long processing them takes judging by earlier runs
+func (bo *BaseOperation) estimateScope(config domain.OperationConfig, totalSteps int) (*domain.ProgressInfo, error) {
+	started := time.Now()
+	sampler := &scopeSampler{config: config, deadline: started.Add(estimateBudget)}
+	rng := rand.New(rand.NewSource(started.UnixNano()))
+
+	var total scopeCount
+	exact := true
+	for _, root := range config.TargetPaths {
+		count, rootExact := sampler.sample(root, rng)
+		total.add(count, 1)
+		exact = exact && rootExact
+	}
+
+	items, bytes := int64(total.files+0.5), int64(total.bytes+0.5)
+	duration := bo.engine.expectedDuration(bo.operationType, items, bytes)
+	return &domain.ProgressInfo{
+		ID:            bo.id,
+		OperationType: bo.operationType,
+		Status:        domain.StatusPending,
+		TotalSteps:    totalSteps,
+		TotalItems:    items,
+		TotalBytes:    bytes,
+		EstimatedETA:  &duration,
+		Details: map[string]interface{}{
+			"directories":         int64(total.dirs + 0.5),
+			"exact":               exact,
+			"sampled_directories": sampler.reads,
+			"sampling_time":       time.Since(started),
+		},
+	}, nil
+}
+
+// expectedDuration predicts how long an operation takes for the given amount
+// of work from the rates of its last completed runs
+func (e *Engine) expectedDuration(operationType domain.OperationType, items, bytes int64) time.Duration {
+	e.mu.RLock()
+	repository := e.repository
+	e.mu.RUnlock()
+
+	var seconds, pastItems, pastBytes float64
+	if repository != nil {
+		results, _ := repository.ListResults(map[string]interface{}{
+			"operation_type": operationType,
+			"status":         domain.StatusCompleted,
+		})
+		runs := 0
+		for _, result := range results {
+			if runs == 10 {
+				break
+			}
+			if result.Duration <= 0 || result.ItemsProcessed == 0 || (result.Config != nil && result.Config.DryRun) {
+				continue
+			}
+			seconds += result.Duration.Seconds()
+			pastItems += float64(result.ItemsProcessed)
+			pastBytes += float64(result.BytesProcessed)
+			runs++
+		}
+	}
+
+	// Byte-bound work is predicted by bytes, the rest by items
+	if seconds > 0 && bytes > 0 && pastBytes > 0 {
+		return time.Duration(float64(bytes) / pastBytes * seconds * float64(time.Second))
+	}
+	if seconds > 0 {
+		return time.Duration(float64(items) / pastItems * seconds * float64(time.Second))
+	}
+	estimate := float64(items)/defaultItemsPerSecond + float64(bytes)/defaultBytesPerSecond
+	return time.Duration(estimate * float64(time.Second))
+}
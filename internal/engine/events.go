@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/events"
+)
+
+// loggedEvents are the events written to the log
+var loggedEvents = []events.Type{
+	events.OperationStarted, events.OperationFinished, events.StepCompleted,
+	events.FileDeleted, events.FileMoved, events.ErrorOccurred, events.WarningRaised,
+	events.BatchCommitted,
+}
+
+// logEvent writes an event of an operation to the log
+func (e *Engine) logEvent(event events.Event) {
+	switch event.Type {
+	case events.OperationStarted:
+		e.logger.Info("Starting operation", "id", event.OperationID, "type", event.OperationType)
+	case events.OperationFinished:
+		if event.Status == domain.StatusCompleted {
+			e.logger.Info("Operation completed", "id", event.OperationID, "duration", event.Duration)
+		} else {
+			e.logger.Error("Operation failed", "id", event.OperationID, "error", event.Error)
+		}
+	case events.StepCompleted:
+		e.logger.Debug("Step completed", "id", event.OperationID, "step", event.Step, "duration", event.Duration)
+	case events.FileDeleted:
+		e.logger.Debug("Deleted", "id", event.OperationID, "path", event.Path, "size", event.Bytes)
+	case events.FileMoved:
+		e.logger.Debug("Moved", "id", event.OperationID, "path", event.Path, "target", event.Target)
+	case events.BatchCommitted:
+		e.logger.Debug("Undo journal batch committed", "id", event.OperationID, "batch", event.Batch, "changes", event.Items)
+	case events.ErrorOccurred:
+		e.logger.Error("Operation error", "id", event.OperationID, "error", event.Error)
+	case events.WarningRaised:
+		e.logger.Warn("Operation warning", "id", event.OperationID, "kind", string(event.Warning), "message", event.Message)
+	}
+}
@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// validateExtensions checks the per-extension actions of the configuration
+func validateExtensions(config domain.OperationConfig) error {
+	entries := make([]string, 0, len(config.Extensions))
+	for pattern, action := range config.Extensions {
+		entries = append(entries, pattern+"="+action)
+	}
+	_, err := domain.ParseExtensions(entries)
+	return err
+}
+
+// hasExtensionAction reports whether any extension is configured with action
+func hasExtensionAction(config domain.OperationConfig, action string) bool {
+	for _, configured := range config.Extensions {
+		if configured == action {
+			return true
+		}
+	}
+	return false
+}
+
+// extensionFile is a file cleanup removes because of its extension
+type extensionFile struct {
+	info *domain.FileInfo
+	root string
+}
+
+// findExtensionDeletions collects the files below all roots whose extension
+// is configured to be deleted
+func (co *CleanupOperation) findExtensionDeletions(ctx context.Context, config domain.OperationConfig) ([]extensionFile, error) {
+	found := make([]extensionFile, 0)
+	for _, rootPath := range config.TargetPaths {
+		err := co.Walk(ctx, rootPath, config, func(path string, info *domain.FileInfo, err error) error {
+			if err != nil {
+				co.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+				return nil // Continue processing
+			}
+			if err := co.CheckContext(ctx); err != nil {
+				return err
+			}
+			if info == nil {
+				return nil
+			}
+			if info.IsDir {
+				if path != rootPath && !co.shouldProcessDirectory(path, config) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			co.IncrementProgress(1, 0)
+			if os.FileMode(info.Mode).IsRegular() && config.ExtensionAction(path) == domain.ExtensionDelete {
+				found = append(found, extensionFile{info: info, root: rootPath})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].info.Path < found[j].info.Path })
+	return found, nil
+}
+
+// processExtensionDeletions removes the files found by extension, or moves
+// them to the backup directory when one is configured
+func (co *CleanupOperation) processExtensionDeletions(ctx context.Context, config domain.OperationConfig, files []extensionFile) error {
+	for _, file := range files {
+		if err := co.CheckContext(ctx); err != nil {
+			return err
+		}
+		path := file.info.Path
+
+		if config.DryRun {
+			co.removedByExtension.Add(path)
+			co.extensionBytes += file.info.Size
+			co.engine.logger.Info("Would remove file by extension", "path", path)
+			continue
+		}
+
+		var err error
+		if config.BackupBeforeDelete && config.BackupDirectory != "" {
+			err = co.backupPath(file.root, path, config.BackupDirectory)
+		} else if err = co.engine.fileSystem.Remove(path); err == nil {
+			co.RecordDeletion(path, file.info.Size)
+		}
+		if err != nil {
+			co.AddFileError(path, fmt.Errorf("failed to remove %s: %w", path, err))
+			continue
+		}
+		co.removedByExtension.Add(path)
+		co.extensionBytes += file.info.Size
+		co.engine.logger.Info("Removed file by extension", "path", path)
+	}
+	return nil
+}
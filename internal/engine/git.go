@@ -0,0 +1,222 @@
+package engine
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// Git filters selected with OperationConfig.GitFilter
+const (
+	GitProtectTracked = "protect"   // Skip files tracked by git
+	GitUntrackedOnly  = "untracked" // Only untracked files of repositories, ignored ones included
+	GitIgnoredOnly    = "ignored"   // Only files of repositories that git ignores
+)
+
+// gitRepo is what git reports about the files of one work tree
+type gitRepo struct {
+	root        string
+	tracked     map[string]bool // Tracked files, relative with forward slashes
+	trackedDirs map[string]bool // Directories containing tracked files
+	ignored     map[string]bool // Ignored files and directories
+	err         error           // git could not be asked; nothing is considered safe
+}
+
+// gitState caches the repositories seen by an engine
+type gitState struct {
+	mu    sync.Mutex
+	roots map[string]string // Directory to the work tree containing it ("" outside of any)
+	repos map[string]*gitRepo
+}
+
+// validGitFilter reports whether a git filter is known
+func validGitFilter(filter string) bool {
+	switch filter {
+	case "", GitProtectTracked, GitUntrackedOnly, GitIgnoredOnly:
+		return true
+	}
+	return false
+}
+
+// gitFilterWalk wraps fn so that only the files the git filter allows reach
+// it. Repository metadata (.git) is never entered.
+func (e *Engine) gitFilterWalk(filter string, fn domain.WalkFunc) domain.WalkFunc {
+	return func(path string, info *domain.FileInfo, err error) error {
+		if err != nil || info == nil {
+			return fn(path, info, err)
+		}
+		if info.IsDir {
+			if filepath.Base(path) == ".git" {
+				return filepath.SkipDir
+			}
+			return fn(path, info, err) // Directories hold files of every kind
+		}
+		if filepath.Base(path) == ".git" || !e.gitAllows(path, false, filter) {
+			return nil // .git files link submodules to their repository
+		}
+		return fn(path, info, err)
+	}
+}
+
+// gitAllows reports whether the git filter lets an operation act on path. A
+// directory is allowed only when it holds no tracked files and no repository
+// of its own. Outside of any repository everything is allowed when protecting
+// tracked files and nothing otherwise.
+func (e *Engine) gitAllows(path string, isDir bool, filter string) bool {
+	if filter == "" {
+		return true
+	}
+	if isDir && containsRepository(path) {
+		return false
+	}
+	repo := e.gitRepoOf(path)
+	if repo == nil {
+		return filter == GitProtectTracked
+	}
+	if repo.err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(repo.root, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+
+	containsTracked := repo.tracked[rel] || (isDir && repo.trackedDirs[rel])
+	switch filter {
+	case GitProtectTracked, GitUntrackedOnly:
+		return !containsTracked
+	case GitIgnoredOnly:
+		if containsTracked {
+			return false
+		}
+		for current := rel; current != "."; current = filepath.ToSlash(filepath.Dir(current)) {
+			if repo.ignored[current] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// gitRepoOf returns the repository whose work tree contains path, or nil
+func (e *Engine) gitRepoOf(path string) *gitRepo {
+	e.git.mu.Lock()
+	defer e.git.mu.Unlock()
+	if e.git.roots == nil {
+		e.git.roots = make(map[string]string)
+		e.git.repos = make(map[string]*gitRepo)
+	}
+
+	// Find the nearest directory with a .git entry, caching every step
+	visited := make([]string, 0)
+	root := ""
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if cached, ok := e.git.roots[dir]; ok {
+			root = cached
+			break
+		}
+		visited = append(visited, dir)
+		if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+			root = dir
+			break
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	for _, dir := range visited {
+		e.git.roots[dir] = root
+	}
+	if root == "" {
+		return nil
+	}
+
+	if repo, ok := e.git.repos[root]; ok {
+		return repo
+	}
+	repo := e.loadGitRepo(root)
+	e.git.repos[root] = repo
+	return repo
+}
+
+// loadGitRepo asks git for the tracked and ignored files of a work tree
+func (e *Engine) loadGitRepo(root string) *gitRepo {
+	repo := &gitRepo{
+		root:        root,
+		tracked:     make(map[string]bool),
+		trackedDirs: make(map[string]bool),
+		ignored:     make(map[string]bool),
+	}
+
+	tracked, err := gitLsFiles(root)
+	if err != nil {
+		repo.err = err
+		e.logger.Warn("Unable to list tracked files, leaving the repository alone", "repository", root, "error", err)
+		return repo
+	}
+	for _, file := range tracked {
+		repo.tracked[file] = true
+		for dir := filepath.ToSlash(filepath.Dir(file)); dir != "."; dir = filepath.ToSlash(filepath.Dir(dir)) {
+			repo.trackedDirs[dir] = true
+		}
+	}
+
+	// Ignored directories are listed once with a trailing slash
+	ignored, err := gitLsFiles(root, "--others", "--ignored", "--exclude-standard", "--directory")
+	if err != nil {
+		repo.err = err
+		e.logger.Warn("Unable to list ignored files, leaving the repository alone", "repository", root, "error", err)
+		return repo
+	}
+	for _, file := range ignored {
+		repo.ignored[strings.TrimSuffix(file, "/")] = true
+	}
+	return repo
+}
+
+// gitLsFiles runs git ls-files in a work tree and returns the listed paths
+func gitLsFiles(root string, args ...string) ([]string, error) {
+	cmd := exec.Command("git", append([]string{"-C", root, "ls-files", "-z"}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("git ls-files: %s", message)
+		}
+		return nil, err
+	}
+	files := make([]string, 0)
+	for _, file := range strings.Split(string(output), "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// errRepositoryFound stops the search of containsRepository
+var errRepositoryFound = errors.New("repository found")
+
+// containsRepository reports whether a git repository lives in or below dir
+func containsRepository(dir string) bool {
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Unreadable parts cannot be checked; skip them
+		}
+		if entry.Name() == ".git" {
+			return errRepositoryFound
+		}
+		return nil
+	})
+	return errors.Is(err, errRepositoryFound)
+}
@@ -0,0 +1,449 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// Duplicate policies of ingestion, choosing what happens to an inbox file
+// whose content the library already holds
+const (
+	IngestDuplicatesSkip   = "skip"   // Leave it in the inbox and report it
+	IngestDuplicatesRemove = "remove" // Delete the inbox copy
+)
+
+// DefaultQuarantineDir is the folder of an inbox receiving files the scanner
+// hook rejects
+const DefaultQuarantineDir = ".quarantine"
+
+// defaultSettle is how long a file's size must stay the same before it counts
+// as fully written
+const defaultSettle = 10 * time.Second
+
+// IngestFactory creates inbox ingestion operations
+type IngestFactory struct {
+	engine *Engine
+}
+
+// Create creates a new ingestion operation
+func (inf *IngestFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewIngestOperation(id, config, inf.engine), nil
+}
+
+// Validate validates the ingestion configuration
+func (inf *IngestFactory) Validate(config domain.OperationConfig) error {
+	if len(config.TargetPaths) == 0 {
+		return fmt.Errorf("at least one inbox is required")
+	}
+	destination := settingString(config, "destination", "")
+	if destination == "" {
+		return fmt.Errorf("destination parameter is required")
+	}
+	for _, inbox := range config.TargetPaths {
+		if withinPrefixes(destination, []string{inbox}) || withinPrefixes(inbox, []string{destination}) {
+			return fmt.Errorf("inbox %s and library %s must not contain each other", inbox, destination)
+		}
+	}
+	if err := (&OrganizationFactory{}).Validate(config); err != nil {
+		return err
+	}
+	if settle, err := time.ParseDuration(settingString(config, "settle", defaultSettle.String())); err != nil || settle < 0 {
+		return fmt.Errorf("invalid settle time %q", settingString(config, "settle", ""))
+	}
+	if scanner := settingString(config, "scanner", ""); scanner != "" {
+		if _, err := exec.LookPath(strings.Fields(scanner)[0]); err != nil {
+			return fmt.Errorf("scanner hook %q: %w", scanner, err)
+		}
+	}
+	switch settingString(config, "duplicates", IngestDuplicatesSkip) {
+	case IngestDuplicatesSkip, IngestDuplicatesRemove:
+	default:
+		return fmt.Errorf("duplicates must be %q or %q", IngestDuplicatesSkip, IngestDuplicatesRemove)
+	}
+	return nil
+}
+
+// IngestOperation takes files dropped into an inbox into the library: once a
+// file's size has settled it is optionally checked by a scanner hook, hashed
+// and compared with the library, and moved into the organized structure the
+// organize operation would give it. Files still being written, rejected by
+// the scanner or already in the library do not reach the library.
+type IngestOperation struct {
+	*BaseOperation
+	organizer   *OrganizationOperation // Classifies files like organize
+	ingested    *pathList
+	settling    *pathList
+	quarantined *pathList
+	duplicates  *pathList
+	categories  map[string]int
+}
+
+// NewIngestOperation creates a new ingestion operation
+func NewIngestOperation(id string, config domain.OperationConfig, engine *Engine) *IngestOperation {
+	base := NewBaseOperation(id, domain.OperationIngest, config, engine)
+	return &IngestOperation{
+		BaseOperation: base,
+		organizer:     NewOrganizationOperation(id, config, engine),
+		ingested:      base.newPathList("ingested"),
+		settling:      base.newPathList("settling"),
+		quarantined:   base.newPathList("quarantined"),
+		duplicates:    base.newPathList("duplicates"),
+		categories:    make(map[string]int),
+	}
+}
+
+// ingestCandidate is an inbox file with the inbox it was found in
+type ingestCandidate struct {
+	inbox string
+	info  *domain.FileInfo
+}
+
+// Execute ingests the settled files of the inboxes
+func (ig *IngestOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	tracker := ig.engine.progressTracker.StartOperation(ig.id, domain.OperationIngest, 4)
+	ig.SetTracker(tracker)
+
+	destination := settingString(config, "destination", "")
+	strategy := settingString(config, "strategy", "type")
+	settle, _ := time.ParseDuration(settingString(config, "settle", defaultSettle.String()))
+	scanner := strings.Fields(settingString(config, "scanner", ""))
+	quarantine := settingString(config, "quarantine", "")
+	duplicates := settingString(config, "duplicates", IngestDuplicatesSkip)
+	algorithm := config.HashAlgorithm
+	if algorithm == "" {
+		algorithm = "blake2b" // Default
+	}
+
+	ig.UpdateStep("Waiting for files to settle")
+
+	files, err := ig.scan(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	if files, err = ig.settled(ctx, files, settle); err != nil {
+		return nil, err
+	}
+
+	ig.UpdateStep("Indexing library")
+
+	library, err := ig.indexLibrary(ctx, destination, files, config)
+	if err != nil {
+		return nil, err
+	}
+
+	ig.UpdateStep("Ingesting files")
+	var totalSize int64
+	for _, file := range files {
+		totalSize += file.info.Size
+	}
+	ig.SetProgress(0, int64(len(files)), 0, totalSize)
+
+	classify, index := ig.organizer.classifier(strategy, config)
+	planned := make(map[string]bool)
+	for _, file := range files {
+		if err := ig.CheckContext(ctx); err != nil {
+			ig.organizer.saveIndex(index, config)
+			return nil, err
+		}
+		path := file.info.Path
+
+		if len(scanner) > 0 {
+			clean, err := ig.runScanner(ctx, scanner, path)
+			if err != nil {
+				ig.AddFileError(path, fmt.Errorf("failed to scan %s: %w", path, err))
+				ig.IncrementProgress(1, file.info.Size)
+				continue
+			}
+			if !clean {
+				ig.quarantine(file, quarantine, config)
+				ig.IncrementProgress(1, file.info.Size)
+				continue
+			}
+		}
+
+		hash, err := ig.engine.fileSystem.ComputeHash(path, algorithm)
+		if err != nil {
+			ig.AddFileError(path, fmt.Errorf("failed to hash %s: %w", path, err))
+			ig.IncrementProgress(1, file.info.Size)
+			continue
+		}
+		if existing := library.find(file.info.Size, hash); existing != "" {
+			ig.duplicate(file, existing, duplicates, config)
+			ig.IncrementProgress(1, file.info.Size)
+			continue
+		}
+
+		decision, err := classify(ctx, file.info)
+		if err != nil {
+			ig.AddFileError(path, fmt.Errorf("failed to classify %s: %w", path, err))
+			ig.IncrementProgress(1, file.info.Size)
+			continue
+		}
+		category := decision.category
+		if category == "" {
+			category = "Unsorted"
+		}
+		taken := func(target string) bool {
+			return planned[target] || ig.engine.fileSystem.Exists(target)
+		}
+		target := filepath.Join(destination, filepath.FromSlash(category), file.info.Name)
+		if taken(target) {
+			target = ig.uniqueTarget(target, taken)
+		}
+		planned[target] = true
+
+		if config.DryRun {
+			ig.engine.logger.Debug("Would ingest file", "source", path, "target", target)
+		} else if err := ig.move(path, target); err != nil {
+			ig.AddFileError(path, fmt.Errorf("failed to move %s: %w", path, err))
+			ig.IncrementProgress(1, file.info.Size)
+			continue
+		}
+		// Later inbox files with the same content are duplicates of this one
+		library.add(file.info.Size, hash, target)
+		ig.ingested.Add(path)
+		ig.categories[category]++
+		ig.IncrementProgress(1, file.info.Size)
+	}
+
+	ig.UpdateStep("Completing ingestion")
+	ig.organizer.saveIndex(index, config)
+
+	details := map[string]interface{}{
+		"ingested":    ig.ingested.Result(),
+		"settling":    ig.settling.Result(),
+		"quarantined": ig.quarantined.Result(),
+		"duplicates":  ig.duplicates.Result(),
+		"categories":  ig.categories,
+		"strategy":    strategy,
+		"destination": destination,
+		"dry_run":     config.DryRun,
+	}
+
+	summary := fmt.Sprintf("Ingestion: %d files moved into %d categories, %d duplicates of library files, %d quarantined, %d still being written",
+		ig.ingested.Len(), len(ig.categories), ig.duplicates.Len(), ig.quarantined.Len(), ig.settling.Len())
+	if config.DryRun {
+		summary = fmt.Sprintf("Ingestion (dry run): %d files would be moved into %d categories, %d duplicates of library files, %d would be quarantined, %d still being written",
+			ig.ingested.Len(), len(ig.categories), ig.duplicates.Len(), ig.quarantined.Len(), ig.settling.Len())
+	}
+
+	return ig.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// scan collects the files of the inboxes, skipping their quarantine folders
+// and partial downloads
+func (ig *IngestOperation) scan(ctx context.Context, config domain.OperationConfig) ([]ingestCandidate, error) {
+	quarantine := settingString(config, "quarantine", "")
+	files := make([]ingestCandidate, 0)
+	for _, inbox := range config.TargetPaths {
+		err := ig.Walk(ctx, inbox, config, func(path string, info *domain.FileInfo, err error) error {
+			if err != nil {
+				ig.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+				return nil // Continue walking
+			}
+			if info == nil {
+				return nil
+			}
+			if ig.isExcluded(path, config) || info.IsDir && (info.Name == DefaultQuarantineDir || path == quarantine) {
+				if info.IsDir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !os.FileMode(info.Mode).IsRegular() {
+				return nil
+			}
+			if isPartialDownloadName(info.Name) {
+				ig.settling.Add(path)
+				return nil
+			}
+			files = append(files, ingestCandidate{inbox: inbox, info: info})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk path %s: %w", inbox, err)
+		}
+	}
+	return files, nil
+}
+
+// settled waits for settle and returns the files whose size and modification
+// time did not change meanwhile. The others are still being written and are
+// left for the next run.
+func (ig *IngestOperation) settled(ctx context.Context, files []ingestCandidate, settle time.Duration) ([]ingestCandidate, error) {
+	if len(files) == 0 || settle == 0 {
+		return files, nil
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(settle):
+	}
+
+	stable := make([]ingestCandidate, 0, len(files))
+	for _, file := range files {
+		info, err := ig.engine.fileSystem.Stat(file.info.Path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				ig.AddFileError(file.info.Path, fmt.Errorf("error accessing %s: %w", file.info.Path, err))
+			}
+			continue
+		}
+		if info.Size != file.info.Size || !info.ModTime.Equal(file.info.ModTime) {
+			ig.settling.Add(file.info.Path)
+			continue
+		}
+		stable = append(stable, file)
+	}
+	return stable, nil
+}
+
+// runScanner runs the scanner hook on path. Exit status 0 means clean and 1
+// means rejected, as with clamscan; any other status is an error.
+func (ig *IngestOperation) runScanner(ctx context.Context, scanner []string, path string) (bool, error) {
+	args := append(append([]string{}, scanner[1:]...), path)
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, scanner[0], args...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		ig.engine.logger.Warn("Scanner rejected file", "path", path, "output", strings.TrimSpace(output.String()))
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s: %w: %s", scanner[0], err, strings.TrimSpace(output.String()))
+	}
+	return true, nil
+}
+
+// quarantine moves a file the scanner rejected into the quarantine folder,
+// by default .quarantine in its inbox
+func (ig *IngestOperation) quarantine(file ingestCandidate, dir string, config domain.OperationConfig) {
+	path := file.info.Path
+	ig.quarantined.Add(path)
+	if config.DryRun {
+		return
+	}
+	if dir == "" {
+		dir = filepath.Join(file.inbox, DefaultQuarantineDir)
+	}
+	target := filepath.Join(dir, file.info.Name)
+	taken := ig.engine.fileSystem.Exists
+	if taken(target) {
+		target = ig.uniqueTarget(target, taken)
+	}
+	if err := ig.move(path, target); err != nil {
+		ig.AddFileError(path, fmt.Errorf("failed to quarantine %s: %w", path, err))
+	}
+}
+
+// duplicate handles an inbox file whose content the library holds at existing
+func (ig *IngestOperation) duplicate(file ingestCandidate, existing, policy string, config domain.OperationConfig) {
+	path := file.info.Path
+	ig.duplicates.Add(path)
+	ig.engine.logger.Info("Already in the library", "path", path, "existing", existing)
+	if policy != IngestDuplicatesRemove || config.DryRun {
+		return
+	}
+	// The hashes matched; compare the bytes before deleting anything
+	same, err := sameContent(path, existing)
+	if err != nil || !same {
+		ig.AddFileError(path, fmt.Errorf("failed to remove %s: content differs from %s", path, existing))
+		return
+	}
+	if err := ig.engine.fileSystem.Remove(path); err != nil {
+		ig.AddFileError(path, fmt.Errorf("failed to remove %s: %w", path, err))
+		return
+	}
+	ig.RecordDeletion(path, file.info.Size)
+}
+
+// libraryIndex finds library files by content. Only library files with the
+// size of an inbox file are hashed, and only when first needed.
+type libraryIndex struct {
+	ig        *IngestOperation
+	algorithm string
+	bySize    map[int64][]string
+	hashes    map[string]string // Path -> hash
+}
+
+// indexLibrary collects the library files with the size of one of files
+func (ig *IngestOperation) indexLibrary(ctx context.Context, destination string, files []ingestCandidate, config domain.OperationConfig) (*libraryIndex, error) {
+	algorithm := config.HashAlgorithm
+	if algorithm == "" {
+		algorithm = "blake2b" // Default
+	}
+	library := &libraryIndex{ig: ig, algorithm: algorithm, bySize: make(map[int64][]string), hashes: make(map[string]string)}
+	sizes := make(map[int64]bool, len(files))
+	for _, file := range files {
+		sizes[file.info.Size] = true
+	}
+	if len(files) == 0 || !ig.engine.fileSystem.Exists(destination) {
+		return library, nil
+	}
+
+	err := ig.Walk(ctx, destination, config, func(path string, info *domain.FileInfo, err error) error {
+		if err != nil || info == nil {
+			return nil // Unreadable library files cannot be compared
+		}
+		if ig.isExcluded(path, config) {
+			if info.IsDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if os.FileMode(info.Mode).IsRegular() && sizes[info.Size] {
+			library.bySize[info.Size] = append(library.bySize[info.Size], path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk library %s: %w", destination, err)
+	}
+	return library, nil
+}
+
+// find returns a library file with the given size and hash, or ""
+func (l *libraryIndex) find(size int64, hash string) string {
+	for _, path := range l.bySize[size] {
+		known, ok := l.hashes[path]
+		if !ok {
+			var err error
+			if known, err = l.ig.engine.fileSystem.ComputeHash(path, l.algorithm); err != nil {
+				l.ig.engine.logger.Warn("Failed to hash library file", "path", path, "error", err)
+			}
+			l.hashes[path] = known
+		}
+		if known == hash {
+			return path
+		}
+	}
+	return ""
+}
+
+// add records a file that joined the library during the run
+func (l *libraryIndex) add(size int64, hash, path string) {
+	l.bySize[size] = append(l.bySize[size], path)
+	l.hashes[path] = hash
+}
+
+// Validate validates the ingestion operation configuration
+func (ig *IngestOperation) Validate(config domain.OperationConfig) error {
+	return ig.ValidateConfig()
+}
+
+// EstimateProgress provides an estimate of the operation's scope
+func (ig *IngestOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	return ig.estimateScope(config, 4)
+}
@@ -0,0 +1,294 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/mail"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// mailStrategies are the supported ways of choosing an attachment's folder
+var mailStrategies = []string{"date", "sender", "type", "flat"}
+
+// MailExtractFactory creates attachment extraction operations
+type MailExtractFactory struct {
+	engine *Engine
+}
+
+// Create creates a new attachment extraction operation
+func (mf *MailExtractFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewMailExtractOperation(id, config, mf.engine), nil
+}
+
+// Validate validates the attachment extraction configuration
+func (mf *MailExtractFactory) Validate(config domain.OperationConfig) error {
+	if len(config.TargetPaths) == 0 {
+		return fmt.Errorf("at least one path is required")
+	}
+	if settingString(config, "destination", "") == "" {
+		return fmt.Errorf("destination parameter is required")
+	}
+	for _, pattern := range settingStrings(config, "patterns") {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid attachment pattern %q", pattern)
+		}
+	}
+	for _, key := range []string{"since", "until"} {
+		if value := settingString(config, key, ""); value != "" {
+			if _, err := time.Parse(time.DateOnly, value); err != nil {
+				return fmt.Errorf("invalid %s date %q, expected YYYY-MM-DD", key, value)
+			}
+		}
+	}
+	strategy := settingString(config, "strategy", "date")
+	for _, supported := range mailStrategies {
+		if strategy == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported strategy %q, must be one of %v", strategy, mailStrategies)
+}
+
+// MailExtractOperation writes the attachments of mbox and EML archives that
+// match the filters into folders below a destination, by the date or sender
+// of their message or by file type. Attachments already extracted (the same
+// content under the same name) are skipped, so runs can be repeated as an
+// archive grows. The archives are not changed.
+type MailExtractOperation struct {
+	*BaseOperation
+	extracted *pathList
+	existing  *pathList // Attachments found extracted already
+	archives  *pathList
+	size      int64
+	planned   map[string]bool // Targets of this run
+}
+
+// NewMailExtractOperation creates a new attachment extraction operation
+func NewMailExtractOperation(id string, config domain.OperationConfig, engine *Engine) *MailExtractOperation {
+	base := NewBaseOperation(id, domain.OperationMailExtract, config, engine)
+	return &MailExtractOperation{
+		BaseOperation: base,
+		extracted:     base.newPathList("extracted"),
+		existing:      base.newPathList("existing"),
+		archives:      base.newPathList("archives"),
+		planned:       make(map[string]bool),
+	}
+}
+
+// mailFilter selects the attachments to extract
+type mailFilter struct {
+	patterns []string
+	from     string
+	since    time.Time
+	until    time.Time
+}
+
+// matches reports whether an attachment passes the filter
+func (f mailFilter) matches(a *mail.Attachment) bool {
+	if len(f.patterns) > 0 {
+		matched := false
+		for _, pattern := range f.patterns {
+			if ok, _ := filepath.Match(strings.ToLower(pattern), strings.ToLower(a.Filename)); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if f.from != "" && !strings.Contains(a.From, f.from) {
+		return false
+	}
+	if !f.since.IsZero() && a.Date.Before(f.since) {
+		return false
+	}
+	// until is inclusive of the whole day
+	if !f.until.IsZero() && !a.Date.Before(f.until.AddDate(0, 0, 1)) {
+		return false
+	}
+	return true
+}
+
+// Execute extracts the matching attachments of the archives below the
+// configured paths
+func (mo *MailExtractOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	tracker := mo.engine.progressTracker.StartOperation(mo.id, domain.OperationMailExtract, 3)
+	mo.SetTracker(tracker)
+
+	destination := settingString(config, "destination", "")
+	strategy := settingString(config, "strategy", "date")
+	filter := mailFilter{
+		patterns: settingStrings(config, "patterns"),
+		from:     strings.ToLower(settingString(config, "from", "")),
+	}
+	filter.since, _ = time.ParseInLocation(time.DateOnly, settingString(config, "since", ""), time.Local)
+	filter.until, _ = time.ParseInLocation(time.DateOnly, settingString(config, "until", ""), time.Local)
+
+	mo.UpdateStep("Scanning mail archives")
+
+	archives := make([]*domain.FileInfo, 0)
+	var totalSize int64
+	for _, root := range config.TargetPaths {
+		err := mo.Walk(ctx, root, config, func(path string, info *domain.FileInfo, err error) error {
+			if err != nil {
+				mo.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+				return nil // Continue walking
+			}
+			if info == nil {
+				return nil
+			}
+			if mo.isExcluded(path, config) || (info.IsDir && path == destination) {
+				if info.IsDir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.IsDir && mail.Supported(path) {
+				archives = append(archives, info)
+				totalSize += info.Size
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk path %s: %w", root, err)
+		}
+	}
+
+	mo.UpdateStep("Extracting attachments")
+	mo.SetProgress(0, int64(len(archives)), 0, totalSize)
+
+	detector := filesystem.NewFileTypeDetector()
+	for _, archive := range archives {
+		if err := mo.CheckContext(ctx); err != nil {
+			return nil, err
+		}
+		err := mail.Walk(archive.Path, func(a *mail.Attachment) error {
+			if err := mo.CheckContext(ctx); err != nil {
+				return err
+			}
+			if !filter.matches(a) {
+				return nil
+			}
+			target := filepath.Join(destination, mailFolder(a, strategy, detector), a.Filename)
+			if err := mo.extract(a, target, config.DryRun); err != nil {
+				mo.AddFileError(a.Path(), fmt.Errorf("failed to extract %s: %w", a.Path(), err))
+			}
+			return nil
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			mo.AddFileError(archive.Path, fmt.Errorf("failed to read mail archive %s: %w", archive.Path, err))
+		} else {
+			mo.archives.Add(archive.Path)
+		}
+		mo.IncrementProgress(1, archive.Size)
+	}
+
+	mo.UpdateStep("Completing extraction")
+
+	details := map[string]interface{}{
+		"extracted":   mo.extracted.Result(),
+		"existing":    mo.existing.Result(),
+		"archives":    mo.archives.Result(),
+		"size":        mo.size,
+		"destination": destination,
+		"strategy":    strategy,
+		"dry_run":     config.DryRun,
+	}
+
+	summary := fmt.Sprintf("Mail extraction: %d attachments extracted from %d archives, %d extracted before",
+		mo.extracted.Len(), mo.archives.Len(), mo.existing.Len())
+	if config.DryRun {
+		summary = fmt.Sprintf("Mail extraction (dry run): %d attachments would be extracted from %d archives, %d extracted before",
+			mo.extracted.Len(), mo.archives.Len(), mo.existing.Len())
+	}
+
+	return mo.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// mailFolder returns the folder of an attachment below the destination
+func mailFolder(a *mail.Attachment, strategy string, detector *filesystem.FileTypeDetector) string {
+	switch strategy {
+	case "date":
+		if a.Date.IsZero() {
+			return "Undated"
+		}
+		return a.Date.Local().Format("2006/01")
+	case "sender":
+		if a.From == "" {
+			return "Unknown sender"
+		}
+		sender := strings.ReplaceAll(a.From, "/", "_")
+		_, host, _ := strings.Cut(sender, "@")
+		return filepath.Join(host, sender)
+	case "type":
+		return detector.GetCategory(a.Filename)
+	}
+	return ""
+}
+
+// extract writes an attachment to target, or to a numbered variant when
+// target holds something else. An identical file at either is kept.
+func (mo *MailExtractOperation) extract(a *mail.Attachment, target string, dryRun bool) error {
+	ext := filepath.Ext(target)
+	base := strings.TrimSuffix(target, ext)
+	for i := 0; ; i++ {
+		candidate := target
+		if i > 0 {
+			candidate = fmt.Sprintf("%s_%d%s", base, i, ext)
+		}
+		if mo.planned[candidate] {
+			continue
+		}
+		existing, err := os.ReadFile(candidate)
+		if err == nil {
+			if bytes.Equal(existing, a.Data) {
+				mo.existing.Add(candidate)
+				return nil
+			}
+			continue
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		target = candidate
+		break
+	}
+
+	mo.planned[target] = true
+	if !dryRun {
+		if err := mo.engine.fileSystem.CreateDir(filepath.Dir(target)); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, a.Data, 0o644); err != nil {
+			return err
+		}
+		if !a.Date.IsZero() {
+			os.Chtimes(target, a.Date, a.Date)
+		}
+		mo.engine.logger.Debug("Extracted attachment", "attachment", a.Path(), "target", target)
+	}
+	mo.extracted.Add(target)
+	mo.size += int64(len(a.Data))
+	return nil
+}
+
+// Validate validates the attachment extraction operation configuration
+func (mo *MailExtractOperation) Validate(config domain.OperationConfig) error {
+	return mo.ValidateConfig()
+}
+
+// EstimateProgress provides an estimate of the operation's scope
+func (mo *MailExtractOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	return mo.estimateScope(config, 3)
+}
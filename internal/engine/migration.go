@@ -0,0 +1,669 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// MigrationStateFile is the file in the new root recording which files were
+// migrated and verified, so an interrupted migration resumes where it
+// stopped. It is removed once both trees match.
+const MigrationStateFile = ".fileops-migration.json"
+
+// migrationStateInterval is how often the state is written while verifying
+const migrationStateInterval = 10 * time.Second
+
+// MigrationFactory creates disk migration operations
+type MigrationFactory struct {
+	engine *Engine
+}
+
+// Create creates a new migration operation
+func (mf *MigrationFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewMigrationOperation(id, config, mf.engine), nil
+}
+
+// Validate validates the migration configuration
+func (mf *MigrationFactory) Validate(config domain.OperationConfig) error {
+	if len(config.TargetPaths) != 1 {
+		return fmt.Errorf("exactly one old root is required")
+	}
+	destination := settingString(config, "destination", "")
+	if destination == "" {
+		return fmt.Errorf("destination parameter is required")
+	}
+	source := config.TargetPaths[0]
+	if withinPrefixes(destination, []string{source}) || withinPrefixes(source, []string{destination}) {
+		return fmt.Errorf("old root %s and new root %s must not contain each other", source, destination)
+	}
+	switch settingString(config, "space_check", SpaceCheckRefuse) {
+	case SpaceCheckRefuse, SpaceCheckWarn, SpaceCheckOff:
+	default:
+		return fmt.Errorf("unsupported space check mode: %s", config.CustomSettings["space_check"])
+	}
+	return nil
+}
+
+// migrationEntry is a file recorded in the migration state
+type migrationEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Sum     string    `json:"sum"`
+}
+
+// migrationState lists the files verified on the new root by their path
+// relative to the roots
+type migrationState struct {
+	Source    string                     `json:"source"`
+	Algorithm string                     `json:"algorithm"`
+	Verified  map[string]*migrationEntry `json:"verified"`
+}
+
+// migrationFile is a file of the old root with where it goes
+type migrationFile struct {
+	info   *domain.FileInfo
+	rel    string
+	target string
+	ours   bool // The target was written by the migration and may be replaced
+}
+
+// MigrationOperation moves everything from an old root to a new one in
+// four phases: it scans the old root, copies the files the new root lacks
+// with their modification times, verifies every copy against its original
+// by checksum and compares both trees. Verified files are recorded in the
+// MigrationStateFile, so running the migration again skips them and only
+// copies and verifies what is left. Files the new root already holds that
+// the state does not record are verified when they look like their original
+// and otherwise reported as differing; they are never overwritten or
+// removed. The old root is never changed.
+type MigrationOperation struct {
+	*BaseOperation
+	copied     *pathList
+	verified   *pathList
+	migrated   *pathList // Verified by an earlier run
+	mismatch   *pathList
+	missing    *pathList // Old root files without a verified copy
+	differing  *pathList // Files of the new root that differ from their original
+	extra      *pathList // Files only on the new root
+	justCopied []migrationFile
+	conflicts  map[string]bool // Files of the new root the migration leaves alone, by relative path
+	copiedSize int64
+	mu         sync.Mutex
+}
+
+// NewMigrationOperation creates a new migration operation
+func NewMigrationOperation(id string, config domain.OperationConfig, engine *Engine) *MigrationOperation {
+	base := NewBaseOperation(id, domain.OperationMigration, config, engine)
+	return &MigrationOperation{
+		BaseOperation: base,
+		copied:        base.newPathList("copied"),
+		verified:      base.newPathList("verified"),
+		migrated:      base.newPathList("already_migrated"),
+		mismatch:      base.newPathList("mismatched"),
+		missing:       base.newPathList("missing"),
+		differing:     base.newPathList("differing"),
+		extra:         base.newPathList("extra"),
+		conflicts:     make(map[string]bool),
+	}
+}
+
+// Execute migrates the old root to the new root
+func (mo *MigrationOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	tracker := mo.engine.progressTracker.StartOperation(mo.id, domain.OperationMigration, 5)
+	mo.SetTracker(tracker)
+
+	source := config.TargetPaths[0]
+	destination := settingString(config, "destination", "")
+	report := settingString(config, "report", "")
+	algorithm := config.HashAlgorithm
+	if algorithm == "" {
+		algorithm = "blake2b" // Default
+	}
+
+	mo.UpdateStep("Scanning old root")
+
+	files, dirs, err := mo.scan(ctx, source, destination, config)
+	if err != nil {
+		return nil, err
+	}
+
+	state := mo.loadState(destination, source, algorithm)
+	pendingCopy, pendingVerify := mo.plan(files, state, config)
+
+	mo.UpdateStep("Checking destination space")
+
+	jobs := make([]CopyJob, 0, len(pendingCopy))
+	for _, file := range pendingCopy {
+		jobs = append(jobs, CopyJob{Source: file.info.Path, SourceRoot: source, Target: file.target, Size: file.info.Size})
+	}
+	spaceMode := settingString(config, "space_check", SpaceCheckRefuse)
+	if config.DryRun && spaceMode == SpaceCheckRefuse {
+		spaceMode = SpaceCheckWarn // Dry runs only report
+	}
+	spaceEstimate, err := mo.engine.CheckDiskSpace(destination, jobs, "copy", spaceMode)
+	if err != nil {
+		return nil, err
+	}
+	if spaceEstimate != nil && !spaceEstimate.Sufficient {
+		mo.AddWarning(spaceEstimate.Destination, domain.WarningInsufficientSpace, fmt.Sprintf("insufficient space at %s: %d bytes required, %d bytes available",
+			spaceEstimate.Destination, spaceEstimate.Required, spaceEstimate.Available))
+	}
+
+	mo.UpdateStep("Copying files")
+	if err := mo.copyFiles(ctx, pendingCopy, dirs, source, destination, config); err != nil {
+		mo.saveState(destination, state, config)
+		return nil, err
+	}
+
+	mo.UpdateStep("Verifying copies")
+	pendingVerify = append(pendingVerify, mo.justCopied...)
+	if config.DryRun {
+		pendingVerify = append(pendingVerify, pendingCopy...) // Copied by the real run
+	}
+	if err := mo.verifyFiles(ctx, pendingVerify, state, algorithm, config); err != nil {
+		mo.saveState(destination, state, config)
+		return nil, err
+	}
+
+	mo.UpdateStep("Comparing old and new root")
+	if err := mo.compare(ctx, files, state, destination, config); err != nil {
+		mo.saveState(destination, state, config)
+		return nil, err
+	}
+
+	// A retry of some files cannot tell whether the others match
+	complete := mo.missing.Len() == 0 && mo.differing.Len() == 0 && len(config.OnlyPaths) == 0
+	if complete && !config.DryRun {
+		if err := os.Remove(filepath.Join(destination, MigrationStateFile)); err != nil && !os.IsNotExist(err) {
+			mo.AddFileError(destination, fmt.Errorf("failed to remove migration state: %w", err))
+		}
+	} else {
+		mo.saveState(destination, state, config)
+	}
+
+	details := map[string]interface{}{
+		"copied":           mo.copied.Result(),
+		"verified":         mo.verified.Result(),
+		"already_migrated": mo.migrated.Result(),
+		"mismatched":       mo.mismatch.Result(),
+		"missing":          mo.missing.Result(),
+		"differing":        mo.differing.Result(),
+		"extra":            mo.extra.Result(),
+		"source":           source,
+		"destination":      destination,
+		"total_files":      len(files),
+		"copied_size":      mo.copiedSize,
+		"complete":         complete,
+		"algorithm":        algorithm,
+		"dry_run":          config.DryRun,
+	}
+	if spaceEstimate != nil {
+		details["space_estimate"] = spaceEstimate
+	}
+
+	if report != "" {
+		if err := mo.writeReport(report, details); err != nil {
+			mo.AddFileError(report, fmt.Errorf("failed to write report: %w", err))
+		} else {
+			details["report"] = report
+		}
+	}
+
+	summary := fmt.Sprintf("Migration: %d files copied, %d verified, %d verified earlier; %d missing, %d differing, %d only on the new root",
+		mo.copied.Len(), mo.verified.Len(), mo.migrated.Len(), mo.missing.Len(), mo.differing.Len(), mo.extra.Len())
+	if config.DryRun {
+		summary = fmt.Sprintf("Migration (dry run): %d files would be copied, %d verified, %d verified earlier; %d missing, %d differing, %d only on the new root",
+			mo.copied.Len(), mo.verified.Len(), mo.migrated.Len(), mo.missing.Len(), mo.differing.Len(), mo.extra.Len())
+	}
+
+	return mo.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// scan collects the files and directories of the old root
+func (mo *MigrationOperation) scan(ctx context.Context, source, destination string, config domain.OperationConfig) ([]migrationFile, []string, error) {
+	files := make([]migrationFile, 0)
+	dirs := make([]string, 0)
+	err := mo.Walk(ctx, source, config, func(path string, info *domain.FileInfo, err error) error {
+		if err != nil {
+			mo.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+			return nil // Continue walking
+		}
+		if info == nil || path == source {
+			return nil
+		}
+		if mo.isExcluded(path, config) {
+			if info.IsDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return nil
+		}
+		if info.IsDir {
+			dirs = append(dirs, rel)
+			return nil
+		}
+		files = append(files, migrationFile{info: info, rel: rel, target: filepath.Join(destination, rel)})
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to walk path %s: %w", source, err)
+	}
+	return files, dirs, nil
+}
+
+// plan sorts the files into those verified by an earlier run, those whose
+// copy only needs verifying and those to copy. Copies are renamed into place
+// once complete, so a target with the size and modification time of its
+// original was either copied by an earlier run that stopped before verifying
+// it or is the user's own; verifying tells them apart without changing it.
+// Only targets the state records as verified copies of an older version are
+// copied over. Any other existing target is a conflict.
+func (mo *MigrationOperation) plan(files []migrationFile, state *migrationState, config domain.OperationConfig) (copies, verifies []migrationFile) {
+	for _, file := range files {
+		target, err := os.Lstat(file.target)
+		if os.IsNotExist(err) {
+			delete(state.Verified, file.rel)
+			copies = append(copies, file)
+			continue
+		}
+		regular := err == nil && target.Mode().IsRegular()
+		sameAsSource := regular && target.Size() == file.info.Size &&
+			config.MTimeTolerance.Equal(target.ModTime(), file.info.ModTime)
+
+		entry := state.Verified[file.rel]
+		switch {
+		case sameAsSource && entry != nil && entry.Size == file.info.Size && config.MTimeTolerance.Equal(entry.ModTime, file.info.ModTime):
+			mo.migrated.Add(file.info.Path)
+		case regular && entry != nil && target.Size() == entry.Size && config.MTimeTolerance.Equal(target.ModTime(), entry.ModTime):
+			// A verified copy of what the original was before it changed
+			delete(state.Verified, file.rel)
+			file.ours = true
+			copies = append(copies, file)
+		case sameAsSource:
+			delete(state.Verified, file.rel)
+			verifies = append(verifies, file)
+		default:
+			delete(state.Verified, file.rel)
+			mo.conflicts[file.rel] = true
+		}
+	}
+	return copies, verifies
+}
+
+// copyFiles recreates the directories of the old root and copies the files
+func (mo *MigrationOperation) copyFiles(ctx context.Context, files []migrationFile, dirs []string, source, destination string, config domain.OperationConfig) error {
+	var totalSize int64
+	jobs := make([]CopyJob, 0, len(files))
+	byTarget := make(map[string]migrationFile, len(files))
+	for _, file := range files {
+		totalSize += file.info.Size
+		jobs = append(jobs, CopyJob{Source: file.info.Path, SourceRoot: source, Target: file.target, Size: file.info.Size})
+		byTarget[file.target] = file
+	}
+	mo.SetProgress(0, int64(len(jobs)), 0, totalSize)
+
+	if !config.DryRun {
+		// Empty directories are part of the tree too
+		for _, dir := range dirs {
+			if err := mo.engine.fileSystem.CreateDir(filepath.Join(destination, dir)); err != nil {
+				mo.AddFileError(filepath.Join(source, dir), fmt.Errorf("failed to create %s: %w", filepath.Join(destination, dir), err))
+			}
+		}
+	}
+
+	scheduler := NewCopyScheduler(
+		settingInt(config, "source_concurrency", 2),
+		settingInt(config, "destination_concurrency", 2),
+		config.DryRun,
+	)
+
+	// Stop scheduling new copies as soon as the error budget is exhausted
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	gate := mo.workerGate(settingInt(config, "destination_concurrency", 2))
+	err := scheduler.Run(runCtx, jobs, func(ctx context.Context, job CopyJob) error {
+		if err := mo.CheckContext(ctx); err != nil {
+			return err
+		}
+		if config.DryRun {
+			mo.planTransfer(job.Source, job.Target, job.Size, false)
+			return nil
+		}
+		gate.Enter(ctx)
+		defer gate.Leave()
+		return mo.copyFile(job, byTarget[job.Target])
+	}, func(job CopyJob, err error) {
+		mo.mu.Lock()
+		defer mo.mu.Unlock()
+		switch {
+		case err == nil:
+			mo.copied.Add(job.Source)
+			if !config.DryRun {
+				file := byTarget[job.Target]
+				file.ours = true
+				mo.justCopied = append(mo.justCopied, file)
+			}
+			mo.copiedSize += job.Size
+			mo.IncrementProgress(1, job.Size)
+		case ctx.Err() != nil || mo.ErrorBudgetErr() != nil:
+			// Aborted before the copy started; the next run copies it
+		default:
+			mo.AddFileError(job.Source, fmt.Errorf("failed to copy %s: %w", job.Source, err))
+			mo.IncrementProgress(1, 0)
+		}
+		if mo.ErrorBudgetErr() != nil {
+			cancel()
+		}
+	})
+	if budgetErr := mo.ErrorBudgetErr(); budgetErr != nil {
+		return budgetErr
+	}
+	return err
+}
+
+// copyFile copies a file under a temporary name, gives the copy the
+// modification time of its original and renames it into place, so
+// interrupted copies never sit at the target. A target that appeared since
+// the plan is left alone unless the migration wrote it.
+func (mo *MigrationOperation) copyFile(job CopyJob, file migrationFile) error {
+	if err := mo.engine.fileSystem.CreateDir(filepath.Dir(job.Target)); err != nil {
+		return err
+	}
+	tmp := job.Target + ".fileops-migrate"
+	if err := mo.copyWarning(tmp, mo.engine.fileSystem.Copy(job.Source, tmp)); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Chtimes(tmp, time.Now(), file.info.ModTime); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if _, err := os.Lstat(job.Target); !file.ours && !os.IsNotExist(err) {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("%s appeared on the new root during the migration: %w", job.Target, os.ErrExist)
+	}
+	if err := os.Rename(tmp, job.Target); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// verifyFiles compares the checksum of every copy with its original and
+// records the verified ones in the state. Copies made by this run that differ
+// are removed so the next run copies them again; other files that differ are
+// left alone and reported as differing.
+func (mo *MigrationOperation) verifyFiles(ctx context.Context, files []migrationFile, state *migrationState, algorithm string, config domain.OperationConfig) error {
+	var totalSize int64
+	for _, file := range files {
+		totalSize += file.info.Size
+	}
+	mo.SetProgress(0, int64(len(files)), 0, totalSize)
+	if config.DryRun {
+		// Every original and its copy are read to compare them
+		for _, file := range files {
+			mo.planRead(file.info.Path, file.info.Size)
+			mo.planRead(file.target, file.info.Size)
+		}
+		return nil
+	}
+
+	workers := config.Parallelism
+	if workers <= 0 {
+		workers = 4 // Default
+	}
+
+	// Stop handing out work once the context is cancelled or the error budget is used up
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	saved := time.Now()
+	gate := mo.workerGate(workers)
+	jobs := make(chan migrationFile)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range jobs {
+				gate.Enter(runCtx)
+				mo.verify(file, state, algorithm)
+				gate.Leave()
+				mo.mu.Lock()
+				if time.Since(saved) > migrationStateInterval {
+					mo.writeState(settingString(config, "destination", ""), state)
+					saved = time.Now()
+				}
+				mo.mu.Unlock()
+				if mo.ErrorBudgetErr() != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, file := range files {
+		if err := mo.CheckContext(runCtx); err != nil {
+			break
+		}
+		select {
+		case jobs <- file:
+		case <-runCtx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := mo.ErrorBudgetErr(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// verify compares the checksums of a file and its copy
+func (mo *MigrationOperation) verify(file migrationFile, state *migrationState, algorithm string) {
+	defer mo.IncrementProgress(1, file.info.Size)
+
+	original, err := filesystem.HashFile(file.info.Path, algorithm)
+	if err != nil {
+		mo.AddFileError(file.info.Path, fmt.Errorf("failed to hash %s: %w", file.info.Path, err))
+		return
+	}
+	copied, err := filesystem.HashFile(file.target, algorithm)
+	if err != nil {
+		mo.AddFileError(file.target, fmt.Errorf("failed to hash %s: %w", file.target, err))
+		return
+	}
+
+	mo.mu.Lock()
+	defer mo.mu.Unlock()
+	if original[0] != copied[0] {
+		if !file.ours {
+			mo.conflicts[file.rel] = true
+			mo.AddFileError(file.info.Path, fmt.Errorf("%s was already on the new root and differs from %s; it was left alone: %w", file.target, file.info.Path, os.ErrExist))
+			return
+		}
+		mo.mismatch.Add(file.info.Path)
+		mo.AddFileError(file.info.Path, fmt.Errorf("copy %s differs from %s, it was removed and is copied again by the next run", file.target, file.info.Path))
+		if err := mo.engine.fileSystem.Remove(file.target); err != nil {
+			mo.AddFileError(file.target, fmt.Errorf("failed to remove %s: %w", file.target, err))
+		}
+		return
+	}
+	state.Verified[file.rel] = &migrationEntry{Size: file.info.Size, ModTime: file.info.ModTime, Sum: original[0]}
+	mo.verified.Add(file.info.Path)
+}
+
+// compare walks the new root and lists what differs from the old one: files
+// without a verified copy, copies that differ and files only on the new root.
+// Retries of some files (OnlyPaths) compare only those.
+func (mo *MigrationOperation) compare(ctx context.Context, files []migrationFile, state *migrationState, destination string, config domain.OperationConfig) error {
+	retry := len(config.OnlyPaths) > 0
+	config.OnlyPaths = nil // They name files of the old root
+	expected := make(map[string]migrationFile, len(files))
+	for _, file := range files {
+		expected[file.rel] = file
+	}
+	mo.SetProgress(0, int64(len(files)), 0, 0)
+
+	found := make(map[string]bool, len(files))
+	if _, err := os.Stat(destination); os.IsNotExist(err) {
+		// A dry run before the first copy
+		for _, file := range files {
+			mo.missing.Add(file.info.Path)
+		}
+		return nil
+	}
+	err := mo.Walk(ctx, destination, config, func(path string, info *domain.FileInfo, err error) error {
+		if err != nil {
+			mo.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+			return nil // Continue walking
+		}
+		if info == nil || info.IsDir || path == filepath.Join(destination, MigrationStateFile) {
+			return nil
+		}
+		rel, err := filepath.Rel(destination, path)
+		if err != nil {
+			return nil
+		}
+		file, ok := expected[rel]
+		if !ok {
+			if !retry {
+				mo.extra.Add(path)
+			}
+			return nil
+		}
+		found[rel] = true
+		if mo.conflicts[rel] {
+			mo.differing.Add(path)
+		} else if state.Verified[rel] == nil && !config.DryRun {
+			mo.differing.Add(path)
+		} else if info.Size != file.info.Size {
+			mo.differing.Add(path)
+		}
+		mo.IncrementProgress(1, 0)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to walk path %s: %w", destination, err)
+	}
+
+	for _, file := range files {
+		if !found[file.rel] {
+			mo.missing.Add(file.info.Path)
+		}
+	}
+	return nil
+}
+
+// loadState reads the state of an earlier run; a state of another old root
+// or checksum algorithm is discarded
+func (mo *MigrationOperation) loadState(destination, source, algorithm string) *migrationState {
+	fresh := &migrationState{Source: source, Algorithm: algorithm, Verified: make(map[string]*migrationEntry)}
+
+	data, err := os.ReadFile(filepath.Join(destination, MigrationStateFile))
+	if err != nil {
+		return fresh
+	}
+	var state migrationState
+	if err := json.Unmarshal(data, &state); err != nil {
+		mo.engine.logger.Warn("Ignoring unreadable migration state", "destination", destination, "error", err)
+		return fresh
+	}
+	if state.Source != source || state.Algorithm != algorithm || state.Verified == nil {
+		return fresh
+	}
+	return &state
+}
+
+// saveState writes the state unless this is a dry run
+func (mo *MigrationOperation) saveState(destination string, state *migrationState, config domain.OperationConfig) {
+	if config.DryRun {
+		return
+	}
+	mo.mu.Lock()
+	defer mo.mu.Unlock()
+	mo.writeState(destination, state)
+}
+
+// writeState replaces the state file; mo.mu must be held
+func (mo *MigrationOperation) writeState(destination string, state *migrationState) {
+	data, err := json.Marshal(state)
+	if err == nil {
+		err = os.MkdirAll(destination, 0o755)
+	}
+	if err == nil {
+		err = filesystem.WriteFileAtomic(filepath.Join(destination, MigrationStateFile), data, 0o644)
+	}
+	if err != nil {
+		mo.AddFileError(destination, fmt.Errorf("failed to save migration state: %w", err))
+	}
+}
+
+// writeReport writes the comparison of both roots as text
+func (mo *MigrationOperation) writeReport(path string, details map[string]interface{}) error {
+	// The lists are read back like a stored result, as long ones were
+	// streamed to files
+	result := &domain.OperationResult{ID: mo.id, OperationType: domain.OperationMigration, Details: details}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Migration of %s to %s\n", details["source"], details["destination"])
+	fmt.Fprintf(&b, "Operation: %s\n", mo.id)
+	fmt.Fprintf(&b, "Date:      %s\n\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&b, "Copied:           %d\n", mo.copied.Len())
+	fmt.Fprintf(&b, "Verified:         %d\n", mo.verified.Len())
+	fmt.Fprintf(&b, "Verified earlier: %d\n", mo.migrated.Len())
+	if details["complete"] == true {
+		b.WriteString("\nEvery file of the old root has a verified copy on the new root.\n")
+	} else {
+		b.WriteString("\nThe migration is incomplete; run it again to resume.\n")
+	}
+
+	sections := []struct{ key, heading string }{
+		{"missing", "Missing on the new root"},
+		{"differing", "Differing from the old root"},
+		{"mismatched", "Mismatched checksums (copied again by the next run)"},
+		{"extra", "Only on the new root"},
+	}
+	for _, section := range sections {
+		paths, err := ResultFiles(result, section.key)
+		if err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			continue
+		}
+		sort.Strings(paths)
+		fmt.Fprintf(&b, "\n%s (%d):\n", section.heading, len(paths))
+		for _, p := range paths {
+			fmt.Fprintf(&b, "  %s\n", p)
+		}
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
+
+// Validate validates the migration operation configuration
+func (mo *MigrationOperation) Validate(config domain.OperationConfig) error {
+	return mo.ValidateConfig()
+}
+
+// EstimateProgress provides an estimate of the operation's scope
+func (mo *MigrationOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	return mo.estimateScope(config, 5)
+}
@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// maxOperationIDLength bounds caller-supplied IDs, which name files and directories
+const maxOperationIDLength = 128
+
+// crockford is the base32 alphabet of ULIDs
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulids generates monotonic ULIDs: within the same millisecond the random
+// part of the previous ID is incremented, so IDs sort in creation order
+var ulids struct {
+	mu     sync.Mutex
+	millis uint64
+	random [10]byte
+}
+
+// NewOperationID returns a new ID for an operation of the given type, the
+// type followed by a ULID, e.g. deduplication-01HQ3Z9V5B8M2K7T4XWJ6N0RCE.
+// IDs sort by creation time and do not collide, even across processes
+// starting operations in the same millisecond.
+func NewOperationID(operationType domain.OperationType) string {
+	return fmt.Sprintf("%s-%s", operationType, newULID(time.Now()))
+}
+
+// newULID returns the ULID of now: 48 bits of Unix milliseconds and 80 random
+// bits, in 26 characters of Crockford base32
+func newULID(now time.Time) string {
+	millis := uint64(now.UnixMilli())
+
+	ulids.mu.Lock()
+	if millis <= ulids.millis {
+		millis = ulids.millis
+		for i := len(ulids.random) - 1; i >= 0; i-- {
+			ulids.random[i]++
+			if ulids.random[i] != 0 {
+				break
+			}
+		}
+	} else {
+		ulids.millis = millis
+		rand.Read(ulids.random[:])
+	}
+	var id [16]byte
+	binary.BigEndian.PutUint16(id[0:], uint16(millis>>32))
+	binary.BigEndian.PutUint32(id[2:], uint32(millis))
+	copy(id[6:], ulids.random[:])
+	ulids.mu.Unlock()
+
+	// 128 bits in 26 characters of 5 bits, the first holding only 3
+	hi, lo := binary.BigEndian.Uint64(id[:8]), binary.BigEndian.Uint64(id[8:])
+	var text [26]byte
+	for i := 25; i >= 0; i-- {
+		text[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(text[:])
+}
+
+// ValidateOperationID checks an ID supplied by a caller. IDs name the run's
+// files and directories, so they are limited to letters, digits, dots,
+// dashes and underscores.
+func ValidateOperationID(id string) error {
+	if id == "" || id == "." || id == ".." {
+		return fmt.Errorf("invalid operation ID %q", id)
+	}
+	if len(id) > maxOperationIDLength {
+		return fmt.Errorf("operation ID %q is longer than %d characters", id, maxOperationIDLength)
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return fmt.Errorf("invalid operation ID %q: only letters, digits, '.', '-' and '_' are allowed", id)
+		}
+	}
+	return nil
+}
+
+// claimOperationID reserves id for a run until the returned function is
+// called. IDs of running operations and of results stored in repository are
+// refused.
+func (e *Engine) claimOperationID(id string, repository domain.Repository) (func(), error) {
+	e.mu.Lock()
+	if e.runningIDs[id] {
+		e.mu.Unlock()
+		return nil, fmt.Errorf("operation ID %s is already in use by a running operation", id)
+	}
+	e.runningIDs[id] = true
+	e.mu.Unlock()
+
+	release := func() {
+		e.mu.Lock()
+		delete(e.runningIDs, id)
+		e.mu.Unlock()
+	}
+	if repository != nil {
+		if _, err := repository.GetResult(id); err == nil {
+			release()
+			return nil, fmt.Errorf("operation ID %s is already used by a stored result", id)
+		}
+	}
+	return release, nil
+}
@@ -11,150 +11,9 @@ import (
 	"time"
 
 	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/schema"
 )
 
-// DeduplicationFactory creates deduplication operations
-type DeduplicationFactory struct {
-	engine *Engine
-}
-
-// Create creates a new deduplication operation
-func (df *DeduplicationFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
-	return NewDeduplicationOperation(id, config, df.engine), nil
-}
-
-// Validate validates the deduplication configuration
-func (df *DeduplicationFactory) Validate(config domain.OperationConfig) error {
-	// Add deduplication-specific validation
-	if config.HashAlgorithm == "" {
-		config.HashAlgorithm = "blake2b" // Default
-	}
-	return nil
-}
-
-// DeduplicationOperation implements file deduplication functionality
-type DeduplicationOperation struct {
-	*BaseOperation
-	duplicateGroups []domain.DuplicateGroup
-	totalSize       int64
-	saveableSize    int64
-}
-
-// NewDeduplicationOperation creates a new deduplication operation
-func NewDeduplicationOperation(id string, config domain.OperationConfig, engine *Engine) *DeduplicationOperation {
-	base := NewBaseOperation(id, domain.OperationDeduplication, config, engine)
-	return &DeduplicationOperation{
-		BaseOperation:   base,
-		duplicateGroups: make([]domain.DuplicateGroup, 0),
-	}
-}
-
-// Execute performs the deduplication operation
-func (do *DeduplicationOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
-	// Start tracking progress
-	tracker := do.engine.progressTracker.StartOperation(do.id, domain.OperationDeduplication, 5)
-	do.SetTracker(tracker)
-
-	tracker.UpdateStep("Scanning files")
-
-	// Basic implementation - TODO: Add full deduplication logic
-	details := map[string]interface{}{
-		"duplicate_groups": len(do.duplicateGroups),
-		"total_size":       do.totalSize,
-		"saveable_size":    do.saveableSize,
-		"hash_algorithm":   config.HashAlgorithm,
-		"dry_run":          config.DryRun,
-	}
-
-	summary := "Deduplication operation completed (basic implementation)"
-	tracker.UpdateStep("Completed")
-	return do.CreateResult(domain.StatusCompleted, summary, details), nil
-}
-
-// Validate validates the deduplication operation configuration
-func (do *DeduplicationOperation) Validate(config domain.OperationConfig) error {
-	return do.ValidateConfig()
-}
-
-// EstimateProgress provides an estimate of the operation's scope
-func (do *DeduplicationOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
-	return &domain.ProgressInfo{
-		ID:            do.id,
-		OperationType: domain.OperationDeduplication,
-		Status:        domain.StatusPending,
-		TotalSteps:    5,
-		TotalItems:    1000, // Estimated
-	}, nil
-}
-
-// ConsolidationFactory creates consolidation operations
-type ConsolidationFactory struct {
-	engine *Engine
-}
-
-// Create creates a new consolidation operation
-func (cf *ConsolidationFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
-	return NewConsolidationOperation(id, config, cf.engine), nil
-}
-
-// Validate validates the consolidation configuration
-func (cf *ConsolidationFactory) Validate(config domain.OperationConfig) error {
-	// Add consolidation-specific validation
-	return nil
-}
-
-// ConsolidationOperation implements file consolidation functionality
-type ConsolidationOperation struct {
-	*BaseOperation
-	movedFiles  []string
-	copiedFiles []string
-}
-
-// NewConsolidationOperation creates a new consolidation operation
-func NewConsolidationOperation(id string, config domain.OperationConfig, engine *Engine) *ConsolidationOperation {
-	base := NewBaseOperation(id, domain.OperationConsolidation, config, engine)
-	return &ConsolidationOperation{
-		BaseOperation: base,
-		movedFiles:    make([]string, 0),
-		copiedFiles:   make([]string, 0),
-	}
-}
-
-// Execute performs the consolidation operation
-func (co *ConsolidationOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
-	// Start tracking progress
-	tracker := co.engine.progressTracker.StartOperation(co.id, domain.OperationConsolidation, 4)
-	co.SetTracker(tracker)
-
-	tracker.UpdateStep("Planning consolidation")
-
-	// Implementation will be added in the next phase
-	details := map[string]interface{}{
-		"moved_files":  co.movedFiles,
-		"copied_files": co.copiedFiles,
-		"dry_run":      config.DryRun,
-	}
-
-	summary := "Consolidation operation completed (placeholder implementation)"
-	return co.CreateResult(domain.StatusCompleted, summary, details), nil
-}
-
-// Validate validates the consolidation operation configuration
-func (co *ConsolidationOperation) Validate(config domain.OperationConfig) error {
-	return co.ValidateConfig()
-}
-
-// EstimateProgress provides an estimate of the operation's scope
-func (co *ConsolidationOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
-	return &domain.ProgressInfo{
-		ID:            co.id,
-		OperationType: domain.OperationConsolidation,
-		Status:        domain.StatusPending,
-		TotalSteps:    4,
-		TotalItems:    500, // Estimated
-	}, nil
-}
-
 // OwnershipFactory creates ownership change operations
 type OwnershipFactory struct {
 	engine *Engine
@@ -182,9 +41,9 @@ func (of *OwnershipFactory) Validate(config domain.OperationConfig) error {
 // OwnershipOperation implements file/directory ownership change functionality
 type OwnershipOperation struct {
 	*BaseOperation
-	changedItems []string
-	skippedItems []string
-	errors       []string
+	changedItems *pathList
+	skippedItems *pathList
+	errors       *pathList
 }
 
 // NewOwnershipOperation creates a new ownership operation
@@ -192,9 +51,9 @@ func NewOwnershipOperation(id string, config domain.OperationConfig, engine *Eng
 	base := NewBaseOperation(id, domain.OperationOwnership, config, engine)
 	return &OwnershipOperation{
 		BaseOperation: base,
-		changedItems:  make([]string, 0),
-		skippedItems:  make([]string, 0),
-		errors:        make([]string, 0),
+		changedItems:  base.newPathList("changed_items"),
+		skippedItems:  base.newPathList("skipped_items"),
+		errors:        base.newPathList("errors"),
 	}
 }
 
@@ -204,21 +63,26 @@ func (oo *OwnershipOperation) Execute(ctx context.Context, config domain.Operati
 	tracker := oo.engine.progressTracker.StartOperation(oo.id, domain.OperationOwnership, 3)
 	oo.SetTracker(tracker)
 
-	defer func() {
-		tracker.Complete()
-	}()
-
 	// Step 1: Scan files/directories
-	tracker.UpdateStep("Scanning paths...")
+	oo.UpdateStep("Scanning paths...")
+
+	// Ownership parameters
+	uid := settingNumber(config, "uid", 0)
+	gid := settingNumber(config, "gid", 0)
+	fromUID := settingNumber(config, "from_uid", -1)
+	fromGID := settingNumber(config, "from_gid", -1)
+	noDereference := settingBool(config, "no_dereference", false)
 
 	var filesToProcess []string
 	var scanErrors []error
 	var scannedCount int64
+	var notMatched int
 
-	for _, pattern := range config.IncludePatterns {
-		err := oo.engine.fileSystem.Walk(ctx, pattern, func(path string, info *domain.FileInfo, err error) error {
+	for _, root := range config.TargetPaths {
+		err := oo.Walk(ctx, root, config, func(path string, info *domain.FileInfo, err error) error {
 			if err != nil {
 				scanErrors = append(scanErrors, fmt.Errorf("error walking %s: %w", path, err))
+				oo.AddFileError(path, fmt.Errorf("error walking %s: %w", path, err))
 				return nil // Continue walking
 			}
 
@@ -233,73 +97,76 @@ func (oo *OwnershipOperation) Execute(ctx context.Context, config domain.Operati
 
 			if !excluded {
 				// If not recursive, only include direct children
-				if !config.Recursive && len(strings.Split(path, string(filepath.Separator))) > len(strings.Split(pattern, string(filepath.Separator)))+1 {
+				if !config.Recursive && len(strings.Split(path, string(filepath.Separator))) > len(strings.Split(root, string(filepath.Separator)))+1 {
 					return nil
 				}
 
-				filesToProcess = append(filesToProcess, path)
+				if oo.ownedBy(path, info, fromUID, fromGID, noDereference) {
+					filesToProcess = append(filesToProcess, path)
+				} else {
+					notMatched++
+				}
 			}
 
 			// Update progress in real-time during scanning
 			scannedCount++
 			if scannedCount%100 == 0 || scannedCount < 100 {
-				tracker.UpdateProgress(scannedCount, 0, 0, 0) // TotalItems unknown during scanning
+				oo.SetProgress(scannedCount, 0, 0, 0) // TotalItems unknown during scanning
 			}
 
 			return nil
 		})
 
 		if err != nil {
-			return nil, fmt.Errorf("failed to walk path %s: %w", pattern, err)
+			return nil, fmt.Errorf("failed to walk path %s: %w", root, err)
 		}
 	}
 
 	// Report any scan errors but continue
 	if len(scanErrors) > 0 {
 		for _, err := range scanErrors {
-			oo.errors = append(oo.errors, err.Error())
+			oo.errors.Add(err.Error())
 		}
 	}
 
 	// Update progress with final scan count and total items
-	tracker.UpdateProgress(int64(len(filesToProcess)), int64(len(filesToProcess)), 0, 0)
+	oo.SetProgress(int64(len(filesToProcess)), int64(len(filesToProcess)), 0, 0)
 
 	// Step 2: Change ownership
-	tracker.UpdateStep("Changing ownership...")
-
-	// Get ownership parameters
-	uid, _ := config.CustomSettings["uid"].(int)
-	gid, _ := config.CustomSettings["gid"].(int)
+	oo.UpdateStep("Changing ownership...")
 
 	processed := int64(0)
 	for _, file := range filesToProcess {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
+		if err := oo.CheckContext(ctx); err != nil {
+			return nil, err
 		}
 
-		err := oo.changeFileOwnership(file, uid, gid, config.DryRun)
+		err := oo.changeFileOwnership(file, uid, gid, noDereference, config.DryRun)
 		if err != nil {
-			oo.errors = append(oo.errors, fmt.Sprintf("%s: %v", file, err))
-			oo.skippedItems = append(oo.skippedItems, file)
+			oo.AddFileError(file, fmt.Errorf("%s: %w", file, err))
+			oo.errors.Add(fmt.Sprintf("%s: %v", file, err))
+			oo.skippedItems.Add(file)
 		} else {
-			oo.changedItems = append(oo.changedItems, file)
+			oo.changedItems.Add(file)
 		}
 
 		processed++
-		tracker.UpdateProgress(processed, int64(len(filesToProcess)), 0, 0)
+		oo.SetProgress(processed, int64(len(filesToProcess)), 0, 0)
 	}
 
 	// Step 3: Complete
-	tracker.UpdateStep("Finalizing...")
+	oo.UpdateStep("Finalizing...")
 
 	// Create result
 	summary := fmt.Sprintf("Ownership change (%s): %d items changed, %d skipped, %d errors",
 		map[bool]string{true: "dry run", false: "completed"}[config.DryRun],
-		len(oo.changedItems), len(oo.skippedItems), len(oo.errors))
+		oo.changedItems.Len(), oo.skippedItems.Len(), oo.errors.Len())
+	if fromUID >= 0 || fromGID >= 0 {
+		summary += fmt.Sprintf(", %d not owned by --from", notMatched)
+	}
 
 	result := &domain.OperationResult{
+		SchemaVersion: schema.ResultVersion,
 		ID:            oo.id,
 		OperationType: domain.OperationOwnership,
 		Status:        domain.StatusCompleted,
@@ -307,9 +174,10 @@ func (oo *OwnershipOperation) Execute(ctx context.Context, config domain.Operati
 		EndTime:       time.Now(),
 		Summary:       summary,
 		Details: map[string]interface{}{
-			"changed_items": oo.changedItems,
-			"skipped_items": oo.skippedItems,
-			"errors":        oo.errors,
+			"changed_items": oo.changedItems.Result(),
+			"skipped_items": oo.skippedItems.Result(),
+			"errors":        oo.errors.Result(),
+			"not_matched":   notMatched,
 		},
 	}
 
@@ -329,8 +197,26 @@ func (oo *OwnershipOperation) Validate(config domain.OperationConfig) error {
 	return nil
 }
 
-// changeFileOwnership changes ownership of a single file/directory
-func (oo *OwnershipOperation) changeFileOwnership(path string, uid, gid int, dryRun bool) error {
+// ownedBy reports whether the item is currently owned by fromUID and fromGID
+// (-1 matches any), like chown --from. Symbolic links are judged by their
+// target unless noDereference is set, as that is what would be changed.
+func (oo *OwnershipOperation) ownedBy(path string, info *domain.FileInfo, fromUID, fromGID int, noDereference bool) bool {
+	if fromUID < 0 && fromGID < 0 {
+		return true
+	}
+	if !noDereference && os.FileMode(info.Mode)&os.ModeSymlink != 0 {
+		target, err := oo.engine.fileSystem.Stat(path)
+		if err != nil {
+			return false // Dangling links have no owner to match
+		}
+		info = target
+	}
+	return (fromUID < 0 || info.UID == fromUID) && (fromGID < 0 || info.GID == fromGID)
+}
+
+// changeFileOwnership changes ownership of a single file/directory. A
+// symbolic link itself is changed with noDereference, otherwise its target.
+func (oo *OwnershipOperation) changeFileOwnership(path string, uid, gid int, noDereference, dryRun bool) error {
 	if dryRun {
 		return nil // Don't actually change anything in dry run
 	}
@@ -338,6 +224,8 @@ func (oo *OwnershipOperation) changeFileOwnership(path string, uid, gid int, dry
 	if runtime.GOOS == "windows" {
 		// On Windows, use simplified ownership change
 		return oo.changeOwnershipWindows(path)
+	} else if noDereference {
+		return os.Lchown(path, uid, gid)
 	} else {
 		// On Unix-like systems, use chown
 		return syscall.Chown(path, uid, gid)
@@ -363,11 +251,5 @@ func (oo *OwnershipOperation) changeOwnershipWindows(path string) error {
 
 // EstimateProgress provides an estimate of the operation's scope
 func (oo *OwnershipOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
-	return &domain.ProgressInfo{
-		ID:            oo.id,
-		OperationType: domain.OperationOwnership,
-		Status:        domain.StatusPending,
-		TotalSteps:    3,
-		TotalItems:    100, // Estimated
-	}, nil
+	return oo.estimateScope(config, 3)
 }
@@ -0,0 +1,551 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/content"
+	"github.com/a4abhishek/fileops/internal/ocr"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// organizeStrategies are the supported ways of choosing a file's category
+var organizeStrategies = []string{"content", "type", "date"}
+
+// DefaultAlbumMarker is the file that marks a folder as a curated album
+const DefaultAlbumMarker = ".album"
+
+// Metadata keys of OCR results cached in the repository
+const (
+	ocrTextKey   = "ocr_text"
+	ocrEngineKey = "ocr_engine"
+)
+
+// OrganizationFactory creates file organization operations
+type OrganizationFactory struct {
+	engine *Engine
+}
+
+// Create creates a new organization operation
+func (of *OrganizationFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewOrganizationOperation(id, config, of.engine), nil
+}
+
+// Validate validates the organization configuration
+func (of *OrganizationFactory) Validate(config domain.OperationConfig) error {
+	if len(config.TargetPaths) == 0 {
+		return fmt.Errorf("at least one path is required")
+	}
+	if settingString(config, "destination", "") == "" {
+		return fmt.Errorf("destination parameter is required")
+	}
+	strategy := settingString(config, "strategy", "type")
+	if settingBool(config, "rename", false) && strategy != "content" {
+		return fmt.Errorf("renaming requires the content strategy")
+	}
+	for _, pattern := range settingStrings(config, "album_patterns") {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid album pattern %q", pattern)
+		}
+	}
+	if err := validateExtensions(config); err != nil {
+		return err
+	}
+	for _, supported := range organizeStrategies {
+		if strategy == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported organization strategy %q, must be one of %v", strategy, organizeStrategies)
+}
+
+// OrganizationOperation moves files into <destination>/<category>/ folders. The
+// category comes from the document text (content), the file type (type) or the
+// modification month (date). With keep_albums, folders that are curated
+// albums are left as they are and only loose files are sorted.
+type OrganizationOperation struct {
+	*BaseOperation
+	suggestions  []domain.OrganizationSuggestion
+	moved        *pathList
+	organized    *pathList
+	unclassified *pathList
+	categories   map[string]int
+	pairs        int      // RAW+JPEG pairs moved together
+	sidecars     int      // Sidecars moved with their primary
+	albums       []string // Album folders left as they are
+}
+
+// NewOrganizationOperation creates a new organization operation
+func NewOrganizationOperation(id string, config domain.OperationConfig, engine *Engine) *OrganizationOperation {
+	base := NewBaseOperation(id, domain.OperationOrganization, config, engine)
+	return &OrganizationOperation{
+		BaseOperation: base,
+		suggestions:   make([]domain.OrganizationSuggestion, 0),
+		moved:         base.newPathList("moved"),
+		organized:     base.newPathList("organized"),
+		unclassified:  base.newPathList("unclassified"),
+		categories:    make(map[string]int),
+		albums:        make([]string, 0),
+	}
+}
+
+// Execute classifies the files below the configured paths and moves them into place
+func (oo *OrganizationOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	tracker := oo.engine.progressTracker.StartOperation(oo.id, domain.OperationOrganization, 3)
+	oo.SetTracker(tracker)
+
+	strategy := settingString(config, "strategy", "type")
+	destination := settingString(config, "destination", "")
+	preserveStructure := settingBool(config, "preserve_structure", false)
+	rename := settingBool(config, "rename", false)
+
+	classify, index := oo.classifier(strategy, config)
+
+	oo.UpdateStep("Scanning files")
+
+	files, totalSize, err := oo.scan(ctx, strategy, config)
+	if err != nil {
+		return nil, err
+	}
+
+	oo.UpdateStep("Organizing files")
+	oo.SetProgress(0, int64(len(files)), 0, totalSize)
+
+	labels := newLabelDestination(destination)
+	planned := make(map[string]bool)
+	related := newCompanions()
+	for _, file := range files {
+		if err := oo.CheckContext(ctx); err != nil {
+			oo.saveIndex(index, config)
+			return nil, err
+		}
+
+		// The RAW half of a RAW+JPEG pair and sidecars go wherever their
+		// primary goes
+		if related.follows(file.info.Path) {
+			oo.IncrementProgress(1, file.info.Size)
+			continue
+		}
+
+		decision, err := classify(ctx, file.info)
+		if err != nil {
+			oo.AddFileError(file.info.Path, fmt.Errorf("failed to classify %s: %w", file.info.Path, err))
+			oo.IncrementProgress(1, file.info.Size)
+			continue
+		}
+		category := decision.category
+		if category == "" {
+			oo.unclassified.Add(file.info.Path)
+			oo.IncrementProgress(1, file.info.Size)
+			continue
+		}
+
+		categoryDir := filepath.Join(destination, filepath.FromSlash(category))
+		if strings.HasPrefix(file.info.Path, categoryDir+string(filepath.Separator)) {
+			oo.organized.Add(file.info.Path)
+			oo.IncrementProgress(1, file.info.Size)
+			continue
+		}
+
+		targetDir := categoryDir
+		if preserveStructure {
+			if rel, err := filepath.Rel(file.root, filepath.Dir(file.info.Path)); err == nil && rel != "." {
+				targetDir = filepath.Join(categoryDir, rel)
+			}
+		}
+		name := file.info.Name
+		if rename {
+			name = renameFor(name, decision.text)
+		}
+		group := related.group(file.info.Path)
+		taken := func(target string) bool {
+			if planned[target] || oo.engine.fileSystem.Exists(target) {
+				return true
+			}
+			for _, companion := range group {
+				if other := companionTarget(file.info.Path, companion, target); planned[other] || oo.engine.fileSystem.Exists(other) {
+					return true
+				}
+			}
+			return false
+		}
+		target := filepath.Join(targetDir, name)
+		if taken(target) {
+			target = oo.uniqueTarget(target, taken)
+		}
+		planned[target] = true
+		for _, companion := range group {
+			planned[companionTarget(file.info.Path, companion, target)] = true
+		}
+
+		oo.suggestions = append(oo.suggestions, domain.OrganizationSuggestion{
+			File:          *file.info,
+			SuggestedPath: target,
+			Reason:        decision.reason,
+			Confidence:    decision.confidence,
+			Category:      category,
+			Tags:          []string{strategy},
+		})
+		for _, companion := range group {
+			reason, tag := "sidecar of ", "sidecar"
+			if isRawPhoto(companion) {
+				reason, tag = "RAW of ", "raw"
+				oo.pairs++
+			} else {
+				oo.sidecars++
+			}
+			if info, err := oo.engine.fileSystem.Stat(companion); err == nil {
+				oo.suggestions = append(oo.suggestions, domain.OrganizationSuggestion{
+					File:          *info,
+					SuggestedPath: companionTarget(file.info.Path, companion, target),
+					Reason:        reason + file.info.Name,
+					Confidence:    decision.confidence,
+					Category:      category,
+					Tags:          []string{strategy, tag},
+				})
+			}
+		}
+		oo.categories[category]++
+		oo.checkSecurityLabels(file.info, labels, config)
+
+		if config.DryRun {
+			oo.engine.logger.Debug("Would organize file", "source", file.info.Path, "target", target)
+			oo.planTransfer(file.info.Path, target, file.info.Size, true)
+			oo.IncrementProgress(1, file.info.Size)
+			continue
+		}
+
+		if err := oo.move(file.info.Path, target); err != nil {
+			oo.AddFileError(file.info.Path, fmt.Errorf("failed to move %s: %w", file.info.Path, err))
+			oo.IncrementProgress(1, file.info.Size)
+			continue
+		}
+		if decision.ocrEngine != "" {
+			oo.cacheRecognized(file.info, target, decision.text, decision.ocrEngine)
+		}
+		if index != nil {
+			// Keep the indexed text reachable under the new path
+			if doc, ok := index.Get(file.info.Path); ok {
+				moved := *doc
+				moved.Path = target
+				index.Remove(file.info.Path)
+				index.Put(&moved)
+			}
+		}
+		oo.moved.Add(file.info.Path)
+		for _, companion := range group {
+			if err := oo.move(companion, companionTarget(file.info.Path, companion, target)); err != nil {
+				oo.AddFileError(companion, fmt.Errorf("failed to move %s with %s: %w", companion, file.info.Name, err))
+			} else {
+				oo.moved.Add(companion)
+			}
+		}
+		oo.IncrementProgress(1, file.info.Size)
+	}
+
+	oo.UpdateStep("Completing organization")
+	oo.saveIndex(index, config)
+
+	details := map[string]interface{}{
+		"suggestions":  oo.suggestions,
+		"moved":        oo.moved.Result(),
+		"organized":    oo.organized.Result(),
+		"unclassified": oo.unclassified.Result(),
+		"categories":   oo.categories,
+		"raw_pairs":    oo.pairs,
+		"sidecars":     oo.sidecars,
+		"albums":       oo.albums,
+		"strategy":     strategy,
+		"destination":  destination,
+		"dry_run":      config.DryRun,
+	}
+
+	summary := fmt.Sprintf("Organization: %d files moved into %d categories, %d already organized, %d unclassified",
+		oo.moved.Len(), len(oo.categories), oo.organized.Len(), oo.unclassified.Len())
+	if config.DryRun {
+		summary = fmt.Sprintf("Organization (dry run): %d files would be moved into %d categories, %d already organized, %d unclassified",
+			len(oo.suggestions), len(oo.categories), oo.organized.Len(), oo.unclassified.Len())
+	}
+	if oo.pairs > 0 {
+		summary += fmt.Sprintf(", %d RAW+JPEG pairs kept together", oo.pairs)
+	}
+	if oo.sidecars > 0 {
+		summary += fmt.Sprintf(", %d sidecars moved with their files", oo.sidecars)
+	}
+	if len(oo.albums) > 0 {
+		summary += fmt.Sprintf(", %d album folders left as they are", len(oo.albums))
+	}
+
+	return oo.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// organizeCandidate is a file to organize together with the root it was found under
+type organizeCandidate struct {
+	root string
+	info *domain.FileInfo
+}
+
+// organizeDecision is where a file belongs and why. An empty category leaves
+// the file where it is.
+type organizeDecision struct {
+	category   string
+	confidence float64
+	reason     string
+	text       string // Document text the decision was based on (content strategy)
+	ocrEngine  string // OCR engine that produced text, empty for text layers
+}
+
+// classifyFunc decides the category of a file
+type classifyFunc func(ctx context.Context, info *domain.FileInfo) (organizeDecision, error)
+
+// classifier returns the classification function for strategy. For the content
+// strategy it also returns the content index whose text is reused when current.
+func (oo *OrganizationOperation) classifier(strategy string, config domain.OperationConfig) (classifyFunc, *content.Index) {
+	switch strategy {
+	case "date":
+		return func(ctx context.Context, info *domain.FileInfo) (organizeDecision, error) {
+			return organizeDecision{
+				category:   info.ModTime.Format("2006/01"),
+				confidence: 1.0,
+				reason:     "modified " + info.ModTime.Format("January 2006"),
+			}, nil
+		}, nil
+	case "content":
+		var index *content.Index
+		if dir := settingString(config, "index_directory", ""); dir != "" {
+			var err error
+			if index, err = content.OpenIndex(dir); err != nil {
+				oo.engine.logger.Warn("Content index unavailable, extracting all documents", "error", err)
+				index = nil
+			}
+		}
+		extractor := content.NewExtractor(settingInt(config, "max_text_size", 0))
+		rules := content.NewClassifier(settingRules(config))
+		recognizer := oo.recognizer(config)
+
+		return func(ctx context.Context, info *domain.FileInfo) (organizeDecision, error) {
+			var text string
+			if index != nil && index.UpToDate(info.Path, info.Size, info.ModTime, config.MTimeTolerance) {
+				doc, _ := index.Get(info.Path)
+				text = doc.Text
+			} else if content.Supported(info.Path) {
+				var err error
+				if text, err = extractor.Extract(info.Path); err != nil {
+					return organizeDecision{}, err
+				}
+			}
+
+			// Scanned documents and photos of paper have no text layer
+			decision := organizeDecision{}
+			if strings.TrimSpace(text) == "" && recognizer != nil && ocr.Supported(info.Path) {
+				var err error
+				if text, err = oo.recognize(ctx, recognizer, info); err != nil {
+					return organizeDecision{}, err
+				}
+				decision.ocrEngine = recognizer.Name()
+			}
+			decision.text = text
+
+			classification := rules.Classify(text)
+			if classification == nil {
+				return decision, nil
+			}
+			decision.category = classification.Category
+			decision.confidence = classification.Confidence
+			decision.reason = "matched keywords: " + strings.Join(classification.Matched, ", ")
+			if decision.ocrEngine != "" {
+				decision.reason = "OCR " + decision.reason
+			}
+			return decision, nil
+		}, index
+	default:
+		detector := filesystem.NewFileTypeDetector()
+		return func(ctx context.Context, info *domain.FileInfo) (organizeDecision, error) {
+			return organizeDecision{
+				category:   detector.GetCategory(info.Path),
+				confidence: 1.0,
+				reason:     "file type " + detector.DetectMimeType(info.Path),
+			}, nil
+		}, nil
+	}
+}
+
+// recognizer returns the configured OCR engine, or nil when OCR is disabled or unavailable
+func (oo *OrganizationOperation) recognizer(config domain.OperationConfig) ocr.Recognizer {
+	if !settingBool(config, "ocr", false) {
+		return nil
+	}
+
+	timeout, _ := time.ParseDuration(settingString(config, "ocr_timeout", ""))
+	recognizer, err := ocr.New(ocr.Options{
+		Engine:        settingString(config, "ocr_engine", "auto"),
+		Languages:     settingStrings(config, "ocr_languages"),
+		TesseractPath: settingString(config, "tesseract_path", ""),
+		ServiceURL:    settingString(config, "ocr_service_url", ""),
+		MaxPages:      settingInt(config, "ocr_max_pages", 10),
+		Timeout:       timeout,
+	})
+	if err != nil {
+		oo.engine.logger.Warn("OCR disabled", "error", err)
+		return nil
+	}
+	return recognizer
+}
+
+// recognize returns the OCR text of a file, reusing the text cached in the
+// repository while the file is unchanged
+func (oo *OrganizationOperation) recognize(ctx context.Context, recognizer ocr.Recognizer, info *domain.FileInfo) (string, error) {
+	repository := oo.engine.repository
+	if repository != nil {
+		if cached, err := repository.GetFileInfo(info.Path); err == nil &&
+			cached.Size == info.Size && oo.config.MTimeTolerance.Equal(cached.ModTime, info.ModTime) {
+			if text, ok := cached.Metadata[ocrTextKey]; ok {
+				return text, nil
+			}
+		}
+	}
+
+	text, err := recognizer.Recognize(ctx, info.Path)
+	if err != nil {
+		return "", err
+	}
+	oo.cacheRecognized(info, info.Path, text, recognizer.Name())
+	return text, nil
+}
+
+// cacheRecognized stores the OCR text of info in the repository under path
+func (oo *OrganizationOperation) cacheRecognized(info *domain.FileInfo, path, text, engine string) {
+	repository := oo.engine.repository
+	if repository == nil {
+		return
+	}
+
+	record := *info
+	record.Path = path
+	record.Name = filepath.Base(path)
+	record.Metadata = map[string]string{ocrTextKey: text, ocrEngineKey: engine}
+	if err := repository.SaveFileInfo(&record); err != nil {
+		oo.engine.logger.Warn("Failed to cache OCR text", "path", path, "error", err)
+	}
+}
+
+// renameFor builds "<date> <vendor><ext>" from the fields recognized in text,
+// returning the original name when neither is found
+func renameFor(name, text string) string {
+	fields := content.ExtractFields(text)
+
+	parts := make([]string, 0, 2)
+	if !fields.Date.IsZero() {
+		parts = append(parts, fields.Date.Format("2006-01-02"))
+	}
+	if vendor := sanitizeFileName(fields.Vendor); vendor != "" {
+		parts = append(parts, vendor)
+	}
+	if len(parts) == 0 {
+		return name
+	}
+	return strings.Join(parts, " ") + strings.ToLower(filepath.Ext(name))
+}
+
+// sanitizeFileName removes characters that are invalid in file names on common platforms
+func sanitizeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>:"/\|?*`, r) || r < 0x20 {
+			return -1
+		}
+		return r
+	}, name)
+	return strings.Trim(name, " .")
+}
+
+// scan collects the files to organize below all roots. The content strategy
+// only considers documents it can extract text from, plus images with OCR.
+func (oo *OrganizationOperation) scan(ctx context.Context, strategy string, config domain.OperationConfig) ([]organizeCandidate, int64, error) {
+	files := make([]organizeCandidate, 0)
+	var totalSize int64
+	useOCR := settingBool(config, "ocr", false)
+	keepAlbums := settingBool(config, "keep_albums", false)
+	albumPatterns := settingStrings(config, "album_patterns")
+	albumMarker := settingString(config, "album_marker", DefaultAlbumMarker)
+
+	for _, root := range config.TargetPaths {
+		err := oo.Walk(ctx, root, config, func(path string, info *domain.FileInfo, err error) error {
+			if err != nil {
+				oo.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+				return nil // Continue walking
+			}
+			if info == nil {
+				return nil
+			}
+			if oo.isExcluded(path, config) {
+				if info.IsDir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir {
+				if keepAlbums && oo.isAlbum(path, path != root, albumPatterns, albumMarker) {
+					oo.albums = append(oo.albums, path)
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if action := config.ExtensionAction(path); action == domain.ExtensionSkip || action == domain.ExtensionKeep {
+				return nil // Left where it is
+			}
+			if strategy == "content" && !content.Supported(path) && !(useOCR && ocr.IsImage(path)) {
+				return nil
+			}
+			files = append(files, organizeCandidate{root: root, info: info})
+			totalSize += info.Size
+			return nil
+		})
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to walk path %s: %w", root, err)
+		}
+	}
+	return files, totalSize, nil
+}
+
+// isAlbum reports whether dir is a curated album: it holds the marker file
+// or, unless it is the organized root itself, its name matches one of the
+// patterns
+func (oo *OrganizationOperation) isAlbum(dir string, byName bool, patterns []string, marker string) bool {
+	if marker != "" && oo.engine.fileSystem.Exists(filepath.Join(dir, marker)) {
+		return true
+	}
+	if !byName {
+		return false
+	}
+	name := strings.ToLower(filepath.Base(dir))
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(strings.ToLower(pattern), name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// saveIndex persists path changes made to the content index
+func (oo *OrganizationOperation) saveIndex(index *content.Index, config domain.OperationConfig) {
+	if index == nil || config.DryRun {
+		return
+	}
+	if err := index.Save(); err != nil {
+		oo.engine.logger.Warn("Failed to save content index", "error", err)
+	}
+}
+
+// Validate validates the organization operation configuration
+func (oo *OrganizationOperation) Validate(config domain.OperationConfig) error {
+	return oo.ValidateConfig()
+}
+
+// EstimateProgress provides an estimate of the operation's scope
+func (oo *OrganizationOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	return oo.estimateScope(config, 3)
+}
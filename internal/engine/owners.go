@@ -0,0 +1,252 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// OwnerUsage is what one user or group owns below the scanned paths
+type OwnerUsage struct {
+	ID    int    `json:"id"` // -1 when ownership is not available on the platform
+	Name  string `json:"name,omitempty"`
+	Files int64  `json:"files"`
+	Dirs  int64  `json:"dirs"`
+	Bytes int64  `json:"bytes"`
+}
+
+// ownerTally counts usage per uid and gid
+type ownerTally struct {
+	users  map[int]*OwnerUsage
+	groups map[int]*OwnerUsage
+}
+
+func newOwnerTally() *ownerTally {
+	return &ownerTally{users: make(map[int]*OwnerUsage), groups: make(map[int]*OwnerUsage)}
+}
+
+// add counts an item for its owner and group
+func (t *ownerTally) add(info *domain.FileInfo) {
+	for _, entry := range []struct {
+		usage map[int]*OwnerUsage
+		id    int
+	}{{t.users, info.UID}, {t.groups, info.GID}} {
+		usage := entry.usage[entry.id]
+		if usage == nil {
+			usage = &OwnerUsage{ID: entry.id}
+			entry.usage[entry.id] = usage
+		}
+		if info.IsDir {
+			usage.Dirs++
+		} else {
+			usage.Files++
+			usage.Bytes += info.Size
+		}
+	}
+}
+
+// merge adds the counts of other
+func (t *ownerTally) merge(other *ownerTally) {
+	for _, pair := range [][2]map[int]*OwnerUsage{{t.users, other.users}, {t.groups, other.groups}} {
+		for id, usage := range pair[1] {
+			if total := pair[0][id]; total != nil {
+				total.Files += usage.Files
+				total.Dirs += usage.Dirs
+				total.Bytes += usage.Bytes
+			} else {
+				pair[0][id] = usage
+			}
+		}
+	}
+}
+
+// OwnersFactory creates ownership report operations
+type OwnersFactory struct {
+	engine *Engine
+}
+
+// Create creates a new owners operation
+func (of *OwnersFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewOwnersOperation(id, config, of.engine), nil
+}
+
+// Validate validates the owners configuration
+func (of *OwnersFactory) Validate(config domain.OperationConfig) error {
+	if len(config.TargetPaths) == 0 {
+		return fmt.Errorf("at least one path is required")
+	}
+	return nil
+}
+
+// OwnersOperation reports file counts and bytes per user and group. With the
+// "not_uid" or "not_gid" settings only items owned by someone else are
+// counted and listed, e.g. to see what a chown would change.
+type OwnersOperation struct {
+	*BaseOperation
+	mismatched *pathList
+}
+
+// NewOwnersOperation creates a new owners operation
+func NewOwnersOperation(id string, config domain.OperationConfig, engine *Engine) *OwnersOperation {
+	base := NewBaseOperation(id, domain.OperationOwners, config, engine)
+	return &OwnersOperation{
+		BaseOperation: base,
+		mismatched:    base.newPathList("not_owned"),
+	}
+}
+
+// Execute walks the paths, several at once, and sums up usage per owner
+func (oo *OwnersOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	tracker := oo.engine.progressTracker.StartOperation(oo.id, domain.OperationOwners, 2)
+	oo.SetTracker(tracker)
+
+	notUID := settingNumber(config, "not_uid", -1)
+	notGID := settingNumber(config, "not_gid", -1)
+	filtered := notUID >= 0 || notGID >= 0
+
+	oo.UpdateStep("Scanning ownership")
+
+	workers := config.Parallelism
+	if workers < 1 {
+		workers = 1
+	}
+	total := newOwnerTally()
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		walkErr  error
+		slots    = make(chan struct{}, workers)
+		excluded = func(path string) bool {
+			for _, pattern := range config.ExcludePatterns {
+				if matched, _ := filepath.Match(pattern, filepath.Base(path)); matched {
+					return true
+				}
+			}
+			return false
+		}
+	)
+	for _, root := range config.TargetPaths {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(root string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			tally := newOwnerTally()
+			err := oo.Walk(ctx, root, config, func(path string, info *domain.FileInfo, err error) error {
+				if err != nil {
+					oo.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+					return nil // Continue walking
+				}
+				if info == nil {
+					return nil
+				}
+				if path != root && excluded(path) {
+					if info.IsDir {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if filtered && (notUID < 0 || info.UID == notUID) && (notGID < 0 || info.GID == notGID) {
+					return nil
+				}
+				tally.add(info)
+				if filtered {
+					oo.mismatched.Add(path)
+				}
+				oo.IncrementProgress(1, info.Size)
+				return nil
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			total.merge(tally)
+			if err != nil && walkErr == nil {
+				walkErr = fmt.Errorf("failed to walk path %s: %w", root, err)
+			}
+		}(root)
+	}
+	wg.Wait()
+	if walkErr != nil {
+		return nil, walkErr
+	}
+
+	oo.UpdateStep("Completed")
+
+	users := sortedUsage(total.users, lookupUserName)
+	groups := sortedUsage(total.groups, lookupGroupName)
+	var files, bytes int64
+	for _, usage := range users {
+		files += usage.Files + usage.Dirs
+		bytes += usage.Bytes
+	}
+
+	summary := fmt.Sprintf("Owners: %d items (%d bytes) owned by %d users and %d groups", files, bytes, len(users), len(groups))
+	if filtered {
+		summary = fmt.Sprintf("Owners: %d items (%d bytes) owned by someone else, %d users and %d groups", files, bytes, len(users), len(groups))
+	}
+
+	details := map[string]interface{}{
+		"users":     users,
+		"groups":    groups,
+		"not_owned": oo.mismatched.Result(),
+		"items":     files,
+		"bytes":     bytes,
+	}
+	return oo.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// sortedUsage resolves the names of the owners and orders them by bytes,
+// largest first
+func sortedUsage(usage map[int]*OwnerUsage, name func(int) string) []OwnerUsage {
+	sorted := make([]OwnerUsage, 0, len(usage))
+	for _, entry := range usage {
+		entry.Name = name(entry.ID)
+		sorted = append(sorted, *entry)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Bytes != sorted[j].Bytes {
+			return sorted[i].Bytes > sorted[j].Bytes
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+	return sorted
+}
+
+// lookupUserName returns the login name of uid, or "" when it has none
+func lookupUserName(uid int) string {
+	if uid < 0 {
+		return ""
+	}
+	if u, err := user.LookupId(strconv.Itoa(uid)); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+// lookupGroupName returns the name of gid, or "" when it has none
+func lookupGroupName(gid int) string {
+	if gid < 0 {
+		return ""
+	}
+	if g, err := user.LookupGroupId(strconv.Itoa(gid)); err == nil {
+		return g.Name
+	}
+	return ""
+}
+
+// Validate validates the owners operation configuration
+func (oo *OwnersOperation) Validate(config domain.OperationConfig) error {
+	return oo.ValidateConfig()
+}
+
+// EstimateProgress provides an estimate of the operation's scope
+func (oo *OwnersOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	return oo.estimateScope(config, 2)
+}
@@ -0,0 +1,248 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// Cleanup rule sets selected with the "rules" setting
+const (
+	RuleEmptyDirs        = "empty-dirs"
+	RulePartialDownloads = "partial-downloads"
+)
+
+// defaultPartialAgeDays is how long a partial download must be untouched
+// before it counts as abandoned
+const defaultPartialAgeDays = 7
+
+// partialDownloadSuffixes are the extensions browsers, download managers and
+// torrent clients give files while they are being downloaded
+var partialDownloadSuffixes = []string{
+	".part",       // Firefox, wget, youtube-dl
+	".partial",    // Internet Explorer, Edge legacy
+	".crdownload", // Chrome, Chromium based browsers
+	".opdownload", // Opera
+	".!ut",        // uTorrent
+	".!qb",        // qBittorrent
+	".!bt",        // BitTorrent
+	".bc!",        // BitComet
+	".aria2",      // aria2 control file
+}
+
+// minSparseSize keeps tiny files out of the zero-progress rule; filesystems
+// storing small files inline in the inode report no allocated blocks for them
+const minSparseSize = 64 * 1024
+
+// cleanupRules returns the configured rule sets, defaulting to empty directories
+func cleanupRules(config domain.OperationConfig) []string {
+	rules := settingStrings(config, "rules")
+	if len(rules) == 0 {
+		return []string{RuleEmptyDirs}
+	}
+	return rules
+}
+
+// hasRule reports whether a rule set is enabled
+func hasRule(config domain.OperationConfig, rule string) bool {
+	for _, enabled := range cleanupRules(config) {
+		if enabled == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// partialDownload is a file detected as an unfinished download
+type partialDownload struct {
+	info   *domain.FileInfo
+	root   string
+	reason string
+}
+
+// isPartialDownloadName reports whether a file name carries a partial download extension
+func isPartialDownloadName(name string) bool {
+	lower := strings.ToLower(name)
+	for _, suffix := range partialDownloadSuffixes {
+		if strings.HasSuffix(lower, suffix) && len(lower) > len(suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// findPartialDownloads collects partial downloads below all roots. Files
+// modified within the minimum age may still be downloading and are only
+// reported as active.
+func (co *CleanupOperation) findPartialDownloads(ctx context.Context, config domain.OperationConfig) ([]partialDownload, error) {
+	minAge := time.Duration(settingInt(config, "older_than_days", defaultPartialAgeDays)) * 24 * time.Hour
+	cutoff := time.Now().Add(-minAge)
+	found := make([]partialDownload, 0)
+
+	for _, rootPath := range config.TargetPaths {
+		candidates := make(map[string]partialDownload)
+
+		err := co.Walk(ctx, rootPath, config, func(path string, info *domain.FileInfo, err error) error {
+			if err != nil {
+				co.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+				return nil // Continue processing
+			}
+			if err := co.CheckContext(ctx); err != nil {
+				return err
+			}
+			if info == nil {
+				return nil
+			}
+			if info.IsDir {
+				if path != rootPath && !co.shouldProcessDirectory(path, config) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !os.FileMode(info.Mode).IsRegular() {
+				return nil
+			}
+			co.IncrementProgress(1, 0)
+			if action := config.ExtensionAction(path); action == domain.ExtensionSkip || action == domain.ExtensionKeep {
+				return nil
+			}
+
+			if isPartialDownloadName(info.Name) {
+				candidates[path] = partialDownload{info: info, root: rootPath, reason: "partial download"}
+			} else if co.isZeroProgress(info) {
+				candidates[path] = partialDownload{info: info, root: rootPath, reason: "preallocated, no data written"}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// Firefox creates an empty placeholder next to each file.part and aria2
+		// writes the data next to its file.aria2 control file
+		for path := range candidates {
+			lower := strings.ToLower(path)
+			isPart, isAria2 := strings.HasSuffix(lower, ".part"), strings.HasSuffix(lower, ".aria2")
+			if !isPart && !isAria2 {
+				continue
+			}
+			companion := strings.TrimSuffix(path, filepath.Ext(path))
+			if _, ok := candidates[companion]; ok {
+				continue
+			}
+			info, err := co.engine.fileSystem.Stat(companion)
+			if err != nil || !os.FileMode(info.Mode).IsRegular() {
+				continue
+			}
+			if isAria2 {
+				candidates[companion] = partialDownload{info: info, root: rootPath, reason: "data of an aria2 download"}
+			} else if info.Size == 0 {
+				candidates[companion] = partialDownload{info: info, root: rootPath, reason: "placeholder of a partial download"}
+			}
+		}
+
+		paths := make([]string, 0, len(candidates))
+		for path := range candidates {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			candidate := candidates[path]
+			if candidate.info.ModTime.After(cutoff) {
+				co.activePartials.Add(candidate.info.Path)
+				continue
+			}
+			found = append(found, candidate)
+		}
+	}
+
+	return found, nil
+}
+
+// isZeroProgress reports whether a file was preallocated by a download client
+// without any data arriving: it has a size but no allocated blocks and starts
+// with zeros
+func (co *CleanupOperation) isZeroProgress(info *domain.FileInfo) bool {
+	if info.Size < minSparseSize {
+		return false
+	}
+	allocated, err := filesystem.AllocatedSize(info.Path)
+	if err != nil || allocated > 0 {
+		return false
+	}
+
+	file, err := os.Open(info.Path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	head := make([]byte, minSparseSize)
+	if _, err := io.ReadFull(file, head); err != nil {
+		return false
+	}
+	return bytes.Count(head, []byte{0}) == len(head)
+}
+
+// processPartialDownloads removes the detected partial downloads, or moves
+// them to the backup directory when one is configured
+func (co *CleanupOperation) processPartialDownloads(ctx context.Context, config domain.OperationConfig, partials []partialDownload) error {
+	for _, partial := range partials {
+		if err := co.CheckContext(ctx); err != nil {
+			return err
+		}
+		path := partial.info.Path
+
+		if config.DryRun {
+			co.removedPartials.Add(path)
+			co.partialBytes += partial.info.Size
+			co.engine.logger.Info("Would remove partial download", "path", path, "reason", partial.reason)
+			continue
+		}
+
+		// The file may have been resumed since the scan
+		current, err := co.engine.fileSystem.Stat(path)
+		if err != nil {
+			co.AddFileError(path, fmt.Errorf("failed to check %s: %w", path, err))
+			continue
+		}
+		if !current.ModTime.Equal(partial.info.ModTime) || current.Size != partial.info.Size {
+			co.activePartials.Add(path)
+			continue
+		}
+
+		if config.BackupBeforeDelete && config.BackupDirectory != "" {
+			err = co.backupPath(partial.root, path, config.BackupDirectory)
+		} else if err = co.engine.fileSystem.Remove(path); err == nil {
+			co.RecordDeletion(path, partial.info.Size)
+		}
+		if err != nil {
+			co.AddFileError(path, fmt.Errorf("failed to remove partial download %s: %w", path, err))
+			continue
+		}
+		co.removedPartials.Add(path)
+		co.partialBytes += partial.info.Size
+		co.engine.logger.Info("Removed partial download", "path", path, "reason", partial.reason)
+	}
+	return nil
+}
+
+// backupPath moves a file or directory below the backup directory, keeping
+// its path relative to the root it was found in
+func (co *CleanupOperation) backupPath(root, path, backupDir string) error {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return err
+	}
+	return co.move(path, filepath.Join(backupDir, filepath.Base(root), rel))
+}
@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"bufio"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// inlinePathLimit is how many paths of a list are kept in memory
+const inlinePathLimit = 1000
+
+// pathListStore is implemented by repositories that store long path lists
+// next to the operation results
+type pathListStore interface {
+	CreatePathList(id, key string) (*os.File, error)
+}
+
+// pathList collects the paths an operation reports under one result detail.
+// The first inlinePathLimit paths are kept in memory; after that every path
+// is streamed to a file of the result repository and only counted, so
+// operations on millions of items do not hold all paths in memory. It is safe
+// for concurrent use.
+type pathList struct {
+	op   *BaseOperation
+	key  string
+	mu   sync.Mutex
+	list domain.PathList
+	file *os.File
+	out  *bufio.Writer
+}
+
+// newPathList creates the path list reported as detail key
+func (bo *BaseOperation) newPathList(key string) *pathList {
+	return &pathList{op: bo, key: key, list: domain.PathList{Paths: make([]string, 0)}}
+}
+
+// Add records paths
+func (p *pathList) Add(paths ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, path := range paths {
+		p.list.Count++
+		if p.list.Count <= inlinePathLimit {
+			p.list.Paths = append(p.list.Paths, path)
+			continue
+		}
+		if p.list.Count == inlinePathLimit+1 {
+			p.spill()
+		}
+		if p.out != nil {
+			p.out.WriteString(path + "\n")
+		}
+	}
+}
+
+// spill opens the file of the list and writes the inline paths to it. Without
+// a repository the paths beyond the inline ones are only counted.
+func (p *pathList) spill() {
+	p.op.mu.RLock()
+	store, ok := p.op.repository.(pathListStore)
+	p.op.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	file, err := store.CreatePathList(p.op.id, p.key)
+	if err != nil {
+		p.op.engine.logger.Warn("Failed to store path list, only counting paths", "key", p.key, "error", err)
+		return
+	}
+	p.file, p.out = file, bufio.NewWriter(file)
+	p.list.File = file.Name()
+	for _, path := range p.list.Paths {
+		p.out.WriteString(path + "\n")
+	}
+}
+
+// Len returns the number of recorded paths
+func (p *pathList) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.list.Count
+}
+
+// Result completes the file of the list and returns the list for the result
+// details. Deterministic operations list the paths sorted, whatever order
+// their workers added them in.
+func (p *pathList) Result() domain.PathList {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.file != nil {
+		err := p.out.Flush()
+		if closeErr := p.file.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil && p.op.config.Deterministic {
+			err = p.sortFile()
+		}
+		if err != nil {
+			p.op.engine.logger.Warn("Failed to store path list", "key", p.key, "error", err)
+			p.list.File = ""
+		}
+		p.file, p.out = nil, nil
+	} else if p.op.config.Deterministic {
+		sort.Strings(p.list.Paths)
+	}
+	return p.list
+}
+
+// sortFile sorts the stored paths and keeps the first of them inline
+func (p *pathList) sortFile() error {
+	data, err := os.ReadFile(p.list.File)
+	if err != nil {
+		return err
+	}
+	paths := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	sort.Strings(paths)
+	if err := os.WriteFile(p.list.File, []byte(strings.Join(paths, "\n")+"\n"), 0o644); err != nil {
+		return err
+	}
+	p.list.Paths = paths[:min(len(paths), inlinePathLimit)]
+	return nil
+}
@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// Limits of the slow paths of a result
+const (
+	slowPathMean   = 10 * time.Millisecond // Average wait per entry from which a directory is slow
+	slowPathsShown = 20
+	pathStatsLimit = 10000 // Directories tracked before the fast ones are dropped
+)
+
+// pathStats samples how long the walks of an operation wait for each
+// directory. The walker lists a directory and gets the information of its
+// entries between two calls of the walk function, so the time from one call
+// returning to the next call is what the next entry cost, and is charged to
+// its directory; the work of the operation itself is not counted. A dying
+// disk or a misbehaving network share shows up as the directories on it
+// being slow or failing.
+type pathStats struct {
+	mu   sync.Mutex
+	dirs map[string]*domain.PathLatency
+}
+
+func newPathStats() *pathStats {
+	return &pathStats{dirs: make(map[string]*domain.PathLatency)}
+}
+
+// sample wraps the walk function of one walk to time the walker
+func (s *pathStats) sample(root string, fn domain.WalkFunc) domain.WalkFunc {
+	last := time.Now()
+	return func(path string, info *domain.FileInfo, err error) error {
+		waited := time.Since(last)
+
+		// The root and directories that cannot be listed are charged to
+		// themselves, everything else to the directory holding it
+		dir := filepath.Dir(path)
+		if path == root || (err != nil && info != nil && info.IsDir) {
+			dir = path
+		}
+		s.record(dir, waited, err != nil)
+
+		result := fn(path, info, err)
+		last = time.Now()
+		return result
+	}
+}
+
+// record adds one wait for an entry of dir
+func (s *pathStats) record(dir string, waited time.Duration, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.dirs[dir]
+	if stats == nil {
+		if len(s.dirs) >= pathStatsLimit {
+			s.prune()
+		}
+		stats = &domain.PathLatency{Path: dir}
+		s.dirs[dir] = stats
+	}
+	stats.Entries++
+	stats.Total += waited
+	stats.Max = max(stats.Max, waited)
+	if failed {
+		stats.Errors++
+	}
+}
+
+// prune drops the directories that are not slow so far, and when too many
+// are, all but the slowest half of the limit
+func (s *pathStats) prune() {
+	for dir, stats := range s.dirs {
+		if !slowPath(stats) {
+			delete(s.dirs, dir)
+		}
+	}
+	if len(s.dirs) < pathStatsLimit/2 {
+		return
+	}
+	slowest := s.sorted()
+	for _, stats := range slowest[pathStatsLimit/2:] {
+		delete(s.dirs, stats.Path)
+	}
+}
+
+// sorted returns the directories, those waited for longest first
+func (s *pathStats) sorted() []*domain.PathLatency {
+	dirs := make([]*domain.PathLatency, 0, len(s.dirs))
+	for _, stats := range s.dirs {
+		dirs = append(dirs, stats)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if dirs[i].Total != dirs[j].Total {
+			return dirs[i].Total > dirs[j].Total
+		}
+		return dirs[i].Path < dirs[j].Path
+	})
+	return dirs
+}
+
+// slowPaths returns the slowest directories that were slow or failed
+func (s *pathStats) slowPaths() []domain.PathLatency {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var slow []domain.PathLatency
+	for _, stats := range s.sorted() {
+		if len(slow) == slowPathsShown {
+			break
+		}
+		if slowPath(stats) {
+			slow = append(slow, *stats)
+		}
+	}
+	return slow
+}
+
+// slowPath reports whether a directory was slow to read or failed
+func slowPath(stats *domain.PathLatency) bool {
+	return stats.Errors > 0 || stats.Mean() >= slowPathMean
+}
@@ -0,0 +1,370 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/a4abhishek/fileops/internal/pdf"
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// DefaultPDFPattern selects the files merged or split
+const DefaultPDFPattern = "*.pdf"
+
+// PDFMergeFactory creates PDF merging operations
+type PDFMergeFactory struct {
+	engine *Engine
+}
+
+// Create creates a new PDF merging operation
+func (pf *PDFMergeFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewPDFMergeOperation(id, config, pf.engine), nil
+}
+
+// Validate validates the PDF merging configuration
+func (pf *PDFMergeFactory) Validate(config domain.OperationConfig) error {
+	if len(config.TargetPaths) == 0 {
+		return fmt.Errorf("at least one path is required")
+	}
+	if output := settingString(config, "output", ""); output == "" || !filepath.IsAbs(output) {
+		return fmt.Errorf("an absolute output path is required")
+	}
+	return validatePDFPattern(config)
+}
+
+// PDFSplitFactory creates PDF splitting operations
+type PDFSplitFactory struct {
+	engine *Engine
+}
+
+// Create creates a new PDF splitting operation
+func (pf *PDFSplitFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewPDFSplitOperation(id, config, pf.engine), nil
+}
+
+// Validate validates the PDF splitting configuration
+func (pf *PDFSplitFactory) Validate(config domain.OperationConfig) error {
+	if len(config.TargetPaths) == 0 {
+		return fmt.Errorf("at least one path is required")
+	}
+	ranges := settingString(config, "ranges", "")
+	every := settingInt(config, "pages_per_file", 0)
+	if (ranges == "") == (every <= 0) {
+		return fmt.Errorf("either page ranges or a number of pages per file is required")
+	}
+	if ranges != "" {
+		// Syntax only; "end" and bounds depend on each document
+		if _, err := pdf.ParseRanges(ranges, 1<<30); err != nil {
+			return err
+		}
+	}
+	return validatePDFPattern(config)
+}
+
+// validatePDFPattern checks the file name pattern
+func validatePDFPattern(config domain.OperationConfig) error {
+	if _, err := filepath.Match(settingString(config, "pattern", DefaultPDFPattern), ""); err != nil {
+		return fmt.Errorf("invalid pattern: %w", err)
+	}
+	return nil
+}
+
+// PDFMergeOperation merges the PDFs matching a pattern into one file, in
+// order of their paths. The sources are left as they are.
+type PDFMergeOperation struct {
+	*BaseOperation
+	merged *pathList
+	pages  int
+}
+
+// NewPDFMergeOperation creates a new PDF merging operation
+func NewPDFMergeOperation(id string, config domain.OperationConfig, engine *Engine) *PDFMergeOperation {
+	base := NewBaseOperation(id, domain.OperationPDFMerge, config, engine)
+	return &PDFMergeOperation{
+		BaseOperation: base,
+		merged:        base.newPathList("merged"),
+	}
+}
+
+// Execute merges the matching PDFs below the configured paths
+func (pm *PDFMergeOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	tracker := pm.engine.progressTracker.StartOperation(pm.id, domain.OperationPDFMerge, 3)
+	pm.SetTracker(tracker)
+
+	output := settingString(config, "output", "")
+	if pm.engine.fileSystem.Exists(output) {
+		return nil, fmt.Errorf("%s: %w", output, os.ErrExist)
+	}
+
+	pm.UpdateStep("Scanning PDFs")
+
+	files, totalSize, err := collectPDFs(ctx, pm.BaseOperation, config, output)
+	if err != nil {
+		return nil, err
+	}
+
+	pm.UpdateStep("Merging PDFs")
+	pm.SetProgress(0, int64(len(files)), 0, totalSize)
+
+	pages := make([]pdf.Page, 0)
+	for _, file := range files {
+		if err := pm.CheckContext(ctx); err != nil {
+			return nil, err
+		}
+		doc, err := pdf.Open(file.Path)
+		if err != nil {
+			pm.AddFileError(file.Path, fmt.Errorf("failed to read %s: %w", file.Path, err))
+			pm.IncrementProgress(1, file.Size)
+			continue
+		}
+		pages = append(pages, doc.Pages()...)
+		pm.merged.Add(file.Path)
+		pm.IncrementProgress(1, file.Size)
+	}
+	pm.pages = len(pages)
+
+	var outputSize int64
+	if !config.DryRun && len(pages) > 0 {
+		size, err := writePDF(output, pages)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", output, err)
+		}
+		outputSize = size
+		pm.engine.logger.Info("Merged PDFs", "output", output, "files", pm.merged.Len(), "pages", pm.pages)
+	}
+
+	pm.UpdateStep("Completing merge")
+
+	details := map[string]interface{}{
+		"merged":      pm.merged.Result(),
+		"output":      output,
+		"pages":       pm.pages,
+		"output_size": outputSize,
+		"dry_run":     config.DryRun,
+	}
+
+	summary := fmt.Sprintf("PDF merge: %d files with %d pages merged into %s", pm.merged.Len(), pm.pages, filepath.Base(output))
+	if config.DryRun {
+		summary = fmt.Sprintf("PDF merge (dry run): %d files with %d pages would be merged into %s", pm.merged.Len(), pm.pages, filepath.Base(output))
+	} else if pm.pages == 0 {
+		summary = "PDF merge: no PDFs to merge"
+	}
+
+	return pm.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// Validate validates the PDF merging operation configuration
+func (pm *PDFMergeOperation) Validate(config domain.OperationConfig) error {
+	return pm.ValidateConfig()
+}
+
+// EstimateProgress provides an estimate of the operation's scope
+func (pm *PDFMergeOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	return pm.estimateScope(config, 3)
+}
+
+// PDFSplitOperation splits PDFs into one file per page range, or per chunk
+// of pages, next to their source or in a destination directory. The sources
+// are left as they are.
+type PDFSplitOperation struct {
+	*BaseOperation
+	split   *pathList
+	created *pathList
+	skipped *pathList // PDFs with fewer pages than the threshold
+}
+
+// NewPDFSplitOperation creates a new PDF splitting operation
+func NewPDFSplitOperation(id string, config domain.OperationConfig, engine *Engine) *PDFSplitOperation {
+	base := NewBaseOperation(id, domain.OperationPDFSplit, config, engine)
+	return &PDFSplitOperation{
+		BaseOperation: base,
+		split:         base.newPathList("split"),
+		created:       base.newPathList("created"),
+		skipped:       base.newPathList("skipped"),
+	}
+}
+
+// Execute splits the matching PDFs below the configured paths
+func (ps *PDFSplitOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	tracker := ps.engine.progressTracker.StartOperation(ps.id, domain.OperationPDFSplit, 3)
+	ps.SetTracker(tracker)
+
+	spec := settingString(config, "ranges", "")
+	every := settingInt(config, "pages_per_file", 0)
+	minPages := settingInt(config, "min_pages", 0)
+	destination := settingString(config, "destination", "")
+
+	ps.UpdateStep("Scanning PDFs")
+
+	files, totalSize, err := collectPDFs(ctx, ps.BaseOperation, config, "")
+	if err != nil {
+		return nil, err
+	}
+
+	ps.UpdateStep("Splitting PDFs")
+	ps.SetProgress(0, int64(len(files)), 0, totalSize)
+
+	for _, file := range files {
+		if err := ps.CheckContext(ctx); err != nil {
+			return nil, err
+		}
+		if err := ps.splitFile(file.Path, spec, every, minPages, destination, config.DryRun); err != nil {
+			ps.AddFileError(file.Path, fmt.Errorf("failed to split %s: %w", file.Path, err))
+		}
+		ps.IncrementProgress(1, file.Size)
+	}
+
+	ps.UpdateStep("Completing split")
+
+	details := map[string]interface{}{
+		"split":   ps.split.Result(),
+		"created": ps.created.Result(),
+		"skipped": ps.skipped.Result(),
+		"dry_run": config.DryRun,
+	}
+
+	summary := fmt.Sprintf("PDF split: %d files split into %d, %d too small to split",
+		ps.split.Len(), ps.created.Len(), ps.skipped.Len())
+	if config.DryRun {
+		summary = fmt.Sprintf("PDF split (dry run): %d files would be split into %d, %d too small to split",
+			ps.split.Len(), ps.created.Len(), ps.skipped.Len())
+	}
+
+	return ps.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// splitFile writes one file per range of a PDF. Nothing is written when one
+// of the files already exists.
+func (ps *PDFSplitOperation) splitFile(path, spec string, every, minPages int, destination string, dryRun bool) error {
+	doc, err := pdf.Open(path)
+	if err != nil {
+		return err
+	}
+	count := len(doc.Pages())
+	if count < minPages || (spec == "" && count <= every) {
+		ps.skipped.Add(path)
+		return nil
+	}
+	var ranges []pdf.Range
+	if spec != "" {
+		if ranges, err = pdf.ParseRanges(spec, count); err != nil {
+			return err
+		}
+	} else {
+		ranges = pdf.Chunks(count, every)
+	}
+
+	dir := filepath.Dir(path)
+	if destination != "" {
+		dir = destination
+	}
+	stem := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	targets := make([]string, len(ranges))
+	for i, r := range ranges {
+		targets[i] = filepath.Join(dir, fmt.Sprintf("%s_pages-%s.pdf", stem, r))
+		if ps.engine.fileSystem.Exists(targets[i]) {
+			return fmt.Errorf("%s: %w", targets[i], os.ErrExist)
+		}
+	}
+
+	if !dryRun {
+		if err := ps.engine.fileSystem.CreateDir(dir); err != nil {
+			return err
+		}
+	}
+	for i, r := range ranges {
+		if !dryRun {
+			if _, err := writePDF(targets[i], doc.Select([]pdf.Range{r})); err != nil {
+				return err
+			}
+			ps.engine.logger.Debug("Wrote PDF pages", "source", path, "pages", r.String(), "target", targets[i])
+		}
+		ps.created.Add(targets[i])
+	}
+	ps.split.Add(path)
+	return nil
+}
+
+// Validate validates the PDF splitting operation configuration
+func (ps *PDFSplitOperation) Validate(config domain.OperationConfig) error {
+	return ps.ValidateConfig()
+}
+
+// EstimateProgress provides an estimate of the operation's scope
+func (ps *PDFSplitOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	return ps.estimateScope(config, 3)
+}
+
+// collectPDFs returns the files below the configured paths whose name
+// matches the pattern, ignoring case, sorted by path. skip is left out, so a
+// merge never reads its own output.
+func collectPDFs(ctx context.Context, bo *BaseOperation, config domain.OperationConfig, skip string) ([]*domain.FileInfo, int64, error) {
+	pattern := strings.ToLower(settingString(config, "pattern", DefaultPDFPattern))
+	files := make([]*domain.FileInfo, 0)
+	var totalSize int64
+	for _, root := range config.TargetPaths {
+		err := bo.Walk(ctx, root, config, func(path string, info *domain.FileInfo, err error) error {
+			if err != nil {
+				bo.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+				return nil // Continue walking
+			}
+			if info == nil {
+				return nil
+			}
+			for _, exclude := range config.ExcludePatterns {
+				if matched, _ := filepath.Match(exclude, filepath.Base(path)); matched {
+					if info.IsDir {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+			}
+			if info.IsDir || path == skip {
+				return nil
+			}
+			if matched, _ := filepath.Match(pattern, strings.ToLower(filepath.Base(path))); matched {
+				files = append(files, info)
+				totalSize += info.Size
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to walk path %s: %w", root, err)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, totalSize, nil
+}
+
+// writePDF writes pages to path through a temporary file, so an interrupted
+// run leaves no truncated PDF behind. It returns the size written.
+func writePDF(path string, pages []pdf.Page) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, err
+	}
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	file, err := os.Create(tmp)
+	if err != nil {
+		return 0, err
+	}
+	if err := pdf.Write(file, pages); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return 0, err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmp)
+		return 0, err
+	}
+	return info.Size(), os.Rename(tmp, path)
+}
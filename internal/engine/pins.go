@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// Pins are files deduplication must never delete or replace, whatever group
+// they fall in. A pin file lists one entry per line:
+//
+//	/srv/library/masters        a file or everything below a directory
+//	/srv/photos/*/cover.jpg     a glob pattern
+//	sha256:9f86d081884c7d65...  every file with this content
+//
+// Relative paths are relative to the pin file; blank lines and lines
+// starting with # are ignored.
+type Pins struct {
+	paths    []string
+	patterns []string
+	hashes   map[string]map[string]bool // Hashes by algorithm
+
+	mu     sync.Mutex
+	hashed map[string]string // Hashes computed for checks, by algorithm and path
+}
+
+// LoadPins reads a pin file
+func LoadPins(path string) (*Pins, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	pins := &Pins{hashes: make(map[string]map[string]bool), hashed: make(map[string]string)}
+	base := filepath.Dir(path)
+	scanner := bufio.NewScanner(file)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if algorithm, hash, ok := strings.Cut(line, ":"); ok && !strings.ContainsAny(line, `/\`) {
+			if _, err := filesystem.NewHasher(algorithm); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, number, err)
+			}
+			if pins.hashes[algorithm] == nil {
+				pins.hashes[algorithm] = make(map[string]bool)
+			}
+			pins.hashes[algorithm][strings.ToLower(hash)] = true
+			continue
+		}
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(base, line)
+		}
+		if strings.ContainsAny(line, "*?[") {
+			if _, err := filepath.Match(line, ""); err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid pattern %q", path, number, line)
+			}
+			pins.patterns = append(pins.patterns, filepath.Clean(line))
+		} else {
+			pins.paths = append(pins.paths, filepath.Clean(line))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return pins, nil
+}
+
+// Len returns the number of entries of the pin file
+func (p *Pins) Len() int {
+	n := len(p.paths) + len(p.patterns)
+	for _, hashes := range p.hashes {
+		n += len(hashes)
+	}
+	return n
+}
+
+// Pinned reports whether path is pinned. hash is its content hash with
+// algorithm when known; pins with other algorithms hash the file themselves.
+func (p *Pins) Pinned(fs domain.FileSystem, path, algorithm, hash string) bool {
+	if p == nil {
+		return false
+	}
+	if withinPrefixes(path, p.paths) {
+		return true
+	}
+	for _, pattern := range p.patterns {
+		if matched, _ := filepath.Match(pattern, path); matched {
+			return true
+		}
+	}
+	for pinAlgorithm, hashes := range p.hashes {
+		if pinAlgorithm == algorithm && hash != "" {
+			if hashes[strings.ToLower(hash)] {
+				return true
+			}
+			continue
+		}
+		if hashes[p.hashOf(fs, path, pinAlgorithm)] {
+			return true
+		}
+	}
+	return false
+}
+
+// hashOf hashes path with algorithm once per run
+func (p *Pins) hashOf(fs domain.FileSystem, path, algorithm string) string {
+	key := algorithm + ":" + path
+	p.mu.Lock()
+	hash, ok := p.hashed[key]
+	p.mu.Unlock()
+	if ok {
+		return hash
+	}
+	hash, err := fs.ComputeHash(path, algorithm)
+	if err != nil {
+		return "" // Unreadable files are not replaced either way
+	}
+	hash = strings.ToLower(hash)
+	p.mu.Lock()
+	p.hashed[key] = hash
+	p.mu.Unlock()
+	return hash
+}
@@ -0,0 +1,199 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPolicyFile is the policy of shared servers. When it exists it
+// applies, whatever policy file is configured.
+const DefaultPolicyFile = "/etc/fileops/policy.yaml"
+
+// ErrPolicyDenied is returned for operations the policy does not allow
+var ErrPolicyDenied = errors.New("denied by policy")
+
+// Role says which operations its holders may run, below which paths, and
+// whether they may delete, move or rewrite existing files
+type Role struct {
+	Operations  []string `yaml:"operations"` // Operation types, or "*" for all
+	Paths       []string `yaml:"paths"`      // Path prefixes; empty allows every path
+	Destructive bool     `yaml:"destructive"`
+}
+
+// Policy assigns roles to users and API tokens. Tokens are listed by their
+// SHA-256 ("sha256:<hex>") so the file holds no secrets. The user "*" stands
+// for every user without an entry; principals without a role may run nothing.
+type Policy struct {
+	Roles  map[string]Role   `yaml:"roles"`
+	Users  map[string]string `yaml:"users"`
+	Tokens map[string]string `yaml:"tokens"`
+}
+
+// Principal is who runs an operation
+type Principal struct {
+	User  string
+	Token string // API token, when the operation was requested with one
+}
+
+// LoadPolicy reads a policy file and checks that every role it assigns exists
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var policy Policy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %w", path, err)
+	}
+	for _, assignments := range []map[string]string{policy.Users, policy.Tokens} {
+		for who, role := range assignments {
+			if _, ok := policy.Roles[role]; !ok {
+				return nil, fmt.Errorf("invalid policy %s: %s has unknown role %q", path, who, role)
+			}
+		}
+	}
+	for token := range policy.Tokens {
+		if !strings.HasPrefix(token, "sha256:") {
+			return nil, fmt.Errorf("invalid policy %s: tokens are listed as sha256:<hex>", path)
+		}
+	}
+	return &policy, nil
+}
+
+// HashToken returns the form in which a policy lists token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// RoleOf returns the role of principal and its name. A known token decides
+// over the user.
+func (p *Policy) RoleOf(principal Principal) (string, Role, bool) {
+	name, ok := "", false
+	if principal.Token != "" {
+		name, ok = p.Tokens[HashToken(principal.Token)]
+	}
+	if !ok {
+		name, ok = p.Users[principal.User]
+	}
+	if !ok {
+		name, ok = p.Users["*"]
+	}
+	if !ok {
+		return "", Role{}, false
+	}
+	return name, p.Roles[name], true
+}
+
+// Check returns an error wrapping ErrPolicyDenied unless principal may run
+// the operation with config
+func (p *Policy) Check(principal Principal, operationType domain.OperationType, config domain.OperationConfig) error {
+	name, role, ok := p.RoleOf(principal)
+	if !ok {
+		return fmt.Errorf("%w: %s has no role", ErrPolicyDenied, principal.User)
+	}
+	if !slices.Contains(role.Operations, "*") && !slices.Contains(role.Operations, string(operationType)) {
+		return fmt.Errorf("%w: role %s may not run %s", ErrPolicyDenied, name, operationType)
+	}
+	if !role.Destructive && destructive(operationType, config) {
+		return fmt.Errorf("%w: role %s may not delete, move or rewrite files (use a dry run)", ErrPolicyDenied, name)
+	}
+	if len(role.Paths) > 0 {
+		for _, root := range lockRoots(config) {
+			if !withinPrefixes(root, role.Paths) {
+				return fmt.Errorf("%w: role %s may not act on %s", ErrPolicyDenied, name, root)
+			}
+		}
+	}
+	return nil
+}
+
+// destructive reports whether an operation may delete, move, replace or
+// rewrite existing files. Dry runs never are.
+func destructive(operationType domain.OperationType, config domain.OperationConfig) bool {
+	if config.DryRun {
+		return false
+	}
+	switch operationType {
+	case domain.OperationDeduplication:
+		switch settingString(config, "mode", DedupModeReport) {
+		case DedupModeReport:
+			return settingBool(config, "remove_variants", false)
+		case DedupModeNames:
+			return settingBool(config, "remove_copies", false)
+		}
+		return true
+	case domain.OperationConsolidation:
+		// Overwriting replaces files of the destination, and links are
+		// renamed over whatever is at the target by then
+		return settingBool(config, "move", false) ||
+			settingString(config, "conflict_resolution", "skip") == "overwrite" ||
+			settingString(config, "link", "") != ""
+	case domain.OperationEncrypt, domain.OperationDecrypt:
+		return settingBool(config, "remove_source", false)
+	case domain.OperationUpload:
+		return settingBool(config, "remove_source", false) || settingBool(config, "stub", false)
+	case domain.OperationVersions:
+		return settingBool(config, "archive", false)
+	case domain.OperationSimilarity:
+		return settingString(config, "decisions", "") != "" || settingBool(config, "remove_others", false)
+	case domain.OperationTranscode:
+		return settingBool(config, "replace", false)
+	case domain.OperationStats, domain.OperationChecksum, domain.OperationContentIndex,
+		domain.OperationThumbnail, domain.OperationOwners, domain.OperationPack,
+		domain.OperationPDFMerge, domain.OperationPDFSplit, domain.OperationMailExtract,
+		domain.OperationBagExport, domain.OperationBagValidate, domain.OperationRehash,
+		domain.OperationVerifyTree:
+		return false
+	}
+	// Cleanup, organization, ownership, grant, metadata stripping, repair,
+	// triage and restores change files in place or remove them; migrations
+	// replace their own earlier copies on the new root, tiering
+	// moves files between tiers and reviewed plans delete and move what they list
+	return true
+}
+
+// withinPrefixes reports whether path lies below one of prefixes. Symlinks
+// are resolved on both sides so a link cannot lead out of an allowed tree.
+func withinPrefixes(path string, prefixes []string) bool {
+	resolved := resolvePath(path)
+	for _, prefix := range prefixes {
+		prefix = resolvePath(prefix)
+		if resolved == prefix || strings.HasPrefix(resolved, strings.TrimSuffix(prefix, string(filepath.Separator))+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePath returns the absolute path with the symlinks of its existing
+// part resolved
+func resolvePath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	existing, err := filesystem.NearestExisting(abs)
+	if err != nil {
+		return abs
+	}
+	real, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return abs
+	}
+	rest, err := filepath.Rel(existing, abs)
+	if err != nil {
+		return abs
+	}
+	return filepath.Join(real, rest)
+}
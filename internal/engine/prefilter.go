@@ -0,0 +1,282 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+	"github.com/cespare/xxhash/v2"
+)
+
+// Files smaller than prefilterMinSize are hashed right away; reading samples
+// of them first would cost about as much as hashing them
+const (
+	prefilterMinSize    = 64 * 1024
+	prefilterSampleSize = 4 * 1024
+	prefilterFalseRate  = 0.01
+)
+
+// prefilterMagic starts every partial hash cache file
+const prefilterMagic = "fileops-prefilter 1\n"
+
+// bloomFilter is a fixed-size Bloom filter over 64-bit keys. It can report a
+// key that was never added, but never misses one that was.
+type bloomFilter struct {
+	bits   []uint64
+	hashes uint64
+}
+
+// newBloomFilter sizes a filter for n keys at the given false positive rate
+func newBloomFilter(n int, falseRate float64) *bloomFilter {
+	if n < 1 {
+		n = 1
+	}
+	m := math.Ceil(-float64(n) * math.Log(falseRate) / (math.Ln2 * math.Ln2))
+	k := math.Max(1, math.Round(m/float64(n)*math.Ln2))
+	return &bloomFilter{bits: make([]uint64, (uint64(m)+63)/64), hashes: uint64(k)}
+}
+
+// positions calls fn for every bit of key (double hashing)
+func (b *bloomFilter) positions(key uint64, fn func(word int, mask uint64) bool) bool {
+	size := uint64(len(b.bits)) * 64
+	h1, h2 := key, mix64(key)|1
+	for i := uint64(0); i < b.hashes; i++ {
+		bit := (h1 + i*h2) % size
+		if !fn(int(bit/64), 1<<(bit%64)) {
+			return false
+		}
+	}
+	return true
+}
+
+// add inserts key
+func (b *bloomFilter) add(key uint64) {
+	b.positions(key, func(word int, mask uint64) bool {
+		b.bits[word] |= mask
+		return true
+	})
+}
+
+// test reports whether key may have been added
+func (b *bloomFilter) test(key uint64) bool {
+	return b.positions(key, func(word int, mask uint64) bool {
+		return b.bits[word]&mask != 0
+	})
+}
+
+// partialID identifies an unchanged file across runs
+type partialID struct {
+	device  uint64
+	inode   uint64
+	size    int64
+	modTime int64
+}
+
+// partialIDOf returns the identity of a scanned file; false when the platform
+// provides no inode numbers
+func partialIDOf(info *domain.FileInfo) (partialID, bool) {
+	return partialID{info.Device, info.Inode, info.Size, info.ModTime.UnixNano()}, info.Inode != 0
+}
+
+// partialHash hashes three samples (start, middle and end) of a file
+func partialHash(path string, size int64) (uint64, error) {
+	file, err := filesystem.OpenFile(context.Background(), path, os.O_RDONLY, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer filesystem.CloseFile(file)
+	return partialHashOf(file, size)
+}
+
+// partialHashOf hashes the samples of content read from r
+func partialHashOf(r io.ReaderAt, size int64) (uint64, error) {
+	digest := xxhash.New()
+	buf := make([]byte, prefilterSampleSize)
+	for _, offset := range []int64{0, size/2 - prefilterSampleSize/2, size - prefilterSampleSize} {
+		n, err := r.ReadAt(buf, offset)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+		digest.Write(buf[:n])
+	}
+	return digest.Sum64(), nil
+}
+
+// prefilterCachePath returns where the partial hashes of a set of roots are kept
+func prefilterCachePath(cacheDir string, roots []string) string {
+	sorted := append([]string(nil), roots...)
+	sort.Strings(sorted)
+	sum := sha256.New()
+	for _, root := range sorted {
+		sum.Write([]byte(root + "\x00"))
+	}
+	return filepath.Join(cacheDir, "dedup-"+hex.EncodeToString(sum.Sum(nil)[:8])+".prefilter")
+}
+
+// loadPartialHashes reads the partial hashes of the previous run
+func loadPartialHashes(path string) (map[partialID]uint64, error) {
+	hashes := make(map[partialID]uint64)
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return hashes, nil
+	}
+	if err != nil {
+		return hashes, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	magic := make([]byte, len(prefilterMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil || string(magic) != prefilterMagic {
+		return hashes, fmt.Errorf("%s is not a partial hash cache", path)
+	}
+	record := make([]byte, 40)
+	for {
+		if _, err := io.ReadFull(reader, record); err != nil {
+			break // A truncated last record is dropped
+		}
+		id := partialID{
+			device:  binary.LittleEndian.Uint64(record[0:]),
+			inode:   binary.LittleEndian.Uint64(record[8:]),
+			size:    int64(binary.LittleEndian.Uint64(record[16:])),
+			modTime: int64(binary.LittleEndian.Uint64(record[24:])),
+		}
+		hashes[id] = binary.LittleEndian.Uint64(record[32:])
+	}
+	return hashes, nil
+}
+
+// savePartialHashes replaces the cache with the partial hashes of this run
+func savePartialHashes(path string, hashes map[partialID]uint64) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	writer.WriteString(prefilterMagic)
+	record := make([]byte, 40)
+	for id, hash := range hashes {
+		binary.LittleEndian.PutUint64(record[0:], id.device)
+		binary.LittleEndian.PutUint64(record[8:], id.inode)
+		binary.LittleEndian.PutUint64(record[16:], uint64(id.size))
+		binary.LittleEndian.PutUint64(record[24:], uint64(id.modTime))
+		binary.LittleEndian.PutUint64(record[32:], hash)
+		writer.Write(record)
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// prefilter drops the candidates that provably have no duplicate: files whose
+// size and partial hash no other candidate shares. Candidates are counted in
+// two Bloom filters (seen once, seen again), so a false positive only keeps a
+// file that is then hashed in full. Partial hashes of unchanged files are
+// taken from the cache of the previous run over the same roots.
+func (do *DeduplicationOperation) prefilter(ctx context.Context, config domain.OperationConfig, inodes []*dedupInode) ([]*dedupInode, error) {
+	cachePath := ""
+	if cacheDir := settingString(config, "cache_directory", ""); cacheDir != "" {
+		cachePath = prefilterCachePath(cacheDir, config.TargetPaths)
+	}
+	cached := make(map[partialID]uint64)
+	if cachePath != "" {
+		var err error
+		if cached, err = loadPartialHashes(cachePath); err != nil {
+			do.engine.logger.Warn("Ignoring partial hash cache", "path", cachePath, "error", err)
+		}
+	}
+
+	large := make([]*dedupInode, 0)
+	keep := make([]*dedupInode, 0, len(inodes))
+	for _, inode := range inodes {
+		if inode.info.Size < prefilterMinSize {
+			keep = append(keep, inode)
+			continue
+		}
+		if id, ok := partialIDOf(inode.info); ok {
+			if hash, ok := cached[id]; ok {
+				inode.partial, inode.hasPartial = hash, true
+				do.prefilterCached++
+			}
+		}
+		large = append(large, inode)
+	}
+
+	toRead := make([]*dedupInode, 0)
+	for _, inode := range large {
+		if !inode.hasPartial {
+			toRead = append(toRead, inode)
+		}
+	}
+	err := do.forEach(ctx, config, toRead, func(inode *dedupInode) {
+		hash, err := partialHash(inode.info.Path, inode.info.Size)
+		if err == nil {
+			inode.partial, inode.hasPartial = hash, true
+		}
+		// Unreadable files are kept and reported by the full hash
+		do.IncrementProgress(1, 3*prefilterSampleSize)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := newBloomFilter(len(large), prefilterFalseRate)
+	repeated := newBloomFilter(len(large), prefilterFalseRate)
+	keys := make(map[*dedupInode]uint64, len(large))
+	for _, inode := range large {
+		if !inode.hasPartial {
+			continue
+		}
+		key := xxhash.Sum64String(fmt.Sprintf("%s:%016x", inode.key, inode.partial))
+		keys[inode] = key
+		if seen.test(key) {
+			repeated.add(key)
+		} else {
+			seen.add(key)
+		}
+	}
+
+	current := make(map[partialID]uint64, len(large))
+	for _, inode := range large {
+		key, ok := keys[inode]
+		if ok {
+			if id, hasID := partialIDOf(inode.info); hasID {
+				current[id] = inode.partial
+			}
+		}
+		if !ok || repeated.test(key) {
+			keep = append(keep, inode)
+		} else {
+			do.prefilterSkipped++
+		}
+	}
+
+	if cachePath != "" {
+		if err := savePartialHashes(cachePath, current); err != nil {
+			do.engine.logger.Warn("Failed to save partial hash cache", "path", cachePath, "error", err)
+		}
+	}
+	return keep, nil
+}
@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// Space check modes
+const (
+	SpaceCheckRefuse = "refuse"
+	SpaceCheckWarn   = "warn"
+	SpaceCheckOff    = "off"
+)
+
+// linkOverheadBytes approximates the metadata cost of a hardlink or reflink entry
+const linkOverheadBytes = 4096
+
+// SpaceEstimate describes how much destination space an operation needs
+type SpaceEstimate struct {
+	Destination string `json:"destination"`
+	Strategy    string `json:"strategy"`
+	Files       int    `json:"files"`
+	Required    int64  `json:"required"`
+	Available   int64  `json:"available"`
+	Sufficient  bool   `json:"sufficient"`
+}
+
+// EstimateRequiredSpace estimates the bytes the jobs consume at destination.
+// Copies need their full size. Moves on the device of destination are renames
+// that need nothing, and hardlinks and reflinks on it only directory and
+// extent metadata; from other devices, or where its filesystem cannot link,
+// they copy.
+func EstimateRequiredSpace(jobs []CopyJob, destination string) int64 {
+	var device uint64
+	known := false
+	existing, err := filesystem.NearestExisting(destination)
+	if err == nil {
+		device, err = filesystem.DeviceID(existing)
+		known = err == nil
+	}
+
+	// Links the destination filesystem does not support are copies
+	linked := make(map[string]bool)
+	if known && len(jobs) > 0 && jobs[0].Link != "" {
+		if caps, err := filesystem.ProbeCapabilities(existing); err == nil {
+			linked[LinkHardlink], linked[LinkReflink] = caps.Hardlink, caps.Reflink
+		}
+	}
+
+	devices := make(map[string]uint64) // Device of every source directory
+	local := func(source string) bool {
+		if !known {
+			return false
+		}
+		dir := filepath.Dir(source)
+		sourceDevice, ok := devices[dir]
+		if !ok {
+			sourceDevice, _ = filesystem.DeviceID(dir)
+			devices[dir] = sourceDevice
+		}
+		return sourceDevice == device
+	}
+
+	var required int64
+	for _, job := range jobs {
+		switch {
+		case job.Move && local(job.Source):
+		case linked[job.Link] && local(job.Source):
+			required += linkOverheadBytes
+		default:
+			required += job.Size
+		}
+	}
+	return required
+}
+
+// CheckDiskSpace compares required bytes with the space available at
+// destination. strategy (copy, move, hardlink or reflink) is reported with the
+// estimate.
+// In refuse mode an error is returned when space is insufficient; in warn mode
+// the estimate is returned with Sufficient=false and the caller decides.
+func (e *Engine) CheckDiskSpace(destination string, jobs []CopyJob, strategy, mode string) (*SpaceEstimate, error) {
+	if mode == SpaceCheckOff {
+		return nil, nil
+	}
+
+	space, err := filesystem.GetDiskSpace(destination)
+	if err != nil {
+		e.logger.Warn("Unable to determine free space", "destination", destination, "error", err)
+		return nil, nil
+	}
+
+	estimate := &SpaceEstimate{
+		Destination: space.Path,
+		Strategy:    strategy,
+		Files:       len(jobs),
+		Required:    EstimateRequiredSpace(jobs, destination),
+		Available:   int64(space.Available),
+	}
+	estimate.Sufficient = estimate.Required <= estimate.Available
+
+	if !estimate.Sufficient && mode == SpaceCheckRefuse {
+		return estimate, fmt.Errorf("insufficient space at %s: %d bytes required, %d bytes available",
+			space.Path, estimate.Required, estimate.Available)
+	}
+
+	return estimate, nil
+}
@@ -0,0 +1,362 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// RuleBuildArtifacts removes build outputs of recognized projects
+const RuleBuildArtifacts = "build-artifacts"
+
+// projectKind is a kind of project recognized by its manifest, with the
+// directories its tooling regenerates
+type projectKind struct {
+	name      string
+	manifests []string // Files marking the project root; globs allowed
+	artifacts []projectArtifact
+}
+
+// projectArtifact is a build output directory next to the manifest
+type projectArtifact struct {
+	dir    string
+	reason string
+	// regenerable checks the manifest proves the directory can be rebuilt;
+	// nil when the manifest alone is enough
+	regenerable func(project string) bool
+}
+
+// projectKinds are the recognized project layouts
+var projectKinds = []projectKind{
+	{
+		name:      "Node.js",
+		manifests: []string{"package.json"},
+		artifacts: []projectArtifact{
+			{dir: "node_modules", reason: "installed from package.json by npm, yarn or pnpm", regenerable: hasNodeDependencies},
+			{dir: "dist", reason: "output of the build script in package.json", regenerable: hasNodeScript("build")},
+			{dir: "build", reason: "output of the build script in package.json", regenerable: hasNodeScript("build")},
+			{dir: ".next", reason: "Next.js build output and cache"},
+			{dir: ".nuxt", reason: "Nuxt build output"},
+			{dir: ".svelte-kit", reason: "SvelteKit build output"},
+			{dir: ".parcel-cache", reason: "Parcel build cache"},
+			{dir: ".turbo", reason: "Turborepo cache"},
+		},
+	},
+	{
+		name:      "Rust",
+		manifests: []string{"Cargo.toml"},
+		artifacts: []projectArtifact{{dir: "target", reason: "compiled by cargo build"}},
+	},
+	{
+		name:      "Maven",
+		manifests: []string{"pom.xml"},
+		artifacts: []projectArtifact{{dir: "target", reason: "compiled by mvn package"}},
+	},
+	{
+		name:      "Gradle",
+		manifests: []string{"build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts"},
+		artifacts: []projectArtifact{
+			{dir: "build", reason: "compiled by gradle build"},
+			{dir: ".gradle", reason: "Gradle project cache"},
+		},
+	},
+	{
+		name:      "Python",
+		manifests: []string{"pyproject.toml", "setup.py", "setup.cfg"},
+		artifacts: []projectArtifact{
+			{dir: "build", reason: "output of the package build", regenerable: hasPythonBuild},
+			{dir: "dist", reason: "packages built from the project", regenerable: hasPythonBuild},
+			{dir: ".tox", reason: "tox test environments"},
+			{dir: ".nox", reason: "nox test environments"},
+			{dir: ".pytest_cache", reason: "pytest cache"},
+			{dir: ".mypy_cache", reason: "mypy cache"},
+			{dir: ".ruff_cache", reason: "ruff cache"},
+		},
+	},
+	{
+		name:      ".NET",
+		manifests: []string{"*.csproj", "*.fsproj", "*.vbproj"},
+		artifacts: []projectArtifact{
+			{dir: "bin", reason: "compiled by dotnet build"},
+			{dir: "obj", reason: "intermediate output of dotnet build"},
+		},
+	},
+	{
+		name:      "Go",
+		manifests: []string{"go.mod"},
+		artifacts: []projectArtifact{{dir: "vendor", reason: "copied from the module cache by go mod vendor", regenerable: hasVendorManifest}},
+	},
+	{
+		name:      "Elixir",
+		manifests: []string{"mix.exs"},
+		artifacts: []projectArtifact{
+			{dir: "_build", reason: "compiled by mix compile"},
+			{dir: "deps", reason: "fetched by mix deps.get"},
+		},
+	},
+	{
+		name:      "Dart",
+		manifests: []string{"pubspec.yaml"},
+		artifacts: []projectArtifact{
+			{dir: ".dart_tool", reason: "Dart tool cache"},
+			{dir: "build", reason: "output of flutter build"},
+		},
+	},
+	{
+		name:      "Swift",
+		manifests: []string{"Package.swift"},
+		artifacts: []projectArtifact{{dir: ".build", reason: "compiled by swift build"}},
+	},
+}
+
+// BuildArtifact is a build output directory of a recognized project
+type BuildArtifact struct {
+	Path    string `json:"path"`
+	Project string `json:"project"` // Directory holding the manifest
+	Kind    string `json:"kind"`
+	Reason  string `json:"reason"`
+	Files   int64  `json:"files"`
+	Size    int64  `json:"size"`
+}
+
+// buildOutput returns the artifact a directory is, if its parent is a project
+// whose manifest proves it regenerable. Inside a git repository the directory
+// must also be ignored, so nothing committed is treated as an artifact.
+func buildOutput(dir string) (BuildArtifact, bool) {
+	name := filepath.Base(dir)
+	project := filepath.Dir(dir)
+	for _, kind := range projectKinds {
+		for _, artifact := range kind.artifacts {
+			if artifact.dir != name || !hasManifest(project, kind.manifests) {
+				continue
+			}
+			if info, err := os.Lstat(dir); err != nil || !info.IsDir() {
+				return BuildArtifact{}, false // Symlinks may point into source trees
+			}
+			if artifact.regenerable != nil && !artifact.regenerable(project) {
+				continue
+			}
+			if !gitIgnored(dir) {
+				continue
+			}
+			return BuildArtifact{Path: dir, Project: project, Kind: kind.name, Reason: artifact.reason}, true
+		}
+	}
+	return BuildArtifact{}, false
+}
+
+// hasManifest reports whether dir contains one of the manifests
+func hasManifest(dir string, manifests []string) bool {
+	for _, manifest := range manifests {
+		if strings.ContainsAny(manifest, "*?[") {
+			if matches, _ := filepath.Glob(filepath.Join(dir, manifest)); len(matches) > 0 {
+				return true
+			}
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(dir, manifest)); err == nil && !info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// packageJSON is the part of package.json that proves outputs are regenerable
+type packageJSON struct {
+	Dependencies    map[string]string `json:"dependencies"`
+	DevDependencies map[string]string `json:"devDependencies"`
+	Scripts         map[string]string `json:"scripts"`
+}
+
+// readPackageJSON parses the package.json of a project
+func readPackageJSON(project string) (packageJSON, bool) {
+	var manifest packageJSON
+	data, err := os.ReadFile(filepath.Join(project, "package.json"))
+	if err != nil || json.Unmarshal(data, &manifest) != nil {
+		return manifest, false
+	}
+	return manifest, true
+}
+
+// hasNodeDependencies reports whether package.json lists what node_modules holds
+func hasNodeDependencies(project string) bool {
+	manifest, ok := readPackageJSON(project)
+	return ok && len(manifest.Dependencies)+len(manifest.DevDependencies) > 0
+}
+
+// hasNodeScript returns a check for a script in package.json
+func hasNodeScript(script string) func(string) bool {
+	return func(project string) bool {
+		manifest, ok := readPackageJSON(project)
+		return ok && manifest.Scripts[script] != ""
+	}
+}
+
+// hasPythonBuild reports whether the project can build its own packages
+func hasPythonBuild(project string) bool {
+	if hasManifest(project, []string{"setup.py"}) {
+		return true
+	}
+	data, err := os.ReadFile(filepath.Join(project, "pyproject.toml"))
+	return err == nil && strings.Contains(string(data), "[build-system]")
+}
+
+// hasVendorManifest reports whether vendor/ was created by go mod vendor
+func hasVendorManifest(project string) bool {
+	return hasManifest(filepath.Join(project, "vendor"), []string{"modules.txt"})
+}
+
+// gitIgnored reports whether dir is ignored by the git repository it lives
+// in. Directories outside of any repository count as ignored.
+func gitIgnored(dir string) bool {
+	repo := ""
+	for current := filepath.Dir(dir); ; current = filepath.Dir(current) {
+		if _, err := os.Lstat(filepath.Join(current, ".git")); err == nil {
+			repo = current
+			break
+		}
+		if filepath.Dir(current) == current {
+			return true
+		}
+	}
+
+	for current := filepath.Dir(dir); ; current = filepath.Dir(current) {
+		rel, err := filepath.Rel(current, dir)
+		if err == nil && gitignoreMatches(filepath.Join(current, ".gitignore"), filepath.ToSlash(rel)) {
+			return true
+		}
+		if current == repo {
+			return false
+		}
+	}
+}
+
+// gitignoreMatches reports whether a .gitignore ignores the directory at rel.
+// Only the plain patterns used for build outputs are understood ("dist",
+// "/target/", "**/node_modules", "build/*").
+func gitignoreMatches(gitignore, rel string) bool {
+	file, err := os.Open(gitignore)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	name := filepath.Base(rel)
+	ignored := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		negated := strings.HasPrefix(line, "!")
+		pattern := strings.TrimPrefix(line, "!")
+		pattern = strings.TrimSuffix(strings.TrimSuffix(pattern, "/*"), "/")
+		anchored := strings.Contains(pattern, "/") && !strings.HasPrefix(pattern, "**/")
+		pattern = strings.TrimPrefix(strings.TrimPrefix(pattern, "**/"), "/")
+
+		var matched bool
+		if anchored {
+			matched, _ = filepath.Match(pattern, rel)
+		} else {
+			matched, _ = filepath.Match(pattern, name)
+		}
+		if matched {
+			ignored = !negated
+		}
+	}
+	return ignored
+}
+
+// skipBuildOutputs wraps fn so that build outputs of recognized projects are
+// not entered
+func skipBuildOutputs(fn domain.WalkFunc) domain.WalkFunc {
+	return func(path string, info *domain.FileInfo, err error) error {
+		if err == nil && info != nil && info.IsDir {
+			if _, ok := buildOutput(path); ok {
+				return filepath.SkipDir
+			}
+		}
+		return fn(path, info, err)
+	}
+}
+
+// findBuildArtifacts returns the build outputs below the configured roots
+func (co *CleanupOperation) findBuildArtifacts(ctx context.Context, config domain.OperationConfig) ([]BuildArtifact, error) {
+	artifacts := make([]BuildArtifact, 0)
+	for _, root := range config.TargetPaths {
+		err := co.Walk(ctx, root, config, func(path string, info *domain.FileInfo, err error) error {
+			if err != nil {
+				co.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+				return nil // Continue walking
+			}
+			if info == nil || !info.IsDir || path == root {
+				return nil
+			}
+			if err := co.CheckContext(ctx); err != nil {
+				return err
+			}
+			if artifact, ok := buildOutput(path); ok && co.shouldProcessArtifact(path, config) &&
+				co.engine.gitAllows(path, true, config.GitFilter) {
+				artifacts = append(artifacts, artifact)
+				return filepath.SkipDir
+			}
+			// Dependencies of dependencies are not projects of their own
+			if filepath.Base(path) == ".git" || filepath.Base(path) == "node_modules" {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].Path < artifacts[j].Path })
+	return artifacts, nil
+}
+
+// shouldProcessArtifact checks an artifact against the exclude patterns
+func (co *CleanupOperation) shouldProcessArtifact(path string, config domain.OperationConfig) bool {
+	for _, pattern := range config.ExcludePatterns {
+		if matched, _ := filepath.Match(pattern, filepath.Base(path)); matched {
+			return false
+		}
+	}
+	return true
+}
+
+// processBuildArtifacts measures and removes the build outputs, or moves
+// them to the backup directory when one is configured
+func (co *CleanupOperation) processBuildArtifacts(ctx context.Context, config domain.OperationConfig, artifacts []BuildArtifact) error {
+	for _, artifact := range artifacts {
+		if err := co.CheckContext(ctx); err != nil {
+			return err
+		}
+		artifact.Files, artifact.Size = co.measure(ctx, artifact.Path)
+
+		if !config.DryRun {
+			var err error
+			if config.BackupBeforeDelete && config.BackupDirectory != "" {
+				err = co.backupPath(filepath.Dir(artifact.Project), artifact.Path, config.BackupDirectory)
+			} else if err = co.engine.fileSystem.RemoveAll(artifact.Path); err == nil {
+				co.RecordDeletion(artifact.Path, artifact.Size)
+			}
+			if err != nil {
+				co.AddFileError(artifact.Path, fmt.Errorf("failed to remove build output %s: %w", artifact.Path, err))
+				continue
+			}
+			co.engine.logger.Info("Removed build output", "path", artifact.Path, "kind", artifact.Kind)
+		}
+		co.artifacts = append(co.artifacts, artifact)
+		co.artifactBytes += artifact.Size
+		co.IncrementProgress(1, artifact.Size)
+	}
+	return nil
+}
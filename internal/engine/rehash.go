@@ -0,0 +1,318 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/a4abhishek/fileops/internal/integrity"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// RehashFactory creates checksum migration operations
+type RehashFactory struct {
+	engine *Engine
+}
+
+// Create creates a new checksum migration operation
+func (rf *RehashFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewRehashOperation(id, config, rf.engine), nil
+}
+
+// Validate validates the checksum migration configuration
+func (rf *RehashFactory) Validate(config domain.OperationConfig) error {
+	if len(config.TargetPaths) == 0 {
+		return fmt.Errorf("at least one path is required")
+	}
+	from := settingString(config, "from", "")
+	to := settingString(config, "to", "")
+	for _, algorithm := range []string{from, to} {
+		if _, err := filesystem.NewHasher(algorithm); err != nil {
+			return err
+		}
+	}
+	if from == to {
+		return fmt.Errorf("checksums are already recorded with %s", to)
+	}
+	if settingInt(config, "limit", 0) < 0 {
+		return fmt.Errorf("limit must not be negative")
+	}
+	return nil
+}
+
+// RehashOperation moves the checksums recorded by the checksum operation from
+// one algorithm to another. Each file is read once for both: the old checksum
+// is verified before the new one is recorded next to it, in the same layout,
+// so a migration never adopts bit-rot. Files already recorded with the new
+// algorithm are skipped, and a limit spreads a large migration over several
+// runs, starting with the paths needed first.
+type RehashOperation struct {
+	*BaseOperation
+	migrated  *pathList
+	upToDate  *pathList
+	remaining *pathList
+	corrupt   *pathList
+	removed   int
+}
+
+// NewRehashOperation creates a new checksum migration operation
+func NewRehashOperation(id string, config domain.OperationConfig, engine *Engine) *RehashOperation {
+	base := NewBaseOperation(id, domain.OperationRehash, config, engine)
+	return &RehashOperation{
+		BaseOperation: base,
+		migrated:      base.newPathList("migrated"),
+		upToDate:      base.newPathList("up_to_date"),
+		remaining:     base.newPathList("remaining"),
+		corrupt:       base.newPathList("corrupt"),
+	}
+}
+
+// rehashFile is a file with a checksum of the old algorithm
+type rehashFile struct {
+	info     *domain.FileInfo
+	recorded *integrity.Checksum
+}
+
+// Execute records the checksums of the new algorithm
+func (ro *RehashOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	tracker := ro.engine.progressTracker.StartOperation(ro.id, domain.OperationRehash, 3)
+	ro.SetTracker(tracker)
+
+	from := settingString(config, "from", "")
+	to := settingString(config, "to", "")
+	first := settingStrings(config, "first")
+	limit := settingInt(config, "limit", 0)
+	removeOld := settingBool(config, "remove_old", false)
+
+	ro.UpdateStep("Scanning files")
+
+	files, err := ro.scan(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := integrity.NewCatalog()
+	pending := make([]rehashFile, 0)
+	dirs := make(map[string]bool) // Directories with an old manifest
+	for _, info := range files {
+		recorded, found, err := catalog.Lookup(info.Path, from)
+		if err != nil {
+			ro.AddFileError(info.Path, fmt.Errorf("failed to read checksum of %s: %w", info.Path, err))
+			continue
+		}
+		if !found {
+			continue // Nothing to migrate
+		}
+		if recorded.Source == integrity.ManifestPath(filepath.Dir(info.Path), from) {
+			dirs[filepath.Dir(info.Path)] = true
+		}
+
+		current, found, err := catalog.Lookup(info.Path, to)
+		if err != nil {
+			ro.AddFileError(info.Path, fmt.Errorf("failed to read checksum of %s: %w", info.Path, err))
+			continue
+		}
+		if found && !config.MTimeTolerance.After(info.ModTime, current.Recorded) {
+			ro.upToDate.Add(info.Path)
+			if removeOld && recorded.Source == integrity.SidecarPath(info.Path, from) {
+				ro.removeOld(recorded.Source, config)
+			}
+			continue
+		}
+		pending = append(pending, rehashFile{info: info, recorded: recorded})
+	}
+
+	// Files below the first paths are migrated before all others, so a
+	// limited run covers what the next jobs read
+	sort.SliceStable(pending, func(i, j int) bool {
+		return withinPrefixes(pending[i].info.Path, first) && !withinPrefixes(pending[j].info.Path, first)
+	})
+	if limit > 0 && len(pending) > limit {
+		for _, file := range pending[limit:] {
+			ro.remaining.Add(file.info.Path)
+		}
+		pending = pending[:limit]
+	}
+
+	ro.UpdateStep("Computing checksums")
+	var totalSize int64
+	for _, file := range pending {
+		totalSize += file.info.Size
+	}
+	ro.SetProgress(0, int64(len(pending)), 0, totalSize)
+
+	manifests := make(map[string]map[string]string) // Directory -> updated manifest entries of the new algorithm
+	for _, file := range pending {
+		if err := ro.CheckContext(ctx); err != nil {
+			ro.writeManifests(manifests, nil, catalog, from, to, false, config)
+			return nil, err
+		}
+		ro.migrate(file, catalog, manifests, from, to, removeOld, config)
+		ro.IncrementProgress(1, file.info.Size)
+	}
+
+	ro.UpdateStep("Writing manifests")
+	ro.writeManifests(manifests, dirs, catalog, from, to, removeOld, config)
+
+	details := map[string]interface{}{
+		"migrated":   ro.migrated.Result(),
+		"up_to_date": ro.upToDate.Result(),
+		"remaining":  ro.remaining.Result(),
+		"corrupt":    ro.corrupt.Result(),
+		"removed":    ro.removed,
+		"from":       from,
+		"to":         to,
+		"dry_run":    config.DryRun,
+	}
+
+	summary := fmt.Sprintf("Rehash %s to %s: %d migrated, %d already migrated, %d left for later runs, %d corrupt",
+		from, to, ro.migrated.Len(), ro.upToDate.Len(), ro.remaining.Len(), ro.corrupt.Len())
+	if config.DryRun {
+		summary = fmt.Sprintf("Rehash %s to %s (dry run): %d would be migrated, %d already migrated, %d left for later runs, %d corrupt",
+			from, to, ro.migrated.Len(), ro.upToDate.Len(), ro.remaining.Len(), ro.corrupt.Len())
+	}
+	if ro.removed > 0 {
+		summary += fmt.Sprintf(", %d %s sidecars and manifests removed", ro.removed, from)
+	}
+
+	return ro.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// migrate verifies the old checksum of a file and records the new one in the
+// layout the old one used
+func (ro *RehashOperation) migrate(file rehashFile, catalog *integrity.Catalog, manifests map[string]map[string]string, from, to string, removeOld bool, config domain.OperationConfig) {
+	path := file.info.Path
+	sums, err := filesystem.HashFile(path, from, to)
+	if err != nil {
+		ro.AddFileError(path, fmt.Errorf("failed to hash %s: %w", path, err))
+		return
+	}
+
+	// A file modified after its checksum was recorded cannot be verified
+	// against it and is recorded as it is now, as the checksum operation does
+	if !config.MTimeTolerance.After(file.info.ModTime, file.recorded.Recorded) && sums[0] != file.recorded.Sum {
+		ro.AddFileError(path, fmt.Errorf("%w: %s does not match %s, run repair", integrity.ErrCorrupt, path, file.recorded.Source))
+		ro.corrupt.Add(path)
+		return
+	}
+	ro.migrated.Add(path)
+
+	dir := filepath.Dir(path)
+	if file.recorded.Source == integrity.ManifestPath(dir, from) {
+		if manifests[dir] == nil {
+			if manifests[dir], err = catalog.Manifest(dir, to); err != nil {
+				manifests[dir] = make(map[string]string)
+			}
+		}
+		manifests[dir][filepath.Base(path)] = sums[1]
+		return
+	}
+	if config.DryRun {
+		return
+	}
+	if err := integrity.WriteSidecar(path, to, sums[1]); err != nil {
+		ro.AddFileError(path, fmt.Errorf("failed to write checksum of %s: %w", path, err))
+		return
+	}
+	if removeOld {
+		ro.removeOld(file.recorded.Source, config)
+	}
+}
+
+// writeManifests writes the updated per-directory manifests of the new
+// algorithm. With removeOld the old manifest of each of dirs is removed once
+// every file it lists that still exists is in the new one.
+func (ro *RehashOperation) writeManifests(manifests map[string]map[string]string, dirs map[string]bool, catalog *integrity.Catalog, from, to string, removeOld bool, config domain.OperationConfig) {
+	if config.DryRun {
+		return
+	}
+	for dir, sums := range manifests {
+		if err := integrity.WriteManifest(dir, to, sums); err != nil {
+			ro.AddFileError(dir, fmt.Errorf("failed to write checksum manifest in %s: %w", dir, err))
+			delete(dirs, dir)
+		}
+	}
+	if !removeOld {
+		return
+	}
+	for dir := range dirs {
+		old, err := catalog.Manifest(dir, from)
+		if err != nil {
+			continue
+		}
+		current, ok := manifests[dir]
+		if !ok {
+			if current, err = catalog.Manifest(dir, to); err != nil {
+				continue
+			}
+		}
+		covered := true
+		for name := range old {
+			if _, migrated := current[name]; !migrated {
+				if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+					covered = false
+					break
+				}
+			}
+		}
+		if covered {
+			ro.removeOld(integrity.ManifestPath(dir, from), config)
+		}
+	}
+}
+
+// removeOld removes a sidecar or manifest of the old algorithm
+func (ro *RehashOperation) removeOld(path string, config domain.OperationConfig) {
+	if config.DryRun {
+		return
+	}
+	if err := os.Remove(path); err != nil {
+		ro.AddFileError(path, fmt.Errorf("failed to remove %s: %w", path, err))
+		return
+	}
+	ro.removed++
+}
+
+// Validate validates the checksum migration configuration
+func (ro *RehashOperation) Validate(config domain.OperationConfig) error {
+	return ro.ValidateConfig()
+}
+
+// EstimateProgress provides an estimate of the operation's scope
+func (ro *RehashOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	return ro.estimateScope(config, 3)
+}
+
+// scan collects the files below all roots, skipping sidecars, manifests and
+// recovery files
+func (ro *RehashOperation) scan(ctx context.Context, config domain.OperationConfig) ([]*domain.FileInfo, error) {
+	files := make([]*domain.FileInfo, 0)
+	for _, root := range config.TargetPaths {
+		err := ro.Walk(ctx, root, config, func(path string, info *domain.FileInfo, err error) error {
+			if err != nil {
+				ro.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+				return nil // Continue walking
+			}
+			if info == nil {
+				return nil
+			}
+			if ro.isExcluded(path, config) {
+				if info.IsDir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.IsDir && !integrity.IsSidecar(path) {
+				files = append(files, info)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk path %s: %w", root, err)
+		}
+	}
+	return files, nil
+}
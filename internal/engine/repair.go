@@ -0,0 +1,303 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/a4abhishek/fileops/internal/integrity"
+	"github.com/a4abhishek/fileops/internal/stub"
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// RepairFactory creates verify/repair operations
+type RepairFactory struct {
+	engine *Engine
+}
+
+// Create creates a new repair operation
+func (rf *RepairFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewRepairOperation(id, config, rf.engine), nil
+}
+
+// Validate validates the repair configuration
+func (rf *RepairFactory) Validate(config domain.OperationConfig) error {
+	if len(config.TargetPaths) == 0 {
+		return fmt.Errorf("at least one path is required")
+	}
+	return nil
+}
+
+// RepairOperation verifies files against their checksum sidecars and rebuilds
+// corrupt files from their recovery data. Files modified after their checksum
+// was recorded are reported as modified rather than corrupt. Stub files left
+// for offloaded content are checked by the hash they carry instead.
+type RepairOperation struct {
+	*BaseOperation
+	verified     *pathList
+	modified     *pathList
+	corrupt      *pathList
+	repaired     *pathList
+	unrepairable *pathList
+	missing      *pathList
+	unprotected  *pathList
+	offloaded    *pathList
+}
+
+// NewRepairOperation creates a new repair operation
+func NewRepairOperation(id string, config domain.OperationConfig, engine *Engine) *RepairOperation {
+	base := NewBaseOperation(id, domain.OperationRepair, config, engine)
+	return &RepairOperation{
+		BaseOperation: base,
+		verified:      base.newPathList("verified"),
+		modified:      base.newPathList("modified"),
+		corrupt:       base.newPathList("corrupt"),
+		repaired:      base.newPathList("repaired"),
+		unrepairable:  base.newPathList("unrepairable"),
+		missing:       base.newPathList("missing"),
+		unprotected:   base.newPathList("unprotected"),
+		offloaded:     base.newPathList("offloaded"),
+	}
+}
+
+// Execute verifies and repairs all files below the configured paths
+func (ro *RepairOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	tracker := ro.engine.progressTracker.StartOperation(ro.id, domain.OperationRepair, 3)
+	ro.SetTracker(tracker)
+
+	ro.UpdateStep("Scanning files")
+
+	files, sidecars, totalSize, err := ro.scan(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	ro.findMissing(sidecars)
+
+	ro.UpdateStep("Verifying files")
+	ro.SetProgress(0, int64(len(files)), 0, totalSize)
+
+	catalog := integrity.NewCatalog()
+	for _, info := range files {
+		if err := ro.CheckContext(ctx); err != nil {
+			return nil, err
+		}
+		ro.verify(catalog, info, config)
+		ro.IncrementProgress(1, info.Size)
+	}
+
+	ro.UpdateStep("Completed")
+
+	details := map[string]interface{}{
+		"verified":     ro.verified.Result(),
+		"modified":     ro.modified.Result(),
+		"corrupt":      ro.corrupt.Result(),
+		"repaired":     ro.repaired.Result(),
+		"unrepairable": ro.unrepairable.Result(),
+		"missing":      ro.missing.Result(),
+		"unprotected":  ro.unprotected.Result(),
+		"offloaded":    ro.offloaded.Result(),
+		"dry_run":      config.DryRun,
+	}
+
+	summary := fmt.Sprintf("Verified %d files: %d intact, %d repaired, %d unrepairable, %d modified, %d missing, %d without checksum",
+		len(files), ro.verified.Len(), ro.repaired.Len(), ro.unrepairable.Len(), ro.modified.Len(), ro.missing.Len(), ro.unprotected.Len())
+	if config.DryRun {
+		summary = fmt.Sprintf("Verified %d files (dry run): %d intact, %d corrupt, %d modified, %d missing, %d without checksum",
+			len(files), ro.verified.Len(), ro.corrupt.Len(), ro.modified.Len(), ro.missing.Len(), ro.unprotected.Len())
+	}
+	if offloaded := ro.offloaded.Len(); offloaded > 0 {
+		summary += fmt.Sprintf(", %d offloaded", offloaded)
+	}
+
+	return ro.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// verify checks one file and repairs it when it is corrupt and recovery data exists
+func (ro *RepairOperation) verify(catalog *integrity.Catalog, info *domain.FileInfo, config domain.OperationConfig) {
+	if s, err := stub.Detect(info.Path, info.Size); err == nil {
+		ro.verifyStub(catalog, info, s)
+		return
+	}
+
+	recorded, found, err := catalog.Lookup(info.Path, "")
+	if err != nil {
+		ro.AddFileError(info.Path, fmt.Errorf("failed to read checksum of %s: %w", info.Path, err))
+		return
+	}
+
+	hasParity := integrity.HasParity(info.Path)
+	if !found {
+		if !hasParity {
+			ro.unprotected.Add(info.Path)
+			return
+		}
+		// Recovery data carries its own block checksums
+		damaged, err := integrity.DamagedBlocks(info.Path)
+		if err != nil {
+			ro.AddFileError(info.Path, fmt.Errorf("failed to verify %s: %w", info.Path, err))
+			return
+		}
+		if damaged == 0 {
+			ro.verified.Add(info.Path)
+			return
+		}
+		ro.corrupted(info, config, nil)
+		return
+	}
+
+	sum, err := ro.engine.fileSystem.ComputeHash(info.Path, recorded.Algorithm)
+	if err != nil {
+		ro.AddFileError(info.Path, fmt.Errorf("failed to hash %s: %w", info.Path, err))
+		return
+	}
+	switch {
+	case sum == recorded.Sum:
+		ro.verified.Add(info.Path)
+	case info.ModTime.After(recorded.Recorded):
+		// An edit, not bit-rot; "fileops checksum" records the new content
+		ro.modified.Add(info.Path)
+	case !hasParity:
+		ro.corrupt.Add(info.Path)
+		ro.unrepairable.Add(info.Path)
+		ro.AddFileError(info.Path, fmt.Errorf("%w: %s (no recovery data)", integrity.ErrCorrupt, info.Path))
+	default:
+		ro.corrupted(info, config, recorded)
+	}
+}
+
+// verifyStub checks the hash a stub carries for its offloaded content against
+// the checksum recorded before the content went. The content itself is not
+// read, so a stub without a comparable checksum only counts as offloaded.
+func (ro *RepairOperation) verifyStub(catalog *integrity.Catalog, info *domain.FileInfo, s *stub.Stub) {
+	if s.Algorithm == "" {
+		ro.offloaded.Add(info.Path)
+		return
+	}
+	recorded, found, err := catalog.Lookup(info.Path, strings.ToLower(s.Algorithm))
+	if err != nil {
+		ro.AddFileError(info.Path, fmt.Errorf("failed to read checksum of %s: %w", info.Path, err))
+		return
+	}
+	switch {
+	case !found || strings.EqualFold(recorded.Sum, s.Hash):
+		ro.offloaded.Add(info.Path)
+	case s.ModTime.After(recorded.Recorded):
+		ro.modified.Add(info.Path)
+	default:
+		// Rotten before it was offloaded; the recovery data was for the local copy
+		ro.corrupt.Add(info.Path)
+		ro.unrepairable.Add(info.Path)
+		ro.AddFileError(info.Path, fmt.Errorf("%w: content of %s offloaded to %s does not match %s", integrity.ErrCorrupt, info.Path, s.Location, recorded.Source))
+	}
+}
+
+// corrupted repairs a corrupt file from its recovery data and confirms the
+// result against the recorded checksum when there is one
+func (ro *RepairOperation) corrupted(info *domain.FileInfo, config domain.OperationConfig, recorded *integrity.Checksum) {
+	ro.corrupt.Add(info.Path)
+	if config.DryRun {
+		return
+	}
+
+	if _, err := integrity.Repair(info.Path, config.MTimeTolerance); err != nil {
+		ro.unrepairable.Add(info.Path)
+		if !errors.Is(err, integrity.ErrUnrepairable) && !errors.Is(err, integrity.ErrStaleParity) {
+			err = fmt.Errorf("%w: %w", integrity.ErrUnrepairable, err)
+		}
+		ro.AddFileError(info.Path, fmt.Errorf("failed to repair %s: %w", info.Path, err))
+		return
+	}
+
+	if recorded != nil {
+		sum, err := ro.engine.fileSystem.ComputeHash(info.Path, recorded.Algorithm)
+		if err != nil || sum != recorded.Sum {
+			// The recovery data was created from different content than the checksum
+			ro.unrepairable.Add(info.Path)
+			ro.AddFileError(info.Path, fmt.Errorf("%w: %s still does not match %s after repair", integrity.ErrUnrepairable, info.Path, recorded.Source))
+			return
+		}
+	}
+	ro.repaired.Add(info.Path)
+}
+
+// findMissing reports files that have a checksum but no longer exist
+func (ro *RepairOperation) findMissing(sidecars []string) {
+	seen := make(map[string]bool)
+	for _, sidecar := range sidecars {
+		if strings.HasSuffix(sidecar, integrity.ParityExtension) {
+			path := strings.TrimSuffix(sidecar, integrity.ParityExtension)
+			if _, err := os.Stat(path); os.IsNotExist(err) && !seen[path] {
+				seen[path] = true
+				ro.missing.Add(path)
+			}
+			continue
+		}
+
+		sums, err := integrity.ReadManifest(sidecar)
+		if err != nil {
+			ro.AddFileError(sidecar, fmt.Errorf("failed to read %s: %w", sidecar, err))
+			continue
+		}
+		for name := range sums {
+			path := filepath.Join(filepath.Dir(sidecar), name)
+			if _, err := os.Stat(path); os.IsNotExist(err) && !seen[path] {
+				seen[path] = true
+				ro.missing.Add(path)
+			}
+		}
+	}
+}
+
+// Validate validates the repair operation configuration
+func (ro *RepairOperation) Validate(config domain.OperationConfig) error {
+	return ro.ValidateConfig()
+}
+
+// EstimateProgress provides an estimate of the operation's scope
+func (ro *RepairOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	return ro.estimateScope(config, 3)
+}
+
+// scan collects the files to verify and the sidecars found next to them
+func (ro *RepairOperation) scan(ctx context.Context, config domain.OperationConfig) ([]*domain.FileInfo, []string, int64, error) {
+	files := make([]*domain.FileInfo, 0)
+	sidecars := make([]string, 0)
+	var totalSize int64
+
+	for _, root := range config.TargetPaths {
+		err := ro.Walk(ctx, root, config, func(path string, info *domain.FileInfo, err error) error {
+			if err != nil {
+				ro.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+				return nil // Continue walking
+			}
+			if info == nil {
+				return nil
+			}
+			if ro.isExcluded(path, config) {
+				if info.IsDir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir {
+				return nil
+			}
+			if integrity.IsSidecar(path) {
+				if !strings.HasPrefix(info.Name, ".fileops-") {
+					sidecars = append(sidecars, path)
+				}
+				return nil
+			}
+			files = append(files, info)
+			totalSize += info.Size
+			return nil
+		})
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("failed to walk path %s: %w", root, err)
+		}
+	}
+	return files, sidecars, totalSize, nil
+}
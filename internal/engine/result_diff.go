@@ -0,0 +1,275 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// ResultDiff compares two stored results of the same operation type, e.g.
+// last week's deduplication report with this week's
+type ResultDiff struct {
+	OperationType domain.OperationType `json:"operation_type"`
+	Old           string               `json:"old"`
+	New           string               `json:"new"`
+	// Duplicate groups (deduplication results only)
+	Appeared  []domain.DuplicateGroup `json:"appeared,omitempty"`  // Content duplicated only in the newer result
+	Resolved  []domain.DuplicateGroup `json:"resolved,omitempty"`  // Content duplicated only in the older result
+	Changed   []GroupChange           `json:"changed,omitempty"`   // Duplicated in both, with other copies
+	Unchanged int                     `json:"unchanged,omitempty"` // Duplicated in both with the same copies
+	// Reclaimable space of every stored result of the same type and paths
+	// from the older to the newer one, oldest first
+	Trend []TrendPoint `json:"trend,omitempty"`
+	// Path lists present in both results, e.g. removed_directories, of
+	// results without duplicate groups
+	Lists []ListDiff `json:"lists,omitempty"`
+}
+
+// GroupChange is content duplicated in both results with other copies
+type GroupChange struct {
+	Old     domain.DuplicateGroup `json:"old"`
+	New     domain.DuplicateGroup `json:"new"`
+	Added   []string              `json:"added,omitempty"`   // Copies only in the newer result
+	Removed []string              `json:"removed,omitempty"` // Copies only in the older result
+}
+
+// TrendPoint is the reclaimable space found by one run
+type TrendPoint struct {
+	ID          string    `json:"id"`
+	Time        time.Time `json:"time"`
+	Groups      int       `json:"groups"`
+	Reclaimable int64     `json:"reclaimable"`
+}
+
+// ListDiff lists the paths added to and removed from a path list
+type ListDiff struct {
+	Key     string   `json:"key"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// AppearedSize returns the reclaimable space of the appeared groups
+func (d *ResultDiff) AppearedSize() int64 {
+	return saveableSize(d.Appeared)
+}
+
+// ResolvedSize returns the reclaimable space of the resolved groups
+func (d *ResultDiff) ResolvedSize() int64 {
+	return saveableSize(d.Resolved)
+}
+
+// DiffResults compares older with newer. history are other stored results;
+// those of the same type and paths between the two make up the trend.
+func DiffResults(older, newer *domain.OperationResult, history []*domain.OperationResult) (*ResultDiff, error) {
+	if older.OperationType != newer.OperationType {
+		return nil, fmt.Errorf("cannot compare a %s result with a %s result", older.OperationType, newer.OperationType)
+	}
+	if newer.StartTime.Before(older.StartTime) {
+		older, newer = newer, older
+	}
+	diff := &ResultDiff{OperationType: older.OperationType, Old: older.ID, New: newer.ID}
+
+	oldGroups, oldOK, err := ResultGroups(older)
+	if err != nil {
+		return nil, err
+	}
+	newGroups, newOK, err := ResultGroups(newer)
+	if err != nil {
+		return nil, err
+	}
+	if oldOK && newOK {
+		diff.diffGroups(oldGroups, newGroups)
+		diff.Trend = trend(older, newer, history)
+		return diff, nil
+	}
+
+	for _, key := range sharedLists(older, newer) {
+		oldPaths, err := ResultFiles(older, key)
+		if err != nil {
+			return nil, err
+		}
+		newPaths, err := ResultFiles(newer, key)
+		if err != nil {
+			return nil, err
+		}
+		added, removed := diffPaths(oldPaths, newPaths)
+		if len(added) > 0 || len(removed) > 0 {
+			diff.Lists = append(diff.Lists, ListDiff{Key: key, Added: added, Removed: removed})
+		}
+	}
+	return diff, nil
+}
+
+// diffGroups matches the groups of both results by their content
+func (d *ResultDiff) diffGroups(oldGroups, newGroups []domain.DuplicateGroup) {
+	byContent := make(map[string]domain.DuplicateGroup, len(oldGroups))
+	for _, group := range oldGroups {
+		byContent[groupContent(group)] = group
+	}
+	for _, group := range newGroups {
+		key := groupContent(group)
+		old, ok := byContent[key]
+		if !ok {
+			d.Appeared = append(d.Appeared, group)
+			continue
+		}
+		delete(byContent, key)
+		added, removed := diffPaths(groupPaths(old), groupPaths(group))
+		if len(added) == 0 && len(removed) == 0 {
+			d.Unchanged++
+			continue
+		}
+		d.Changed = append(d.Changed, GroupChange{Old: old, New: group, Added: added, Removed: removed})
+	}
+	for _, group := range oldGroups {
+		if _, ok := byContent[groupContent(group)]; ok {
+			d.Resolved = append(d.Resolved, group)
+		}
+	}
+
+	// Largest first, that is where space goes
+	for _, groups := range [][]domain.DuplicateGroup{d.Appeared, d.Resolved} {
+		sort.SliceStable(groups, func(i, j int) bool { return groups[i].SaveablSize > groups[j].SaveablSize })
+	}
+}
+
+// ResultGroups returns the duplicate groups of a result, and whether it has
+// any, also for results loaded from the repository
+func ResultGroups(result *domain.OperationResult) ([]domain.DuplicateGroup, bool, error) {
+	value, ok := result.Details["groups"]
+	if !ok || result.OperationType != domain.OperationDeduplication {
+		return nil, false, nil
+	}
+	if groups, ok := value.([]domain.DuplicateGroup); ok {
+		return groups, true, nil
+	}
+	// Results loaded from the repository hold the decoded JSON
+	var groups []domain.DuplicateGroup
+	data, err := json.Marshal(value)
+	if err == nil {
+		err = json.Unmarshal(data, &groups)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("groups of %s result %s are not duplicate groups", result.OperationType, result.ID)
+	}
+	return groups, true, nil
+}
+
+// groupContent identifies the content of a group across runs: its hash, or
+// its size and files when it was matched by size alone
+func groupContent(group domain.DuplicateGroup) string {
+	if len(group.Files) == 0 {
+		return group.ID
+	}
+	if first := group.Files[0]; first.Hash != "" {
+		return first.HashType + ":" + first.Hash
+	}
+	return fmt.Sprintf("size:%d:%s", group.Files[0].Size, strings.Join(groupPaths(group), "\x00"))
+}
+
+// groupPaths returns the sorted paths of the copies of a group
+func groupPaths(group domain.DuplicateGroup) []string {
+	paths := make([]string, 0, len(group.Files))
+	for _, file := range group.Files {
+		paths = append(paths, file.Path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// diffPaths returns the paths only in newer and those only in older, sorted
+func diffPaths(older, newer []string) (added, removed []string) {
+	inOld := make(map[string]bool, len(older))
+	for _, path := range older {
+		inOld[path] = true
+	}
+	inNew := make(map[string]bool, len(newer))
+	for _, path := range newer {
+		inNew[path] = true
+		if !inOld[path] {
+			added = append(added, path)
+		}
+	}
+	for _, path := range older {
+		if !inNew[path] {
+			removed = append(removed, path)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// sharedLists returns the keys of the path lists both results hold, sorted
+func sharedLists(older, newer *domain.OperationResult) []string {
+	var keys []string
+	for key, value := range newer.Details {
+		if _, ok := older.Details[key]; ok && isPathList(value) && isPathList(older.Details[key]) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isPathList reports whether a detail is a path list, also as decoded JSON
+func isPathList(value interface{}) bool {
+	switch value := value.(type) {
+	case domain.PathList:
+		return true
+	case map[string]interface{}:
+		_, hasCount := value["count"]
+		_, hasPaths := value["paths"]
+		return hasCount && hasPaths
+	}
+	return false
+}
+
+// trend lists the reclaimable space of the results of the same type and
+// paths as older and newer that ran between them, both included
+func trend(older, newer *domain.OperationResult, history []*domain.OperationResult) []TrendPoint {
+	seen := make(map[string]bool)
+	var points []TrendPoint
+	for _, result := range append([]*domain.OperationResult{older, newer}, history...) {
+		if seen[result.ID] || result.OperationType != older.OperationType ||
+			result.StartTime.Before(older.StartTime) || result.StartTime.After(newer.StartTime) ||
+			!samePaths(targetPaths(result), targetPaths(older)) {
+			continue
+		}
+		groups, ok, err := ResultGroups(result)
+		if err != nil || !ok {
+			continue
+		}
+		seen[result.ID] = true
+		points = append(points, TrendPoint{ID: result.ID, Time: result.StartTime, Groups: len(groups), Reclaimable: saveableSize(groups)})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+	return points
+}
+
+// targetPaths returns the paths a result's operation ran on
+func targetPaths(result *domain.OperationResult) []string {
+	if result.Config == nil {
+		return nil
+	}
+	return result.Config.TargetPaths
+}
+
+// samePaths reports whether a and b hold the same paths in any order
+func samePaths(a, b []string) bool {
+	added, removed := diffPaths(a, b)
+	return len(added) == 0 && len(removed) == 0
+}
+
+// saveableSize adds up the reclaimable space of groups
+func saveableSize(groups []domain.DuplicateGroup) int64 {
+	var size int64
+	for _, group := range groups {
+		size += group.SaveablSize
+	}
+	return size
+}
@@ -0,0 +1,275 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+	"github.com/a4abhishek/fileops/pkg/schema"
+)
+
+// Actions of a planned change
+const (
+	PlanDelete = "delete"
+	PlanMove   = "move"
+)
+
+// ReviewPlan lists the deletions and moves a dry run planned, so they can be
+// reviewed one by one ("fileops review") before they are applied. Rejected
+// changes are left out when the plan is applied.
+type ReviewPlan struct {
+	SchemaVersion int            `json:"schema_version"`      // See package schema
+	Operation     string         `json:"operation,omitempty"` // ID of the dry run that planned the changes
+	Created       time.Time      `json:"created"`
+	Roots         []string       `json:"roots"` // Paths the changes were planned under
+	Changes       []ReviewChange `json:"changes"`
+}
+
+// ReviewChange is one planned deletion or move. Size and modification time
+// are those seen when it was planned; files that differ are not touched.
+type ReviewChange struct {
+	Action   string    `json:"action"`
+	Path     string    `json:"path"`
+	Target   string    `json:"target,omitempty"` // Where a move puts Path
+	IsDir    bool      `json:"is_dir,omitempty"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"mod_time"`
+	Reason   string    `json:"reason,omitempty"`
+	Rejected bool      `json:"rejected,omitempty"`
+}
+
+// LoadReviewPlan reads a plan file
+func LoadReviewPlan(path string) (*ReviewPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = schema.Upgrade(schema.ReviewPlan, data); err != nil {
+		return nil, fmt.Errorf("invalid plan %s: %w", path, err)
+	}
+	var plan ReviewPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("invalid plan %s: %w", path, err)
+	}
+	if len(plan.Roots) == 0 {
+		return nil, fmt.Errorf("invalid plan %s: no roots", path)
+	}
+	for _, change := range plan.Changes {
+		switch {
+		case change.Action != PlanDelete && change.Action != PlanMove:
+			return nil, fmt.Errorf("invalid plan %s: unknown action %q for %s", path, change.Action, change.Path)
+		case !filepath.IsAbs(change.Path):
+			return nil, fmt.Errorf("invalid plan %s: %s is not an absolute path", path, change.Path)
+		case !withinPrefixes(change.Path, plan.Roots):
+			return nil, fmt.Errorf("invalid plan %s: %s is outside the planned roots", path, change.Path)
+		case change.Action == PlanMove && !filepath.IsAbs(change.Target):
+			return nil, fmt.Errorf("invalid plan %s: move of %s needs an absolute target", path, change.Path)
+		}
+	}
+	return &plan, nil
+}
+
+// Save writes the plan to path, replacing the file atomically
+func (p *ReviewPlan) Save(path string) error {
+	p.SchemaVersion = schema.ReviewPlanVersion
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return filesystem.WriteFileAtomic(path, append(data, '\n'), 0o644)
+}
+
+// Approved returns the changes that were not rejected
+func (p *ReviewPlan) Approved() []ReviewChange {
+	approved := make([]ReviewChange, 0, len(p.Changes))
+	for _, change := range p.Changes {
+		if !change.Rejected {
+			approved = append(approved, change)
+		}
+	}
+	return approved
+}
+
+// ApplyPlanFactory creates operations that apply reviewed plans
+type ApplyPlanFactory struct {
+	engine *Engine
+}
+
+// Create creates a new apply plan operation
+func (af *ApplyPlanFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewApplyPlanOperation(id, config, af.engine), nil
+}
+
+// Validate validates the apply plan configuration
+func (af *ApplyPlanFactory) Validate(config domain.OperationConfig) error {
+	path := settingString(config, "plan", "")
+	if path == "" {
+		return fmt.Errorf("plan parameter is required")
+	}
+	_, err := LoadReviewPlan(path)
+	return err
+}
+
+// ApplyPlanOperation carries out the approved changes of a reviewed plan.
+// Files that changed since they were planned are skipped, moves never
+// overwrite, and deleted items go to the backup directory when one is set.
+type ApplyPlanOperation struct {
+	*BaseOperation
+	deleted  *pathList
+	moved    *pathList
+	rejected *pathList
+	changed  *pathList // Skipped because they differ from what was planned
+	freed    int64
+}
+
+// NewApplyPlanOperation creates a new apply plan operation
+func NewApplyPlanOperation(id string, config domain.OperationConfig, engine *Engine) *ApplyPlanOperation {
+	base := NewBaseOperation(id, domain.OperationApplyPlan, config, engine)
+	return &ApplyPlanOperation{
+		BaseOperation: base,
+		deleted:       base.newPathList("deleted"),
+		moved:         base.newPathList("moved"),
+		rejected:      base.newPathList("rejected"),
+		changed:       base.newPathList("changed"),
+	}
+}
+
+// Execute applies the approved changes in plan order
+func (ao *ApplyPlanOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	tracker := ao.engine.progressTracker.StartOperation(ao.id, domain.OperationApplyPlan, 2)
+	ao.SetTracker(tracker)
+
+	path := settingString(config, "plan", "")
+	plan, err := LoadReviewPlan(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ao.UpdateStep("Applying changes")
+	var totalSize int64
+	for _, change := range plan.Changes {
+		totalSize += change.Size
+	}
+	ao.SetProgress(0, int64(len(plan.Changes)), 0, totalSize)
+
+	for _, change := range plan.Changes {
+		if err := ao.CheckContext(ctx); err != nil {
+			return nil, err
+		}
+		if err := ao.ErrorBudgetErr(); err != nil {
+			return nil, err
+		}
+		if change.Rejected {
+			ao.rejected.Add(change.Path)
+		} else {
+			ao.apply(ctx, change, config)
+		}
+		ao.IncrementProgress(1, change.Size)
+	}
+
+	ao.UpdateStep("Completed")
+
+	details := map[string]interface{}{
+		"plan":        path,
+		"deleted":     ao.deleted.Result(),
+		"moved":       ao.moved.Result(),
+		"rejected":    ao.rejected.Result(),
+		"changed":     ao.changed.Result(),
+		"bytes_freed": ao.freed,
+		"dry_run":     config.DryRun,
+	}
+
+	summary := fmt.Sprintf("Plan applied: %d deleted, %d moved, %d rejected, %d changed since planned",
+		ao.deleted.Len(), ao.moved.Len(), ao.rejected.Len(), ao.changed.Len())
+	if config.DryRun {
+		summary = fmt.Sprintf("Plan (dry run): %d would be deleted, %d moved, %d rejected, %d changed since planned",
+			ao.deleted.Len(), ao.moved.Len(), ao.rejected.Len(), ao.changed.Len())
+	}
+
+	return ao.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// apply deletes or moves one item after checking it did not change since it
+// was planned. Directories are measured again, so nothing added to them after
+// the review goes with them.
+func (ao *ApplyPlanOperation) apply(ctx context.Context, change ReviewChange, config domain.OperationConfig) {
+	current, err := ao.engine.fileSystem.Stat(change.Path)
+	if err != nil {
+		ao.AddFileError(change.Path, fmt.Errorf("failed to check %s: %w", change.Path, err))
+		return
+	}
+	if current.IsDir {
+		current.Size, current.ModTime = ao.measureDir(ctx, change.Path)
+	}
+	if current.IsDir != change.IsDir || current.Size != change.Size || !config.MTimeTolerance.Equal(current.ModTime, change.ModTime) {
+		ao.changed.Add(change.Path)
+		return
+	}
+
+	switch change.Action {
+	case PlanMove:
+		if ao.engine.fileSystem.Exists(change.Target) {
+			ao.AddFileError(change.Path, fmt.Errorf("failed to move %s: %s already exists", change.Path, change.Target))
+			return
+		}
+		if !config.DryRun {
+			if err := ao.move(change.Path, change.Target); err != nil {
+				ao.AddFileError(change.Path, fmt.Errorf("failed to move %s: %w", change.Path, err))
+				return
+			}
+		}
+		ao.moved.Add(change.Path)
+	case PlanDelete:
+		if !config.DryRun {
+			if config.BackupBeforeDelete && config.BackupDirectory != "" {
+				err = ao.move(change.Path, ao.freeTarget(filepath.Join(config.BackupDirectory, filepath.Base(change.Path))))
+			} else if err = ao.engine.fileSystem.RemoveAll(change.Path); err == nil {
+				ao.RecordDeletion(change.Path, change.Size)
+			}
+			if err != nil {
+				ao.AddFileError(change.Path, fmt.Errorf("failed to delete %s: %w", change.Path, err))
+				return
+			}
+		}
+		ao.deleted.Add(change.Path)
+		ao.freed += change.Size
+	}
+}
+
+// Validate validates the apply plan operation configuration
+func (ao *ApplyPlanOperation) Validate(config domain.OperationConfig) error {
+	return ao.ValidateConfig()
+}
+
+// EstimateProgress counts the approved changes of the plan; no walk is needed
+func (ao *ApplyPlanOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	plan, err := LoadReviewPlan(settingString(config, "plan", ""))
+	if err != nil {
+		return nil, err
+	}
+	var items, bytes int64
+	for _, change := range plan.Approved() {
+		items++
+		bytes += change.Size
+	}
+	duration := ao.engine.expectedDuration(ao.operationType, items, bytes)
+	return &domain.ProgressInfo{
+		ID:            ao.id,
+		OperationType: ao.operationType,
+		Status:        domain.StatusPending,
+		TotalSteps:    2,
+		TotalItems:    items,
+		TotalBytes:    bytes,
+		EstimatedETA:  &duration,
+		Details: map[string]interface{}{
+			"directories":         int64(0),
+			"exact":               true,
+			"sampled_directories": 0,
+		},
+	}, nil
+}
@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// moveLog records the moves of a run in the order they happened
+type moveLog struct {
+	mu      sync.Mutex
+	sources []string
+	targets []string
+}
+
+func (m *moveLog) add(source, target string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sources = append(m.sources, source)
+	m.targets = append(m.targets, target)
+}
+
+// rollBack moves the files of an aborted run back, the last moved first, and
+// returns the abort error with how much of the run was undone. Deletions are
+// not undone: only a snapshot taken before the run restores those.
+func (e *Engine) rollBack(moves *moveLog, cause error) error {
+	moves.mu.Lock()
+	defer moves.mu.Unlock()
+
+	failed := 0
+	for i := len(moves.sources) - 1; i >= 0; i-- {
+		source, target := moves.sources[i], moves.targets[i]
+		if err := e.moveBack(target, source); err != nil {
+			e.logger.Error("Failed to move file back", "path", target, "target", source, "error", err)
+			failed++
+		}
+	}
+
+	total := len(moves.sources)
+	if failed > 0 {
+		return fmt.Errorf("%w; rolled back %d of %d moves, see the log for the others", cause, total-failed, total)
+	}
+	return fmt.Errorf("%w; rolled back %d moves", cause, total)
+}
+
+// moveBack moves target back to the path it was moved from, copying it
+// when they are on different devices. A file that took its place is kept.
+func (e *Engine) moveBack(target, source string) error {
+	if e.fileSystem.Exists(source) {
+		return fmt.Errorf("%s: %w", source, os.ErrExist)
+	}
+	if err := e.fileSystem.CreateDir(filepath.Dir(source)); err != nil {
+		return err
+	}
+	err := e.fileSystem.Move(target, source)
+	if !filesystem.IsCrossDevice(err) {
+		return err
+	}
+	if err := e.fileSystem.Copy(target, source); err != nil {
+		return err
+	}
+	return e.fileSystem.Remove(target)
+}
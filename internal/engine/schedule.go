@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrTimeLimit is returned when an operation stops because it ran for its
+// maximum duration and has no window to continue in
+var ErrTimeLimit = errors.New("time limit reached")
+
+// Window is a daily span of local time operations may run in, e.g.
+// 22:00-06:00. A window that ends before it starts spans midnight.
+type Window struct {
+	Start time.Duration // Since midnight
+	End   time.Duration // Since midnight
+}
+
+// ParseWindow parses a window written as HH:MM-HH:MM
+func ParseWindow(text string) (Window, error) {
+	start, end, ok := strings.Cut(text, "-")
+	if !ok {
+		return Window{}, fmt.Errorf("invalid window %q (use HH:MM-HH:MM, e.g. 22:00-06:00)", text)
+	}
+	var window Window
+	var err error
+	if window.Start, err = parseClock(start); err != nil {
+		return Window{}, fmt.Errorf("invalid window %q: %w", text, err)
+	}
+	if window.End, err = parseClock(end); err != nil {
+		return Window{}, fmt.Errorf("invalid window %q: %w", text, err)
+	}
+	if window.Start == window.End {
+		return Window{}, fmt.Errorf("invalid window %q: start and end are the same", text)
+	}
+	return window, nil
+}
+
+// parseClock parses HH:MM into the time since midnight
+func parseClock(text string) (time.Duration, error) {
+	clock, err := time.Parse("15:04", strings.TrimSpace(text))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time of day", strings.TrimSpace(text))
+	}
+	return time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute, nil
+}
+
+// sinceMidnight returns the time of day of t
+func sinceMidnight(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+}
+
+// Contains reports whether t is inside the window
+func (w Window) Contains(t time.Time) bool {
+	day := sinceMidnight(t)
+	if w.Start < w.End {
+		return day >= w.Start && day < w.End
+	}
+	return day >= w.Start || day < w.End
+}
+
+// Next returns when the window opens next after t
+func (w Window) Next(t time.Time) time.Time {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	opens := midnight.Add(w.Start)
+	if !opens.After(t) {
+		opens = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location()).Add(w.Start)
+	}
+	return opens
+}
+
+// String returns the window as HH:MM-HH:MM
+func (w Window) String() string {
+	clock := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return clock(w.Start) + "-" + clock(w.End)
+}
+
+// checkSchedule keeps the operation within its window and time limit. Outside
+// the window, or once it ran for MaxDuration in the current window, the
+// operation pauses where it is until the window opens again. Without a window
+// the time limit stops it with ErrTimeLimit instead.
+func (bo *BaseOperation) checkSchedule(ctx context.Context) error {
+	if bo.config.Window == "" && bo.config.MaxDuration <= 0 {
+		return nil
+	}
+	// Workers of the operation wait here together while it is paused
+	bo.scheduleMu.Lock()
+	defer bo.scheduleMu.Unlock()
+
+	now := time.Now()
+	if bo.config.Window == "" {
+		if now.Sub(bo.sessionStart) >= bo.config.MaxDuration {
+			return fmt.Errorf("%w: ran for %v", ErrTimeLimit, bo.config.MaxDuration)
+		}
+		return nil
+	}
+
+	window, err := ParseWindow(bo.config.Window)
+	if err != nil {
+		return err
+	}
+	var reason string
+	switch {
+	case !window.Contains(now):
+		reason = "outside the window " + window.String()
+	case bo.config.MaxDuration > 0 && now.Sub(bo.sessionStart) >= bo.config.MaxDuration:
+		reason = fmt.Sprintf("ran for %v in this window", bo.config.MaxDuration)
+	default:
+		return nil
+	}
+
+	opens := window.Next(now)
+	bo.engine.logger.Info("Operation paused", "id", bo.id, "reason", reason, "resumes", opens.Format("2006-01-02 15:04"))
+	// The pause shows as the current step; the interrupted step continues after it
+	bo.mu.RLock()
+	step := bo.step
+	bo.mu.RUnlock()
+	bo.UpdateStep(fmt.Sprintf("Paused %s until %s", reason, opens.Format("Mon 15:04")))
+	if bo.tracker != nil {
+		bo.tracker.Pause()
+		defer bo.tracker.Resume()
+	}
+
+	timer := time.NewTimer(time.Until(opens))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+	}
+
+	bo.sessionStart = time.Now()
+	bo.engine.logger.Info("Operation resumed", "id", bo.id, "window", window.String())
+	bo.UpdateStep(step)
+	return nil
+}
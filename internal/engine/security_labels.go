@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// labelDestination is the filesystem files are planned to move to, as far as
+// keeping their SELinux contexts and file capabilities is concerned
+type labelDestination struct {
+	device    uint64
+	supported bool
+	known     bool
+}
+
+// newLabelDestination inspects the filesystem that will hold destination
+func newLabelDestination(destination string) labelDestination {
+	probe, err := filesystem.NearestExisting(destination)
+	if err != nil {
+		return labelDestination{}
+	}
+	device, err := filesystem.DeviceID(probe)
+	if err != nil {
+		return labelDestination{}
+	}
+	return labelDestination{
+		device:    device,
+		supported: filesystem.SecurityLabelsSupported(probe),
+		known:     true,
+	}
+}
+
+// checkSecurityLabels warns when moving a file to dest would lose its
+// security labels. Within a filesystem a move is a rename that keeps them;
+// across filesystems the file is copied, which keeps them only with the
+// SecurityLabels setting and a destination able to store them.
+func (bo *BaseOperation) checkSecurityLabels(info *domain.FileInfo, dest labelDestination, config domain.OperationConfig) {
+	if !dest.known || info.Device == 0 || info.Device == dest.device {
+		return
+	}
+	if config.SecurityLabels && dest.supported {
+		return
+	}
+	labels, err := filesystem.ReadSecurityLabels(info.Path)
+	if err != nil || labels.Empty() {
+		return
+	}
+
+	reason := "use --security-labels to copy them"
+	if !dest.supported {
+		reason = "the destination filesystem cannot store them"
+	}
+	bo.AddWarning(info.Path, domain.WarningSecurityLabelsLost,
+		fmt.Sprintf("moving %s to another filesystem loses its %s (%s)", info.Path, labels, reason))
+}
@@ -0,0 +1,70 @@
+package engine
+
+import "github.com/a4abhishek/fileops/pkg/domain"
+
+// settingString reads a string custom setting, returning def when missing
+func settingString(config domain.OperationConfig, key, def string) string {
+	if value, ok := config.CustomSettings[key].(string); ok && value != "" {
+		return value
+	}
+	return def
+}
+
+// settingInt reads an integer custom setting, returning def when missing or non-positive
+func settingInt(config domain.OperationConfig, key string, def int) int {
+	switch value := config.CustomSettings[key].(type) {
+	case int:
+		if value > 0 {
+			return value
+		}
+	case int64:
+		if value > 0 {
+			return int(value)
+		}
+	case float64:
+		if value > 0 {
+			return int(value)
+		}
+	}
+	return def
+}
+
+// settingNumber reads an integer custom setting that may be zero or negative
+// (e.g. a uid), also accepting the float64 produced by a JSON round trip
+func settingNumber(config domain.OperationConfig, key string, def int) int {
+	switch value := config.CustomSettings[key].(type) {
+	case int:
+		return value
+	case int64:
+		return int(value)
+	case float64:
+		return int(value)
+	}
+	return def
+}
+
+// settingBool reads a boolean custom setting, returning def when missing
+func settingBool(config domain.OperationConfig, key string, def bool) bool {
+	if value, ok := config.CustomSettings[key].(bool); ok {
+		return value
+	}
+	return def
+}
+
+// settingStrings reads a string list custom setting, also accepting the
+// []interface{} produced by a JSON round trip
+func settingStrings(config domain.OperationConfig, key string) []string {
+	switch value := config.CustomSettings[key].(type) {
+	case []string:
+		return value
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if str, ok := item.(string); ok {
+				values = append(values, str)
+			}
+		}
+		return values
+	}
+	return nil
+}
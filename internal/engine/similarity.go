@@ -0,0 +1,619 @@
+package engine
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/a4abhishek/fileops/internal/thumbnail"
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// errOutsideReview is returned for decisions on images below none of the
+// reviewed paths
+var errOutsideReview = errors.New("outside the reviewed paths, not removed")
+
+// defaultImageSimilarity is the share of equal fingerprint bits from which
+// two images count as similar
+const defaultImageSimilarity = 0.85
+
+// SimilarityDecisions are the choices made while reviewing similarity
+// groups, e.g. in the HTML gallery: per group the image to keep and the
+// images to remove
+type SimilarityDecisions struct {
+	Operation string               `json:"operation,omitempty"` // Operation ID of the reviewed groups
+	Roots     []string             `json:"roots"`               // Roots the groups were found under
+	Decisions []SimilarityDecision `json:"decisions"`
+}
+
+// SimilarityDecision is the choice made for one similarity group
+type SimilarityDecision struct {
+	Group  string   `json:"group"`
+	Keep   string   `json:"keep"`
+	Remove []string `json:"remove"`
+}
+
+// LoadSimilarityDecisions reads a decisions file
+func LoadSimilarityDecisions(path string) (*SimilarityDecisions, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var decisions SimilarityDecisions
+	if err := json.Unmarshal(data, &decisions); err != nil {
+		return nil, fmt.Errorf("invalid decisions file %s: %w", path, err)
+	}
+	if len(decisions.Roots) == 0 {
+		return nil, fmt.Errorf("invalid decisions file %s: no roots", path)
+	}
+	for _, decision := range decisions.Decisions {
+		for _, path := range decision.Remove {
+			if path == decision.Keep {
+				return nil, fmt.Errorf("invalid decisions file: %s is both kept and removed", path)
+			}
+		}
+	}
+	return &decisions, nil
+}
+
+// SimilarityFactory creates image similarity operations
+type SimilarityFactory struct {
+	engine *Engine
+}
+
+// Create creates a new similarity operation
+func (sf *SimilarityFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewSimilarityOperation(id, config, sf.engine), nil
+}
+
+// Validate validates the similarity configuration
+func (sf *SimilarityFactory) Validate(config domain.OperationConfig) error {
+	if len(config.TargetPaths) == 0 {
+		return fmt.Errorf("at least one path is required")
+	}
+	if config.SimilarityThreshold < 0 || config.SimilarityThreshold > 1 {
+		return fmt.Errorf("similarity threshold must be between 0.0 and 1.0")
+	}
+	if path := settingString(config, "decisions", ""); path != "" {
+		if _, err := LoadSimilarityDecisions(path); err != nil {
+			return err
+		}
+	}
+	detector, err := sf.engine.SimilarityDetector(settingString(config, "method", DefaultSimilarityMethod))
+	if err != nil {
+		return err
+	}
+	if external, ok := detector.(ExternalDetector); ok && settingString(config, "decisions", "") == "" {
+		if err := external.Available(); err != nil {
+			return err
+		}
+	}
+	if settingBool(config, "remove_others", false) && settingString(config, "decisions", "") != "" {
+		return fmt.Errorf("a decisions file decides which files to remove; removing all but the best file of each group cannot be combined with it")
+	}
+	return nil
+}
+
+// SimilarityOperation groups similar files, by default visually similar
+// images (re-encoded, resized or slightly edited copies) by their difference
+// hash; the method setting picks another SimilarityDetector. With a decisions
+// file it instead applies the review of earlier groups: the images to remove
+// are deleted, or moved to the destination setting. With remove_others all
+// but the first file of each group, the best one, are removed that way.
+type SimilarityOperation struct {
+	*BaseOperation
+	detector    SimilarityDetector
+	unsupported *pathList
+	removed     *pathList
+	photos      *companions
+	mu          sync.Mutex
+}
+
+// NewSimilarityOperation creates a new similarity operation
+func NewSimilarityOperation(id string, config domain.OperationConfig, engine *Engine) *SimilarityOperation {
+	base := NewBaseOperation(id, domain.OperationSimilarity, config, engine)
+	return &SimilarityOperation{
+		BaseOperation: base,
+		unsupported:   base.newPathList("unsupported"),
+		removed:       base.newPathList("removed"),
+		photos:        newCompanions(),
+	}
+}
+
+// similarImage is a fingerprinted file, mostly an image
+type similarImage struct {
+	info        *domain.FileInfo
+	fingerprint Fingerprint
+	pixels      int     // Resolution of images, 0 for other files
+	quality     float64 // Quality the detector ranks the file by, see QualityRanker
+	raw         bool    // The JPEG half of a RAW+JPEG pair
+}
+
+// Execute finds the similarity groups, or applies decisions
+func (so *SimilarityOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	if path := settingString(config, "decisions", ""); path != "" {
+		return so.applyDecisions(ctx, config, path)
+	}
+
+	removeOthers := settingBool(config, "remove_others", false)
+	steps := 3
+	if removeOthers {
+		steps++
+	}
+	tracker := so.engine.progressTracker.StartOperation(so.id, domain.OperationSimilarity, steps)
+	so.SetTracker(tracker)
+
+	method := settingString(config, "method", DefaultSimilarityMethod)
+	detector, err := so.engine.SimilarityDetector(method)
+	if err != nil {
+		return nil, err
+	}
+	so.detector = detector
+
+	threshold := config.SimilarityThreshold
+	if threshold <= 0 {
+		threshold = defaultImageSimilarity
+		if suggested, ok := detector.(DefaultSimilarity); ok {
+			threshold = suggested.DefaultSimilarity()
+		}
+	}
+	// Files of the formats asked for are tried, and reported when the
+	// method cannot read them; otherwise the method picks its files
+	formats := settingStrings(config, "formats")
+
+	so.UpdateStep("Scanning files")
+
+	images := make([]*similarImage, 0)
+	var totalSize int64
+	for _, root := range config.TargetPaths {
+		err := so.Walk(ctx, root, config, func(path string, info *domain.FileInfo, err error) error {
+			if err != nil {
+				so.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+				return nil
+			}
+			if info == nil {
+				return nil
+			}
+			if info.IsDir {
+				if path != root && (!config.Recursive || so.isExcluded(path, config)) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if so.isExcluded(path, config) || !hasFormat(path, formats) {
+				return nil
+			}
+			if len(formats) == 0 && !detector.Accepts(path) {
+				return nil
+			}
+			images = append(images, &similarImage{info: info})
+			totalSize += info.Size
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk path %s: %w", root, err)
+		}
+	}
+
+	so.UpdateStep("Fingerprinting files")
+	so.SetProgress(0, int64(len(images)), 0, totalSize)
+	if err := so.fingerprint(ctx, config, images); err != nil {
+		return nil, err
+	}
+
+	so.UpdateStep("Grouping similar files")
+	fingerprinted := make([]*similarImage, 0, len(images))
+	for _, image := range images {
+		if image.fingerprint != nil {
+			fingerprinted = append(fingerprinted, image)
+		}
+	}
+	groups := so.group(fingerprinted, threshold, method)
+
+	var reviewable int64
+	for _, group := range groups {
+		for _, file := range group.Files[1:] {
+			reviewable += file.Size
+		}
+	}
+
+	details := map[string]interface{}{
+		"groups":          groups,
+		"similar_groups":  len(groups),
+		"images":          len(fingerprinted),
+		"unsupported":     so.unsupported.Result(),
+		"reviewable_size": reviewable,
+		"threshold":       threshold,
+		"method":          method,
+		"total_size":      totalSize,
+		"dry_run":         config.DryRun,
+	}
+	noun := "images"
+	if method != DefaultSimilarityMethod {
+		noun = "files"
+	}
+	summary := fmt.Sprintf("Similar %s: %d groups among %d %s, %d unsupported",
+		noun, len(groups), len(fingerprinted), noun, so.unsupported.Len())
+
+	if removeOthers {
+		decisions := &SimilarityDecisions{Operation: so.id, Roots: config.TargetPaths}
+		for _, group := range groups {
+			decision := SimilarityDecision{Group: group.ID, Keep: group.Files[0].Path}
+			for _, file := range group.Files[1:] {
+				decision.Remove = append(decision.Remove, file.Path)
+			}
+			decisions.Decisions = append(decisions.Decisions, decision)
+		}
+		kept, reclaimed, err := so.removeRejected(ctx, config, decisions)
+		if err != nil {
+			return nil, err
+		}
+		details["removed"] = so.removed.Result()
+		details["kept"] = kept
+		details["reclaimed"] = reclaimed
+		details["destination"] = settingString(config, "destination", "")
+		if config.DryRun {
+			summary += fmt.Sprintf(", %d %s would be removed", so.removed.Len(), noun)
+		} else {
+			summary += fmt.Sprintf(", %d %s removed", so.removed.Len(), noun)
+		}
+	}
+	return so.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// fingerprint computes the fingerprint and resolution of every image in
+// parallel. Formats the standard library cannot decode are reported as
+// unsupported.
+func (so *SimilarityOperation) fingerprint(ctx context.Context, config domain.OperationConfig, images []*similarImage) error {
+	workers := config.Parallelism
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	gate := so.workerGate(workers)
+	jobs := make(chan *similarImage)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for image := range jobs {
+				gate.Enter(runCtx)
+				so.fingerprintOne(runCtx, image)
+				gate.Leave()
+				if so.ErrorBudgetErr() != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, image := range images {
+		if err := so.CheckContext(runCtx); err != nil {
+			break
+		}
+		select {
+		case jobs <- image:
+		case <-runCtx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := so.ErrorBudgetErr(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// fingerprintOne fingerprints a file with the detector and records the
+// resolution of images, which decides the image kept of a group
+func (so *SimilarityOperation) fingerprintOne(ctx context.Context, image *similarImage) {
+	defer so.IncrementProgress(1, image.info.Size)
+
+	path := image.info.Path
+	fingerprint, err := so.detector.Fingerprint(ctx, path)
+	if errors.Is(err, ErrUnsupportedFormat) {
+		so.unsupported.Add(path)
+		return
+	}
+	if err != nil {
+		so.AddFileError(path, fmt.Errorf("failed to fingerprint %s: %w", path, err))
+		return
+	}
+	width, height, err := thumbnail.Dimensions(path)
+	if err != nil && !errors.Is(err, thumbnail.ErrUnsupported) {
+		so.AddFileError(path, fmt.Errorf("failed to read the size of %s: %w", path, err))
+		return
+	}
+
+	so.mu.Lock()
+	defer so.mu.Unlock()
+	image.fingerprint = fingerprint
+	image.pixels = width * height
+	image.info.Hash = hex.EncodeToString(fingerprint)
+	image.info.HashType = settingString(so.config, "method", DefaultSimilarityMethod)
+	if err == nil {
+		image.info.Metadata = map[string]string{
+			"width":  strconv.Itoa(width),
+			"height": strconv.Itoa(height),
+		}
+	}
+	if ranker, ok := so.detector.(QualityRanker); ok {
+		image.quality = ranker.Quality(image.info, fingerprint)
+	}
+	if describer, ok := so.detector.(SimilarityDescriber); ok {
+		for key, value := range describer.Describe(image.info, fingerprint) {
+			if image.info.Metadata == nil {
+				image.info.Metadata = make(map[string]string)
+			}
+			image.info.Metadata[key] = value
+		}
+	}
+	if raw, ok := so.photos.sibling(path); ok {
+		image.raw = true
+		image.info.Metadata["raw"] = raw
+	}
+}
+
+// group joins files whose fingerprints are at least threshold similar,
+// directly or through other files of the group. Each group starts with the
+// file to keep by default: an image with a RAW sibling, then the best quality
+// the detector ranks, then the largest resolution, then the largest file,
+// then the oldest.
+func (so *SimilarityOperation) group(images []*similarImage, threshold float64, method string) []domain.SimilarityGroup {
+	sort.Slice(images, func(i, j int) bool { return images[i].info.Path < images[j].info.Path })
+
+	parent := make([]int, len(images))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i := range images {
+		for j := i + 1; j < len(images); j++ {
+			if so.similarity(images[i].fingerprint, images[j].fingerprint) >= threshold {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	members := make(map[int][]*similarImage)
+	roots := make([]int, 0)
+	for i, image := range images {
+		root := find(i)
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], image)
+	}
+
+	groups := make([]domain.SimilarityGroup, 0)
+	for _, root := range roots {
+		group := members[root]
+		if len(group) < 2 {
+			continue
+		}
+		sort.SliceStable(group, func(i, j int) bool {
+			a, b := group[i], group[j]
+			if a.raw != b.raw {
+				return a.raw
+			}
+			if a.quality != b.quality {
+				return a.quality > b.quality
+			}
+			if a.pixels != b.pixels {
+				return a.pixels > b.pixels
+			}
+			if a.info.Size != b.info.Size {
+				return a.info.Size > b.info.Size
+			}
+			return a.info.ModTime.Before(b.info.ModTime)
+		})
+
+		similarity := 1.0
+		files := make([]domain.FileInfo, 0, len(group))
+		for _, image := range group {
+			similarity = min(similarity, so.similarity(group[0].fingerprint, image.fingerprint))
+			files = append(files, *image.info)
+		}
+		id := fmt.Sprintf("group-%d", len(groups)+1)
+		if so.config.Deterministic {
+			id = groupID(hex.EncodeToString(group[0].fingerprint))
+		}
+		groups = append(groups, domain.SimilarityGroup{
+			ID:         id,
+			Files:      files,
+			Similarity: similarity,
+			Method:     method,
+		})
+	}
+	return groups
+}
+
+// similarity is 1 - the distance of two fingerprints, clamped to 0..1
+func (so *SimilarityOperation) similarity(a, b Fingerprint) float64 {
+	return min(max(1-so.detector.Distance(a, b), 0), 1)
+}
+
+// applyDecisions removes the images a review decided against, together with
+// their RAW sibling. An image is only removed while the image kept in its
+// place still exists, and only below the roots that were reviewed.
+func (so *SimilarityOperation) applyDecisions(ctx context.Context, config domain.OperationConfig, path string) (*domain.OperationResult, error) {
+	tracker := so.engine.progressTracker.StartOperation(so.id, domain.OperationSimilarity, 2)
+	so.SetTracker(tracker)
+
+	decisions, err := LoadSimilarityDecisions(path)
+	if err != nil {
+		return nil, err
+	}
+	destination := settingString(config, "destination", "")
+
+	kept, reclaimed, err := so.removeRejected(ctx, config, decisions)
+	if err != nil {
+		return nil, err
+	}
+
+	so.UpdateStep("Completed")
+
+	details := map[string]interface{}{
+		"decisions":   path,
+		"removed":     so.removed.Result(),
+		"kept":        kept,
+		"reclaimed":   reclaimed,
+		"destination": destination,
+		"dry_run":     config.DryRun,
+	}
+	verb := "removed"
+	if destination != "" {
+		verb = "moved to " + destination
+	}
+	summary := fmt.Sprintf("Similar images: %d images %s, %d kept", so.removed.Len(), verb, len(kept))
+	if config.DryRun {
+		summary = fmt.Sprintf("Similar images (dry run): %d images would be %s, %d kept", so.removed.Len(), verb, len(kept))
+	}
+	return so.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// removeRejected removes the files decisions reject and returns the files
+// kept in their place and the space reclaimed
+func (so *SimilarityOperation) removeRejected(ctx context.Context, config domain.OperationConfig, decisions *SimilarityDecisions) ([]string, int64, error) {
+	destination := settingString(config, "destination", "")
+
+	total := 0
+	for _, decision := range decisions.Decisions {
+		total += len(decision.Remove)
+	}
+	so.UpdateStep("Applying decisions")
+	so.SetProgress(0, int64(total), 0, 0)
+
+	kept := make([]string, 0)
+	var reclaimed int64
+	for _, decision := range decisions.Decisions {
+		if len(decision.Remove) == 0 {
+			continue
+		}
+		if _, err := os.Stat(decision.Keep); err != nil {
+			so.AddFileError(decision.Keep, fmt.Errorf("%s not applied, the file to keep is gone: %w", decision.Group, err))
+			so.IncrementProgress(int64(len(decision.Remove)), 0)
+			continue
+		}
+		kept = append(kept, decision.Keep)
+
+		for _, remove := range decision.Remove {
+			if err := so.CheckContext(ctx); err != nil {
+				return nil, 0, err
+			}
+			so.IncrementProgress(1, 0)
+			if !withinPrefixes(remove, config.TargetPaths) {
+				so.AddFileError(remove, fmt.Errorf("%s is %w", remove, errOutsideReview))
+				continue
+			}
+			// A RAW+JPEG pair is one photo and goes as a whole, with its
+			// sidecars when they are moved or their deletion was asked for
+			halves := append([]string{remove}, so.photos.group(remove)...)
+			if destination == "" && !config.DeleteSidecars && hasSidecars(halves) {
+				so.AddFileError(remove, fmt.Errorf("%s %w", remove, errSidecarsLeft))
+				continue
+			}
+			// Every half is checked before any goes, so a pair is not split
+			sizes := make([]int64, 0, len(halves))
+			for _, half := range halves {
+				info, err := os.Stat(half)
+				if err != nil {
+					so.AddFileError(half, fmt.Errorf("failed to access %s: %w", half, err))
+					break
+				}
+				sizes = append(sizes, info.Size())
+			}
+			if len(sizes) < len(halves) {
+				continue
+			}
+			for i, half := range halves {
+				if !config.DryRun {
+					if err := so.remove(half, destination, config.TargetPaths); err != nil {
+						so.AddFileError(half, fmt.Errorf("failed to remove %s: %w", half, err))
+						break
+					}
+				}
+				so.removed.Add(half)
+				reclaimed += sizes[i]
+			}
+		}
+	}
+
+	return kept, reclaimed, nil
+}
+
+// remove deletes path, or moves it below destination keeping its place
+// relative to its root
+func (so *SimilarityOperation) remove(path, destination string, roots []string) error {
+	fs := so.engine.fileSystem
+	if destination == "" {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if err := fs.Remove(path); err != nil {
+			return err
+		}
+		so.RecordDeletion(path, info.Size())
+		return nil
+	}
+
+	target := filepath.Join(destination, filepath.Base(path))
+	for _, root := range roots {
+		if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+			target = filepath.Join(destination, filepath.Base(root), rel)
+			break
+		}
+	}
+	if fs.Exists(target) {
+		return fmt.Errorf("%s: %w", target, os.ErrExist)
+	}
+	return so.move(path, target)
+}
+
+// hasFormat reports whether path has one of the formats (extensions
+// without dot), or any format when none are given
+func hasFormat(path string, formats []string) bool {
+	if len(formats) == 0 {
+		return true
+	}
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	for _, format := range formats {
+		if strings.EqualFold(strings.TrimPrefix(format, "."), ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate validates the similarity operation configuration
+func (so *SimilarityOperation) Validate(config domain.OperationConfig) error {
+	return so.ValidateConfig()
+}
+
+// EstimateProgress provides an estimate of the operation's scope
+func (so *SimilarityOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	return so.estimateScope(config, 3)
+}
@@ -0,0 +1,139 @@
+package engine
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+
+	"github.com/a4abhishek/fileops/internal/thumbnail"
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// DefaultSimilarityMethod is the detector similarity runs use unless the
+// method setting names another
+const DefaultSimilarityMethod = "dhash"
+
+// ErrUnsupportedFormat is returned by detectors for files they cannot
+// fingerprint; such files are reported as unsupported rather than failed
+var ErrUnsupportedFormat = errors.New("format not supported by the similarity method")
+
+// Fingerprint is the compact description of a file's content a
+// SimilarityDetector compares, e.g. a perceptual hash or an embedding
+type Fingerprint []byte
+
+// SimilarityDetector finds files with similar content: it fingerprints files
+// and measures how far apart two fingerprints are. Detectors are registered
+// with the engine like operation factories (see RegisterSimilarityDetector),
+// so new methods such as audio fingerprints or embeddings plug into similarity
+// runs without changes to the operation or the command.
+type SimilarityDetector interface {
+	// Accepts reports whether the detector fingerprints files like path,
+	// usually by extension
+	Accepts(path string) bool
+
+	// Fingerprint describes the content of the file at path. Files the
+	// detector cannot read return an error wrapping ErrUnsupportedFormat.
+	Fingerprint(ctx context.Context, path string) (Fingerprint, error)
+
+	// Distance tells how different two fingerprints are, from 0 for the
+	// same content to 1 for unrelated content
+	Distance(a, b Fingerprint) float64
+}
+
+// DefaultSimilarity is implemented by detectors that suggest the similarity
+// (1 - distance) from which files count as similar when the run sets none
+type DefaultSimilarity interface {
+	DefaultSimilarity() float64
+}
+
+// QualityRanker is implemented by detectors that rank similar files by
+// quality, e.g. recordings by format and bitrate. The best file of a group
+// comes first and is the one kept.
+type QualityRanker interface {
+	Quality(file *domain.FileInfo, fingerprint Fingerprint) float64
+}
+
+// SimilarityDescriber is implemented by detectors that describe a file by
+// its fingerprint, e.g. with the duration of a recording. The description is
+// stored in the metadata of the file.
+type SimilarityDescriber interface {
+	Describe(file *domain.FileInfo, fingerprint Fingerprint) map[string]string
+}
+
+// ExternalDetector is implemented by detectors that depend on an external
+// tool; Available fails when it is not installed
+type ExternalDetector interface {
+	Available() error
+}
+
+// RegisterSimilarityDetector registers a similarity method under name
+func (e *Engine) RegisterSimilarityDetector(name string, detector SimilarityDetector) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.detectors[name] = detector
+}
+
+// SimilarityDetector returns the detector registered under name
+func (e *Engine) SimilarityDetector(name string) (SimilarityDetector, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	detector, ok := e.detectors[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown similarity method %q (available: %v)", name, e.similarityMethods())
+	}
+	return detector, nil
+}
+
+// SimilarityMethods lists the names of the registered similarity detectors
+func (e *Engine) SimilarityMethods() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.similarityMethods()
+}
+
+// similarityMethods lists the detector names; e.mu must be held
+func (e *Engine) similarityMethods() []string {
+	names := make([]string, 0, len(e.detectors))
+	for name := range e.detectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// dhashDetector compares images by their 64-bit difference hash, which
+// survives re-encoding, resizing and small edits
+type dhashDetector struct{}
+
+// Accepts reports whether path is an image the standard library decodes
+func (dhashDetector) Accepts(path string) bool {
+	return thumbnail.Fingerprintable(path)
+}
+
+// Fingerprint computes the difference hash of an image
+func (dhashDetector) Fingerprint(ctx context.Context, path string) (Fingerprint, error) {
+	hash, err := thumbnail.Fingerprint(path)
+	if errors.Is(err, thumbnail.ErrUnsupported) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return binary.BigEndian.AppendUint64(nil, hash), nil
+}
+
+// Distance is the share of differing bits
+func (dhashDetector) Distance(a, b Fingerprint) float64 {
+	if len(a) != 8 || len(b) != 8 {
+		return 1
+	}
+	return float64(bits.OnesCount64(binary.BigEndian.Uint64(a)^binary.BigEndian.Uint64(b))) / 64
+}
+
+// DefaultSimilarity is the share of equal bits from which images are similar
+func (dhashDetector) DefaultSimilarity() float64 {
+	return defaultImageSimilarity
+}
@@ -0,0 +1,459 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/archive"
+	"github.com/a4abhishek/fileops/internal/stub"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// inventoryDepth is how many directory levels below a root an inventory
+// keeps the size of
+const inventoryDepth = 3
+
+// Inventory is the stored summary of one scan of a set of roots
+type Inventory struct {
+	Time           time.Time                `json:"time"`
+	Roots          []string                 `json:"roots"`
+	Files          int64                    `json:"files"`
+	Bytes          int64                    `json:"bytes"`
+	DuplicateBytes int64                    `json:"duplicate_bytes"`           // Bytes in extra copies of files with the same content
+	Archives       int64                    `json:"archives,omitempty"`        // Archives whose entries were counted
+	ArchivedFiles  int64                    `json:"archived_files,omitempty"`  // Files inside those archives
+	ArchivedBytes  int64                    `json:"archived_bytes,omitempty"`  // Their uncompressed size
+	Offloaded      int64                    `json:"offloaded,omitempty"`       // Stub files standing for content moved elsewhere
+	OffloadedBytes int64                    `json:"offloaded_bytes,omitempty"` // Size of that content
+	Categories     map[string]CategoryUsage `json:"categories"`
+	Directories    map[string]int64         `json:"directories"` // Bytes below each directory, down to inventoryDepth
+}
+
+// CategoryUsage is what one kind of file takes up. Files inside archives are
+// counted apart, by their uncompressed size, since the archive itself is
+// counted among the files on disk.
+type CategoryUsage struct {
+	Category      string `json:"category,omitempty"`
+	Files         int64  `json:"files"`
+	Bytes         int64  `json:"bytes"`
+	ArchivedFiles int64  `json:"archived_files,omitempty"`
+	ArchivedBytes int64  `json:"archived_bytes,omitempty"`
+}
+
+// SnapshotStats are the totals of one stored inventory
+type SnapshotStats struct {
+	Time           time.Time `json:"time"`
+	Files          int64     `json:"files"`
+	Bytes          int64     `json:"bytes"`
+	DuplicateBytes int64     `json:"duplicate_bytes"`
+	DuplicateRatio float64   `json:"duplicate_ratio"`
+	Archives       int64     `json:"archives,omitempty"`
+	ArchivedFiles  int64     `json:"archived_files,omitempty"`
+	ArchivedBytes  int64     `json:"archived_bytes,omitempty"`
+	Offloaded      int64     `json:"offloaded,omitempty"`
+	OffloadedBytes int64     `json:"offloaded_bytes,omitempty"`
+}
+
+// DirectoryGrowth is how much a directory grew between two inventories
+type DirectoryGrowth struct {
+	Path   string `json:"path"`
+	Before int64  `json:"before"`
+	After  int64  `json:"after"`
+	Delta  int64  `json:"delta"`
+}
+
+// StorageStats aggregates the stored inventories of a set of roots
+type StorageStats struct {
+	Roots      []string          `json:"roots"`
+	Snapshots  []SnapshotStats   `json:"snapshots"` // Oldest first
+	Categories []CategoryUsage   `json:"categories"`
+	Growth     []DirectoryGrowth `json:"growth"`
+	Since      time.Time         `json:"since,omitempty"` // Inventory the growth is measured from
+}
+
+// StatsFactory creates storage statistics operations
+type StatsFactory struct {
+	engine *Engine
+}
+
+// Create creates a new stats operation
+func (sf *StatsFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewStatsOperation(id, config, sf.engine), nil
+}
+
+// Validate validates the stats configuration
+func (sf *StatsFactory) Validate(config domain.OperationConfig) error {
+	if len(config.TargetPaths) == 0 {
+		return fmt.Errorf("at least one path is required")
+	}
+	if settingString(config, "inventory_directory", "") == "" && !settingBool(config, "scan", true) {
+		return fmt.Errorf("an inventory directory is required to report stored inventories")
+	}
+	switch settingString(config, "compare", "first") {
+	case "first", "previous":
+	default:
+		return fmt.Errorf("invalid compare value %q (use first or previous)", settingString(config, "compare", ""))
+	}
+	return nil
+}
+
+// StatsOperation scans the roots into an inventory, stores it next to the
+// earlier inventories of the same roots and reports how storage developed:
+// totals and duplicate ratio per inventory, composition by category and the
+// directories that grew most. With "scan" off only stored inventories are read.
+// With "archives" on the files inside zip and tar archives are counted too,
+// without extracting them. Stub files left for offloaded content are counted
+// apart by the size of that content.
+type StatsOperation struct {
+	*BaseOperation
+}
+
+// NewStatsOperation creates a new stats operation
+func NewStatsOperation(id string, config domain.OperationConfig, engine *Engine) *StatsOperation {
+	return &StatsOperation{BaseOperation: NewBaseOperation(id, domain.OperationStats, config, engine)}
+}
+
+// Execute scans, stores and aggregates the inventories
+func (so *StatsOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	tracker := so.engine.progressTracker.StartOperation(so.id, domain.OperationStats, 3)
+	so.SetTracker(tracker)
+
+	dir := settingString(config, "inventory_directory", "")
+	if dir != "" {
+		dir = inventoryPath(dir, config.TargetPaths)
+	}
+
+	var history []*Inventory
+	if dir != "" {
+		var err error
+		if history, err = loadInventories(dir); err != nil {
+			return nil, fmt.Errorf("failed to read stored inventories: %w", err)
+		}
+	}
+
+	if settingBool(config, "scan", true) {
+		so.UpdateStep("Scanning storage")
+		inventory, err := so.scan(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, inventory)
+		if dir != "" && settingBool(config, "save", true) {
+			if err := saveInventory(dir, inventory); err != nil {
+				so.engine.logger.Warn("Inventory not stored", "directory", dir, "error", err)
+			}
+		}
+	}
+	if len(history) == 0 {
+		return nil, fmt.Errorf("no stored inventories of %v, run stats without --stored first", config.TargetPaths)
+	}
+
+	so.UpdateStep("Aggregating inventories")
+	stats := aggregateInventories(history, settingString(config, "compare", "first"), settingInt(config, "top", 10))
+	stats.Roots = config.TargetPaths
+
+	so.UpdateStep("Completed")
+
+	latest := stats.Snapshots[len(stats.Snapshots)-1]
+	summary := fmt.Sprintf("Stats: %d files, %d bytes, %.1f%% duplicates, %d inventories",
+		latest.Files, latest.Bytes, latest.DuplicateRatio*100, len(stats.Snapshots))
+	if latest.Archives > 0 {
+		summary += fmt.Sprintf(", %d files (%d bytes) inside %d archives", latest.ArchivedFiles, latest.ArchivedBytes, latest.Archives)
+	}
+	if latest.Offloaded > 0 {
+		summary += fmt.Sprintf(", %d files (%d bytes) offloaded", latest.Offloaded, latest.OffloadedBytes)
+	}
+	if len(stats.Snapshots) > 1 {
+		first := stats.Snapshots[0]
+		summary += fmt.Sprintf(", %+d bytes since %s", latest.Bytes-first.Bytes, first.Time.Format("2006-01-02"))
+	}
+
+	details := map[string]interface{}{
+		"stats": stats,
+	}
+	return so.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// scan walks the roots and summarizes them. Files sharing a size are compared
+// by a partial hash of their content to count duplicate bytes; hardlinks are
+// counted once.
+func (so *StatsOperation) scan(ctx context.Context, config domain.OperationConfig) (*Inventory, error) {
+	inventory := &Inventory{
+		Time:        time.Now(),
+		Roots:       config.TargetPaths,
+		Categories:  make(map[string]CategoryUsage),
+		Directories: make(map[string]int64),
+	}
+	detector := filesystem.NewFileTypeDetector()
+	bySize := make(map[int64][]*domain.FileInfo)
+	linked := make(map[[2]uint64]bool)
+	archives := make([]string, 0)
+	listArchives := settingBool(config, "archives", false)
+
+	for _, root := range config.TargetPaths {
+		err := so.Walk(ctx, root, config, func(path string, info *domain.FileInfo, err error) error {
+			if err != nil {
+				so.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+				return nil // Continue walking
+			}
+			if info == nil {
+				return nil
+			}
+			if path != root && so.isExcluded(path, config) {
+				if info.IsDir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir || !os.FileMode(info.Mode).IsRegular() {
+				return nil
+			}
+			if info.Links > 1 && info.Inode != 0 {
+				key := [2]uint64{info.Device, info.Inode}
+				if linked[key] {
+					return nil
+				}
+				linked[key] = true
+			}
+			if s, err := stub.Detect(path, info.Size); err == nil {
+				inventory.Offloaded++
+				inventory.OffloadedBytes += s.Size
+				so.IncrementProgress(1, info.Size)
+				return nil
+			}
+
+			inventory.Files++
+			inventory.Bytes += info.Size
+			category := detector.GetCategory(path)
+			usage := inventory.Categories[category]
+			usage.Files++
+			usage.Bytes += info.Size
+			inventory.Categories[category] = usage
+			for _, dir := range inventoryDirectories(root, path) {
+				inventory.Directories[dir] += info.Size
+			}
+			if info.Size > 0 {
+				bySize[info.Size] = append(bySize[info.Size], info)
+			}
+			if listArchives && archive.Supported(path) {
+				archives = append(archives, path)
+			}
+			so.IncrementProgress(1, info.Size)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk path %s: %w", root, err)
+		}
+	}
+
+	for _, path := range archives {
+		if err := so.CheckContext(ctx); err != nil {
+			return nil, err
+		}
+		if err := so.countArchive(ctx, inventory, detector, path); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			so.AddFileError(path, fmt.Errorf("failed to list archive %s: %w", path, err))
+		}
+	}
+
+	for size, files := range bySize {
+		if len(files) < 2 {
+			continue
+		}
+		if err := so.CheckContext(ctx); err != nil {
+			return nil, err
+		}
+		seen := make(map[uint64]bool, len(files))
+		for _, file := range files {
+			hash, err := partialHash(file.Path, size)
+			if err != nil {
+				so.AddFileError(file.Path, fmt.Errorf("failed to read %s: %w", file.Path, err))
+				continue
+			}
+			if seen[hash] {
+				inventory.DuplicateBytes += size
+			}
+			seen[hash] = true
+		}
+	}
+	return inventory, nil
+}
+
+// countArchive adds the files inside the archive at path to the inventory.
+// They are added only once the whole archive was read, so a damaged archive
+// does not count partially.
+func (so *StatsOperation) countArchive(ctx context.Context, inventory *Inventory, detector *filesystem.FileTypeDetector, path string) error {
+	var entries []*archive.Entry
+	err := archive.Walk(path, func(entry *archive.Entry) error {
+		entries = append(entries, entry)
+		return so.CheckContext(ctx)
+	})
+	if err != nil {
+		return err
+	}
+	inventory.Archives++
+	for _, entry := range entries {
+		inventory.ArchivedFiles++
+		inventory.ArchivedBytes += entry.Size
+		category := detector.GetCategory(entry.Name)
+		usage := inventory.Categories[category]
+		usage.ArchivedFiles++
+		usage.ArchivedBytes += entry.Size
+		inventory.Categories[category] = usage
+	}
+	return nil
+}
+
+// inventoryDirectories returns the directories of path below root whose size
+// an inventory keeps, root included
+func inventoryDirectories(root, path string) []string {
+	rel, err := filepath.Rel(root, filepath.Dir(path))
+	if err != nil {
+		return nil
+	}
+	dirs := []string{root}
+	if rel == "." {
+		return dirs
+	}
+	current := root
+	for i, part := range strings.Split(rel, string(filepath.Separator)) {
+		if i >= inventoryDepth {
+			break
+		}
+		current = filepath.Join(current, part)
+		dirs = append(dirs, current)
+	}
+	return dirs
+}
+
+// aggregateInventories builds the statistics of inventories sorted oldest
+// first. Growth is measured from the first or the previous inventory.
+func aggregateInventories(inventories []*Inventory, compare string, top int) *StorageStats {
+	sort.Slice(inventories, func(i, j int) bool { return inventories[i].Time.Before(inventories[j].Time) })
+	stats := &StorageStats{}
+	for _, inventory := range inventories {
+		snapshot := SnapshotStats{
+			Time:           inventory.Time,
+			Files:          inventory.Files,
+			Bytes:          inventory.Bytes,
+			DuplicateBytes: inventory.DuplicateBytes,
+			Archives:       inventory.Archives,
+			ArchivedFiles:  inventory.ArchivedFiles,
+			ArchivedBytes:  inventory.ArchivedBytes,
+			Offloaded:      inventory.Offloaded,
+			OffloadedBytes: inventory.OffloadedBytes,
+		}
+		if inventory.Bytes > 0 {
+			snapshot.DuplicateRatio = float64(inventory.DuplicateBytes) / float64(inventory.Bytes)
+		}
+		stats.Snapshots = append(stats.Snapshots, snapshot)
+	}
+
+	latest := inventories[len(inventories)-1]
+	for category, usage := range latest.Categories {
+		usage.Category = category
+		stats.Categories = append(stats.Categories, usage)
+	}
+	sort.Slice(stats.Categories, func(i, j int) bool {
+		if stats.Categories[i].Bytes != stats.Categories[j].Bytes {
+			return stats.Categories[i].Bytes > stats.Categories[j].Bytes
+		}
+		return stats.Categories[i].Category < stats.Categories[j].Category
+	})
+
+	if len(inventories) < 2 {
+		return stats
+	}
+	baseline := inventories[0]
+	if compare == "previous" {
+		baseline = inventories[len(inventories)-2]
+	}
+	stats.Since = baseline.Time
+	for dir, after := range latest.Directories {
+		if before := baseline.Directories[dir]; after > before {
+			stats.Growth = append(stats.Growth, DirectoryGrowth{Path: dir, Before: before, After: after, Delta: after - before})
+		}
+	}
+	sort.Slice(stats.Growth, func(i, j int) bool {
+		if stats.Growth[i].Delta != stats.Growth[j].Delta {
+			return stats.Growth[i].Delta > stats.Growth[j].Delta
+		}
+		return stats.Growth[i].Path < stats.Growth[j].Path
+	})
+	if top > 0 && len(stats.Growth) > top {
+		stats.Growth = stats.Growth[:top]
+	}
+	return stats
+}
+
+// inventoryPath returns where the inventories of a set of roots are kept
+func inventoryPath(dir string, roots []string) string {
+	sorted := append([]string(nil), roots...)
+	sort.Strings(sorted)
+	sum := sha256.New()
+	for _, root := range sorted {
+		sum.Write([]byte(root + "\x00"))
+	}
+	return filepath.Join(dir, "inventories", hex.EncodeToString(sum.Sum(nil)[:8]))
+}
+
+// loadInventories reads the inventories stored in dir
+func loadInventories(dir string) ([]*Inventory, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	inventories := make([]*Inventory, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var inventory Inventory
+		if err := json.Unmarshal(data, &inventory); err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		inventories = append(inventories, &inventory)
+	}
+	return inventories, nil
+}
+
+// saveInventory stores inventory in dir, named by its time
+func saveInventory(dir string, inventory *Inventory) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(inventory)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, inventory.Time.UTC().Format("20060102-150405.000000000")+".json")
+	return filesystem.WriteFileAtomic(path, data, 0o644)
+}
+
+// Validate validates the stats operation configuration
+func (so *StatsOperation) Validate(config domain.OperationConfig) error {
+	return so.ValidateConfig()
+}
+
+// EstimateProgress provides an estimate of the operation's scope
+func (so *StatsOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	return so.estimateScope(config, 3)
+}
@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// destinationSettings are the custom settings holding where an operation
+// writes, expanded like the backup directory
+var destinationSettings = []string{"destination", "target"}
+
+// PathContext holds the values available to templated destination and
+// report paths, e.g. /backups/{{.Date}}/{{.Operation}}
+type PathContext struct {
+	Date      string // 2006-01-02
+	Time      string // 150405
+	Operation string // Operation type, e.g. consolidation
+	ID        string // Operation ID
+	Hostname  string
+}
+
+// NewPathContext returns the template values of an operation starting now
+func NewPathContext(operationType domain.OperationType, operationID string) PathContext {
+	now := time.Now()
+	host, _ := os.Hostname()
+	return PathContext{
+		Date:      now.Format("2006-01-02"),
+		Time:      now.Format("150405"),
+		Operation: string(operationType),
+		ID:        operationID,
+		Hostname:  host,
+	}
+}
+
+// ExpandPath resolves the template variables of path. Paths without any are
+// returned unchanged.
+func (c PathContext) ExpandPath(path string) (string, error) {
+	if !strings.Contains(path, "{{") {
+		return path, nil
+	}
+	tmpl, err := template.New("path").Option("missingkey=error").Parse(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid path template %q: %w", path, err)
+	}
+	var expanded strings.Builder
+	if err := tmpl.Execute(&expanded, c); err != nil {
+		return "", fmt.Errorf("invalid path template %q: %w", path, err)
+	}
+	return expanded.String(), nil
+}
+
+// expandDestinations returns config with the templates of its backup
+// directory and destination settings resolved
+func (c PathContext) expandDestinations(config domain.OperationConfig) (domain.OperationConfig, error) {
+	backup, err := c.ExpandPath(config.BackupDirectory)
+	if err != nil {
+		return config, err
+	}
+	config.BackupDirectory = backup
+
+	settings := make(map[string]interface{}, len(config.CustomSettings))
+	for key, value := range config.CustomSettings {
+		settings[key] = value
+	}
+	for _, key := range destinationSettings {
+		value, ok := settings[key].(string)
+		if !ok {
+			continue
+		}
+		if settings[key], err = c.ExpandPath(value); err != nil {
+			return config, err
+		}
+	}
+	if config.CustomSettings != nil {
+		config.CustomSettings = settings
+	}
+	return config, nil
+}
+
+// SetReportPath writes the result of every operation as JSON to path, which
+// may use the variables of PathContext, e.g. reports/{{.ID}}.json
+func (e *Engine) SetReportPath(path string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.reportPath = path
+}
+
+// writeReport writes result to the report path, when one is set
+func (e *Engine) writeReport(paths PathContext, result *domain.OperationResult, deterministic bool) {
+	e.mu.RLock()
+	reportPath := e.reportPath
+	e.mu.RUnlock()
+	if reportPath == "" || result == nil {
+		return
+	}
+
+	path, err := paths.ExpandPath(reportPath)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0o755)
+	}
+	if err == nil {
+		report := result
+		if deterministic {
+			report = reproducible(result)
+		}
+		var data []byte
+		if data, err = json.MarshalIndent(report, "", "  "); err == nil {
+			err = os.WriteFile(path, append(data, '\n'), 0o644)
+		}
+	}
+	if err != nil {
+		e.logger.Warn("Failed to write the operation report", "path", reportPath, "error", err)
+		return
+	}
+	e.logger.Debug("Operation report written", "path", path)
+}
@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// throttleInterval is how often the load of the machine is read
+const throttleInterval = time.Second
+
+// ThrottleLimits are the levels of system load above which operations give
+// up workers, so that they stay unnoticeable on a machine someone works on
+type ThrottleLimits struct {
+	CPUPercent float64 // Share of all CPUs busy, 0 to 100 (0 = not watched)
+	DiskQueue  int64   // I/O requests in flight on the busiest disk (0 = not watched)
+}
+
+// Throttle decides how many of their workers the operations of an engine may
+// run. The share of workers halves while the load exceeds a limit and grows
+// back by a quarter each interval the load stays below half the limits. Every
+// pool keeps at least one worker. Where the load cannot be read nothing is
+// throttled.
+type Throttle struct {
+	limits  ThrottleLimits
+	sample  func() (filesystem.LoadSample, error)
+	mu      sync.Mutex
+	last    filesystem.LoadSample
+	sampled time.Time
+	share   float64
+	failed  bool // The load cannot be read
+}
+
+// NewThrottle creates a throttle for the given limits
+func NewThrottle(limits ThrottleLimits) *Throttle {
+	return &Throttle{limits: limits, sample: filesystem.SystemLoad, share: 1}
+}
+
+// Share returns the share of workers currently allowed, up to 1
+func (t *Throttle) Share() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.failed || time.Since(t.sampled) < throttleInterval {
+		return t.share
+	}
+
+	sample, err := t.sample()
+	if err != nil {
+		t.failed, t.share = true, 1
+		return t.share
+	}
+	earlier, first := t.last, t.sampled.IsZero()
+	t.last, t.sampled = sample, time.Now()
+	if first {
+		return t.share
+	}
+
+	cpu := sample.CPUPercent(earlier)
+	over := (t.limits.CPUPercent > 0 && cpu > t.limits.CPUPercent) ||
+		(t.limits.DiskQueue > 0 && sample.DiskQueue > t.limits.DiskQueue)
+	idle := (t.limits.CPUPercent <= 0 || cpu < t.limits.CPUPercent/2) &&
+		(t.limits.DiskQueue <= 0 || sample.DiskQueue*2 < t.limits.DiskQueue)
+	switch {
+	case over:
+		t.share = math.Max(t.share/2, 0.01)
+	case idle:
+		t.share = math.Min(t.share+0.25, 1)
+	}
+	return t.share
+}
+
+// workerGate is passed by the workers of a pool before each item; it lets
+// through as many as the throttle allows
+type workerGate struct {
+	throttle *Throttle
+	workers  int
+	mu       sync.Mutex
+	active   int
+}
+
+// workerGate returns the gate for a pool of workers, nil (which lets every
+// worker through) when the engine does not throttle
+func (bo *BaseOperation) workerGate(workers int) *workerGate {
+	bo.engine.mu.RLock()
+	throttle := bo.engine.throttle
+	bo.engine.mu.RUnlock()
+	if throttle == nil || workers <= 1 {
+		return nil
+	}
+	return &workerGate{throttle: throttle, workers: workers}
+}
+
+// Enter waits until the worker may take its next item. Once ctx is done it
+// lets the worker through so that it can notice.
+func (g *workerGate) Enter(ctx context.Context) {
+	if g == nil {
+		return
+	}
+	for {
+		allowed := max(1, int(math.Ceil(float64(g.workers)*g.throttle.Share())))
+		g.mu.Lock()
+		if g.active < allowed || ctx.Err() != nil {
+			g.active++
+			g.mu.Unlock()
+			return
+		}
+		g.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(throttleInterval / 4):
+		}
+	}
+}
+
+// Leave is called when the worker finished its item
+func (g *workerGate) Leave() {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	g.active--
+	g.mu.Unlock()
+}
@@ -0,0 +1,256 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/a4abhishek/fileops/internal/thumbnail"
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// ThumbnailFactory creates thumbnail generation operations
+type ThumbnailFactory struct {
+	engine *Engine
+}
+
+// Create creates a new thumbnail operation
+func (tf *ThumbnailFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewThumbnailOperation(id, config, tf.engine), nil
+}
+
+// Validate validates the thumbnail configuration
+func (tf *ThumbnailFactory) Validate(config domain.OperationConfig) error {
+	if len(config.TargetPaths) == 0 {
+		return fmt.Errorf("at least one path is required")
+	}
+	if settingString(config, "cache_directory", "") == "" {
+		return fmt.Errorf("cache_directory parameter is required")
+	}
+	if quality := settingInt(config, "quality", 80); quality > 100 {
+		return fmt.Errorf("quality must be between 1 and 100")
+	}
+	return nil
+}
+
+// ThumbnailOperation renders previews of all images and videos into a cache directory.
+// Runs are incremental: thumbnails whose modification time matches their source are kept.
+type ThumbnailOperation struct {
+	*BaseOperation
+	generated  *pathList
+	upToDate   *pathList
+	failed     *pathList
+	skipped    *pathList
+	thumbnails map[string]string
+	mu         sync.Mutex
+}
+
+// NewThumbnailOperation creates a new thumbnail operation
+func NewThumbnailOperation(id string, config domain.OperationConfig, engine *Engine) *ThumbnailOperation {
+	base := NewBaseOperation(id, domain.OperationThumbnail, config, engine)
+	return &ThumbnailOperation{
+		BaseOperation: base,
+		generated:     base.newPathList("generated"),
+		upToDate:      base.newPathList("up_to_date"),
+		failed:        base.newPathList("failed"),
+		skipped:       base.newPathList("skipped"),
+		thumbnails:    make(map[string]string),
+	}
+}
+
+// Execute generates the missing and outdated thumbnails
+func (to *ThumbnailOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	tracker := to.engine.progressTracker.StartOperation(to.id, domain.OperationThumbnail, 3)
+	to.SetTracker(tracker)
+
+	cacheDir := settingString(config, "cache_directory", "")
+	size := settingInt(config, "size", 256)
+	force := settingBool(config, "force", false)
+	generator := thumbnail.NewGenerator(size, settingInt(config, "quality", 80))
+	if generator.FFmpegPath == "" {
+		to.engine.logger.Warn("ffmpeg not found, videos and non-native image formats are skipped")
+	}
+
+	to.UpdateStep("Scanning media files")
+
+	media, totalSize, err := to.scan(ctx, config, generator)
+	if err != nil {
+		return nil, err
+	}
+
+	to.UpdateStep("Generating thumbnails")
+	to.SetProgress(0, int64(len(media)), 0, totalSize)
+
+	workers := config.Parallelism
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	// Stop handing out work once the context is cancelled or the error budget is used up
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	gate := to.workerGate(workers)
+	jobs := make(chan *domain.FileInfo)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for info := range jobs {
+				gate.Enter(runCtx)
+				to.process(runCtx, generator, info, cacheDir, size, force, config.DryRun)
+				gate.Leave()
+				if to.ErrorBudgetErr() != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, info := range media {
+		if err := to.CheckContext(runCtx); err != nil {
+			break
+		}
+		select {
+		case jobs <- info:
+		case <-runCtx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := to.ErrorBudgetErr(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	to.UpdateStep("Completing thumbnail generation")
+
+	details := map[string]interface{}{
+		"generated":       to.generated.Result(),
+		"up_to_date":      to.upToDate.Result(),
+		"failed":          to.failed.Result(),
+		"skipped":         to.skipped.Result(),
+		"thumbnails":      to.thumbnails,
+		"cache_directory": cacheDir,
+		"size":            size,
+		"dry_run":         config.DryRun,
+	}
+
+	summary := fmt.Sprintf("Thumbnails: %d generated, %d up to date, %d failed, %d unsupported",
+		to.generated.Len(), to.upToDate.Len(), to.failed.Len(), to.skipped.Len())
+	if config.DryRun {
+		summary = fmt.Sprintf("Thumbnails (dry run): %d would be generated, %d up to date, %d unsupported",
+			to.generated.Len(), to.upToDate.Len(), to.skipped.Len())
+	}
+
+	return to.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// scan collects the media files below all roots
+func (to *ThumbnailOperation) scan(ctx context.Context, config domain.OperationConfig, generator *thumbnail.Generator) ([]*domain.FileInfo, int64, error) {
+	cacheDir := settingString(config, "cache_directory", "")
+	media := make([]*domain.FileInfo, 0)
+	var totalSize int64
+
+	for _, root := range config.TargetPaths {
+		err := to.Walk(ctx, root, config, func(path string, info *domain.FileInfo, err error) error {
+			if err != nil {
+				to.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+				return nil // Continue walking
+			}
+
+			if info == nil {
+				return nil
+			}
+
+			// Never descend into the cache itself
+			if info.IsDir {
+				if path == cacheDir || to.isExcluded(path, config) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if to.isExcluded(path, config) || !(thumbnail.IsImage(path) || thumbnail.IsVideo(path)) {
+				return nil
+			}
+
+			if !generator.Supports(path) {
+				to.skipped.Add(path)
+				return nil
+			}
+
+			media = append(media, info)
+			totalSize += info.Size
+			return nil
+		})
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to walk path %s: %w", root, err)
+		}
+	}
+
+	return media, totalSize, nil
+}
+
+// process renders the thumbnail of a single file unless it is up to date
+func (to *ThumbnailOperation) process(ctx context.Context, generator *thumbnail.Generator, info *domain.FileInfo, cacheDir string, size int, force, dryRun bool) {
+	target := thumbnail.CachePath(cacheDir, info.Path, size)
+
+	if !force && thumbnail.UpToDate(info.Path, target) {
+		to.record(info, target, nil, false)
+		return
+	}
+
+	if dryRun {
+		to.record(info, "", nil, true)
+		return
+	}
+	err := generator.Generate(ctx, info.Path, target)
+	if ctx.Err() != nil {
+		err = ctx.Err() // Interrupted, not a broken file
+	}
+	to.record(info, target, err, true)
+}
+
+// record stores the outcome for a single file (an empty target means nothing was written)
+func (to *ThumbnailOperation) record(info *domain.FileInfo, target string, err error, rendered bool) {
+	to.mu.Lock()
+	defer to.mu.Unlock()
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return
+	case err != nil:
+		// Corrupt or unreadable media must not stop the run
+		to.AddFileError(info.Path, fmt.Errorf("failed to render %s: %w", info.Path, err))
+		to.failed.Add(info.Path)
+	case rendered:
+		to.generated.Add(info.Path)
+		if target != "" {
+			to.thumbnails[info.Path] = target
+		}
+	default:
+		to.upToDate.Add(info.Path)
+		to.thumbnails[info.Path] = target
+	}
+	to.IncrementProgress(1, info.Size)
+}
+
+// Validate validates the thumbnail operation configuration
+func (to *ThumbnailOperation) Validate(config domain.OperationConfig) error {
+	return to.ValidateConfig()
+}
+
+// EstimateProgress provides an estimate of the operation's scope
+func (to *ThumbnailOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	return to.estimateScope(config, 3)
+}
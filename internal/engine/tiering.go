@@ -0,0 +1,673 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/stub"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// TierManifestFile is the file in the cold tier recording where each file
+// moved there came from, which is how files are restored
+const TierManifestFile = ".fileops-tier.json"
+
+// What tiering leaves in place of a file moved to the cold tier
+const (
+	TierLeaveNone    = "none"    // Nothing; restore through the manifest
+	TierLeaveSymlink = "symlink" // A symlink to the moved file
+	TierLeaveStub    = "stub"    // A stub file naming the moved file and its hash
+)
+
+// Tiers of a tiering composition report
+const (
+	TierHot  = "hot"
+	TierCold = "cold"
+)
+
+// tierManifestInterval is how often the manifest is written while files
+// are moved; originals are only replaced once it records their copies
+const tierManifestInterval = 10 * time.Second
+
+// errTierConflict marks files changed in the hot tier since they were moved
+var errTierConflict = errors.New("changed since it was moved to the cold tier")
+
+// TieringFactory creates storage tiering operations
+type TieringFactory struct {
+	engine *Engine
+}
+
+// Create creates a new tiering operation
+func (tf *TieringFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewTieringOperation(id, config, tf.engine), nil
+}
+
+// Validate validates the tiering configuration
+func (tf *TieringFactory) Validate(config domain.OperationConfig) error {
+	if len(config.TargetPaths) == 0 {
+		return fmt.Errorf("at least one path is required")
+	}
+	destination := settingString(config, "destination", "")
+	if destination == "" {
+		return fmt.Errorf("destination parameter is required")
+	}
+	restore := settingBool(config, "restore", false)
+	if !restore && len(config.TargetPaths) != 1 {
+		return fmt.Errorf("exactly one hot root is required")
+	}
+	for _, path := range config.TargetPaths {
+		inside := path
+		if restore {
+			inside = filepath.Dir(path) // A path to restore may be a symlink into the cold tier
+		}
+		if withinPrefixes(destination, []string{path}) || withinPrefixes(inside, []string{destination}) {
+			return fmt.Errorf("hot tier %s and cold tier %s must not contain each other", path, destination)
+		}
+	}
+	switch settingString(config, "leave", TierLeaveSymlink) {
+	case TierLeaveNone, TierLeaveSymlink, TierLeaveStub:
+	default:
+		return fmt.Errorf("unsupported leave mode: %s (use none, symlink or stub)", config.CustomSettings["leave"])
+	}
+	if settingInt(config, "older_than", 90) < 0 {
+		return fmt.Errorf("older_than must not be negative")
+	}
+	return nil
+}
+
+// tierEntry is a file of the cold tier in the manifest
+type tierEntry struct {
+	Origin  string    `json:"origin"` // Path in the hot tier
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Sum     string    `json:"sum"`
+	Leave   string    `json:"leave"`
+	Moved   time.Time `json:"moved"`
+}
+
+// tierManifest lists the files of the cold tier by their path relative to it
+type tierManifest struct {
+	Algorithm string                `json:"algorithm"`
+	Files     map[string]*tierEntry `json:"files"`
+}
+
+// tierPending is a file copied to the cold tier whose original is replaced
+// once the manifest recording the copy has been written
+type tierPending struct {
+	info   *domain.FileInfo
+	target string
+	sum    string
+}
+
+// TierUsage is what a tier holds, by category
+type TierUsage struct {
+	Tier       string          `json:"tier"`
+	Files      int64           `json:"files"`
+	Bytes      int64           `json:"bytes"`
+	Categories []CategoryUsage `json:"categories"`
+}
+
+// TieringOperation moves files untouched (neither modified nor read) for
+// older_than days from a fast hot tier to a slow cold tier, keeping their
+// relative paths. Each file is copied, verified by checksum and recorded in
+// the TierManifestFile of the cold tier before the original is replaced by a
+// symlink, a stub file or nothing (leave). With restore, the files of the
+// manifest that came from the given paths are moved back. Both report what
+// each tier holds afterwards.
+type TieringOperation struct {
+	*BaseOperation
+	moved     *pathList
+	restored  *pathList
+	recent    *pathList // Old files read within the period
+	movedSize int64
+	mu        sync.Mutex
+}
+
+// NewTieringOperation creates a new tiering operation
+func NewTieringOperation(id string, config domain.OperationConfig, engine *Engine) *TieringOperation {
+	base := NewBaseOperation(id, domain.OperationTiering, config, engine)
+	return &TieringOperation{
+		BaseOperation: base,
+		moved:         base.newPathList("moved"),
+		restored:      base.newPathList("restored"),
+		recent:        base.newPathList("recently_read"),
+	}
+}
+
+// Execute moves old files to the cold tier, or restores them
+func (to *TieringOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	tracker := to.engine.progressTracker.StartOperation(to.id, domain.OperationTiering, 3)
+	to.SetTracker(tracker)
+
+	destination := settingString(config, "destination", "")
+	leave := settingString(config, "leave", TierLeaveSymlink)
+	restore := settingBool(config, "restore", false)
+	algorithm := config.HashAlgorithm
+	if algorithm == "" {
+		algorithm = "blake2b" // Default
+	}
+
+	manifest, err := loadTierManifest(destination, algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	var hot []*domain.FileInfo
+	var restoring map[string]bool
+	if restore {
+		to.UpdateStep("Restoring from the cold tier")
+		if restoring, err = to.restore(ctx, manifest, destination, config); err != nil {
+			to.saveManifest(destination, manifest, config)
+			return nil, err
+		}
+	} else {
+		to.UpdateStep("Scanning hot tier")
+		root := config.TargetPaths[0]
+		olderThan := time.Duration(settingInt(config, "older_than", 90)) * 24 * time.Hour
+		candidates, kept, err := to.scan(ctx, root, time.Now().Add(-olderThan), config)
+		if err != nil {
+			return nil, err
+		}
+		hot = kept
+
+		to.UpdateStep("Moving to the cold tier")
+		moved, err := to.demote(ctx, candidates, manifest, root, destination, leave, config)
+		hot = append(hot, moved...) // Still in the hot tier
+		if err != nil {
+			to.saveManifest(destination, manifest, config)
+			return nil, err
+		}
+	}
+	to.saveManifest(destination, manifest, config)
+
+	to.UpdateStep("Summarizing tiers")
+	details := map[string]interface{}{
+		"moved":         to.moved.Result(),
+		"restored":      to.restored.Result(),
+		"recently_read": to.recent.Result(),
+		"moved_size":    to.movedSize,
+		"destination":   destination,
+		"leave":         leave,
+		"restore":       restore,
+		"algorithm":     algorithm,
+		"dry_run":       config.DryRun,
+	}
+	tiers := []TierUsage{tierComposition(TierCold, manifest.files(restoring))}
+	if !restore {
+		tiers = append([]TierUsage{tierComposition(TierHot, hot)}, tiers...)
+	}
+	details["tiers"] = tiers
+
+	verb, count := "moved to the cold tier", to.moved.Len()
+	if restore {
+		verb, count = "restored", to.restored.Len()
+	}
+	summary := fmt.Sprintf("Tiering: %d files %s", count, verb)
+	if config.DryRun {
+		summary = fmt.Sprintf("Tiering (dry run): %d files would be %s", count, verb)
+	}
+	if !restore && to.recent.Len() > 0 {
+		summary += fmt.Sprintf(", %d old files kept as they were read recently", to.recent.Len())
+	}
+
+	return to.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// scan splits the regular files of the hot root into those untouched since
+// cutoff and the others. Symlinks and stubs left by earlier runs are skipped.
+func (to *TieringOperation) scan(ctx context.Context, root string, cutoff time.Time, config domain.OperationConfig) (candidates, kept []*domain.FileInfo, err error) {
+	err = to.Walk(ctx, root, config, func(path string, info *domain.FileInfo, err error) error {
+		if err != nil {
+			to.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+			return nil // Continue walking
+		}
+		if info == nil || info.IsDir || !fs.FileMode(info.Mode).IsRegular() || stub.Is(path, info.Size) {
+			return nil
+		}
+		if to.isExcluded(path, config) || info.ModTime.After(cutoff) {
+			kept = append(kept, info)
+			return nil
+		}
+		if stat, err := os.Stat(path); err == nil {
+			if accessed, ok := filesystem.AccessTime(stat); ok && accessed.After(cutoff) {
+				to.recent.Add(path)
+				kept = append(kept, info)
+				return nil
+			}
+		}
+		candidates = append(candidates, info)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to walk path %s: %w", root, err)
+	}
+	return candidates, kept, nil
+}
+
+// demote moves the candidates to the cold tier and returns those that could
+// not be moved
+func (to *TieringOperation) demote(ctx context.Context, candidates []*domain.FileInfo, manifest *tierManifest, root, destination, leave string, config domain.OperationConfig) ([]*domain.FileInfo, error) {
+	var totalSize int64
+	for _, info := range candidates {
+		totalSize += info.Size
+	}
+	to.SetProgress(0, int64(len(candidates)), 0, totalSize)
+
+	failed := make([]*domain.FileInfo, 0)
+	pending := make([]tierPending, 0)
+	saved := time.Now()
+	for i, info := range candidates {
+		if err := to.CheckContext(ctx); err != nil {
+			failed = append(failed, to.replaceOriginals(pending, manifest, destination, leave)...)
+			return append(failed, candidates[i:]...), err
+		}
+		rel, err := filepath.Rel(root, info.Path)
+		if err != nil {
+			failed = append(failed, info)
+			continue
+		}
+		if config.DryRun {
+			manifest.Files[filepath.ToSlash(rel)] = &tierEntry{Origin: info.Path, Size: info.Size, ModTime: info.ModTime}
+			// The copy is read back and compared with the original before that goes
+			target := filepath.Join(destination, rel)
+			to.planTransfer(info.Path, target, info.Size, false)
+			to.planRead(info.Path, info.Size)
+			to.planRead(target, info.Size)
+			to.planFree(info.Path, info.Size)
+			to.moved.Add(info.Path)
+			to.movedSize += info.Size
+			to.IncrementProgress(1, info.Size)
+			continue
+		}
+
+		target, sum, err := to.demoteFile(info, rel, manifest, destination, leave)
+		if err != nil {
+			to.AddFileError(info.Path, err)
+			failed = append(failed, info)
+			to.IncrementProgress(1, 0)
+		} else {
+			// Originals are replaced, and counted as moved, once the manifest is written
+			pending = append(pending, tierPending{info: info, target: target, sum: sum})
+			if time.Since(saved) > tierManifestInterval {
+				failed = append(failed, to.replaceOriginals(pending, manifest, destination, leave)...)
+				pending = pending[:0]
+				saved = time.Now()
+			}
+		}
+		if budgetErr := to.ErrorBudgetErr(); budgetErr != nil {
+			failed = append(failed, to.replaceOriginals(pending, manifest, destination, leave)...)
+			return append(failed, candidates[i+1:]...), budgetErr
+		}
+	}
+	return append(failed, to.replaceOriginals(pending, manifest, destination, leave)...), nil
+}
+
+// demoteFile copies a file to the cold tier, verifies the copy and records
+// it in the manifest, returning the copy and its checksum. Whatever is at
+// the target already is left alone unless the manifest records it as an
+// earlier copy of this very file.
+func (to *TieringOperation) demoteFile(info *domain.FileInfo, rel string, manifest *tierManifest, destination, leave string) (string, string, error) {
+	key := filepath.ToSlash(rel)
+	target := filepath.Join(destination, rel)
+	entry := manifest.Files[key]
+	if entry != nil && entry.Origin != info.Path {
+		return "", "", fmt.Errorf("%s already holds %s: %w", target, entry.Origin, os.ErrExist)
+	}
+	if existing, err := os.Lstat(target); err == nil {
+		same := entry != nil && existing.Mode().IsRegular() &&
+			existing.Size() == entry.Size && existing.ModTime().Equal(entry.ModTime) &&
+			info.Size == entry.Size && info.ModTime.Equal(entry.ModTime)
+		if !same {
+			return "", "", fmt.Errorf("%s already exists and is not a cold copy of %s: %w", target, info.Path, os.ErrExist)
+		}
+	} else if !os.IsNotExist(err) {
+		return "", "", err
+	}
+
+	if err := to.engine.fileSystem.CreateDir(filepath.Dir(target)); err != nil {
+		return "", "", err
+	}
+	// Copied under a temporary name so an interrupted copy never sits at the target
+	tmp := target + ".fileops-tier"
+	if err := to.copyWarning(tmp, to.engine.fileSystem.Copy(info.Path, tmp)); err != nil {
+		_ = os.Remove(tmp)
+		return "", "", fmt.Errorf("failed to copy %s: %w", info.Path, err)
+	}
+	if err := os.Chtimes(tmp, time.Now(), info.ModTime); err != nil {
+		_ = os.Remove(tmp)
+		return "", "", err
+	}
+
+	sums, err := filesystem.HashFile(info.Path, manifest.Algorithm)
+	if err != nil {
+		_ = os.Remove(tmp)
+		return "", "", fmt.Errorf("failed to hash %s: %w", info.Path, err)
+	}
+	copied, err := filesystem.HashFile(tmp, manifest.Algorithm)
+	if err != nil {
+		_ = os.Remove(tmp)
+		return "", "", fmt.Errorf("failed to hash %s: %w", tmp, err)
+	}
+	if sums[0] != copied[0] {
+		_ = os.Remove(tmp)
+		return "", "", fmt.Errorf("copy %s differs from %s; the original was kept", target, info.Path)
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		_ = os.Remove(tmp)
+		return "", "", err
+	}
+
+	to.mu.Lock()
+	manifest.Files[key] = &tierEntry{Origin: info.Path, Size: info.Size, ModTime: info.ModTime, Sum: sums[0], Leave: leave, Moved: time.Now()}
+	to.mu.Unlock()
+	return target, sums[0], nil
+}
+
+// replaceOriginals writes the manifest and then replaces the originals of
+// the pending files with what leave asks for, so a crash cannot lose track of
+// a file. It returns the files whose originals could not be replaced; when
+// the manifest cannot be written, that is all of them.
+func (to *TieringOperation) replaceOriginals(pending []tierPending, manifest *tierManifest, destination, leave string) []*domain.FileInfo {
+	if len(pending) == 0 {
+		return nil
+	}
+	to.mu.Lock()
+	err := to.writeManifest(destination, manifest)
+	to.mu.Unlock()
+
+	failed := make([]*domain.FileInfo, 0)
+	if err != nil {
+		for _, p := range pending {
+			to.AddFileError(p.info.Path, fmt.Errorf("copied %s to %s but kept the original, as the tier manifest could not be saved: %w", p.info.Path, p.target, err))
+			failed = append(failed, p.info)
+			to.IncrementProgress(1, 0)
+		}
+		return failed
+	}
+	for _, p := range pending {
+		var err error
+		switch leave {
+		case TierLeaveSymlink:
+			err = replaceWithSymlink(p.info.Path, p.target)
+		case TierLeaveStub:
+			err = stub.Write(p.info.Path, stub.Stub{Location: p.target, Size: p.info.Size, ModTime: p.info.ModTime, Algorithm: manifest.Algorithm, Hash: p.sum})
+		default:
+			err = to.engine.fileSystem.Remove(p.info.Path)
+		}
+		if err != nil {
+			to.AddFileError(p.info.Path, fmt.Errorf("moved %s to %s but failed to replace the original: %w", p.info.Path, p.target, err))
+			failed = append(failed, p.info)
+			to.IncrementProgress(1, 0)
+			continue
+		}
+		to.RecordMove(p.info.Path, p.target)
+		to.moved.Add(p.info.Path)
+		to.movedSize += p.info.Size
+		to.IncrementProgress(1, p.info.Size)
+	}
+	return failed
+}
+
+// restore moves the files of the manifest that came from the target paths
+// back to where they were. It returns the manifest keys of the files a dry
+// run would restore.
+func (to *TieringOperation) restore(ctx context.Context, manifest *tierManifest, destination string, config domain.OperationConfig) (map[string]bool, error) {
+	keys := make([]string, 0)
+	restoring := make(map[string]bool)
+	var totalSize int64
+	only := make(map[string]bool, len(config.OnlyPaths)) // Retries of some files
+	for _, path := range config.OnlyPaths {
+		only[path] = true
+	}
+	for key, entry := range manifest.Files {
+		if tieredFrom(entry.Origin, config.TargetPaths) && (len(only) == 0 || only[entry.Origin]) {
+			keys = append(keys, key)
+			totalSize += entry.Size
+		}
+	}
+	sort.Strings(keys)
+	to.SetProgress(0, int64(len(keys)), 0, totalSize)
+
+	sources := make([]string, 0) // Cold copies to remove once the manifest no longer lists them
+	defer func() { to.removeColdCopies(sources, manifest, destination) }()
+	saved := time.Now()
+	for _, key := range keys {
+		if err := to.CheckContext(ctx); err != nil {
+			return restoring, err
+		}
+		entry := manifest.Files[key]
+		source := filepath.Join(destination, filepath.FromSlash(key))
+		if !config.DryRun {
+			if err := to.restoreFile(entry, source, manifest.Algorithm); err != nil {
+				to.AddFileError(entry.Origin, err)
+				to.IncrementProgress(1, 0)
+				if budgetErr := to.ErrorBudgetErr(); budgetErr != nil {
+					return restoring, budgetErr
+				}
+				continue
+			}
+			to.mu.Lock()
+			delete(manifest.Files, key)
+			to.mu.Unlock()
+			sources = append(sources, source)
+			if time.Since(saved) > tierManifestInterval {
+				to.removeColdCopies(sources, manifest, destination)
+				sources = sources[:0]
+				saved = time.Now()
+			}
+		} else {
+			restoring[key] = true
+			// The copy is read back and checked before the cold copy goes
+			to.planTransfer(source, entry.Origin, entry.Size, false)
+			to.planRead(entry.Origin, entry.Size)
+			to.planFree(source, entry.Size)
+		}
+		to.restored.Add(entry.Origin)
+		to.movedSize += entry.Size
+		to.IncrementProgress(1, entry.Size)
+	}
+	return restoring, nil
+}
+
+// restoreFile copies a file of the cold tier back over what was left in its
+// place; the cold copy is removed by removeColdCopies. A file changed in the
+// hot tier since is not overwritten.
+func (to *TieringOperation) restoreFile(entry *tierEntry, source, algorithm string) error {
+	if info, err := os.Lstat(entry.Origin); err == nil {
+		left := false
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			link, _ := os.Readlink(entry.Origin)
+			left = link == source
+		case info.Mode().IsRegular():
+			s, err := stub.Read(entry.Origin)
+			left = err == nil && s.Location == source
+		}
+		if !left {
+			return fmt.Errorf("%s %w; the cold copy stays at %s: %w", entry.Origin, errTierConflict, source, os.ErrExist)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := to.engine.fileSystem.CreateDir(filepath.Dir(entry.Origin)); err != nil {
+		return err
+	}
+	tmp := entry.Origin + ".fileops-restore"
+	if err := to.copyWarning(tmp, to.engine.fileSystem.Copy(source, tmp)); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to copy %s: %w", source, err)
+	}
+	if err := os.Chtimes(tmp, time.Now(), entry.ModTime); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if entry.Sum != "" {
+		sums, err := filesystem.HashFile(tmp, algorithm)
+		if err != nil || sums[0] != entry.Sum {
+			_ = os.Remove(tmp)
+			return fmt.Errorf("the cold copy %s no longer matches its checksum; nothing was restored", source)
+		}
+	}
+	if err := os.Rename(tmp, entry.Origin); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	to.RecordMove(source, entry.Origin)
+	return nil
+}
+
+// removeColdCopies writes the manifest and then removes the cold copies of
+// restored files, so a crash cannot leave the manifest listing copies that
+// are gone. When the manifest cannot be written, the copies are kept.
+func (to *TieringOperation) removeColdCopies(sources []string, manifest *tierManifest, destination string) {
+	if len(sources) == 0 {
+		return
+	}
+	to.mu.Lock()
+	err := to.writeManifest(destination, manifest)
+	to.mu.Unlock()
+	if err != nil {
+		for _, source := range sources {
+			to.AddFileError(source, fmt.Errorf("restored the file but kept the cold copy %s, as the tier manifest could not be saved: %w", source, err))
+		}
+		return
+	}
+	for _, source := range sources {
+		if err := to.engine.fileSystem.Remove(source); err != nil {
+			to.AddFileError(source, fmt.Errorf("restored the file but failed to remove the cold copy %s: %w", source, err))
+		}
+	}
+}
+
+// tieredFrom reports whether origin is one of paths or lies below one. What
+// is left at origin is not resolved, as a symlink leads to the cold tier.
+func tieredFrom(origin string, paths []string) bool {
+	for _, path := range paths {
+		if filepath.Clean(path) == origin {
+			return true
+		}
+	}
+	return withinPrefixes(filepath.Dir(origin), paths)
+}
+
+// replaceWithSymlink atomically replaces path with a symlink to target
+func replaceWithSymlink(path, target string) error {
+	tmp := path + ".fileops-link"
+	_ = os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// files returns the files of the manifest except those of skip, by key
+func (m *tierManifest) files(skip map[string]bool) []*domain.FileInfo {
+	files := make([]*domain.FileInfo, 0, len(m.Files))
+	for key, entry := range m.Files {
+		if skip[key] {
+			continue
+		}
+		files = append(files, &domain.FileInfo{Path: filepath.FromSlash(key), Size: entry.Size})
+	}
+	return files
+}
+
+// tierComposition sums files by category
+func tierComposition(tier string, files []*domain.FileInfo) TierUsage {
+	detector := filesystem.NewFileTypeDetector()
+	usage := TierUsage{Tier: tier, Categories: make([]CategoryUsage, 0)}
+	byCategory := make(map[string]*CategoryUsage)
+	for _, info := range files {
+		category := detector.GetCategory(info.Path)
+		entry := byCategory[category]
+		if entry == nil {
+			entry = &CategoryUsage{Category: category}
+			byCategory[category] = entry
+		}
+		entry.Files++
+		entry.Bytes += info.Size
+		usage.Files++
+		usage.Bytes += info.Size
+	}
+	for _, entry := range byCategory {
+		usage.Categories = append(usage.Categories, *entry)
+	}
+	sort.Slice(usage.Categories, func(i, j int) bool {
+		if usage.Categories[i].Bytes != usage.Categories[j].Bytes {
+			return usage.Categories[i].Bytes > usage.Categories[j].Bytes
+		}
+		return usage.Categories[i].Category < usage.Categories[j].Category
+	})
+	return usage
+}
+
+// loadTierManifest reads the manifest of the cold tier. A manifest written
+// with another checksum algorithm keeps its own.
+func loadTierManifest(destination, algorithm string) (*tierManifest, error) {
+	manifest := &tierManifest{Algorithm: algorithm, Files: make(map[string]*tierEntry)}
+	data, err := os.ReadFile(filepath.Join(destination, TierManifestFile))
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("unreadable tier manifest %s: %w", filepath.Join(destination, TierManifestFile), err)
+	}
+	if manifest.Files == nil {
+		manifest.Files = make(map[string]*tierEntry)
+	}
+	return manifest, nil
+}
+
+// saveManifest writes the manifest unless this is a dry run
+func (to *TieringOperation) saveManifest(destination string, manifest *tierManifest, config domain.OperationConfig) {
+	if config.DryRun {
+		return
+	}
+	to.mu.Lock()
+	defer to.mu.Unlock()
+	if err := to.writeManifest(destination, manifest); err != nil {
+		to.AddFileError(destination, err)
+	}
+}
+
+// writeManifest replaces the manifest file; to.mu must be held
+func (to *TieringOperation) writeManifest(destination string, manifest *tierManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(destination, 0o755)
+	}
+	if err == nil {
+		err = filesystem.WriteFileAtomic(filepath.Join(destination, TierManifestFile), data, 0o644)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save tier manifest: %w", err)
+	}
+	return nil
+}
+
+// Validate validates the tiering operation configuration
+func (to *TieringOperation) Validate(config domain.OperationConfig) error {
+	return to.ValidateConfig()
+}
+
+// EstimateProgress provides an estimate of the operation's scope
+func (to *TieringOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	return to.estimateScope(config, 3)
+}
@@ -0,0 +1,383 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/transcode"
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// DefaultTranscodeFormats are the legacy video formats transcoded by default
+var DefaultTranscodeFormats = []string{"avi", "wmv", "mpg", "mpeg", "flv", "3gp", "vob", "divx"}
+
+// defaultDurationTolerance is how far the duration of a transcoded video may
+// differ from its source's
+const defaultDurationTolerance = time.Second
+
+// TranscodeFactory creates video transcoding operations
+type TranscodeFactory struct {
+	engine *Engine
+}
+
+// Create creates a new transcoding operation
+func (tf *TranscodeFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewTranscodeOperation(id, config, tf.engine), nil
+}
+
+// Validate validates the transcoding configuration
+func (tf *TranscodeFactory) Validate(config domain.OperationConfig) error {
+	if len(config.TargetPaths) == 0 {
+		return fmt.Errorf("at least one path is required")
+	}
+	if _, err := transcode.ParseArguments(transcodeArguments(config)); err != nil {
+		return err
+	}
+	if _, err := time.ParseDuration(settingString(config, "tolerance", defaultDurationTolerance.String())); err != nil {
+		return fmt.Errorf("invalid duration tolerance: %w", err)
+	}
+	if ext := settingString(config, "extension", "mp4"); ext == "" || strings.ContainsAny(ext, `/\`) {
+		return fmt.Errorf("invalid output extension %q", ext)
+	}
+	return nil
+}
+
+// transcodeArguments returns the configured ffmpeg argument templates
+func transcodeArguments(config domain.OperationConfig) []string {
+	if args := settingStrings(config, "arguments"); len(args) > 0 {
+		return args
+	}
+	return transcode.DefaultArguments
+}
+
+// TranscodeOperation hands videos matching the filters to ffmpeg. Each
+// output is written under a temporary name, checked to play as long as its
+// source and only then put in place; with replace the source is removed
+// afterwards.
+type TranscodeOperation struct {
+	*BaseOperation
+	transcoded *pathList
+	skipped    *pathList // Videos whose codec is not transcoded
+	replaced   *pathList
+	sourceSize int64
+	outputSize int64
+}
+
+// transcodeJob is one video to transcode
+type transcodeJob struct {
+	root string
+	info *domain.FileInfo
+}
+
+// NewTranscodeOperation creates a new transcoding operation
+func NewTranscodeOperation(id string, config domain.OperationConfig, engine *Engine) *TranscodeOperation {
+	base := NewBaseOperation(id, domain.OperationTranscode, config, engine)
+	return &TranscodeOperation{
+		BaseOperation: base,
+		transcoded:    base.newPathList("transcoded"),
+		skipped:       base.newPathList("skipped"),
+		replaced:      base.newPathList("replaced"),
+	}
+}
+
+// Execute transcodes the matching videos below the configured paths
+func (tc *TranscodeOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	tracker := tc.engine.progressTracker.StartOperation(tc.id, domain.OperationTranscode, 3)
+	tc.SetTracker(tracker)
+
+	formats := settingStrings(config, "formats")
+	if len(formats) == 0 {
+		formats = DefaultTranscodeFormats
+	}
+	codecs := settingStrings(config, "codecs")
+	// Dry runs only need ffprobe to filter by codec
+	transcoder, err := transcode.New()
+	if err != nil && (!config.DryRun || len(codecs) > 0) {
+		return nil, err
+	}
+	args, err := transcode.ParseArguments(transcodeArguments(config))
+	if err != nil {
+		return nil, err
+	}
+	extension := strings.TrimPrefix(settingString(config, "extension", "mp4"), ".")
+	destination := settingString(config, "destination", "")
+	replace := settingBool(config, "replace", false)
+	tolerance, _ := time.ParseDuration(settingString(config, "tolerance", defaultDurationTolerance.String()))
+
+	tc.UpdateStep("Scanning videos")
+
+	jobs := make([]transcodeJob, 0)
+	var totalSize int64
+	for _, root := range config.TargetPaths {
+		err := tc.Walk(ctx, root, config, func(path string, info *domain.FileInfo, err error) error {
+			if err != nil {
+				tc.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+				return nil // Continue walking
+			}
+			if info == nil {
+				return nil
+			}
+			if tc.isExcluded(path, config) {
+				if info.IsDir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.IsDir && hasFormat(path, formats) {
+				jobs = append(jobs, transcodeJob{root: root, info: info})
+				totalSize += info.Size
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk path %s: %w", root, err)
+		}
+	}
+
+	tc.UpdateStep("Transcoding videos")
+	tc.SetProgress(0, int64(len(jobs)), 0, totalSize)
+
+	for _, job := range jobs {
+		if err := tc.CheckContext(ctx); err != nil {
+			return nil, err
+		}
+		source := job.info.Path
+
+		if len(codecs) > 0 {
+			codec, err := transcoder.Codec(ctx, source)
+			if err != nil {
+				tc.AddFileError(source, fmt.Errorf("failed to probe %s: %w", source, err))
+				tc.IncrementProgress(1, job.info.Size)
+				continue
+			}
+			if !containsFold(codecs, codec) {
+				tc.skipped.Add(source)
+				tc.IncrementProgress(1, job.info.Size)
+				continue
+			}
+		}
+
+		target := tc.target(job, destination, extension, replace)
+		if target != source && tc.engine.fileSystem.Exists(target) {
+			tc.AddFileError(source, fmt.Errorf("failed to transcode %s: %s: %w", source, target, os.ErrExist))
+			tc.IncrementProgress(1, job.info.Size)
+			continue
+		}
+
+		if config.DryRun {
+			tc.transcoded.Add(source)
+			tc.sourceSize += job.info.Size
+			tc.engine.logger.Debug("Would transcode video", "source", source, "target", target)
+			tc.IncrementProgress(1, job.info.Size)
+			continue
+		}
+
+		size, err := tc.transcode(ctx, transcoder, args, job, target, tolerance, replace, config)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			tc.AddFileError(source, fmt.Errorf("failed to transcode %s: %w", source, err))
+			continue
+		}
+		tc.transcoded.Add(source)
+		tc.sourceSize += job.info.Size
+		tc.outputSize += size
+		if replace {
+			tc.replaced.Add(source)
+		}
+		tc.engine.logger.Info("Transcoded video", "source", source, "target", target)
+	}
+
+	tc.UpdateStep("Completing transcoding")
+
+	details := map[string]interface{}{
+		"transcoded":  tc.transcoded.Result(),
+		"skipped":     tc.skipped.Result(),
+		"replaced":    tc.replaced.Result(),
+		"source_size": tc.sourceSize,
+		"output_size": tc.outputSize,
+		"formats":     formats,
+		"codecs":      codecs,
+		"arguments":   transcodeArguments(config),
+		"dry_run":     config.DryRun,
+	}
+
+	summary := fmt.Sprintf("Transcoding: %d videos transcoded, %d sources replaced, %d skipped by codec",
+		tc.transcoded.Len(), tc.replaced.Len(), tc.skipped.Len())
+	if config.DryRun {
+		summary = fmt.Sprintf("Transcoding (dry run): %d videos would be transcoded, %d skipped by codec",
+			tc.transcoded.Len(), tc.skipped.Len())
+	}
+
+	return tc.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// target returns where the transcoded video goes: next to its source or
+// below destination at the same place relative to its root. A video that
+// keeps its extension is replaced in place, or gets a .transcoded name.
+func (tc *TranscodeOperation) target(job transcodeJob, destination, extension string, replace bool) string {
+	source := job.info.Path
+	dir := filepath.Dir(source)
+	if destination != "" {
+		dir = destination
+		if rel, err := filepath.Rel(job.root, filepath.Dir(source)); err == nil && !strings.HasPrefix(rel, "..") {
+			dir = filepath.Join(destination, filepath.Base(job.root), rel)
+		}
+	}
+	stem := strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))
+	target := filepath.Join(dir, stem+"."+extension)
+	if target == source && !replace {
+		target = filepath.Join(dir, stem+".transcoded."+extension)
+	}
+	return target
+}
+
+// transcode runs ffmpeg into a temporary file next to target, checks its
+// duration and moves it into place; with replace the source is removed only
+// once the output is in place. It returns the size of the output.
+func (tc *TranscodeOperation) transcode(ctx context.Context, transcoder *transcode.Transcoder, args transcode.Arguments, job transcodeJob, target string, tolerance time.Duration, replace bool, config domain.OperationConfig) (int64, error) {
+	source := job.info.Path
+	duration, err := transcoder.Duration(ctx, source)
+	if err != nil {
+		tc.IncrementProgress(1, job.info.Size)
+		return 0, err
+	}
+
+	if err := tc.engine.fileSystem.CreateDir(filepath.Dir(target)); err != nil {
+		tc.IncrementProgress(1, job.info.Size)
+		return 0, err
+	}
+	// Keep the extension, ffmpeg picks the container by it
+	temp := filepath.Join(filepath.Dir(target), "."+strings.TrimSuffix(filepath.Base(target), filepath.Ext(target))+".transcoding"+filepath.Ext(target))
+	defer os.Remove(temp)
+
+	expanded, err := args.Expand(transcode.NewVariables(source, temp))
+	if err != nil {
+		tc.IncrementProgress(1, job.info.Size)
+		return 0, err
+	}
+
+	// Report the bytes of the source that correspond to the position reached
+	var reported int64
+	err = transcoder.Run(ctx, expanded, func(position time.Duration) {
+		if duration <= 0 {
+			return
+		}
+		done := min(int64(float64(job.info.Size)*position.Seconds()/duration.Seconds()), job.info.Size)
+		if done > reported {
+			tc.IncrementProgress(0, done-reported)
+			reported = done
+		}
+	})
+	tc.IncrementProgress(1, job.info.Size-reported)
+	if err != nil {
+		return 0, err
+	}
+
+	transcoded, err := transcoder.Duration(ctx, temp)
+	if err != nil {
+		return 0, err
+	}
+	if difference := time.Duration(math.Abs(float64(transcoded - duration))); difference > tolerance {
+		return 0, fmt.Errorf("%w: %s instead of %s", transcode.ErrDurationMismatch,
+			transcoded.Round(time.Millisecond), duration.Round(time.Millisecond))
+	}
+
+	info, err := tc.engine.fileSystem.Stat(temp)
+	if err != nil {
+		return 0, err
+	}
+	if replace && target == source {
+		if err := tc.replaceSource(job, temp, config); err != nil {
+			return 0, err
+		}
+		return info.Size, nil
+	}
+	if err := tc.engine.fileSystem.Move(temp, target); err != nil {
+		return 0, err
+	}
+	if replace {
+		if err := tc.removeSource(job, config); err != nil {
+			return 0, fmt.Errorf("transcoded to %s but failed to remove the source: %w", target, err)
+		}
+	}
+	return info.Size, nil
+}
+
+// replaceSource puts the output over a source of the same name. Without
+// backups it is renamed over the source in one step; with them the source is
+// moved to the backup directory first and moved back if that fails.
+func (tc *TranscodeOperation) replaceSource(job transcodeJob, temp string, config domain.OperationConfig) error {
+	source := job.info.Path
+	backup, ok := tc.backupTarget(job, config)
+	if !ok {
+		if err := tc.engine.fileSystem.Move(temp, source); err != nil {
+			return err
+		}
+		tc.RecordDeletion(source, job.info.Size)
+		return nil
+	}
+	if err := tc.move(source, backup); err != nil {
+		return fmt.Errorf("failed to back up the source: %w", err)
+	}
+	if err := tc.engine.fileSystem.Move(temp, source); err != nil {
+		if restoreErr := tc.move(backup, source); restoreErr != nil {
+			return fmt.Errorf("%w; the source stays at %s: %v", err, backup, restoreErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// removeSource deletes a transcoded source, or moves it to the backup
+// directory when backups are enabled
+func (tc *TranscodeOperation) removeSource(job transcodeJob, config domain.OperationConfig) error {
+	source := job.info.Path
+	if backup, ok := tc.backupTarget(job, config); ok {
+		return tc.move(source, backup)
+	}
+	if err := tc.engine.fileSystem.Remove(source); err != nil {
+		return err
+	}
+	tc.RecordDeletion(source, job.info.Size)
+	return nil
+}
+
+// backupTarget returns where the source of job is backed up, if backups are
+// enabled
+func (tc *TranscodeOperation) backupTarget(job transcodeJob, config domain.OperationConfig) (string, bool) {
+	if !config.BackupBeforeDelete || config.BackupDirectory == "" {
+		return "", false
+	}
+	target := filepath.Join(config.BackupDirectory, filepath.Base(job.root))
+	if rel, err := filepath.Rel(job.root, job.info.Path); err == nil {
+		target = filepath.Join(target, rel)
+	}
+	return target, true
+}
+
+// containsFold reports whether values contains value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate validates the transcoding operation configuration
+func (tc *TranscodeOperation) Validate(config domain.OperationConfig) error {
+	return tc.ValidateConfig()
+}
+
+// EstimateProgress provides an estimate of the operation's scope
+func (tc *TranscodeOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	return tc.estimateScope(config, 3)
+}
@@ -0,0 +1,346 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+	"github.com/a4abhishek/fileops/pkg/schema"
+)
+
+// Triage buckets
+const (
+	TriageKeep    = "keep"
+	TriageArchive = "archive"
+	TriageDelete  = "delete"
+)
+
+// Default ages of the triage rules
+const (
+	defaultArchiveAfterDays   = 90
+	defaultInstallerAfterDays = 30
+)
+
+// installerExtensions are packages that are usually installed once and then dead weight
+var installerExtensions = map[string]bool{
+	".dmg": true, ".pkg": true, ".exe": true, ".msi": true, ".deb": true,
+	".rpm": true, ".appimage": true, ".apk": true, ".flatpakref": true,
+}
+
+// archiveExtensions are compressed files that are commonly extracted next to themselves
+var archiveExtensions = []string{".tar.gz", ".tar.xz", ".tar.bz2", ".tar.zst", ".tgz", ".zip", ".7z", ".rar", ".tar"}
+
+// TriageItem is a top-level entry of the triaged folder with the suggested bucket
+type TriageItem struct {
+	Path     string    `json:"path"`
+	IsDir    bool      `json:"is_dir"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"mod_time"`
+	Category string    `json:"category"`
+	Bucket   string    `json:"bucket"`
+	Reason   string    `json:"reason"`
+}
+
+// TriageFactory creates download folder triage operations
+type TriageFactory struct {
+	engine *Engine
+}
+
+// Create creates a new triage operation
+func (tf *TriageFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewTriageOperation(id, config, tf.engine), nil
+}
+
+// Validate validates the triage configuration
+func (tf *TriageFactory) Validate(config domain.OperationConfig) error {
+	if len(config.TargetPaths) != 1 {
+		return fmt.Errorf("exactly one folder is required")
+	}
+	if settingString(config, "destination", "") == "" {
+		return fmt.Errorf("destination parameter is required")
+	}
+	for path, bucket := range triageDecisions(config) {
+		if bucket != TriageKeep && bucket != TriageArchive && bucket != TriageDelete {
+			return fmt.Errorf("invalid bucket %q for %s", bucket, path)
+		}
+	}
+	return nil
+}
+
+// TriageOperation sorts the entries of a downloads folder into keep, archive
+// and delete buckets by type and age. Without a dry run it carries out the
+// suggestions, or only the reviewed decisions in the "decisions" setting.
+type TriageOperation struct {
+	*BaseOperation
+	plan     []TriageItem
+	archived *pathList
+	deleted  *pathList
+	freed    int64
+}
+
+// NewTriageOperation creates a new triage operation
+func NewTriageOperation(id string, config domain.OperationConfig, engine *Engine) *TriageOperation {
+	base := NewBaseOperation(id, domain.OperationTriage, config, engine)
+	return &TriageOperation{
+		BaseOperation: base,
+		plan:          make([]TriageItem, 0),
+		archived:      base.newPathList("archived"),
+		deleted:       base.newPathList("deleted"),
+	}
+}
+
+// Execute classifies the folder and applies the approved actions
+func (to *TriageOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	tracker := to.engine.progressTracker.StartOperation(to.id, domain.OperationTriage, 3)
+	to.SetTracker(tracker)
+
+	root := config.TargetPaths[0]
+	destination := settingString(config, "destination", "")
+
+	to.UpdateStep("Classifying files")
+
+	if err := to.classify(ctx, root, destination, config); err != nil {
+		return nil, err
+	}
+
+	to.UpdateStep("Applying actions")
+	to.SetProgress(0, int64(len(to.plan)), 0, 0)
+
+	decisions := triageDecisions(config)
+	counts := make(map[string]int)
+	for _, item := range to.plan {
+		if err := to.CheckContext(ctx); err != nil {
+			return nil, err
+		}
+
+		bucket := item.Bucket
+		if decisions != nil {
+			// Reviewed runs only act on what was approved
+			bucket = decisions[item.Path]
+			if bucket == "" {
+				bucket = TriageKeep
+			}
+		}
+		counts[bucket]++
+
+		if !config.DryRun && bucket != TriageKeep {
+			to.apply(item, bucket, root, destination, config)
+		}
+		to.IncrementProgress(1, item.Size)
+	}
+
+	to.UpdateStep("Completed")
+
+	details := map[string]interface{}{
+		"plan":        to.plan,
+		"archived":    to.archived.Result(),
+		"deleted":     to.deleted.Result(),
+		"bytes_freed": to.freed,
+		"destination": destination,
+		"dry_run":     config.DryRun,
+	}
+
+	summary := fmt.Sprintf("Triage: %d archived, %d deleted, %d kept",
+		to.archived.Len(), to.deleted.Len(), counts[TriageKeep])
+	if config.DryRun {
+		summary = fmt.Sprintf("Triage (dry run): %d to keep, %d to archive, %d to delete",
+			counts[TriageKeep], counts[TriageArchive], counts[TriageDelete])
+	}
+
+	return to.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// classify suggests a bucket for every top-level entry of root. Directories
+// are triaged as a whole, by their total size and newest modification.
+func (to *TriageOperation) classify(ctx context.Context, root, destination string, config domain.OperationConfig) error {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", root, err)
+	}
+
+	names := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		names[entry.Name()] = entry.IsDir()
+	}
+
+	detector := filesystem.NewFileTypeDetector()
+	now := time.Now()
+	archiveAfter := settingInt(config, "archive_after_days", defaultArchiveAfterDays)
+	installerAfter := settingInt(config, "installer_after_days", defaultInstallerAfterDays)
+
+	for _, entry := range entries {
+		if err := to.CheckContext(ctx); err != nil {
+			return err
+		}
+		path := filepath.Join(root, entry.Name())
+		if path == destination || strings.HasPrefix(entry.Name(), ".") || to.isExcluded(path, config) ||
+			!to.engine.gitAllows(path, entry.IsDir(), config.GitFilter) {
+			continue
+		}
+
+		info, err := to.engine.fileSystem.Stat(path)
+		if err != nil {
+			to.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+			continue
+		}
+		item := TriageItem{Path: path, IsDir: info.IsDir, Size: info.Size, ModTime: info.ModTime, Category: "folders"}
+		if info.IsDir {
+			item.Size, item.ModTime = to.measureDir(ctx, path)
+		} else {
+			item.Category = detector.GetCategory(path)
+			if installerExtensions[strings.ToLower(filepath.Ext(path))] {
+				item.Category = "installers"
+			}
+		}
+
+		days := int(now.Sub(item.ModTime).Hours() / 24)
+		lower := strings.ToLower(entry.Name())
+		switch {
+		case !item.IsDir && isPartialDownloadName(entry.Name()):
+			if days >= defaultPartialAgeDays {
+				item.Bucket, item.Reason = TriageDelete, fmt.Sprintf("unfinished download, untouched for %d days", days)
+			} else {
+				item.Bucket, item.Reason = TriageKeep, "download may still be in progress"
+			}
+		case item.Category == "installers" && days >= installerAfter:
+			item.Bucket, item.Reason = TriageDelete, fmt.Sprintf("installer downloaded %d days ago", days)
+		case !item.IsDir && extractedSibling(lower, names) != "":
+			item.Bucket, item.Reason = TriageDelete, fmt.Sprintf("already extracted to %s/", extractedSibling(lower, names))
+		case days >= archiveAfter:
+			item.Bucket, item.Reason = TriageArchive, fmt.Sprintf("not modified for %d days", days)
+		default:
+			item.Bucket, item.Reason = TriageKeep, fmt.Sprintf("modified %d days ago", days)
+		}
+		to.plan = append(to.plan, item)
+	}
+
+	sort.Slice(to.plan, func(i, j int) bool { return to.plan[i].Path < to.plan[j].Path })
+	return nil
+}
+
+// extractedSibling returns the name of the directory an archive was extracted
+// to, if one with the archive's base name sits next to it
+func extractedSibling(lowerName string, names map[string]bool) string {
+	for _, ext := range archiveExtensions {
+		if !strings.HasSuffix(lowerName, ext) {
+			continue
+		}
+		stem := lowerName[:len(lowerName)-len(ext)]
+		for name, isDir := range names {
+			if isDir && strings.ToLower(name) == stem {
+				return name
+			}
+		}
+		return ""
+	}
+	return ""
+}
+
+// apply archives or deletes an item after checking it did not change since it
+// was classified. Deleted items go to the backup directory when one is set.
+func (to *TriageOperation) apply(item TriageItem, bucket, root, destination string, config domain.OperationConfig) {
+	current, err := to.engine.fileSystem.Stat(item.Path)
+	if err != nil {
+		to.AddFileError(item.Path, fmt.Errorf("failed to check %s: %w", item.Path, err))
+		return
+	}
+	if !current.IsDir && (current.Size != item.Size || !current.ModTime.Equal(item.ModTime)) {
+		to.engine.logger.Info("Skipping file modified during triage", "path", item.Path)
+		return
+	}
+
+	switch bucket {
+	case TriageArchive:
+		target := to.freeTarget(filepath.Join(destination, item.Category, filepath.Base(item.Path)))
+		if err := to.move(item.Path, target); err != nil {
+			to.AddFileError(item.Path, fmt.Errorf("failed to archive %s: %w", item.Path, err))
+			return
+		}
+		to.archived.Add(item.Path)
+		to.engine.logger.Info("Archived file", "source", item.Path, "target", target)
+	case TriageDelete:
+		if config.BackupBeforeDelete && config.BackupDirectory != "" {
+			target := to.freeTarget(filepath.Join(config.BackupDirectory, filepath.Base(root), filepath.Base(item.Path)))
+			err = to.move(item.Path, target)
+		} else if err = to.engine.fileSystem.RemoveAll(item.Path); err == nil {
+			to.RecordDeletion(item.Path, item.Size)
+		}
+		if err != nil {
+			to.AddFileError(item.Path, fmt.Errorf("failed to delete %s: %w", item.Path, err))
+			return
+		}
+		to.deleted.Add(item.Path)
+		to.freed += item.Size
+		to.engine.logger.Info("Deleted file", "path", item.Path)
+	}
+}
+
+// Validate validates the triage operation configuration
+func (to *TriageOperation) Validate(config domain.OperationConfig) error {
+	return to.ValidateConfig()
+}
+
+// EstimateProgress provides an estimate of the operation's scope
+func (to *TriageOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	return to.estimateScope(config, 3)
+}
+
+// TriageReviewPlan turns the suggestions of a triage dry run into a plan for
+// "fileops review": the items to delete, and the items to archive as moves
+// into the archive directory
+func TriageReviewPlan(operationID, root, destination string, items []TriageItem) *ReviewPlan {
+	plan := &ReviewPlan{SchemaVersion: schema.ReviewPlanVersion, Operation: operationID, Created: time.Now(), Roots: []string{root}, Changes: make([]ReviewChange, 0)}
+	taken := make(map[string]bool)
+	for _, item := range items {
+		change := ReviewChange{Path: item.Path, IsDir: item.IsDir, Size: item.Size, ModTime: item.ModTime, Reason: item.Reason}
+		switch item.Bucket {
+		case TriageDelete:
+			change.Action = PlanDelete
+		case TriageArchive:
+			change.Action = PlanMove
+			change.Target = freeTarget(filepath.Join(destination, item.Category, filepath.Base(item.Path)), taken)
+		default:
+			continue
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+	return plan
+}
+
+// freeTarget returns a variant of target (name_1.ext, name_2.ext, ...) that
+// neither exists nor was taken, and takes it
+func freeTarget(target string, taken map[string]bool) string {
+	ext := filepath.Ext(target)
+	base := strings.TrimSuffix(target, ext)
+	candidate := target
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) && !taken[candidate] {
+			taken[candidate] = true
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s_%d%s", base, i, ext)
+	}
+}
+
+// triageDecisions reads the reviewed bucket per path, also accepting the
+// map[string]interface{} produced by a JSON round trip. It is nil when the
+// suggestions are to be applied as they are.
+func triageDecisions(config domain.OperationConfig) map[string]string {
+	switch value := config.CustomSettings["decisions"].(type) {
+	case map[string]string:
+		return value
+	case map[string]interface{}:
+		decisions := make(map[string]string, len(value))
+		for path, bucket := range value {
+			decisions[path], _ = bucket.(string)
+		}
+		return decisions
+	}
+	return nil
+}
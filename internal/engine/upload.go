@@ -0,0 +1,350 @@
+package engine
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"github.com/a4abhishek/fileops/internal/cloud"
+	"github.com/a4abhishek/fileops/internal/stub"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// defaultUploadWorkers is the number of parallel uploads; transfers are
+// bound by the network rather than the CPU
+const defaultUploadWorkers = 4
+
+// UploadFactory creates operations that upload files to cloud storage
+type UploadFactory struct {
+	engine *Engine
+}
+
+// Create creates a new upload operation
+func (uf *UploadFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewUploadOperation(id, config, uf.engine), nil
+}
+
+// Validate validates the upload configuration
+func (uf *UploadFactory) Validate(config domain.OperationConfig) error {
+	if len(config.TargetPaths) == 0 {
+		return fmt.Errorf("at least one path is required")
+	}
+	if settingString(config, "target", "") == "" {
+		return fmt.Errorf("target parameter is required")
+	}
+	if settingBool(config, "remove_source", false) && !settingBool(config, "verify", true) {
+		return fmt.Errorf("remove_source requires verify")
+	}
+	if settingBool(config, "stub", false) && !settingBool(config, "verify", true) {
+		return fmt.Errorf("stub requires verify")
+	}
+	_, _, err := cloud.Open(settingString(config, "target", ""), uploadOptions(config))
+	return err
+}
+
+// UploadOperation uploads files to S3, GCS, Azure Blob storage or an rclone
+// remote. Each path is stored under its base name below the target prefix.
+// Files whose object already holds the same content are skipped, so an
+// interrupted upload resumes where it stopped; when the local files are
+// removed or stubbed, only objects the service reports a matching hash for
+// count, and the others are uploaded again. With "stub" on, uploaded files
+// are replaced by stub files naming their object; stubs are never uploaded.
+type UploadOperation struct {
+	*BaseOperation
+	uploaded   *pathList
+	skipped    *pathList
+	removed    *pathList
+	stubbed    *pathList
+	normalized *pathList // Objects found under the other Unicode normalization of their key
+	bytes      int64
+	mu         sync.Mutex
+}
+
+// NewUploadOperation creates a new upload operation
+func NewUploadOperation(id string, config domain.OperationConfig, engine *Engine) *UploadOperation {
+	base := NewBaseOperation(id, domain.OperationUpload, config, engine)
+	return &UploadOperation{
+		BaseOperation: base,
+		uploaded:      base.newPathList("uploaded"),
+		skipped:       base.newPathList("skipped"),
+		removed:       base.newPathList("removed"),
+		stubbed:       base.newPathList("stubbed"),
+		normalized:    base.newPathList("normalization_differences"),
+	}
+}
+
+// uploadJob is a file to upload with the key it is stored under
+type uploadJob struct {
+	info *domain.FileInfo
+	key  string
+}
+
+// Execute uploads all files below the configured paths
+func (uo *UploadOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	tracker := uo.engine.progressTracker.StartOperation(uo.id, domain.OperationUpload, 3)
+	uo.SetTracker(tracker)
+
+	target := settingString(config, "target", "")
+	backend, prefix, err := cloud.Open(target, uploadOptions(config))
+	if err != nil {
+		return nil, err
+	}
+
+	uo.UpdateStep("Scanning files")
+
+	files, totalSize, err := uo.scan(ctx, config, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	uo.UpdateStep("Uploading files")
+	uo.SetProgress(0, int64(len(files)), 0, totalSize)
+
+	workers := config.Parallelism
+	if workers <= 0 {
+		workers = defaultUploadWorkers
+	}
+
+	// Stop handing out work once the context is cancelled or the error budget is used up
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	gate := uo.workerGate(workers)
+	jobs := make(chan uploadJob)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				gate.Enter(runCtx)
+				uo.upload(runCtx, backend, job, config)
+				gate.Leave()
+				if uo.ErrorBudgetErr() != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, job := range files {
+		if err := uo.CheckContext(runCtx); err != nil {
+			break
+		}
+		select {
+		case jobs <- job:
+		case <-runCtx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := uo.ErrorBudgetErr(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	uo.UpdateStep("Completed")
+
+	details := map[string]interface{}{
+		"target":                    target,
+		"uploaded":                  uo.uploaded.Result(),
+		"skipped":                   uo.skipped.Result(),
+		"removed":                   uo.removed.Result(),
+		"stubbed":                   uo.stubbed.Result(),
+		"normalization_differences": uo.normalized.Result(),
+		"bytes":                     uo.bytes,
+		"dry_run":                   config.DryRun,
+	}
+
+	summary := fmt.Sprintf("Uploaded %d files, %d already up to date, removed %d local files",
+		uo.uploaded.Len(), uo.skipped.Len(), uo.removed.Len())
+	if uo.stubbed.Len() > 0 {
+		summary += fmt.Sprintf(", replaced %d by stubs", uo.stubbed.Len())
+	}
+	if config.DryRun {
+		summary = fmt.Sprintf("Would upload %d files (dry run), %d already up to date", uo.uploaded.Len(), uo.skipped.Len())
+	}
+
+	return uo.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// upload uploads a single file unless the object already matches it, verifies
+// the stored object and removes the local file or replaces it by a stub if
+// configured
+func (uo *UploadOperation) upload(ctx context.Context, backend cloud.Backend, job uploadJob, config domain.OperationConfig) {
+	defer uo.IncrementProgress(1, job.info.Size)
+
+	file, err := filesystem.OpenFile(ctx, job.info.Path, os.O_RDONLY, 0)
+	if err != nil {
+		uo.fail(ctx, job, err)
+		return
+	}
+	defer filesystem.CloseFile(file)
+
+	sums, err := cloud.Checksum(file)
+	if err != nil {
+		uo.fail(ctx, job, err)
+		return
+	}
+
+	object, err := backend.Stat(ctx, job.key)
+	if errors.Is(err, cloud.ErrNotFound) && config.UnicodeNormalization != filesystem.NormalizeNone {
+		// Stored from a system that writes names in the other normalization
+		if alternate := filesystem.Alternate(job.key); alternate != job.key {
+			if other, altErr := backend.Stat(ctx, alternate); altErr == nil {
+				uo.record(uo.normalized, fmt.Sprintf("%s -> %s", job.key, alternate), 0)
+				if other.Matches(sums) {
+					object, err = other, nil
+				}
+			}
+		}
+	}
+	if err != nil && !errors.Is(err, cloud.ErrNotFound) {
+		uo.fail(ctx, job, err)
+		return
+	}
+	// Before the local file goes, an object found in place must match by a
+	// hash the service computed; otherwise it is uploaded again, which the
+	// service checks against the MD5 of every part
+	removing := settingBool(config, "stub", false) || settingBool(config, "remove_source", false)
+	current := object != nil && object.Matches(sums) && (!removing || object.Verified(sums))
+
+	if !current {
+		if config.DryRun {
+			uo.record(uo.uploaded, job.key, 0)
+			return
+		}
+		if err := backend.Upload(ctx, job.key, file, sums); err != nil {
+			uo.fail(ctx, job, err)
+			return
+		}
+		if settingBool(config, "verify", true) {
+			object, err := backend.Stat(ctx, job.key)
+			if err != nil {
+				uo.fail(ctx, job, fmt.Errorf("verification failed: %w", err))
+				return
+			}
+			// Remotes that report no hash are checked by size; their transfer was verified by rclone
+			if !object.Consistent(sums) {
+				// Do not leave a damaged copy that a later run could mistake for a backup
+				if err := backend.Delete(ctx, job.key); err != nil {
+					uo.engine.logger.Warn("Failed to delete mismatched object", "key", job.key, "error", err)
+				}
+				uo.fail(ctx, job, fmt.Errorf("%w: stored object %s differs from %s", cloud.ErrChecksumMismatch, job.key, job.info.Path))
+				return
+			}
+		}
+		uo.record(uo.uploaded, job.key, sums.Size)
+	} else {
+		uo.record(uo.skipped, job.key, 0)
+	}
+
+	if settingBool(config, "stub", false) && !config.DryRun {
+		location := cloud.Location(settingString(config, "target", ""), job.key)
+		s := stub.Stub{Location: location, Size: sums.Size, ModTime: job.info.ModTime, Algorithm: "sha256", Hash: hex.EncodeToString(sums.SHA256)}
+		if err := stub.Write(job.info.Path, s); err != nil {
+			uo.AddFileError(job.info.Path, fmt.Errorf("failed to replace %s by a stub: %w", job.info.Path, err))
+		} else {
+			uo.record(uo.stubbed, job.info.Path, 0)
+			uo.RecordMove(job.info.Path, location)
+		}
+	} else if settingBool(config, "remove_source", false) && !config.DryRun {
+		if err := uo.engine.fileSystem.Remove(job.info.Path); err != nil {
+			uo.AddFileError(job.info.Path, fmt.Errorf("failed to remove %s: %w", job.info.Path, err))
+		} else {
+			uo.record(uo.removed, job.info.Path, 0)
+			uo.RecordDeletion(job.info.Path, job.info.Size)
+		}
+	}
+}
+
+// record appends to one of the result lists
+func (uo *UploadOperation) record(list *pathList, item string, bytes int64) {
+	uo.mu.Lock()
+	defer uo.mu.Unlock()
+	list.Add(item)
+	uo.bytes += bytes
+}
+
+// fail records a failed upload unless the operation was cancelled
+func (uo *UploadOperation) fail(ctx context.Context, job uploadJob, err error) {
+	if ctx.Err() != nil {
+		return // Interrupted, the file is uploaded again by the next run
+	}
+	uo.AddFileError(job.info.Path, fmt.Errorf("failed to upload %s: %w", job.info.Path, err))
+}
+
+// Validate validates the upload operation configuration
+func (uo *UploadOperation) Validate(config domain.OperationConfig) error {
+	return uo.ValidateConfig()
+}
+
+// EstimateProgress provides an estimate of the operation's scope
+func (uo *UploadOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	return uo.estimateScope(config, 3)
+}
+
+// scan collects the regular files below all roots with their object keys
+func (uo *UploadOperation) scan(ctx context.Context, config domain.OperationConfig, prefix string) ([]uploadJob, int64, error) {
+	jobs := make([]uploadJob, 0)
+	var totalSize int64
+	patterns := settingStrings(config, "patterns")
+
+	for _, root := range config.TargetPaths {
+		base := filepath.Base(root)
+		err := uo.Walk(ctx, root, config, func(filePath string, info *domain.FileInfo, err error) error {
+			if err != nil {
+				uo.AddFileError(filePath, fmt.Errorf("error accessing %s: %w", filePath, err))
+				return nil // Continue walking
+			}
+			if info == nil {
+				return nil
+			}
+			if uo.isExcluded(filePath, config) {
+				if info.IsDir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !os.FileMode(info.Mode).IsRegular() || !matchesAny(info.Name, patterns) || stub.Is(filePath, info.Size) {
+				return nil
+			}
+
+			rel, err := filepath.Rel(root, filePath)
+			if err != nil {
+				return nil
+			}
+			key := filesystem.NormalizeName(path.Join(prefix, base, filepath.ToSlash(rel)), config.UnicodeNormalization)
+			jobs = append(jobs, uploadJob{info: info, key: key})
+			totalSize += info.Size
+			return nil
+		})
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to walk path %s: %w", root, err)
+		}
+	}
+	return jobs, totalSize, nil
+}
+
+// uploadOptions builds the cloud options from the settings. Credentials are
+// taken from the environment and never stored in the settings.
+func uploadOptions(config domain.OperationConfig) cloud.Options {
+	return cloud.Options{
+		Endpoint: settingString(config, "endpoint", ""),
+		Region:   settingString(config, "region", ""),
+		PartSize: int64(settingInt(config, "part_size", cloud.DefaultPartSize)),
+
+		RclonePath: settingString(config, "rclone_path", ""),
+	}
+}
@@ -0,0 +1,358 @@
+package engine
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// Kinds of the entries of a Merkle tree
+const (
+	merkleFile = "file"
+	merkleDir  = "dir"
+	merkleLink = "link"
+)
+
+// VerifyTreeFactory creates tree verification operations
+type VerifyTreeFactory struct {
+	engine *Engine
+}
+
+// Create creates a new tree verification operation
+func (vf *VerifyTreeFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewVerifyTreeOperation(id, config, vf.engine), nil
+}
+
+// Validate validates the tree verification configuration
+func (vf *VerifyTreeFactory) Validate(config domain.OperationConfig) error {
+	if len(config.TargetPaths) != 2 {
+		return fmt.Errorf("exactly two directories are required")
+	}
+	for _, root := range config.TargetPaths {
+		if info, err := os.Stat(root); err != nil || !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", root)
+		}
+	}
+	if _, err := filesystem.NewHasher(settingString(config, "algorithm", "blake3")); err != nil {
+		return err
+	}
+	return nil
+}
+
+// VerifyTreeOperation proves two directory trees equal by their Merkle hashes.
+// The hash of a file is the hash of its content, that of a link the hash of
+// its target and that of a directory the hash of the names, kinds and hashes
+// of its entries. Both trees are hashed in parallel; equal root hashes prove
+// the trees equal. Otherwise the comparison descends only into directories
+// whose hashes differ, down to the first entries that diverge: a directory
+// missing from one tree is reported once, not with every file below it.
+type VerifyTreeOperation struct {
+	*BaseOperation
+	different  *pathList // Files and links of the first tree whose content differs
+	onlyFirst  *pathList // Entries only in the first tree
+	onlySecond *pathList // Entries only in the second tree
+	kindDiffer *pathList // Entries of the first tree that are of another kind in the second
+}
+
+// merkleNode is an entry of a Merkle tree
+type merkleNode struct {
+	path     string
+	kind     string
+	size     int64
+	hash     string
+	children map[string]*merkleNode // Entries of a directory by name
+}
+
+// NewVerifyTreeOperation creates a new tree verification operation
+func NewVerifyTreeOperation(id string, config domain.OperationConfig, engine *Engine) *VerifyTreeOperation {
+	base := NewBaseOperation(id, domain.OperationVerifyTree, config, engine)
+	return &VerifyTreeOperation{
+		BaseOperation: base,
+		different:     base.newPathList("different"),
+		onlyFirst:     base.newPathList("only_in_first"),
+		onlySecond:    base.newPathList("only_in_second"),
+		kindDiffer:    base.newPathList("kind_differs"),
+	}
+}
+
+// Execute hashes both trees and compares them
+func (vo *VerifyTreeOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	tracker := vo.engine.progressTracker.StartOperation(vo.id, domain.OperationVerifyTree, 3)
+	vo.SetTracker(tracker)
+
+	algorithm := settingString(config, "algorithm", "blake3")
+
+	vo.UpdateStep("Scanning trees")
+	roots := make([]*merkleNode, 2)
+	files := make([][]*merkleNode, 2)
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	for i, root := range config.TargetPaths {
+		wg.Add(1)
+		go func(i int, root string) {
+			defer wg.Done()
+			roots[i], files[i], errs[i] = vo.scan(ctx, root, config)
+		}(i, root)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk path %s: %w", config.TargetPaths[i], err)
+		}
+	}
+
+	// Interleave the files of both trees so that both are read at once
+	var queue []*merkleNode
+	var totalSize int64
+	for i := 0; i < len(files[0]) || i < len(files[1]); i++ {
+		for _, tree := range files {
+			if i < len(tree) {
+				queue = append(queue, tree[i])
+				totalSize += tree[i].size
+			}
+		}
+	}
+
+	vo.UpdateStep("Hashing files")
+	vo.SetProgress(0, int64(len(queue)), 0, totalSize)
+	if err := vo.hashFiles(ctx, config, algorithm, queue); err != nil {
+		return nil, err
+	}
+
+	vo.UpdateStep("Comparing trees")
+	for _, root := range roots {
+		if err := merkleHash(root, algorithm); err != nil {
+			return nil, err
+		}
+	}
+	vo.compare(roots[0], roots[1])
+
+	equal := roots[0].hash == roots[1].hash
+	details := map[string]interface{}{
+		"equal":          equal,
+		"first_root":     config.TargetPaths[0],
+		"second_root":    config.TargetPaths[1],
+		"first_hash":     roots[0].hash,
+		"second_hash":    roots[1].hash,
+		"algorithm":      algorithm,
+		"files":          len(files[0]), // Files and links of the first tree
+		"bytes":          totalSize,
+		"different":      vo.different.Result(),
+		"only_in_first":  vo.onlyFirst.Result(),
+		"only_in_second": vo.onlySecond.Result(),
+		"kind_differs":   vo.kindDiffer.Result(),
+	}
+
+	summary := fmt.Sprintf("Trees are identical: %d files each, root hash %s", len(files[0]), roots[0].hash)
+	if !equal {
+		summary = fmt.Sprintf("Trees differ: %d different, %d only in the first, %d only in the second, %d of another kind",
+			vo.different.Len(), vo.onlyFirst.Len(), vo.onlySecond.Len(), vo.kindDiffer.Len())
+	}
+	return vo.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// scan builds the tree below root, without hashes, and returns it with its
+// files and links
+func (vo *VerifyTreeOperation) scan(ctx context.Context, root string, config domain.OperationConfig) (*merkleNode, []*merkleNode, error) {
+	top := &merkleNode{path: root, kind: merkleDir, children: make(map[string]*merkleNode)}
+	dirs := map[string]*merkleNode{root: top}
+	var files []*merkleNode
+
+	err := vo.Walk(ctx, root, config, func(path string, info *domain.FileInfo, err error) error {
+		if err != nil {
+			vo.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+			return nil // Continue walking
+		}
+		if info == nil || path == root {
+			return nil
+		}
+		if err := vo.CheckContext(ctx); err != nil {
+			return err
+		}
+		parent := dirs[filepath.Dir(path)]
+		if parent == nil {
+			return nil // Below a directory that was skipped
+		}
+
+		node := &merkleNode{path: path, kind: merkleFile, size: info.Size}
+		switch mode := fs.FileMode(info.Mode); {
+		case info.IsDir:
+			node.kind, node.size = merkleDir, 0
+			node.children = make(map[string]*merkleNode)
+			dirs[path] = node
+		case mode&fs.ModeSymlink != 0:
+			node.kind, node.size = merkleLink, 0
+			files = append(files, node)
+		case mode.IsRegular():
+			files = append(files, node)
+		default:
+			return nil // Devices, sockets and pipes have no content to compare
+		}
+		parent.children[filepath.Base(path)] = node
+		return nil
+	})
+	return top, files, err
+}
+
+// hashFiles hashes the content of files and the targets of links on
+// config.Parallelism workers
+func (vo *VerifyTreeOperation) hashFiles(ctx context.Context, config domain.OperationConfig, algorithm string, nodes []*merkleNode) error {
+	workers := config.Parallelism
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	gate := vo.workerGate(workers)
+	jobs := make(chan *merkleNode)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for node := range jobs {
+				gate.Enter(runCtx)
+				vo.hashNode(node, algorithm)
+				gate.Leave()
+				vo.IncrementProgress(1, node.size)
+				if vo.ErrorBudgetErr() != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, node := range nodes {
+		if err := vo.CheckContext(runCtx); err != nil {
+			break
+		}
+		select {
+		case jobs <- node:
+		case <-runCtx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := vo.ErrorBudgetErr(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// hashNode sets the hash of a file or link. Unreadable entries get a hash
+// no other entry has, so that they never count as equal.
+func (vo *VerifyTreeOperation) hashNode(node *merkleNode, algorithm string) {
+	var err error
+	if node.kind == merkleLink {
+		var target string
+		if target, err = os.Readlink(node.path); err == nil {
+			node.hash, err = hashBytes(algorithm, []byte(target))
+		}
+	} else {
+		node.hash, err = vo.engine.fileSystem.ComputeHash(node.path, algorithm)
+	}
+	if err != nil {
+		vo.AddFileError(node.path, fmt.Errorf("failed to hash %s: %w", node.path, err))
+		node.hash = "unreadable:" + node.path
+	}
+}
+
+// merkleHash sets the hashes of dir and the directories below it from the
+// hashes of their entries
+func merkleHash(dir *merkleNode, algorithm string) error {
+	names := make([]string, 0, len(dir.children))
+	for name, child := range dir.children {
+		if child.kind == merkleDir {
+			if err := merkleHash(child, algorithm); err != nil {
+				return err
+			}
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hasher, err := filesystem.NewHasher(algorithm)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		child := dir.children[name]
+		fmt.Fprintf(hasher, "%s\x00%s\x00%s\n", name, child.kind, child.hash)
+	}
+	dir.hash = hex.EncodeToString(hasher.Sum(nil))
+	return nil
+}
+
+// hashBytes returns the hash of data as hex
+func hashBytes(algorithm string, data []byte) (string, error) {
+	hasher, err := filesystem.NewHasher(algorithm)
+	if err != nil {
+		return "", err
+	}
+	hasher.Write(data)
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// compare records where first and second diverge, descending only into
+// directories whose hashes differ
+func (vo *VerifyTreeOperation) compare(first, second *merkleNode) {
+	if first.hash == second.hash {
+		return
+	}
+	if first.kind != second.kind {
+		vo.kindDiffer.Add(first.path)
+		return
+	}
+	if first.kind != merkleDir {
+		vo.different.Add(first.path)
+		return
+	}
+
+	names := make([]string, 0, len(first.children)+len(second.children))
+	for name := range first.children {
+		names = append(names, name)
+	}
+	for name := range second.children {
+		if _, ok := first.children[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		a, inFirst := first.children[name]
+		b, inSecond := second.children[name]
+		switch {
+		case !inSecond:
+			vo.onlyFirst.Add(a.path)
+		case !inFirst:
+			vo.onlySecond.Add(b.path)
+		default:
+			vo.compare(a, b)
+		}
+	}
+}
+
+// Validate validates the tree verification configuration
+func (vo *VerifyTreeOperation) Validate(config domain.OperationConfig) error {
+	return vo.ValidateConfig()
+}
+
+// EstimateProgress provides an estimate of the operation's scope
+func (vo *VerifyTreeOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	return vo.estimateScope(config, 3)
+}
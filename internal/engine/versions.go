@@ -0,0 +1,358 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// minFamilySimilarity is the name similarity from which two normalized names
+// are treated as the same document
+const minFamilySimilarity = 0.85
+
+// VersionedFile is one member of a version family
+type VersionedFile struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// VersionFamily is a group of files that are versions of the same document,
+// oldest first
+type VersionFamily struct {
+	Name  string          `json:"name"` // Normalized name shared by the family
+	Dir   string          `json:"dir"`
+	Files []VersionedFile `json:"files"`
+	Keep  string          `json:"keep"`           // Newest version
+	Note  string          `json:"note,omitempty"` // Warns when the names disagree with the modification times
+}
+
+// versionMarkers are removed from a normalized name to get the document name
+var versionMarkers = []*regexp.Regexp{
+	regexp.MustCompile(`\bv ?\d+(?: \d+)*\b`),                              // v1, v2 3
+	regexp.MustCompile(`\b(?:version|ver|rev|revision|draft)(?: ?\d+)*\b`), // version 2, rev3
+	regexp.MustCompile(`\b(?:final|latest|old|new|updated|edited|edit|copy|backup|bak|orig|original|wip)\b`),
+	regexp.MustCompile(`\b\d{4} ?\d{2} ?\d{2}\b`), // Dates
+}
+
+// finalMarker matches names that claim to be the last version
+var finalMarker = regexp.MustCompile(`(?i)final`)
+
+// copyNumber is the number browsers and file managers append to copies
+var copyNumber = regexp.MustCompile(`\(\d{1,3}\)`)
+
+// separators are replaced by spaces before markers are removed
+var separators = regexp.MustCompile(`[\s_\-.()\[\]]+`)
+
+// versionFamilyName returns the document name of a file with version markers,
+// dates, copy numbers and separators removed
+func versionFamilyName(name string) string {
+	stem := strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))
+	stem = copyNumber.ReplaceAllString(stem, " ")
+	stem = separators.ReplaceAllString(stem, " ")
+	// Markers can repeat ("final final") and overlap their separators
+	for i := 0; i < 3; i++ {
+		for _, marker := range versionMarkers {
+			stem = marker.ReplaceAllString(stem, " ")
+		}
+	}
+	return strings.Join(strings.Fields(stem), " ")
+}
+
+// VersionsFactory creates version family operations
+type VersionsFactory struct {
+	engine *Engine
+}
+
+// Create creates a new versions operation
+func (vf *VersionsFactory) Create(id string, config domain.OperationConfig) (domain.Operation, error) {
+	return NewVersionsOperation(id, config, vf.engine), nil
+}
+
+// Validate validates the versions configuration
+func (vf *VersionsFactory) Validate(config domain.OperationConfig) error {
+	if len(config.TargetPaths) == 0 {
+		return fmt.Errorf("at least one path is required")
+	}
+	if settingBool(config, "archive", false) && settingString(config, "destination", "") == "" {
+		return fmt.Errorf("destination parameter is required to archive old versions")
+	}
+	return nil
+}
+
+// VersionsOperation finds families of versioned files (report_v1.docx,
+// report_v2.docx, report_final.docx) and, with the "archive" setting, moves
+// all but the newest of each family to the destination.
+type VersionsOperation struct {
+	*BaseOperation
+	families []VersionFamily
+	archived []string
+	bytes    int64
+}
+
+// NewVersionsOperation creates a new versions operation
+func NewVersionsOperation(id string, config domain.OperationConfig, engine *Engine) *VersionsOperation {
+	base := NewBaseOperation(id, domain.OperationVersions, config, engine)
+	return &VersionsOperation{
+		BaseOperation: base,
+		families:      make([]VersionFamily, 0),
+		archived:      make([]string, 0),
+	}
+}
+
+// Execute groups the files into version families and archives old versions
+func (vo *VersionsOperation) Execute(ctx context.Context, config domain.OperationConfig) (*domain.OperationResult, error) {
+	tracker := vo.engine.progressTracker.StartOperation(vo.id, domain.OperationVersions, 3)
+	vo.SetTracker(tracker)
+
+	destination := settingString(config, "destination", "")
+	archive := settingBool(config, "archive", false)
+
+	vo.UpdateStep("Scanning files")
+
+	byDir := make(map[string][]VersionedFile)
+	for _, root := range config.TargetPaths {
+		err := vo.Walk(ctx, root, config, func(path string, info *domain.FileInfo, err error) error {
+			if err != nil {
+				vo.AddFileError(path, fmt.Errorf("error accessing %s: %w", path, err))
+				return nil // Continue walking
+			}
+			if info == nil {
+				return nil
+			}
+			if info.IsDir {
+				if (destination != "" && path == destination) || vo.isExcluded(path, config) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !os.FileMode(info.Mode).IsRegular() || vo.isExcluded(path, config) {
+				return nil
+			}
+			dir := filepath.Dir(path)
+			byDir[dir] = append(byDir[dir], VersionedFile{Path: path, Size: info.Size, ModTime: info.ModTime})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk path %s: %w", root, err)
+		}
+	}
+
+	vo.UpdateStep("Grouping versions")
+
+	for dir, files := range byDir {
+		if err := vo.CheckContext(ctx); err != nil {
+			return nil, err
+		}
+		vo.families = append(vo.families, versionFamilies(dir, files)...)
+	}
+	sort.Slice(vo.families, func(i, j int) bool {
+		if vo.families[i].Dir != vo.families[j].Dir {
+			return vo.families[i].Dir < vo.families[j].Dir
+		}
+		return vo.families[i].Name < vo.families[j].Name
+	})
+
+	vo.UpdateStep("Archiving old versions")
+
+	var old int
+	for _, family := range vo.families {
+		old += len(family.Files) - 1
+	}
+	vo.SetProgress(0, int64(old), 0, 0)
+
+	var oldBytes int64
+	for _, family := range vo.families {
+		for _, file := range family.Files[:len(family.Files)-1] {
+			if err := vo.CheckContext(ctx); err != nil {
+				return nil, err
+			}
+			oldBytes += file.Size
+			if archive && !config.DryRun {
+				vo.archive(file, destination, config)
+			}
+			vo.IncrementProgress(1, file.Size)
+		}
+	}
+
+	vo.UpdateStep("Completed")
+
+	details := map[string]interface{}{
+		"families":       vo.families,
+		"old_versions":   old,
+		"old_bytes":      oldBytes,
+		"archived":       vo.archived,
+		"archived_bytes": vo.bytes,
+		"destination":    destination,
+		"dry_run":        config.DryRun,
+	}
+
+	summary := fmt.Sprintf("Versions: %d families with %d older versions (%d bytes)", len(vo.families), old, oldBytes)
+	if archive && !config.DryRun {
+		summary = fmt.Sprintf("Versions: %d families, %d older versions archived (%d bytes)", len(vo.families), len(vo.archived), vo.bytes)
+	} else if archive {
+		summary = fmt.Sprintf("Versions (dry run): %d families, %d older versions would be archived (%d bytes)", len(vo.families), old, oldBytes)
+	}
+
+	return vo.CreateResult(domain.StatusCompleted, summary, details), nil
+}
+
+// versionFamilies groups the files of one directory by extension and
+// normalized name, merging names that differ only by a typo or a word
+func versionFamilies(dir string, files []VersionedFile) []VersionFamily {
+	type group struct {
+		name  string
+		files []VersionedFile
+	}
+	groups := make([]*group, 0)
+	byKey := make(map[string]*group)
+	for _, file := range files {
+		name := versionFamilyName(filepath.Base(file.Path))
+		if name == "" {
+			continue // Nothing but markers, e.g. "final.docx"
+		}
+		ext := strings.ToLower(filepath.Ext(file.Path))
+		key := ext + "\x00" + name
+		if byKey[key] == nil {
+			byKey[key] = &group{name: name}
+			groups = append(groups, byKey[key])
+		}
+		byKey[key].files = append(byKey[key].files, file)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].name < groups[j].name })
+
+	// Merge similar names of the same extension into the larger group
+	merged := make([]*group, 0, len(groups))
+	for _, g := range groups {
+		ext := strings.ToLower(filepath.Ext(g.files[0].Path))
+		var target *group
+		for _, m := range merged {
+			if strings.ToLower(filepath.Ext(m.files[0].Path)) == ext && similarNames(m.name, g.name) {
+				target = m
+				break
+			}
+		}
+		if target == nil {
+			merged = append(merged, g)
+			continue
+		}
+		target.files = append(target.files, g.files...)
+	}
+
+	families := make([]VersionFamily, 0)
+	for _, g := range merged {
+		if len(g.files) < 2 {
+			continue
+		}
+		sort.Slice(g.files, func(i, j int) bool {
+			if !g.files[i].ModTime.Equal(g.files[j].ModTime) {
+				return g.files[i].ModTime.Before(g.files[j].ModTime)
+			}
+			return g.files[i].Path < g.files[j].Path
+		})
+		newest := g.files[len(g.files)-1]
+		family := VersionFamily{Name: g.name, Dir: dir, Files: g.files, Keep: newest.Path}
+		for _, file := range g.files[:len(g.files)-1] {
+			if finalMarker.MatchString(filepath.Base(file.Path)) && !finalMarker.MatchString(filepath.Base(newest.Path)) {
+				family.Note = fmt.Sprintf("%s is newer than %s", filepath.Base(newest.Path), filepath.Base(file.Path))
+			}
+		}
+		families = append(families, family)
+	}
+	return families
+}
+
+// similarNames reports whether two normalized names are close enough to be
+// the same document. Names that differ only in their numbers ("chapter 1",
+// "chapter 2") are different documents.
+func similarNames(a, b string) bool {
+	if a == b {
+		return true
+	}
+	longest := max(len(a), len(b))
+	if min(len(a), len(b)) < 5 {
+		return false
+	}
+	if stripDigits(a) == stripDigits(b) {
+		return false
+	}
+	return 1-float64(editDistance(a, b))/float64(longest) >= minFamilySimilarity
+}
+
+// stripDigits removes all digits from s
+func stripDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// archive moves an old version below the destination, keeping its path
+// relative to the scanned root, after checking it did not change
+func (vo *VersionsOperation) archive(file VersionedFile, destination string, config domain.OperationConfig) {
+	current, err := vo.engine.fileSystem.Stat(file.Path)
+	if err != nil {
+		vo.AddFileError(file.Path, fmt.Errorf("failed to check %s: %w", file.Path, err))
+		return
+	}
+	if current.Size != file.Size || !current.ModTime.Equal(file.ModTime) {
+		vo.engine.logger.Info("Skipping file modified since it was scanned", "path", file.Path)
+		return
+	}
+
+	relative := filepath.Base(file.Path)
+	for _, root := range config.TargetPaths {
+		if rel, err := filepath.Rel(root, file.Path); err == nil && !strings.HasPrefix(rel, "..") {
+			relative = rel
+			break
+		}
+	}
+	target := vo.freeTarget(filepath.Join(destination, relative))
+	if err := vo.move(file.Path, target); err != nil {
+		vo.AddFileError(file.Path, fmt.Errorf("failed to archive %s: %w", file.Path, err))
+		return
+	}
+	vo.archived = append(vo.archived, file.Path)
+	vo.bytes += file.Size
+	vo.engine.logger.Info("Archived old version", "source", file.Path, "target", target)
+}
+
+// Validate validates the versions operation configuration
+func (vo *VersionsOperation) Validate(config domain.OperationConfig) error {
+	return vo.ValidateConfig()
+}
+
+// EstimateProgress provides an estimate of the operation's scope
+func (vo *VersionsOperation) EstimateProgress(config domain.OperationConfig) (*domain.ProgressInfo, error) {
+	return vo.estimateScope(config, 3)
+}
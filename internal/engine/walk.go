@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/a4abhishek/fileops/internal/snapshot"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// Walk traverses root honoring the traversal options of the operation configuration.
+// With OneFileSystem set, directories living on a different device than root
+// (other mounts, network shares, snapshots, pseudo filesystems) are not entered.
+// With OnlyPaths set, only those items (and everything below them) are visited.
+// With IncludePatterns set, only files whose name matches one of them reach
+// fn; all directories are still traversed.
+// With the "skip_build_outputs" setting, build outputs of recognized projects
+// (node_modules, target, dist, ...) are not entered. With GitFilter set, files
+// of git repositories only reach fn when the filter allows them. Snapshots
+// taken before destructive runs are never entered.
+func (e *Engine) Walk(ctx context.Context, root string, config domain.OperationConfig, fn domain.WalkFunc) error {
+	return e.walk(ctx, root, config, fn, nil)
+}
+
+// walk is Walk that also times the walker into stats, unless it is nil
+func (e *Engine) walk(ctx context.Context, root string, config domain.OperationConfig, fn domain.WalkFunc, stats *pathStats) error {
+	fn = skipSnapshots(fn)
+	if len(config.OnlyPaths) > 0 {
+		fn = onlyPathsFilter(config.OnlyPaths, fn)
+	}
+	if len(config.IncludePatterns) > 0 {
+		fn = includeFilter(config, fn)
+	}
+	if settingBool(config, "skip_build_outputs", false) {
+		fn = skipBuildOutputs(fn)
+	}
+	if config.GitFilter != "" {
+		fn = e.gitFilterWalk(config.GitFilter, fn)
+	}
+	if label := config.RootLabels[root]; label != "" {
+		fn = labelWalk(label, fn)
+	}
+
+	if config.OneFileSystem {
+		if rootDevice, err := filesystem.DeviceID(root); err != nil {
+			e.logger.Warn("Unable to determine device of root, one-file-system disabled", "path", root, "error", err)
+		} else {
+			fn = e.oneFileSystem(root, rootDevice, fn)
+		}
+	}
+	if stats != nil {
+		fn = stats.sample(root, fn)
+	}
+	return e.fileSystem.Walk(ctx, root, fn)
+}
+
+// oneFileSystem wraps fn so that directories on another device than root
+// are not entered
+func (e *Engine) oneFileSystem(root string, rootDevice uint64, fn domain.WalkFunc) domain.WalkFunc {
+	return func(path string, info *domain.FileInfo, err error) error {
+		if err == nil && info != nil && info.IsDir && path != root {
+			// Prefer the device captured during the scan over an extra stat call
+			device := info.Device
+			if device == 0 {
+				var devErr error
+				if device, devErr = filesystem.DeviceID(path); devErr != nil {
+					return fn(path, info, err)
+				}
+			}
+			if device != rootDevice {
+				e.logger.Debug("Not crossing filesystem boundary", "path", path)
+				return filepath.SkipDir
+			}
+		}
+		return fn(path, info, err)
+	}
+}
+
+// skipSnapshots wraps fn so that snapshot directories are not entered
+func skipSnapshots(fn domain.WalkFunc) domain.WalkFunc {
+	return func(path string, info *domain.FileInfo, err error) error {
+		if info != nil && info.IsDir && filepath.Base(path) == snapshot.Dir {
+			return filepath.SkipDir
+		}
+		return fn(path, info, err)
+	}
+}
+
+// includeFilter wraps fn so that only files matching the include patterns
+// reach it
+func includeFilter(config domain.OperationConfig, fn domain.WalkFunc) domain.WalkFunc {
+	return func(path string, info *domain.FileInfo, err error) error {
+		if err == nil && info != nil && !info.IsDir && !config.Includes(path) {
+			return nil
+		}
+		return fn(path, info, err)
+	}
+}
+
+// onlyPathsFilter wraps fn so that only the given paths and their descendants
+// reach it. Directories leading to one of the paths are traversed silently.
+// Lookups go through sets, so the paths may be a whole result set of another
+// operation.
+func onlyPathsFilter(paths []string, fn domain.WalkFunc) domain.WalkFunc {
+	targets := make(map[string]bool, len(paths))
+	leading := make(map[string]bool) // Parents of the targets
+	for _, path := range paths {
+		path = filepath.Clean(path)
+		targets[path] = true
+		for dir := filepath.Dir(path); !leading[dir]; dir = filepath.Dir(dir) {
+			leading[dir] = true
+			if filepath.Dir(dir) == dir {
+				break
+			}
+		}
+	}
+
+	return func(path string, info *domain.FileInfo, err error) error {
+		path = filepath.Clean(path)
+		for ancestor := path; ; ancestor = filepath.Dir(ancestor) {
+			if targets[ancestor] {
+				return fn(path, info, err)
+			}
+			if filepath.Dir(ancestor) == ancestor {
+				break
+			}
+		}
+
+		if info != nil && info.IsDir && !leading[path] {
+			return filepath.SkipDir
+		}
+		return nil
+	}
+}
+
+// labelWalk wraps fn so that every item it sees carries the label of its root
+func labelWalk(label string, fn domain.WalkFunc) domain.WalkFunc {
+	return func(path string, info *domain.FileInfo, err error) error {
+		if info != nil {
+			info.Label = label
+		}
+		return fn(path, info, err)
+	}
+}
+
+// labelRoot returns the first root of config carrying label
+func labelRoot(config domain.OperationConfig, label string) (string, bool) {
+	for _, root := range config.TargetPaths {
+		if config.RootLabels[root] == label {
+			return root, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,98 @@
+package integrity
+
+import "errors"
+
+// errSingular is returned when a recovery matrix cannot be inverted
+var errSingular = errors.New("singular recovery matrix")
+
+// Arithmetic in GF(2^8) with the polynomial x^8 + x^4 + x^3 + x^2 + 1 (0x11D),
+// the field used by PAR2 and most Reed-Solomon implementations
+var (
+	gfExp [512]byte
+	gfLog [256]byte
+	gfMul [256][256]byte
+)
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i] = byte(x)
+		gfLog[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11D
+		}
+	}
+	for i := 255; i < len(gfExp); i++ {
+		gfExp[i] = gfExp[i-255]
+	}
+	for a := 1; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			gfMul[a][b] = gfExp[int(gfLog[a])+int(gfLog[b])]
+		}
+	}
+}
+
+// gfInverse returns the multiplicative inverse of a non-zero element
+func gfInverse(a byte) byte {
+	return gfExp[255-int(gfLog[a])]
+}
+
+// cauchy returns the coefficient of data block i in recovery block j. Every
+// square submatrix of a Cauchy matrix is invertible, so any combination of
+// lost data blocks can be rebuilt from as many intact recovery blocks.
+func cauchy(j, i, dataBlocks int) byte {
+	return gfInverse(byte(dataBlocks+j) ^ byte(i))
+}
+
+// mulAdd adds coefficient * src to dst
+func mulAdd(dst, src []byte, coefficient byte) {
+	if coefficient == 0 {
+		return
+	}
+	row := &gfMul[coefficient]
+	for k, b := range src {
+		dst[k] ^= row[b]
+	}
+}
+
+// invertMatrix inverts a square matrix over GF(2^8) by Gauss-Jordan elimination
+func invertMatrix(matrix [][]byte) ([][]byte, error) {
+	size := len(matrix)
+	work := make([][]byte, size)
+	for i := range matrix {
+		work[i] = make([]byte, 2*size)
+		copy(work[i], matrix[i])
+		work[i][size+i] = 1
+	}
+
+	for col := 0; col < size; col++ {
+		pivot := -1
+		for row := col; row < size; row++ {
+			if work[row][col] != 0 {
+				pivot = row
+				break
+			}
+		}
+		if pivot < 0 {
+			return nil, errSingular
+		}
+		work[col], work[pivot] = work[pivot], work[col]
+
+		scale := gfInverse(work[col][col])
+		for k := range work[col] {
+			work[col][k] = gfMul[scale][work[col][k]]
+		}
+		for row := 0; row < size; row++ {
+			if row != col && work[row][col] != 0 {
+				mulAdd(work[row], work[col], work[row][col])
+			}
+		}
+	}
+
+	inverse := make([][]byte, size)
+	for i := range work {
+		inverse[i] = work[i][size:]
+	}
+	return inverse, nil
+}
@@ -0,0 +1,487 @@
+package integrity
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// ParityExtension is appended to a file's name for its recovery data
+const ParityExtension = ".parity"
+
+// parityMagic ends every recovery file
+var parityMagic = []byte("FOPSPAR1")
+
+const (
+	// maxDataBlocks keeps data and recovery blocks within the 256 elements of GF(2^8)
+	maxDataBlocks = 128
+	minBlockSize  = 4096
+	chunkSize     = 64 * 1024
+)
+
+// ErrCorrupt is returned for files whose content no longer matches their checksum
+var ErrCorrupt = errors.New("checksum mismatch")
+
+// ErrUnrepairable is returned when more blocks are damaged than recovery data can rebuild
+var ErrUnrepairable = errors.New("not enough recovery data")
+
+// ErrStaleParity is returned when a file was modified after its recovery data was written
+var ErrStaleParity = errors.New("recovery data is older than the file")
+
+// parityHeader describes the protected file and the recovery blocks. It is
+// stored as JSON after the recovery blocks.
+type parityHeader struct {
+	Size           int64     `json:"size"`
+	ModTime        time.Time `json:"mod_time"`
+	BlockSize      int64     `json:"block_size"`
+	DataBlocks     int       `json:"data_blocks"`
+	RecoveryBlocks int       `json:"recovery_blocks"`
+	BlockHashes    []string  `json:"block_hashes"`
+	RecoveryHashes []string  `json:"recovery_hashes"`
+}
+
+// ParityPath returns where the recovery data of path is stored
+func ParityPath(path string) string {
+	return path + ParityExtension
+}
+
+// HasParity reports whether recovery data exists for path
+func HasParity(path string) bool {
+	_, err := os.Stat(ParityPath(path))
+	return err == nil
+}
+
+// CreateParity writes Reed-Solomon recovery data for path that can rebuild up to
+// redundancy percent of the file (at least one block). The file is split into
+// at most 128 blocks; every damaged block costs one recovery block to repair.
+func CreateParity(path string, redundancy int) error {
+	if redundancy < 1 || redundancy > 100 {
+		return fmt.Errorf("redundancy must be between 1 and 100 percent")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	header := newParityHeader(info.Size(), redundancy)
+	header.ModTime = info.ModTime()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".fileops-parity-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	if err := encode(file, tmp, header); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := writeTrailer(tmp, header); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, ParityPath(path))
+}
+
+// newParityHeader chooses the block layout for a file of the given size
+func newParityHeader(size int64, redundancy int) *parityHeader {
+	blockSize := (size + maxDataBlocks - 1) / maxDataBlocks
+	blockSize = (blockSize + minBlockSize - 1) / minBlockSize * minBlockSize
+	if blockSize < minBlockSize {
+		blockSize = minBlockSize
+	}
+	dataBlocks := int((size + blockSize - 1) / blockSize)
+	if dataBlocks == 0 {
+		dataBlocks = 1
+	}
+	recoveryBlocks := (dataBlocks*redundancy + 99) / 100
+
+	return &parityHeader{
+		Size:           size,
+		BlockSize:      blockSize,
+		DataBlocks:     dataBlocks,
+		RecoveryBlocks: recoveryBlocks,
+	}
+}
+
+// encode streams the data blocks of file and writes the recovery blocks to out,
+// filling in the hashes of header
+func encode(file io.ReaderAt, out io.WriterAt, header *parityHeader) error {
+	blockHashes := newHashers(header.DataBlocks)
+	recoveryHashes := newHashers(header.RecoveryBlocks)
+
+	data := make([][]byte, header.DataBlocks)
+	recovery := make([][]byte, header.RecoveryBlocks)
+
+	for offset := int64(0); offset < header.BlockSize; offset += chunkSize {
+		length := min(int64(chunkSize), header.BlockSize-offset)
+
+		for i := range data {
+			chunk, err := readChunk(file, header, i, offset, length)
+			if err != nil {
+				return err
+			}
+			data[i] = chunk
+			blockHashes[i].Write(trimToFile(chunk, header, i, offset))
+		}
+
+		for j := range recovery {
+			recovery[j] = make([]byte, length)
+			for i := range data {
+				mulAdd(recovery[j], data[i], cauchy(j, i, header.DataBlocks))
+			}
+			recoveryHashes[j].Write(recovery[j])
+			if _, err := out.WriteAt(recovery[j], int64(j)*header.BlockSize+offset); err != nil {
+				return err
+			}
+		}
+	}
+
+	header.BlockHashes = sums(blockHashes)
+	header.RecoveryHashes = sums(recoveryHashes)
+	return nil
+}
+
+// writeTrailer appends the header, its length and the magic after the recovery blocks
+func writeTrailer(out *os.File, header *parityHeader) error {
+	data, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	offset := int64(header.RecoveryBlocks) * header.BlockSize
+
+	trailer := make([]byte, 0, len(data)+4+len(parityMagic))
+	trailer = append(trailer, data...)
+	trailer = binary.BigEndian.AppendUint32(trailer, uint32(len(data)))
+	trailer = append(trailer, parityMagic...)
+	_, err = out.WriteAt(trailer, offset)
+	return err
+}
+
+// readParity opens the recovery file of path and decodes its header
+func readParity(path string) (*os.File, *parityHeader, error) {
+	parity, err := os.Open(ParityPath(path))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := parity.Stat()
+	if err != nil {
+		parity.Close()
+		return nil, nil, err
+	}
+
+	tail := make([]byte, 4+len(parityMagic))
+	if info.Size() < int64(len(tail)) {
+		parity.Close()
+		return nil, nil, fmt.Errorf("recovery file %s is truncated", ParityPath(path))
+	}
+	if _, err := parity.ReadAt(tail, info.Size()-int64(len(tail))); err != nil {
+		parity.Close()
+		return nil, nil, err
+	}
+	if !bytes.Equal(tail[4:], parityMagic) {
+		parity.Close()
+		return nil, nil, fmt.Errorf("%s is not a recovery file", ParityPath(path))
+	}
+
+	length := int64(binary.BigEndian.Uint32(tail[:4]))
+	start := info.Size() - int64(len(tail)) - length
+	if start < 0 {
+		parity.Close()
+		return nil, nil, fmt.Errorf("recovery file %s is truncated", ParityPath(path))
+	}
+	data := make([]byte, length)
+	if _, err := parity.ReadAt(data, start); err != nil {
+		parity.Close()
+		return nil, nil, err
+	}
+
+	var header parityHeader
+	if err := json.Unmarshal(data, &header); err != nil {
+		parity.Close()
+		return nil, nil, fmt.Errorf("corrupt recovery file %s: %w", ParityPath(path), err)
+	}
+	if header.DataBlocks < 1 || header.DataBlocks+header.RecoveryBlocks > 256 || header.BlockSize < 1 ||
+		len(header.BlockHashes) != header.DataBlocks || len(header.RecoveryHashes) != header.RecoveryBlocks {
+		parity.Close()
+		return nil, nil, fmt.Errorf("corrupt recovery file %s: invalid layout", ParityPath(path))
+	}
+	return parity, &header, nil
+}
+
+// DamagedBlocks returns the number of blocks of path that no longer match its recovery data
+func DamagedBlocks(path string) (int, error) {
+	parity, header, err := readParity(path)
+	if err != nil {
+		return 0, err
+	}
+	defer parity.Close()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	damaged, err := damagedBlocks(file, info.Size(), header)
+	if err != nil {
+		return 0, err
+	}
+	return len(damaged), nil
+}
+
+// Repair rebuilds the damaged blocks of path from its recovery data and returns
+// the number of blocks rewritten. The modification time of the file is kept so
+// the repair is not mistaken for an edit. The recovery data is used while the
+// file's modification time matches the recorded one within tolerance.
+func Repair(path string, tolerance domain.TimeTolerance) (int, error) {
+	parity, header, err := readParity(path)
+	if err != nil {
+		return 0, err
+	}
+	defer parity.Close()
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	modTime := info.ModTime()
+
+	if !tolerance.Equal(modTime, header.ModTime) {
+		return 0, fmt.Errorf("%w: %s", ErrStaleParity, path)
+	}
+
+	damaged, err := damagedBlocks(file, info.Size(), header)
+	if err != nil {
+		return 0, err
+	}
+	if len(damaged) == 0 {
+		if info.Size() != header.Size {
+			return 0, file.Truncate(header.Size)
+		}
+		return 0, nil
+	}
+
+	usable, err := intactRecoveryBlocks(parity, header)
+	if err != nil {
+		return 0, err
+	}
+	if len(usable) < len(damaged) {
+		return 0, fmt.Errorf("%w: %d damaged blocks, %d intact recovery blocks", ErrUnrepairable, len(damaged), len(usable))
+	}
+	usable = usable[:len(damaged)]
+
+	if err := rebuild(file, parity, header, damaged, usable); err != nil {
+		return 0, err
+	}
+	if err := file.Truncate(header.Size); err != nil {
+		return 0, err
+	}
+	if err := file.Sync(); err != nil {
+		return 0, err
+	}
+
+	// Confirm the result before reporting success
+	remaining, err := damagedBlocks(file, header.Size, header)
+	if err != nil {
+		return 0, err
+	}
+	if len(remaining) > 0 {
+		return 0, fmt.Errorf("%w: %d blocks still damaged after repair", ErrUnrepairable, len(remaining))
+	}
+
+	if err := os.Chtimes(path, time.Now(), modTime); err != nil {
+		return 0, err
+	}
+	return len(damaged), nil
+}
+
+// rebuild solves for the damaged data blocks chunk by chunk and writes them back
+func rebuild(file *os.File, parity io.ReaderAt, header *parityHeader, damaged, usable []int) error {
+	matrix := make([][]byte, len(usable))
+	for a, j := range usable {
+		matrix[a] = make([]byte, len(damaged))
+		for b, i := range damaged {
+			matrix[a][b] = cauchy(j, i, header.DataBlocks)
+		}
+	}
+	inverse, err := invertMatrix(matrix)
+	if err != nil {
+		return err
+	}
+
+	isDamaged := make(map[int]bool, len(damaged))
+	for _, i := range damaged {
+		isDamaged[i] = true
+	}
+
+	for offset := int64(0); offset < header.BlockSize; offset += chunkSize {
+		length := min(int64(chunkSize), header.BlockSize-offset)
+
+		// Syndromes: recovery chunk minus the contribution of the intact data blocks
+		syndromes := make([][]byte, len(usable))
+		for a, j := range usable {
+			syndromes[a] = make([]byte, length)
+			if _, err := parity.ReadAt(syndromes[a], int64(j)*header.BlockSize+offset); err != nil {
+				return err
+			}
+		}
+		for i := 0; i < header.DataBlocks; i++ {
+			if isDamaged[i] {
+				continue
+			}
+			chunk, err := readChunk(file, header, i, offset, length)
+			if err != nil {
+				return err
+			}
+			for a, j := range usable {
+				mulAdd(syndromes[a], chunk, cauchy(j, i, header.DataBlocks))
+			}
+		}
+
+		for b, i := range damaged {
+			restored := make([]byte, length)
+			for a := range usable {
+				mulAdd(restored, syndromes[a], inverse[b][a])
+			}
+			restored = trimToFile(restored, header, i, offset)
+			if len(restored) == 0 {
+				continue
+			}
+			if _, err := file.WriteAt(restored, int64(i)*header.BlockSize+offset); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// damagedBlocks returns the indexes of the data blocks whose hash does not match
+func damagedBlocks(file io.ReaderAt, size int64, header *parityHeader) ([]int, error) {
+	hashers := newHashers(header.DataBlocks)
+	for offset := int64(0); offset < header.BlockSize; offset += chunkSize {
+		length := min(int64(chunkSize), header.BlockSize-offset)
+		for i := range hashers {
+			chunk, err := readChunkSized(file, size, header, i, offset, length)
+			if err != nil {
+				return nil, err
+			}
+			hashers[i].Write(trimToFile(chunk, header, i, offset))
+		}
+	}
+
+	damaged := make([]int, 0)
+	for i, sum := range sums(hashers) {
+		if sum != header.BlockHashes[i] {
+			damaged = append(damaged, i)
+		}
+	}
+	return damaged, nil
+}
+
+// intactRecoveryBlocks returns the indexes of recovery blocks matching their hash
+func intactRecoveryBlocks(parity io.ReaderAt, header *parityHeader) ([]int, error) {
+	intact := make([]int, 0, header.RecoveryBlocks)
+	buffer := make([]byte, chunkSize)
+	for j := 0; j < header.RecoveryBlocks; j++ {
+		hasher := sha256.New()
+		for offset := int64(0); offset < header.BlockSize; offset += chunkSize {
+			length := min(int64(chunkSize), header.BlockSize-offset)
+			n, err := parity.ReadAt(buffer[:length], int64(j)*header.BlockSize+offset)
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			hasher.Write(buffer[:n])
+		}
+		if hex.EncodeToString(hasher.Sum(nil)) == header.RecoveryHashes[j] {
+			intact = append(intact, j)
+		}
+	}
+	return intact, nil
+}
+
+// readChunk reads part of data block i, zero-padded beyond the protected size
+func readChunk(file io.ReaderAt, header *parityHeader, i int, offset, length int64) ([]byte, error) {
+	return readChunkSized(file, header.Size, header, i, offset, length)
+}
+
+// readChunkSized reads part of data block i from a file of the given actual
+// size; bytes beyond it or beyond the protected size read as zero
+func readChunkSized(file io.ReaderAt, size int64, header *parityHeader, i int, offset, length int64) ([]byte, error) {
+	chunk := make([]byte, length)
+	start := int64(i)*header.BlockSize + offset
+	end := min(start+length, size, header.Size)
+	if end <= start {
+		return chunk, nil
+	}
+	if _, err := file.ReadAt(chunk[:end-start], start); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return chunk, nil
+}
+
+// trimToFile cuts a chunk of block i to the part that lies within the protected file
+func trimToFile(chunk []byte, header *parityHeader, i int, offset int64) []byte {
+	start := int64(i)*header.BlockSize + offset
+	if start >= header.Size {
+		return nil
+	}
+	if remaining := header.Size - start; remaining < int64(len(chunk)) {
+		return chunk[:remaining]
+	}
+	return chunk
+}
+
+// newHashers creates count sha256 hashers
+func newHashers(count int) []hash.Hash {
+	hashers := make([]hash.Hash, count)
+	for i := range hashers {
+		hashers[i] = sha256.New()
+	}
+	return hashers
+}
+
+// sums returns the hex digests of hashers
+func sums(hashers []hash.Hash) []string {
+	values := make([]string, len(hashers))
+	for i, hasher := range hashers {
+		values[i] = hex.EncodeToString(hasher.Sum(nil))
+	}
+	return values
+}
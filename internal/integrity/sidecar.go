@@ -0,0 +1,168 @@
+package integrity
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// Sidecar layouts
+const (
+	LayoutFile      = "file"      // <name>.<algorithm> next to every file
+	LayoutDirectory = "directory" // one <ALGORITHM>SUMS manifest per directory
+)
+
+// Checksum is the recorded checksum of a file
+type Checksum struct {
+	Algorithm string
+	Sum       string
+	Source    string    // Sidecar or manifest the checksum was read from
+	Recorded  time.Time // Modification time of the source
+}
+
+// SidecarPath returns the per-file sidecar of path
+func SidecarPath(path, algorithm string) string {
+	return path + "." + strings.ToLower(algorithm)
+}
+
+// ManifestPath returns the per-directory manifest in dir, e.g. SHA256SUMS
+func ManifestPath(dir, algorithm string) string {
+	return filepath.Join(dir, strings.ToUpper(algorithm)+"SUMS")
+}
+
+// IsSidecar reports whether path is a checksum sidecar, manifest or recovery file
+func IsSidecar(path string) bool {
+	name := filepath.Base(path)
+	if strings.HasSuffix(name, ParityExtension) || strings.HasPrefix(name, ".fileops-") {
+		return true
+	}
+	ext := strings.TrimPrefix(filepath.Ext(name), ".")
+	for _, algorithm := range filesystem.SupportedHashAlgorithms {
+		if ext == algorithm || name == strings.ToUpper(algorithm)+"SUMS" {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteSidecar writes the checksum of path in the format of sha256sum and friends
+func WriteSidecar(path, algorithm, sum string) error {
+	line := fmt.Sprintf("%s  %s\n", sum, filepath.Base(path))
+	return filesystem.WriteFileAtomic(SidecarPath(path, algorithm), []byte(line), 0644)
+}
+
+// WriteManifest writes the checksums of the files in dir, keyed by file name
+func WriteManifest(dir, algorithm string, sums map[string]string) error {
+	names := make([]string, 0, len(sums))
+	for name := range sums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var content strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&content, "%s  %s\n", sums[name], name)
+	}
+	return filesystem.WriteFileAtomic(ManifestPath(dir, algorithm), []byte(content.String()), 0644)
+}
+
+// ReadManifest reads a sidecar or manifest into a map of file name to checksum
+func ReadManifest(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// "<sum>  <name>" or "<sum> *<name>" (binary mode marker)
+		sum, name, found := strings.Cut(line, " ")
+		if !found {
+			return nil, fmt.Errorf("malformed checksum line in %s: %q", path, line)
+		}
+		sums[strings.TrimPrefix(strings.TrimPrefix(name, " "), "*")] = strings.ToLower(sum)
+	}
+	return sums, scanner.Err()
+}
+
+// Catalog looks up recorded checksums, caching parsed manifests
+type Catalog struct {
+	manifests map[string]map[string]string
+	modTimes  map[string]time.Time
+}
+
+// NewCatalog creates an empty catalog
+func NewCatalog() *Catalog {
+	return &Catalog{
+		manifests: make(map[string]map[string]string),
+		modTimes:  make(map[string]time.Time),
+	}
+}
+
+// Lookup returns the recorded checksum of path in the given algorithm, or of
+// any supported algorithm when algorithm is empty. A per-file sidecar takes
+// precedence over the directory manifest.
+func (c *Catalog) Lookup(path, algorithm string) (*Checksum, bool, error) {
+	algorithms := filesystem.SupportedHashAlgorithms
+	if algorithm != "" {
+		algorithms = []string{algorithm}
+	}
+
+	name := filepath.Base(path)
+	for _, algorithm := range algorithms {
+		for _, source := range []string{SidecarPath(path, algorithm), ManifestPath(filepath.Dir(path), algorithm)} {
+			sums, modTime, err := c.load(source)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, false, err
+			}
+			if sum, ok := sums[name]; ok {
+				return &Checksum{Algorithm: algorithm, Sum: sum, Source: source, Recorded: modTime}, true, nil
+			}
+		}
+	}
+	return nil, false, nil
+}
+
+// Manifest returns the entries of the manifest of dir
+func (c *Catalog) Manifest(dir, algorithm string) (map[string]string, error) {
+	sums, _, err := c.load(ManifestPath(dir, algorithm))
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	return sums, err
+}
+
+// load parses a sidecar or manifest once
+func (c *Catalog) load(path string) (map[string]string, time.Time, error) {
+	if sums, ok := c.manifests[path]; ok {
+		return sums, c.modTimes[path], nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	sums, err := ReadManifest(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	c.manifests[path] = sums
+	c.modTimes[path] = info.ModTime()
+	return sums, info.ModTime(), nil
+}
@@ -0,0 +1,355 @@
+//go:build linux
+
+package journal
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+	"golang.org/x/sys/unix"
+)
+
+// fanotifyMask selects the events that change the content or listing of a directory
+const fanotifyMask = unix.FAN_CREATE | unix.FAN_DELETE | unix.FAN_MOVED_FROM | unix.FAN_MOVED_TO |
+	unix.FAN_MODIFY | unix.FAN_ATTRIB | unix.FAN_ONDIR
+
+// eventMetadataSize is the size of struct fanotify_event_metadata
+const eventMetadataSize = 24
+
+// flushInterval is how often collected changes are appended to the recording;
+// repeated writes to a file within it are recorded once
+const flushInterval = time.Second
+
+// Markers written to the recording when changes may have been lost
+const (
+	markerStopped  = "# stopped"
+	markerOverflow = "# overflow"
+)
+
+// recording describes the recorder writing the changes of a filesystem
+type recording struct {
+	Session string    `json:"session"`
+	PID     int       `json:"pid"`
+	Path    string    `json:"path"` // Path the recorder was started on
+	Started time.Time `json:"started"`
+}
+
+// recordingFiles returns the state and log file of the recording of a filesystem
+func recordingFiles(dir string, device uint64) (string, string) {
+	base := filepath.Join(dir, fmt.Sprintf("fanotify-%x", device))
+	return base + ".json", base + ".log"
+}
+
+// Record watches the whole filesystem holding path with fanotify and appends
+// every change to a recording in dir until ctx is done. Readers started later
+// get the changes from the recording as long as the recorder keeps running.
+// It requires Linux 5.9 and CAP_SYS_ADMIN. flushed is called with the number
+// of changes written by every flush.
+func Record(ctx context.Context, path, dir string, flushed func(int)) error {
+	device, err := filesystem.DeviceID(path)
+	if err != nil {
+		return err
+	}
+
+	fd, err := unix.FanotifyInit(unix.FAN_CLASS_NOTIF|unix.FAN_REPORT_DFID_NAME|unix.FAN_CLOEXEC|unix.FAN_NONBLOCK, unix.O_RDONLY|unix.O_LARGEFILE)
+	switch {
+	case errors.Is(err, unix.EPERM):
+		return fmt.Errorf("fanotify requires CAP_SYS_ADMIN (run as root): %w", err)
+	case errors.Is(err, unix.EINVAL):
+		return fmt.Errorf("fanotify with file names requires Linux 5.9 or newer: %w", err)
+	case err != nil:
+		return fmt.Errorf("failed to initialize fanotify: %w", err)
+	}
+	defer unix.Close(fd)
+
+	if err := unix.FanotifyMark(fd, unix.FAN_MARK_ADD|unix.FAN_MARK_FILESYSTEM, fanotifyMask, unix.AT_FDCWD, path); err != nil {
+		return fmt.Errorf("failed to watch the filesystem of %s: %w", path, err)
+	}
+	mountFD, err := unix.Open(path, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(mountFD)
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	statePath, logPath := recordingFiles(dir, device)
+	log, err := os.Create(logPath) // Cursors of an earlier recording are gaps anyway
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+	state := recording{Session: strconv.FormatInt(time.Now().UnixNano(), 36), PID: os.Getpid(), Path: path, Started: time.Now()}
+	data, _ := json.MarshalIndent(state, "", "  ")
+	if err := os.WriteFile(statePath, data, 0o644); err != nil {
+		return err
+	}
+
+	// The recording itself lives on a watched filesystem more often than not
+	ownDir, _ := filepath.Abs(dir)
+	pending := make(map[string]bool)
+	flush := func(marker string) error {
+		paths := make([]string, 0, len(pending))
+		for changed := range pending {
+			paths = append(paths, changed)
+		}
+		sort.Strings(paths)
+		var buf strings.Builder
+		for _, changed := range paths {
+			kind := "M"
+			if pending[changed] {
+				kind = "D"
+			}
+			buf.WriteString(kind + "\t" + changed + "\n")
+		}
+		if marker != "" {
+			buf.WriteString(marker + "\n")
+		}
+		if buf.Len() == 0 {
+			return nil
+		}
+		if _, err := log.WriteString(buf.String()); err != nil {
+			return err
+		}
+		if flushed != nil && len(paths) > 0 {
+			flushed(len(paths))
+		}
+		clear(pending)
+		return nil
+	}
+	defer func() { _ = flush(markerStopped) }()
+
+	buf := make([]byte, 256*1024)
+	lastFlush := time.Now()
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if time.Since(lastFlush) >= flushInterval {
+			if err := flush(""); err != nil {
+				return err
+			}
+			lastFlush = time.Now()
+		}
+
+		fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+		if _, err := unix.Poll(fds, int(flushInterval/time.Millisecond/2)); err != nil && !errors.Is(err, unix.EINTR) {
+			return err
+		}
+		n, err := unix.Read(fd, buf)
+		if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read fanotify events: %w", err)
+		}
+
+		handles := make(map[string]string) // Directory handles resolved in this batch
+		overflow := parseEvents(buf[:n], func(handleType int32, handle []byte, name string, removed bool) {
+			key := string(handle)
+			dirPath, ok := handles[key]
+			if !ok {
+				dirPath = resolveHandle(mountFD, handleType, handle)
+				handles[key] = dirPath
+			}
+			if dirPath == "" {
+				return // Gone before it could be resolved
+			}
+			changed := dirPath
+			if name != "" && name != "." {
+				changed = filepath.Join(dirPath, name)
+			}
+			if changed == ownDir || strings.HasPrefix(changed, ownDir+string(filepath.Separator)) {
+				return
+			}
+			pending[changed] = removed
+		})
+		if overflow {
+			if err := flush(markerOverflow); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// parseEvents calls fn for every directory handle and name in a buffer of
+// fanotify events and reports whether the event queue overflowed
+func parseEvents(buf []byte, fn func(handleType int32, handle []byte, name string, removed bool)) bool {
+	overflow := false
+	for offset := 0; offset+eventMetadataSize <= len(buf); {
+		eventLen := int(binary.NativeEndian.Uint32(buf[offset:]))
+		metadataLen := int(binary.NativeEndian.Uint16(buf[offset+6:]))
+		mask := binary.NativeEndian.Uint64(buf[offset+8:])
+		if eventLen < eventMetadataSize || offset+eventLen > len(buf) {
+			break
+		}
+		if mask&unix.FAN_Q_OVERFLOW != 0 {
+			overflow = true
+		}
+		removed := mask&(unix.FAN_DELETE|unix.FAN_MOVED_FROM) != 0
+
+		for info := offset + metadataLen; info+4 <= offset+eventLen; {
+			infoType := buf[info]
+			infoLen := int(binary.NativeEndian.Uint16(buf[info+2:]))
+			if infoLen == 0 || info+infoLen > offset+eventLen {
+				break
+			}
+			if infoType == unix.FAN_EVENT_INFO_TYPE_DFID_NAME || infoType == unix.FAN_EVENT_INFO_TYPE_DFID {
+				// Header (4 bytes), fsid (8 bytes), then struct file_handle
+				h := info + 12
+				handleBytes := int(binary.NativeEndian.Uint32(buf[h:]))
+				handleType := int32(binary.NativeEndian.Uint32(buf[h+4:]))
+				if h+8+handleBytes <= info+infoLen {
+					handle := buf[h+8 : h+8+handleBytes]
+					name := ""
+					if infoType == unix.FAN_EVENT_INFO_TYPE_DFID_NAME {
+						name = string(buf[h+8+handleBytes : info+infoLen])
+						if end := strings.IndexByte(name, 0); end >= 0 {
+							name = name[:end]
+						}
+					}
+					fn(handleType, handle, name, removed)
+				}
+			}
+			info += infoLen
+		}
+		offset += eventLen
+	}
+	return overflow
+}
+
+// resolveHandle returns the current path of the directory a file handle refers to
+func resolveHandle(mountFD int, handleType int32, handle []byte) string {
+	fd, err := unix.OpenByHandleAt(mountFD, unix.NewFileHandle(handleType, append([]byte(nil), handle...)), unix.O_PATH|unix.O_CLOEXEC)
+	if err != nil {
+		return ""
+	}
+	defer unix.Close(fd)
+	path, err := os.Readlink("/proc/self/fd/" + strconv.Itoa(fd))
+	if err != nil || strings.HasSuffix(path, " (deleted)") {
+		return ""
+	}
+	return path
+}
+
+// fanotifySource reads the recording of a running recorder
+type fanotifySource struct {
+	device    uint64
+	statePath string
+	logPath   string
+}
+
+// openSource returns the recording of the filesystem holding root
+func openSource(root, dir string) (Source, error) {
+	device, err := filesystem.DeviceID(root)
+	if err != nil {
+		return nil, err
+	}
+	statePath, logPath := recordingFiles(dir, device)
+	source := &fanotifySource{device: device, statePath: statePath, logPath: logPath}
+	if _, err := source.running(); err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// running returns the state of the recorder, failing when none is running
+func (s *fanotifySource) running() (recording, error) {
+	var state recording
+	data, err := os.ReadFile(s.statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, fmt.Errorf("%w: no recorder is running, start one with \"fileops journal record\"", ErrUnsupported)
+	}
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("invalid recording state %s: %w", s.statePath, err)
+	}
+	if err := syscall.Kill(state.PID, 0); err != nil && !errors.Is(err, syscall.EPERM) {
+		return state, fmt.Errorf("%w: the recorder started %s is no longer running", ErrUnsupported, state.Started.Format(time.RFC3339))
+	}
+	return state, nil
+}
+
+// Current returns the end of the recording
+func (s *fanotifySource) Current() (Cursor, error) {
+	state, err := s.running()
+	if err != nil {
+		return Cursor{}, err
+	}
+	info, err := os.Stat(s.logPath)
+	if err != nil {
+		return Cursor{}, err
+	}
+	return Cursor{Backend: "fanotify", Volume: fmt.Sprintf("%x", s.device), Session: state.Session, Position: info.Size()}, nil
+}
+
+// Since reads the changes recorded after cursor
+func (s *fanotifySource) Since(ctx context.Context, cursor Cursor) ([]Change, Cursor, error) {
+	state, err := s.running()
+	if errors.Is(err, ErrUnsupported) {
+		return nil, cursor, fmt.Errorf("%w (%v)", ErrGap, err)
+	}
+	if err != nil {
+		return nil, cursor, err
+	}
+	if cursor.Backend != "fanotify" || cursor.Volume != fmt.Sprintf("%x", s.device) || cursor.Session != state.Session {
+		return nil, cursor, fmt.Errorf("%w: the recorder was restarted", ErrGap)
+	}
+
+	file, err := os.Open(s.logPath)
+	if err != nil {
+		return nil, cursor, err
+	}
+	defer file.Close()
+	if _, err := file.Seek(cursor.Position, io.SeekStart); err != nil {
+		return nil, cursor, err
+	}
+
+	changes := make([]Change, 0)
+	next := cursor
+	reader := bufio.NewReader(file)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, cursor, err
+		}
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			break // An incomplete line is read by the next run
+		}
+		if err != nil {
+			return nil, cursor, err
+		}
+		next.Position += int64(len(line))
+		line = strings.TrimSuffix(line, "\n")
+		if strings.HasPrefix(line, "#") {
+			return nil, cursor, fmt.Errorf("%w: %s", ErrGap, strings.TrimPrefix(line, "# "))
+		}
+		kind, path, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		changes = append(changes, Change{Path: path, Removed: kind == "D"})
+	}
+	return changes, next, nil
+}
+
+// Close releases the recording
+func (s *fanotifySource) Close() error {
+	return nil
+}
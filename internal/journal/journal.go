@@ -0,0 +1,112 @@
+package journal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// ErrUnsupported is returned when no change journal is available for a path
+var ErrUnsupported = errors.New("no change journal is available for this filesystem")
+
+// ErrGap is returned when the journal does not cover the whole time since a
+// cursor was taken (the journal wrapped, was recreated or the recorder stopped)
+var ErrGap = errors.New("the change journal does not cover the time since the last run")
+
+// Change is a path that was created, modified, renamed or removed
+type Change struct {
+	Path    string `json:"path"`
+	Removed bool   `json:"removed"` // Deleted or renamed away
+}
+
+// Cursor is a position in the change journal of a volume
+type Cursor struct {
+	Backend  string `json:"backend"`  // usn or fanotify
+	Volume   string `json:"volume"`   // Drive or device the journal belongs to
+	Session  string `json:"session"`  // Journal instance; a new one invalidates old cursors
+	Position int64  `json:"position"` // USN or recording offset
+}
+
+// Source reads the change journal of the volume holding a path
+type Source interface {
+	// Current returns the cursor of the latest recorded change
+	Current() (Cursor, error)
+	// Since returns the changes recorded after cursor and the cursor to continue from
+	Since(ctx context.Context, cursor Cursor) ([]Change, Cursor, error)
+	// Close releases the journal
+	Close() error
+}
+
+// Open returns the change journal of the volume holding root. Recordings of
+// the Linux recorder are kept in dir.
+func Open(root, dir string) (Source, error) {
+	return openSource(root, dir)
+}
+
+// Under returns the sorted, distinct paths of the changes at or below root
+func Under(changes []Change, root string) []string {
+	prefix := strings.TrimSuffix(root, string(filepath.Separator)) + string(filepath.Separator)
+	seen := make(map[string]bool)
+	paths := make([]string, 0)
+	for _, change := range changes {
+		if (change.Path == root || strings.HasPrefix(change.Path, prefix)) && !seen[change.Path] {
+			seen[change.Path] = true
+			paths = append(paths, change.Path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// cursorFile returns where the cursor of a consumer for root is stored
+func cursorFile(dir, consumer, root string) string {
+	sum := sha256.Sum256([]byte(root))
+	return filepath.Join(dir, "cursors", consumer+"-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+// storedCursor is a cursor with the root it belongs to
+type storedCursor struct {
+	Root   string `json:"root"`
+	Cursor Cursor `json:"cursor"`
+}
+
+// LoadCursor returns the cursor a consumer ("index", "checksum") saved for root
+func LoadCursor(dir, consumer, root string) (Cursor, bool, error) {
+	data, err := os.ReadFile(cursorFile(dir, consumer, root))
+	if errors.Is(err, os.ErrNotExist) {
+		return Cursor{}, false, nil
+	}
+	if err != nil {
+		return Cursor{}, false, err
+	}
+	var stored storedCursor
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return Cursor{}, false, fmt.Errorf("invalid journal cursor: %w", err)
+	}
+	if stored.Root != root {
+		return Cursor{}, false, nil
+	}
+	return stored.Cursor, true, nil
+}
+
+// SaveCursor records the cursor a consumer reached for root
+func SaveCursor(dir, consumer, root string, cursor Cursor) error {
+	path := cursorFile(dir, consumer, root)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(storedCursor{Root: root, Cursor: cursor}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return filesystem.WriteFileAtomic(path, data, 0o644)
+}
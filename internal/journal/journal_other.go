@@ -0,0 +1,15 @@
+//go:build !linux && !windows
+
+package journal
+
+import "context"
+
+// openSource reports that no change journal reader exists for this platform
+func openSource(root, dir string) (Source, error) {
+	return nil, ErrUnsupported
+}
+
+// Record reports that no change journal recorder exists for this platform
+func Record(ctx context.Context, path, dir string, flushed func(int)) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,234 @@
+//go:build windows
+
+package journal
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Control codes of the NTFS change journal
+const (
+	fsctlQueryUsnJournal = 0x000900f4
+	fsctlReadUsnJournal  = 0x000900bb
+)
+
+// Reasons of a USN record that take the name away from its path
+const (
+	usnReasonFileDelete    = 0x00000200
+	usnReasonRenameOldName = 0x00001000
+)
+
+var procOpenFileByID = windows.NewLazySystemDLL("kernel32.dll").NewProc("OpenFileById")
+
+// usnJournalData is USN_JOURNAL_DATA_V0
+type usnJournalData struct {
+	UsnJournalID    uint64
+	FirstUsn        int64
+	NextUsn         int64
+	LowestValidUsn  int64
+	MaxUsn          int64
+	MaximumSize     uint64
+	AllocationDelta uint64
+}
+
+// readUsnJournalData is READ_USN_JOURNAL_DATA_V0
+type readUsnJournalData struct {
+	StartUsn          int64
+	ReasonMask        uint32
+	ReturnOnlyOnClose uint32
+	Timeout           uint64
+	BytesToWaitFor    uint64
+	UsnJournalID      uint64
+}
+
+// fileIDDescriptor is FILE_ID_DESCRIPTOR
+type fileIDDescriptor struct {
+	Size   uint32
+	Type   uint32 // 0 for 64-bit, 2 for 128-bit file IDs
+	FileID [16]byte
+}
+
+// usnSource reads the USN change journal of an NTFS or ReFS volume
+type usnSource struct {
+	volume  string
+	handle  windows.Handle
+	parents map[[16]byte]string // Resolved directory paths by file ID
+}
+
+// openSource opens the change journal of the volume holding root. Reading it
+// requires administrator rights.
+func openSource(root, dir string) (Source, error) {
+	volume := filepath.VolumeName(root)
+	if len(volume) != 2 || volume[1] != ':' {
+		return nil, fmt.Errorf("%w: %s is not on a local drive", ErrUnsupported, root)
+	}
+	path, err := windows.UTF16PtrFromString(`\\.\` + volume)
+	if err != nil {
+		return nil, err
+	}
+	handle, err := windows.CreateFile(path, windows.GENERIC_READ,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		nil, windows.OPEN_EXISTING, 0, 0)
+	if errors.Is(err, windows.ERROR_ACCESS_DENIED) {
+		return nil, fmt.Errorf("%w: reading the USN journal of %s requires administrator rights", ErrUnsupported, volume)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open volume %s: %w", volume, err)
+	}
+	return &usnSource{volume: volume, handle: handle, parents: make(map[[16]byte]string)}, nil
+}
+
+// query returns the state of the journal
+func (s *usnSource) query() (usnJournalData, error) {
+	var data usnJournalData
+	var returned uint32
+	err := windows.DeviceIoControl(s.handle, fsctlQueryUsnJournal, nil, 0,
+		(*byte)(unsafe.Pointer(&data)), uint32(unsafe.Sizeof(data)), &returned, nil)
+	if errors.Is(err, windows.ERROR_JOURNAL_NOT_ACTIVE) {
+		return data, fmt.Errorf("%w: the USN journal of %s is not active", ErrUnsupported, s.volume)
+	}
+	if err != nil {
+		return data, fmt.Errorf("failed to query the USN journal of %s: %w", s.volume, err)
+	}
+	return data, nil
+}
+
+// Current returns the next USN of the journal
+func (s *usnSource) Current() (Cursor, error) {
+	data, err := s.query()
+	if err != nil {
+		return Cursor{}, err
+	}
+	return Cursor{Backend: "usn", Volume: s.volume, Session: strconv.FormatUint(data.UsnJournalID, 16), Position: data.NextUsn}, nil
+}
+
+// Since reads the records written after cursor
+func (s *usnSource) Since(ctx context.Context, cursor Cursor) ([]Change, Cursor, error) {
+	data, err := s.query()
+	if err != nil {
+		return nil, cursor, err
+	}
+	if cursor.Backend != "usn" || cursor.Volume != s.volume || cursor.Session != strconv.FormatUint(data.UsnJournalID, 16) {
+		return nil, cursor, fmt.Errorf("%w: the USN journal of %s was recreated", ErrGap, s.volume)
+	}
+	if cursor.Position < data.LowestValidUsn {
+		return nil, cursor, fmt.Errorf("%w: the USN journal of %s wrapped", ErrGap, s.volume)
+	}
+
+	changes := make([]Change, 0)
+	request := readUsnJournalData{StartUsn: cursor.Position, ReasonMask: 0xffffffff, UsnJournalID: data.UsnJournalID}
+	buf := make([]byte, 1024*1024)
+	for request.StartUsn < data.NextUsn {
+		if err := ctx.Err(); err != nil {
+			return nil, cursor, err
+		}
+		var returned uint32
+		err := windows.DeviceIoControl(s.handle, fsctlReadUsnJournal,
+			(*byte)(unsafe.Pointer(&request)), uint32(unsafe.Sizeof(request)),
+			&buf[0], uint32(len(buf)), &returned, nil)
+		if errors.Is(err, windows.ERROR_JOURNAL_ENTRY_DELETED) {
+			return nil, cursor, fmt.Errorf("%w: the USN journal of %s wrapped", ErrGap, s.volume)
+		}
+		if err != nil {
+			return nil, cursor, fmt.Errorf("failed to read the USN journal of %s: %w", s.volume, err)
+		}
+		if returned <= 8 {
+			break
+		}
+		request.StartUsn = int64(binary.LittleEndian.Uint64(buf))
+		changes = append(changes, s.parseRecords(buf[8:returned])...)
+	}
+	return changes, Cursor{Backend: cursor.Backend, Volume: s.volume, Session: cursor.Session, Position: data.NextUsn}, nil
+}
+
+// parseRecords converts USN_RECORD_V2 and V3 records to changes
+func (s *usnSource) parseRecords(buf []byte) []Change {
+	changes := make([]Change, 0)
+	for offset := 0; offset+8 <= len(buf); {
+		length := int(binary.LittleEndian.Uint32(buf[offset:]))
+		if length == 0 || offset+length > len(buf) {
+			break
+		}
+		record := buf[offset : offset+length]
+		offset += length
+
+		var parent [16]byte
+		var reason uint32
+		var nameLength, nameOffset int
+		switch binary.LittleEndian.Uint16(record[4:]) {
+		case 2:
+			copy(parent[:8], record[16:24])
+			reason = binary.LittleEndian.Uint32(record[40:])
+			nameLength = int(binary.LittleEndian.Uint16(record[56:]))
+			nameOffset = int(binary.LittleEndian.Uint16(record[58:]))
+		case 3:
+			copy(parent[:], record[24:40])
+			reason = binary.LittleEndian.Uint32(record[56:])
+			nameLength = int(binary.LittleEndian.Uint16(record[72:]))
+			nameOffset = int(binary.LittleEndian.Uint16(record[74:]))
+		default:
+			continue
+		}
+		if nameOffset+nameLength > len(record) {
+			continue
+		}
+
+		dir := s.resolve(parent, binary.LittleEndian.Uint16(record[4:]) == 3)
+		if dir == "" {
+			continue // Parent is gone as well; its own record reports it
+		}
+		name := make([]uint16, nameLength/2)
+		for i := range name {
+			name[i] = binary.LittleEndian.Uint16(record[nameOffset+2*i:])
+		}
+		changes = append(changes, Change{
+			Path:    filepath.Join(dir, windows.UTF16ToString(name)),
+			Removed: reason&(usnReasonFileDelete|usnReasonRenameOldName) != 0,
+		})
+	}
+	return changes
+}
+
+// resolve returns the current path of a directory by its file ID
+func (s *usnSource) resolve(id [16]byte, extended bool) string {
+	if path, ok := s.parents[id]; ok {
+		return path
+	}
+	descriptor := fileIDDescriptor{Size: uint32(unsafe.Sizeof(fileIDDescriptor{})), FileID: id}
+	if extended {
+		descriptor.Type = 2
+	}
+	// FILE_FLAG_BACKUP_SEMANTICS is required to open directories
+	r, _, _ := procOpenFileByID.Call(uintptr(s.handle), uintptr(unsafe.Pointer(&descriptor)), 0,
+		uintptr(windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE),
+		0, uintptr(windows.FILE_FLAG_BACKUP_SEMANTICS))
+	path := ""
+	if handle := windows.Handle(r); handle != windows.InvalidHandle {
+		buf := make([]uint16, windows.MAX_LONG_PATH)
+		if n, err := windows.GetFinalPathNameByHandle(handle, &buf[0], uint32(len(buf)), 0); err == nil && int(n) < len(buf) {
+			path = strings.TrimPrefix(windows.UTF16ToString(buf[:n]), `\\?\`)
+		}
+		windows.CloseHandle(handle)
+	}
+	s.parents[id] = path
+	return path
+}
+
+// Close releases the volume handle
+func (s *usnSource) Close() error {
+	return windows.CloseHandle(s.handle)
+}
+
+// Record is not needed on Windows, where NTFS maintains the USN journal itself
+func Record(ctx context.Context, path, dir string, flushed func(int)) error {
+	return errors.New("Windows maintains the USN change journal itself, no recorder is needed")
+}
@@ -0,0 +1,251 @@
+package lock
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// registryLockName is the global lock guarding reads/writes of root lock files
+const registryLockName = "registry.lock"
+
+// staleRegistryAge is how long a registry lock may be held before it is considered abandoned
+const staleRegistryAge = 30 * time.Second
+
+// Info describes the holder of a root lock
+type Info struct {
+	OperationID string    `json:"operation_id"`
+	Root        string    `json:"root"`
+	PID         int       `json:"pid"`
+	Hostname    string    `json:"hostname"`
+	AcquiredAt  time.Time `json:"acquired_at"`
+}
+
+// ConflictError is returned when a requested root overlaps a root locked by another operation
+type ConflictError struct {
+	Root   string
+	Holder Info
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("path %s overlaps %s locked by operation %s (pid %d on %s since %s)",
+		e.Root, e.Holder.Root, e.Holder.OperationID, e.Holder.PID, e.Holder.Hostname,
+		e.Holder.AcquiredAt.Format(time.RFC3339))
+}
+
+// Manager hands out advisory locks on target roots.
+//
+// Each locked root is represented by a JSON lock file in the lock directory.
+// Acquisition happens under a short-lived global registry lock so overlapping
+// roots (one being a parent of the other) are detected atomically.
+type Manager struct {
+	dir string
+}
+
+// NewManager creates a lock manager storing lock files in dir
+func NewManager(dir string) *Manager {
+	return &Manager{dir: dir}
+}
+
+// Lock is a set of held root locks belonging to one operation
+type Lock struct {
+	manager *Manager
+	files   []string
+}
+
+// Acquire locks all roots for operationID. If another live operation holds an
+// overlapping root, Acquire waits up to wait (polling) and then returns a
+// *ConflictError. A zero wait refuses immediately.
+func (m *Manager) Acquire(ctx context.Context, operationID string, roots []string, wait time.Duration) (*Lock, error) {
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		lock, err := m.tryAcquire(operationID, roots)
+		if err == nil {
+			return lock, nil
+		}
+
+		var conflict *ConflictError
+		if !errors.As(err, &conflict) || time.Now().After(deadline) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// tryAcquire makes a single attempt to lock all roots
+func (m *Manager) tryAcquire(operationID string, roots []string) (*Lock, error) {
+	release, err := m.lockRegistry()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	held, err := m.liveLocks()
+	if err != nil {
+		return nil, err
+	}
+
+	cleaned := make([]string, 0, len(roots))
+	for _, root := range roots {
+		root = filepath.Clean(root)
+		for _, holder := range held {
+			if overlaps(root, holder.Root) {
+				return nil, &ConflictError{Root: root, Holder: holder}
+			}
+		}
+		cleaned = append(cleaned, root)
+	}
+
+	hostname, _ := os.Hostname()
+	lock := &Lock{manager: m}
+	for _, root := range cleaned {
+		info := Info{
+			OperationID: operationID,
+			Root:        root,
+			PID:         os.Getpid(),
+			Hostname:    hostname,
+			AcquiredAt:  time.Now(),
+		}
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			_ = lock.Release()
+			return nil, err
+		}
+
+		file := filepath.Join(m.dir, lockFileName(root))
+		if err := os.WriteFile(file, data, 0644); err != nil {
+			_ = lock.Release()
+			return nil, fmt.Errorf("failed to write lock file: %w", err)
+		}
+		lock.files = append(lock.files, file)
+	}
+
+	return lock, nil
+}
+
+// Release removes all lock files held by the lock
+func (l *Lock) Release() error {
+	if l == nil {
+		return nil
+	}
+
+	var firstErr error
+	for _, file := range l.files {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) && firstErr == nil {
+			firstErr = err
+		}
+	}
+	l.files = nil
+	return firstErr
+}
+
+// List returns all currently held (live) locks
+func (m *Manager) List() ([]Info, error) {
+	release, err := m.lockRegistry()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return m.liveLocks()
+}
+
+// liveLocks reads all root lock files, removing those left behind by dead processes
+func (m *Manager) liveLocks() ([]Info, error) {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+	locks := make([]Info, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() == registryLockName || !strings.HasSuffix(entry.Name(), ".lock") {
+			continue
+		}
+
+		file := filepath.Join(m.dir, entry.Name())
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+
+		var info Info
+		if err := json.Unmarshal(data, &info); err != nil {
+			continue
+		}
+
+		// Locks from crashed processes on this host are stale and can be reclaimed
+		if info.Hostname == hostname && !processAlive(info.PID) {
+			_ = os.Remove(file)
+			continue
+		}
+
+		locks = append(locks, info)
+	}
+
+	return locks, nil
+}
+
+// lockRegistry takes the global registry lock using exclusive file creation
+func (m *Manager) lockRegistry() (func(), error) {
+	path := filepath.Join(m.dir, registryLockName)
+	deadline := time.Now().Add(staleRegistryAge)
+
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(file, "%d\n", os.Getpid())
+			file.Close()
+			return func() { _ = os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to acquire registry lock: %w", err)
+		}
+
+		// Break registry locks abandoned by a crash
+		if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) > staleRegistryAge {
+			_ = os.Remove(path)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for registry lock %s", path)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// overlaps reports whether one path equals or contains the other
+func overlaps(a, b string) bool {
+	if a == b {
+		return true
+	}
+	sep := string(filepath.Separator)
+	return strings.HasPrefix(a, strings.TrimSuffix(b, sep)+sep) ||
+		strings.HasPrefix(b, strings.TrimSuffix(a, sep)+sep)
+}
+
+// lockFileName derives a stable lock file name for a root
+func lockFileName(root string) string {
+	sum := sha256.Sum256([]byte(root))
+	return fmt.Sprintf("%x.lock", sum[:8])
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/a4abhishek/fileops/internal/ui"
 	"github.com/fatih/color"
 )
 
@@ -161,7 +162,8 @@ func (l *Logger) logConsole(level LogLevel, icon, msg string, keysAndValues ...i
 		output += " " + formatKeyValues(keysAndValues...)
 	}
 
-	os.Stdout.WriteString(output + "\n")
+	// Through the terminal writer, above the progress line of a running operation
+	_, _ = ui.Stdout.Write([]byte(output + "\n"))
 }
 
 // logFile logs to file
@@ -0,0 +1,292 @@
+// Package mail reads the attachments of mbox archives and EML messages.
+// Outlook PST files are not read; convert them to mbox first (e.g. with
+// readpst).
+package mail
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrMalformed is returned for archives that contain no readable message
+var ErrMalformed = errors.New("malformed mail archive")
+
+// Extensions are the file extensions of supported archives
+var Extensions = []string{".mbox", ".mbx", ".eml"}
+
+// maxDepth limits how deep multiparts and forwarded messages are followed
+const maxDepth = 16
+
+// Attachment is a file attached to a message of an archive
+type Attachment struct {
+	Archive     string    // mbox or EML file holding the message
+	Message     int       // Position of the message in the archive, from 1
+	MessageID   string    // Message-ID header, without angle brackets
+	Subject     string    // Decoded subject
+	From        string    // Sender address
+	Date        time.Time // Date the message was sent, zero when unknown
+	Filename    string    // Decoded attachment name
+	ContentType string    // Media type, e.g. application/pdf
+	Data        []byte    // Decoded content
+}
+
+// Path names the attachment as if it were a file below its archive, e.g.
+// /mail/inbox.mbox#12/invoice.pdf for an attachment of the 12th message
+func (a *Attachment) Path() string {
+	return fmt.Sprintf("%s#%d/%s", a.Archive, a.Message, a.Filename)
+}
+
+// Supported reports whether path is an archive by its extension
+func Supported(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, supported := range Extensions {
+		if ext == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// Walk calls fn for every attachment in the archive at path, in order. EML
+// files hold one message; anything else is read as an mbox. Messages that
+// cannot be parsed are skipped. Walk stops at the first error fn returns.
+func Walk(path string, fn func(*Attachment) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".eml") {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return err
+		}
+		if err := walkMessage(path, 1, data, fn); err != nil && !errors.Is(err, ErrMalformed) {
+			return err
+		}
+		return nil
+	}
+
+	messages, parsed := 0, 0
+	err = splitMbox(file, func(data []byte) error {
+		messages++
+		err := walkMessage(path, messages, data, fn)
+		if errors.Is(err, ErrMalformed) {
+			return nil
+		}
+		parsed++
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if messages > 0 && parsed == 0 {
+		return fmt.Errorf("%w: no message of %s could be read", ErrMalformed, path)
+	}
+	return nil
+}
+
+// splitMbox calls fn with each message of an mbox. Messages start at "From "
+// lines; quoted ">From " lines inside them are unquoted (mboxrd).
+func splitMbox(r io.Reader, fn func([]byte) error) error {
+	reader := bufio.NewReaderSize(r, 64*1024)
+	var message bytes.Buffer
+	started, blank := false, true
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if blank && bytes.HasPrefix(line, []byte("From ")) {
+				if started {
+					if err := fn(message.Bytes()); err != nil {
+						return err
+					}
+				}
+				message.Reset()
+				started = true
+			} else if started {
+				if unquoted := bytes.TrimLeft(line, ">"); len(unquoted) < len(line) && bytes.HasPrefix(unquoted, []byte("From ")) {
+					line = line[1:]
+				}
+				message.Write(line)
+			}
+			blank = len(bytes.TrimRight(line, "\r\n")) == 0
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if started {
+		return fn(message.Bytes())
+	}
+	return nil
+}
+
+// headerDecoder decodes RFC 2047 encoded words in headers and names
+var headerDecoder = &mime.WordDecoder{}
+
+// walkMessage calls fn for the attachments of one message
+func walkMessage(archive string, number int, data []byte, fn func(*Attachment) error) error {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	template := Attachment{
+		Archive:   archive,
+		Message:   number,
+		MessageID: strings.Trim(strings.TrimSpace(msg.Header.Get("Message-ID")), "<>"),
+		Subject:   decodeHeader(msg.Header.Get("Subject")),
+	}
+	if from, err := mail.ParseAddress(msg.Header.Get("From")); err == nil {
+		template.From = strings.ToLower(from.Address)
+	}
+	if date, err := msg.Header.Date(); err == nil {
+		template.Date = date
+	}
+	return walkPart(template, msg.Header, msg.Body, 0, fn)
+}
+
+// header is what walkPart needs of a mail.Header or textproto.MIMEHeader
+type header interface {
+	Get(key string) string
+}
+
+// walkPart calls fn for the attachments in one MIME part and its children
+func walkPart(template Attachment, h header, body io.Reader, depth int, fn func(*Attachment) error) error {
+	if depth > maxDepth {
+		return nil
+	}
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if params["boundary"] == "" {
+			return nil
+		}
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return nil // A truncated multipart ends the message
+			}
+			if err := walkPart(template, part.Header, part, depth+1, fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	name := dispositionParams["filename"]
+	if name == "" {
+		name = params["name"]
+	}
+
+	// Forwarded messages carry attachments of their own
+	if mediaType == "message/rfc822" && disposition != "attachment" {
+		data, err := io.ReadAll(decodeBody(h, body))
+		if err != nil {
+			return nil
+		}
+		forwarded, err := mail.ReadMessage(bytes.NewReader(data))
+		if err != nil {
+			return nil
+		}
+		return walkPart(template, forwarded.Header, forwarded.Body, depth+1, fn)
+	}
+
+	if disposition != "attachment" && name == "" {
+		return nil // Message text
+	}
+	data, err := io.ReadAll(decodeBody(h, body))
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	attachment := template
+	attachment.Filename = cleanName(decodeHeader(name), mediaType)
+	attachment.ContentType = mediaType
+	attachment.Data = data
+	return fn(&attachment)
+}
+
+// decodeBody undoes the transfer encoding of a part
+func decodeBody(h header, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(h.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &base64Cleaner{r: body})
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+	return body
+}
+
+// base64Cleaner drops line breaks and other bytes that are not base64, which
+// mailers insert freely
+type base64Cleaner struct {
+	r io.Reader
+}
+
+// Read reads base64 characters only
+func (c *base64Cleaner) Read(p []byte) (int, error) {
+	for {
+		n, err := c.r.Read(p)
+		kept := 0
+		for _, b := range p[:n] {
+			if b >= 'A' && b <= 'Z' || b >= 'a' && b <= 'z' || b >= '0' && b <= '9' || b == '+' || b == '/' || b == '=' {
+				p[kept] = b
+				kept++
+			}
+		}
+		if kept > 0 || err != nil {
+			return kept, err
+		}
+	}
+}
+
+// decodeHeader decodes encoded words, keeping the raw value when it cannot
+func decodeHeader(value string) string {
+	decoded, err := headerDecoder.DecodeHeader(value)
+	if err != nil {
+		return strings.TrimSpace(value)
+	}
+	return strings.TrimSpace(decoded)
+}
+
+// cleanName makes an attachment name safe to use as a file name, naming
+// unnamed attachments after their media type
+func cleanName(name, mediaType string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = filepath.Base(strings.TrimSpace(name))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`<>:"|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == "/" || name == ".." {
+		name = "attachment"
+		if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			name += exts[0]
+		}
+	}
+	return name
+}
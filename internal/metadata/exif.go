@@ -0,0 +1,267 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// TIFF tags that point to sub-directories
+const (
+	tagExifIFD    = 0x8769
+	tagGPSIFD     = 0x8825
+	tagInteropIFD = 0xA005
+)
+
+// exifTagNames names the tags users are likely to whitelist; others are reported as Tag0xNNNN
+var exifTagNames = map[uint16]string{
+	0x010E: "ImageDescription",
+	0x010F: "Make",
+	0x0110: "Model",
+	0x0112: "Orientation",
+	0x011A: "XResolution",
+	0x011B: "YResolution",
+	0x0128: "ResolutionUnit",
+	0x0131: "Software",
+	0x0132: "DateTime",
+	0x013B: "Artist",
+	0x0213: "YCbCrPositioning",
+	0x8769: "ExifIFD",
+	0x8825: "GPS",
+	0x8298: "Copyright",
+	0x829A: "ExposureTime",
+	0x829D: "FNumber",
+	0x8822: "ExposureProgram",
+	0x8827: "ISOSpeedRatings",
+	0x9000: "ExifVersion",
+	0x9003: "DateTimeOriginal",
+	0x9004: "DateTimeDigitized",
+	0x9010: "OffsetTime",
+	0x9011: "OffsetTimeOriginal",
+	0x9201: "ShutterSpeedValue",
+	0x9202: "ApertureValue",
+	0x9209: "Flash",
+	0x920A: "FocalLength",
+	0x927C: "MakerNote",
+	0x9286: "UserComment",
+	0x9C9B: "XPTitle",
+	0x9C9C: "XPComment",
+	0x9C9D: "XPAuthor",
+	0x9C9E: "XPKeywords",
+	0x9C9F: "XPSubject",
+	0xA000: "FlashpixVersion",
+	0xA001: "ColorSpace",
+	0xA002: "PixelXDimension",
+	0xA003: "PixelYDimension",
+	0xA420: "ImageUniqueID",
+	0xA430: "CameraOwnerName",
+	0xA431: "BodySerialNumber",
+	0xA433: "LensMake",
+	0xA434: "LensModel",
+	0xA435: "LensSerialNumber",
+}
+
+// tiffTypeSizes maps TIFF field types to their size in bytes
+var tiffTypeSizes = map[uint16]uint32{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+// tiffEntry is a directory entry with its value bytes resolved
+type tiffEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+// exifTagName returns the display name of a tag
+func exifTagName(tag uint16) string {
+	if name, ok := exifTagNames[tag]; ok {
+		return name
+	}
+	return fmt.Sprintf("Tag0x%04X", tag)
+}
+
+// filterExif rebuilds a TIFF/EXIF block keeping only whitelisted tags. It
+// returns nil when nothing is kept. GPS data is kept or dropped as a whole
+// (field name "GPS"); the embedded thumbnail directory is always dropped.
+func (s *Stripper) filterExif(tiff []byte) ([]byte, []string, error) {
+	if len(tiff) < 8 {
+		return nil, nil, fmt.Errorf("truncated EXIF header")
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, nil, fmt.Errorf("invalid EXIF byte order")
+	}
+
+	ifd0, _, err := readIFD(tiff, order, order.Uint32(tiff[4:8]))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	removed := make([]string, 0)
+	var keptIFD0, keptExif, keptGPS []tiffEntry
+
+	for _, entry := range ifd0 {
+		if (entry.tag == tagExifIFD || entry.tag == tagGPSIFD) && len(entry.value) != 4 {
+			removed = append(removed, "EXIF:"+exifTagName(entry.tag))
+			continue // Malformed sub-directory pointer
+		}
+
+		switch entry.tag {
+		case tagExifIFD:
+			exif, _, err := readIFD(tiff, order, order.Uint32(entry.value))
+			if err != nil {
+				removed = append(removed, "EXIF:ExifIFD")
+				continue
+			}
+			for _, sub := range exif {
+				if sub.tag == tagInteropIFD {
+					continue
+				}
+				if name := exifTagName(sub.tag); s.keeps(name) {
+					keptExif = append(keptExif, sub)
+				} else {
+					removed = append(removed, "EXIF:"+name)
+				}
+			}
+		case tagGPSIFD:
+			if !s.keeps("GPS") {
+				removed = append(removed, "EXIF:GPS")
+				continue
+			}
+			if gps, _, err := readIFD(tiff, order, order.Uint32(entry.value)); err == nil {
+				keptGPS = gps
+			}
+		default:
+			if name := exifTagName(entry.tag); s.keeps(name) {
+				keptIFD0 = append(keptIFD0, entry)
+			} else {
+				removed = append(removed, "EXIF:"+name)
+			}
+		}
+	}
+
+	if len(keptIFD0) == 0 && len(keptExif) == 0 && len(keptGPS) == 0 {
+		return nil, removed, nil
+	}
+	return writeTIFF(order, keptIFD0, keptExif, keptGPS), removed, nil
+}
+
+// readIFD parses the directory at offset, resolving out-of-line values
+func readIFD(tiff []byte, order binary.ByteOrder, offset uint32) ([]tiffEntry, uint32, error) {
+	if uint64(offset)+2 > uint64(len(tiff)) {
+		return nil, 0, fmt.Errorf("EXIF directory out of range")
+	}
+	count := uint32(order.Uint16(tiff[offset:]))
+	end := uint64(offset) + 2 + uint64(count)*12
+	if end+4 > uint64(len(tiff)) {
+		return nil, 0, fmt.Errorf("truncated EXIF directory")
+	}
+
+	entries := make([]tiffEntry, 0, count)
+	for i := uint32(0); i < count; i++ {
+		raw := tiff[offset+2+i*12 : offset+2+(i+1)*12]
+		entry := tiffEntry{
+			tag:   order.Uint16(raw[0:2]),
+			typ:   order.Uint16(raw[2:4]),
+			count: order.Uint32(raw[4:8]),
+		}
+
+		size, ok := tiffTypeSizes[entry.typ]
+		if !ok {
+			continue // Unknown type, cannot be copied safely
+		}
+		length := uint64(size) * uint64(entry.count)
+		if length <= 4 {
+			entry.value = append([]byte(nil), raw[8:8+length]...)
+		} else {
+			start := uint64(order.Uint32(raw[8:12]))
+			if start+length > uint64(len(tiff)) {
+				continue // Corrupt pointer; drop the entry
+			}
+			entry.value = append([]byte(nil), tiff[start:start+length]...)
+		}
+		entries = append(entries, entry)
+	}
+
+	next := order.Uint32(tiff[end : end+4])
+	return entries, next, nil
+}
+
+// writeTIFF serializes IFD0 with optional Exif and GPS sub-directories
+func writeTIFF(order binary.ByteOrder, ifd0, exif, gps []tiffEntry) []byte {
+	buf := make([]byte, 8)
+	if order == binary.LittleEndian {
+		copy(buf, "II")
+	} else {
+		copy(buf, "MM")
+	}
+	order.PutUint16(buf[2:], 42)
+	order.PutUint32(buf[4:], 8)
+
+	// Pointer entries are patched once the sub-directories have been placed
+	if len(exif) > 0 {
+		ifd0 = append(ifd0, tiffEntry{tag: tagExifIFD, typ: 4, count: 1, value: make([]byte, 4)})
+	}
+	if len(gps) > 0 {
+		ifd0 = append(ifd0, tiffEntry{tag: tagGPSIFD, typ: 4, count: 1, value: make([]byte, 4)})
+	}
+	sortEntries(ifd0)
+
+	buf, pointers := appendIFD(buf, order, ifd0)
+	if len(exif) > 0 {
+		order.PutUint32(buf[pointers[tagExifIFD]:], uint32(len(buf)))
+		buf, _ = appendIFD(buf, order, exif)
+	}
+	if len(gps) > 0 {
+		order.PutUint32(buf[pointers[tagGPSIFD]:], uint32(len(buf)))
+		buf, _ = appendIFD(buf, order, gps)
+	}
+	return buf
+}
+
+// appendIFD writes a directory followed by its out-of-line values. It returns
+// the positions of the inline value fields of sub-directory pointer entries.
+func appendIFD(buf []byte, order binary.ByteOrder, entries []tiffEntry) ([]byte, map[uint16]int) {
+	start := len(buf)
+	dataStart := start + 2 + len(entries)*12 + 4
+	buf = append(buf, make([]byte, dataStart-start)...)
+	order.PutUint16(buf[start:], uint16(len(entries)))
+
+	pointers := make(map[uint16]int)
+	for i, entry := range entries {
+		pos := start + 2 + i*12
+		order.PutUint16(buf[pos:], entry.tag)
+		order.PutUint16(buf[pos+2:], entry.typ)
+		order.PutUint32(buf[pos+4:], entry.count)
+		if len(entry.value) <= 4 {
+			copy(buf[pos+8:pos+12], entry.value)
+			if entry.tag == tagExifIFD || entry.tag == tagGPSIFD {
+				pointers[entry.tag] = pos + 8
+			}
+			continue
+		}
+		if len(buf)%2 == 1 {
+			buf = append(buf, 0) // Values start on word boundaries
+		}
+		order.PutUint32(buf[pos+8:], uint32(len(buf)))
+		buf = append(buf, entry.value...)
+	}
+	// Next-IFD offset stays zero: no thumbnail directory is written
+	if len(buf)%2 == 1 {
+		buf = append(buf, 0)
+	}
+	return buf, pointers
+}
+
+// sortEntries orders entries by tag as required by the TIFF specification
+func sortEntries(entries []tiffEntry) {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].tag < entries[j].tag })
+}
@@ -0,0 +1,102 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// JPEG markers relevant to metadata
+const (
+	markerSOS  = 0xDA
+	markerAPP1 = 0xE1
+	markerAPP2 = 0xE2
+	markerAPPD = 0xED
+	markerCOM  = 0xFE
+)
+
+var (
+	exifHeader = []byte("Exif\x00\x00")
+	xmpHeader  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	iccHeader  = []byte("ICC_PROFILE\x00")
+)
+
+// stripJPEG rewrites the marker segments of a JPEG, filtering EXIF and
+// dropping XMP, IPTC/Photoshop and comment segments. Image data is copied as is.
+func (s *Stripper) stripJPEG(data []byte) ([]byte, []string, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, nil, fmt.Errorf("not a JPEG file")
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:2])
+	removed := make([]string, 0)
+
+	pos := 2
+	for pos < len(data) {
+		if data[pos] != 0xFF {
+			return nil, nil, fmt.Errorf("invalid JPEG marker at offset %d", pos)
+		}
+		marker := data[pos+1]
+
+		// Standalone markers carry no length
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) || marker == 0xFF {
+			out.Write(data[pos : pos+2])
+			pos += 2
+			continue
+		}
+
+		if pos+4 > len(data) {
+			return nil, nil, fmt.Errorf("truncated JPEG segment")
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, nil, fmt.Errorf("truncated JPEG segment")
+		}
+		payload := data[pos+4 : end]
+
+		// Entropy-coded image data follows the start of scan; copy the rest verbatim
+		if marker == markerSOS {
+			out.Write(data[pos:])
+			break
+		}
+
+		switch {
+		case marker == markerAPP1 && bytes.HasPrefix(payload, exifHeader):
+			kept, names, err := s.filterExif(payload[len(exifHeader):])
+			if err != nil {
+				// Unparseable EXIF cannot be filtered; drop it entirely
+				removed = append(removed, "EXIF")
+				break
+			}
+			removed = append(removed, names...)
+			if kept != nil {
+				writeSegment(out, markerAPP1, append(append([]byte(nil), exifHeader...), kept...))
+			}
+		case marker == markerAPP1 && bytes.HasPrefix(payload, xmpHeader) && !s.keeps("XMP"):
+			removed = append(removed, "XMP")
+		case marker == markerAPPD && !s.keeps("IPTC"):
+			removed = append(removed, "IPTC")
+		case marker == markerCOM && !s.keeps("Comment"):
+			removed = append(removed, "Comment")
+		case marker == markerAPP2 && bytes.HasPrefix(payload, iccHeader) && !s.keeps("ICC"):
+			removed = append(removed, "ICC")
+		default:
+			out.Write(data[pos:end])
+		}
+		pos = end
+	}
+
+	return out.Bytes(), removed, nil
+}
+
+// writeSegment writes a marker segment; oversized payloads are dropped
+func writeSegment(out *bytes.Buffer, marker byte, payload []byte) {
+	if len(payload)+2 > 0xFFFF {
+		return
+	}
+	out.Write([]byte{0xFF, marker})
+	_ = binary.Write(out, binary.BigEndian, uint16(len(payload)+2))
+	out.Write(payload)
+}
@@ -0,0 +1,136 @@
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrUnsupported is returned for file formats the stripper does not handle
+var ErrUnsupported = errors.New("unsupported file format")
+
+// ErrMalformed is returned for files whose structure cannot be parsed
+var ErrMalformed = errors.New("malformed file")
+
+// DefaultKeep are the fields kept when no whitelist is given; they affect how
+// a file is displayed rather than who made it or where
+var DefaultKeep = []string{"Orientation", "ColorSpace", "ICC"}
+
+// Report describes the metadata found (and removed, unless dry run) in a file
+type Report struct {
+	Path    string   `json:"path"`
+	Format  string   `json:"format"`
+	Removed []string `json:"removed"` // Field names prefixed with their group, e.g. "EXIF:GPS"
+}
+
+// Stripper removes privacy-sensitive metadata from images and documents
+type Stripper struct {
+	keep map[string]bool
+}
+
+// NewStripper creates a stripper that keeps the given field names (case-insensitive)
+func NewStripper(keep []string) *Stripper {
+	s := &Stripper{keep: make(map[string]bool, len(keep))}
+	for _, name := range keep {
+		s.keep[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	return s
+}
+
+// Supported reports whether the format of path is handled
+func Supported(path string) bool {
+	return formatOf(path) != ""
+}
+
+// keeps reports whether the named field is whitelisted
+func (s *Stripper) keeps(name string) bool {
+	return s.keep[strings.ToLower(name)]
+}
+
+// Strip removes metadata from the file at path. In dry run mode the file is
+// left untouched and the report lists what would be removed. The modification
+// time and permissions of the file are preserved.
+func (s *Stripper) Strip(path string, dryRun bool) (*Report, error) {
+	format := formatOf(path)
+	if format == "" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var stripped []byte
+	var removed []string
+	switch format {
+	case "jpeg":
+		stripped, removed, err = s.stripJPEG(data)
+	case "png":
+		stripped, removed, err = s.stripPNG(data)
+	case "pdf":
+		stripped, removed, err = s.stripPDF(data)
+	case "office":
+		stripped, removed, err = s.stripOffice(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %v", ErrMalformed, path, err)
+	}
+
+	report := &Report{Path: path, Format: format, Removed: removed}
+	if dryRun || len(removed) == 0 {
+		return report, nil
+	}
+
+	if err := replaceFile(path, stripped, info); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// formatOf maps a file extension to a handled format
+func formatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg":
+		return "jpeg"
+	case ".png":
+		return "png"
+	case ".pdf":
+		return "pdf"
+	case ".docx", ".xlsx", ".pptx", ".docm", ".xlsm", ".pptm":
+		return "office"
+	}
+	return ""
+}
+
+// replaceFile atomically replaces path with data, keeping its mode and modification time
+func replaceFile(path string, data []byte, info os.FileInfo) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".fileops-strip-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := os.Chtimes(tmpName, time.Now(), info.ModTime()); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, path)
+}
@@ -0,0 +1,126 @@
+package metadata
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+// officeProperties maps document property elements to the names used in reports and whitelists
+var officeProperties = map[string][]struct{ element, name string }{
+	"docProps/core.xml": {
+		{"dc:title", "Title"},
+		{"dc:subject", "Subject"},
+		{"dc:creator", "Creator"},
+		{"dc:description", "Description"},
+		{"cp:keywords", "Keywords"},
+		{"cp:category", "Category"},
+		{"cp:lastModifiedBy", "LastModifiedBy"},
+		{"cp:lastPrinted", "LastPrinted"},
+		{"cp:revision", "Revision"},
+		{"dcterms:created", "Created"},
+		{"dcterms:modified", "Modified"},
+	},
+	"docProps/app.xml": {
+		{"Company", "Company"},
+		{"Manager", "Manager"},
+		{"Template", "Template"},
+		{"HyperlinkBase", "HyperlinkBase"},
+	},
+}
+
+// emptyCustomProperties replaces docProps/custom.xml so its relationship stays valid
+const emptyCustomProperties = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/custom-properties" xmlns:vt="http://schemas.openxmlformats.org/officeDocument/2006/docPropsVTypes"/>`
+
+// stripOffice removes the document properties of an Office Open XML package.
+// Unchanged parts are copied without recompression.
+func (s *Stripper) stripOffice(data []byte) ([]byte, []string, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("not an Office document: %w", err)
+	}
+
+	var out bytes.Buffer
+	writer := zip.NewWriter(&out)
+	removed := make([]string, 0)
+
+	for _, file := range reader.File {
+		replacement, names, err := s.stripOfficePart(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		removed = append(removed, names...)
+
+		if replacement == nil {
+			if err := writer.Copy(file); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		header := file.FileHeader
+		part, err := writer.CreateHeader(&header)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := part.Write(replacement); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, nil, err
+	}
+	return out.Bytes(), removed, nil
+}
+
+// stripOfficePart returns the cleaned content of a property part, or nil if it is unchanged
+func (s *Stripper) stripOfficePart(file *zip.File) ([]byte, []string, error) {
+	properties, isProperties := officeProperties[file.Name]
+	if !isProperties && file.Name != "docProps/custom.xml" {
+		return nil, nil, nil
+	}
+
+	content, err := readZipFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if file.Name == "docProps/custom.xml" {
+		if s.keeps("CustomProperties") || !bytes.Contains(content, []byte("<property")) {
+			return nil, nil, nil
+		}
+		return []byte(emptyCustomProperties), []string{"Office:CustomProperties"}, nil
+	}
+
+	removed := make([]string, 0)
+	for _, property := range properties {
+		if s.keeps(property.name) {
+			continue
+		}
+		element := regexp.MustCompile(`(?s)<` + regexp.QuoteMeta(property.element) + `(\s[^>]*)?>(.*?)</` + regexp.QuoteMeta(property.element) + `>`)
+		if loc := element.FindSubmatchIndex(content); loc != nil && loc[5] > loc[4] {
+			// All document properties are optional, so the element is dropped entirely
+			content = element.ReplaceAll(content, nil)
+			removed = append(removed, "Office:"+property.name)
+		}
+	}
+
+	if len(removed) == 0 {
+		return nil, nil, nil
+	}
+	return content, removed, nil
+}
+
+// readZipFile reads the uncompressed content of a zip entry
+func readZipFile(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
@@ -0,0 +1,144 @@
+package metadata
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+)
+
+// pdfInfoKeys are the document information entries that are blanked
+var pdfInfoKeys = []string{"Title", "Author", "Subject", "Keywords", "Creator", "Producer", "CreationDate", "ModDate"}
+
+var (
+	pdfInfoRef      = regexp.MustCompile(`/Info\s+(\d+)\s+(\d+)\s+R`)
+	pdfXMPPacket    = regexp.MustCompile(`(?s)<\?xpacket begin=.*?\?>(.*?)<\?xpacket end=`)
+	pdfKeyDelimiter = []byte(" \t\r\n\f/<>[]()%")
+)
+
+// stripPDF blanks the document information dictionary and XMP packets in
+// place. Values are overwritten with padding of the same length, so object
+// offsets in the cross-reference table remain valid without rewriting the file.
+func (s *Stripper) stripPDF(data []byte) ([]byte, []string, error) {
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return nil, nil, fmt.Errorf("not a PDF file")
+	}
+
+	out := append([]byte(nil), data...)
+	removed := make([]string, 0)
+	seen := make(map[string]bool)
+
+	// Every incremental update may reference its own Info dictionary
+	references := pdfInfoRef.FindAllSubmatch(data, -1)
+	found := false
+	for _, match := range references {
+		header := regexp.MustCompile(`(?m)(^|\s)` + string(match[1]) + `\s+` + string(match[2]) + `\s+obj\b`)
+		for _, loc := range header.FindAllIndex(out, -1) {
+			end := bytes.Index(out[loc[1]:], []byte("endobj"))
+			if end < 0 {
+				continue
+			}
+			found = true
+			for _, name := range s.blankPDFInfo(out[loc[1] : loc[1]+end]) {
+				if !seen[name] {
+					seen[name] = true
+					removed = append(removed, "PDF:"+name)
+				}
+			}
+		}
+	}
+
+	if !s.keeps("XMP") {
+		for _, loc := range pdfXMPPacket.FindAllSubmatchIndex(out, -1) {
+			if loc[3] > loc[2] && len(bytes.TrimSpace(out[loc[2]:loc[3]])) > 0 {
+				fill(out[loc[2]:loc[3]], ' ')
+				if !seen["XMP"] {
+					seen["XMP"] = true
+					removed = append(removed, "XMP")
+				}
+			}
+		}
+	}
+
+	if len(references) > 0 && !found {
+		return nil, nil, fmt.Errorf("document information is stored in a compressed object stream")
+	}
+
+	return out, removed, nil
+}
+
+// blankPDFInfo overwrites the string values of the Info entries in dict and
+// returns the names of the entries that were blanked
+func (s *Stripper) blankPDFInfo(dict []byte) []string {
+	blanked := make([]string, 0)
+	for _, key := range pdfInfoKeys {
+		if s.keeps(key) {
+			continue
+		}
+
+		token := []byte("/" + key)
+		offset := 0
+		for {
+			i := bytes.Index(dict[offset:], token)
+			if i < 0 {
+				break
+			}
+			pos := offset + i + len(token)
+			offset = pos
+			// Make sure the whole key matched (e.g. not /Authors)
+			if pos < len(dict) && bytes.IndexByte(pdfKeyDelimiter, dict[pos]) < 0 {
+				continue
+			}
+			for pos < len(dict) && bytes.IndexByte([]byte(" \t\r\n\f"), dict[pos]) >= 0 {
+				pos++
+			}
+			if start, end, ok := pdfStringBounds(dict, pos); ok && len(bytes.Trim(dict[start:end], " 0\t\r\n")) > 0 {
+				padding := byte(' ')
+				if dict[pos] == '<' {
+					padding = '0'
+				}
+				fill(dict[start:end], padding)
+				blanked = append(blanked, key)
+			}
+		}
+	}
+	return blanked
+}
+
+// pdfStringBounds returns the content range of the literal or hex string starting at pos
+func pdfStringBounds(data []byte, pos int) (int, int, bool) {
+	if pos >= len(data) {
+		return 0, 0, false
+	}
+	switch data[pos] {
+	case '(':
+		depth := 0
+		for i := pos; i < len(data); i++ {
+			switch data[i] {
+			case '\\':
+				i++ // Skip the escaped character
+			case '(':
+				depth++
+			case ')':
+				depth--
+				if depth == 0 {
+					return pos + 1, i, true
+				}
+			}
+		}
+	case '<':
+		if pos+1 < len(data) && data[pos+1] == '<' {
+			return 0, 0, false // A dictionary, not a hex string
+		}
+		if end := bytes.IndexByte(data[pos:], '>'); end > 0 {
+			return pos + 1, pos + end, true
+		}
+	}
+	return 0, 0, false
+}
+
+// fill overwrites b with c
+func fill(b []byte, c byte) {
+	for i := range b {
+		b[i] = c
+	}
+}
@@ -0,0 +1,92 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// stripPNG drops textual, time and EXIF chunks (filtering EXIF when tags are
+// whitelisted). Text chunks are identified by their keyword, e.g. "Author".
+func (s *Stripper) stripPNG(data []byte) ([]byte, []string, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, nil, fmt.Errorf("not a PNG file")
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(pngSignature)
+	removed := make([]string, 0)
+
+	pos := len(pngSignature)
+	for pos < len(data) {
+		if pos+12 > len(data) {
+			return nil, nil, fmt.Errorf("truncated PNG chunk")
+		}
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, nil, fmt.Errorf("truncated PNG chunk")
+		}
+		chunkType := string(data[pos+4 : pos+8])
+		payload := data[pos+8 : pos+8+length]
+
+		switch chunkType {
+		case "tEXt", "zTXt", "iTXt":
+			keyword := string(payload)
+			if i := bytes.IndexByte(payload, 0); i >= 0 {
+				keyword = string(payload[:i])
+			}
+			if s.keeps(keyword) {
+				out.Write(data[pos:end])
+			} else {
+				removed = append(removed, "PNG:"+keyword)
+			}
+		case "tIME":
+			if s.keeps("ModifyTime") {
+				out.Write(data[pos:end])
+			} else {
+				removed = append(removed, "PNG:ModifyTime")
+			}
+		case "eXIf":
+			kept, names, err := s.filterExif(payload)
+			if err != nil {
+				removed = append(removed, "EXIF")
+				break
+			}
+			removed = append(removed, names...)
+			if kept != nil {
+				writeChunk(out, chunkType, kept)
+			}
+		case "iCCP":
+			if s.keeps("ICC") {
+				out.Write(data[pos:end])
+			} else {
+				removed = append(removed, "ICC")
+			}
+		default:
+			out.Write(data[pos:end])
+		}
+
+		pos = end
+		if chunkType == "IEND" {
+			break
+		}
+	}
+
+	return out.Bytes(), removed, nil
+}
+
+// writeChunk writes a PNG chunk with its CRC
+func writeChunk(out *bytes.Buffer, chunkType string, payload []byte) {
+	_ = binary.Write(out, binary.BigEndian, uint32(len(payload)))
+	out.WriteString(chunkType)
+	out.Write(payload)
+
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(chunkType))
+	crc.Write(payload)
+	_ = binary.Write(out, binary.BigEndian, crc.Sum32())
+}
@@ -0,0 +1,212 @@
+// Package monitor watches the free space of volumes and runs a pipeline on a
+// volume once its usage crosses a threshold, e.g. a cache cleanup when /home
+// is 90% full. A volume's pipeline runs at most once per cooldown; while the
+// volume stays above its threshold within the cooldown nothing is run again.
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/internal/pipeline"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+)
+
+// Kinds of events
+const (
+	EventTriggered   = "triggered"    // The threshold was crossed and the pipeline starts
+	EventCompleted   = "completed"    // The pipeline finished
+	EventFailed      = "failed"       // The pipeline or the space query failed
+	EventCoolingDown = "cooling_down" // Still above the threshold, but the pipeline ran within the cooldown
+	EventRecovered   = "recovered"    // Usage fell below the threshold again
+)
+
+// Volume is a monitored location and what to do when it fills up. The
+// threshold is a used share (UsedPercent) or the free space below which the
+// volume counts as full (MinFree); with both, either one triggers.
+type Volume struct {
+	Path        string
+	UsedPercent float64       // Used share in percent from which the volume is full; 0 = not checked
+	MinFree     int64         // Available bytes below which the volume is full; 0 = not checked
+	Pipeline    string        // Pipeline file run when the volume is full
+	Cooldown    time.Duration // Least time between two runs of the pipeline
+	Notify      string        // Command run with the message of each event as its last argument
+}
+
+// Usage is the state of a volume at one check
+type Usage struct {
+	Total       uint64  `json:"total"`
+	Available   uint64  `json:"available"`
+	UsedPercent float64 `json:"used_percent"`
+}
+
+// Event reports what happened on a volume. Before is the usage that caused
+// the event; After is set once a pipeline completed.
+type Event struct {
+	Kind   string
+	Volume Volume
+	Before Usage
+	After  *Usage
+	Steps  []pipeline.StepResult
+	Err    error
+}
+
+// Monitor checks volumes and runs their pipelines
+type Monitor struct {
+	volumes []Volume
+	engine  *engine.Engine
+	dryRun  bool
+	lastRun map[string]time.Time // By volume path
+	full    map[string]bool      // Volumes above their threshold at the last check
+	cooling map[string]bool      // Volumes whose cooldown was already reported
+}
+
+// New creates a monitor for volumes; dryRun runs the pipelines as dry runs
+func New(e *engine.Engine, volumes []Volume, dryRun bool) (*Monitor, error) {
+	if len(volumes) == 0 {
+		return nil, fmt.Errorf("no volumes to monitor")
+	}
+	for _, volume := range volumes {
+		if volume.UsedPercent <= 0 && volume.MinFree <= 0 {
+			return nil, fmt.Errorf("volume %s: a threshold is required", volume.Path)
+		}
+		if volume.UsedPercent > 100 {
+			return nil, fmt.Errorf("volume %s: threshold %.0f%% is above 100%%", volume.Path, volume.UsedPercent)
+		}
+		p, err := pipeline.Load(volume.Pipeline)
+		if err != nil {
+			return nil, fmt.Errorf("volume %s: %w", volume.Path, err)
+		}
+		if err := p.Validate(e.GetSupportedOperations()); err != nil {
+			return nil, fmt.Errorf("volume %s: %w", volume.Path, err)
+		}
+	}
+	return &Monitor{
+		volumes: volumes,
+		engine:  e,
+		dryRun:  dryRun,
+		lastRun: make(map[string]time.Time),
+		full:    make(map[string]bool),
+		cooling: make(map[string]bool),
+	}, nil
+}
+
+// Run checks the volumes every interval until ctx is done, calling report
+// with each event
+func (m *Monitor) Run(ctx context.Context, interval time.Duration, report func(Event)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.Check(ctx, report)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check looks at every volume once and runs the pipelines of those above
+// their threshold and out of their cooldown
+func (m *Monitor) Check(ctx context.Context, report func(Event)) {
+	for _, volume := range m.volumes {
+		if ctx.Err() != nil {
+			return
+		}
+		m.check(ctx, volume, report)
+	}
+}
+
+// check handles one volume
+func (m *Monitor) check(ctx context.Context, volume Volume, report func(Event)) {
+	usage, err := Measure(volume.Path)
+	if err != nil {
+		report(Event{Kind: EventFailed, Volume: volume, Err: err})
+		return
+	}
+
+	if !volume.Full(usage) {
+		if m.full[volume.Path] {
+			report(Event{Kind: EventRecovered, Volume: volume, Before: usage})
+		}
+		m.full[volume.Path] = false
+		m.cooling[volume.Path] = false
+		return
+	}
+	m.full[volume.Path] = true
+
+	if last, ok := m.lastRun[volume.Path]; ok && time.Since(last) < volume.Cooldown {
+		if !m.cooling[volume.Path] {
+			m.cooling[volume.Path] = true
+			report(Event{Kind: EventCoolingDown, Volume: volume, Before: usage})
+		}
+		return
+	}
+	m.cooling[volume.Path] = false
+	m.lastRun[volume.Path] = time.Now()
+
+	report(Event{Kind: EventTriggered, Volume: volume, Before: usage})
+	p, err := pipeline.Load(volume.Pipeline)
+	if err != nil {
+		report(Event{Kind: EventFailed, Volume: volume, Before: usage, Err: err})
+		return
+	}
+	steps, err := p.Run(ctx, m.engine, m.dryRun, nil)
+	if err != nil {
+		report(Event{Kind: EventFailed, Volume: volume, Before: usage, Steps: steps, Err: err})
+		return
+	}
+	event := Event{Kind: EventCompleted, Volume: volume, Before: usage, Steps: steps}
+	if after, err := Measure(volume.Path); err == nil {
+		event.After = &after
+	}
+	report(event)
+}
+
+// Full reports whether usage is above the threshold of the volume
+func (v Volume) Full(usage Usage) bool {
+	if v.UsedPercent > 0 && usage.UsedPercent >= v.UsedPercent {
+		return true
+	}
+	return v.MinFree > 0 && usage.Available < uint64(v.MinFree)
+}
+
+// Measure returns the usage of the volume holding path
+func Measure(path string) (Usage, error) {
+	space, err := filesystem.GetDiskSpace(path)
+	if err != nil {
+		return Usage{}, fmt.Errorf("failed to query free space of %s: %w", path, err)
+	}
+	usage := Usage{Total: space.Total, Available: space.Available}
+	// Used as seen by unprivileged users: reserved blocks count as used
+	if space.Total > 0 {
+		usage.UsedPercent = float64(space.Total-space.Available) / float64(space.Total) * 100
+	}
+	return usage, nil
+}
+
+// Notify runs a notification command with message as its last argument,
+// e.g. "notify-send fileops". The event and volume are also passed as
+// FILEOPS_EVENT and FILEOPS_VOLUME.
+func Notify(ctx context.Context, command string, event Event, message string) error {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil
+	}
+	args := append(append([]string{}, fields[1:]...), message)
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, fields[0], args...)
+	cmd.Env = append(os.Environ(), "FILEOPS_EVENT="+event.Kind, "FILEOPS_VOLUME="+event.Volume.Path)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w: %s", fields[0], err, strings.TrimSpace(output.String()))
+	}
+	return nil
+}
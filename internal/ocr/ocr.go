@@ -0,0 +1,267 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnavailable is returned when no OCR engine is installed or reachable
+var ErrUnavailable = errors.New("no OCR engine available")
+
+// ErrUnsupported is returned for files OCR cannot be run on
+var ErrUnsupported = errors.New("unsupported file type for OCR")
+
+// imageExtensions are passed to the OCR engine as is
+var imageExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".tif": true, ".tiff": true,
+	".bmp": true, ".gif": true, ".webp": true, ".pnm": true,
+}
+
+// Supported reports whether OCR can be run on path
+func Supported(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return imageExtensions[ext] || ext == ".pdf"
+}
+
+// IsImage reports whether path is an image that OCR can read
+func IsImage(path string) bool {
+	return imageExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// Recognizer turns images and scanned PDFs into text
+type Recognizer interface {
+	// Name identifies the engine, e.g. "tesseract"
+	Name() string
+
+	// Recognize returns the text found in the file at path
+	Recognize(ctx context.Context, path string) (string, error)
+}
+
+// Options configure the OCR engines
+type Options struct {
+	Engine        string        // "auto", "tesseract" or "service"
+	Languages     []string      // Tesseract language codes, e.g. eng, deu
+	TesseractPath string        // tesseract binary; looked up on PATH when empty
+	ServiceURL    string        // Base URL of the AI service
+	MaxPages      int           // Pages of a PDF that are recognized
+	Timeout       time.Duration // Upper bound per file
+}
+
+// New returns the recognizer selected by options. With "auto" the local
+// tesseract is preferred and the AI service is used when it is not installed.
+func New(options Options) (Recognizer, error) {
+	if options.MaxPages <= 0 {
+		options.MaxPages = 10
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 2 * time.Minute
+	}
+
+	switch options.Engine {
+	case "tesseract":
+		return newTesseract(options)
+	case "service":
+		return newService(options)
+	case "", "auto":
+		if recognizer, err := newTesseract(options); err == nil {
+			return recognizer, nil
+		}
+		if options.ServiceURL != "" {
+			return newService(options)
+		}
+		return nil, ErrUnavailable
+	}
+	return nil, fmt.Errorf("unknown OCR engine %q, must be one of auto, tesseract, service", options.Engine)
+}
+
+// Tesseract runs the tesseract command line tool. PDFs are rasterized with
+// pdftoppm (poppler) first.
+type Tesseract struct {
+	path     string
+	pdftoppm string
+	options  Options
+}
+
+// newTesseract locates the tesseract and pdftoppm binaries
+func newTesseract(options Options) (*Tesseract, error) {
+	path := options.TesseractPath
+	if path == "" {
+		path = "tesseract"
+	}
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: tesseract not found", ErrUnavailable)
+	}
+	pdftoppm, _ := exec.LookPath("pdftoppm")
+	return &Tesseract{path: resolved, pdftoppm: pdftoppm, options: options}, nil
+}
+
+// Name identifies the engine
+func (t *Tesseract) Name() string {
+	return "tesseract"
+}
+
+// Recognize returns the text of an image or of the first pages of a PDF
+func (t *Tesseract) Recognize(ctx context.Context, path string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.options.Timeout)
+	defer cancel()
+
+	ext := strings.ToLower(filepath.Ext(path))
+	switch {
+	case imageExtensions[ext]:
+		return t.recognizeImage(ctx, path)
+	case ext == ".pdf":
+		return t.recognizePDF(ctx, path)
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnsupported, path)
+}
+
+// recognizeImage runs tesseract on a single image
+func (t *Tesseract) recognizeImage(ctx context.Context, path string) (string, error) {
+	args := []string{path, "stdout"}
+	if len(t.options.Languages) > 0 {
+		args = append(args, "-l", strings.Join(t.options.Languages, "+"))
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, t.path, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("tesseract %s: %w: %s", path, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// recognizePDF rasterizes the first pages of a PDF and recognizes each of them
+func (t *Tesseract) recognizePDF(ctx context.Context, path string) (string, error) {
+	if t.pdftoppm == "" {
+		return "", fmt.Errorf("%w: pdftoppm is required to OCR PDFs", ErrUnavailable)
+	}
+
+	dir, err := os.MkdirTemp("", "fileops-ocr-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	args := []string{"-r", "300", "-gray", "-png", "-l", strconv.Itoa(t.options.MaxPages), path, filepath.Join(dir, "page")}
+	if output, err := exec.CommandContext(ctx, t.pdftoppm, args...).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("pdftoppm %s: %w: %s", path, err, strings.TrimSpace(string(output)))
+	}
+
+	pages, err := filepath.Glob(filepath.Join(dir, "page*.png"))
+	if err != nil {
+		return "", err
+	}
+	// pdftoppm pads page numbers to the same width, so lexical order is page order
+	sort.Strings(pages)
+
+	var text strings.Builder
+	for _, page := range pages {
+		pageText, err := t.recognizeImage(ctx, page)
+		if err != nil {
+			return "", err
+		}
+		text.WriteString(pageText)
+		text.WriteString("\n")
+	}
+	return text.String(), nil
+}
+
+// Service sends files to the OCR endpoint of the AI service.
+//
+// The endpoint receives a multipart POST to <url>/ocr with the file in the
+// "file" field and the languages in "languages", and answers {"text": "..."}.
+type Service struct {
+	url     string
+	client  *http.Client
+	options Options
+}
+
+// newService creates a client for the AI service
+func newService(options Options) (*Service, error) {
+	if options.ServiceURL == "" {
+		return nil, fmt.Errorf("%w: AI service URL is not configured", ErrUnavailable)
+	}
+	return &Service{
+		url:     strings.TrimSuffix(options.ServiceURL, "/") + "/ocr",
+		client:  &http.Client{Timeout: options.Timeout},
+		options: options,
+	}, nil
+}
+
+// Name identifies the engine
+func (s *Service) Name() string {
+	return "service"
+}
+
+// Recognize uploads the file to the AI service and returns the recognized text
+func (s *Service) Recognize(ctx context.Context, path string) (string, error) {
+	if !Supported(path) {
+		return "", fmt.Errorf("%w: %s", ErrUnsupported, path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filepath.Base(path))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return "", err
+	}
+	if err := writer.WriteField("languages", strings.Join(s.options.Languages, "+")); err != nil {
+		return "", err
+	}
+	if err := writer.WriteField("max_pages", strconv.Itoa(s.options.MaxPages)); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, &body)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("OCR service: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return "", fmt.Errorf("OCR service returned %s: %s", response.Status, strings.TrimSpace(string(message)))
+	}
+
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("invalid OCR service response: %w", err)
+	}
+	return result.Text, nil
+}
@@ -0,0 +1,276 @@
+package pack
+
+import (
+	"archive/tar"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+	"lukechampine.com/blake3"
+)
+
+// Snapshot formats
+const (
+	FormatChunks = "chunks"
+	FormatTarZst = "tar.zst"
+)
+
+// CatalogName is the last member of every archive and holds the snapshot
+// index, so an archive can be restored without its store
+const CatalogName = ".fileops-catalog.json"
+
+// countingWriter counts the bytes written to the archive file
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// ArchiveWriter writes a tar.zst archive in which every tar member is a
+// separate zstd frame. The frames concatenate to a regular zstd stream that
+// standard tools extract, while the offset of each frame allows restoring a
+// single file without decompressing the archive up to it.
+type ArchiveWriter struct {
+	file    *os.File // nil in a dry run
+	path    string
+	out     *countingWriter
+	encoder *zstd.Encoder
+	tar     *tar.Writer
+	start   int64
+}
+
+// CreateArchive starts the archive of a snapshot. It becomes visible in the
+// store only when Close succeeds.
+func (s *Store) CreateArchive(id string) (*ArchiveWriter, error) {
+	aw := &ArchiveWriter{path: s.archivePath(id)}
+	if s.dryRun {
+		aw.out = &countingWriter{w: io.Discard}
+	} else {
+		if err := os.MkdirAll(filepath.Dir(aw.path), 0755); err != nil {
+			return nil, err
+		}
+		file, err := os.CreateTemp(filepath.Dir(aw.path), ".fileops-pack-*")
+		if err != nil {
+			return nil, err
+		}
+		aw.file = file
+		aw.out = &countingWriter{w: file}
+	}
+
+	encoder, err := zstd.NewWriter(aw.out)
+	if err != nil {
+		aw.Abort()
+		return nil, err
+	}
+	aw.encoder = encoder
+	aw.tar = tar.NewWriter(encoder)
+	return aw, nil
+}
+
+// archivePath returns where the archive of a snapshot is stored
+func (s *Store) archivePath(id string) string {
+	return filepath.Join(s.dir, "archives", id+"."+FormatTarZst)
+}
+
+// Add writes an entry as a new member and records its offset, compressed
+// length and, for files, the content hash in the entry. path is read for
+// file entries.
+func (aw *ArchiveWriter) Add(entry *Entry, path string) error {
+	header := &tar.Header{
+		Name:     entry.Path,
+		Mode:     int64(os.FileMode(entry.Mode).Perm()),
+		ModTime:  entry.ModTime,
+		Typeflag: tar.TypeReg,
+		Format:   tar.FormatPAX,
+	}
+	switch entry.Type {
+	case TypeDir:
+		header.Name += "/"
+		header.Typeflag = tar.TypeDir
+	case TypeSymlink:
+		header.Typeflag = tar.TypeSymlink
+		header.Linkname = entry.Target
+	default:
+		header.Size = entry.Size
+	}
+
+	var file *os.File
+	if entry.Type == TypeFile {
+		var err error
+		if file, err = os.Open(path); err != nil {
+			return err
+		}
+		defer file.Close()
+	}
+
+	if err := aw.tar.WriteHeader(header); err != nil {
+		return aw.rollback(err)
+	}
+	if file != nil {
+		hasher := blake3.New(32, nil)
+		n, err := io.Copy(aw.tar, io.TeeReader(io.LimitReader(file, entry.Size), hasher))
+		if err != nil {
+			return aw.rollback(err)
+		}
+		if n != entry.Size {
+			return aw.rollback(fmt.Errorf("%s changed while packing", path))
+		}
+		entry.Hash = hex.EncodeToString(hasher.Sum(nil))
+	}
+	return aw.endFrame(entry)
+}
+
+// rollback drops a partially written member so the archive stays valid
+// after a file failed to pack
+func (aw *ArchiveWriter) rollback(cause error) error {
+	aw.encoder.Reset(aw.out)
+	aw.tar = tar.NewWriter(aw.encoder)
+	if aw.file != nil {
+		if err := aw.file.Truncate(aw.start); err != nil {
+			return fmt.Errorf("%w (archive left unusable: %v)", cause, err)
+		}
+		if _, err := aw.file.Seek(aw.start, io.SeekStart); err != nil {
+			return fmt.Errorf("%w (archive left unusable: %v)", cause, err)
+		}
+	}
+	aw.out.n = aw.start
+	return cause
+}
+
+// endFrame pads the current member, ends its zstd frame and records where the
+// frame is in the archive
+func (aw *ArchiveWriter) endFrame(entry *Entry) error {
+	if err := aw.tar.Flush(); err != nil {
+		return err
+	}
+	if err := aw.encoder.Close(); err != nil {
+		return err
+	}
+	if entry != nil {
+		entry.Offset = aw.start
+		entry.Length = aw.out.n - aw.start
+	}
+	aw.start = aw.out.n
+	aw.encoder.Reset(aw.out)
+	return nil
+}
+
+// Size returns the number of compressed bytes written so far
+func (aw *ArchiveWriter) Size() int64 {
+	return aw.out.n
+}
+
+// Close embeds the snapshot as catalog, finishes the archive and moves it into
+// place. The snapshot itself still has to be saved to the store.
+func (aw *ArchiveWriter) Close(snapshot *Snapshot) error {
+	catalog, err := json.Marshal(snapshot)
+	if err != nil {
+		aw.Abort()
+		return err
+	}
+	header := &tar.Header{
+		Name:     CatalogName,
+		Mode:     0644,
+		ModTime:  snapshot.Created,
+		Size:     int64(len(catalog)),
+		Typeflag: tar.TypeReg,
+		Format:   tar.FormatPAX,
+	}
+	if err := aw.tar.WriteHeader(header); err == nil {
+		_, err = aw.tar.Write(catalog)
+	}
+	if err == nil {
+		err = aw.tar.Close()
+	}
+	if err == nil {
+		err = aw.encoder.Close()
+	}
+	if err != nil {
+		aw.Abort()
+		return err
+	}
+	if aw.file == nil {
+		return nil
+	}
+
+	tmpName := aw.file.Name()
+	if err := aw.file.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Chmod(tmpName, 0644); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, aw.path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
+
+// Abort discards an unfinished archive
+func (aw *ArchiveWriter) Abort() {
+	if aw.file != nil {
+		aw.file.Close()
+		os.Remove(aw.file.Name())
+		aw.file = nil
+	}
+}
+
+// extractMember decompresses the frame of a file entry and writes the file
+// content to w, verifying it against the recorded hash
+func (s *Store) extractMember(snapshot *Snapshot, entry Entry, w io.Writer) (int64, error) {
+	file, err := os.Open(s.archivePath(snapshot.ID))
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	decoder, err := zstd.NewReader(io.NewSectionReader(file, entry.Offset, entry.Length))
+	if err != nil {
+		return 0, err
+	}
+	defer decoder.Close()
+
+	reader := tar.NewReader(decoder)
+	header, err := reader.Next()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s: %v", ErrCorruptChunk, entry.Path, err)
+	}
+	if header.Name != entry.Path {
+		return 0, fmt.Errorf("%w: expected %s at offset %d, found %s", ErrCorruptChunk, entry.Path, entry.Offset, header.Name)
+	}
+
+	hasher := blake3.New(32, nil)
+	n, err := io.Copy(io.MultiWriter(w, hasher), corruptReader{reader})
+	if err != nil {
+		return n, err
+	}
+	if hex.EncodeToString(hasher.Sum(nil)) != entry.Hash {
+		return n, fmt.Errorf("%w: %s does not match its recorded hash", ErrCorruptChunk, entry.Path)
+	}
+	return n, nil
+}
+
+// corruptReader reports errors decoding a member as corruption of the archive
+type corruptReader struct {
+	r io.Reader
+}
+
+func (cr corruptReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("%w: %v", ErrCorruptChunk, err)
+	}
+	return n, err
+}
@@ -0,0 +1,115 @@
+package pack
+
+import "io"
+
+// Content-defined chunking with FastCDC: chunk boundaries depend on the data,
+// not on offsets, so an insertion only changes the chunks around it and
+// similar files share most of their chunks.
+const (
+	minChunkSize = 16 * 1024
+	avgChunkSize = 64 * 1024
+	maxChunkSize = 256 * 1024
+
+	// Normalized chunking: a stricter mask before the average size and a
+	// looser one after it concentrate chunk sizes around the average
+	maskStrict = 0xFFFFC00000000000 // 18 bits
+	maskLoose  = 0xFFFC000000000000 // 14 bits
+)
+
+// gear maps every byte to a random 64-bit value. It is derived from a fixed
+// seed and must never change, or existing stores would stop deduplicating.
+var gear [256]uint64
+
+func init() {
+	seed := uint64(0x66696c656f707321) // "fileops!"
+	for i := range gear {
+		// splitmix64
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		gear[i] = z ^ (z >> 31)
+	}
+}
+
+// Chunker splits a stream into content-defined chunks
+type Chunker struct {
+	reader io.Reader
+	buf    []byte
+	start  int
+	end    int
+	eof    bool
+}
+
+// NewChunker creates a chunker reading from r
+func NewChunker(r io.Reader) *Chunker {
+	return &Chunker{reader: r, buf: make([]byte, 2*maxChunkSize)}
+}
+
+// Next returns the next chunk, or io.EOF after the last one. The returned
+// slice is only valid until the next call.
+func (c *Chunker) Next() ([]byte, error) {
+	if err := c.fill(); err != nil {
+		return nil, err
+	}
+	if c.start == c.end {
+		return nil, io.EOF
+	}
+
+	data := c.buf[c.start:c.end]
+	n := cut(data)
+	c.start += n
+	return data[:n], nil
+}
+
+// fill makes sure at least maxChunkSize bytes are buffered unless the stream ended
+func (c *Chunker) fill() error {
+	if c.eof || c.end-c.start >= maxChunkSize {
+		return nil
+	}
+	copy(c.buf, c.buf[c.start:c.end])
+	c.end -= c.start
+	c.start = 0
+
+	for c.end < len(c.buf) && !c.eof {
+		n, err := c.reader.Read(c.buf[c.end:])
+		c.end += n
+		if err == io.EOF {
+			c.eof = true
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cut returns the length of the first chunk of data
+func cut(data []byte) int {
+	n := len(data)
+	if n <= minChunkSize {
+		return n
+	}
+	if n > maxChunkSize {
+		n = maxChunkSize
+	}
+	normal := avgChunkSize
+	if normal > n {
+		normal = n
+	}
+
+	var hash uint64
+	i := minChunkSize
+	for ; i < normal; i++ {
+		hash = (hash << 1) + gear[data[i]]
+		if hash&maskStrict == 0 {
+			return i + 1
+		}
+	}
+	for ; i < n; i++ {
+		hash = (hash << 1) + gear[data[i]]
+		if hash&maskLoose == 0 {
+			return i + 1
+		}
+	}
+	return n
+}
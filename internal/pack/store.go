@@ -0,0 +1,353 @@
+package pack
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+	"lukechampine.com/blake3"
+)
+
+// storeVersion is written to new stores; stores of other versions are refused
+const storeVersion = 1
+
+// Entry types
+const (
+	TypeFile    = "file"
+	TypeDir     = "dir"
+	TypeSymlink = "symlink"
+)
+
+// ErrCorruptChunk is returned when a stored chunk no longer matches its address
+var ErrCorruptChunk = errors.New("corrupt chunk")
+
+// ErrSnapshotNotFound is returned for unknown snapshot IDs
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// storeConfig identifies a store and the chunking it was written with
+type storeConfig struct {
+	Version      int    `json:"version"`
+	Hash         string `json:"hash"`
+	MinChunkSize int    `json:"min_chunk_size"`
+	AvgChunkSize int    `json:"avg_chunk_size"`
+	MaxChunkSize int    `json:"max_chunk_size"`
+}
+
+// Entry is a file, directory or symlink in a snapshot
+type Entry struct {
+	Path    string    `json:"path"` // Slash-separated, relative to the snapshot root
+	Type    string    `json:"type"`
+	Mode    uint32    `json:"mode"`
+	ModTime time.Time `json:"mod_time"`
+	Size    int64     `json:"size,omitempty"`
+	Target  string    `json:"target,omitempty"` // Symlink target
+	Chunks  []string  `json:"chunks,omitempty"`
+
+	// Location of the member in a tar.zst archive and the hash of the file content
+	Hash   string `json:"hash,omitempty"`
+	Offset int64  `json:"offset,omitempty"`
+	Length int64  `json:"length,omitempty"`
+}
+
+// Snapshot is the index of one pack run
+type Snapshot struct {
+	ID         string    `json:"id"`
+	Format     string    `json:"format,omitempty"` // FormatChunks when empty
+	Created    time.Time `json:"created"`
+	Roots      []string  `json:"roots"`
+	Files      int       `json:"files"`
+	Size       int64     `json:"size"`        // Total size of the packed files
+	StoredSize int64     `json:"stored_size"` // Size of the chunks or archive this snapshot added to the store
+	Entries    []Entry   `json:"entries,omitempty"`
+}
+
+// Store is a content-addressed chunk store. Chunks live below chunks/ named
+// after their BLAKE3 hash; snapshots/ holds one JSON index per snapshot
+// listing the chunks of every file. Snapshots in the tar.zst format keep their
+// files in archives/ instead and list the archive offset of every file.
+type Store struct {
+	dir     string
+	dryRun  bool
+	planned map[string]bool // Chunks a dry run would have stored
+}
+
+// OpenStore opens the store in dir, creating it when create is set
+func OpenStore(dir string, create bool) (*Store, error) {
+	configPath := filepath.Join(dir, "store.json")
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) && create {
+		for _, sub := range []string{"chunks", "snapshots"} {
+			if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+				return nil, err
+			}
+		}
+		config := storeConfig{
+			Version:      storeVersion,
+			Hash:         "blake3",
+			MinChunkSize: minChunkSize,
+			AvgChunkSize: avgChunkSize,
+			MaxChunkSize: maxChunkSize,
+		}
+		data, err := json.MarshalIndent(config, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := filesystem.WriteFileAtomic(configPath, data, 0644); err != nil {
+			return nil, err
+		}
+		return &Store{dir: dir, planned: make(map[string]bool)}, nil
+	}
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no pack store in %s", dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var config storeConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid pack store %s: %w", dir, err)
+	}
+	if config.Version != storeVersion {
+		return nil, fmt.Errorf("unsupported pack store version %d in %s", config.Version, dir)
+	}
+	return &Store{dir: dir, planned: make(map[string]bool)}, nil
+}
+
+// PlanStore returns a store in dir, which need not exist yet, that computes
+// chunks and reports which ones would be new without writing anything
+func PlanStore(dir string) *Store {
+	return &Store{dir: dir, dryRun: true, planned: make(map[string]bool)}
+}
+
+// Dir returns the directory of the store
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// chunkPath returns where a chunk is stored, fanned out by the first byte
+func (s *Store) chunkPath(hash string) string {
+	return filepath.Join(s.dir, "chunks", hash[:2], hash)
+}
+
+// PutChunk stores a chunk unless it is already present and returns its address
+// and whether it was new
+func (s *Store) PutChunk(data []byte) (string, bool, error) {
+	sum := blake3.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	path := s.chunkPath(hash)
+	if _, err := os.Stat(path); err == nil || s.planned[hash] {
+		return hash, false, nil
+	}
+	if s.dryRun {
+		s.planned[hash] = true
+		return hash, true, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", false, err
+	}
+	if err := filesystem.WriteFileAtomic(path, data, 0644); err != nil {
+		return "", false, err
+	}
+	return hash, true, nil
+}
+
+// ReadChunk reads a chunk and verifies it against its address
+func (s *Store) ReadChunk(hash string) ([]byte, error) {
+	if len(hash) != 64 {
+		return nil, fmt.Errorf("%w: invalid address %q", ErrCorruptChunk, hash)
+	}
+	data, err := os.ReadFile(s.chunkPath(hash))
+	if err != nil {
+		return nil, err
+	}
+	sum := blake3.Sum256(data)
+	if hex.EncodeToString(sum[:]) != hash {
+		return nil, fmt.Errorf("%w: %s", ErrCorruptChunk, hash)
+	}
+	return data, nil
+}
+
+// AddFile splits a file into chunks, stores the new ones and returns the
+// addresses of all chunks and the number of bytes added to the store
+func (s *Store) AddFile(path string) ([]string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	chunks := make([]string, 0)
+	var added int64
+	chunker := NewChunker(file)
+	for {
+		data, err := chunker.Next()
+		if err == io.EOF {
+			return chunks, added, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		hash, stored, err := s.PutChunk(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		if stored {
+			added += int64(len(data))
+		}
+		chunks = append(chunks, hash)
+	}
+}
+
+// RestoreFile reassembles a file entry of a snapshot at target. Existing files
+// are not replaced.
+func (s *Store) RestoreFile(snapshot *Snapshot, entry Entry, target string) error {
+	if _, err := os.Lstat(target); err == nil {
+		return fmt.Errorf("%w: %s", os.ErrExist, target)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".fileops-restore-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	var written int64
+	if snapshot.Format == FormatTarZst {
+		written, err = s.extractMember(snapshot, entry, tmp)
+	} else {
+		written, err = s.writeChunks(entry, tmp)
+	}
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if written != entry.Size {
+		return fmt.Errorf("%w: %s restored to %d bytes, expected %d", ErrCorruptChunk, entry.Path, written, entry.Size)
+	}
+	if err := os.Chmod(tmpName, os.FileMode(entry.Mode).Perm()); err != nil {
+		return err
+	}
+	if err := os.Chtimes(tmpName, entry.ModTime, entry.ModTime); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, target)
+}
+
+// writeChunks writes the chunks of a file entry to w
+func (s *Store) writeChunks(entry Entry, w io.Writer) (int64, error) {
+	var written int64
+	for _, hash := range entry.Chunks {
+		data, err := s.ReadChunk(hash)
+		if err != nil {
+			return written, err
+		}
+		n, err := w.Write(data)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// NewSnapshotID returns an ID for a snapshot created now that is not taken yet
+func (s *Store) NewSnapshotID(now time.Time) string {
+	base := now.Format("20060102-150405")
+	id := base
+	for i := 2; ; i++ {
+		if _, err := os.Stat(s.snapshotPath(id)); os.IsNotExist(err) {
+			return id
+		}
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// snapshotPath returns where the index of a snapshot is stored
+func (s *Store) snapshotPath(id string) string {
+	return filepath.Join(s.dir, "snapshots", id+".json")
+}
+
+// SaveSnapshot writes a snapshot index. It is written after all of its
+// chunks, so an interrupted run never leaves a snapshot with missing chunks.
+func (s *Store) SaveSnapshot(snapshot *Snapshot) error {
+	if s.dryRun {
+		return nil
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return filesystem.WriteFileAtomic(s.snapshotPath(snapshot.ID), data, 0644)
+}
+
+// Snapshot loads a snapshot by ID; "latest" selects the newest one
+func (s *Store) Snapshot(id string) (*Snapshot, error) {
+	if id == "latest" {
+		snapshots, err := s.Snapshots()
+		if err != nil {
+			return nil, err
+		}
+		if len(snapshots) == 0 {
+			return nil, fmt.Errorf("%w: store is empty", ErrSnapshotNotFound)
+		}
+		id = snapshots[len(snapshots)-1].ID
+	}
+	if strings.ContainsAny(id, `/\`) {
+		return nil, fmt.Errorf("%w: %s", ErrSnapshotNotFound, id)
+	}
+
+	data, err := os.ReadFile(s.snapshotPath(id))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrSnapshotNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("invalid snapshot %s: %w", id, err)
+	}
+	return &snapshot, nil
+}
+
+// Snapshots lists all snapshots without their entries, oldest first
+func (s *Store) Snapshots() ([]*Snapshot, error) {
+	names, err := filepath.Glob(filepath.Join(s.dir, "snapshots", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]*Snapshot, 0, len(names))
+	for _, name := range names {
+		snapshot, err := s.Snapshot(strings.TrimSuffix(filepath.Base(name), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Entries = nil
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		if !snapshots[i].Created.Equal(snapshots[j].Created) {
+			return snapshots[i].Created.Before(snapshots[j].Created)
+		}
+		return snapshots[i].ID < snapshots[j].ID
+	})
+	return snapshots, nil
+}
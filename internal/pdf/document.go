@@ -0,0 +1,541 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// ErrEncrypted is returned for encrypted documents
+var ErrEncrypted = errors.New("encrypted PDFs are not supported")
+
+// objectHeader finds "n g obj" when the cross-reference data is unusable
+var objectHeader = regexp.MustCompile(`(?m)(?:^|[\r\n\s])(\d+)\s+(\d+)\s+obj\b`)
+
+// xrefEntry locates an object: at an offset in the file, or as the index-th
+// object of an object stream
+type xrefEntry struct {
+	offset   int
+	stream   int // Object stream number, 0 for objects stored directly
+	index    int
+	inStream bool
+}
+
+// Document is a parsed PDF file
+type Document struct {
+	Path    string
+	data    []byte
+	xref    map[int]xrefEntry
+	trailer Dict
+	cache   map[int]interface{}
+	pages   []Page
+}
+
+// Page is a page of a document with its inherited attributes resolved
+type Page struct {
+	Doc  *Document
+	Ref  Ref
+	Dict Dict
+}
+
+// inheritable are the page attributes a page may take from its ancestors
+var inheritable = []Name{"Resources", "MediaBox", "CropBox", "Rotate"}
+
+// Open reads and parses the PDF at path
+func Open(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, data)
+}
+
+// Parse parses a PDF held in memory that was read from path
+func Parse(path string, data []byte) (*Document, error) {
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return nil, fmt.Errorf("%w: no PDF header", ErrMalformed)
+	}
+	d := &Document{Path: path, data: data, xref: make(map[int]xrefEntry), cache: make(map[int]interface{})}
+	if err := d.readXref(); err == nil && d.trailer["Root"] != nil {
+		if d.trailer["Encrypt"] != nil {
+			return nil, ErrEncrypted
+		}
+		if err := d.loadPages(); err == nil {
+			return d, nil
+		}
+	}
+
+	// Damaged cross-reference data: find the objects by scanning
+	d.xref, d.cache, d.pages, d.trailer = make(map[int]xrefEntry), make(map[int]interface{}), nil, nil
+	d.scanObjects()
+	if err := d.loadPages(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Pages returns the pages in order
+func (d *Document) Pages() []Page {
+	return d.pages
+}
+
+// readXref follows the chain of cross-reference sections from the last one.
+// Newer sections take precedence over the ones they update.
+func (d *Document) readXref() error {
+	at := bytes.LastIndex(d.data, []byte("startxref"))
+	if at < 0 {
+		return fmt.Errorf("%w: no startxref", ErrMalformed)
+	}
+	p := &parser{data: d.data, pos: at + len("startxref")}
+	offset, err := strconv.Atoi(p.keyword())
+	if err != nil {
+		return fmt.Errorf("%w: invalid startxref", ErrMalformed)
+	}
+
+	seen := make(map[int]bool)
+	for offset > 0 && offset < len(d.data) && !seen[offset] {
+		seen[offset] = true
+		trailer, err := d.readXrefSection(offset)
+		if err != nil {
+			return err
+		}
+		if d.trailer == nil {
+			d.trailer = trailer
+		}
+		// Hybrid files keep compressed entries in an additional stream
+		if stm, ok := trailer["XRefStm"].(int64); ok && !seen[int(stm)] {
+			seen[int(stm)] = true
+			if _, err := d.readXrefSection(int(stm)); err != nil {
+				return err
+			}
+		}
+		prev, _ := trailer["Prev"].(int64)
+		offset = int(prev)
+	}
+	return nil
+}
+
+// readXrefSection reads a cross-reference table or stream at offset and
+// returns its trailer dictionary
+func (d *Document) readXrefSection(offset int) (Dict, error) {
+	p := &parser{data: d.data, pos: offset}
+	if p.peekKeyword() != "xref" {
+		return d.readXrefStream(offset)
+	}
+	p.keyword()
+	for {
+		token := p.keyword()
+		if token == "trailer" {
+			trailer, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			dict, ok := trailer.(Dict)
+			if !ok {
+				return nil, fmt.Errorf("%w: invalid trailer", ErrMalformed)
+			}
+			return dict, nil
+		}
+		start, err1 := strconv.Atoi(token)
+		count, err2 := strconv.Atoi(p.keyword())
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("%w: invalid xref table", ErrMalformed)
+		}
+		for i := 0; i < count; i++ {
+			offset, err1 := strconv.Atoi(p.keyword())
+			_, err2 := strconv.Atoi(p.keyword())
+			kind := p.keyword()
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("%w: invalid xref entry", ErrMalformed)
+			}
+			if _, known := d.xref[start+i]; !known && kind == "n" {
+				d.xref[start+i] = xrefEntry{offset: offset}
+			} else if !known {
+				d.xref[start+i] = xrefEntry{offset: -1} // Free
+			}
+		}
+	}
+}
+
+// readXrefStream reads a cross-reference stream (PDF 1.5)
+func (d *Document) readXrefStream(offset int) (Dict, error) {
+	_, value, err := d.parseObjectAt(offset)
+	if err != nil {
+		return nil, err
+	}
+	stream, ok := value.(*Stream)
+	if !ok || stream.Dict["Type"] != Name("XRef") {
+		return nil, fmt.Errorf("%w: no cross-reference at offset %d", ErrMalformed, offset)
+	}
+	data, err := d.decode(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	widths, _ := stream.Dict["W"].(Array)
+	if len(widths) != 3 {
+		return nil, fmt.Errorf("%w: invalid xref stream widths", ErrMalformed)
+	}
+	w := make([]int, 3)
+	for i, width := range widths {
+		n, _ := width.(int64)
+		w[i] = int(n)
+	}
+	index, _ := stream.Dict["Index"].(Array)
+	if index == nil {
+		size, _ := stream.Dict["Size"].(int64)
+		index = Array{int64(0), size}
+	}
+
+	field := func(b []byte) int {
+		n := 0
+		for _, c := range b {
+			n = n<<8 | int(c)
+		}
+		return n
+	}
+	row := w[0] + w[1] + w[2]
+	pos := 0
+	for i := 0; i+1 < len(index); i += 2 {
+		start, _ := index[i].(int64)
+		count, _ := index[i+1].(int64)
+		for n := 0; n < int(count) && pos+row <= len(data); n++ {
+			kind := 1 // Default type when its width is 0
+			if w[0] > 0 {
+				kind = field(data[pos : pos+w[0]])
+			}
+			a := field(data[pos+w[0] : pos+w[0]+w[1]])
+			b := field(data[pos+w[0]+w[1] : pos+row])
+			pos += row
+			num := int(start) + n
+			if _, known := d.xref[num]; known {
+				continue
+			}
+			switch kind {
+			case 1:
+				d.xref[num] = xrefEntry{offset: a}
+			case 2:
+				d.xref[num] = xrefEntry{stream: a, index: b, inStream: true}
+			default:
+				d.xref[num] = xrefEntry{offset: -1}
+			}
+		}
+	}
+	return stream.Dict, nil
+}
+
+// scanObjects rebuilds the cross-reference data by looking for object
+// headers; the catalog becomes the root
+func (d *Document) scanObjects() {
+	d.trailer = make(Dict)
+	for _, match := range objectHeader.FindAllSubmatchIndex(d.data, -1) {
+		num, _ := strconv.Atoi(string(d.data[match[2]:match[3]]))
+		d.xref[num] = xrefEntry{offset: match[2]} // Later definitions win
+	}
+	// Objects in object streams have no header of their own
+	for num, entry := range d.xref {
+		stream, ok := d.Resolve(Ref{Num: num}).(*Stream)
+		if entry.inStream || !ok || stream.Dict["Type"] != Name("ObjStm") {
+			continue
+		}
+		data, err := d.decode(stream)
+		if err != nil {
+			continue
+		}
+		count, _ := stream.Dict["N"].(int64)
+		header := &parser{data: data}
+		for i := 0; i < int(count); i++ {
+			contained, err1 := strconv.Atoi(header.keyword())
+			_, err2 := strconv.Atoi(header.keyword())
+			if err1 != nil || err2 != nil {
+				break
+			}
+			if _, known := d.xref[contained]; !known {
+				d.xref[contained] = xrefEntry{stream: num, index: i, inStream: true}
+			}
+		}
+	}
+	for num := range d.xref {
+		if dict, ok := d.Resolve(Ref{Num: num}).(Dict); ok && dict["Type"] == Name("Catalog") {
+			d.trailer["Root"] = Ref{Num: num}
+		}
+	}
+}
+
+// parseObjectAt parses "n g obj value [stream] endobj" at offset
+func (d *Document) parseObjectAt(offset int) (int, interface{}, error) {
+	p := &parser{data: d.data, pos: offset}
+	num, err1 := strconv.Atoi(p.keyword())
+	_, err2 := strconv.Atoi(p.keyword())
+	if err1 != nil || err2 != nil || p.keyword() != "obj" {
+		return 0, nil, fmt.Errorf("%w: no object at offset %d", ErrMalformed, offset)
+	}
+	value, err := p.value()
+	if err != nil {
+		return 0, nil, err
+	}
+	dict, ok := value.(Dict)
+	if !ok || p.peekKeyword() != "stream" {
+		return num, value, nil
+	}
+
+	p.keyword()
+	// The keyword is followed by CRLF or LF
+	if p.pos < len(d.data) && d.data[p.pos] == '\r' {
+		p.pos++
+	}
+	if p.pos < len(d.data) && d.data[p.pos] == '\n' {
+		p.pos++
+	}
+	start := p.pos
+	end := -1
+	if length, ok := d.resolveLength(dict["Length"]); ok && start+length <= len(d.data) {
+		end = start + length
+		if !bytes.Contains(d.data[end:min(end+32, len(d.data))], []byte("endstream")) {
+			end = -1 // Wrong length
+		}
+	}
+	if end < 0 {
+		at := bytes.Index(d.data[start:], []byte("endstream"))
+		if at < 0 {
+			return 0, nil, fmt.Errorf("%w: unterminated stream at offset %d", ErrMalformed, offset)
+		}
+		end = start + at
+		for end > start && (d.data[end-1] == '\n' || d.data[end-1] == '\r') {
+			end--
+		}
+	}
+	return num, &Stream{Dict: dict, Data: d.data[start:end]}, nil
+}
+
+// resolveLength returns a stream length, which may be an indirect object
+func (d *Document) resolveLength(value interface{}) (int, bool) {
+	if ref, ok := value.(Ref); ok {
+		entry, known := d.xref[ref.Num]
+		if !known || entry.inStream || entry.offset < 0 {
+			return 0, false
+		}
+		_, resolved, err := d.parseObjectAt(entry.offset)
+		if err != nil {
+			return 0, false
+		}
+		value = resolved
+	}
+	n, ok := value.(int64)
+	return int(n), ok && n >= 0
+}
+
+// Resolve returns the object a reference points to, or value itself when it
+// is not a reference. Missing objects resolve to null.
+func (d *Document) Resolve(value interface{}) interface{} {
+	ref, ok := value.(Ref)
+	if !ok {
+		return value
+	}
+	if cached, ok := d.cache[ref.Num]; ok {
+		return cached
+	}
+	d.cache[ref.Num] = nil // Guards against reference cycles while loading
+	resolved := d.load(ref.Num)
+	d.cache[ref.Num] = resolved
+	return resolved
+}
+
+// load reads object num
+func (d *Document) load(num int) interface{} {
+	entry, ok := d.xref[num]
+	if !ok || entry.offset < 0 {
+		return nil
+	}
+	if !entry.inStream {
+		_, value, err := d.parseObjectAt(entry.offset)
+		if err != nil {
+			return nil
+		}
+		return value
+	}
+
+	stream, ok := d.Resolve(Ref{Num: entry.stream}).(*Stream)
+	if !ok {
+		return nil
+	}
+	data, err := d.decode(stream)
+	if err != nil {
+		return nil
+	}
+	count, _ := stream.Dict["N"].(int64)
+	first, _ := stream.Dict["First"].(int64)
+	header := &parser{data: data}
+	for i := 0; i < int(count); i++ {
+		n, err1 := strconv.Atoi(header.keyword())
+		offset, err2 := strconv.Atoi(header.keyword())
+		if err1 != nil || err2 != nil {
+			return nil
+		}
+		if n == num {
+			p := &parser{data: data, pos: int(first) + offset}
+			value, err := p.value()
+			if err != nil {
+				return nil
+			}
+			return value
+		}
+	}
+	return nil
+}
+
+// decode returns the decoded data of a Flate-compressed or unfiltered stream
+func (d *Document) decode(stream *Stream) ([]byte, error) {
+	filters := make([]Name, 0)
+	switch filter := d.Resolve(stream.Dict["Filter"]).(type) {
+	case Name:
+		filters = append(filters, filter)
+	case Array:
+		for _, f := range filter {
+			if name, ok := d.Resolve(f).(Name); ok {
+				filters = append(filters, name)
+			}
+		}
+	}
+	data := stream.Data
+	for _, filter := range filters {
+		if filter != "FlateDecode" {
+			return nil, fmt.Errorf("%w: unsupported filter %s", ErrMalformed, filter)
+		}
+		reader, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		decoded, err := io.ReadAll(reader)
+		if err != nil && len(decoded) == 0 {
+			return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		data = decoded
+	}
+	if params, ok := d.Resolve(stream.Dict["DecodeParms"]).(Dict); ok {
+		predictor, _ := params["Predictor"].(int64)
+		columns, _ := params["Columns"].(int64)
+		if predictor >= 10 && columns > 0 {
+			return unpredict(data, int(columns))
+		}
+	}
+	return data, nil
+}
+
+// unpredict reverses PNG predictors, as used by cross-reference streams
+func unpredict(data []byte, columns int) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	previous := make([]byte, columns)
+	for pos := 0; pos+columns+1 <= len(data); pos += columns + 1 {
+		kind, row := data[pos], append([]byte(nil), data[pos+1:pos+1+columns]...)
+		for i := range row {
+			var left, upLeft byte
+			if i > 0 {
+				left, upLeft = row[i-1], previous[i-1]
+			}
+			up := previous[i]
+			switch kind {
+			case 0:
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			default:
+				return nil, fmt.Errorf("%w: invalid predictor %d", ErrMalformed, kind)
+			}
+		}
+		out = append(out, row...)
+		previous = row
+	}
+	return out, nil
+}
+
+// paeth is the PNG Paeth predictor
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// loadPages walks the page tree from the catalog
+func (d *Document) loadPages() error {
+	catalog, ok := d.Resolve(d.trailer["Root"]).(Dict)
+	if !ok {
+		return fmt.Errorf("%w: no document catalog", ErrMalformed)
+	}
+	root, ok := catalog["Pages"].(Ref)
+	if !ok {
+		return fmt.Errorf("%w: no page tree", ErrMalformed)
+	}
+	visited := make(map[int]bool)
+	var walk func(ref Ref, inherited Dict) error
+	walk = func(ref Ref, inherited Dict) error {
+		if visited[ref.Num] {
+			return fmt.Errorf("%w: page tree cycle", ErrMalformed)
+		}
+		visited[ref.Num] = true
+		node, ok := d.Resolve(ref).(Dict)
+		if !ok {
+			return nil // Missing pages are dropped
+		}
+		if node["Type"] == Name("Page") || node["Kids"] == nil {
+			page := make(Dict, len(node))
+			for key, value := range node {
+				page[key] = value
+			}
+			for _, key := range inheritable {
+				if page[key] == nil && inherited[key] != nil {
+					page[key] = inherited[key]
+				}
+			}
+			d.pages = append(d.pages, Page{Doc: d, Ref: ref, Dict: page})
+			return nil
+		}
+		attributes := make(Dict)
+		for _, key := range inheritable {
+			if node[key] != nil {
+				attributes[key] = node[key]
+			} else if inherited[key] != nil {
+				attributes[key] = inherited[key]
+			}
+		}
+		kids, _ := d.Resolve(node["Kids"]).(Array)
+		for _, kid := range kids {
+			if kidRef, ok := kid.(Ref); ok {
+				if err := walk(kidRef, attributes); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(root, Dict{}); err != nil {
+		return err
+	}
+	if len(d.pages) == 0 {
+		return fmt.Errorf("%w: no pages", ErrMalformed)
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+// Package pdf reads the page tree of PDF files and writes selected pages of
+// one or more documents into a new one, enough to merge and split PDFs
+// without external tools. Encrypted documents are not supported.
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrMalformed is returned for files that cannot be parsed as PDF
+var ErrMalformed = errors.New("malformed PDF")
+
+// Object types. A value is nil (null), bool, int64, float64, Name, String,
+// Array, Dict, *Stream or Ref.
+type (
+	// Name is a PDF name without the leading slash
+	Name string
+	// String is the decoded content of a literal or hexadecimal string
+	String []byte
+	// Array is a PDF array
+	Array []interface{}
+	// Dict is a PDF dictionary
+	Dict map[Name]interface{}
+	// Ref is an indirect reference
+	Ref struct {
+		Num int
+		Gen int
+	}
+)
+
+// Stream is a stream object with its still encoded data
+type Stream struct {
+	Dict Dict
+	Data []byte
+}
+
+// parser reads objects from a PDF byte buffer
+type parser struct {
+	data []byte
+	pos  int
+}
+
+// isWhite reports whether c is PDF whitespace
+func isWhite(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+// isDelimiter reports whether c ends a name, number or keyword
+func isDelimiter(c byte) bool {
+	return isWhite(c) || bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+// skip skips whitespace and comments
+func (p *parser) skip() {
+	for p.pos < len(p.data) {
+		switch c := p.data[p.pos]; {
+		case isWhite(c):
+			p.pos++
+		case c == '%':
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' && p.data[p.pos] != '\r' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// keyword reads a regular token such as obj, R, true or a number
+func (p *parser) keyword() string {
+	p.skip()
+	start := p.pos
+	for p.pos < len(p.data) && !isDelimiter(p.data[p.pos]) {
+		p.pos++
+	}
+	return string(p.data[start:p.pos])
+}
+
+// peekKeyword returns the next regular token without consuming it
+func (p *parser) peekKeyword() string {
+	pos := p.pos
+	token := p.keyword()
+	p.pos = pos
+	return token
+}
+
+// value parses the next value. References "n g R" are recognized after
+// integers.
+func (p *parser) value() (interface{}, error) {
+	p.skip()
+	if p.pos >= len(p.data) {
+		return nil, fmt.Errorf("%w: unexpected end of data", ErrMalformed)
+	}
+	switch c := p.data[p.pos]; {
+	case c == '/':
+		return p.name(), nil
+	case c == '(':
+		return p.literal()
+	case c == '<' && p.pos+1 < len(p.data) && p.data[p.pos+1] == '<':
+		return p.dict()
+	case c == '<':
+		return p.hex()
+	case c == '[':
+		p.pos++
+		array := make(Array, 0)
+		for {
+			p.skip()
+			if p.pos >= len(p.data) {
+				return nil, fmt.Errorf("%w: unterminated array", ErrMalformed)
+			}
+			if p.data[p.pos] == ']' {
+				p.pos++
+				return array, nil
+			}
+			item, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, item)
+		}
+	}
+
+	start := p.pos
+	token := p.keyword()
+	switch token {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	case "":
+		return nil, fmt.Errorf("%w: unexpected %q at offset %d", ErrMalformed, p.data[p.pos], p.pos)
+	}
+	if n, err := strconv.ParseInt(token, 10, 64); err == nil {
+		// n g R
+		after := p.pos
+		if gen, err := strconv.Atoi(p.keyword()); err == nil && gen >= 0 {
+			if p.keyword() == "R" {
+				return Ref{Num: int(n), Gen: gen}, nil
+			}
+		}
+		p.pos = after
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(token, 64); err == nil {
+		return f, nil
+	}
+	return nil, fmt.Errorf("%w: unexpected %q at offset %d", ErrMalformed, token, start)
+}
+
+// name reads a name, decoding #xx escapes
+func (p *parser) name() Name {
+	p.pos++ // '/'
+	var name []byte
+	for p.pos < len(p.data) && !isDelimiter(p.data[p.pos]) {
+		c := p.data[p.pos]
+		if c == '#' && p.pos+2 < len(p.data) {
+			if b, err := strconv.ParseUint(string(p.data[p.pos+1:p.pos+3]), 16, 8); err == nil {
+				name = append(name, byte(b))
+				p.pos += 3
+				continue
+			}
+		}
+		name = append(name, c)
+		p.pos++
+	}
+	return Name(name)
+}
+
+// literal reads a (string) with nested parentheses and escapes
+func (p *parser) literal() (String, error) {
+	p.pos++ // '('
+	var s []byte
+	depth := 1
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return s, nil
+			}
+		case '\\':
+			if p.pos >= len(p.data) {
+				continue
+			}
+			e := p.data[p.pos]
+			p.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+					p.pos++
+				}
+				continue // Line continuation
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					value := int(e - '0')
+					for i := 0; i < 2 && p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '7'; i++ {
+						value = value*8 + int(p.data[p.pos]-'0')
+						p.pos++
+					}
+					c = byte(value)
+				} else {
+					c = e
+				}
+			}
+		}
+		s = append(s, c)
+	}
+	return nil, fmt.Errorf("%w: unterminated string", ErrMalformed)
+}
+
+// hex reads a <hexadecimal string>
+func (p *parser) hex() (String, error) {
+	p.pos++ // '<'
+	digits := make([]byte, 0)
+	for p.pos < len(p.data) && p.data[p.pos] != '>' {
+		if c := p.data[p.pos]; !isWhite(c) {
+			digits = append(digits, c)
+		}
+		p.pos++
+	}
+	if p.pos >= len(p.data) {
+		return nil, fmt.Errorf("%w: unterminated hex string", ErrMalformed)
+	}
+	p.pos++ // '>'
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	s := make(String, len(digits)/2)
+	for i := range s {
+		b, err := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid hex string", ErrMalformed)
+		}
+		s[i] = byte(b)
+	}
+	return s, nil
+}
+
+// dict reads a <<dictionary>>
+func (p *parser) dict() (Dict, error) {
+	p.pos += 2 // "<<"
+	dict := make(Dict)
+	for {
+		p.skip()
+		if p.pos+1 >= len(p.data) {
+			return nil, fmt.Errorf("%w: unterminated dictionary", ErrMalformed)
+		}
+		if p.data[p.pos] == '>' && p.data[p.pos+1] == '>' {
+			p.pos += 2
+			return dict, nil
+		}
+		if p.data[p.pos] != '/' {
+			return nil, fmt.Errorf("%w: dictionary key expected at offset %d", ErrMalformed, p.pos)
+		}
+		key := p.name()
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		dict[key] = value
+	}
+}
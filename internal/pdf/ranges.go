@@ -0,0 +1,100 @@
+package pdf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Range is an inclusive range of page numbers counted from 1
+type Range struct {
+	First int
+	Last  int
+}
+
+// String formats the range as "3" or "1-4"
+func (r Range) String() string {
+	if r.First == r.Last {
+		return strconv.Itoa(r.First)
+	}
+	return fmt.Sprintf("%d-%d", r.First, r.Last)
+}
+
+// ParseRanges parses a comma-separated list such as "1-3,4,7-end" for a
+// document of count pages. "end" is the last page and an open range ("7-")
+// runs to it; ranges reaching past the last page are cut short.
+func ParseRanges(spec string, count int) ([]Range, error) {
+	ranges := make([]Range, 0)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(part, "-")
+		if !isRange {
+			last = first
+		}
+		if last == "" {
+			last = "end"
+		}
+		r := Range{}
+		var err error
+		if r.First, err = pageNumber(first, count); err != nil {
+			return nil, fmt.Errorf("invalid page range %q: %w", part, err)
+		}
+		if r.Last, err = pageNumber(last, count); err != nil {
+			return nil, fmt.Errorf("invalid page range %q: %w", part, err)
+		}
+		if r.First > r.Last {
+			return nil, fmt.Errorf("invalid page range %q: first page is after the last", part)
+		}
+		if r.First > count {
+			return nil, fmt.Errorf("page range %q starts after the last page (%d)", part, count)
+		}
+		if r.Last > count {
+			r.Last = count
+		}
+		ranges = append(ranges, r)
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no page ranges in %q", spec)
+	}
+	return ranges, nil
+}
+
+// pageNumber parses a page number or "end"
+func pageNumber(s string, count int) (int, error) {
+	s = strings.TrimSpace(s)
+	if strings.EqualFold(s, "end") {
+		return count, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%q is not a page number", s)
+	}
+	return n, nil
+}
+
+// Chunks splits count pages into ranges of size pages each
+func Chunks(count, size int) []Range {
+	ranges := make([]Range, 0, (count+size-1)/size)
+	for first := 1; first <= count; first += size {
+		last := first + size - 1
+		if last > count {
+			last = count
+		}
+		ranges = append(ranges, Range{First: first, Last: last})
+	}
+	return ranges
+}
+
+// Select returns the pages in ranges, in order
+func (d *Document) Select(ranges []Range) []Page {
+	pages := make([]Page, 0)
+	for _, r := range ranges {
+		for n := r.First; n <= r.Last && n <= len(d.pages); n++ {
+			pages = append(pages, d.pages[n-1])
+		}
+	}
+	return pages
+}
@@ -0,0 +1,227 @@
+package pdf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// Object numbers of the catalog and page tree root in written documents
+const (
+	catalogNum = 1
+	pagesNum   = 2
+)
+
+// writer copies pages and everything they reference into a new document
+type writer struct {
+	out     *bufio.Writer
+	written int
+	offsets map[int]int
+	next    int
+	mapping map[*Document]map[int]int // Old object numbers to new ones
+	queue   []pending
+}
+
+// pending is an object still to be copied
+type pending struct {
+	doc *Document
+	num int
+	new int
+}
+
+// Write writes pages, which may come from several documents, as a new PDF
+// with a single flat page tree. Objects the pages share, such as fonts, are
+// copied once per source document; references to pages that are not copied
+// become null.
+func Write(w io.Writer, pages []Page) error {
+	if len(pages) == 0 {
+		return fmt.Errorf("no pages to write")
+	}
+	wr := &writer{
+		out:     bufio.NewWriter(w),
+		offsets: make(map[int]int),
+		next:    pagesNum + 1,
+		mapping: make(map[*Document]map[int]int),
+	}
+	wr.print("%s", "%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+
+	// Number the pages first so references between them are kept
+	kids := make(Array, 0, len(pages))
+	numbers := make([]int, len(pages))
+	for i, page := range pages {
+		numbers[i] = wr.number(page.Doc, page.Ref.Num, false)
+		kids = append(kids, Ref{Num: numbers[i]})
+	}
+
+	wr.object(catalogNum, Dict{"Type": Name("Catalog"), "Pages": Ref{Num: pagesNum}})
+	wr.object(pagesNum, Dict{"Type": Name("Pages"), "Kids": kids, "Count": int64(len(kids))})
+	done := make(map[int]bool)
+	for i, page := range pages {
+		if done[numbers[i]] {
+			continue // The same page selected twice
+		}
+		done[numbers[i]] = true
+		dict := wr.translate(page.Doc, page.Dict).(Dict)
+		dict["Parent"] = Ref{Num: pagesNum}
+		wr.object(numbers[i], dict)
+	}
+
+	for len(wr.queue) > 0 {
+		item := wr.queue[0]
+		wr.queue = wr.queue[1:]
+		wr.copy(item)
+	}
+
+	// Cross-reference table
+	start := wr.written
+	wr.print("xref\n0 %d\n0000000000 65535 f \n", wr.next)
+	for num := 1; num < wr.next; num++ {
+		wr.print("%010d 00000 n \n", wr.offsets[num])
+	}
+	wr.print("trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", wr.next, catalogNum, start)
+	return wr.out.Flush()
+}
+
+// number returns the new number of an object, queueing it for copying
+// unless it is one of the selected pages
+func (wr *writer) number(doc *Document, num int, queue bool) int {
+	if wr.mapping[doc] == nil {
+		wr.mapping[doc] = make(map[int]int)
+	}
+	if n, ok := wr.mapping[doc][num]; ok {
+		return n
+	}
+	n := wr.next
+	wr.next++
+	wr.mapping[doc][num] = n
+	if queue {
+		wr.queue = append(wr.queue, pending{doc: doc, num: num, new: n})
+	}
+	return n
+}
+
+// copy writes one referenced object. Pages that were not selected, the page
+// tree and the catalog are not copied, so following a reference to them
+// cannot pull in the rest of the source document.
+func (wr *writer) copy(item pending) {
+	value := item.doc.Resolve(Ref{Num: item.num})
+	if dict, ok := value.(Dict); ok {
+		switch dict["Type"] {
+		case Name("Page"), Name("Pages"), Name("Catalog"):
+			value = nil
+		}
+	}
+	if stream, ok := value.(*Stream); ok {
+		dict := make(Dict, len(stream.Dict))
+		for key, v := range stream.Dict {
+			dict[key] = v
+		}
+		dict["Length"] = int64(len(stream.Data)) // May have been indirect
+		value = &Stream{Dict: wr.translate(item.doc, dict).(Dict), Data: stream.Data}
+	} else {
+		value = wr.translate(item.doc, value)
+	}
+	wr.object(item.new, value)
+}
+
+// translate renumbers the references in value
+func (wr *writer) translate(doc *Document, value interface{}) interface{} {
+	switch v := value.(type) {
+	case Ref:
+		return Ref{Num: wr.number(doc, v.Num, true)}
+	case Array:
+		array := make(Array, len(v))
+		for i, item := range v {
+			array[i] = wr.translate(doc, item)
+		}
+		return array
+	case Dict:
+		dict := make(Dict, len(v))
+		for key, item := range v {
+			dict[key] = wr.translate(doc, item)
+		}
+		return dict
+	}
+	return value
+}
+
+// object writes an indirect object
+func (wr *writer) object(num int, value interface{}) {
+	wr.offsets[num] = wr.written
+	wr.print("%d 0 obj\n", num)
+	if stream, ok := value.(*Stream); ok {
+		wr.value(stream.Dict)
+		wr.print("\nstream\n")
+		n, _ := wr.out.Write(stream.Data)
+		wr.written += n
+		wr.print("\nendstream")
+	} else {
+		wr.value(value)
+	}
+	wr.print("\nendobj\n")
+}
+
+// value serializes a direct value
+func (wr *writer) value(value interface{}) {
+	switch v := value.(type) {
+	case nil:
+		wr.print("null")
+	case bool:
+		wr.print("%t", v)
+	case int64:
+		wr.print("%d", v)
+	case float64:
+		wr.print("%s", strconv.FormatFloat(v, 'f', -1, 64))
+	case Name:
+		wr.print("%s", formatName(v))
+	case String:
+		wr.print("<%x>", []byte(v))
+	case Ref:
+		wr.print("%d %d R", v.Num, v.Gen)
+	case Array:
+		wr.print("[")
+		for i, item := range v {
+			if i > 0 {
+				wr.print(" ")
+			}
+			wr.value(item)
+		}
+		wr.print("]")
+	case Dict:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, string(key))
+		}
+		sort.Strings(keys)
+		wr.print("<<")
+		for _, key := range keys {
+			wr.print("%s ", formatName(Name(key)))
+			wr.value(v[Name(key)])
+		}
+		wr.print(">>")
+	default:
+		wr.print("null")
+	}
+}
+
+// formatName writes a name with delimiters and non-printable bytes escaped
+func formatName(name Name) string {
+	out := []byte{'/'}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c < 0x21 || c > 0x7e || c == '#' || isDelimiter(c) {
+			out = append(out, fmt.Sprintf("#%02X", c)...)
+		} else {
+			out = append(out, c)
+		}
+	}
+	return string(out)
+}
+
+// print writes formatted text and tracks the offset
+func (wr *writer) print(format string, args ...interface{}) {
+	n, _ := fmt.Fprintf(wr.out, format, args...)
+	wr.written += n
+}
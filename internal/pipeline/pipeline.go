@@ -0,0 +1,238 @@
+// Package pipeline runs a sequence of operations defined in a YAML file, each
+// optionally fed with a file set of an earlier step's result:
+//
+//	name: tidy-downloads
+//	steps:
+//	  - name: dedup
+//	    operation: deduplication
+//	    paths: [~/Downloads]
+//	  - name: organize
+//	    operation: organization
+//	    input: steps.dedup.unique_files
+//	    settings:
+//	      destination: ~/Sorted
+//	      strategy: type
+//
+// With a window the steps only run in that daily span of local time, pausing
+// outside it; max_duration pauses a step after running that long in a window:
+//
+//	window: 22:00-06:00
+//	max_duration: 2h
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"gopkg.in/yaml.v3"
+)
+
+// Pipeline is a named sequence of steps
+type Pipeline struct {
+	Name        string        `yaml:"name"`
+	Window      string        `yaml:"window"`       // Daily span of local time the steps run in, e.g. 22:00-06:00
+	MaxDuration time.Duration `yaml:"max_duration"` // Time a step runs in a window before it pauses
+	Steps       []Step        `yaml:"steps"`
+}
+
+// Step runs one operation. Paths default to those of the input step.
+type Step struct {
+	Name      string                 `yaml:"name"`
+	Operation domain.OperationType   `yaml:"operation"`
+	Paths     []string               `yaml:"paths"`
+	Input     string                 `yaml:"input"` // steps.<name>.<result list>, e.g. steps.dedup.unique_files
+	Exclude   []string               `yaml:"exclude"`
+	Include   []string               `yaml:"include"`
+	DryRun    bool                   `yaml:"dry_run"`
+	Settings  map[string]interface{} `yaml:"settings"`
+}
+
+// StepResult is the outcome of a step. Steps whose input set is empty are
+// skipped and have no result.
+type StepResult struct {
+	Step    Step
+	Result  *domain.OperationResult
+	Skipped bool
+}
+
+// Load reads a pipeline file
+func Load(path string) (*Pipeline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var pipeline Pipeline
+	if err := yaml.Unmarshal(data, &pipeline); err != nil {
+		return nil, fmt.Errorf("invalid pipeline file %s: %w", path, err)
+	}
+	if pipeline.Name == "" {
+		pipeline.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return &pipeline, nil
+}
+
+// Validate checks the steps; supported are the operations the engine runs
+func (p *Pipeline) Validate(supported []domain.OperationType) error {
+	if len(p.Steps) == 0 {
+		return fmt.Errorf("pipeline %s has no steps", p.Name)
+	}
+	if p.Window != "" {
+		if _, err := engine.ParseWindow(p.Window); err != nil {
+			return fmt.Errorf("pipeline %s: %w", p.Name, err)
+		}
+	}
+	known := make(map[domain.OperationType]bool, len(supported))
+	for _, operation := range supported {
+		known[operation] = true
+	}
+
+	earlier := make(map[string]bool)
+	for i, step := range p.Steps {
+		label := fmt.Sprintf("step %d (%s)", i+1, step)
+		if !known[step.Operation] {
+			return fmt.Errorf("%s: unknown operation %q", label, step.Operation)
+		}
+		if step.Input != "" {
+			from, _, err := parseInput(step.Input)
+			if err != nil {
+				return fmt.Errorf("%s: %w", label, err)
+			}
+			if !earlier[from] {
+				return fmt.Errorf("%s: input %s does not name an earlier step", label, step.Input)
+			}
+		} else if len(step.Paths) == 0 {
+			return fmt.Errorf("%s: paths or an input are required", label)
+		}
+		if step.Name != "" {
+			if earlier[step.Name] {
+				return fmt.Errorf("%s: the name is used twice", label)
+			}
+			earlier[step.Name] = true
+		}
+	}
+	return nil
+}
+
+// String returns the name of the step, or its operation when it has none
+func (s Step) String() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return string(s.Operation)
+}
+
+// parseInput splits steps.<name>.<key>
+func parseInput(input string) (string, string, error) {
+	parts := strings.SplitN(input, ".", 3)
+	if len(parts) != 3 || parts[0] != "steps" || parts[1] == "" || parts[2] == "" {
+		return "", "", fmt.Errorf("invalid input %q (use steps.<name>.<result list>, e.g. steps.dedup.unique_files)", input)
+	}
+	return parts[1], parts[2], nil
+}
+
+// Run executes the steps in order, feeding each step with the file set named
+// by its input, and stops at the first failing step. done is called after
+// each step. dryRun makes every step a dry run.
+func (p *Pipeline) Run(ctx context.Context, e *engine.Engine, dryRun bool, done func(StepResult)) ([]StepResult, error) {
+	if err := p.Validate(e.GetSupportedOperations()); err != nil {
+		return nil, err
+	}
+
+	results := make([]StepResult, 0, len(p.Steps))
+	configs := make(map[string]domain.OperationConfig)
+	byName := make(map[string]*domain.OperationResult)
+	for _, step := range p.Steps {
+		config, err := stepConfig(step, dryRun)
+		if err != nil {
+			return results, fmt.Errorf("step %s: %w", step, err)
+		}
+		config.Window, config.MaxDuration = p.Window, p.MaxDuration
+
+		if step.Input != "" {
+			from, key, _ := parseInput(step.Input)
+			if len(config.TargetPaths) == 0 {
+				config.TargetPaths = configs[from].TargetPaths
+			}
+			previous := byName[from]
+			var files []string
+			if previous != nil {
+				if files, err = engine.ResultFiles(previous, key); err != nil {
+					return results, fmt.Errorf("step %s: %w", step, err)
+				}
+			}
+			config, err = engine.FeedFiles(config, files)
+			if errors.Is(err, engine.ErrNoInput) {
+				configs[step.Name] = config
+				result := StepResult{Step: step, Skipped: true}
+				results = append(results, result)
+				if done != nil {
+					done(result)
+				}
+				continue
+			}
+			if err != nil {
+				return results, fmt.Errorf("step %s: %w", step, err)
+			}
+		}
+
+		result, err := e.ExecuteOperation(ctx, step.Operation, config)
+		if err != nil {
+			return results, fmt.Errorf("step %s failed: %w", step, err)
+		}
+		configs[step.Name] = config
+		byName[step.Name] = result
+		results = append(results, StepResult{Step: step, Result: result})
+		if done != nil {
+			done(results[len(results)-1])
+		}
+	}
+	return results, nil
+}
+
+// stepConfig builds the operation configuration of a step
+func stepConfig(step Step, dryRun bool) (domain.OperationConfig, error) {
+	paths := make([]string, 0, len(step.Paths))
+	for _, path := range step.Paths {
+		absPath, err := filepath.Abs(expandHome(path))
+		if err != nil {
+			return domain.OperationConfig{}, fmt.Errorf("invalid path %s: %w", path, err)
+		}
+		paths = append(paths, absPath)
+	}
+
+	settings := make(map[string]interface{}, len(step.Settings))
+	for key, value := range step.Settings {
+		if str, ok := value.(string); ok {
+			value = expandHome(str)
+		}
+		settings[key] = value
+	}
+
+	return domain.OperationConfig{
+		DryRun:          dryRun || step.DryRun,
+		Recursive:       true,
+		ExcludePatterns: step.Exclude,
+		TargetPaths:     paths,
+		IncludePatterns: step.Include,
+		CustomSettings:  settings,
+	}, nil
+}
+
+// expandHome expands a leading ~/ to the home directory
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
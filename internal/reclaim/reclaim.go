@@ -0,0 +1,339 @@
+// Package reclaim plans how to free a given amount of space below a path by
+// combining the operations that free space, taking the ones with the least
+// risk to data first:
+//
+//  1. caches: partial downloads and build outputs that are regenerated on
+//     demand, plus the locations of cleaner profiles (cleanup)
+//  2. duplicates: identical copies replaced by hardlinks to one copy, so
+//     every path keeps its content (deduplication)
+//  3. archive: old large files moved to an archive location on another
+//     drive (consolidation)
+//
+// Analyze measures each strategy with dry runs and picks what meets the
+// target; Execute runs the approved plan.
+package reclaim
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/internal/engine"
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// Strategies, in the order of the risk they pose to data
+const (
+	StrategyCaches     = "caches"
+	StrategyDuplicates = "duplicates"
+	StrategyArchive    = "archive"
+)
+
+// Risks of the strategies
+var risks = map[string]string{
+	StrategyCaches:     "none: regenerated on demand",
+	StrategyDuplicates: "low: every path keeps its content as a hardlink",
+	StrategyArchive:    "medium: the files move to the archive",
+}
+
+// Options configure the analysis
+type Options struct {
+	Target        int64         // Bytes to free
+	Profiles      []string      // Cleaner profiles whose locations count as caches
+	ArchiveTo     string        // Where old large files are moved; no archiving without
+	MinSize       int64         // Size from which a file counts as large
+	OlderThan     time.Duration // Age from which a file counts as old
+	Exclude       []string      // Exclude patterns of all operations
+	OneFileSystem bool          // Do not cross mount points while walking
+	HashAlgorithm string        // Hash algorithm of the duplicate search
+}
+
+// Action is one step of a plan: an operation with what it frees
+type Action struct {
+	Strategy    string                 `json:"strategy"`
+	Risk        string                 `json:"risk"`
+	Description string                 `json:"description"`
+	Bytes       int64                  `json:"bytes"`
+	Operation   domain.OperationType   `json:"operation"`
+	Config      domain.OperationConfig `json:"config"`
+}
+
+// Plan is the set of actions chosen to meet the target
+type Plan struct {
+	Root      string           `json:"root"`
+	Target    int64            `json:"target"`
+	Actions   []Action         `json:"actions"`
+	Bytes     int64            `json:"bytes"`     // Freed by the actions
+	Available map[string]int64 `json:"available"` // Freeable per strategy, whether chosen or not
+}
+
+// Reached reports whether the plan frees the target
+func (p *Plan) Reached() bool {
+	return p.Bytes >= p.Target
+}
+
+// Analyze measures what each strategy could free below root and plans the
+// least risky combination that frees opts.Target. Strategies are taken in the
+// order of their risk; duplicates and old files are taken largest first and
+// only as many as needed.
+func Analyze(ctx context.Context, e *engine.Engine, root string, opts Options) (*Plan, error) {
+	plan := &Plan{Root: root, Target: opts.Target, Available: make(map[string]int64)}
+
+	base := domain.OperationConfig{
+		DryRun:          true,
+		Recursive:       true,
+		OneFileSystem:   opts.OneFileSystem,
+		ExcludePatterns: opts.Exclude,
+		TargetPaths:     []string{root},
+		HashAlgorithm:   opts.HashAlgorithm,
+	}
+
+	// Caches
+	cacheConfig := base
+	cacheConfig.CustomSettings = map[string]interface{}{
+		"rules": []string{engine.RulePartialDownloads, engine.RuleBuildArtifacts},
+	}
+	result, err := e.ExecuteOperation(ctx, domain.OperationCleanup, cacheConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze caches: %w", err)
+	}
+	partialBytes, _ := result.Details["partial_bytes"].(int64)
+	artifactBytes, _ := result.Details["artifact_bytes"].(int64)
+	cachePaths := make([]string, 0)
+	if artifacts, ok := result.Details["build_artifacts"].([]engine.BuildArtifact); ok {
+		for _, artifact := range artifacts {
+			cachePaths = append(cachePaths, artifact.Path)
+		}
+	}
+	if partials, err := engine.ResultFiles(result, "partial_downloads"); err == nil {
+		cachePaths = append(cachePaths, partials...)
+	}
+	if bytes := partialBytes + artifactBytes; bytes > 0 {
+		plan.Available[StrategyCaches] += bytes
+		plan.add(Action{
+			Strategy:    StrategyCaches,
+			Description: fmt.Sprintf("Remove %d build output directories and partial downloads", len(cachePaths)),
+			Bytes:       bytes,
+			Operation:   domain.OperationCleanup,
+			Config:      live(cacheConfig),
+		})
+	}
+
+	if len(opts.Profiles) > 0 {
+		profileConfig := base
+		profileConfig.TargetPaths = nil
+		profileConfig.CustomSettings = map[string]interface{}{"profiles": opts.Profiles}
+		result, err := e.ExecuteOperation(ctx, domain.OperationCleanup, profileConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to analyze cleaner profiles: %w", err)
+		}
+		bytes, _ := result.Details["profile_bytes"].(int64)
+		plan.Available[StrategyCaches] += bytes
+		if bytes > 0 && !plan.Reached() {
+			plan.add(Action{
+				Strategy:    StrategyCaches,
+				Description: fmt.Sprintf("Clear the cache locations of %s", strings.Join(opts.Profiles, ", ")),
+				Bytes:       bytes,
+				Operation:   domain.OperationCleanup,
+				Config:      live(profileConfig),
+			})
+		}
+	}
+
+	// Duplicates, except those in caches the plan removes anyway
+	dedupConfig := base
+	dedupConfig.CustomSettings = map[string]interface{}{"mode": engine.DedupModeHardlink}
+	result, err = e.ExecuteOperation(ctx, domain.OperationDeduplication, dedupConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze duplicates: %w", err)
+	}
+	groups, _ := result.Details["groups"].([]domain.DuplicateGroup)
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].SaveablSize > groups[j].SaveablSize })
+	var dupFiles []string
+	var dupBytes int64
+	dupGroups := 0
+	for _, group := range groups {
+		if group.SaveablSize <= 0 || inAny(group, cachePaths, plan) {
+			continue
+		}
+		plan.Available[StrategyDuplicates] += group.SaveablSize
+		if plan.Bytes+dupBytes >= plan.Target {
+			continue // Enough already; keep counting what is available
+		}
+		for _, file := range group.Files {
+			dupFiles = append(dupFiles, file.Path)
+		}
+		dupBytes += group.SaveablSize
+		dupGroups++
+	}
+	if dupGroups > 0 {
+		config, err := engine.FeedFiles(live(dedupConfig), dupFiles)
+		if err != nil {
+			return nil, err
+		}
+		plan.add(Action{
+			Strategy:    StrategyDuplicates,
+			Description: fmt.Sprintf("Hardlink the copies of %d duplicate groups (%d files)", dupGroups, len(dupFiles)),
+			Bytes:       dupBytes,
+			Operation:   domain.OperationDeduplication,
+			Config:      config,
+		})
+	}
+
+	// Old large files
+	if opts.ArchiveTo != "" {
+		linked := make(map[string]bool, len(dupFiles))
+		for _, path := range dupFiles {
+			linked[path] = true
+		}
+		if err := plan.addArchive(ctx, e, base, opts, cachePaths, linked); err != nil {
+			return nil, err
+		}
+	}
+
+	return plan, nil
+}
+
+// addArchive plans moving the largest old files to the archive until the
+// target is met
+func (p *Plan) addArchive(ctx context.Context, e *engine.Engine, base domain.OperationConfig, opts Options, cachePaths []string, linked map[string]bool) error {
+	cutoff := time.Now().Add(-opts.OlderThan)
+	candidates := make([]*domain.FileInfo, 0)
+	err := e.Walk(ctx, p.Root, base, func(path string, info *domain.FileInfo, err error) error {
+		if err != nil || info == nil {
+			return nil // Unreadable items are not archived
+		}
+		if info.IsDir {
+			if within(path, opts.ArchiveTo) || (p.removesCaches() && withinAny(path, cachePaths)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if excluded(path, opts.Exclude) || linked[path] {
+			return nil
+		}
+		if info.Size >= opts.MinSize && info.ModTime.Before(cutoff) {
+			candidates = append(candidates, info)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to find old large files: %w", err)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Size > candidates[j].Size })
+	paths := make([]string, 0)
+	var bytes int64
+	for _, info := range candidates {
+		p.Available[StrategyArchive] += info.Size
+		if p.Bytes+bytes >= p.Target {
+			continue
+		}
+		paths = append(paths, info.Path)
+		bytes += info.Size
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	archiveConfig := live(base)
+	archiveConfig.CustomSettings = map[string]interface{}{
+		"destination":        opts.ArchiveTo,
+		"move":               true,
+		"preserve_structure": true,
+	}
+	archiveConfig, err = engine.FeedFiles(archiveConfig, paths)
+	if err != nil {
+		return err
+	}
+	p.add(Action{
+		Strategy:    StrategyArchive,
+		Description: fmt.Sprintf("Move %d old large files to %s", len(paths), opts.ArchiveTo),
+		Bytes:       bytes,
+		Operation:   domain.OperationConsolidation,
+		Config:      archiveConfig,
+	})
+	return nil
+}
+
+// add appends an action to the plan
+func (p *Plan) add(action Action) {
+	action.Risk = risks[action.Strategy]
+	p.Actions = append(p.Actions, action)
+	p.Bytes += action.Bytes
+}
+
+// removesCaches reports whether the plan clears the caches below the root
+func (p *Plan) removesCaches() bool {
+	for _, action := range p.Actions {
+		if action.Strategy == StrategyCaches && len(action.Config.TargetPaths) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Execute runs the actions of the plan in order, calling done after each,
+// and stops at the first failing one
+func (p *Plan) Execute(ctx context.Context, e *engine.Engine, done func(Action, *domain.OperationResult)) ([]*domain.OperationResult, error) {
+	results := make([]*domain.OperationResult, 0, len(p.Actions))
+	for _, action := range p.Actions {
+		result, err := e.ExecuteOperation(ctx, action.Operation, action.Config)
+		if err != nil {
+			return results, fmt.Errorf("%s: %w", action.Strategy, err)
+		}
+		results = append(results, result)
+		if done != nil {
+			done(action, result)
+		}
+	}
+	return results, nil
+}
+
+// live turns a dry run configuration into the one that changes files
+func live(config domain.OperationConfig) domain.OperationConfig {
+	config.DryRun = false
+	return config
+}
+
+// inAny reports whether a file of group lies in a cache location the plan
+// removes
+func inAny(group domain.DuplicateGroup, cachePaths []string, plan *Plan) bool {
+	if !plan.removesCaches() {
+		return false
+	}
+	for _, file := range group.Files {
+		if withinAny(file.Path, cachePaths) {
+			return true
+		}
+	}
+	return false
+}
+
+// withinAny reports whether path is one of prefixes or lies below one
+func withinAny(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if within(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// within reports whether path is prefix or lies below it
+func within(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// excluded checks the file name of path against exclude patterns
+func excluded(path string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, filepath.Base(path)); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,214 @@
+// Package simulate generates synthetic directory trees, so settings and
+// performance can be tried out before operations touch real data
+package simulate
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// A generated dataset is a directory holding the tree and, next to it so
+// operations on the tree do not see it, its manifest
+const (
+	DataDir      = "data"
+	ManifestFile = "manifest.json"
+)
+
+// Size distributions of generated files
+const (
+	DistributionUniform   = "uniform"   // Every size between the bounds is equally likely
+	DistributionLogNormal = "lognormal" // Mostly small files and a long tail of large ones, like real trees
+)
+
+// extensions are given to generated files in turn, so that organizing them
+// yields several categories
+var extensions = []string{".txt", ".jpg", ".pdf", ".mp3", ".docx", ".zip", ".csv", ".png", ".mp4", ".log"}
+
+// Spec describes a tree to generate. The same spec and seed generate the same tree.
+type Spec struct {
+	Depth          int     `json:"depth"`           // Levels of directories below the root
+	Fanout         int     `json:"fanout"`          // Subdirectories of every directory above the last level
+	Files          int     `json:"files"`           // Files spread over all directories
+	MinSize        int64   `json:"min_size"`        // Smallest file size in bytes
+	MaxSize        int64   `json:"max_size"`        // Largest file size in bytes
+	Distribution   string  `json:"distribution"`    // uniform or lognormal
+	DuplicateRatio float64 `json:"duplicate_ratio"` // Share of files that copy an earlier file (0.0-1.0)
+	Seed           int64   `json:"seed"`
+}
+
+// Manifest describes a generated tree, including what operations should find in it
+type Manifest struct {
+	Spec            Spec      `json:"spec"`
+	Root            string    `json:"root"` // The tree, DataDir of the dataset
+	Created         time.Time `json:"created"`
+	Directories     int       `json:"directories"`
+	Files           int       `json:"files"`
+	Bytes           int64     `json:"bytes"`
+	DuplicateGroups int       `json:"duplicate_groups"` // Files with at least one copy
+	Duplicates      int       `json:"duplicates"`       // Copies, not counting the file they copy
+	DuplicateBytes  int64     `json:"duplicate_bytes"`  // Size of the copies, what deduplication can save
+}
+
+// Validate checks the spec
+func (s Spec) Validate() error {
+	switch {
+	case s.Depth < 0:
+		return fmt.Errorf("depth must not be negative")
+	case s.Depth > 0 && s.Fanout < 1:
+		return fmt.Errorf("fanout must be at least 1")
+	case s.Files < 0:
+		return fmt.Errorf("the number of files must not be negative")
+	case s.MinSize < 1:
+		return fmt.Errorf("the minimum size must be at least 1 byte, as empty files are all duplicates of each other")
+	case s.MaxSize < s.MinSize:
+		return fmt.Errorf("the maximum size must not be smaller than the minimum size")
+	case s.DuplicateRatio < 0 || s.DuplicateRatio > 1:
+		return fmt.Errorf("duplicate ratio must be between 0.0 and 1.0")
+	}
+	switch s.Distribution {
+	case DistributionUniform, DistributionLogNormal:
+	default:
+		return fmt.Errorf("unknown size distribution %q (use %s or %s)", s.Distribution, DistributionUniform, DistributionLogNormal)
+	}
+	return nil
+}
+
+// source is a generated file the later copies of which share its content
+type source struct {
+	seed   int64
+	size   int64
+	copies int
+}
+
+// Generate creates a dataset in dir, which must not exist or be empty: the
+// tree described by spec and its manifest
+func Generate(ctx context.Context, dir string, spec Spec) (*Manifest, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("%s is not empty", dir)
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	root := filepath.Join(dir, DataDir)
+
+	rng := rand.New(rand.NewSource(spec.Seed))
+	dirs := []string{root}
+	for level, parents := 0, []string{root}; level < spec.Depth; level++ {
+		children := make([]string, 0, len(parents)*spec.Fanout)
+		for _, parent := range parents {
+			for i := 0; i < spec.Fanout; i++ {
+				children = append(children, filepath.Join(parent, fmt.Sprintf("dir-%d-%02d", level+1, i)))
+			}
+		}
+		dirs = append(dirs, children...)
+		parents = children
+	}
+	for _, path := range dirs {
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	manifest := &Manifest{Spec: spec, Root: root, Created: time.Now(), Directories: len(dirs) - 1}
+	sources := make([]*source, 0, spec.Files)
+	newest := time.Now()
+	for i := 0; i < spec.Files; i++ {
+		if err := ctx.Err(); err != nil {
+			return manifest, err
+		}
+
+		var file *source
+		if len(sources) > 0 && rng.Float64() < spec.DuplicateRatio {
+			file = sources[rng.Intn(len(sources))]
+			if file.copies == 0 {
+				manifest.DuplicateGroups++
+			}
+			file.copies++
+			manifest.Duplicates++
+			manifest.DuplicateBytes += file.size
+		} else {
+			file = &source{seed: rng.Int63(), size: spec.size(rng)}
+			sources = append(sources, file)
+		}
+
+		name := fmt.Sprintf("file-%06d%s", i, extensions[i%len(extensions)])
+		path := filepath.Join(dirs[rng.Intn(len(dirs))], name)
+		if err := writeFile(path, file); err != nil {
+			return manifest, err
+		}
+		// Spread modification times over three years for date-based operations
+		modified := newest.Add(-time.Duration(rng.Int63n(int64(3 * 365 * 24 * time.Hour))))
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			return manifest, err
+		}
+		manifest.Files++
+		manifest.Bytes += file.size
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, err
+	}
+	return manifest, os.WriteFile(filepath.Join(dir, ManifestFile), append(data, '\n'), 0o644)
+}
+
+// LoadManifest reads the manifest of the dataset generated in dir
+func LoadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid simulation manifest in %s: %w", dir, err)
+	}
+	return &manifest, nil
+}
+
+// size draws a file size from the distribution of the spec
+func (s Spec) size(rng *rand.Rand) int64 {
+	if s.MaxSize == s.MinSize {
+		return s.MinSize
+	}
+	if s.Distribution == DistributionUniform {
+		return s.MinSize + rng.Int63n(s.MaxSize-s.MinSize+1)
+	}
+	// Centered on the geometric mean of the bounds, which lie about three
+	// standard deviations away
+	low, high := math.Log(float64(s.MinSize)), math.Log(float64(s.MaxSize))
+	size := int64(math.Exp((low+high)/2 + rng.NormFloat64()*(high-low)/6))
+	return min(max(size, s.MinSize), s.MaxSize)
+}
+
+// writeFile writes the pseudo-random content of file to path
+func writeFile(path string, file *source) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriterSize(out, 256*1024)
+	if _, err := io.CopyN(writer, rand.New(rand.NewSource(file.seed)), file.size); err != nil {
+		out.Close()
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
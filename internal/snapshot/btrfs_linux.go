@@ -0,0 +1,136 @@
+//go:build linux
+
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// btrfsMagic is the statfs f_type of btrfs
+	btrfsMagic = 0x9123683e
+	// btrfsSubvolumeInode is the inode number of every subvolume's top directory
+	btrfsSubvolumeInode = 256
+	// btrfsIocSnapCreateV2 is BTRFS_IOC_SNAP_CREATE_V2, _IOW(0x94, 23, struct btrfs_ioctl_vol_args_v2)
+	btrfsIocSnapCreateV2 = 0x50009417
+	// btrfsSubvolReadOnly is BTRFS_SUBVOL_RDONLY
+	btrfsSubvolReadOnly = 1 << 1
+	// btrfsNameMax is BTRFS_SUBVOL_NAME_MAX
+	btrfsNameMax = 4039
+)
+
+// btrfsVolArgsV2 mirrors struct btrfs_ioctl_vol_args_v2 (4096 bytes)
+type btrfsVolArgsV2 struct {
+	fd      int64
+	transid uint64
+	flags   uint64
+	unused  [4]uint64
+	name    [btrfsNameMax + 1]byte
+}
+
+// Snapshot snapshots the subvolume of each root once
+func (Btrfs) Snapshot(ctx context.Context, roots []string, name string) ([]Snapshot, error) {
+	if len(name) > btrfsNameMax {
+		return nil, fmt.Errorf("snapshot name %s is too long", name)
+	}
+	snapshots := make([]Snapshot, 0, len(roots))
+	seen := make(map[string]bool)
+	for _, root := range roots {
+		if err := ctx.Err(); err != nil {
+			return snapshots, err
+		}
+		subvolume, err := subvolumeOf(root)
+		if err != nil {
+			return snapshots, err
+		}
+		if seen[subvolume] {
+			continue
+		}
+		seen[subvolume] = true
+
+		dir := filepath.Join(subvolume, Dir)
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return snapshots, fmt.Errorf("failed to create snapshot directory: %w", err)
+		}
+		if err := createBtrfsSnapshot(subvolume, dir, name); err != nil {
+			return snapshots, fmt.Errorf("failed to snapshot btrfs subvolume %s: %w", subvolume, err)
+		}
+		snapshots = append(snapshots, Snapshot{
+			Root:    root,
+			Method:  MethodBtrfs,
+			ID:      filepath.Join(dir, name),
+			Created: time.Now(),
+		})
+	}
+	return snapshots, nil
+}
+
+// subvolumeOf returns the top directory of the btrfs subvolume holding path,
+// or of its closest existing parent when path does not exist yet
+func subvolumeOf(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", fmt.Errorf("no existing parent of %s", path)
+		}
+		path = parent
+	}
+
+	var fs unix.Statfs_t
+	if err := unix.Statfs(path, &fs); err != nil {
+		return "", err
+	}
+	if uint32(fs.Type) != btrfsMagic {
+		return "", fmt.Errorf("%s is not on a btrfs filesystem", path)
+	}
+
+	for {
+		var stat unix.Stat_t
+		if err := unix.Stat(path, &stat); err != nil {
+			return "", err
+		}
+		if stat.Ino == btrfsSubvolumeInode {
+			return path, nil
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", fmt.Errorf("no btrfs subvolume holds %s", path)
+		}
+		path = parent
+	}
+}
+
+// createBtrfsSnapshot creates a read-only snapshot of subvolume named name in dir
+func createBtrfsSnapshot(subvolume, dir, name string) error {
+	source, err := unix.Open(subvolume, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(source)
+	target, err := unix.Open(dir, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(target)
+
+	args := btrfsVolArgsV2{fd: int64(source), flags: btrfsSubvolReadOnly}
+	copy(args.name[:], name)
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(target), btrfsIocSnapCreateV2, uintptr(unsafe.Pointer(&args))); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package snapshot
+
+import (
+	"context"
+	"fmt"
+)
+
+// Snapshot is not supported outside Linux
+func (Btrfs) Snapshot(ctx context.Context, roots []string, name string) ([]Snapshot, error) {
+	return nil, fmt.Errorf("btrfs snapshots are only supported on Linux")
+}
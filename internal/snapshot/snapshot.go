@@ -0,0 +1,126 @@
+// Package snapshot takes filesystem snapshots of the paths an operation is
+// about to change, so a destructive run can be rolled back in one step.
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Snapshot methods
+const (
+	MethodNone    = ""
+	MethodBtrfs   = "btrfs"
+	MethodCommand = "command"
+)
+
+// Dir is the directory at the top of a btrfs subvolume holding the snapshots
+// taken of it. Like other ".fileops-" names it is never walked by operations.
+const Dir = ".fileops-snapshots"
+
+// Snapshot records a snapshot taken before an operation
+type Snapshot struct {
+	Root    string    `json:"root"`
+	Method  string    `json:"method"`
+	ID      string    `json:"id"`
+	Created time.Time `json:"created"`
+}
+
+// Snapshotter snapshots the filesystems holding roots. Roots sharing a
+// filesystem (a subvolume, dataset or volume) are snapshotted once.
+type Snapshotter interface {
+	Snapshot(ctx context.Context, roots []string, name string) ([]Snapshot, error)
+}
+
+// New returns the snapshotter of method, or nil when snapshots are disabled.
+// command is the shell command template of the command method.
+func New(method, command string) (Snapshotter, error) {
+	switch method {
+	case MethodNone, "off":
+		return nil, nil
+	case MethodBtrfs:
+		return Btrfs{}, nil
+	case MethodCommand:
+		return NewCommand(command)
+	}
+	return nil, fmt.Errorf("unknown snapshot method %q (use %s or %s)", method, MethodBtrfs, MethodCommand)
+}
+
+// Command snapshots through a shell command, e.g.
+//
+//	zfs snapshot tank/data@{{.Name}}
+//	lvcreate --snapshot --size 5G --name {{.Name}} vg0/data
+//
+// {{.Path}} is the root being snapshotted and {{.Name}} the snapshot name;
+// both are also passed as FILEOPS_SNAPSHOT_PATH and FILEOPS_SNAPSHOT_NAME.
+// The last line the command prints is recorded as the snapshot ID, the name
+// when it prints nothing. Roots expanding to the same command run it once.
+type Command struct {
+	template *template.Template
+}
+
+// commandContext holds the values a command template can use
+type commandContext struct {
+	Path string
+	Name string
+}
+
+// NewCommand parses a command template
+func NewCommand(command string) (*Command, error) {
+	if strings.TrimSpace(command) == "" {
+		return nil, fmt.Errorf("the command snapshot method needs a command")
+	}
+	tmpl, err := template.New("snapshot").Option("missingkey=error").Parse(command)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot command: %w", err)
+	}
+	return &Command{template: tmpl}, nil
+}
+
+// Snapshot runs the command for each distinct expansion over roots
+func (c *Command) Snapshot(ctx context.Context, roots []string, name string) ([]Snapshot, error) {
+	snapshots := make([]Snapshot, 0, len(roots))
+	seen := make(map[string]bool)
+	for _, root := range roots {
+		var command bytes.Buffer
+		if err := c.template.Execute(&command, commandContext{Path: root, Name: name}); err != nil {
+			return snapshots, fmt.Errorf("invalid snapshot command: %w", err)
+		}
+		if seen[command.String()] {
+			continue
+		}
+		seen[command.String()] = true
+
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "sh", "-c", command.String())
+		cmd.Env = append(os.Environ(), "FILEOPS_SNAPSHOT_PATH="+root, "FILEOPS_SNAPSHOT_NAME="+name)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			if message := strings.TrimSpace(stderr.String()); message != "" {
+				err = fmt.Errorf("%w: %s", err, message)
+			}
+			return snapshots, fmt.Errorf("snapshot command for %s failed: %w", root, err)
+		}
+
+		id := name
+		lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+		if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+			id = last
+		}
+		snapshots = append(snapshots, Snapshot{Root: root, Method: MethodCommand, ID: id, Created: time.Now()})
+	}
+	return snapshots, nil
+}
+
+// Btrfs takes read-only snapshots of the btrfs subvolumes holding the roots
+// through the kernel directly, without the btrfs tools. Each is stored as
+// <subvolume>/.fileops-snapshots/<name>; restoring is a matter of
+// "btrfs subvolume snapshot" of it back into place, or copying files out.
+type Btrfs struct{}
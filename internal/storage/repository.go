@@ -0,0 +1,226 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+	"github.com/a4abhishek/fileops/pkg/schema"
+)
+
+// ErrNotFound is returned when a requested record does not exist
+var ErrNotFound = errors.New("not found")
+
+// FileRepository is a domain.Repository storing records as JSON files.
+//
+// Layout below the root directory:
+//
+//	<operation-id>.json         operation results
+//	<operation-id>.<key>.paths  long path lists of a result, one path per line
+//	files/<hash>.json           file metadata keyed by path
+//	duplicates/<id>.json        duplicate groups
+type FileRepository struct {
+	dir string
+}
+
+// NewFileRepository creates a repository rooted at dir
+func NewFileRepository(dir string) (*FileRepository, error) {
+	for _, sub := range []string{dir, filepath.Join(dir, "files"), filepath.Join(dir, "duplicates")} {
+		if err := os.MkdirAll(sub, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create repository directory: %w", err)
+		}
+	}
+	return &FileRepository{dir: dir}, nil
+}
+
+// SaveResult saves an operation result
+func (r *FileRepository) SaveResult(result *domain.OperationResult) error {
+	if err := validateID(result.ID); err != nil {
+		return err
+	}
+	return writeJSON(filepath.Join(r.dir, result.ID+".json"), result)
+}
+
+// CreatePathList creates the file receiving the path list key of a result
+func (r *FileRepository) CreatePathList(id, key string) (*os.File, error) {
+	if err := validateID(id); err != nil {
+		return nil, err
+	}
+	if err := validateID(key); err != nil {
+		return nil, err
+	}
+	return os.Create(filepath.Join(r.dir, id+"."+key+".paths"))
+}
+
+// GetResult retrieves an operation result by ID
+func (r *FileRepository) GetResult(id string) (*domain.OperationResult, error) {
+	if err := validateID(id); err != nil {
+		return nil, err
+	}
+
+	result, err := readResult(filepath.Join(r.dir, id+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("operation %s: %w", id, err)
+	}
+	return result, nil
+}
+
+// ListResults lists stored results, newest first. Supported filter keys are
+// "operation_type" and "status".
+func (r *FileRepository) ListResults(filter map[string]interface{}) ([]*domain.OperationResult, error) {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*domain.OperationResult, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		result, err := readResult(filepath.Join(r.dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		if matchesFilter(result, filter) {
+			results = append(results, result)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].StartTime.After(results[j].StartTime)
+	})
+	return results, nil
+}
+
+// SaveFileInfo saves file metadata
+func (r *FileRepository) SaveFileInfo(info *domain.FileInfo) error {
+	return writeJSON(r.fileInfoPath(info.Path), info)
+}
+
+// GetFileInfo retrieves file metadata by path
+func (r *FileRepository) GetFileInfo(path string) (*domain.FileInfo, error) {
+	var info domain.FileInfo
+	if err := readJSON(r.fileInfoPath(path), &info); err != nil {
+		return nil, fmt.Errorf("file info %s: %w", path, err)
+	}
+	return &info, nil
+}
+
+// SaveDuplicateGroup saves a duplicate group
+func (r *FileRepository) SaveDuplicateGroup(group *domain.DuplicateGroup) error {
+	if err := validateID(group.ID); err != nil {
+		return err
+	}
+	return writeJSON(filepath.Join(r.dir, "duplicates", group.ID+".json"), group)
+}
+
+// GetDuplicateGroups retrieves all duplicate groups
+func (r *FileRepository) GetDuplicateGroups() ([]*domain.DuplicateGroup, error) {
+	dir := filepath.Join(r.dir, "duplicates")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]*domain.DuplicateGroup, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		var group domain.DuplicateGroup
+		if err := readJSON(filepath.Join(dir, entry.Name()), &group); err != nil {
+			continue
+		}
+		groups = append(groups, &group)
+	}
+	return groups, nil
+}
+
+// Close closes the repository
+func (r *FileRepository) Close() error {
+	return nil
+}
+
+// fileInfoPath returns the record path for the metadata of path
+func (r *FileRepository) fileInfoPath(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return filepath.Join(r.dir, "files", fmt.Sprintf("%x.json", sum[:16]))
+}
+
+// matchesFilter reports whether result satisfies every filter entry
+func matchesFilter(result *domain.OperationResult, filter map[string]interface{}) bool {
+	for key, value := range filter {
+		expected := fmt.Sprint(value)
+		switch key {
+		case "operation_type":
+			if string(result.OperationType) != expected {
+				return false
+			}
+		case "status":
+			if string(result.Status) != expected {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// validateID rejects identifiers that could escape the repository directory
+func validateID(id string) error {
+	if id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return fmt.Errorf("invalid record id %q", id)
+	}
+	return nil
+}
+
+// writeJSON atomically writes value as indented JSON to path
+func writeJSON(path string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return filesystem.WriteFileAtomic(path, data, 0644)
+}
+
+// readResult reads the result at path, converted to the current schema
+// version
+func readResult(path string) (*domain.OperationResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if data, err = schema.Upgrade(schema.Result, data); err != nil {
+		return nil, err
+	}
+	var result domain.OperationResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// readJSON decodes the JSON file at path into value
+func readJSON(path string, value interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
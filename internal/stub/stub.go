@@ -0,0 +1,110 @@
+// Package stub reads and writes stub files: small placeholders left where a
+// file was offloaded to another volume, naming where its content went and how
+// to check it. A stub keeps the name and modification time of its file.
+//
+//	{"fileops_stub":1,"location":"/mnt/cold/photos/2019/trip.mov","size":4831838208,...}
+package stub
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Version is the format version written to new stubs
+const Version = 1
+
+// MaxSize is the largest size of a stub file; larger files are never stubs
+const MaxSize = 4096
+
+// ErrNotStub is returned when reading a file that is not a stub
+var ErrNotStub = errors.New("not a stub file")
+
+// prefix starts every stub file
+var prefix = []byte(`{"fileops_stub":`)
+
+// Stub describes offloaded content
+type Stub struct {
+	Version   int       `json:"fileops_stub"`
+	Location  string    `json:"location"` // Where the content is now
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"mod_time"`
+	Algorithm string    `json:"algorithm"`
+	Hash      string    `json:"hash"`
+}
+
+// Read reads the stub at path
+func Read(path string) (*Stub, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxSize || !bytes.HasPrefix(data, prefix) {
+		return nil, ErrNotStub
+	}
+	var s Stub
+	if err := json.Unmarshal(data, &s); err != nil || s.Location == "" {
+		return nil, fmt.Errorf("%w: %s is malformed", ErrNotStub, path)
+	}
+	return &s, nil
+}
+
+// Detect reads the file at path, of the given size, if it is a stub. Files
+// too large or too small to be one are not opened.
+func Detect(path string, size int64) (*Stub, error) {
+	if size > MaxSize || size < int64(len(prefix)) {
+		return nil, ErrNotStub
+	}
+	return Read(path)
+}
+
+// Is reports whether the file at path, of the given size, is a stub
+func Is(path string, size int64) bool {
+	_, err := Detect(path, size)
+	return err == nil
+}
+
+// Write replaces the file at path with a stub, atomically, and gives the stub
+// the modification time of the content it stands for
+func Write(path string, s Stub) error {
+	s.Version = Version
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".fileops-stub-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if !s.ModTime.IsZero() {
+		if err := os.Chtimes(tmp.Name(), time.Now(), s.ModTime); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,312 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math/bits"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+
+	// Register the natively supported decoders
+	_ "image/gif"
+	_ "image/png"
+)
+
+// ErrUnsupported is returned for files that cannot be rendered in this environment
+var ErrUnsupported = errors.New("unsupported media type")
+
+// ErrCorrupt is returned for media files that cannot be decoded
+var ErrCorrupt = errors.New("corrupt media")
+
+// nativeImageExtensions are decoded with the standard library
+var nativeImageExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true,
+}
+
+// externalImageExtensions are image formats rendered through ffmpeg when available
+var externalImageExtensions = map[string]bool{
+	".webp": true, ".bmp": true, ".tif": true, ".tiff": true, ".heic": true, ".heif": true,
+}
+
+// videoExtensions are rendered from a frame near the start of the video through ffmpeg
+var videoExtensions = map[string]bool{
+	".mp4": true, ".mov": true, ".m4v": true, ".mkv": true, ".avi": true,
+	".webm": true, ".wmv": true, ".mpg": true, ".mpeg": true, ".3gp": true,
+}
+
+// IsImage reports whether path has an image extension
+func IsImage(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return nativeImageExtensions[ext] || externalImageExtensions[ext]
+}
+
+// IsVideo reports whether path has a video extension
+func IsVideo(path string) bool {
+	return videoExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// CachePath returns the cache location of the thumbnail of source at the given size.
+// The name is derived from the absolute source path so it is stable across runs.
+func CachePath(cacheDir, source string, size int) string {
+	if abs, err := filepath.Abs(source); err == nil {
+		source = abs
+	}
+	sum := sha256.Sum256([]byte(source))
+	name := fmt.Sprintf("%x_%d.jpg", sum[:16], size)
+	return filepath.Join(cacheDir, name[:2], name)
+}
+
+// Generator renders thumbnails
+type Generator struct {
+	Size       int    // Longest edge of the thumbnail in pixels
+	Quality    int    // JPEG quality (1-100)
+	FFmpegPath string // ffmpeg binary used for videos and non-native images; empty disables them
+}
+
+// NewGenerator creates a generator, locating ffmpeg on PATH if installed
+func NewGenerator(size, quality int) *Generator {
+	ffmpeg, _ := exec.LookPath("ffmpeg")
+	return &Generator{Size: size, Quality: quality, FFmpegPath: ffmpeg}
+}
+
+// Supports reports whether the generator can render path
+func (g *Generator) Supports(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	if nativeImageExtensions[ext] {
+		return true
+	}
+	return g.FFmpegPath != "" && (externalImageExtensions[ext] || videoExtensions[ext])
+}
+
+// UpToDate reports whether target exists and was rendered from the current version of source
+func UpToDate(source, target string) bool {
+	sourceInfo, err := os.Stat(source)
+	if err != nil {
+		return false
+	}
+	targetInfo, err := os.Stat(target)
+	if err != nil {
+		return false
+	}
+	return targetInfo.ModTime().Equal(sourceInfo.ModTime())
+}
+
+// Generate renders the thumbnail of source into target. The thumbnail gets the
+// modification time of the source so UpToDate can detect later changes.
+func (g *Generator) Generate(ctx context.Context, source, target string) (err error) {
+	sourceInfo, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	// Decoders may panic on malformed input; treat that as a corrupt file
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w %s: %v", ErrCorrupt, source, r)
+		}
+	}()
+
+	ext := strings.ToLower(filepath.Ext(source))
+	switch {
+	case nativeImageExtensions[ext]:
+		err = g.renderNative(source, target)
+	case g.FFmpegPath != "" && (externalImageExtensions[ext] || videoExtensions[ext]):
+		err = g.renderExternal(ctx, source, target, videoExtensions[ext])
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupported, source)
+	}
+	if err != nil {
+		_ = os.Remove(target)
+		return err
+	}
+
+	return os.Chtimes(target, time.Now(), sourceInfo.ModTime())
+}
+
+// renderNative decodes source with the standard library and writes a scaled JPEG
+func (g *Generator) renderNative(source, target string) error {
+	file, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return fmt.Errorf("%w %s: %w", ErrCorrupt, source, err)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scale(img, g.Size), &jpeg.Options{Quality: g.Quality}); err != nil {
+		return err
+	}
+	return filesystem.WriteFileAtomic(target, buf.Bytes(), 0644)
+}
+
+// renderExternal extracts a scaled frame with ffmpeg
+func (g *Generator) renderExternal(ctx context.Context, source, target string, video bool) error {
+	tmp := target + ".tmp.jpg"
+	defer os.Remove(tmp)
+
+	size := strconv.Itoa(g.Size)
+	filter := fmt.Sprintf("scale='if(gt(iw,ih),%s,-2)':'if(gt(iw,ih),-2,%s)'", size, size)
+	args := []string{"-v", "error", "-y"}
+	if video {
+		// Seek a little into the video to skip black intro frames
+		args = append(args, "-ss", "1")
+	}
+	// JPEG quality for ffmpeg is 2 (best) to 31 (worst)
+	qscale := 2 + (100-g.Quality)*29/100
+	args = append(args, "-i", source, "-frames:v", "1", "-vf", filter, "-q:v", strconv.Itoa(qscale), tmp)
+
+	output, err := exec.CommandContext(ctx, g.FFmpegPath, args...).CombinedOutput()
+	if err != nil && video {
+		// Videos shorter than the seek offset: take the very first frame instead
+		retryArgs := append([]string{"-v", "error", "-y"}, args[5:]...)
+		output, err = exec.CommandContext(ctx, g.FFmpegPath, retryArgs...).CombinedOutput()
+	}
+	if err != nil {
+		return fmt.Errorf("%w %s: ffmpeg: %s", ErrCorrupt, source, strings.TrimSpace(string(output)))
+	}
+	return os.Rename(tmp, target)
+}
+
+// scale downsizes img so its longest edge is at most size, averaging source pixels
+func scale(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= 0 || height <= 0 {
+		return img
+	}
+
+	dstW, dstH := width, height
+	if width >= height && width > size {
+		dstW, dstH = size, max(1, height*size/width)
+	} else if height > width && height > size {
+		dstW, dstH = max(1, width*size/height), size
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0 := bounds.Min.Y + y*height/dstH
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*height/dstH)
+		for x := 0; x < dstW; x++ {
+			x0 := bounds.Min.X + x*width/dstW
+			x1 := max(x0+1, bounds.Min.X+(x+1)*width/dstW)
+
+			// Sample at most 4x4 source pixels per output pixel to bound the cost on large photos
+			stepX, stepY := max(1, (x1-x0)/4), max(1, (y1-y0)/4)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy += stepY {
+				for sx := x0; sx < x1; sx += stepX {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
+
+// Fingerprintable reports whether Fingerprint can read path: a JPEG, PNG or
+// GIF image
+func Fingerprintable(path string) bool {
+	return nativeImageExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// Fingerprint returns a 64-bit difference hash of a natively decodable image.
+// Re-encoded, resized or slightly edited copies of a picture differ in only a
+// few bits, see Similarity.
+func Fingerprint(path string) (uint64, error) {
+	if !Fingerprintable(path) {
+		return 0, fmt.Errorf("%w: %s", ErrUnsupported, path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return 0, fmt.Errorf("%w %s: %w", ErrCorrupt, path, err)
+	}
+
+	// Average a small copy down to 9x8 gray cells, then compare neighbours
+	small := scale(img, 256)
+	bounds := small.Bounds()
+	var cells [8][9]uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 9; x++ {
+			x0, x1 := bounds.Min.X+x*bounds.Dx()/9, bounds.Min.X+max((x+1)*bounds.Dx()/9, x*bounds.Dx()/9+1)
+			y0, y1 := bounds.Min.Y+y*bounds.Dy()/8, bounds.Min.Y+max((y+1)*bounds.Dy()/8, y*bounds.Dy()/8+1)
+			var sum, n uint64
+			for sy := y0; sy < y1 && sy < bounds.Max.Y; sy++ {
+				for sx := x0; sx < x1 && sx < bounds.Max.X; sx++ {
+					r, g, b, _ := small.At(sx, sy).RGBA()
+					sum += (299*uint64(r) + 587*uint64(g) + 114*uint64(b)) / 1000
+					n++
+				}
+			}
+			if n > 0 {
+				cells[y][x] = sum / n
+			}
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if cells[y][x] > cells[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash, nil
+}
+
+// Similarity returns the share of equal bits of two fingerprints (0.0-1.0)
+func Similarity(a, b uint64) float64 {
+	return 1 - float64(bits.OnesCount64(a^b))/64
+}
+
+// Dimensions returns the width and height of a natively decodable image
+// without decoding its pixels
+func Dimensions(path string) (int, int, error) {
+	if !nativeImageExtensions[strings.ToLower(filepath.Ext(path))] {
+		return 0, 0, fmt.Errorf("%w: %s", ErrUnsupported, path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w %s: %w", ErrCorrupt, path, err)
+	}
+	return config.Width, config.Height, nil
+}
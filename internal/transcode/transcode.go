@@ -0,0 +1,176 @@
+// Package transcode converts videos by handing them to ffmpeg
+package transcode
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ErrUnavailable is returned when ffmpeg or ffprobe is not installed
+var ErrUnavailable = errors.New("ffmpeg and ffprobe are required for transcoding")
+
+// ErrDurationMismatch is returned when a transcoded video is shorter or
+// longer than its source, e.g. because ffmpeg stopped at a damaged frame
+var ErrDurationMismatch = errors.New("output duration does not match the source")
+
+// DefaultArguments transcode to H.265 video and AAC audio
+var DefaultArguments = []string{
+	"-i", "{{.Input}}", "-c:v", "libx265", "-crf", "28", "-preset", "medium",
+	"-c:a", "aac", "-b:a", "128k", "{{.Output}}",
+}
+
+// Variables are available to argument templates
+type Variables struct {
+	Input  string // Source video
+	Output string // File ffmpeg must write
+	Name   string // Source file name, e.g. holiday.avi
+	Stem   string // Source name without extension, e.g. holiday
+	Ext    string // Source extension without dot, e.g. avi
+	Dir    string // Directory of the source
+}
+
+// NewVariables returns the variables for transcoding input into output
+func NewVariables(input, output string) Variables {
+	name := filepath.Base(input)
+	return Variables{
+		Input:  input,
+		Output: output,
+		Name:   name,
+		Stem:   strings.TrimSuffix(name, filepath.Ext(name)),
+		Ext:    strings.TrimPrefix(filepath.Ext(name), "."),
+		Dir:    filepath.Dir(input),
+	}
+}
+
+// Arguments are ffmpeg arguments, each a text/template over Variables
+type Arguments []*template.Template
+
+// ParseArguments parses argument templates. They must read {{.Input}} and
+// write {{.Output}}.
+func ParseArguments(args []string) (Arguments, error) {
+	parsed := make(Arguments, 0, len(args))
+	input, output := false, false
+	for i, arg := range args {
+		tmpl, err := template.New(strconv.Itoa(i)).Option("missingkey=error").Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid argument %q: %w", arg, err)
+		}
+		input = input || strings.Contains(arg, ".Input")
+		output = output || strings.Contains(arg, ".Output")
+		parsed = append(parsed, tmpl)
+	}
+	if !input || !output {
+		return nil, fmt.Errorf("arguments must contain {{.Input}} and {{.Output}}")
+	}
+	return parsed, nil
+}
+
+// Expand returns the arguments for one video
+func (a Arguments) Expand(vars Variables) ([]string, error) {
+	args := make([]string, 0, len(a))
+	for _, tmpl := range a {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, vars); err != nil {
+			return nil, err
+		}
+		args = append(args, buf.String())
+	}
+	return args, nil
+}
+
+// Transcoder runs ffmpeg and ffprobe
+type Transcoder struct {
+	FFmpegPath  string
+	FFprobePath string
+}
+
+// New locates ffmpeg and ffprobe on PATH
+func New() (*Transcoder, error) {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, ErrUnavailable
+	}
+	ffprobe, err := exec.LookPath("ffprobe")
+	if err != nil {
+		return nil, ErrUnavailable
+	}
+	return &Transcoder{FFmpegPath: ffmpeg, FFprobePath: ffprobe}, nil
+}
+
+// Duration returns the playing time of a video
+func (t *Transcoder) Duration(ctx context.Context, path string) (time.Duration, error) {
+	output, err := t.probe(ctx, path, "-show_entries", "format=duration")
+	if err != nil {
+		return 0, err
+	}
+	seconds, err := strconv.ParseFloat(output, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s has no duration", path)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// Codec returns the codec of the first video stream, e.g. mpeg4 or h264
+func (t *Transcoder) Codec(ctx context.Context, path string) (string, error) {
+	return t.probe(ctx, path, "-select_streams", "v:0", "-show_entries", "stream=codec_name")
+}
+
+// probe returns one value ffprobe reports about path
+func (t *Transcoder) probe(ctx context.Context, path string, args ...string) (string, error) {
+	args = append([]string{"-v", "error"}, args...)
+	args = append(args, "-of", "default=noprint_wrappers=1:nokey=1", path)
+	output, err := exec.CommandContext(ctx, t.FFprobePath, args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("ffprobe %s: %s", path, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(strings.SplitN(string(output), "\n", 2)[0]), nil
+}
+
+// Run runs ffmpeg with args, calling progress with the position ffmpeg has
+// reached in the video as it advances
+func (t *Transcoder) Run(ctx context.Context, args []string, progress func(time.Duration)) error {
+	args = append([]string{"-hide_banner", "-nostdin", "-nostats", "-loglevel", "error", "-progress", "pipe:1", "-y"}, args...)
+	cmd := exec.CommandContext(ctx, t.FFmpegPath, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	// -progress writes key=value blocks; out_time_us is the position reached
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), "=")
+		if key != "out_time_us" || progress == nil {
+			continue
+		}
+		if micros, err := strconv.ParseInt(value, 10, 64); err == nil && micros >= 0 {
+			progress(time.Duration(micros) * time.Microsecond)
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return fmt.Errorf("ffmpeg: %s", message)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package ui
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// Everything printed to the terminal goes through a single writer goroutine,
+// so that the progress line of a running operation and the messages and log
+// lines written while it runs (from any goroutine) never clobber each other.
+// The progress line is the status line: it stays at the bottom, is cleared
+// before other output is written and drawn again below it. It is only drawn
+// when standard output is a terminal.
+var (
+	Stdout io.Writer = terminalWriter{os.Stdout}
+	Stderr io.Writer = terminalWriter{os.Stderr}
+)
+
+// clearLine returns the cursor to the start of the line and erases the line
+const clearLine = "\r\033[K"
+
+// terminalRequest is one write or status change handed to the writer
+type terminalRequest struct {
+	file   *os.File
+	text   []byte
+	status *string // New status line instead of a write; empty clears it
+	done   chan terminalResult
+}
+
+type terminalResult struct {
+	n   int
+	err error
+}
+
+// terminal is the state of the writer goroutine
+var terminal struct {
+	start    sync.Once
+	requests chan terminalRequest
+	status   string // Status line to keep at the bottom
+	shown    bool   // Whether the status line is on the screen
+	partial  bool   // Whether the last write left a line unfinished, such as a prompt
+	enabled  bool   // Whether standard output is a terminal
+}
+
+// terminalWriter writes to a file through the writer goroutine
+type terminalWriter struct {
+	file *os.File
+}
+
+// Write writes p once the status line is out of the way
+func (w terminalWriter) Write(p []byte) (int, error) {
+	result := send(terminalRequest{file: w.file, text: p})
+	return result.n, result.err
+}
+
+// SetStatus shows line as the status line, replacing the previous one
+func SetStatus(line string) {
+	send(terminalRequest{status: &line})
+}
+
+// ClearStatus removes the status line
+func ClearStatus() {
+	SetStatus("")
+}
+
+// send hands a request to the writer goroutine and waits until it is done,
+// so that output is never lost when the program exits
+func send(request terminalRequest) terminalResult {
+	terminal.start.Do(func() {
+		info, err := os.Stdout.Stat()
+		terminal.enabled = err == nil && info.Mode()&os.ModeCharDevice != 0
+		terminal.requests = make(chan terminalRequest)
+		go runTerminal()
+	})
+	request.done = make(chan terminalResult, 1)
+	terminal.requests <- request
+	return <-request.done
+}
+
+// runTerminal is the only goroutine writing to standard output and error
+func runTerminal() {
+	for request := range terminal.requests {
+		var result terminalResult
+		if request.status != nil {
+			hideStatus()
+			terminal.status = *request.status
+			drawStatus()
+		} else {
+			hideStatus()
+			result.n, result.err = request.file.Write(request.text)
+			// A partly written line is left alone until it is finished
+			if len(request.text) > 0 {
+				terminal.partial = request.text[len(request.text)-1] != '\n'
+			}
+			drawStatus()
+		}
+		request.done <- result
+	}
+}
+
+// hideStatus erases the status line from the screen
+func hideStatus() {
+	if terminal.shown {
+		_, _ = os.Stdout.WriteString(clearLine)
+		terminal.shown = false
+	}
+}
+
+// drawStatus writes the status line at the cursor unless a partly written
+// line is pending; otherwise the cursor is at the start of the last line
+func drawStatus() {
+	if terminal.enabled && terminal.status != "" && !terminal.partial {
+		_, _ = os.Stdout.WriteString(terminal.status)
+		terminal.shown = true
+	}
+}
@@ -0,0 +1,127 @@
+package ui
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Runes that continue an icon
+const (
+	variationSelector = '\uFE0F' // Shows the preceding symbol as emoji
+	zeroWidthJoiner   = '\u200D' // Joins two emoji into one
+)
+
+// statusTags replace the icons that carry meaning in the ASCII theme
+var statusTags = map[rune]string{
+	'✅': "[OK]",
+	'❌': "[ERROR]",
+	'⚠': "[WARN]",
+	'❗': "[!]",
+	'❓': "[?]",
+	'💡': "[TIP]",
+	'ℹ': "[INFO]",
+	'💀': "[FATAL]",
+	'🛑': "[STOP]",
+}
+
+// asciiSymbols replace the arrows and markers of running text in the ASCII
+// theme; the other themes keep them
+var asciiSymbols = map[rune]string{
+	'→': "->",
+	'←': "<-",
+	'⇔': "<=>",
+	'↳': "->",
+	'↻': "(r)",
+	'•': "*",
+	'…': "...",
+	'✓': "+",
+	'✗': "x",
+}
+
+// Apply renders text in the configured theme
+func Apply(text string) string {
+	return ApplyTheme(Theme(), text)
+}
+
+// ApplyTheme renders text in theme. Icons are dropped with the spaces after
+// them, so "⏱️  Total time" becomes "Total time" and "  ✓ Removed" keeps its
+// indentation.
+func ApplyTheme(theme, text string) string {
+	if theme == ThemeEmoji || isASCII(text) {
+		return text
+	}
+
+	var out strings.Builder
+	out.Grow(len(text))
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+
+		if theme == ThemeASCII {
+			if symbol, ok := asciiSymbols[r]; ok {
+				out.WriteString(symbol)
+				continue
+			}
+		}
+		if !isIcon(r) {
+			out.WriteRune(r)
+			continue
+		}
+
+		// Skip the rest of the icon: variation selectors and joined icons
+		for i < len(text) {
+			next, nextSize := utf8.DecodeRuneInString(text[i:])
+			if next != variationSelector && next != zeroWidthJoiner && !(isIcon(next) && prevJoiner(text, i)) {
+				break
+			}
+			i += nextSize
+		}
+		spaced := false
+		for i < len(text) && text[i] == ' ' {
+			i++
+			spaced = true
+		}
+
+		tag, ok := statusTags[r]
+		if theme == ThemeASCII && ok {
+			out.WriteString(tag)
+			if spaced {
+				out.WriteByte(' ')
+			}
+		}
+	}
+	return out.String()
+}
+
+// isIcon reports whether r is an emoji or pictograph rather than text
+func isIcon(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // Emoji and pictographs
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // Miscellaneous symbols and dingbats
+		return r != '✓' && r != '✗'
+	case r >= 0x2300 && r <= 0x23FF: // Technical symbols: ⏱ ⏳ ⏭
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF: // ⭐
+		return true
+	case r == 'ℹ':
+		return true
+	}
+	return false
+}
+
+// prevJoiner reports whether the rune before text[i] is a zero-width joiner
+func prevJoiner(text string, i int) bool {
+	prev, _ := utf8.DecodeLastRuneInString(text[:i])
+	return prev == zeroWidthJoiner
+}
+
+// isASCII reports whether text has nothing to render
+func isASCII(text string) bool {
+	for i := 0; i < len(text); i++ {
+		if text[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
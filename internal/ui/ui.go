@@ -0,0 +1,182 @@
+// Package ui renders the messages of the command line in the configured theme
+// and language. Messages are written with English format strings, which are
+// also the keys of the message catalogs in locales/:
+//
+//	ui.Printf("⏱️  Total time: %v\n", elapsed)
+//
+// prints "⏱️  Gesamtzeit: 1.2s" with the German catalog, and
+// "Total time: 1.2s" with the minimal theme.
+package ui
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Themes of the output
+const (
+	ThemeEmoji   = "emoji"   // Messages as written
+	ThemeASCII   = "ascii"   // Status icons as [OK], [WARN], ...; other icons dropped, arrows as ->
+	ThemeMinimal = "minimal" // No icons; arrows and list markers are kept
+)
+
+// DefaultLocale is the language the messages are written in
+const DefaultLocale = "en"
+
+//go:embed locales/*.yaml
+var catalogFiles embed.FS
+
+// settings are set once at startup, before any output
+var settings struct {
+	mu      sync.RWMutex
+	theme   string
+	locale  string
+	catalog map[string]string // English format -> translated format
+}
+
+// Configure sets the theme and the locale of all further output. An empty
+// locale is taken from the environment (LC_ALL, LC_MESSAGES, LANG); locales
+// without a catalog fall back to English.
+func Configure(theme, locale string) error {
+	if !ValidTheme(theme) {
+		return fmt.Errorf("unknown output theme %q (use %s, %s or %s)", theme, ThemeEmoji, ThemeASCII, ThemeMinimal)
+	}
+	if locale == "" {
+		locale = EnvironmentLocale()
+	}
+	language := Language(locale)
+
+	catalog, err := loadCatalog(language)
+	if err != nil {
+		return err
+	}
+	if catalog == nil {
+		language = DefaultLocale
+	}
+
+	settings.mu.Lock()
+	settings.theme = theme
+	settings.locale = language
+	settings.catalog = catalog
+	settings.mu.Unlock()
+	return nil
+}
+
+// ValidTheme reports whether theme names a theme
+func ValidTheme(theme string) bool {
+	switch theme {
+	case ThemeEmoji, ThemeASCII, ThemeMinimal:
+		return true
+	}
+	return false
+}
+
+// Theme returns the configured theme
+func Theme() string {
+	settings.mu.RLock()
+	defer settings.mu.RUnlock()
+	if settings.theme == "" {
+		return ThemeEmoji
+	}
+	return settings.theme
+}
+
+// Locale returns the language messages are printed in, e.g. de
+func Locale() string {
+	settings.mu.RLock()
+	defer settings.mu.RUnlock()
+	if settings.locale == "" {
+		return DefaultLocale
+	}
+	return settings.locale
+}
+
+// Locales lists the languages with a message catalog, English included
+func Locales() []string {
+	locales := []string{DefaultLocale}
+	entries, _ := catalogFiles.ReadDir("locales")
+	for _, entry := range entries {
+		locales = append(locales, strings.TrimSuffix(entry.Name(), path.Ext(entry.Name())))
+	}
+	sort.Strings(locales[1:])
+	return locales
+}
+
+// EnvironmentLocale returns the locale of messages set in the environment
+func EnvironmentLocale() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return DefaultLocale
+}
+
+// Language returns the language of a locale: de for de_DE.UTF-8 or de-AT.
+// The C and POSIX locales are English.
+func Language(locale string) string {
+	language := strings.ToLower(locale)
+	if i := strings.IndexAny(language, "_-.@"); i >= 0 {
+		language = language[:i]
+	}
+	if language == "" || language == "c" || language == "posix" {
+		return DefaultLocale
+	}
+	return language
+}
+
+// loadCatalog reads the catalog of a language, nil when there is none
+func loadCatalog(language string) (map[string]string, error) {
+	if language == DefaultLocale {
+		return nil, nil
+	}
+	data, err := catalogFiles.ReadFile("locales/" + language + ".yaml")
+	if err != nil {
+		return nil, nil
+	}
+	var catalog map[string]string
+	if err := yaml.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("invalid message catalog %s: %w", language, err)
+	}
+	return catalog, nil
+}
+
+// T translates a message that is not a format string, e.g. a label
+func T(message string) string {
+	settings.mu.RLock()
+	defer settings.mu.RUnlock()
+	if translated, ok := settings.catalog[message]; ok {
+		return translated
+	}
+	return message
+}
+
+// Sprintf translates format, formats it and renders the result in the theme
+func Sprintf(format string, args ...interface{}) string {
+	return Apply(fmt.Sprintf(T(format), args...))
+}
+
+// Printf writes a message to standard output
+func Printf(format string, args ...interface{}) {
+	fmt.Fprint(Stdout, Sprintf(format, args...))
+}
+
+// Fprintf writes a message to w; standard output and error are written
+// through the terminal writer
+func Fprintf(w io.Writer, format string, args ...interface{}) {
+	switch w {
+	case os.Stdout:
+		w = Stdout
+	case os.Stderr:
+		w = Stderr
+	}
+	fmt.Fprint(w, Sprintf(format, args...))
+}
@@ -2,6 +2,10 @@ package domain
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -20,6 +24,14 @@ type FileInfo struct {
 	Metadata    map[string]string `json:"metadata,omitempty"`
 	ContentHash string            `json:"content_hash,omitempty"`
 	PartialHash string            `json:"partial_hash,omitempty"`
+	Label       string            `json:"label,omitempty"` // Label of the root the file was found under, e.g. "laptop"
+
+	// Platform metadata captured during the scan (zero when unavailable)
+	Device uint64 `json:"device,omitempty"`
+	Inode  uint64 `json:"inode,omitempty"`
+	Links  uint64 `json:"links,omitempty"`
+	UID    int    `json:"uid"` // -1 when ownership is not available on the platform
+	GID    int    `json:"gid"` // -1 when ownership is not available on the platform
 }
 
 // OperationType defines the type of operation being performed
@@ -33,6 +45,33 @@ const (
 	OperationOrganization  OperationType = "organization"
 	OperationOwnership     OperationType = "ownership"
 	OperationPipeline      OperationType = "pipeline"
+	OperationThumbnail     OperationType = "thumbnail"
+	OperationStripMetadata OperationType = "strip_metadata"
+	OperationContentIndex  OperationType = "content_index"
+	OperationChecksum      OperationType = "checksum"
+	OperationRepair        OperationType = "repair"
+	OperationEncrypt       OperationType = "encrypt"
+	OperationDecrypt       OperationType = "decrypt"
+	OperationPack          OperationType = "pack"
+	OperationPackRestore   OperationType = "pack_restore"
+	OperationUpload        OperationType = "upload"
+	OperationTriage        OperationType = "triage"
+	OperationVersions      OperationType = "versions"
+	OperationOwners        OperationType = "owners"
+	OperationGrant         OperationType = "grant"
+	OperationStats         OperationType = "stats"
+	OperationTranscode     OperationType = "transcode"
+	OperationPDFMerge      OperationType = "pdf_merge"
+	OperationPDFSplit      OperationType = "pdf_split"
+	OperationMailExtract   OperationType = "mail_extract"
+	OperationBagExport     OperationType = "bag_export"
+	OperationBagValidate   OperationType = "bag_validate"
+	OperationRehash        OperationType = "rehash"
+	OperationIngest        OperationType = "ingest"
+	OperationMigration     OperationType = "migration"
+	OperationTiering       OperationType = "tiering"
+	OperationApplyPlan     OperationType = "apply_plan"
+	OperationVerifyTree    OperationType = "verify_tree"
 )
 
 // String returns the string representation of the operation type
@@ -76,7 +115,8 @@ type ProgressInfo struct {
 	TotalItems     int64                  `json:"total_items"`
 	BytesProcessed int64                  `json:"bytes_processed"`
 	TotalBytes     int64                  `json:"total_bytes"`
-	Speed          int64                  `json:"speed"` // items per second
+	Speed          int64                  `json:"speed"`      // items per second
+	ByteSpeed      int64                  `json:"byte_speed"` // bytes per second
 	EstimatedETA   *time.Duration         `json:"estimated_eta,omitempty"`
 	Error          string                 `json:"error,omitempty"`
 	Details        map[string]interface{} `json:"details,omitempty"`
@@ -84,6 +124,7 @@ type ProgressInfo struct {
 
 // OperationResult represents the result of an operation
 type OperationResult struct {
+	SchemaVersion  int                    `json:"schema_version"` // See package schema
 	ID             string                 `json:"id"`
 	OperationType  OperationType          `json:"operation_type"`
 	Status         OperationStatus        `json:"status"`
@@ -96,7 +137,32 @@ type OperationResult struct {
 	Summary        string                 `json:"summary"`
 	Details        map[string]interface{} `json:"details"`
 	Errors         []OperationError       `json:"errors,omitempty"`
-	Warnings       []string               `json:"warnings,omitempty"`
+	Warnings       []OperationWarning     `json:"warnings,omitempty"`
+	SlowPaths      []PathLatency          `json:"slow_paths,omitempty"`  // Directories that were slow to read or failed while walking
+	Config         *OperationConfig       `json:"config,omitempty"`      // Configuration the operation ran with
+	Artifacts      string                 `json:"artifacts,omitempty"`   // Directory holding the plan, report, undo journal, errors and log of the run
+	Environment    *Environment           `json:"environment,omitempty"` // Who ran the operation, where and with what settings
+}
+
+// Environment records who ran an operation, where and with what settings, so
+// that stored results can be audited
+type Environment struct {
+	Hostname   string            `json:"hostname"`
+	User       string            `json:"user"`
+	Version    string            `json:"version"`               // Version of fileops
+	Command    string            `json:"command,omitempty"`     // Command path, e.g. "fileops dedup"
+	ConfigFile string            `json:"config_file,omitempty"` // Empty when the defaults were used
+	ConfigHash string            `json:"config_hash,omitempty"` // SHA-256 of the configuration file
+	Flags      map[string]string `json:"flags,omitempty"`       // Flags set on the command line or by configured defaults; secrets redacted
+}
+
+// PathList is a list of paths in the details of an operation result. Only the
+// first paths are kept inline; when there are more, all of them are written to
+// File, one per line, and only counted in memory.
+type PathList struct {
+	Count int      `json:"count"`
+	Paths []string `json:"paths"`
+	File  string   `json:"file,omitempty"` // Empty when the paths beyond Paths were not stored
 }
 
 // OperationError represents an error that occurred during an operation
@@ -108,6 +174,57 @@ type OperationError struct {
 	Recoverable bool      `json:"recoverable"`
 }
 
+// WarningKind classifies a warning
+type WarningKind string
+
+const (
+	WarningPermissionDenied     WarningKind = "permission_denied"      // Item skipped because it could not be read
+	WarningSymlinkNotFollowed   WarningKind = "symlink_not_followed"   // Linked directory not traversed
+	WarningMetadataNotPreserved WarningKind = "metadata_not_preserved" // Content copied, permissions or times not
+	WarningInsufficientSpace    WarningKind = "insufficient_space"     // Destination may run out of space
+	WarningSecurityLabelsLost   WarningKind = "security_labels_lost"   // SELinux context or file capabilities not carried over
+)
+
+// OperationWarning is something the user should know about that did not make
+// an item fail. Unlike errors, warnings do not count against the error budget
+// and are never retried.
+type OperationWarning struct {
+	File      string      `json:"file,omitempty"`
+	Operation string      `json:"operation"`
+	Kind      WarningKind `json:"kind"`
+	Message   string      `json:"message"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// UnmarshalJSON also accepts the plain messages older results stored as warnings
+func (w *OperationWarning) UnmarshalJSON(data []byte) error {
+	var message string
+	if err := json.Unmarshal(data, &message); err == nil {
+		*w = OperationWarning{Message: message}
+		return nil
+	}
+	type plain OperationWarning
+	return json.Unmarshal(data, (*plain)(w))
+}
+
+// PathLatency is what reading one directory cost the walks of an operation:
+// listing it and getting the information of its entries
+type PathLatency struct {
+	Path    string        `json:"path"`
+	Entries int64         `json:"entries"` // Entries read, including failed ones
+	Total   time.Duration `json:"total"`   // Time spent waiting for the directory and its entries
+	Max     time.Duration `json:"max"`     // Longest single wait
+	Errors  int64         `json:"errors"`
+}
+
+// Mean returns the average wait per entry
+func (p PathLatency) Mean() time.Duration {
+	if p.Entries == 0 {
+		return 0
+	}
+	return p.Total / time.Duration(p.Entries)
+}
+
 // DuplicateGroup represents a group of duplicate files
 type DuplicateGroup struct {
 	ID          string     `json:"id"`
@@ -115,7 +232,49 @@ type DuplicateGroup struct {
 	TotalSize   int64      `json:"total_size"`
 	SaveablSize int64      `json:"saveable_size"`
 	HashType    string     `json:"hash_type"`
-	Confidence  float64    `json:"confidence"`
+	Confidence  float64    `json:"confidence"`       // How sure the files are identical, see ConfidenceLevel
+	Labels      []string   `json:"labels,omitempty"` // Labels of the roots holding a copy, e.g. laptop and nas
+}
+
+// Confidence of a duplicate group, by how its files were matched
+const (
+	ConfidenceLow     = 0.25 // Same size only
+	ConfidenceMedium  = 0.5  // Same fast hash (crc32, xxhash64)
+	ConfidenceHigh    = 0.9  // Same cryptographic hash
+	ConfidenceCertain = 1.0  // Compared byte by byte
+)
+
+// confidenceLevels names the confidences, lowest first
+var confidenceLevels = []struct {
+	name  string
+	value float64
+}{
+	{"low", ConfidenceLow},
+	{"medium", ConfidenceMedium},
+	{"high", ConfidenceHigh},
+	{"certain", ConfidenceCertain},
+}
+
+// ConfidenceLevel names a confidence: low, medium, high or certain
+func ConfidenceLevel(confidence float64) string {
+	name := "none"
+	for _, level := range confidenceLevels {
+		if confidence >= level.value {
+			name = level.name
+		}
+	}
+	return name
+}
+
+// ParseConfidenceLevel returns the confidence of a level named by
+// ConfidenceLevel
+func ParseConfidenceLevel(name string) (float64, error) {
+	for _, level := range confidenceLevels {
+		if strings.EqualFold(name, level.name) {
+			return level.value, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown confidence level %q (use low, medium, high or certain)", name)
 }
 
 // SimilarityGroup represents a group of similar files (mainly images)
@@ -147,6 +306,9 @@ type ConsolidationPlan struct {
 	TotalFiles  int                      `json:"total_files"`
 	TotalSize   int64                    `json:"total_size"`
 	Conflicts   []ConflictResolution     `json:"conflicts"`
+
+	// Conflicts between names that differ only in their Unicode normalization
+	NormalizationConflicts []ConflictResolution `json:"normalization_conflicts,omitempty"`
 }
 
 // ConsolidationOperation represents a single file operation in consolidation
@@ -167,22 +329,149 @@ type ConflictResolution struct {
 
 // OperationConfig represents configuration for an operation
 type OperationConfig struct {
-	DryRun              bool                   `json:"dry_run"`
-	Recursive           bool                   `json:"recursive"`
-	FollowSymlinks      bool                   `json:"follow_symlinks"`
-	ExcludePatterns     []string               `json:"exclude_patterns"`
-	IncludePatterns     []string               `json:"include_patterns"`
-	MaxDepth            int                    `json:"max_depth"`
-	MaxFileSize         int64                  `json:"max_file_size"`
-	MinFileSize         int64                  `json:"min_file_size"`
-	BackupBeforeDelete  bool                   `json:"backup_before_delete"`
-	BackupDirectory     string                 `json:"backup_directory"`
-	Parallelism         int                    `json:"parallelism"`
-	ChunkSize           int64                  `json:"chunk_size"`
-	HashAlgorithm       string                 `json:"hash_algorithm"`
-	SimilarityThreshold float64                `json:"similarity_threshold"`
-	Extensions          map[string]string      `json:"extensions,omitempty"`
-	CustomSettings      map[string]interface{} `json:"custom_settings,omitempty"`
+	DryRun               bool                   `json:"dry_run"`
+	Recursive            bool                   `json:"recursive"`
+	FollowSymlinks       bool                   `json:"follow_symlinks"`
+	OneFileSystem        bool                   `json:"one_file_system"`
+	ExcludePatterns      []string               `json:"exclude_patterns"`
+	TargetPaths          []string               `json:"target_paths"`                    // Roots the operation works on
+	IncludePatterns      []string               `json:"include_patterns,omitempty"`      // When set, only files whose name matches one of these are processed
+	OnlyPaths            []string               `json:"only_paths,omitempty"`            // Restrict processing to these items (used by retry)
+	RootLabels           map[string]string      `json:"root_labels,omitempty"`           // Label of each root in TargetPaths, e.g. laptop or nas
+	GitFilter            string                 `json:"git_filter,omitempty"`            // Act only on files git allows: protect, untracked or ignored
+	UnicodeNormalization string                 `json:"unicode_normalization,omitempty"` // Normalization of compared and written names: nfc (default), nfd or none
+	MTimeTolerance       TimeTolerance          `json:"mtime_tolerance,omitempty"`       // When a stored modification time still counts as unchanged
+	SecurityLabels       bool                   `json:"security_labels,omitempty"`       // Preserve SELinux contexts and file capabilities of copied files
+	DeleteSidecars       bool                   `json:"delete_sidecars,omitempty"`       // Delete the XMP, AAE, subtitle and other sidecars of deleted files with them
+	MaxDepth             int                    `json:"max_depth"`
+	MaxFileSize          int64                  `json:"max_file_size"`
+	MinFileSize          int64                  `json:"min_file_size"`
+	BackupBeforeDelete   bool                   `json:"backup_before_delete"`
+	BackupDirectory      string                 `json:"backup_directory"`
+	Parallelism          int                    `json:"parallelism"`
+	ChunkSize            int64                  `json:"chunk_size"`
+	HashAlgorithm        string                 `json:"hash_algorithm"`
+	SimilarityThreshold  float64                `json:"similarity_threshold"`
+	MaxErrors            *int                   `json:"max_errors,omitempty"`        // Abort after more than this many errors (nil = unlimited, 0 = on the first)
+	MaxErrorPercent      float64                `json:"max_error_percent,omitempty"` // Abort when errors exceed this share of processed items
+	RollbackOnAbort      bool                   `json:"rollback_on_abort,omitempty"` // Move the files the run moved back when the error budget aborts it
+	MaxDuration          time.Duration          `json:"max_duration,omitempty"`      // Pause (or without a window stop) after running this long in a window
+	Window               string                 `json:"window,omitempty"`            // Daily local time span to run in, e.g. 22:00-06:00; paused outside it
+	Deterministic        bool                   `json:"deterministic,omitempty"`     // Sorted lists, group IDs from content hashes and reports without run times, so runs over the same data report identically
+	Extensions           map[string]string      `json:"extensions,omitempty"`        // Action by file name pattern or extension, e.g. raw: skip (see ExtensionAction)
+	CustomSettings       map[string]interface{} `json:"custom_settings,omitempty"`
+}
+
+// UnmarshalJSON also accepts the configurations older results stored, whose
+// include_patterns held the target paths. Those have no target_paths key at
+// all; a null one is a configuration without target paths.
+func (c *OperationConfig) UnmarshalJSON(data []byte) error {
+	type plain OperationConfig
+	var config plain
+	if err := json.Unmarshal(data, &config); err != nil {
+		return err
+	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return err
+	}
+	*c = OperationConfig(config)
+	if _, ok := keys["target_paths"]; !ok {
+		c.TargetPaths, c.IncludePatterns = c.IncludePatterns, nil
+	}
+	return nil
+}
+
+// TimeTolerance says when two modification times count as the same. FAT and
+// exFAT store times in 2 second steps and in local time, which moves every
+// time by an hour when daylight saving time starts or ends; SMB servers often
+// round to whole seconds.
+type TimeTolerance struct {
+	Granularity time.Duration `json:"granularity,omitempty"` // Differences up to this are ignored
+	DSTSkew     bool          `json:"dst_skew,omitempty"`    // Differences of one hour (within Granularity) are ignored
+}
+
+// Equal reports whether a and b are the same time within the tolerance
+func (t TimeTolerance) Equal(a, b time.Time) bool {
+	diff := a.Sub(b).Abs()
+	if diff <= t.Granularity {
+		return true
+	}
+	return t.DSTSkew && (diff-time.Hour).Abs() <= t.Granularity
+}
+
+// After reports whether a is later than b beyond the tolerance
+func (t TimeTolerance) After(a, b time.Time) bool {
+	return a.After(b) && !t.Equal(a, b)
+}
+
+// Includes reports whether the file name of path matches one of the include
+// patterns, or whether none are set
+func (c OperationConfig) Includes(path string) bool {
+	if len(c.IncludePatterns) == 0 {
+		return true
+	}
+	name := filepath.Base(path)
+	for _, pattern := range c.IncludePatterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Per-extension actions, the values of OperationConfig.Extensions
+const (
+	ExtensionSkip   = "skip"   // Operations leave matching files alone as if excluded
+	ExtensionKeep   = "keep"   // Matching files are never deleted, replaced or moved
+	ExtensionDelete = "delete" // Cleanup removes matching files wherever it finds them
+)
+
+// ParseExtensions parses "pattern=action" entries such as raw=skip or
+// *.tar.gz=keep into a map for OperationConfig.Extensions
+func ParseExtensions(entries []string) (map[string]string, error) {
+	extensions := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		pattern, action, ok := strings.Cut(entry, "=")
+		pattern, action = strings.TrimSpace(pattern), strings.ToLower(strings.TrimSpace(action))
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("invalid extension entry %q (use pattern=action, e.g. raw=skip)", entry)
+		}
+		if action != ExtensionSkip && action != ExtensionKeep && action != ExtensionDelete {
+			return nil, fmt.Errorf("invalid action %q for %s (use %s, %s or %s)", action, pattern, ExtensionSkip, ExtensionKeep, ExtensionDelete)
+		}
+		if _, err := filepath.Match(extensionPattern(pattern), ""); err != nil {
+			return nil, fmt.Errorf("invalid extension pattern %q", pattern)
+		}
+		extensions[pattern] = action
+	}
+	return extensions, nil
+}
+
+// ExtensionAction returns the action configured for the file name of path,
+// or "" when none is. Keys are glob patterns matched against the name (*.raw)
+// or bare extensions (raw, .raw, tar.gz), compared case-insensitively; the
+// longest matching key wins.
+func (c OperationConfig) ExtensionAction(path string) string {
+	name := strings.ToLower(filepath.Base(path))
+	action, matchedKey := "", ""
+	for key, value := range c.Extensions {
+		if matched, _ := filepath.Match(strings.ToLower(extensionPattern(key)), name); !matched {
+			continue
+		}
+		if len(key) > len(matchedKey) || (len(key) == len(matchedKey) && key < matchedKey) {
+			action, matchedKey = value, key
+		}
+	}
+	return action
+}
+
+// extensionPattern turns a bare extension into a file name pattern
+func extensionPattern(key string) string {
+	if strings.ContainsAny(key, "*?[") {
+		return key
+	}
+	return "*." + strings.TrimPrefix(key, ".")
 }
 
 // Operation represents a file operation that can be executed
@@ -244,6 +533,9 @@ type FileSystem interface {
 	// Copy copies a file or directory from source to destination
 	Copy(source, destination string) error
 
+	// Link creates destination as a hard link to source
+	Link(source, destination string) error
+
 	// CreateDir creates a directory at the given path
 	CreateDir(path string) error
 
@@ -269,6 +561,7 @@ const (
 	HashSHA256   HashAlgorithm = "sha256"
 	HashSHA512   HashAlgorithm = "sha512"
 	HashBlake2b  HashAlgorithm = "blake2b"
+	HashBlake3   HashAlgorithm = "blake3"
 	HashXXHash64 HashAlgorithm = "xxhash64"
 	HashCRC32    HashAlgorithm = "crc32"
 )
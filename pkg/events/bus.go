@@ -0,0 +1,117 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/schema"
+)
+
+// Type identifies the kind of an event
+type Type string
+
+// Event types published by the engine and its operations
+const (
+	OperationStarted  Type = "operation_started"
+	OperationFinished Type = "operation_finished"
+	StepCompleted     Type = "step_completed"
+	StepStarted       Type = "step_started"
+	ProgressUpdated   Type = "progress_updated"
+	FileProcessed     Type = "file_processed"
+	FileDeleted       Type = "file_deleted"
+	FileMoved         Type = "file_moved"
+	ErrorOccurred     Type = "error_occurred"
+	WarningRaised     Type = "warning_raised"
+	BatchCommitted    Type = "batch_committed"
+)
+
+// Event is something that happened during an operation. Only the fields that
+// belong to its type are set.
+type Event struct {
+	SchemaVersion int                    `json:"schema_version"` // See package schema, set by Publish
+	Type          Type                   `json:"type"`
+	OperationID   string                 `json:"operation_id"`
+	OperationType domain.OperationType   `json:"operation_type"`
+	Time          time.Time              `json:"time"`
+	Step          string                 `json:"step,omitempty"`        // StepStarted, StepCompleted
+	TotalSteps    int                    `json:"total_steps,omitempty"` // OperationStarted
+	Path          string                 `json:"path,omitempty"`        // FileDeleted, FileMoved, ErrorOccurred, WarningRaised, FileProcessed when known
+	Target        string                 `json:"target,omitempty"`      // FileMoved: where Path went
+	Items         int64                  `json:"items,omitempty"`       // FileProcessed (increment), ProgressUpdated (absolute, -1 only sets totals), BatchCommitted (changes so far)
+	Bytes         int64                  `json:"bytes,omitempty"`       // FileProcessed (increment), FileDeleted, ProgressUpdated (absolute)
+	TotalItems    int64                  `json:"total_items,omitempty"` // ProgressUpdated
+	TotalBytes    int64                  `json:"total_bytes,omitempty"` // ProgressUpdated
+	Status        domain.OperationStatus `json:"status,omitempty"`      // OperationFinished
+	Error         string                 `json:"error,omitempty"`       // ErrorOccurred, failed OperationFinished
+	Warning       domain.WarningKind     `json:"warning,omitempty"`     // WarningRaised
+	Message       string                 `json:"message,omitempty"`     // WarningRaised
+	Duration      time.Duration          `json:"duration,omitempty"`    // StepCompleted, OperationFinished
+	Batch         int                    `json:"batch,omitempty"`       // BatchCommitted
+}
+
+// Handler receives published events. Handlers run on the publishing
+// goroutine, often an operation worker, so they must return quickly.
+type Handler func(Event)
+
+type subscription struct {
+	handler Handler
+	types   map[Type]bool // nil receives every type
+}
+
+// Bus delivers the events of operations to its subscribers
+type Bus struct {
+	mu            sync.RWMutex
+	subscriptions []*subscription // Replaced, never modified, so Publish can iterate without the lock
+}
+
+// NewBus creates an event bus without subscribers
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe calls handler for every published event of the given types, or
+// of all types when none are given. The returned function unsubscribes.
+func (b *Bus) Subscribe(handler Handler, types ...Type) func() {
+	sub := &subscription{handler: handler}
+	if len(types) > 0 {
+		sub.types = make(map[Type]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+
+	b.mu.Lock()
+	b.subscriptions = append(append([]*subscription(nil), b.subscriptions...), sub)
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		remaining := make([]*subscription, 0, len(b.subscriptions))
+		for _, other := range b.subscriptions {
+			if other != sub {
+				remaining = append(remaining, other)
+			}
+		}
+		b.subscriptions = remaining
+	}
+}
+
+// Publish delivers event to its subscribers in the order they subscribed
+func (b *Bus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	event.SchemaVersion = schema.EventVersion
+
+	b.mu.RLock()
+	subscriptions := b.subscriptions
+	b.mu.RUnlock()
+
+	for _, sub := range subscriptions {
+		if sub.types == nil || sub.types[event.Type] {
+			sub.handler(event)
+		}
+	}
+}
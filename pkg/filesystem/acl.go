@@ -0,0 +1,105 @@
+package filesystem
+
+import (
+	"fmt"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// ACLGrant is one access control entry to apply, parsed from setfacl style
+// text such as "g:designers:rwX" or "u:alice:r"
+type ACLGrant struct {
+	Group    bool
+	Name     string
+	ID       string // uid or gid, the SID on Windows
+	Read     bool
+	Write    bool
+	Execute  bool
+	Traverse bool // X: execute on directories and on files that are executable already
+}
+
+// ACLChange is the access control list of an item before and after a grant,
+// in a short textual form
+type ACLChange struct {
+	Before  string
+	After   string
+	Changed bool
+}
+
+// ParseACLGrant parses "u:name:perms" or "g:name:perms" (also "user:" and
+// "group:"), where perms combines r, w, x and X, and resolves the name
+func ParseACLGrant(spec string) (ACLGrant, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) != 3 || parts[1] == "" {
+		return ACLGrant{}, fmt.Errorf("invalid ACL entry %q, expected g:group:rwX or u:user:rwX", spec)
+	}
+
+	grant := ACLGrant{Name: parts[1]}
+	switch parts[0] {
+	case "g", "group":
+		grant.Group = true
+	case "u", "user":
+	default:
+		return ACLGrant{}, fmt.Errorf("invalid ACL entry %q: %q is neither u(ser) nor g(roup)", spec, parts[0])
+	}
+
+	for _, perm := range parts[2] {
+		switch perm {
+		case 'r':
+			grant.Read = true
+		case 'w':
+			grant.Write = true
+		case 'x':
+			grant.Execute = true
+		case 'X':
+			grant.Traverse = true
+		case '-':
+		default:
+			return ACLGrant{}, fmt.Errorf("invalid ACL entry %q: unknown permission %q", spec, perm)
+		}
+	}
+
+	if _, err := strconv.Atoi(grant.Name); err == nil {
+		grant.ID = grant.Name
+		return grant, nil
+	}
+	if grant.Group {
+		info, err := user.LookupGroup(grant.Name)
+		if err != nil {
+			return ACLGrant{}, fmt.Errorf("group not found: %s: %w", grant.Name, err)
+		}
+		grant.ID = info.Gid
+	} else {
+		info, err := user.Lookup(grant.Name)
+		if err != nil {
+			return ACLGrant{}, fmt.Errorf("user not found: %s: %w", grant.Name, err)
+		}
+		grant.ID = info.Uid
+	}
+	return grant, nil
+}
+
+// String returns the grant in the form it was parsed from
+func (g ACLGrant) String() string {
+	kind := "u"
+	if g.Group {
+		kind = "g"
+	}
+	perms := ""
+	for _, p := range []struct {
+		set  bool
+		char string
+	}{{g.Read, "r"}, {g.Write, "w"}, {g.Execute, "x"}, {g.Traverse && !g.Execute, "X"}} {
+		if p.set {
+			perms += p.char
+		}
+	}
+	return kind + ":" + g.Name + ":" + perms
+}
+
+// executes reports whether the grant gives execute permission on an item,
+// resolving X like chmod and setfacl do
+func (g ACLGrant) executes(isDir, executable bool) bool {
+	return g.Execute || (g.Traverse && (isDir || executable))
+}
@@ -0,0 +1,266 @@
+//go:build linux
+
+package filesystem
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// POSIX ACLs are stored in extended attributes (see acl(5) and the kernel's
+// posix_acl_xattr.h): a version header followed by tag, permission and id
+const (
+	aclAccessXattr  = "system.posix_acl_access"
+	aclDefaultXattr = "system.posix_acl_default"
+	aclXattrVersion = 2
+
+	aclUserObj  = 0x01
+	aclUser     = 0x02
+	aclGroupObj = 0x04
+	aclGroup    = 0x08
+	aclMask     = 0x10
+	aclOther    = 0x20
+
+	aclUndefinedID = 0xFFFFFFFF
+)
+
+// aclTagNames are the names of ACL entry tags in getfacl output
+var aclTagNames = map[uint16]string{
+	aclUserObj: "user", aclUser: "user", aclGroupObj: "group",
+	aclGroup: "group", aclMask: "mask", aclOther: "other",
+}
+
+// posixACLEntry is one entry of a POSIX ACL
+type posixACLEntry struct {
+	tag  uint16
+	perm uint16
+	id   uint32
+}
+
+// GrantACL sets the entries of grants in the POSIX ACL of path, like
+// setfacl -m. With inherit, directories also get them in their default ACL,
+// so that new files below inherit them. Symbolic links have no ACLs.
+func GrantACL(path string, mode os.FileMode, grants []ACLGrant, inherit, dryRun bool) (ACLChange, error) {
+	if mode&os.ModeSymlink != 0 {
+		return ACLChange{}, fmt.Errorf("symbolic links have no ACL")
+	}
+	isDir := mode.IsDir()
+	names := make(map[posixACLEntry]string, len(grants)) // Tag and id -> name
+
+	access, err := readPosixACL(path, aclAccessXattr, mode)
+	if err != nil {
+		return ACLChange{}, err
+	}
+	desired := append([]posixACLEntry(nil), access...)
+	for _, grant := range grants {
+		id, err := strconv.ParseUint(grant.ID, 10, 32)
+		if err != nil {
+			return ACLChange{}, fmt.Errorf("invalid id %q of %s", grant.ID, grant.Name)
+		}
+		names[posixACLEntry{tag: grantTag(grant), id: uint32(id)}] = grant.Name
+		desired = setPosixEntry(desired, grant, uint32(id), grant.executes(isDir, mode&0111 != 0))
+	}
+
+	change := ACLChange{
+		Before: formatPosixACL("", access, names),
+		After:  formatPosixACL("", desired, names),
+	}
+
+	var defaults, desiredDefaults []posixACLEntry
+	if inherit && isDir {
+		if defaults, err = readPosixACL(path, aclDefaultXattr, mode); err != nil {
+			return ACLChange{}, err
+		}
+		if len(defaults) == 0 {
+			// Like setfacl -d -m, start from the owner, group and other entries
+			for _, entry := range access {
+				if entry.tag == aclUserObj || entry.tag == aclGroupObj || entry.tag == aclOther {
+					desiredDefaults = append(desiredDefaults, entry)
+				}
+			}
+		} else {
+			desiredDefaults = append([]posixACLEntry(nil), defaults...)
+		}
+		for _, grant := range grants {
+			id, _ := strconv.ParseUint(grant.ID, 10, 32)
+			desiredDefaults = setPosixEntry(desiredDefaults, grant, uint32(id), grant.Execute || grant.Traverse)
+		}
+		change.Before = joinACLText(change.Before, formatPosixACL("default:", defaults, names))
+		change.After = joinACLText(change.After, formatPosixACL("default:", desiredDefaults, names))
+	}
+
+	change.Changed = change.Before != change.After
+	if !change.Changed || dryRun {
+		return change, nil
+	}
+
+	if err := unix.Setxattr(path, aclAccessXattr, encodePosixACL(desired), 0); err != nil {
+		return change, aclError(err)
+	}
+	if desiredDefaults != nil {
+		if err := unix.Setxattr(path, aclDefaultXattr, encodePosixACL(desiredDefaults), 0); err != nil {
+			return change, aclError(err)
+		}
+	}
+	return change, nil
+}
+
+// readPosixACL reads an ACL attribute of path. Without an access ACL the
+// permission bits of mode are the ACL; without a default ACL it is empty.
+func readPosixACL(path, attr string, mode os.FileMode) ([]posixACLEntry, error) {
+	buf := make([]byte, 256)
+	for {
+		n, err := unix.Getxattr(path, attr, buf)
+		if errors.Is(err, unix.ERANGE) {
+			buf = make([]byte, len(buf)*4)
+			continue
+		}
+		if errors.Is(err, unix.ENODATA) {
+			if attr == aclDefaultXattr {
+				return nil, nil
+			}
+			return []posixACLEntry{
+				{tag: aclUserObj, perm: uint16(mode>>6) & 7, id: aclUndefinedID},
+				{tag: aclGroupObj, perm: uint16(mode>>3) & 7, id: aclUndefinedID},
+				{tag: aclOther, perm: uint16(mode) & 7, id: aclUndefinedID},
+			}, nil
+		}
+		if err != nil {
+			return nil, aclError(err)
+		}
+		return decodePosixACL(buf[:n])
+	}
+}
+
+// setPosixEntry sets the entry of grant to exactly the granted permissions
+// and recalculates the mask, as setfacl does
+func setPosixEntry(entries []posixACLEntry, grant ACLGrant, id uint32, execute bool) []posixACLEntry {
+	tag := grantTag(grant)
+	var perm uint16
+	if grant.Read {
+		perm |= 4
+	}
+	if grant.Write {
+		perm |= 2
+	}
+	if execute {
+		perm |= 1
+	}
+
+	found := false
+	for i := range entries {
+		if entries[i].tag == tag && entries[i].id == id {
+			entries[i].perm = perm
+			found = true
+		}
+	}
+	if !found {
+		entries = append(entries, posixACLEntry{tag: tag, perm: perm, id: id})
+	}
+
+	// The mask bounds named entries and the owning group
+	var mask uint16
+	maskIndex := -1
+	for i, entry := range entries {
+		switch entry.tag {
+		case aclUser, aclGroupObj, aclGroup:
+			mask |= entry.perm
+		case aclMask:
+			maskIndex = i
+		}
+	}
+	if maskIndex >= 0 {
+		entries[maskIndex].perm = mask
+	} else {
+		entries = append(entries, posixACLEntry{tag: aclMask, perm: mask, id: aclUndefinedID})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].tag != entries[j].tag {
+			return entries[i].tag < entries[j].tag
+		}
+		return entries[i].id < entries[j].id
+	})
+	return entries
+}
+
+// grantTag returns the tag of the named entry of grant
+func grantTag(grant ACLGrant) uint16 {
+	if grant.Group {
+		return aclGroup
+	}
+	return aclUser
+}
+
+func decodePosixACL(data []byte) ([]posixACLEntry, error) {
+	if len(data) < 4 || binary.LittleEndian.Uint32(data) != aclXattrVersion || (len(data)-4)%8 != 0 {
+		return nil, fmt.Errorf("unsupported ACL format")
+	}
+	entries := make([]posixACLEntry, 0, (len(data)-4)/8)
+	for offset := 4; offset < len(data); offset += 8 {
+		entries = append(entries, posixACLEntry{
+			tag:  binary.LittleEndian.Uint16(data[offset:]),
+			perm: binary.LittleEndian.Uint16(data[offset+2:]),
+			id:   binary.LittleEndian.Uint32(data[offset+4:]),
+		})
+	}
+	return entries, nil
+}
+
+func encodePosixACL(entries []posixACLEntry) []byte {
+	data := make([]byte, 4+8*len(entries))
+	binary.LittleEndian.PutUint32(data, aclXattrVersion)
+	for i, entry := range entries {
+		offset := 4 + 8*i
+		binary.LittleEndian.PutUint16(data[offset:], entry.tag)
+		binary.LittleEndian.PutUint16(data[offset+2:], entry.perm)
+		binary.LittleEndian.PutUint32(data[offset+4:], entry.id)
+	}
+	return data
+}
+
+// formatPosixACL returns entries in the short text form of getfacl -c,
+// separated by commas
+func formatPosixACL(prefix string, entries []posixACLEntry, names map[posixACLEntry]string) string {
+	parts := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		qualifier := ""
+		if entry.tag == aclUser || entry.tag == aclGroup {
+			qualifier = strconv.FormatUint(uint64(entry.id), 10)
+			if name, ok := names[posixACLEntry{tag: entry.tag, id: entry.id}]; ok {
+				qualifier = name
+			}
+		}
+		kind := aclTagNames[entry.tag]
+		perms := []byte("---")
+		for bit, char := range "rwx" {
+			if entry.perm&(4>>bit) != 0 {
+				perms[bit] = byte(char)
+			}
+		}
+		parts = append(parts, prefix+kind+":"+qualifier+":"+string(perms))
+	}
+	return strings.Join(parts, ",")
+}
+
+func joinACLText(access, defaults string) string {
+	if defaults == "" {
+		return access
+	}
+	return access + "," + defaults
+}
+
+// aclError explains failures of filesystems mounted without ACL support
+func aclError(err error) error {
+	if errors.Is(err, unix.EOPNOTSUPP) {
+		return fmt.Errorf("%w: the filesystem does not support POSIX ACLs (mount option acl)", err)
+	}
+	return err
+}
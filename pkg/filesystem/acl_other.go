@@ -0,0 +1,14 @@
+//go:build !linux && !windows
+
+package filesystem
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// GrantACL is not supported on this platform
+func GrantACL(path string, mode os.FileMode, grants []ACLGrant, inherit, dryRun bool) (ACLChange, error) {
+	return ACLChange{}, fmt.Errorf("ACLs are not supported on %s", runtime.GOOS)
+}
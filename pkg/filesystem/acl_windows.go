@@ -0,0 +1,98 @@
+//go:build windows
+
+package filesystem
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// GrantACL sets allow entries of grants in the DACL of path, replacing the
+// entries the same accounts had. With inherit, entries on directories are
+// inherited by the files and folders created below them. Before and after
+// are the DACL in SDDL form.
+func GrantACL(path string, mode os.FileMode, grants []ACLGrant, inherit, dryRun bool) (ACLChange, error) {
+	if mode&os.ModeSymlink != 0 {
+		return ACLChange{}, fmt.Errorf("symbolic links have no ACL")
+	}
+	isDir := mode.IsDir()
+
+	sd, err := windows.GetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return ACLChange{}, err
+	}
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		return ACLChange{}, err
+	}
+
+	entries := make([]windows.EXPLICIT_ACCESS, 0, len(grants))
+	for _, grant := range grants {
+		sid, err := windows.StringToSid(grant.ID)
+		if err != nil {
+			return ACLChange{}, fmt.Errorf("invalid SID %q of %s: %w", grant.ID, grant.Name, err)
+		}
+		var access windows.ACCESS_MASK
+		if grant.Read {
+			access |= windows.FILE_GENERIC_READ
+		}
+		if grant.Write {
+			access |= windows.FILE_GENERIC_WRITE
+		}
+		// Windows has no execute bits, so X only grants traversing directories
+		if grant.executes(isDir, false) {
+			access |= windows.FILE_GENERIC_EXECUTE
+		}
+		inheritance := uint32(windows.NO_INHERITANCE)
+		if inherit && isDir {
+			inheritance = windows.SUB_CONTAINERS_AND_OBJECTS_INHERIT
+		}
+		trusteeType := windows.TRUSTEE_TYPE(windows.TRUSTEE_IS_USER)
+		if grant.Group {
+			trusteeType = windows.TRUSTEE_IS_GROUP
+		}
+		entries = append(entries, windows.EXPLICIT_ACCESS{
+			AccessPermissions: access,
+			AccessMode:        windows.SET_ACCESS,
+			Inheritance:       inheritance,
+			Trustee: windows.TRUSTEE{
+				TrusteeForm:  windows.TRUSTEE_IS_SID,
+				TrusteeType:  trusteeType,
+				TrusteeValue: windows.TrusteeValueFromSID(sid),
+			},
+		})
+	}
+	desired, err := windows.ACLFromEntries(entries, dacl)
+	if err != nil {
+		return ACLChange{}, err
+	}
+
+	var change ACLChange
+	if change.Before, err = daclText(dacl); err != nil {
+		return ACLChange{}, err
+	}
+	if change.After, err = daclText(desired); err != nil {
+		return ACLChange{}, err
+	}
+	change.Changed = change.Before != change.After
+	if !change.Changed || dryRun {
+		return change, nil
+	}
+
+	err = windows.SetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, windows.DACL_SECURITY_INFORMATION, nil, nil, desired, nil)
+	return change, err
+}
+
+// daclText returns acl in SDDL form
+func daclText(acl *windows.ACL) (string, error) {
+	sd, err := windows.NewSecurityDescriptor()
+	if err != nil {
+		return "", err
+	}
+	if err := sd.SetDACL(acl, true, false); err != nil {
+		return "", err
+	}
+	return sd.String(), nil
+}
@@ -0,0 +1,7 @@
+package filesystem
+
+// AllocatedSize returns the number of bytes a file occupies on disk, which is
+// less than its size for sparse files whose holes were never written
+func AllocatedSize(path string) (int64, error) {
+	return allocatedSize(path)
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package filesystem
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// allocatedSize is not supported on this platform
+func allocatedSize(path string) (int64, error) {
+	return 0, fmt.Errorf("allocation size not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,14 @@
+//go:build unix
+
+package filesystem
+
+import "syscall"
+
+// allocatedSize returns st_blocks in bytes without following a final symlink
+func allocatedSize(path string) (int64, error) {
+	var stat syscall.Stat_t
+	if err := syscall.Lstat(path, &stat); err != nil {
+		return 0, err
+	}
+	return int64(stat.Blocks) * 512, nil
+}
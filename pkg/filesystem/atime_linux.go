@@ -0,0 +1,18 @@
+//go:build linux
+
+package filesystem
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// AccessTime returns when info's file was last read, if the platform records it
+func AccessTime(info os.FileInfo) (time.Time, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(stat.Atim.Sec), int64(stat.Atim.Nsec)), true
+}
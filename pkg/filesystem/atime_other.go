@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !windows
+
+package filesystem
+
+import (
+	"os"
+	"time"
+)
+
+// AccessTime is not available on this platform
+func AccessTime(info os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package filesystem
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// AccessTime returns when info's file was last read, if the platform records it
+func AccessTime(info os.FileInfo) (time.Time, bool) {
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(0, data.LastAccessTime.Nanoseconds()), true
+}
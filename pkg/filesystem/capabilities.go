@@ -0,0 +1,66 @@
+package filesystem
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Capabilities summarizes which advanced features the filesystem holding a directory supports
+type Capabilities struct {
+	Path     string   `json:"path"`
+	Writable bool     `json:"writable"`
+	Hardlink bool     `json:"hardlink"`
+	Symlink  bool     `json:"symlink"`
+	Reflink  bool     `json:"reflink"`
+	Xattr    bool     `json:"xattr"`
+	Notes    []string `json:"notes,omitempty"`
+}
+
+// ProbeCapabilities detects filesystem features by exercising them on scratch
+// files inside dir. Nothing outside a temporary probe directory is touched.
+func ProbeCapabilities(dir string) (*Capabilities, error) {
+	caps := &Capabilities{Path: dir}
+
+	probeDir, err := os.MkdirTemp(dir, ".fileops-probe-")
+	if err != nil {
+		caps.Notes = append(caps.Notes, fmt.Sprintf("directory is not writable: %v", err))
+		return caps, nil
+	}
+	defer os.RemoveAll(probeDir)
+	caps.Writable = true
+
+	source := filepath.Join(probeDir, "source")
+	if err := os.WriteFile(source, []byte("fileops capability probe"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to create probe file: %w", err)
+	}
+
+	if err := os.Link(source, filepath.Join(probeDir, "hardlink")); err == nil {
+		caps.Hardlink = true
+	} else {
+		caps.Notes = append(caps.Notes, fmt.Sprintf("hardlinks unavailable: %v", err))
+	}
+
+	if err := os.Symlink(source, filepath.Join(probeDir, "symlink")); err == nil {
+		caps.Symlink = true
+	} else if runtime.GOOS == "windows" {
+		caps.Notes = append(caps.Notes, "symlinks require Developer Mode or the SeCreateSymbolicLinkPrivilege (run as administrator)")
+	} else {
+		caps.Notes = append(caps.Notes, fmt.Sprintf("symlinks unavailable: %v", err))
+	}
+
+	if err := CloneFile(source, filepath.Join(probeDir, "reflink")); err == nil {
+		caps.Reflink = true
+	} else {
+		caps.Notes = append(caps.Notes, fmt.Sprintf("reflinks unavailable: %v", err))
+	}
+
+	if err := setProbeXattr(source); err == nil {
+		caps.Xattr = true
+	} else {
+		caps.Notes = append(caps.Notes, fmt.Sprintf("extended attributes unavailable: %v", err))
+	}
+
+	return caps, nil
+}
@@ -0,0 +1,15 @@
+//go:build darwin
+
+package filesystem
+
+import "golang.org/x/sys/unix"
+
+// CloneFile creates destination as a copy-on-write clone of source (APFS clonefile)
+func CloneFile(source, destination string) error {
+	return unix.Clonefile(source, destination, unix.CLONE_NOFOLLOW)
+}
+
+// setProbeXattr writes a throwaway extended attribute
+func setProbeXattr(path string) error {
+	return unix.Setxattr(path, "com.fileops.probe", []byte("1"), 0)
+}
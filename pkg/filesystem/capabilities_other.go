@@ -0,0 +1,18 @@
+//go:build !linux && !darwin
+
+package filesystem
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// CloneFile is not supported on this platform
+func CloneFile(source, destination string) error {
+	return fmt.Errorf("reflink cloning not supported on %s", runtime.GOOS)
+}
+
+// setProbeXattr is not supported on this platform
+func setProbeXattr(path string) error {
+	return fmt.Errorf("extended attributes not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,135 @@
+package filesystem
+
+import (
+	"sync"
+	"time"
+)
+
+// Bounds and pacing of the chunk size tuning
+const (
+	minChunkSize = 64 * 1024
+
+	// Reads that filled a whole chunk, measured before the size is changed
+	tuneWindow = 16
+
+	// A size change is kept when throughput improved by this factor
+	tuneGain = 1.05
+
+	// Reads slower than this are latency bound (network mounts), where fewer,
+	// larger requests pay off even without a measurable throughput gain
+	highReadLatency = 20 * time.Millisecond
+)
+
+// ChunkTuner picks the read buffer size for each file. Small files get a
+// buffer that just fits them; larger files get the tuned size, which moves
+// between 64KB and the configured maximum towards the best observed
+// throughput. Buffers in use never exceed the memory budget.
+type ChunkTuner struct {
+	mu        sync.Mutex
+	maxSize   int64
+	budget    int64 // 0 = unlimited
+	inUse     int64
+	size      int64
+	direction int64 // 1 = grow, -1 = shrink
+	reads     int
+	bytes     int64
+	elapsed   time.Duration
+	previous  float64 // Throughput at the size before the current one
+	pools     map[int64]*sync.Pool
+}
+
+// NewChunkTuner creates a tuner that never reads more than maxSize at once.
+// Tuning starts at 1MB, growing.
+func NewChunkTuner(maxSize int64) *ChunkTuner {
+	if maxSize < minChunkSize {
+		maxSize = minChunkSize
+	}
+	return &ChunkTuner{
+		maxSize:   maxSize,
+		size:      min(1024*1024, maxSize),
+		direction: 1,
+		pools:     make(map[int64]*sync.Pool),
+	}
+}
+
+// SetMemoryBudget bounds the bytes of all buffers handed out at the same time
+func (t *ChunkTuner) SetMemoryBudget(bytes int64) {
+	t.mu.Lock()
+	t.budget = bytes
+	t.mu.Unlock()
+}
+
+// Size returns the current tuned chunk size
+func (t *ChunkTuner) Size() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.size
+}
+
+// Acquire returns a buffer for reading a file of fileSize bytes (-1 when
+// unknown). Return it with Release.
+func (t *ChunkTuner) Acquire(fileSize int64) []byte {
+	t.mu.Lock()
+	size := t.size
+	if fileSize >= 0 && fileSize < size {
+		size = minChunkSize
+		for size < fileSize {
+			size *= 2
+		}
+	}
+	for t.budget > 0 && size > minChunkSize && t.inUse+size > t.budget {
+		size /= 2
+	}
+	t.inUse += size
+	pool, ok := t.pools[size]
+	if !ok {
+		pool = &sync.Pool{New: func() interface{} { return make([]byte, size) }}
+		t.pools[size] = pool
+	}
+	t.mu.Unlock()
+
+	return pool.Get().([]byte)
+}
+
+// Release returns a buffer from Acquire together with how it was used: the
+// number of reads that filled it, the bytes they returned and how long they
+// took. Only reads that filled the buffer say something about the chunk size.
+func (t *ChunkTuner) Release(buffer []byte, fullReads int, bytes int64, elapsed time.Duration) {
+	size := int64(len(buffer))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.inUse -= size
+	t.pools[size].Put(buffer)
+
+	if size != t.size || fullReads == 0 {
+		return
+	}
+	t.reads += fullReads
+	t.bytes += bytes
+	t.elapsed += elapsed
+	if t.reads < tuneWindow || t.elapsed <= 0 {
+		return
+	}
+
+	throughput := float64(t.bytes) / t.elapsed.Seconds()
+	latency := t.elapsed / time.Duration(t.reads)
+	switch {
+	case latency > highReadLatency:
+		t.direction = 1
+	case t.previous > 0 && throughput < t.previous*tuneGain:
+		t.direction = -t.direction
+	}
+	t.previous = throughput
+	t.reads, t.bytes, t.elapsed = 0, 0, 0
+
+	next := t.size * 2
+	if t.direction < 0 {
+		next = t.size / 2
+	}
+	if next < minChunkSize || next > t.maxSize {
+		t.direction = -t.direction
+		return
+	}
+	t.size = next
+}
@@ -0,0 +1,7 @@
+package filesystem
+
+// IsCrossDevice reports whether err is a rename that failed because source
+// and destination are on different filesystems, so only a copy moves the file
+func IsCrossDevice(err error) bool {
+	return isCrossDevice(err)
+}
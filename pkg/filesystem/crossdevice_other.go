@@ -0,0 +1,8 @@
+//go:build !unix && !windows
+
+package filesystem
+
+// isCrossDevice is not known on this platform; renames fail as they are
+func isCrossDevice(err error) bool {
+	return false
+}
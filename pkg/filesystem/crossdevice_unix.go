@@ -0,0 +1,12 @@
+//go:build unix
+
+package filesystem
+
+import (
+	"errors"
+	"syscall"
+)
+
+func isCrossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
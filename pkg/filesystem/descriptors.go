@@ -0,0 +1,148 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// Descriptors used when the platform has no per-process limit
+const unlimitedDescriptors = 8192
+
+// DescriptorBudget limits how many files the workers of all operations hold
+// open at once, so that parallel hashing and copying wait for a descriptor
+// instead of failing with "too many open files"
+type DescriptorBudget struct {
+	tokens chan struct{}
+	multi  sync.Mutex // Held while taking several, so two takers cannot starve each other
+	soft   uint64     // Detected ulimit -n, 0 when not applicable
+	hard   uint64
+}
+
+// NewDescriptorBudget creates a budget of limit descriptors
+func NewDescriptorBudget(limit int) *DescriptorBudget {
+	if limit < 1 {
+		limit = 1
+	}
+	return &DescriptorBudget{tokens: make(chan struct{}, limit)}
+}
+
+var (
+	descriptors     *DescriptorBudget
+	descriptorsOnce sync.Once
+)
+
+// Descriptors returns the budget shared by the whole process. It is sized
+// from the open file limit, less a reserve for logs, locks, the results
+// repository and the standard streams.
+func Descriptors() *DescriptorBudget {
+	descriptorsOnce.Do(func() {
+		soft, hard, err := MaxOpenFiles()
+		if err != nil || soft == 0 || soft > unlimitedDescriptors*8 {
+			descriptors = NewDescriptorBudget(unlimitedDescriptors)
+			descriptors.soft, descriptors.hard = soft, hard
+			return
+		}
+		reserve := min(soft/4, 64)
+		descriptors = NewDescriptorBudget(int(soft - reserve))
+		descriptors.soft, descriptors.hard = soft, hard
+	})
+	return descriptors
+}
+
+// Limit returns the number of descriptors in the budget
+func (b *DescriptorBudget) Limit() int {
+	return cap(b.tokens)
+}
+
+// InUse returns the number of descriptors currently taken
+func (b *DescriptorBudget) InUse() int {
+	return len(b.tokens)
+}
+
+// Acquire takes n descriptors, waiting until they are free or ctx is done
+func (b *DescriptorBudget) Acquire(ctx context.Context, n int) error {
+	n = min(n, b.Limit())
+	if n > 1 {
+		b.multi.Lock()
+		defer b.multi.Unlock()
+	}
+	for i := 0; i < n; i++ {
+		select {
+		case b.tokens <- struct{}{}:
+		case <-ctx.Done():
+			b.Release(i)
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Release returns n descriptors taken with Acquire
+func (b *DescriptorBudget) Release(n int) {
+	n = min(n, b.Limit())
+	for i := 0; i < n; i++ {
+		<-b.tokens
+	}
+}
+
+// ErrTooManyOpenFiles marks failures caused by running out of file descriptors
+var ErrTooManyOpenFiles = errors.New("too many open files")
+
+// descriptorError explains how to get around running out of descriptors
+type descriptorError struct {
+	err error
+}
+
+func (e *descriptorError) Error() string {
+	budget := Descriptors()
+	if budget.soft == 0 {
+		return fmt.Sprintf("%v: out of file handles, lower --parallelism", e.err)
+	}
+	advice := "lower --parallelism"
+	if budget.hard > budget.soft {
+		advice += fmt.Sprintf(" or raise the limit, e.g. 'ulimit -n %d'", budget.hard)
+	}
+	return fmt.Sprintf("%v: out of file descriptors (ulimit -n is %d), %s", e.err, budget.soft, advice)
+}
+
+func (e *descriptorError) Unwrap() []error {
+	return []error{ErrTooManyOpenFiles, e.err}
+}
+
+// DescriptorError adds remediation advice to errors caused by running out of
+// file descriptors (EMFILE, ENFILE). Other errors are returned unchanged.
+func DescriptorError(err error) error {
+	if err == nil || errors.Is(err, ErrTooManyOpenFiles) {
+		return err
+	}
+	if errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE) {
+		return &descriptorError{err: err}
+	}
+	return err
+}
+
+// OpenFile opens a file like os.OpenFile, waiting for a descriptor from the
+// shared budget first. Close it with CloseFile.
+func OpenFile(ctx context.Context, path string, flag int, perm os.FileMode) (*os.File, error) {
+	if err := Descriptors().Acquire(ctx, 1); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, flag, perm)
+	if err != nil {
+		Descriptors().Release(1)
+		return nil, DescriptorError(err)
+	}
+	return file, nil
+}
+
+// CloseFile closes a file opened with OpenFile and returns its descriptor to
+// the shared budget
+func CloseFile(file *os.File) error {
+	err := file.Close()
+	Descriptors().Release(1)
+	return err
+}
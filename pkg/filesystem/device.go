@@ -0,0 +1,8 @@
+package filesystem
+
+// DeviceID returns an identifier of the filesystem/volume holding path.
+// Two paths on the same mounted filesystem return the same value, which is
+// what one-file-system traversal compares against.
+func DeviceID(path string) (uint64, error) {
+	return deviceID(path)
+}
@@ -0,0 +1,13 @@
+//go:build !unix && !windows
+
+package filesystem
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// deviceID is not supported on this platform
+func deviceID(path string) (uint64, error) {
+	return 0, fmt.Errorf("device identification not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,14 @@
+//go:build unix
+
+package filesystem
+
+import "syscall"
+
+// deviceID returns st_dev for path without following a final symlink
+func deviceID(path string) (uint64, error) {
+	var stat syscall.Stat_t
+	if err := syscall.Lstat(path, &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.Dev), nil
+}
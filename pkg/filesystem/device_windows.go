@@ -0,0 +1,29 @@
+//go:build windows
+
+package filesystem
+
+import "golang.org/x/sys/windows"
+
+// deviceID returns the volume serial number of the volume holding path
+func deviceID(path string) (uint64, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	// FILE_FLAG_BACKUP_SEMANTICS is required to open directories
+	handle, err := windows.CreateFile(pathPtr, 0,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		nil, windows.OPEN_EXISTING,
+		windows.FILE_FLAG_BACKUP_SEMANTICS|windows.FILE_FLAG_OPEN_REPARSE_POINT, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer windows.CloseHandle(handle)
+
+	var info windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(handle, &info); err != nil {
+		return 0, err
+	}
+	return uint64(info.VolumeSerialNumber), nil
+}
@@ -0,0 +1,51 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// DiskSpace describes the capacity of the volume holding a path
+type DiskSpace struct {
+	Path      string `json:"path"`
+	Total     uint64 `json:"total"`
+	Free      uint64 `json:"free"`
+	Available uint64 `json:"available"` // Free space usable by the current (unprivileged) user
+}
+
+// GetDiskSpace returns capacity information for the volume holding path.
+// If path does not exist yet, the nearest existing parent directory is used,
+// which makes it suitable for destinations that will be created later.
+func GetDiskSpace(path string) (*DiskSpace, error) {
+	probe, err := NearestExisting(path)
+	if err != nil {
+		return nil, err
+	}
+
+	space, err := diskSpace(probe)
+	if err != nil {
+		return nil, err
+	}
+	space.Path = probe
+	return space, nil
+}
+
+// NearestExisting returns path made absolute, or its nearest parent directory
+// that exists
+func NearestExisting(path string) (string, error) {
+	probe, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		if _, err := os.Stat(probe); err == nil {
+			return probe, nil
+		}
+		parent := filepath.Dir(probe)
+		if parent == probe {
+			return probe, nil
+		}
+		probe = parent
+	}
+}
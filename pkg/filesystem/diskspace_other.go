@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !windows
+
+package filesystem
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// diskSpace is not supported on this platform
+func diskSpace(path string) (*DiskSpace, error) {
+	return nil, fmt.Errorf("disk space query not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package filesystem
+
+import "golang.org/x/sys/windows"
+
+// diskSpace queries volume capacity using GetDiskFreeSpaceEx
+func diskSpace(path string) (*DiskSpace, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var available, total, free uint64
+	if err := windows.GetDiskFreeSpaceEx(pathPtr, &available, &total, &free); err != nil {
+		return nil, err
+	}
+
+	return &DiskSpace{
+		Total:     total,
+		Free:      free,
+		Available: available,
+	}, nil
+}
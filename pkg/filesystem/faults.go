@@ -0,0 +1,259 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// ErrInjectedFault is the underlying error of every failure produced by FaultInjectingFileSystem
+var ErrInjectedFault = errors.New("injected fault")
+
+// FaultConfig configures which faults a FaultInjectingFileSystem produces
+type FaultConfig struct {
+	ErrorRate     float64       // Probability (0.0-1.0) that a call fails
+	ShortReadRate float64       // Probability (0.0-1.0) that a read-heavy call ends early
+	Latency       time.Duration // Delay added before every call
+	Jitter        time.Duration // Random extra delay in [0, Jitter)
+	Seed          int64         // Random seed; 0 uses the current time
+	Operations    []string      // Restrict faults to these methods (e.g. "remove", "copy"); empty = all
+}
+
+// ParseFaultSpec parses a comma-separated fault specification such as
+// "error=0.05,short=0.01,latency=10ms,jitter=5ms,seed=42,ops=copy+remove"
+func ParseFaultSpec(spec string) (FaultConfig, error) {
+	var config FaultConfig
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return config, fmt.Errorf("invalid fault setting %q (expected key=value)", part)
+		}
+
+		var err error
+		switch strings.ToLower(key) {
+		case "error":
+			config.ErrorRate, err = strconv.ParseFloat(value, 64)
+		case "short":
+			config.ShortReadRate, err = strconv.ParseFloat(value, 64)
+		case "latency":
+			config.Latency, err = time.ParseDuration(value)
+		case "jitter":
+			config.Jitter, err = time.ParseDuration(value)
+		case "seed":
+			config.Seed, err = strconv.ParseInt(value, 10, 64)
+		case "ops":
+			config.Operations = strings.Split(strings.ToLower(value), "+")
+		default:
+			return config, fmt.Errorf("unknown fault setting %q", key)
+		}
+		if err != nil {
+			return config, fmt.Errorf("invalid value for %s: %w", key, err)
+		}
+	}
+
+	if config.ErrorRate < 0 || config.ErrorRate > 1 || config.ShortReadRate < 0 || config.ShortReadRate > 1 {
+		return config, fmt.Errorf("fault rates must be between 0.0 and 1.0")
+	}
+
+	return config, nil
+}
+
+// FaultInjectingFileSystem decorates a FileSystem with random failures, latency and
+// short reads so error handling, retry and rollback paths can be exercised
+type FaultInjectingFileSystem struct {
+	inner    domain.FileSystem
+	config   FaultConfig
+	random   *rand.Rand
+	mu       sync.Mutex
+	injected int64
+}
+
+// NewFaultInjectingFileSystem wraps inner with the given fault configuration
+func NewFaultInjectingFileSystem(inner domain.FileSystem, config FaultConfig) *FaultInjectingFileSystem {
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &FaultInjectingFileSystem{
+		inner:  inner,
+		config: config,
+		random: rand.New(rand.NewSource(seed)),
+	}
+}
+
+// InjectedFaults returns the number of faults injected so far
+func (f *FaultInjectingFileSystem) InjectedFaults() int64 {
+	return atomic.LoadInt64(&f.injected)
+}
+
+// roll returns true with the given probability
+func (f *FaultInjectingFileSystem) roll(probability float64) bool {
+	if probability <= 0 {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.random.Float64() < probability
+}
+
+// delay sleeps for the configured latency plus jitter
+func (f *FaultInjectingFileSystem) delay() {
+	latency := f.config.Latency
+	if f.config.Jitter > 0 {
+		f.mu.Lock()
+		latency += time.Duration(f.random.Int63n(int64(f.config.Jitter)))
+		f.mu.Unlock()
+	}
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+}
+
+// targeted reports whether faults apply to the given method
+func (f *FaultInjectingFileSystem) targeted(op string) bool {
+	if len(f.config.Operations) == 0 {
+		return true
+	}
+	for _, candidate := range f.config.Operations {
+		if candidate == op {
+			return true
+		}
+	}
+	return false
+}
+
+// fault applies latency and possibly returns an injected error for op on path
+func (f *FaultInjectingFileSystem) fault(op, path string) error {
+	f.delay()
+	if f.targeted(op) && f.roll(f.config.ErrorRate) {
+		atomic.AddInt64(&f.injected, 1)
+		return &os.PathError{Op: op, Path: path, Err: ErrInjectedFault}
+	}
+	return nil
+}
+
+// shortRead possibly returns an unexpected EOF for read-heavy calls
+func (f *FaultInjectingFileSystem) shortRead(op, path string) error {
+	if f.targeted(op) && f.roll(f.config.ShortReadRate) {
+		atomic.AddInt64(&f.injected, 1)
+		return &os.PathError{Op: op, Path: path, Err: fmt.Errorf("%w: %w", ErrInjectedFault, io.ErrUnexpectedEOF)}
+	}
+	return nil
+}
+
+// Walk traverses the file system, reporting injected errors for random entries
+func (f *FaultInjectingFileSystem) Walk(ctx context.Context, path string, fn domain.WalkFunc) error {
+	return f.inner.Walk(ctx, path, func(filePath string, info *domain.FileInfo, err error) error {
+		if err == nil {
+			if faultErr := f.fault("walk", filePath); faultErr != nil {
+				return fn(filePath, nil, faultErr)
+			}
+		}
+		return fn(filePath, info, err)
+	})
+}
+
+// Stat returns file information for the given path
+func (f *FaultInjectingFileSystem) Stat(path string) (*domain.FileInfo, error) {
+	if err := f.fault("stat", path); err != nil {
+		return nil, err
+	}
+	return f.inner.Stat(path)
+}
+
+// Remove removes the file or directory at the given path
+func (f *FaultInjectingFileSystem) Remove(path string) error {
+	if err := f.fault("remove", path); err != nil {
+		return err
+	}
+	return f.inner.Remove(path)
+}
+
+// RemoveAll removes the directory and all its contents
+func (f *FaultInjectingFileSystem) RemoveAll(path string) error {
+	if err := f.fault("removeall", path); err != nil {
+		return err
+	}
+	return f.inner.RemoveAll(path)
+}
+
+// Move moves a file or directory from source to destination
+func (f *FaultInjectingFileSystem) Move(source, destination string) error {
+	if err := f.fault("move", source); err != nil {
+		return err
+	}
+	return f.inner.Move(source, destination)
+}
+
+// Copy copies a file or directory; a short read leaves a truncated destination behind
+func (f *FaultInjectingFileSystem) Copy(source, destination string) error {
+	if err := f.fault("copy", source); err != nil {
+		return err
+	}
+	if err := f.inner.Copy(source, destination); err != nil {
+		return err
+	}
+	if err := f.shortRead("copy", source); err != nil {
+		if info, statErr := os.Stat(destination); statErr == nil && !info.IsDir() {
+			_ = os.Truncate(destination, info.Size()/2)
+		}
+		return err
+	}
+	return nil
+}
+
+// Link creates destination as a hard link to source
+func (f *FaultInjectingFileSystem) Link(source, destination string) error {
+	if err := f.fault("link", source); err != nil {
+		return err
+	}
+	return f.inner.Link(source, destination)
+}
+
+// CreateDir creates a directory at the given path
+func (f *FaultInjectingFileSystem) CreateDir(path string) error {
+	if err := f.fault("createdir", path); err != nil {
+		return err
+	}
+	return f.inner.CreateDir(path)
+}
+
+// IsEmpty checks if a directory is empty
+func (f *FaultInjectingFileSystem) IsEmpty(path string) (bool, error) {
+	if err := f.fault("isempty", path); err != nil {
+		return false, err
+	}
+	return f.inner.IsEmpty(path)
+}
+
+// Exists checks if a file or directory exists (never fails, only delayed)
+func (f *FaultInjectingFileSystem) Exists(path string) bool {
+	f.delay()
+	return f.inner.Exists(path)
+}
+
+// ComputeHash computes the hash of a file
+func (f *FaultInjectingFileSystem) ComputeHash(path string, algorithm string) (string, error) {
+	if err := f.fault("hash", path); err != nil {
+		return "", err
+	}
+	if err := f.shortRead("hash", path); err != nil {
+		return "", err
+	}
+	return f.inner.ComputeHash(path, algorithm)
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
+	"errors"
 	"fmt"
 	"hash"
 	"hash/crc32"
@@ -18,23 +19,41 @@ import (
 	"github.com/a4abhishek/fileops/pkg/domain"
 	"github.com/cespare/xxhash/v2"
 	"golang.org/x/crypto/blake2b"
+	"lukechampine.com/blake3"
 )
 
+// ErrMetadataNotPreserved is returned by Copy when the content was copied but
+// the permissions of the source could not be applied to the copy
+var ErrMetadataNotPreserved = errors.New("metadata not preserved")
+
 // OSFileSystem implements the FileSystem interface using the operating system
 type OSFileSystem struct {
-	chunkSize int64
+	chunks           *ChunkTuner
+	preserveSecurity bool
 }
 
-// NewOSFileSystem creates a new OS-based file system implementation
+// NewOSFileSystem creates a new OS-based file system implementation. Files
+// are read in chunks of at most chunkSize bytes, tuned per file.
 func NewOSFileSystem(chunkSize int64) *OSFileSystem {
 	if chunkSize <= 0 {
 		chunkSize = 64 * 1024 * 1024 // 64MB default
 	}
 	return &OSFileSystem{
-		chunkSize: chunkSize,
+		chunks: NewChunkTuner(chunkSize),
 	}
 }
 
+// SetMemoryBudget bounds the memory taken by read buffers at the same time
+func (fs *OSFileSystem) SetMemoryBudget(bytes int64) {
+	fs.chunks.SetMemoryBudget(bytes)
+}
+
+// SetPreserveSecurityLabels makes copies keep the SELinux context and file
+// capabilities of their source
+func (fs *OSFileSystem) SetPreserveSecurityLabels(preserve bool) {
+	fs.preserveSecurity = preserve
+}
+
 // Walk traverses the file system starting from the given path
 func (fs *OSFileSystem) Walk(ctx context.Context, path string, fn domain.WalkFunc) error {
 	return filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
@@ -47,14 +66,7 @@ func (fs *OSFileSystem) Walk(ctx context.Context, path string, fn domain.WalkFun
 
 		var fileInfo *domain.FileInfo
 		if info != nil {
-			fileInfo = &domain.FileInfo{
-				Path:    filePath,
-				Name:    info.Name(),
-				Size:    info.Size(),
-				ModTime: info.ModTime(),
-				IsDir:   info.IsDir(),
-				Mode:    uint32(info.Mode()),
-			}
+			fileInfo = newFileInfo(filePath, info)
 		}
 
 		return fn(filePath, fileInfo, err)
@@ -68,14 +80,21 @@ func (fs *OSFileSystem) Stat(path string) (*domain.FileInfo, error) {
 		return nil, err
 	}
 
-	return &domain.FileInfo{
+	return newFileInfo(path, info), nil
+}
+
+// newFileInfo converts an os.FileInfo into the domain representation
+func newFileInfo(path string, info os.FileInfo) *domain.FileInfo {
+	fileInfo := &domain.FileInfo{
 		Path:    path,
 		Name:    info.Name(),
 		Size:    info.Size(),
 		ModTime: info.ModTime(),
 		IsDir:   info.IsDir(),
 		Mode:    uint32(info.Mode()),
-	}, nil
+	}
+	fillSysInfo(fileInfo, info)
+	return fileInfo
 }
 
 // Remove removes the file or directory at the given path
@@ -93,6 +112,11 @@ func (fs *OSFileSystem) Move(source, destination string) error {
 	return os.Rename(source, destination)
 }
 
+// Link creates destination as a hard link to source
+func (fs *OSFileSystem) Link(source, destination string) error {
+	return os.Link(source, destination)
+}
+
 // Copy copies a file or directory from source to destination
 func (fs *OSFileSystem) Copy(source, destination string) error {
 	sourceInfo, err := os.Stat(source)
@@ -108,9 +132,14 @@ func (fs *OSFileSystem) Copy(source, destination string) error {
 
 // copyFile copies a single file
 func (fs *OSFileSystem) copyFile(source, destination string) error {
+	if err := Descriptors().Acquire(context.Background(), 2); err != nil {
+		return err
+	}
+	defer Descriptors().Release(2)
+
 	sourceFile, err := os.Open(source)
 	if err != nil {
-		return err
+		return DescriptorError(err)
 	}
 	defer sourceFile.Close()
 
@@ -122,7 +151,7 @@ func (fs *OSFileSystem) copyFile(source, destination string) error {
 
 	destFile, err := os.Create(destination)
 	if err != nil {
-		return err
+		return DescriptorError(err)
 	}
 	defer destFile.Close()
 
@@ -138,7 +167,21 @@ func (fs *OSFileSystem) copyFile(source, destination string) error {
 		return err
 	}
 
-	return os.Chmod(destination, sourceInfo.Mode())
+	if err := os.Chmod(destination, sourceInfo.Mode()); err != nil {
+		return fmt.Errorf("%w: %v", ErrMetadataNotPreserved, err)
+	}
+
+	// Writing the content clears file capabilities, so labels come last
+	if fs.preserveSecurity {
+		labels, err := ReadSecurityLabels(source)
+		if err == nil {
+			err = ApplySecurityLabels(destination, labels)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrSecurityLabelsNotPreserved, err)
+		}
+	}
+	return nil
 }
 
 // copyDir copies a directory and all its contents
@@ -158,22 +201,28 @@ func (fs *OSFileSystem) copyDir(source, destination string) error {
 		return err
 	}
 
+	// Copying goes on when only metadata is lost; the first such error is returned
+	var metadataErr error
 	for _, entry := range entries {
 		sourcePath := filepath.Join(source, entry.Name())
 		destPath := filepath.Join(destination, entry.Name())
 
+		var err error
 		if entry.IsDir() {
-			if err := fs.copyDir(sourcePath, destPath); err != nil {
-				return err
-			}
+			err = fs.copyDir(sourcePath, destPath)
 		} else {
-			if err := fs.copyFile(sourcePath, destPath); err != nil {
-				return err
+			err = fs.copyFile(sourcePath, destPath)
+		}
+		if errors.Is(err, ErrMetadataNotPreserved) {
+			if metadataErr == nil {
+				metadataErr = err
 			}
+		} else if err != nil {
+			return err
 		}
 	}
 
-	return nil
+	return metadataErr
 }
 
 // CreateDir creates a directory at the given path
@@ -198,54 +247,121 @@ func (fs *OSFileSystem) Exists(path string) bool {
 
 // ComputeHash computes the hash of a file using the specified algorithm
 func (fs *OSFileSystem) ComputeHash(path string, algorithm string) (string, error) {
-	file, err := os.Open(path)
+	file, err := OpenFile(context.Background(), path, os.O_RDONLY, 0)
 	if err != nil {
 		return "", err
 	}
+	defer CloseFile(file)
+
+	hasher, err := NewHasher(algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	size := int64(-1)
+	if info, err := file.Stat(); err == nil {
+		size = info.Size()
+	}
+
+	// Stream the file content to the hasher in chunks sized for this file
+	buffer := fs.chunks.Acquire(size)
+	var fullReads int
+	var fullBytes int64
+	var elapsed time.Duration
+	defer func() { fs.chunks.Release(buffer, fullReads, fullBytes, elapsed) }()
+
+	for {
+		start := time.Now()
+		n, err := io.ReadFull(file, buffer)
+		if n == len(buffer) {
+			fullReads++
+			fullBytes += int64(n)
+			elapsed += time.Since(start)
+		}
+		if n > 0 {
+			if _, err := hasher.Write(buffer[:n]); err != nil {
+				return "", err
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return fmt.Sprintf("%x", hasher.Sum(nil)), nil
+}
+
+// HashFile reads path once and returns its checksum in each algorithm
+func HashFile(path string, algorithms ...string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
 	defer file.Close()
+	return HashReader(file, algorithms...)
+}
+
+// HashReader reads r to the end and returns its checksum in each algorithm
+func HashReader(r io.Reader, algorithms ...string) ([]string, error) {
+	hashers := make([]hash.Hash, len(algorithms))
+	writers := make([]io.Writer, len(algorithms))
+	for i, algorithm := range algorithms {
+		hasher, err := NewHasher(algorithm)
+		if err != nil {
+			return nil, err
+		}
+		hashers[i], writers[i] = hasher, hasher
+	}
+
+	if _, err := io.Copy(io.MultiWriter(writers...), r); err != nil {
+		return nil, err
+	}
 
-	var hasher hash.Hash
+	sums := make([]string, len(hashers))
+	for i, hasher := range hashers {
+		sums[i] = fmt.Sprintf("%x", hasher.Sum(nil))
+	}
+	return sums, nil
+}
+
+// SupportedHashAlgorithms lists the algorithms accepted by NewHasher
+var SupportedHashAlgorithms = []string{"md5", "sha1", "sha256", "sha512", "blake2b", "blake3", "xxhash64", "crc32"}
 
+// NewHasher creates a hash implementation for the given algorithm name
+func NewHasher(algorithm string) (hash.Hash, error) {
 	switch strings.ToLower(algorithm) {
 	case "md5":
-		hasher = md5.New()
+		return md5.New(), nil
 	case "sha1":
-		hasher = sha1.New()
+		return sha1.New(), nil
 	case "sha256":
-		hasher = sha256.New()
+		return sha256.New(), nil
 	case "sha512":
-		hasher = sha512.New()
+		return sha512.New(), nil
 	case "blake2b":
-		var err error
-		hasher, err = blake2b.New256(nil)
-		if err != nil {
-			return "", err
-		}
+		return blake2b.New256(nil)
+	case "blake3":
+		return blake3.New(32, nil), nil
 	case "xxhash64":
-		hasher = xxhash.New()
+		return xxhash.New(), nil
 	case "crc32":
-		hasher = crc32.NewIEEE()
+		return crc32.NewIEEE(), nil
 	default:
-		return "", fmt.Errorf("unsupported hash algorithm: %s", algorithm)
+		return nil, fmt.Errorf("unsupported hash algorithm: %s", algorithm)
 	}
+}
 
-	// Stream the file content to the hasher in chunks
-	buffer := make([]byte, fs.chunkSize)
-	for {
-		n, err := file.Read(buffer)
-		if err != nil && err != io.EOF {
-			return "", err
-		}
-		if n == 0 {
-			break
-		}
-
-		if _, err := hasher.Write(buffer[:n]); err != nil {
-			return "", err
-		}
+// HashConfidence returns how sure files with equal hashes of algorithm are
+// identical: medium for fast checksums, high for cryptographic hashes
+func HashConfidence(algorithm string) float64 {
+	switch strings.ToLower(algorithm) {
+	case "xxhash64", "crc32":
+		return domain.ConfidenceMedium
 	}
-
-	return fmt.Sprintf("%x", hasher.Sum(nil)), nil
+	return domain.ConfidenceHigh
 }
 
 // PathValidator provides utilities for validating and normalizing paths
@@ -440,6 +556,8 @@ func (mfs *MockFileSystem) AddFile(path string, size int64, modTime time.Time) {
 		ModTime: modTime,
 		IsDir:   false,
 		Mode:    0644,
+		UID:     -1,
+		GID:     -1,
 	}
 }
 
@@ -453,6 +571,8 @@ func (mfs *MockFileSystem) AddDir(path string) {
 		ModTime: time.Now(),
 		IsDir:   true,
 		Mode:    0755,
+		UID:     -1,
+		GID:     -1,
 	}
 }
 
@@ -526,6 +646,11 @@ func (mfs *MockFileSystem) Copy(source, destination string) error {
 	return nil
 }
 
+// Link implements the FileSystem interface for testing
+func (mfs *MockFileSystem) Link(source, destination string) error {
+	return mfs.Copy(source, destination)
+}
+
 // CreateDir implements the FileSystem interface for testing
 func (mfs *MockFileSystem) CreateDir(path string) error {
 	mfs.AddDir(path)
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package filesystem
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// MaxOpenFiles is not available on this platform (no per-process descriptor limit)
+func MaxOpenFiles() (soft, hard uint64, err error) {
+	return 0, 0, fmt.Errorf("open file limit not applicable on %s", runtime.GOOS)
+}
@@ -0,0 +1,14 @@
+//go:build unix
+
+package filesystem
+
+import "syscall"
+
+// MaxOpenFiles returns the soft and hard limits on open file descriptors (ulimit -n)
+func MaxOpenFiles() (soft, hard uint64, err error) {
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil {
+		return 0, 0, err
+	}
+	return uint64(limit.Cur), uint64(limit.Max), nil
+}
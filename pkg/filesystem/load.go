@@ -0,0 +1,18 @@
+package filesystem
+
+// LoadSample is a reading of the counters behind the load of the machine.
+// CPU times only mean something as the difference between two samples.
+type LoadSample struct {
+	CPUBusy   uint64 // Time all CPUs spent working since boot, in clock ticks
+	CPUTotal  uint64 // Time all CPUs spent working or idle since boot
+	DiskQueue int64  // I/O requests in flight on the busiest disk
+}
+
+// CPUPercent returns the share of CPU time spent working between an earlier
+// sample and this one, 0 to 100
+func (s LoadSample) CPUPercent(earlier LoadSample) float64 {
+	if s.CPUTotal <= earlier.CPUTotal || s.CPUBusy < earlier.CPUBusy {
+		return 0
+	}
+	return float64(s.CPUBusy-earlier.CPUBusy) / float64(s.CPUTotal-earlier.CPUTotal) * 100
+}
@@ -0,0 +1,59 @@
+//go:build linux
+
+package filesystem
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// SystemLoad reads the CPU times from /proc/stat and the I/O requests in
+// flight from /proc/diskstats
+func SystemLoad() (LoadSample, error) {
+	var sample LoadSample
+
+	stat, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return sample, err
+	}
+	line, _, _ := strings.Cut(string(stat), "\n")
+	fields := strings.Fields(line)
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return sample, fmt.Errorf("unexpected /proc/stat line %q", line)
+	}
+	// user nice system idle iowait irq softirq steal ...; idle and iowait are not busy
+	for i, field := range fields[1:] {
+		if i >= 8 {
+			break
+		}
+		ticks, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return sample, fmt.Errorf("unexpected /proc/stat line %q", line)
+		}
+		sample.CPUTotal += ticks
+		if i != 3 && i != 4 {
+			sample.CPUBusy += ticks
+		}
+	}
+
+	disks, err := os.Open("/proc/diskstats")
+	if err != nil {
+		return sample, err
+	}
+	defer func() { _ = disks.Close() }()
+	scanner := bufio.NewScanner(disks)
+	for scanner.Scan() {
+		// major minor name reads ... writes ... in-flight ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 12 || strings.HasPrefix(fields[2], "loop") || strings.HasPrefix(fields[2], "ram") {
+			continue
+		}
+		if inFlight, err := strconv.ParseInt(fields[11], 10, 64); err == nil && inFlight > sample.DiskQueue {
+			sample.DiskQueue = inFlight
+		}
+	}
+	return sample, scanner.Err()
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package filesystem
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// SystemLoad is not supported on this platform
+func SystemLoad() (LoadSample, error) {
+	return LoadSample{}, fmt.Errorf("system load not available on %s", runtime.GOOS)
+}
@@ -0,0 +1,14 @@
+//go:build darwin
+
+package filesystem
+
+import "golang.org/x/sys/unix"
+
+// TotalMemory returns the physical memory of this machine in bytes
+func TotalMemory() (int64, error) {
+	memory, err := unix.SysctlUint64("hw.memsize")
+	if err != nil {
+		return 0, err
+	}
+	return int64(memory), nil
+}
@@ -0,0 +1,14 @@
+//go:build linux
+
+package filesystem
+
+import "golang.org/x/sys/unix"
+
+// TotalMemory returns the physical memory of this machine in bytes
+func TotalMemory() (int64, error) {
+	var info unix.Sysinfo_t
+	if err := unix.Sysinfo(&info); err != nil {
+		return 0, err
+	}
+	return int64(info.Totalram) * int64(info.Unit), nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package filesystem
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// TotalMemory is not supported on this platform
+func TotalMemory() (int64, error) {
+	return 0, fmt.Errorf("physical memory size not available on %s", runtime.GOOS)
+}
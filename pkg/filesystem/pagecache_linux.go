@@ -0,0 +1,18 @@
+//go:build linux
+
+package filesystem
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// DropCachedPages asks the kernel to evict the cached pages of file so that
+// following reads come from the storage device. Dirty pages are written first.
+func DropCachedPages(file *os.File) error {
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	return unix.Fadvise(int(file.Fd()), 0, 0, unix.FADV_DONTNEED)
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package filesystem
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// DropCachedPages is not supported on this platform
+func DropCachedPages(file *os.File) error {
+	return fmt.Errorf("evicting cached pages not supported on %s", runtime.GOOS)
+}
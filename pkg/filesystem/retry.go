@@ -0,0 +1,220 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// RetryPolicy configures how transient I/O errors are retried
+type RetryPolicy struct {
+	MaxAttempts    int           // Total attempts including the first one
+	InitialBackoff time.Duration // Delay before the first retry
+	MaxBackoff     time.Duration // Upper bound for the delay between attempts
+	Multiplier     float64       // Backoff growth factor between attempts
+}
+
+// DefaultRetryPolicy returns the policy used when none is configured
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2.0,
+	}
+}
+
+// RetryStats counts retry activity for a single file system method
+type RetryStats struct {
+	Calls     int64 `json:"calls"`
+	Retries   int64 `json:"retries"`
+	Recovered int64 `json:"recovered"` // Calls that succeeded after at least one retry
+	Exhausted int64 `json:"exhausted"` // Calls that still failed after all attempts
+}
+
+// IsRetryable reports whether err is a transient condition worth retrying,
+// such as timeouts, interrupted calls, stale NFS handles or injected faults.
+// Permanent conditions (missing files, permission denied) are not retried.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, os.ErrPermission) || errors.Is(err, os.ErrExist) ||
+		errors.Is(err, ErrMetadataNotPreserved) {
+		return false
+	}
+
+	if errors.Is(err, ErrInjectedFault) || errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		switch errno {
+		case syscall.EAGAIN, syscall.EINTR, syscall.EIO, syscall.EBUSY, syscall.ETIMEDOUT,
+			syscall.ECONNRESET, syscall.ECONNABORTED, syscall.ESTALE, syscall.EHOSTUNREACH:
+			return true
+		}
+		return errno.Timeout() || errno.Temporary()
+	}
+
+	return false
+}
+
+// RetryingFileSystem decorates a FileSystem, retrying transient failures with exponential backoff
+type RetryingFileSystem struct {
+	inner  domain.FileSystem
+	policy RetryPolicy
+	stats  map[string]*RetryStats
+	mu     sync.Mutex
+}
+
+// NewRetryingFileSystem wraps inner with the given retry policy
+func NewRetryingFileSystem(inner domain.FileSystem, policy RetryPolicy) *RetryingFileSystem {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 1
+	}
+	if policy.Multiplier < 1 {
+		policy.Multiplier = 1
+	}
+	return &RetryingFileSystem{
+		inner:  inner,
+		policy: policy,
+		stats:  make(map[string]*RetryStats),
+	}
+}
+
+// RetryStats returns a snapshot of retry statistics keyed by method name
+func (r *RetryingFileSystem) RetryStats() map[string]RetryStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snapshot := make(map[string]RetryStats, len(r.stats))
+	for op, stats := range r.stats {
+		snapshot[op] = *stats
+	}
+	return snapshot
+}
+
+// record updates the statistics of op after a call finished
+func (r *RetryingFileSystem) record(op string, retries int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats, exists := r.stats[op]
+	if !exists {
+		stats = &RetryStats{}
+		r.stats[op] = stats
+	}
+
+	stats.Calls++
+	stats.Retries += int64(retries)
+	if retries > 0 {
+		if err == nil {
+			stats.Recovered++
+		} else {
+			stats.Exhausted++
+		}
+	}
+}
+
+// do runs fn until it succeeds, fails permanently or runs out of attempts
+func (r *RetryingFileSystem) do(op string, fn func() error) error {
+	backoff := r.policy.InitialBackoff
+	retries := 0
+
+	for {
+		err := fn()
+		if err == nil || !IsRetryable(err) || retries+1 >= r.policy.MaxAttempts {
+			r.record(op, retries, err)
+			return err
+		}
+
+		retries++
+		time.Sleep(backoff)
+		backoff = time.Duration(float64(backoff) * r.policy.Multiplier)
+		if r.policy.MaxBackoff > 0 && backoff > r.policy.MaxBackoff {
+			backoff = r.policy.MaxBackoff
+		}
+	}
+}
+
+// Walk traverses the file system (walk callbacks are not retried)
+func (r *RetryingFileSystem) Walk(ctx context.Context, path string, fn domain.WalkFunc) error {
+	return r.inner.Walk(ctx, path, fn)
+}
+
+// Stat returns file information for the given path
+func (r *RetryingFileSystem) Stat(path string) (*domain.FileInfo, error) {
+	var info *domain.FileInfo
+	err := r.do("stat", func() error {
+		var err error
+		info, err = r.inner.Stat(path)
+		return err
+	})
+	return info, err
+}
+
+// Remove removes the file or directory at the given path
+func (r *RetryingFileSystem) Remove(path string) error {
+	return r.do("remove", func() error { return r.inner.Remove(path) })
+}
+
+// RemoveAll removes the directory and all its contents
+func (r *RetryingFileSystem) RemoveAll(path string) error {
+	return r.do("removeall", func() error { return r.inner.RemoveAll(path) })
+}
+
+// Move moves a file or directory from source to destination
+func (r *RetryingFileSystem) Move(source, destination string) error {
+	return r.do("move", func() error { return r.inner.Move(source, destination) })
+}
+
+// Copy copies a file or directory from source to destination
+func (r *RetryingFileSystem) Copy(source, destination string) error {
+	return r.do("copy", func() error { return r.inner.Copy(source, destination) })
+}
+
+// Link creates destination as a hard link to source
+func (r *RetryingFileSystem) Link(source, destination string) error {
+	return r.do("link", func() error { return r.inner.Link(source, destination) })
+}
+
+// CreateDir creates a directory at the given path
+func (r *RetryingFileSystem) CreateDir(path string) error {
+	return r.do("createdir", func() error { return r.inner.CreateDir(path) })
+}
+
+// IsEmpty checks if a directory is empty
+func (r *RetryingFileSystem) IsEmpty(path string) (bool, error) {
+	var empty bool
+	err := r.do("isempty", func() error {
+		var err error
+		empty, err = r.inner.IsEmpty(path)
+		return err
+	})
+	return empty, err
+}
+
+// Exists checks if a file or directory exists
+func (r *RetryingFileSystem) Exists(path string) bool {
+	return r.inner.Exists(path)
+}
+
+// ComputeHash computes the hash of a file
+func (r *RetryingFileSystem) ComputeHash(path string, algorithm string) (string, error) {
+	var sum string
+	err := r.do("hash", func() error {
+		var err error
+		sum, err = r.inner.ComputeHash(path, algorithm)
+		return err
+	})
+	return sum, err
+}
@@ -0,0 +1,35 @@
+package filesystem
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrSecurityLabelsNotPreserved is returned by Copy when the content was
+// copied but the SELinux context or file capabilities of the source could not
+// be applied to the copy
+var ErrSecurityLabelsNotPreserved = fmt.Errorf("%w: SELinux context or file capabilities", ErrMetadataNotPreserved)
+
+// SecurityLabels are the Linux security attributes that a plain copy of a
+// file loses: its SELinux context and the file capabilities set by setcap
+type SecurityLabels struct {
+	Context      string // security.selinux, e.g. system_u:object_r:httpd_sys_content_t:s0
+	Capabilities []byte // security.capability in its binary form
+}
+
+// Empty reports whether there are no labels to preserve
+func (l SecurityLabels) Empty() bool {
+	return l.Context == "" && len(l.Capabilities) == 0
+}
+
+// String describes the labels for messages
+func (l SecurityLabels) String() string {
+	parts := make([]string, 0, 2)
+	if l.Context != "" {
+		parts = append(parts, "SELinux context "+l.Context)
+	}
+	if len(l.Capabilities) > 0 {
+		parts = append(parts, "file capabilities")
+	}
+	return strings.Join(parts, " and ")
+}
@@ -0,0 +1,91 @@
+//go:build linux
+
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	selinuxXattr    = "security.selinux"
+	capabilityXattr = "security.capability"
+)
+
+// ReadSecurityLabels returns the SELinux context and file capabilities of path
+func ReadSecurityLabels(path string) (SecurityLabels, error) {
+	context, err := readSecurityXattr(path, selinuxXattr)
+	if err != nil {
+		return SecurityLabels{}, err
+	}
+	capabilities, err := readSecurityXattr(path, capabilityXattr)
+	if err != nil {
+		return SecurityLabels{}, err
+	}
+	return SecurityLabels{
+		Context:      strings.TrimRight(string(context), "\x00"),
+		Capabilities: capabilities,
+	}, nil
+}
+
+// ApplySecurityLabels sets labels on path. Setting a context needs SELinux
+// permission to relabel, setting capabilities needs CAP_SETFCAP.
+func ApplySecurityLabels(path string, labels SecurityLabels) error {
+	if labels.Context != "" {
+		if err := unix.Setxattr(path, selinuxXattr, []byte(labels.Context), 0); err != nil {
+			return securityLabelError("SELinux context", err)
+		}
+	}
+	if len(labels.Capabilities) > 0 {
+		if err := unix.Setxattr(path, capabilityXattr, labels.Capabilities, 0); err != nil {
+			return securityLabelError("file capabilities", err)
+		}
+	}
+	return nil
+}
+
+// SecurityLabelsSupported reports whether the filesystem holding path (or
+// its nearest existing parent) can store security labels
+func SecurityLabelsSupported(path string) bool {
+	probe, err := NearestExisting(path)
+	if err != nil {
+		return false
+	}
+	_, err = unix.Getxattr(probe, selinuxXattr, nil)
+	return !errors.Is(err, unix.EOPNOTSUPP)
+}
+
+// readSecurityXattr reads an attribute of path; a missing attribute or a
+// filesystem without extended attributes reads as empty
+func readSecurityXattr(path, attr string) ([]byte, error) {
+	buf := make([]byte, 256)
+	for {
+		n, err := unix.Getxattr(path, attr, buf)
+		if errors.Is(err, unix.ERANGE) {
+			buf = make([]byte, len(buf)*4)
+			continue
+		}
+		if errors.Is(err, unix.ENODATA) || errors.Is(err, unix.EOPNOTSUPP) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+}
+
+// securityLabelError explains the usual reasons a label cannot be set
+func securityLabelError(label string, err error) error {
+	switch {
+	case errors.Is(err, unix.EOPNOTSUPP):
+		return fmt.Errorf("%s: the filesystem cannot store it: %w", label, err)
+	case errors.Is(err, unix.EPERM) && label == "file capabilities":
+		return fmt.Errorf("%s: setting them requires CAP_SETFCAP (run as root): %w", label, err)
+	default:
+		return fmt.Errorf("%s: %w", label, err)
+	}
+}
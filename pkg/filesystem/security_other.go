@@ -0,0 +1,27 @@
+//go:build !linux
+
+package filesystem
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// ReadSecurityLabels returns no labels; SELinux contexts and file
+// capabilities exist only on Linux
+func ReadSecurityLabels(path string) (SecurityLabels, error) {
+	return SecurityLabels{}, nil
+}
+
+// ApplySecurityLabels is not supported on this platform
+func ApplySecurityLabels(path string, labels SecurityLabels) error {
+	if labels.Empty() {
+		return nil
+	}
+	return fmt.Errorf("security labels are not supported on %s", runtime.GOOS)
+}
+
+// SecurityLabelsSupported reports false; security labels exist only on Linux
+func SecurityLabelsSupported(path string) bool {
+	return false
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package filesystem
+
+import (
+	"os"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// fillSysInfo marks ownership as unavailable; device and inode data is not
+// part of the directory listing on this platform and is left empty
+func fillSysInfo(fileInfo *domain.FileInfo, info os.FileInfo) {
+	fileInfo.UID, fileInfo.GID = -1, -1
+}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package filesystem
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+)
+
+// fillSysInfo copies device, inode, link count and ownership from the stat result
+func fillSysInfo(fileInfo *domain.FileInfo, info os.FileInfo) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		fileInfo.UID, fileInfo.GID = -1, -1
+		return
+	}
+
+	fileInfo.Device = uint64(stat.Dev)
+	fileInfo.Inode = uint64(stat.Ino)
+	fileInfo.Links = uint64(stat.Nlink)
+	fileInfo.UID = int(stat.Uid)
+	fileInfo.GID = int(stat.Gid)
+}
@@ -0,0 +1,25 @@
+//go:build darwin
+
+package filesystem
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// TimeGranularity returns the step in which the filesystem holding path
+// stores modification times, or 0 when they are precise
+func TimeGranularity(path string) time.Duration {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return 0
+	}
+	switch unix.ByteSliceToString(stat.Fstypename[:]) {
+	case "msdos", "exfat":
+		return 2 * time.Second
+	case "smbfs":
+		return time.Second
+	}
+	return 0
+}
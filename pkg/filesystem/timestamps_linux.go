@@ -0,0 +1,34 @@
+//go:build linux
+
+package filesystem
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Filesystem magic numbers (statfs f_type) of filesystems with coarse timestamps
+const (
+	msdosMagic = 0x4d44
+	exfatMagic = 0x2011bab0
+	smbMagic   = 0x517b
+	cifsMagic  = 0xff534d42
+	smb2Magic  = 0xfe534d42
+)
+
+// TimeGranularity returns the step in which the filesystem holding path
+// stores modification times, or 0 when they are precise
+func TimeGranularity(path string) time.Duration {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return 0
+	}
+	switch uint32(stat.Type) {
+	case msdosMagic, exfatMagic:
+		return 2 * time.Second
+	case smbMagic, cifsMagic, smb2Magic:
+		return time.Second
+	}
+	return 0
+}
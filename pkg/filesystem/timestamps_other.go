@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package filesystem
+
+import "time"
+
+// TimeGranularity is not detected on this platform; times are taken as precise
+func TimeGranularity(path string) time.Duration {
+	return 0
+}
@@ -0,0 +1,105 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Unicode normalization policies for file names. macOS writes names in
+// decomposed form (NFD), most other systems keep what they are given, usually
+// composed (NFC), so the same name can arrive as different bytes.
+const (
+	NormalizeNFC  = "nfc"  // Compare names composed and write new names composed
+	NormalizeNFD  = "nfd"  // Compare names decomposed and write new names decomposed
+	NormalizeNone = "none" // Compare and write names byte for byte
+)
+
+// ValidNormalization reports whether policy is known; empty selects NFC
+func ValidNormalization(policy string) bool {
+	switch policy {
+	case "", NormalizeNFC, NormalizeNFD, NormalizeNone:
+		return true
+	}
+	return false
+}
+
+// NormalizeName returns name in the form of policy
+func NormalizeName(name, policy string) string {
+	switch policy {
+	case NormalizeNone:
+		return name
+	case NormalizeNFD:
+		return norm.NFD.String(name)
+	default:
+		return norm.NFC.String(name)
+	}
+}
+
+// NormalizationOnly reports whether a and b differ in their bytes but not
+// once normalized, i.e. they are the same name written differently
+func NormalizationOnly(a, b string) bool {
+	return a != b && norm.NFC.String(a) == norm.NFC.String(b)
+}
+
+// Alternate returns name in the other normalization form, or name itself when
+// it looks the same in both
+func Alternate(name string) string {
+	if composed := norm.NFC.String(name); composed != name {
+		return composed
+	}
+	return norm.NFD.String(name)
+}
+
+// NameIndex finds existing entries by their normalized names, reading each
+// directory once
+type NameIndex struct {
+	mu   sync.Mutex
+	dirs map[string]map[string]string // Directory -> NFC name -> path on disk
+}
+
+// NewNameIndex creates an empty name index
+func NewNameIndex() *NameIndex {
+	return &NameIndex{dirs: make(map[string]map[string]string)}
+}
+
+// Lookup returns the existing path that names the same entry as path once
+// normalized, element by element
+func (x *NameIndex) Lookup(path string) (string, bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.lookup(filepath.Clean(path))
+}
+
+func (x *NameIndex) lookup(path string) (string, bool) {
+	dir, name := filepath.Split(path)
+	if name == "" {
+		return path, true // The root
+	}
+	dir = filepath.Clean(dir)
+
+	names, ok := x.dirs[dir]
+	if !ok {
+		names = make(map[string]string)
+		if _, err := os.Stat(dir); err != nil {
+			// The directory may exist under a differently normalized name
+			if actual, found := x.lookup(dir); found && actual != dir {
+				entries, _ := os.ReadDir(actual)
+				for _, entry := range entries {
+					names[norm.NFC.String(entry.Name())] = filepath.Join(actual, entry.Name())
+				}
+			}
+		} else {
+			entries, _ := os.ReadDir(dir)
+			for _, entry := range entries {
+				names[norm.NFC.String(entry.Name())] = filepath.Join(dir, entry.Name())
+			}
+		}
+		x.dirs[dir] = names
+	}
+
+	existing, ok := names[norm.NFC.String(name)]
+	return existing, ok
+}
@@ -0,0 +1,47 @@
+package progress
+
+import (
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/events"
+)
+
+// HandleEvent applies a published event to the tracker of its operation.
+// Subscribe it to the event bus of the engine whose progress is shown.
+func (t *Tracker) HandleEvent(event events.Event) {
+	if event.Type == events.OperationStarted {
+		t.StartOperation(event.OperationID, event.OperationType, event.TotalSteps)
+		return
+	}
+
+	tracker := t.GetOperation(event.OperationID)
+	if tracker == nil {
+		return
+	}
+
+	switch event.Type {
+	case events.StepStarted:
+		tracker.UpdateStep(event.Step)
+	case events.ProgressUpdated:
+		if event.Items < 0 {
+			tracker.SetTotals(event.TotalItems, event.TotalBytes)
+		} else {
+			tracker.UpdateProgress(event.Items, event.TotalItems, event.Bytes, event.TotalBytes)
+		}
+	case events.FileProcessed:
+		tracker.IncrementProgress(event.Items, event.Bytes)
+	case events.ErrorOccurred:
+		tracker.AddError(event.Error)
+	case events.BatchCommitted:
+		tracker.SetDetail("committed_batches", event.Batch)
+		tracker.SetDetail("committed_changes", event.Items)
+	case events.OperationFinished:
+		switch event.Status {
+		case domain.StatusCompleted:
+			tracker.Complete()
+		case domain.StatusCancelled:
+			tracker.Cancel()
+		default:
+			tracker.Fail(event.Error)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package progress
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/a4abhishek/fileops/pkg/domain"
+	"github.com/a4abhishek/fileops/pkg/filesystem"
+	"github.com/a4abhishek/fileops/pkg/schema"
+)
+
+// snapshotInterval is how often the progress of a running operation is written
+const snapshotInterval = time.Second
+
+// snapshotRetention is how long snapshots of finished operations are kept
+const snapshotRetention = 7 * 24 * time.Hour
+
+// Snapshot is the persisted progress of an operation, readable by other processes
+type Snapshot struct {
+	SchemaVersion int `json:"schema_version"` // See package schema
+	domain.ProgressInfo
+	PID       int       `json:"pid"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Finished reports whether the operation has ended
+func (s *Snapshot) Finished() bool {
+	switch s.Status {
+	case domain.StatusCompleted, domain.StatusFailed, domain.StatusCancelled:
+		return true
+	}
+	return false
+}
+
+// SetStateDirectory makes the tracker write a snapshot of every operation to
+// dir, once per second while it runs and once when it ends. Snapshots of
+// operations that finished more than a week ago are removed.
+func (t *Tracker) SetStateDirectory(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.stateDir = dir
+	t.mu.Unlock()
+
+	snapshots, _ := ListSnapshots(dir)
+	for _, snapshot := range snapshots {
+		if snapshot.Finished() && time.Since(snapshot.UpdatedAt) > snapshotRetention {
+			_ = os.Remove(snapshotPath(dir, snapshot.ID))
+		}
+	}
+	return nil
+}
+
+// persist writes snapshots of an operation until it ends or is replaced by a
+// new tracker with the same ID
+func (t *Tracker) persist(tracker *OperationTracker) {
+	ticker := time.NewTicker(snapshotInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		t.mu.RLock()
+		current := t.operations[tracker.id]
+		t.mu.RUnlock()
+		if current != tracker {
+			return
+		}
+
+		switch tracker.persistSnapshot() {
+		case domain.StatusCompleted, domain.StatusFailed, domain.StatusCancelled:
+			return
+		}
+	}
+}
+
+// persistSnapshot writes the current progress and returns the status it wrote
+func (ot *OperationTracker) persistSnapshot() domain.OperationStatus {
+	if ot.stateDir == "" {
+		return ""
+	}
+	ot.persistMu.Lock()
+	defer ot.persistMu.Unlock()
+
+	info := ot.GetProgressInfo()
+	_ = writeSnapshot(ot.stateDir, info)
+	return info.Status
+}
+
+// writeSnapshot atomically replaces the snapshot of an operation
+func writeSnapshot(dir string, info domain.ProgressInfo) error {
+	delete(info.Details, "all_errors") // Only the count and latest error are kept
+	data, err := json.MarshalIndent(Snapshot{SchemaVersion: schema.ProgressVersion, ProgressInfo: info, PID: os.Getpid(), UpdatedAt: time.Now()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := snapshotPath(dir, info.ID)
+	return filesystem.WriteFileAtomic(path, data, 0o644)
+}
+
+// snapshotPath returns the snapshot file of an operation
+func snapshotPath(dir, id string) string {
+	return filepath.Join(dir, id+".json")
+}
+
+// LoadSnapshot reads the persisted progress of an operation
+func LoadSnapshot(dir, id string) (*Snapshot, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return nil, errors.New("invalid operation id")
+	}
+	data, err := os.ReadFile(snapshotPath(dir, id))
+	if err != nil {
+		return nil, err
+	}
+	if data, err = schema.Upgrade(schema.Progress, data); err != nil {
+		return nil, err
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// ListSnapshots returns the persisted progress of all operations, most
+// recently updated first
+func ListSnapshots(dir string) ([]*Snapshot, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]*Snapshot, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		if snapshot, err := LoadSnapshot(dir, strings.TrimSuffix(entry.Name(), ".json")); err == nil {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].UpdatedAt.After(snapshots[j].UpdatedAt)
+	})
+	return snapshots, nil
+}
@@ -14,6 +14,7 @@ type Tracker struct {
 	operations    map[string]*OperationTracker
 	subscribers   map[string][]chan domain.ProgressInfo
 	subscribersMu sync.RWMutex
+	stateDir      string // Where snapshots are persisted, empty when disabled
 }
 
 // NewTracker creates a new progress tracker
@@ -26,30 +27,41 @@ func NewTracker() *Tracker {
 
 // OperationTracker tracks progress for a single operation
 type OperationTracker struct {
-	mu              sync.RWMutex
-	id              string
-	operationType   domain.OperationType
-	status          domain.OperationStatus
-	startTime       time.Time
-	endTime         *time.Time
-	currentStep     string
-	stepsCompleted  int
-	totalSteps      int
-	itemsProcessed  int64
-	totalItems      int64
-	bytesProcessed  int64
-	totalBytes      int64
-	lastUpdate      time.Time
-	speedSamples    []speedSample
-	maxSpeedSamples int
-	details         map[string]interface{}
-	errors          []string
-	ctx             context.Context
-	cancel          context.CancelFunc
-	pauseChannel    chan bool
-	resumeChannel   chan bool
-	isPaused        bool
-}
+	mu             sync.RWMutex
+	id             string
+	operationType  domain.OperationType
+	status         domain.OperationStatus
+	startTime      time.Time
+	endTime        *time.Time
+	currentStep    string
+	stepsCompleted int
+	totalSteps     int
+	itemsProcessed int64
+	totalItems     int64
+	bytesProcessed int64
+	totalBytes     int64
+	lastUpdate     time.Time
+	samples        [speedRingSize]speedSample // Ring buffer of counter snapshots
+	sampleHead     int                        // Index of the latest sample
+	sampleCount    int
+	details        map[string]interface{}
+	errors         []string
+	ctx            context.Context
+	cancel         context.CancelFunc
+	pauseChannel   chan bool
+	resumeChannel  chan bool
+	isPaused       bool
+	stateDir       string     // Where snapshots are persisted, empty when disabled
+	persistMu      sync.Mutex // Orders snapshot writes
+}
+
+// Speeds are measured over the samples of the last speedWindow. A sample is
+// taken at most every speedWindow/speedRingSize; updates in between move the
+// latest sample forward.
+const (
+	speedWindow   = 10 * time.Second
+	speedRingSize = 64
+)
 
 type speedSample struct {
 	timestamp time.Time
@@ -62,27 +74,30 @@ func (t *Tracker) StartOperation(id string, operationType domain.OperationType,
 	ctx, cancel := context.WithCancel(context.Background())
 
 	tracker := &OperationTracker{
-		id:              id,
-		operationType:   operationType,
-		status:          domain.StatusRunning,
-		startTime:       time.Now(),
-		currentStep:     "Initializing",
-		stepsCompleted:  0,
-		totalSteps:      totalSteps,
-		lastUpdate:      time.Now(),
-		speedSamples:    make([]speedSample, 0, 10),
-		maxSpeedSamples: 10,
-		details:         make(map[string]interface{}),
-		ctx:             ctx,
-		cancel:          cancel,
-		pauseChannel:    make(chan bool, 1),
-		resumeChannel:   make(chan bool, 1),
+		id:             id,
+		operationType:  operationType,
+		status:         domain.StatusRunning,
+		startTime:      time.Now(),
+		currentStep:    "Initializing",
+		stepsCompleted: 0,
+		totalSteps:     totalSteps,
+		lastUpdate:     time.Now(),
+		details:        make(map[string]interface{}),
+		ctx:            ctx,
+		cancel:         cancel,
+		pauseChannel:   make(chan bool, 1),
+		resumeChannel:  make(chan bool, 1),
 	}
 
 	t.mu.Lock()
 	t.operations[id] = tracker
+	tracker.stateDir = t.stateDir
 	t.mu.Unlock()
 
+	if tracker.stateDir != "" {
+		go t.persist(tracker)
+	}
+
 	// Report initial progress
 	_ = t.reportProgress(tracker.GetProgressInfo())
 
@@ -112,18 +127,7 @@ func (ot *OperationTracker) UpdateProgress(itemsProcessed, totalItems, bytesProc
 	ot.bytesProcessed = bytesProcessed
 	ot.totalBytes = totalBytes
 
-	// Add speed sample
-	sample := speedSample{
-		timestamp: now,
-		items:     itemsProcessed,
-		bytes:     bytesProcessed,
-	}
-
-	ot.speedSamples = append(ot.speedSamples, sample)
-	if len(ot.speedSamples) > ot.maxSpeedSamples {
-		ot.speedSamples = ot.speedSamples[1:]
-	}
-
+	ot.addSample(now)
 	ot.lastUpdate = now
 }
 
@@ -135,18 +139,7 @@ func (ot *OperationTracker) IncrementProgress(items, bytes int64) {
 	ot.itemsProcessed += items
 	ot.bytesProcessed += bytes
 	ot.lastUpdate = time.Now()
-
-	// Add speed sample
-	sample := speedSample{
-		timestamp: ot.lastUpdate,
-		items:     ot.itemsProcessed,
-		bytes:     ot.bytesProcessed,
-	}
-
-	ot.speedSamples = append(ot.speedSamples, sample)
-	if len(ot.speedSamples) > ot.maxSpeedSamples {
-		ot.speedSamples = ot.speedSamples[1:]
-	}
+	ot.addSample(ot.lastUpdate)
 }
 
 // SetTotals updates the total counters
@@ -158,6 +151,14 @@ func (ot *OperationTracker) SetTotals(totalItems, totalBytes int64) {
 	ot.totalBytes = totalBytes
 }
 
+// Counts returns the processed and total item counters without building a full snapshot
+func (ot *OperationTracker) Counts() (itemsProcessed, totalItems int64) {
+	ot.mu.RLock()
+	defer ot.mu.RUnlock()
+
+	return ot.itemsProcessed, ot.totalItems
+}
+
 // SetDetail adds or updates a detail field
 func (ot *OperationTracker) SetDetail(key string, value interface{}) {
 	ot.mu.Lock()
@@ -177,33 +178,36 @@ func (ot *OperationTracker) AddError(err string) {
 // Complete marks the operation as completed
 func (ot *OperationTracker) Complete() {
 	ot.mu.Lock()
-	defer ot.mu.Unlock()
-
 	ot.status = domain.StatusCompleted
 	now := time.Now()
 	ot.endTime = &now
+	ot.mu.Unlock()
+
+	ot.persistSnapshot()
 }
 
 // Fail marks the operation as failed
 func (ot *OperationTracker) Fail(err string) {
 	ot.mu.Lock()
-	defer ot.mu.Unlock()
-
 	ot.status = domain.StatusFailed
 	now := time.Now()
 	ot.endTime = &now
 	ot.errors = append(ot.errors, err)
+	ot.mu.Unlock()
+
+	ot.persistSnapshot()
 }
 
 // Cancel cancels the operation
 func (ot *OperationTracker) Cancel() {
 	ot.mu.Lock()
-	defer ot.mu.Unlock()
-
 	ot.status = domain.StatusCancelled
 	now := time.Now()
 	ot.endTime = &now
 	ot.cancel()
+	ot.mu.Unlock()
+
+	ot.persistSnapshot()
 }
 
 // Pause pauses the operation
@@ -262,39 +266,71 @@ func (ot *OperationTracker) WaitForResume() {
 	}
 }
 
-// calculateSpeed calculates the current processing speed
-func (ot *OperationTracker) calculateSpeed() int64 {
-	if len(ot.speedSamples) < 2 {
-		return 0
+// addSample records the current counters in the speed ring buffer. Counters
+// that went backwards (a new step restarting them) start a new measurement.
+func (ot *OperationTracker) addSample(now time.Time) {
+	sample := speedSample{timestamp: now, items: ot.itemsProcessed, bytes: ot.bytesProcessed}
+	if ot.sampleCount > 0 {
+		latest := ot.samples[ot.sampleHead]
+		if sample.items < latest.items || sample.bytes < latest.bytes {
+			ot.sampleCount = 0
+		} else if ot.sampleCount > 1 && now.Sub(ot.samples[(ot.sampleHead+speedRingSize-1)%speedRingSize].timestamp) < speedWindow/speedRingSize {
+			ot.samples[ot.sampleHead] = sample
+			return
+		}
 	}
 
-	latest := ot.speedSamples[len(ot.speedSamples)-1]
-	oldest := ot.speedSamples[0]
+	ot.sampleHead = (ot.sampleHead + 1) % speedRingSize
+	ot.samples[ot.sampleHead] = sample
+	if ot.sampleCount < speedRingSize {
+		ot.sampleCount++
+	}
+}
 
-	timeDiff := latest.timestamp.Sub(oldest.timestamp).Seconds()
-	if timeDiff == 0 {
-		return 0
+// calculateSpeed returns the items and bytes processed per second over the
+// last speedWindow
+func (ot *OperationTracker) calculateSpeed() (itemsPerSecond, bytesPerSecond int64) {
+	if ot.sampleCount < 2 {
+		return 0, 0
 	}
 
-	itemsDiff := latest.items - oldest.items
-	return int64(float64(itemsDiff) / timeDiff)
-}
+	latest := ot.samples[ot.sampleHead]
+	oldest := latest
+	for i := 1; i < ot.sampleCount; i++ {
+		sample := ot.samples[(ot.sampleHead+speedRingSize-i)%speedRingSize]
+		if latest.timestamp.Sub(sample.timestamp) > speedWindow {
+			break
+		}
+		oldest = sample
+	}
 
-// calculateETA calculates the estimated time to completion
-func (ot *OperationTracker) calculateETA() *time.Duration {
-	if ot.totalItems == 0 || ot.itemsProcessed >= ot.totalItems {
-		return nil
+	elapsed := latest.timestamp.Sub(oldest.timestamp).Seconds()
+	if elapsed <= 0 {
+		return 0, 0
 	}
+	return int64(float64(latest.items-oldest.items) / elapsed), int64(float64(latest.bytes-oldest.bytes) / elapsed)
+}
 
-	speed := ot.calculateSpeed()
-	if speed == 0 {
+// calculateETA estimates the time to completion from the byte speed when the
+// operation reports a byte total (hashing, copying), otherwise from the item speed
+func (ot *OperationTracker) calculateETA(itemsPerSecond, bytesPerSecond int64) *time.Duration {
+	var remaining float64
+	switch {
+	case ot.totalBytes > 0 && bytesPerSecond > 0:
+		if ot.bytesProcessed >= ot.totalBytes {
+			return nil
+		}
+		remaining = float64(ot.totalBytes-ot.bytesProcessed) / float64(bytesPerSecond)
+	case ot.totalItems > 0 && itemsPerSecond > 0:
+		if ot.itemsProcessed >= ot.totalItems {
+			return nil
+		}
+		remaining = float64(ot.totalItems-ot.itemsProcessed) / float64(itemsPerSecond)
+	default:
 		return nil
 	}
 
-	remaining := ot.totalItems - ot.itemsProcessed
-	etaSeconds := float64(remaining) / float64(speed)
-	eta := time.Duration(etaSeconds) * time.Second
-
+	eta := time.Duration(remaining * float64(time.Second))
 	return &eta
 }
 
@@ -303,6 +339,7 @@ func (ot *OperationTracker) GetProgressInfo() domain.ProgressInfo {
 	ot.mu.RLock()
 	defer ot.mu.RUnlock()
 
+	itemsPerSecond, bytesPerSecond := ot.calculateSpeed()
 	progress := domain.ProgressInfo{
 		ID:             ot.id,
 		OperationType:  ot.operationType,
@@ -316,8 +353,9 @@ func (ot *OperationTracker) GetProgressInfo() domain.ProgressInfo {
 		TotalItems:     ot.totalItems,
 		BytesProcessed: ot.bytesProcessed,
 		TotalBytes:     ot.totalBytes,
-		Speed:          ot.calculateSpeed(),
-		EstimatedETA:   ot.calculateETA(),
+		Speed:          itemsPerSecond,
+		ByteSpeed:      bytesPerSecond,
+		EstimatedETA:   ot.calculateETA(itemsPerSecond, bytesPerSecond),
 		Details:        make(map[string]interface{}),
 	}
 
@@ -0,0 +1,225 @@
+// Package schema versions the JSON documents fileops writes for other tools:
+// operation results (stored results, --report files, report.json artifacts),
+// review plans, run plans, progress snapshots, events and the responses of
+// the agent API.
+//
+// Every document carries the version of its kind in a schema_version field.
+// Within a version fields are only ever added; renaming or removing a field,
+// or changing its meaning, increments the version of the kind and adds a
+// converter between the new version and the previous one. Documents of any
+// earlier version can therefore be upgraded to the current one, and current
+// documents downgraded for tools that expect an earlier version. Documents
+// without a schema_version are version 0, written before versions were
+// recorded.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Kind is a kind of document
+type Kind string
+
+// Kinds of documents
+const (
+	Result     Kind = "result"      // Operation results and reports
+	ReviewPlan Kind = "review-plan" // Planned changes to approve with fileops review
+	RunPlan    Kind = "run-plan"    // How an operation was started, plan.json of a run
+	Progress   Kind = "progress"    // Persisted progress of an operation
+	Event      Kind = "event"       // Events published while operations run
+	Agent      Kind = "agent"       // Responses of the agent API
+)
+
+// Current versions of the kinds
+const (
+	ResultVersion     = 1
+	ReviewPlanVersion = 1
+	RunPlanVersion    = 1
+	ProgressVersion   = 1
+	EventVersion      = 1
+	AgentVersion      = 1
+)
+
+// current maps the kinds to their current version
+var current = map[Kind]int{
+	Result:     ResultVersion,
+	ReviewPlan: ReviewPlanVersion,
+	RunPlan:    RunPlanVersion,
+	Progress:   ProgressVersion,
+	Event:      EventVersion,
+	Agent:      AgentVersion,
+}
+
+// Field is the name of the version field of every document
+const Field = "schema_version"
+
+// converter turns documents of a kind from version from into from+1 (up)
+// and back (down). Either may be nil when setting the version is enough.
+type converter struct {
+	kind Kind
+	from int
+	up   func(doc map[string]interface{})
+	down func(doc map[string]interface{})
+}
+
+// converters holds every version step of every kind
+var converters = []converter{
+	{kind: Result, from: 0, up: upgradeResult0},
+	{kind: ReviewPlan, from: 0},
+	{kind: RunPlan, from: 0},
+	{kind: Progress, from: 0},
+	{kind: Event, from: 0},
+	{kind: Agent, from: 0},
+}
+
+// Kinds returns every kind, sorted by name
+func Kinds() []Kind {
+	kinds := make([]Kind, 0, len(current))
+	for kind := range current {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+	return kinds
+}
+
+// Current returns the current version of kind
+func Current(kind Kind) (int, error) {
+	version, ok := current[kind]
+	if !ok {
+		return 0, fmt.Errorf("unknown document kind %q", kind)
+	}
+	return version, nil
+}
+
+// Version returns the version of a decoded document, 0 when it has none
+func Version(doc map[string]interface{}) int {
+	if version, ok := doc[Field].(float64); ok {
+		return int(version)
+	}
+	return 0
+}
+
+// Detect tells the kind of a decoded document by its fields
+func Detect(doc map[string]interface{}) (Kind, bool) {
+	has := func(fields ...string) bool {
+		for _, field := range fields {
+			if _, ok := doc[field]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+	switch {
+	case has("pid", "updated_at"):
+		return Progress, true
+	case has("command", "working_directory"):
+		return RunPlan, true
+	case has("roots", "changes"):
+		return ReviewPlan, true
+	case has("operation_type", "status", "start_time"):
+		return Result, true
+	case has("type", "operation_id", "time"):
+		return Event, true
+	case has("host", "roots"), has("hashes"), has("method"):
+		return Agent, true
+	}
+	return "", false
+}
+
+// Convert converts a decoded document of kind in place to version to,
+// upgrading or downgrading it one version at a time
+func Convert(kind Kind, doc map[string]interface{}, to int) error {
+	latest, err := Current(kind)
+	if err != nil {
+		return err
+	}
+	from := Version(doc)
+	if err := Check(kind, from); err != nil {
+		return err
+	}
+	if to < 0 || to > latest {
+		return fmt.Errorf("no %s schema version %d (versions 0 to %d exist)", kind, to, latest)
+	}
+
+	for version := from; version < to; version++ {
+		if step := find(kind, version); step.up != nil {
+			step.up(doc)
+		}
+	}
+	for version := from; version > to; version-- {
+		if step := find(kind, version-1); step.down != nil {
+			step.down(doc)
+		}
+	}
+	if to == 0 {
+		delete(doc, Field)
+	} else {
+		doc[Field] = to
+	}
+	return nil
+}
+
+// Upgrade returns data, a JSON document of kind, converted to the current
+// version. Current documents are returned as they are.
+func Upgrade(kind Kind, data []byte) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	latest, err := Current(kind)
+	if err != nil {
+		return nil, err
+	}
+	if Version(doc) == latest {
+		return data, nil
+	}
+	if err := Convert(kind, doc, latest); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// Check returns an error when a document of kind has a version newer than
+// this fileops supports
+func Check(kind Kind, version int) error {
+	latest, err := Current(kind)
+	if err != nil {
+		return err
+	}
+	if version > latest {
+		return fmt.Errorf("%s schema version %d is newer than this fileops supports (%d)", kind, version, latest)
+	}
+	return nil
+}
+
+// find returns the step of kind from version from
+func find(kind Kind, from int) converter {
+	for _, step := range converters {
+		if step.kind == kind && step.from == from {
+			return step
+		}
+	}
+	return converter{kind: kind, from: from}
+}
+
+// upgradeResult0 brings along results written before the configuration
+// had target_paths, which held the target paths in include_patterns, and
+// whose warnings were plain messages
+func upgradeResult0(doc map[string]interface{}) {
+	if config, ok := doc["config"].(map[string]interface{}); ok {
+		patterns, legacy := config["include_patterns"]
+		if _, ok := config["target_paths"]; !ok && legacy {
+			config["target_paths"] = patterns
+			delete(config, "include_patterns")
+		}
+	}
+	if warnings, ok := doc["warnings"].([]interface{}); ok {
+		for i, warning := range warnings {
+			if message, ok := warning.(string); ok {
+				warnings[i] = map[string]interface{}{"message": message}
+			}
+		}
+	}
+}